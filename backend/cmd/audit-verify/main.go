@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/fleetflow/backend/internal/config"
+	"github.com/fleetflow/backend/internal/database"
+	"github.com/fleetflow/backend/internal/services"
+)
+
+// audit-verify checks the hash-chained audit log and exits non-zero if any chain is broken.
+//
+//	go run ./cmd/audit-verify -org 12 -archives
+func main() {
+	orgID := flag.Int("org", -1, "Organization chain to verify (0 for platform records, -1 for all)")
+	includeArchives := flag.Bool("archives", false, "Also download and verify archived ranges")
+	checkpoint := flag.Bool("checkpoint", false, "Sign the head of every verified chain when verification passes")
+	flag.Parse()
+
+	if err := config.ValidateRequiredEnv(); err != nil {
+		log.Fatal(err)
+	}
+	cfg := config.Load()
+
+	db, err := database.Initialize(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+
+	auditService := services.NewAuditService(db)
+	signingKey := cfg.AuditSigningKey
+	if signingKey == "" {
+		signingKey = cfg.JWTSecret
+	}
+	auditService.SetCheckpointSigningKey(signingKey)
+	if cfg.IsDevelopment() {
+		auditService.SetArchiveStorage(services.NewLocalStorageService(cfg))
+	} else {
+		auditService.SetArchiveStorage(services.NewS3StorageService(cfg))
+	}
+
+	var orgIDs []uint
+	if *orgID >= 0 {
+		orgIDs = append(orgIDs, uint(*orgID))
+	} else {
+		heads, err := auditService.ListAuditChains()
+		if err != nil {
+			log.Fatal("Failed to list audit chains:", err)
+		}
+		for _, head := range heads {
+			orgIDs = append(orgIDs, head.OrganizationID)
+		}
+	}
+
+	valid := true
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	for _, id := range orgIDs {
+		report, err := auditService.VerifyAuditChain(id, *includeArchives)
+		if err != nil {
+			log.Fatalf("Failed to verify organization %d: %v", id, err)
+		}
+		_ = encoder.Encode(report)
+
+		if !report.Valid {
+			valid = false
+			log.Printf("❌ Audit chain of organization %d has %d issue(s)", id, len(report.Issues))
+			continue
+		}
+		log.Printf("✅ Audit chain of organization %d verified through record %d", id, report.LastSequence)
+
+		if *checkpoint && report.LastSequence > 0 {
+			if _, err := auditService.CreateCheckpoint(id); err != nil {
+				log.Printf("⚠️ Failed to checkpoint organization %d: %v", id, err)
+			}
+		}
+	}
+
+	if !valid {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/fleetflow/backend/internal/config"
+	"github.com/fleetflow/backend/internal/database"
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+)
+
+// fuel-model-eval reports precision and recall of fuel anomaly models against admin-rejected events.
+//
+//	go run ./cmd/fuel-model-eval -type TRUCK -threshold 0.6
+func main() {
+	vehicleType := flag.String("type", "", "Vehicle class to evaluate (default: every class with an active model)")
+	version := flag.Int("version", 0, "Model version to evaluate (default: active)")
+	threshold := flag.Float64("threshold", 0, "Override the model's anomaly threshold")
+	flag.Parse()
+
+	if err := config.ValidateRequiredEnv(); err != nil {
+		log.Fatal(err)
+	}
+	cfg := config.Load()
+
+	db, err := database.Initialize(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+	fuelService := services.NewFuelService(db, services.NewAuditService(db))
+
+	var vehicleTypes []models.VehicleType
+	if *vehicleType != "" {
+		vehicleTypes = append(vehicleTypes, models.VehicleType(strings.ToUpper(*vehicleType)))
+	} else {
+		if err := db.Model(&models.FuelAnomalyModel{}).Where("is_active = ?", true).
+			Order("vehicle_type").Pluck("vehicle_type", &vehicleTypes).Error; err != nil {
+			log.Fatal("Failed to list fuel anomaly models:", err)
+		}
+	}
+	if len(vehicleTypes) == 0 {
+		log.Fatal("No trained fuel anomaly models found")
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	for _, vt := range vehicleTypes {
+		evaluation, err := fuelService.EvaluateFuelAnomalyModel(vt, *version, *threshold)
+		if err != nil {
+			log.Fatalf("Failed to evaluate %s model: %v", vt, err)
+		}
+		_ = encoder.Encode(evaluation)
+
+		if evaluation.Positives == 0 {
+			log.Printf("⚠️ %s v%d: no admin-rejected events to measure recall against", vt, evaluation.ModelVersion)
+			continue
+		}
+		log.Printf("📊 %s v%d @ %.3f: precision %.2f, recall %.2f, AUC %.2f (%d rejected, %d verified)",
+			vt, evaluation.ModelVersion, evaluation.Threshold, evaluation.Precision, evaluation.Recall,
+			evaluation.AUC, evaluation.Positives, evaluation.Negatives)
+	}
+}
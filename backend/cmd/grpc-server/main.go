@@ -46,6 +46,7 @@ func main() {
 	pb.RegisterFuelServiceServer(grpcServer, server.NewFuelServer(serviceContainer))
 	pb.RegisterUploadServiceServer(grpcServer, server.NewUploadServer(serviceContainer))
 	pb.RegisterAnalyticsServiceServer(grpcServer, server.NewAnalyticsServer(serviceContainer))
+	pb.RegisterVideoServiceServer(grpcServer, server.NewVideoServer(serviceContainer))
 
 	// Enable gRPC reflection for development
 	if cfg.IsDevelopment() {
@@ -74,6 +75,7 @@ func main() {
 	log.Printf("   - FuelService (fuel monitoring & theft detection)")
 	log.Printf("   - UploadService (file upload & storage)")
 	log.Printf("   - AnalyticsService (reports & dashboards)")
+	log.Printf("   - VideoService (resumable dashcam clip uploads)")
 
 	// Start gRPC server in a goroutine
 	go func() {
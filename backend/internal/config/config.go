@@ -50,6 +50,41 @@ type Config struct {
 	// Rate limiting
 	RateLimitRPM int // Requests per minute
 
+	// Login risk scoring
+	GeoIPDatabasePath string // CSV of start_ip,end_ip,latitude,longitude ranges client IPs are located with
+
+	// Single sign-on
+	OIDCAllowPrivateIssuers bool          // Let identity providers be reached over http or on internal hosts; development only
+	SSOCleanupInterval      time.Duration // How often expired and used SSO login requests are deleted
+
+	// Audit log integrity
+	AuditSigningKey         string        // Seed for the checkpoint signing key; defaults to JWTSecret
+	AuditCheckpointInterval time.Duration // How often chain heads are signed and old records archived
+	AuditRetentionDays      int           // Days audit records stay in the database before archival
+
+	// Fuel anomaly models
+	FuelModelRetrainInterval time.Duration // How often vehicle classes with newly reviewed fuel events are retrained
+	FuelRuleEvaluationHour   int           // Local hour the nightly fuel threshold evaluation runs at
+	FuelAlertEscalationAfter time.Duration // How long a fuel alert may stay unresolved before each escalation
+
+	// Predictive maintenance
+	MaintenanceForecastHour      int           // Local hour the nightly component failure forecast runs at
+	MaintenanceSchedulerInterval time.Duration // How often service schedules are checked for due work
+	DTCClearAfterReports         int           // Consecutive diagnostic reports a fault code may be missing from before it is cleared
+
+	// Driver safety scoring
+	DriverScoreInterval time.Duration // How often rolling driver safety scores and fleet percentiles are recalculated
+
+	// Tracker gateway
+	TrackerListeners string // Comma separated protocol:network:port listeners, e.g. "teltonika:tcp:5027,gt06:tcp:5023"
+
+	// Dashcam video
+	VideoURLSecret         string        // Key playback URLs are signed with; defaults to JWTSecret
+	VideoURLExpiry         time.Duration // How long a signed playback URL stays valid
+	VideoRetentionInterval time.Duration // How often expired clips, stalled uploads and unanswered retrievals are cleaned up
+	CameraHeartbeatTimeout time.Duration // How long a dashcam may stay silent with the ignition on before it is offline
+	CameraHealthInterval   time.Duration // How often cameras are checked for missed heartbeats
+
 	// File upload limits
 	MaxUploadSize int64 // in bytes
 
@@ -123,6 +158,41 @@ func Load() *Config {
 		// Rate limiting
 		RateLimitRPM: getIntEnv("RATE_LIMIT_RPM", 100),
 
+		// Login risk scoring
+		GeoIPDatabasePath: getEnv("GEOIP_DATABASE_PATH", ""),
+
+		// Single sign-on
+		OIDCAllowPrivateIssuers: getBoolEnv("OIDC_ALLOW_PRIVATE_ISSUERS", false),
+		SSOCleanupInterval:      getDurationEnv("SSO_CLEANUP_INTERVAL", time.Hour),
+
+		// Audit log integrity
+		AuditSigningKey:         getEnv("AUDIT_SIGNING_KEY", ""),
+		AuditCheckpointInterval: getDurationEnv("AUDIT_CHECKPOINT_INTERVAL", time.Hour),
+		AuditRetentionDays:      getIntEnv("AUDIT_RETENTION_DAYS", 365),
+
+		// Fuel anomaly models
+		FuelModelRetrainInterval: getDurationEnv("FUEL_MODEL_RETRAIN_INTERVAL", 24*time.Hour),
+		FuelRuleEvaluationHour:   getIntEnv("FUEL_RULE_EVALUATION_HOUR", 2),
+		FuelAlertEscalationAfter: getDurationEnv("FUEL_ALERT_ESCALATION_AFTER", 2*time.Hour),
+
+		// Predictive maintenance
+		MaintenanceForecastHour:      getIntEnv("MAINTENANCE_FORECAST_HOUR", 3),
+		MaintenanceSchedulerInterval: getDurationEnv("MAINTENANCE_SCHEDULER_INTERVAL", time.Hour),
+		DTCClearAfterReports:         getIntEnv("DTC_CLEAR_AFTER_REPORTS", 3),
+
+		// Driver safety scoring
+		DriverScoreInterval: getDurationEnv("DRIVER_SCORE_INTERVAL", time.Hour),
+
+		// Tracker gateway
+		TrackerListeners: getEnv("TRACKER_LISTENERS", ""),
+
+		// Dashcam video
+		VideoURLSecret:         getEnv("VIDEO_URL_SECRET", ""),
+		VideoURLExpiry:         getDurationEnv("VIDEO_URL_EXPIRY", 15*time.Minute),
+		VideoRetentionInterval: getDurationEnv("VIDEO_RETENTION_INTERVAL", time.Hour),
+		CameraHeartbeatTimeout: getDurationEnv("CAMERA_HEARTBEAT_TIMEOUT", 5*time.Minute),
+		CameraHealthInterval:   getDurationEnv("CAMERA_HEALTH_INTERVAL", time.Minute),
+
 		// File uploads
 		MaxUploadSize: getInt64Env("MAX_UPLOAD_SIZE", 10*1024*1024), // 10MB
 
@@ -77,8 +77,14 @@ func Initialize(databaseURL string) (*gorm.DB, error) {
 		&models.Fleet{},
 		&models.UserAccount{},
 		&models.RefreshToken{},
+		&models.UserSession{},
 		&models.TokenRevocation{}, // NEW: Token blacklist
 		&models.OTPVerification{},
+		&models.RecoveryCode{},
+		&models.OIDCConfig{},
+		&models.OIDCAuthRequest{},
+		&models.UserIdentity{},
+		&models.SystemSettings{},
 		&models.Driver{},
 		&models.Vehicle{},
 		&models.Trip{},
@@ -87,12 +93,35 @@ func Initialize(databaseURL string) (*gorm.DB, error) {
 		&models.DriverChangeRequest{},
 		&models.FuelEvent{},
 		&models.FuelAlert{},
+		&models.FuelThreshold{},
+		&models.FuelLevelEvent{},
+		&models.FuelAnomalyModel{},
+		&models.FuelStation{},
+		&models.FleetPreferredStation{},
+		&models.FuelCardProvider{},
+		&models.FuelCard{},
+		&models.FuelCardImport{},
+		&models.FuelCardTransaction{},
+		&models.FuelPrice{},
 		&models.AuditLog{},
+		&models.AuditChainHead{},
+		&models.AuditCheckpoint{},
+		&models.AuditArchive{},
+		&models.SecurityEvent{},
 		// Maintenance
 		&models.MaintenanceTask{},
 		&models.ServiceSchedule{},
+		&models.ServiceScheduleTemplate{},
 		&models.WorkOrder{},
 		&models.DVIR{},
+		&models.InspectionChecklist{},
+		&models.InspectionChecklistItem{},
+		&models.DVIRDefect{},
+		&models.WorkOrderPart{},
+		&models.WorkOrderLabor{},
+		&models.MechanicRate{},
+		&models.PurchaseOrderSuggestion{},
+		&models.MaintenancePrediction{},
 		// ELD & HOS
 		&models.DutyStatusLog{},
 		&models.HOSCycle{},
@@ -100,9 +129,22 @@ func Initialize(databaseURL string) (*gorm.DB, error) {
 		&models.HOSClocks{},
 		// Safety
 		&models.SafetyEvent{},
+		&models.DriverScore{},
+		&models.IMUCalibration{},
+		&models.SafetyEventTrace{},
+		&models.RoadSegment{},
+		&models.SpeedLimitOverride{},
+		&models.SpeedingEpisode{},
+		&models.CoachingSession{},
+		&models.CoachingNote{},
 		// Telemetry
 		&models.TelemetryLog{},
 		&models.DiagnosticCode{},
+		&models.DTCCatalogEntry{},
+		&models.TelemetryThreshold{},
+		&models.TelemetryBaseline{},
+		&models.TelemetryAnomaly{},
+		&models.TrackerDevice{},
 		// Asset & Yard
 		&models.Asset{},
 		&models.Yard{},
@@ -110,8 +152,12 @@ func Initialize(databaseURL string) (*gorm.DB, error) {
 		&models.InventoryItem{},
 		// Video
 		&models.Camera{},
+		&models.CameraStatusChange{},
 		&models.VideoClip{},
 		&models.AIDetection{},
+		&models.VideoUpload{},
+		&models.VideoRetrievalRequest{},
+		&models.VideoRetentionPolicy{},
 	)
 	if err != nil {
 		return nil, err
@@ -31,6 +31,9 @@ type VerifyOTPResponse struct {
 	RefreshToken string    `json:"refresh_token" example:"eyJhbGciOiJIUzI1NiIs..."`
 	ExpiresIn    int       `json:"expires_in" example:"86400"`
 	User         *UserInfo `json:"user"`
+
+	// TwoFactorSetupRequired is set when the security policy requires 2FA but the user has not enrolled
+	TwoFactorSetupRequired bool `json:"two_factor_setup_required,omitempty" example:"false"`
 }
 
 // RefreshTokenRequest represents the request to refresh access token
@@ -45,6 +48,54 @@ type RefreshTokenResponse struct {
 	ExpiresIn    int    `json:"expires_in" example:"86400"`
 }
 
+// PasswordLoginRequest represents the request to log in with email and password
+type PasswordLoginRequest struct {
+	Email    string `json:"email" binding:"required,email" example:"ops@example.com"`
+	Password string `json:"password" binding:"required" example:"S3cure!Pass"`
+}
+
+// TwoFactorChallengeResponse is returned when a password login still needs a second factor
+type TwoFactorChallengeResponse struct {
+	Message     string `json:"message" example:"Two-factor authentication required"`
+	MFARequired bool   `json:"mfa_required" example:"true"`
+	MFAToken    string `json:"mfa_token" example:"eyJhbGciOiJIUzI1NiIs..."`
+	ExpiresIn   int    `json:"expires_in" example:"300"`
+}
+
+// TwoFactorVerifyRequest represents the second step of a password login
+type TwoFactorVerifyRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required" example:"eyJhbGciOiJIUzI1NiIs..."`
+	Code     string `json:"code" binding:"required" example:"123456"` // TOTP code or recovery code
+}
+
+// ChangePasswordRequest represents the request to change the current user's password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password,omitempty" example:"OldPass123"`
+	NewPassword     string `json:"new_password" binding:"required" example:"N3wPass!234"`
+}
+
+// TOTPSetupResponse represents the TOTP enrollment details
+type TOTPSetupResponse struct {
+	Secret          string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	ProvisioningURI string `json:"provisioning_uri" example:"otpauth://totp/FleetFlow:ops%40example.com?secret=JBSWY3DPEHPK3PXP&issuer=FleetFlow"`
+}
+
+// TOTPCodeRequest represents a request carrying a TOTP code
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required" example:"123456"`
+}
+
+// DisableTOTPRequest represents the request to turn off two-factor authentication
+type DisableTOTPRequest struct {
+	Password string `json:"password" binding:"required" example:"S3cure!Pass"`
+}
+
+// RecoveryCodesResponse returns freshly generated recovery codes (shown only once)
+type RecoveryCodesResponse struct {
+	Message       string   `json:"message" example:"Store these recovery codes in a safe place"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
 // UserInfo represents user information in responses
 type UserInfo struct {
 	ID       uint        `json:"id" example:"1"`
@@ -66,7 +117,12 @@ type DriverInfo struct {
 // UpdateProfileRequest represents the request to update user profile
 type UpdateProfileRequest struct {
 	Name  string `json:"name,omitempty" example:"राहुल शर्मा"`
-	Email string `json:"email,omitempty" example:"rahul@example.com"`
+	Email string `json:"email,omitempty" binding:"omitempty,email" example:"rahul@example.com"` // Takes effect once verified
+}
+
+// VerifyEmailRequest confirms a new email address with the token sent to it
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
 }
 
 // LogoutRequest represents the logout request
@@ -87,14 +143,17 @@ type CreateUserRequest struct {
 
 // UserResponse represents user data in admin responses
 type UserResponse struct {
-	ID        uint        `json:"id" example:"1"`
-	Phone     string      `json:"phone" example:"+919876543210"`
-	Role      models.Role `json:"role" example:"DRIVER"`
-	IsActive  bool        `json:"is_active" example:"true"`
-	LastLogin *time.Time  `json:"last_login,omitempty" example:"2024-01-01T12:00:00Z"`
-	CreatedAt time.Time   `json:"created_at" example:"2024-01-01T10:00:00Z"`
-	DriverID  *uint       `json:"driver_id,omitempty" example:"123"`
-	Driver    *DriverInfo `json:"driver,omitempty"`
+	ID            uint        `json:"id" example:"1"`
+	Phone         string      `json:"phone" example:"+919876543210"`
+	Email         string      `json:"email,omitempty" example:"rahul@example.com"`
+	PendingEmail  string      `json:"pending_email,omitempty" example:"rahul.sharma@example.com"` // Replaces Email once verified
+	EmailVerified bool        `json:"email_verified" example:"true"`
+	Role          models.Role `json:"role" example:"DRIVER"`
+	IsActive      bool        `json:"is_active" example:"true"`
+	LastLogin     *time.Time  `json:"last_login,omitempty" example:"2024-01-01T12:00:00Z"`
+	CreatedAt     time.Time   `json:"created_at" example:"2024-01-01T10:00:00Z"`
+	DriverID      *uint       `json:"driver_id,omitempty" example:"123"`
+	Driver        *DriverInfo `json:"driver,omitempty"`
 }
 
 // UsersListResponse represents paginated list of users
@@ -151,3 +210,56 @@ type FilterParams struct {
 	SortBy   string `form:"sort_by,default=created_at" example:"name"`
 	SortDesc bool   `form:"sort_desc,default=true" example:"true"`
 }
+
+// SSOCallbackRequest carries the authorization response the frontend received from the identity provider
+type SSOCallbackRequest struct {
+	Code  string `json:"code" binding:"required" example:"SplxlOBeZQQYbYS6WxSbIA"`
+	State string `json:"state" binding:"required" example:"af0ifjsldkj"`
+}
+
+// LinkSSOIdentityRequest links an identity at the organization's IdP to an existing account
+type LinkSSOIdentityRequest struct {
+	UserID  uint   `json:"user_id" binding:"required" example:"42"`
+	Subject string `json:"subject" binding:"required" example:"248289761001"` // The IdP's "sub" claim
+}
+
+// OIDCConfigRequest represents an organization's OpenID Connect settings
+type OIDCConfigRequest struct {
+	Issuer              string                 `json:"issuer" binding:"required,url" example:"https://login.example.com/realms/acme"`
+	ClientID            string                 `json:"client_id" binding:"required" example:"fleetflow-web"`
+	ClientSecret        string                 `json:"client_secret,omitempty" example:"s3cr3t"`
+	RedirectURL         string                 `json:"redirect_url" binding:"required,url" example:"https://app.fleetflow.com/sso/callback"`
+	Scopes              string                 `json:"scopes,omitempty" example:"openid email profile"`
+	RoleClaim           string                 `json:"role_claim,omitempty" example:"groups"`
+	RoleMapping         map[string]models.Role `json:"role_mapping,omitempty"`
+	DefaultRole         models.Role            `json:"default_role,omitempty" example:"VIEWER"`
+	AllowedEmailDomains string                 `json:"allowed_email_domains,omitempty" example:"acme.com"`
+	AutoProvision       *bool                  `json:"auto_provision,omitempty" example:"true"`
+	Enabled             *bool                  `json:"enabled,omitempty" example:"true"`
+}
+
+// SessionResponse represents one of the user's logged-in devices
+type SessionResponse struct {
+	ID            uint      `json:"id" example:"42"`
+	DeviceName    string    `json:"device_name,omitempty" example:"Pixel 7"`
+	UserAgent     string    `json:"user_agent,omitempty" example:"FleetFlowDriver/2.3 (Android 14)"`
+	IPAddress     string    `json:"ip_address,omitempty" example:"203.0.113.7"`
+	LastIPAddress string    `json:"last_ip_address,omitempty" example:"203.0.113.9"`
+	Location      string    `json:"location,omitempty" example:"Pune, IN"`
+	AuthMethod    string    `json:"auth_method,omitempty" example:"otp"`
+	CreatedAt     time.Time `json:"created_at" example:"2024-01-01T10:00:00Z"`
+	LastUsedAt    time.Time `json:"last_used_at" example:"2024-01-01T12:00:00Z"`
+	ExpiresAt     time.Time `json:"expires_at" example:"2024-01-08T10:00:00Z"`
+	Current       bool      `json:"current" example:"true"`
+}
+
+// SessionsListResponse represents the user's active sessions
+type SessionsListResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+}
+
+// InvestigateSecurityEventRequest represents an admin's findings on a security event
+type InvestigateSecurityEventRequest struct {
+	Notes     string `json:"notes" binding:"required" example:"Confirmed with driver; new phone issued"`
+	LiftBlock bool   `json:"lift_block" example:"true"`
+}
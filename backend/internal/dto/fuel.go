@@ -0,0 +1,143 @@
+package dto
+
+import "time"
+
+// FuelSensorAnalysisRequest runs tank-level theft and refuel detection over a window
+type FuelSensorAnalysisRequest struct {
+	VehicleID uint       `json:"vehicle_id" binding:"required" example:"12"`
+	From      *time.Time `json:"from,omitempty" example:"2024-03-01T00:00:00Z"`
+	To        *time.Time `json:"to,omitempty" example:"2024-03-02T00:00:00Z"`
+}
+
+// FuelModelTrainRequest trains a new anomaly model version; an empty vehicle type trains every class
+type FuelModelTrainRequest struct {
+	VehicleType string `json:"vehicle_type,omitempty" example:"TRUCK"`
+}
+
+// CreateFuelThresholdRequest adds a global fuel rule, or a per-vehicle override when vehicle_id is set
+type CreateFuelThresholdRequest struct {
+	VehicleID     *uint   `json:"vehicle_id,omitempty" example:"12"`
+	ThresholdType string  `json:"threshold_type" binding:"required,oneof=CONSUMPTION_VARIANCE EFFICIENCY_DROP REFUEL_FREQUENCY PRICE_VARIANCE" example:"CONSUMPTION_VARIANCE"`
+	Value         float64 `json:"value" binding:"required,gt=0" example:"20"`
+	Unit          string  `json:"unit,omitempty" example:"PERCENTAGE"` // Defaults to the first unit the type accepts
+	Severity      string  `json:"severity,omitempty" example:"HIGH"`   // Severity of raised alerts, MEDIUM when empty
+	IsActive      *bool   `json:"is_active,omitempty" example:"true"`
+}
+
+// UpdateFuelThresholdRequest changes a fuel rule; omitted fields are left unchanged
+type UpdateFuelThresholdRequest struct {
+	Value    *float64 `json:"value,omitempty" binding:"omitempty,gt=0" example:"25"`
+	Unit     *string  `json:"unit,omitempty" example:"LITERS"`
+	Severity *string  `json:"severity,omitempty" example:"CRITICAL"`
+	IsActive *bool    `json:"is_active,omitempty" example:"false"`
+}
+
+// ResolveFuelAlertRequest closes a fuel alert
+type ResolveFuelAlertRequest struct {
+	Notes string `json:"notes" binding:"required" example:"Driver topped up a reefer unit; receipt attached"`
+}
+
+// CreateFuelCardProviderRequest registers a fuel-card issuer and how to read its statements
+type CreateFuelCardProviderRequest struct {
+	Name       string            `json:"name" binding:"required" example:"IOCL XTRAPOWER"`
+	Columns    map[string]string `json:"columns" binding:"required" example:"card_number:Card No,transacted_at:Txn Date,litres:Qty (Ltr),amount:Amount (Rs)"` // Canonical field to CSV header
+	Delimiter  string            `json:"delimiter,omitempty" binding:"omitempty,len=1" example:","`
+	DateFormat string            `json:"date_format,omitempty" example:"02/01/2006 15:04:05"` // Go layout
+	Timezone   string            `json:"timezone,omitempty" example:"Asia/Kolkata"`
+	IsActive   *bool             `json:"is_active,omitempty" example:"true"`
+}
+
+// UpdateFuelCardProviderRequest changes a provider; omitted fields are left unchanged
+type UpdateFuelCardProviderRequest struct {
+	Name       *string           `json:"name,omitempty" example:"IOCL XTRAPOWER"`
+	Columns    map[string]string `json:"columns,omitempty"` // Replaces the whole mapping when set
+	Delimiter  *string           `json:"delimiter,omitempty" binding:"omitempty,len=1" example:";"`
+	DateFormat *string           `json:"date_format,omitempty" example:"2006-01-02 15:04"`
+	Timezone   *string           `json:"timezone,omitempty" example:"Asia/Kolkata"`
+	IsActive   *bool             `json:"is_active,omitempty" example:"false"`
+}
+
+// CreateFuelCardRequest assigns a provider's card to a vehicle
+type CreateFuelCardRequest struct {
+	ProviderID uint   `json:"provider_id" binding:"required" example:"1"`
+	CardNumber string `json:"card_number" binding:"required" example:"7001 2345 6789 0123"`
+	VehicleID  *uint  `json:"vehicle_id,omitempty" example:"12"`
+	DriverID   *uint  `json:"driver_id,omitempty" example:"4"`
+	IsActive   *bool  `json:"is_active,omitempty" example:"true"`
+}
+
+// UpdateFuelCardRequest reassigns or deactivates a card; omitted fields are left unchanged
+type UpdateFuelCardRequest struct {
+	VehicleID *uint `json:"vehicle_id,omitempty" example:"15"`
+	DriverID  *uint `json:"driver_id,omitempty" example:"7"`
+	IsActive  *bool `json:"is_active,omitempty" example:"false"`
+}
+
+// FuelPriceFeedEntry is one published price
+type FuelPriceFeedEntry struct {
+	Date          string  `json:"date" binding:"required" example:"2024-03-01"` // YYYY-MM-DD
+	Region        string  `json:"region,omitempty" example:"MAHARASHTRA"`       // State; empty for a national price
+	FuelType      string  `json:"fuel_type,omitempty" example:"DIESEL"`         // DIESEL when empty
+	PricePerLiter float64 `json:"price_per_liter" binding:"required,gt=0" example:"92.15"`
+	FuelStationID *uint   `json:"fuel_station_id,omitempty" example:"3"`
+}
+
+// FuelPriceFeedRequest imports prices published by an oil marketing company or aggregator
+type FuelPriceFeedRequest struct {
+	Source string               `json:"source" binding:"required" example:"IOCL"`
+	Prices []FuelPriceFeedEntry `json:"prices" binding:"required,min=1,dive"`
+}
+
+// FuelPriceRebuildRequest recomputes price history from verified fuel events; defaults to the last 7 days
+type FuelPriceRebuildRequest struct {
+	From *time.Time `json:"from,omitempty" example:"2024-03-01T00:00:00Z"`
+	To   *time.Time `json:"to,omitempty" example:"2024-03-31T00:00:00Z"`
+}
+
+// CreateFuelStationRequest registers a fuel station
+type CreateFuelStationRequest struct {
+	ExternalID string   `json:"external_id,omitempty" example:"IOCL-27-004512"`
+	Name       string   `json:"name" binding:"required" example:"IOC Wakad"`
+	Brand      string   `json:"brand,omitempty" example:"IOC"`
+	Address    string   `json:"address,omitempty" example:"Mumbai-Bangalore Highway, Wakad"`
+	City       string   `json:"city,omitempty" example:"Pune"`
+	State      string   `json:"state,omitempty" example:"Maharashtra"`
+	Pincode    string   `json:"pincode,omitempty" example:"411057"`
+	Latitude   float64  `json:"latitude" binding:"required,min=-90,max=90" example:"18.5986"`
+	Longitude  float64  `json:"longitude" binding:"required,min=-180,max=180" example:"73.765"`
+	Phone      string   `json:"phone,omitempty" example:"+912027654321"`
+	FuelTypes  []string `json:"fuel_types,omitempty" example:"DIESEL,PETROL"`
+	Amenities  []string `json:"amenities,omitempty" example:"TRUCK_PARKING,RESTROOM"`
+	Is24Hours  bool     `json:"is_24_hours" example:"true"`
+	IsActive   *bool    `json:"is_active,omitempty" example:"true"`
+}
+
+// UpdateFuelStationRequest changes a station; omitted fields are left unchanged
+type UpdateFuelStationRequest struct {
+	ExternalID *string  `json:"external_id,omitempty" example:"IOCL-27-004512"`
+	Name       *string  `json:"name,omitempty" example:"IOC Wakad"`
+	Brand      *string  `json:"brand,omitempty" example:"IOC"`
+	Address    *string  `json:"address,omitempty"`
+	City       *string  `json:"city,omitempty" example:"Pune"`
+	State      *string  `json:"state,omitempty" example:"Maharashtra"`
+	Pincode    *string  `json:"pincode,omitempty" example:"411057"`
+	Latitude   *float64 `json:"latitude,omitempty" binding:"omitempty,min=-90,max=90" example:"18.5986"`
+	Longitude  *float64 `json:"longitude,omitempty" binding:"omitempty,min=-180,max=180" example:"73.765"`
+	Phone      *string  `json:"phone,omitempty"`
+	FuelTypes  []string `json:"fuel_types,omitempty"` // Replaces the list when set
+	Amenities  []string `json:"amenities,omitempty"`  // Replaces the list when set
+	Is24Hours  *bool    `json:"is_24_hours,omitempty" example:"false"`
+	IsActive   *bool    `json:"is_active,omitempty" example:"false"`
+}
+
+// ImportFuelStationsRequest bulk-loads stations; entries matching a registered station by
+// external ID, or by name within 300 m, update it
+type ImportFuelStationsRequest struct {
+	Stations []CreateFuelStationRequest `json:"stations" binding:"required,min=1,dive"`
+}
+
+// FleetPreferredStationRequest adds a station to a fleet's preferred list or changes its entry
+type FleetPreferredStationRequest struct {
+	Priority int    `json:"priority" binding:"min=0" example:"1"` // Lower is preferred first
+	Notes    string `json:"notes,omitempty" example:"Negotiated fleet discount"`
+}
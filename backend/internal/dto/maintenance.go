@@ -0,0 +1,109 @@
+package dto
+
+import "time"
+
+// ServiceIntervals is when a service is due (whichever interval comes first) and how early to warn
+type ServiceIntervals struct {
+	IntervalMileage     int     `json:"interval_mileage,omitempty" binding:"min=0" example:"20000"`
+	IntervalMonths      int     `json:"interval_months,omitempty" binding:"min=0" example:"6"`
+	IntervalEngineHours float64 `json:"interval_engine_hours,omitempty" binding:"min=0" example:"500"`
+	DueSoonMileage      int     `json:"due_soon_mileage,omitempty" binding:"min=0" example:"1000"`    // Default 500
+	DueSoonDays         int     `json:"due_soon_days,omitempty" binding:"min=0" example:"14"`         // Default 14
+	DueSoonEngineHours  float64 `json:"due_soon_engine_hours,omitempty" binding:"min=0" example:"25"` // Default 25
+}
+
+// CreateServiceScheduleRequest schedules a maintenance task for a vehicle
+type CreateServiceScheduleRequest struct {
+	VehicleID         uint `json:"vehicle_id" binding:"required" example:"12"`
+	MaintenanceTaskID uint `json:"maintenance_task_id" binding:"required" example:"3"`
+	ServiceIntervals
+}
+
+// ServiceScheduleTemplateRequest sets the standard schedule of a task for a vehicle type
+type ServiceScheduleTemplateRequest struct {
+	VehicleType       string `json:"vehicle_type" binding:"required,oneof=TRUCK VAN BIKE PICKUP TRAILER" example:"TRUCK"`
+	MaintenanceTaskID uint   `json:"maintenance_task_id" binding:"required" example:"3"`
+	IsActive          *bool  `json:"is_active,omitempty" example:"true"`
+	ServiceIntervals
+}
+
+// AddWorkOrderPartRequest reserves stock of an inventory item for a work order
+type AddWorkOrderPartRequest struct {
+	InventoryItemID uint `json:"inventory_item_id" binding:"required" example:"7"`
+	Quantity        int  `json:"quantity" binding:"required,gt=0" example:"2"`
+}
+
+// AddWorkOrderLaborRequest records a mechanic's time; hourly_rate defaults to the mechanic's standard rate
+type AddWorkOrderLaborRequest struct {
+	MechanicID  uint       `json:"mechanic_id" binding:"required" example:"15"`
+	Hours       float64    `json:"hours" binding:"required,gt=0,lte=24" example:"1.5"`
+	HourlyRate  *float64   `json:"hourly_rate,omitempty" binding:"omitempty,min=0" example:"450"`
+	Description string     `json:"description,omitempty" example:"Drained and refilled engine oil"`
+	PerformedAt *time.Time `json:"performed_at,omitempty" example:"2024-03-01T10:00:00Z"`
+}
+
+// MechanicRateRequest sets a mechanic's standard hourly rate
+type MechanicRateRequest struct {
+	HourlyRate float64 `json:"hourly_rate" binding:"min=0" example:"450"`
+}
+
+// UpdatePurchaseSuggestionRequest marks a purchase suggestion ordered or dismissed
+type UpdatePurchaseSuggestionRequest struct {
+	Status string `json:"status" binding:"required,oneof=ORDERED DISMISSED" example:"ORDERED"`
+}
+
+// DVIRDefectInput is one defect found on an inspection, against a checklist item or described freely
+type DVIRDefectInput struct {
+	ChecklistItemID *uint  `json:"checklist_item_id,omitempty" example:"4"`
+	Category        string `json:"category,omitempty" example:"Brakes"`
+	Description     string `json:"description,omitempty" example:"Air leak at rear brake chamber"`
+	Severity        string `json:"severity,omitempty" binding:"omitempty,oneof=MINOR MAJOR OUT_OF_SERVICE" example:"OUT_OF_SERVICE"`
+	PhotoUploadIDs  []uint `json:"photo_upload_ids,omitempty"`
+}
+
+// SubmitDVIRRequest is a driver vehicle inspection report. A pre-trip report must acknowledge
+// every defect a mechanic has certified since the vehicle's last inspection.
+type SubmitDVIRRequest struct {
+	VehicleID             uint              `json:"vehicle_id" binding:"required" example:"12"`
+	DriverID              uint              `json:"driver_id,omitempty" example:"5"` // Defaults to the signed-in driver
+	Type                  string            `json:"type" binding:"required,oneof=PRE_TRIP POST_TRIP" example:"PRE_TRIP"`
+	Odometer              int               `json:"odometer,omitempty" binding:"min=0" example:"48210"`
+	Location              string            `json:"location,omitempty" example:"Bhiwandi depot"`
+	DefectItems           []DVIRDefectInput `json:"defect_items,omitempty" binding:"dive"`
+	Defects               string            `json:"defects,omitempty" example:"[\"Left indicator not working\"]"` // Legacy JSON array of descriptions
+	AcknowledgedDefectIDs []uint            `json:"acknowledged_defect_ids,omitempty"`
+	DriverSign            string            `json:"driver_sign" binding:"required" example:"R. Sharma"`
+}
+
+// InspectionChecklistItemInput is one item of a checklist
+type InspectionChecklistItemInput struct {
+	Code     string `json:"code" binding:"required,max=50" example:"BRAKES_SERVICE"`
+	Category string `json:"category,omitempty" example:"Brakes"`
+	Label    string `json:"label" binding:"required" example:"Service brakes hold and release"`
+	Severity string `json:"severity,omitempty" binding:"omitempty,oneof=MINOR MAJOR OUT_OF_SERVICE" example:"OUT_OF_SERVICE"` // Default MINOR
+}
+
+// InspectionChecklistRequest sets the inspection checklist for a vehicle type
+type InspectionChecklistRequest struct {
+	VehicleType string                         `json:"vehicle_type" binding:"required,oneof=TRUCK VAN BIKE PICKUP TRAILER" example:"TRUCK"`
+	Name        string                         `json:"name" binding:"required" example:"Truck daily inspection"`
+	IsActive    *bool                          `json:"is_active,omitempty" example:"true"`
+	Items       []InspectionChecklistItemInput `json:"items" binding:"required,min=1,dive"`
+}
+
+// CertifyDefectRequest is a mechanic's certification that a defect was repaired or needs no repair
+type CertifyDefectRequest struct {
+	Resolution   string `json:"resolution" binding:"required,oneof=CORRECTED NO_REPAIR_NEEDED" example:"CORRECTED"`
+	Notes        string `json:"notes,omitempty" example:"Replaced brake chamber diaphragm"`
+	MechanicSign string `json:"mechanic_sign" binding:"required" example:"S. Patil"`
+}
+
+// LinkDefectWorkOrderRequest moves a defect onto another open work order of the same vehicle
+type LinkDefectWorkOrderRequest struct {
+	WorkOrderID uint `json:"work_order_id" binding:"required" example:"31"`
+}
+
+// AttachDefectPhotosRequest attaches uploaded photos to a defect
+type AttachDefectPhotosRequest struct {
+	UploadIDs []uint `json:"upload_ids" binding:"required,min=1"`
+}
@@ -0,0 +1,48 @@
+package dto
+
+import "time"
+
+// SpeedLimitOverrideRequest caps the speed limit for a vehicle type on one road class, or on
+// every road when the class is omitted
+type SpeedLimitOverrideRequest struct {
+	VehicleType string  `json:"vehicle_type" binding:"required,oneof=TRUCK VAN BIKE PICKUP TRAILER" example:"TRUCK"`
+	Highway     string  `json:"highway,omitempty" example:"motorway"`           // OSM road class
+	MaxSpeed    float64 `json:"max_speed" binding:"required,gt=0" example:"80"` // km/h
+}
+
+// GeofenceSpeedLimitRequest sets a geofence's speed cap; null removes it
+type GeofenceSpeedLimitRequest struct {
+	SpeedLimit *float64 `json:"speed_limit" example:"25"` // km/h
+}
+
+// CoachingSessionRequest queues a safety event or a video clip for coaching
+type CoachingSessionRequest struct {
+	SafetyEventID *uint      `json:"safety_event_id,omitempty" example:"42"`
+	VideoClipID   *uint      `json:"video_clip_id,omitempty"`
+	DriverID      *uint      `json:"driver_id,omitempty"` // Defaults to the driver the event or clip is attributed to
+	CoachID       *uint      `json:"coach_id,omitempty" example:"7"`
+	DueAt         *time.Time `json:"due_at,omitempty"`
+	Note          string     `json:"note,omitempty"`
+}
+
+// AssignCoachRequest hands a coaching session to a coach
+type AssignCoachRequest struct {
+	CoachID uint       `json:"coach_id" binding:"required" example:"7"`
+	DueAt   *time.Time `json:"due_at,omitempty"`
+}
+
+// CoachingNoteRequest attaches a note to a coaching session
+type CoachingNoteRequest struct {
+	Body string `json:"body" binding:"required" example:"Discussed following distance on the ghat section"`
+}
+
+// ResolveCoachingRequest closes a coaching session
+type ResolveCoachingRequest struct {
+	Status string `json:"status" binding:"required,oneof=COACHED DISMISSED FALSE_POSITIVE" example:"COACHED"`
+	Note   string `json:"note,omitempty"`
+}
+
+// AcknowledgeCoachingRequest is the driver confirming they were coached
+type AcknowledgeCoachingRequest struct {
+	Comment string `json:"comment,omitempty" example:"Understood, will keep to the limit"`
+}
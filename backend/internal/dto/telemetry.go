@@ -0,0 +1,77 @@
+package dto
+
+import "time"
+
+// DiagnosticReportRequest is the set of fault codes a vehicle currently reports. An empty codes
+// list counts towards clearing codes that are no longer reported.
+type DiagnosticReportRequest struct {
+	VehicleID uint       `json:"vehicle_id" binding:"required" example:"12"`
+	Codes     []string   `json:"codes" example:"P0300,SPN110-FMI0"`
+	DM1       string     `json:"dm1,omitempty" example:"0400 6E00000301"` // J1939 DM1 payload in hex
+	Timestamp *time.Time `json:"timestamp,omitempty" example:"2024-03-01T10:00:00Z"`
+}
+
+// DTCCatalogEntryRequest describes an OBD-II code, a J1939 SPN ("SPN110") or SPN and FMI ("SPN110-FMI0")
+type DTCCatalogEntryRequest struct {
+	Code              string `json:"code" binding:"required" example:"SPN110"`
+	Description       string `json:"description" binding:"required" example:"Engine coolant temperature"`
+	Severity          string `json:"severity" binding:"required,oneof=LOW MEDIUM HIGH CRITICAL" example:"HIGH"`
+	RecommendedAction string `json:"recommended_action,omitempty" example:"Check coolant level, fan and thermostat"`
+	Component         string `json:"component,omitempty" example:"COOLING_SYSTEM"`
+}
+
+// TelemetryThresholdRequest sets limits for a signal fleet-wide, for a vehicle make, or for a make and model.
+// Rates of change are per minute.
+type TelemetryThresholdRequest struct {
+	VehicleMake   string   `json:"vehicle_make,omitempty" example:"Tata"`
+	VehicleModel  string   `json:"vehicle_model,omitempty" example:"Prima 4028.S"`
+	Signal        string   `json:"signal" binding:"required" example:"COOLANT_TEMP"`
+	Min           *float64 `json:"min,omitempty"`
+	Max           *float64 `json:"max,omitempty" example:"100"`
+	CriticalMin   *float64 `json:"critical_min,omitempty"`
+	CriticalMax   *float64 `json:"critical_max,omitempty" example:"108"`
+	MaxRisePerMin *float64 `json:"max_rise_per_min,omitempty" example:"4"`
+	MaxFallPerMin *float64 `json:"max_fall_per_min,omitempty"`
+	Severity      string   `json:"severity,omitempty" binding:"omitempty,oneof=LOW MEDIUM HIGH CRITICAL" example:"HIGH"`
+	IsActive      *bool    `json:"is_active,omitempty"`
+}
+
+// TrackerDeviceRequest registers a telematics unit, or reassigns one, by its IMEI
+type TrackerDeviceRequest struct {
+	IMEI      string `json:"imei" binding:"required" example:"356307042441013"`
+	Protocol  string `json:"protocol" binding:"required" example:"teltonika"` // teltonika, gt06, queclink
+	VehicleID *uint  `json:"vehicle_id,omitempty" example:"12"`               // Omit to unassign
+	Model     string `json:"model,omitempty" example:"FMC130"`
+	IsActive  *bool  `json:"is_active,omitempty"`
+}
+
+// TrackerDecodeRequest is a captured tracker payload to decode without storing it
+type TrackerDecodeRequest struct {
+	Protocol string `json:"protocol" binding:"required" example:"gt06"`
+	Payload  string `json:"payload" binding:"required" example:"78780D01012345678901234500018CDD0D0A"` // Hex; queclink reports as sent
+	UDP      bool   `json:"udp,omitempty"`                                                             // A single UDP packet rather than a TCP stream
+}
+
+// IMUSampleRequest is one accelerometer (m/s²) and gyro (deg/s) reading in the sensor's axes
+type IMUSampleRequest struct {
+	Timestamp time.Time `json:"timestamp" binding:"required" example:"2024-03-01T10:00:00.100Z"`
+	AX        float64   `json:"ax" example:"0.2"`
+	AY        float64   `json:"ay" example:"-0.1"`
+	AZ        float64   `json:"az" example:"9.81"`
+	GX        float64   `json:"gx"`
+	GY        float64   `json:"gy"`
+	GZ        float64   `json:"gz" example:"1.5"`
+	Speed     *float64  `json:"speed,omitempty" example:"42"` // GPS km/h when the sample was taken
+}
+
+// IMUBatchRequest is a run of accelerometer and gyro samples from one vehicle. Samples from a
+// sensor mounted any way up are oriented to the vehicle as it learns the mounting; "vehicle"
+// frame samples are already x forward, y left, z up.
+type IMUBatchRequest struct {
+	VehicleID uint               `json:"vehicle_id" binding:"required" example:"12"`
+	DriverID  *uint              `json:"driver_id,omitempty"`
+	Frame     string             `json:"frame,omitempty" binding:"omitempty,oneof=device vehicle" example:"device"`
+	Latitude  *float64           `json:"latitude,omitempty" example:"18.5204"`
+	Longitude *float64           `json:"longitude,omitempty" example:"73.8567"`
+	Samples   []IMUSampleRequest `json:"samples" binding:"required,min=1,dive"`
+}
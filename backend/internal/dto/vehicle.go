@@ -16,6 +16,7 @@ type CreateVehicleRequest struct {
 	FuelType     string             `json:"fuel_type,omitempty" example:"DIESEL"`
 	FuelCapacity float64            `json:"fuel_capacity" example:"400"`
 	LoadCapacity float64            `json:"load_capacity" example:"15000"`
+	FleetID      *uint              `json:"fleet_id,omitempty" example:"1"`
 }
 
 // VehicleResponse represents vehicle data in API responses
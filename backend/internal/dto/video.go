@@ -0,0 +1,49 @@
+package dto
+
+import "time"
+
+// ClipUploadRequest opens a resumable upload of a clip's footage from a camera
+type ClipUploadRequest struct {
+	CameraSerial       string    `json:"camera_serial" binding:"required" example:"DC-0042"`
+	EventType          string    `json:"event_type,omitempty" binding:"omitempty,oneof=HARSH_BRAKING HARSH_ACCELERATION HARSH_CORNERING IMPACT SPEEDING MANUAL DISTRACTION DROWSINESS" example:"IMPACT"`
+	StartTime          time.Time `json:"start_time"`
+	EndTime            time.Time `json:"end_time"`
+	ContentType        string    `json:"content_type,omitempty" example:"video/mp4"`
+	FileSize           int64     `json:"file_size" binding:"required,gt=0" example:"10485760"`
+	SHA256             string    `json:"sha256,omitempty"`  // Hex digest of the whole file, checked once it is assembled
+	ClipID             *uint     `json:"clip_id,omitempty"` // Clip already recorded for an AI event
+	RetrievalRequestID *uint     `json:"retrieval_request_id,omitempty"`
+}
+
+// FootageRetrievalRequest asks a camera, or the camera on a vehicle, to upload the footage of a window
+type FootageRetrievalRequest struct {
+	CameraID  *uint     `json:"camera_id,omitempty"`
+	VehicleID *uint     `json:"vehicle_id,omitempty" example:"12"`
+	StartTime time.Time `json:"start_time" binding:"required"`
+	EndTime   time.Time `json:"end_time" binding:"required"`
+	EventType string    `json:"event_type,omitempty" binding:"omitempty,oneof=HARSH_BRAKING HARSH_ACCELERATION HARSH_CORNERING IMPACT SPEEDING MANUAL DISTRACTION DROWSINESS" example:"MANUAL"`
+	Reason    string    `json:"reason,omitempty" example:"Customer reported a scraped gate"`
+}
+
+// FailRetrievalRequest is a camera answering that it no longer holds the footage asked for
+type FailRetrievalRequest struct {
+	Reason string `json:"reason" binding:"required" example:"Footage overwritten"`
+}
+
+// VideoRetentionRequest sets how many days clips of an event type are kept
+type VideoRetentionRequest struct {
+	EventType     string `json:"event_type" binding:"required,oneof=HARSH_BRAKING HARSH_ACCELERATION HARSH_CORNERING IMPACT SPEEDING MANUAL DISTRACTION DROWSINESS" example:"IMPACT"`
+	RetentionDays int    `json:"retention_days" binding:"required,gt=0" example:"365"`
+}
+
+// CameraHeartbeatRequest is a dashcam's periodic health report
+type CameraHeartbeatRequest struct {
+	Serial         string    `json:"serial" binding:"required" example:"DC-0042"`
+	FirmwareVer    string    `json:"firmware_ver,omitempty" example:"2.4.1"`
+	Timestamp      time.Time `json:"timestamp"`
+	Ignition       *bool     `json:"ignition,omitempty"`
+	Recording      *bool     `json:"recording,omitempty"`
+	SDCardStatus   string    `json:"sd_card_status,omitempty" binding:"omitempty,oneof=OK MISSING FULL ERROR" example:"OK"`
+	LensObstructed bool      `json:"lens_obstructed,omitempty"`
+	Errors         []string  `json:"errors,omitempty"` // Other fault codes
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -201,9 +202,12 @@ func (s *TripServer) StartTrip(ctx context.Context, req *pb.StartTripRequest) (*
 	}
 
 	// Start trip via service
-	err := s.services.TripService.StartTrip(uint(req.TripId))
+	err := s.services.TripService.StartTrip(ctx, uint(req.TripId))
 	if err != nil {
 		log.Printf("❌ Failed to start trip: %v", err)
+		if errors.Is(err, services.ErrVehicleOutOfService) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
 		return nil, status.Error(codes.Internal, "failed to start trip")
 	}
 
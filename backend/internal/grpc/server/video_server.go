@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	pb "github.com/fleetflow/backend/proto/gen"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// clipFlushSize is how much of a streamed clip is buffered before it is staged as a chunk
+const clipFlushSize = 1 << 20
+
+// VideoServer implements the VideoService gRPC service
+type VideoServer struct {
+	pb.UnimplementedVideoServiceServer
+	services *services.Container
+}
+
+// NewVideoServer creates a new VideoServer
+func NewVideoServer(services *services.Container) *VideoServer {
+	return &VideoServer{
+		services: services,
+	}
+}
+
+// UploadClip receives a clip's metadata followed by its file in chunks. Chunks received before
+// the stream breaks are kept, so the camera can resume from the returned offset.
+func (s *VideoServer) UploadClip(stream pb.VideoService_UploadClipServer) error {
+	ctx := stream.Context()
+	first, err := stream.Recv()
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "metadata is required")
+	}
+	meta := first.GetMetadata()
+	if meta == nil {
+		return status.Error(codes.InvalidArgument, "the first message must carry the clip metadata")
+	}
+
+	videoService := s.services.VideoService
+	var upload *models.VideoUpload
+	if meta.GetUploadId() != "" {
+		upload, err = videoService.GetClipUpload(ctx, meta.GetUploadId())
+		if err != nil {
+			return videoStatus(err)
+		}
+		if upload.Status != models.VideoUploadOpen || upload.ReceivedBytes != meta.GetOffset() {
+			return stream.SendAndClose(clipUploadStatus(upload, false, "resume from received_bytes"))
+		}
+	} else {
+		req := services.ClipUploadRequest{
+			CameraSerial: meta.GetCameraSerial(),
+			EventType:    models.VideoEventType(meta.GetEventType()),
+			ContentType:  meta.GetContentType(),
+			FileSize:     meta.GetFileSize(),
+			SHA256:       meta.GetSha256(),
+		}
+		if meta.GetStartTime() != nil {
+			req.StartTime = meta.GetStartTime().AsTime()
+		}
+		if meta.GetEndTime() != nil {
+			req.EndTime = meta.GetEndTime().AsTime()
+		}
+		if id := uint(meta.GetClipId()); id != 0 {
+			req.ClipID = &id
+		}
+		if id := uint(meta.GetRetrievalRequestId()); id != 0 {
+			req.RetrievalRequestID = &id
+		}
+		upload, err = videoService.StartClipUpload(ctx, req)
+		if err != nil {
+			return videoStatus(err)
+		}
+	}
+	log.Printf("🎥 UploadClip %s from offset %d", upload.UploadID, upload.ReceivedBytes)
+
+	var buffer []byte
+	flush := func() error {
+		if len(buffer) == 0 {
+			return nil
+		}
+		// The stream may already be cancelled; what was received is still kept
+		written, err := videoService.WriteClipChunk(context.WithoutCancel(ctx), upload.UploadID, upload.ReceivedBytes, buffer)
+		if written != nil {
+			upload = written
+		}
+		buffer = buffer[:0]
+		return err
+	}
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if flushErr := flush(); flushErr != nil {
+				log.Printf("❌ Failed to keep the received part of upload %s: %v", upload.UploadID, flushErr)
+			}
+			return err
+		}
+		buffer = append(buffer, req.GetChunkData()...)
+		if len(buffer) >= clipFlushSize {
+			if err := flush(); err != nil {
+				return videoStatus(err)
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return videoStatus(err)
+	}
+	// A resumed upload that already has every byte only needs storing again
+	if upload.Status == models.VideoUploadOpen && upload.ReceivedBytes == upload.TotalBytes {
+		written, err := videoService.WriteClipChunk(context.WithoutCancel(ctx), upload.UploadID, upload.ReceivedBytes, nil)
+		if written != nil {
+			upload = written
+		}
+		if err != nil {
+			return videoStatus(err)
+		}
+	}
+
+	complete := upload.Status == models.VideoUploadComplete
+	message := "chunks received"
+	if complete {
+		message = "clip uploaded"
+	}
+	return stream.SendAndClose(clipUploadStatus(upload, complete, message))
+}
+
+// GetClipUploadStatus returns how much of an upload has been received
+func (s *VideoServer) GetClipUploadStatus(ctx context.Context, req *pb.GetClipUploadStatusRequest) (*pb.ClipUploadStatus, error) {
+	if req.GetUploadId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "upload_id is required")
+	}
+	upload, err := s.services.VideoService.GetClipUpload(ctx, req.GetUploadId())
+	if err != nil {
+		return nil, videoStatus(err)
+	}
+	return clipUploadStatus(upload, upload.Status == models.VideoUploadComplete, upload.Error), nil
+}
+
+func clipUploadStatus(upload *models.VideoUpload, success bool, message string) *pb.ClipUploadStatus {
+	return &pb.ClipUploadStatus{
+		UploadId:      upload.UploadID,
+		ClipId:        uint32(upload.VideoClipID),
+		ReceivedBytes: upload.ReceivedBytes,
+		TotalBytes:    upload.TotalBytes,
+		Status:        string(upload.Status),
+		Success:       success,
+		Message:       message,
+	}
+}
+
+// videoStatus maps video service errors to gRPC status codes
+func videoStatus(err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidVideoRequest):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, services.ErrVideoConflict):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Error(codes.NotFound, "not found")
+	default:
+		log.Printf("❌ Video upload failed: %v", err)
+		return status.Error(codes.Internal, "failed to upload clip")
+	}
+}
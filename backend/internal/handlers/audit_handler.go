@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/gin-gonic/gin"
+)
+
+// organizationQuery parses the optional organization_id query parameter (0 is the platform chain)
+func organizationQuery(c *gin.Context) (*uint, bool) {
+	value := c.Query("organization_id")
+	if value == "" {
+		return nil, true
+	}
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid organization_id",
+			Code:    http.StatusBadRequest,
+		})
+		return nil, false
+	}
+	orgID := uint(id)
+	return &orgID, true
+}
+
+// VerifyAuditChain verifies the tamper-evident audit log
+// @Summary Verify Audit Log Integrity
+// @Description Walk the hash chain of one or all organizations and report gaps, edited records, truncation and checkpoint mismatches (admin only)
+// @Tags admin
+// @Produce json
+// @Param organization_id query int false "Verify a single organization's chain (0 for platform records)"
+// @Param archives query bool false "Also download and verify archived ranges"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} dto.APIError
+// @Failure 403 {object} dto.APIError
+// @Security BearerAuth
+// @Router /admin/audit-logs/verify [get]
+func (h *AnalyticsHandler) VerifyAuditChain(c *gin.Context) {
+	orgID, ok := organizationQuery(c)
+	if !ok {
+		return
+	}
+	includeArchives, _ := strconv.ParseBool(c.Query("archives"))
+
+	var orgIDs []uint
+	if orgID != nil {
+		orgIDs = append(orgIDs, *orgID)
+	} else {
+		heads, err := h.services.AuditService.ListAuditChains()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, dto.APIError{
+				Error:   "verification_failed",
+				Message: "Failed to list audit chains",
+				Code:    http.StatusInternalServerError,
+			})
+			return
+		}
+		for _, head := range heads {
+			orgIDs = append(orgIDs, head.OrganizationID)
+		}
+	}
+
+	valid := true
+	reports := make([]interface{}, 0, len(orgIDs))
+	for _, id := range orgIDs {
+		report, err := h.services.AuditService.VerifyAuditChain(id, includeArchives)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, dto.APIError{
+				Error:   "verification_failed",
+				Message: "Failed to verify audit chain",
+				Code:    http.StatusInternalServerError,
+			})
+			return
+		}
+		valid = valid && report.Valid
+		reports = append(reports, report)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":      valid,
+		"chains":     reports,
+		"public_key": h.services.AuditService.CheckpointPublicKey(),
+	})
+}
+
+// GetAuditCheckpoints lists signed audit checkpoints
+// @Summary Get Audit Checkpoints
+// @Description List signed checkpoints of an organization's audit chain, newest first (admin only)
+// @Tags admin
+// @Produce json
+// @Param organization_id query int false "Organization chain (default 0, platform records)"
+// @Param limit query int false "Maximum checkpoints" default(50)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} dto.APIError
+// @Security BearerAuth
+// @Router /admin/audit-logs/checkpoints [get]
+func (h *AnalyticsHandler) GetAuditCheckpoints(c *gin.Context) {
+	orgID, ok := organizationQuery(c)
+	if !ok {
+		return
+	}
+	var id uint
+	if orgID != nil {
+		id = *orgID
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	checkpoints, err := h.services.AuditService.GetCheckpoints(id, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch audit checkpoints",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"checkpoints": checkpoints,
+		"public_key":  h.services.AuditService.CheckpointPublicKey(),
+	})
+}
+
+// CreateAuditCheckpoints signs the current head of audit chains on demand
+// @Summary Create Audit Checkpoints
+// @Description Sign the current head of one or all audit chains without waiting for the periodic job (admin only)
+// @Tags admin
+// @Produce json
+// @Param organization_id query int false "Checkpoint a single organization's chain"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} dto.APIError
+// @Failure 404 {object} dto.APIError
+// @Security BearerAuth
+// @Router /admin/audit-logs/checkpoints [post]
+func (h *AnalyticsHandler) CreateAuditCheckpoints(c *gin.Context) {
+	orgID, ok := organizationQuery(c)
+	if !ok {
+		return
+	}
+
+	if orgID != nil {
+		checkpoint, err := h.services.AuditService.CreateCheckpoint(*orgID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, dto.APIError{
+				Error:   "not_found",
+				Message: "Audit chain not found",
+				Code:    http.StatusNotFound,
+			})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"checkpoints": []interface{}{checkpoint}})
+		return
+	}
+
+	checkpoints, err := h.services.AuditService.CreateCheckpoints()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "checkpoint_failed",
+			Message: "Failed to create audit checkpoints",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"checkpoints": checkpoints})
+}
+
+// GetAuditArchives lists audit ranges moved to cold storage
+// @Summary Get Audit Archives
+// @Description List archived audit chain ranges with their storage location and checksums (admin only)
+// @Tags admin
+// @Produce json
+// @Param organization_id query int false "Filter by organization chain"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} dto.APIError
+// @Security BearerAuth
+// @Router /admin/audit-logs/archives [get]
+func (h *AnalyticsHandler) GetAuditArchives(c *gin.Context) {
+	orgID, ok := organizationQuery(c)
+	if !ok {
+		return
+	}
+
+	archives, err := h.services.AuditService.GetAuditArchives(orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch audit archives",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"archives": archives})
+}
@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/fleetflow/backend/internal/middleware"
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetCoachingQueue handles listing coaching sessions
+// @Summary Get the coaching queue
+// @Description Lists coaching sessions, pending ones first by due date. Drivers only see their own sessions.
+// @Tags safety
+// @Produce json
+// @Param driver_id query int false "Driver ID"
+// @Param coach_id query int false "Coach user ID"
+// @Param status query string false "Status (PENDING, COACHED, DISMISSED, FALSE_POSITIVE)"
+// @Param unassigned query bool false "Only sessions without a coach"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /safety/coaching [get]
+func (h *SafetyHandler) GetCoachingQueue(c *gin.Context) {
+	var pagination dto.PaginationParams
+	if err := c.ShouldBindQuery(&pagination); err != nil || pagination.Page < 1 || pagination.Limit < 1 || pagination.Limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+
+	filter, ok := coachingFilterQuery(c)
+	if !ok {
+		return
+	}
+	filter.Status = models.CoachingStatus(strings.ToUpper(c.Query("status")))
+	if raw := c.Query("unassigned"); raw != "" {
+		unassigned, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unassigned"})
+			return
+		}
+		filter.Unassigned = unassigned
+	}
+
+	sessions, total, err := h.safetyService.GetCoachingQueue(c.Request.Context(), filter, pagination.Page, pagination.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions":    sessions,
+		"total":       total,
+		"page":        pagination.Page,
+		"limit":       pagination.Limit,
+		"total_pages": (total + int64(pagination.Limit) - 1) / int64(pagination.Limit),
+	})
+}
+
+// CreateCoachingSession handles queueing an event or clip for coaching
+// @Summary Queue for coaching
+// @Description Queues a safety event or a video clip for coaching, optionally assigned to a coach, and marks it viewed
+// @Tags safety
+// @Accept json
+// @Produce json
+// @Param session body dto.CoachingSessionRequest true "Event or clip to coach"
+// @Success 201 {object} models.CoachingSession
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /safety/coaching [post]
+func (h *SafetyHandler) CreateCoachingSession(c *gin.Context) {
+	var req dto.CoachingSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.safetyService.CreateCoachingSession(c.Request.Context(), services.CoachingRequest{
+		SafetyEventID: req.SafetyEventID,
+		VideoClipID:   req.VideoClipID,
+		DriverID:      req.DriverID,
+		CoachID:       req.CoachID,
+		DueAt:         req.DueAt,
+		Note:          req.Note,
+		AssignedByID:  currentUserRef(c),
+	})
+	if err != nil {
+		coachingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, session)
+}
+
+// GetCoachingSession handles fetching a coaching session
+// @Summary Get a coaching session
+// @Description Returns a coaching session with its event or clip, coach and notes. Drivers can only fetch their own.
+// @Tags safety
+// @Produce json
+// @Param id path int true "Coaching Session ID"
+// @Success 200 {object} models.CoachingSession
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /safety/coaching/{id} [get]
+func (h *SafetyHandler) GetCoachingSession(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	session, err := h.safetyService.GetCoachingSession(c.Request.Context(), id)
+	if err != nil {
+		coachingError(c, err)
+		return
+	}
+	if middleware.IsDriver(c) {
+		if driverID, ok := middleware.GetCurrentDriverID(c); !ok || driverID != session.DriverID {
+			coachingError(c, services.ErrCoachingForbidden)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// AssignCoach handles assigning a coaching session to a coach
+// @Summary Assign a coach
+// @Description Hands a pending coaching session to a coach, optionally with a due date
+// @Tags safety
+// @Accept json
+// @Produce json
+// @Param id path int true "Coaching Session ID"
+// @Param assignment body dto.AssignCoachRequest true "Coach"
+// @Success 200 {object} models.CoachingSession
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /safety/coaching/{id}/assign [put]
+func (h *SafetyHandler) AssignCoach(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.AssignCoachRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.safetyService.AssignCoach(c.Request.Context(), id, req.CoachID, req.DueAt, currentUserRef(c))
+	if err != nil {
+		coachingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// AddCoachingNote handles adding a note to a coaching session
+// @Summary Add a coaching note
+// @Description Attaches a note to a coaching session
+// @Tags safety
+// @Accept json
+// @Produce json
+// @Param id path int true "Coaching Session ID"
+// @Param note body dto.CoachingNoteRequest true "Note"
+// @Success 201 {object} models.CoachingNote
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /safety/coaching/{id}/notes [post]
+func (h *SafetyHandler) AddCoachingNote(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.CoachingNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	note, err := h.safetyService.AddCoachingNote(c.Request.Context(), id, currentUserRef(c), req.Body)
+	if err != nil {
+		coachingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, note)
+}
+
+// ResolveCoachingSession handles closing a coaching session
+// @Summary Resolve a coaching session
+// @Description Marks a pending session coached, dismissed or a false positive. False positives no longer count against the driver's score.
+// @Tags safety
+// @Accept json
+// @Produce json
+// @Param id path int true "Coaching Session ID"
+// @Param resolution body dto.ResolveCoachingRequest true "Outcome"
+// @Success 200 {object} models.CoachingSession
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /safety/coaching/{id}/resolve [post]
+func (h *SafetyHandler) ResolveCoachingSession(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.ResolveCoachingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.safetyService.ResolveCoachingSession(c.Request.Context(), id,
+		models.CoachingStatus(req.Status), req.Note, currentUserRef(c), time.Now())
+	if err != nil {
+		coachingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// AcknowledgeCoaching handles a driver acknowledging coaching
+// @Summary Acknowledge coaching
+// @Description Records the driver confirming, from the mobile app, that they were coached (drivers only)
+// @Tags safety
+// @Accept json
+// @Produce json
+// @Param id path int true "Coaching Session ID"
+// @Param acknowledgement body dto.AcknowledgeCoachingRequest false "Driver comment"
+// @Success 200 {object} models.CoachingSession
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /safety/coaching/{id}/acknowledge [post]
+func (h *SafetyHandler) AcknowledgeCoaching(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	driverID, ok := middleware.GetCurrentDriverID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "No driver profile linked to this account"})
+		return
+	}
+	var req dto.AcknowledgeCoachingRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	session, err := h.safetyService.AcknowledgeCoaching(c.Request.Context(), id, driverID, req.Comment, time.Now())
+	if err != nil {
+		coachingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// GetCoachingEffectiveness handles summarising coaching outcomes
+// @Summary Get coaching effectiveness
+// @Description Counts sessions by outcome and compares coached drivers' scores over the 30 days before and after coaching
+// @Tags safety
+// @Produce json
+// @Param driver_id query int false "Driver ID"
+// @Param coach_id query int false "Coach user ID"
+// @Success 200 {object} services.CoachingEffectiveness
+// @Failure 400 {object} map[string]string
+// @Router /safety/coaching/effectiveness [get]
+func (h *SafetyHandler) GetCoachingEffectiveness(c *gin.Context) {
+	filter, ok := coachingFilterQuery(c)
+	if !ok {
+		return
+	}
+
+	summary, err := h.safetyService.GetCoachingEffectiveness(c.Request.Context(), filter)
+	if err != nil {
+		coachingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// coachingFilterQuery reads the driver and coach filters, confining drivers to their own sessions
+func coachingFilterQuery(c *gin.Context) (services.CoachingFilter, bool) {
+	var filter services.CoachingFilter
+	for param, target := range map[string]**uint{"driver_id": &filter.DriverID, "coach_id": &filter.CoachID} {
+		if raw := c.Query(param); raw != "" {
+			id, err := strconv.ParseUint(raw, 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param})
+				return filter, false
+			}
+			value := uint(id)
+			*target = &value
+		}
+	}
+	if middleware.IsDriver(c) {
+		driverID, ok := middleware.GetCurrentDriverID(c)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "No driver profile linked to this account"})
+			return filter, false
+		}
+		filter.DriverID = &driverID
+	}
+	return filter, true
+}
+
+// coachingError maps coaching errors to responses
+func coachingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidCoaching):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCoachingForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCoachingConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
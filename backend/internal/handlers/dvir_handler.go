@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/fleetflow/backend/internal/middleware"
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// dvirError maps inspection, defect and checklist errors onto responses
+func dvirError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrDefectsNotAcknowledged), errors.Is(err, services.ErrWorkOrderClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidDVIR):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GetDVIR handles fetching an inspection report
+// @Summary Get a DVIR
+// @Description Returns an inspection report with its defects and their photos
+// @Tags maintenance
+// @Produce json
+// @Param id path int true "DVIR ID"
+// @Success 200 {object} models.DVIR
+// @Failure 404 {object} map[string]string
+// @Router /maintenance/dvir/{id} [get]
+func (h *MaintenanceHandler) GetDVIR(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	dvir, err := h.maintenanceService.GetDVIR(c.Request.Context(), id)
+	if err != nil {
+		dvirError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dvir)
+}
+
+// GetVehicleDefects handles listing a vehicle's inspection defects
+// @Summary List a vehicle's defects
+// @Description Returns defects still open or awaiting the next driver's acknowledgement, or filtered by status
+// @Tags maintenance
+// @Produce json
+// @Param id path int true "Vehicle ID"
+// @Param status query string false "OPEN, CERTIFIED, ACKNOWLEDGED or ALL"
+// @Success 200 {array} models.DVIRDefect
+// @Router /maintenance/vehicles/{id}/defects [get]
+func (h *MaintenanceHandler) GetVehicleDefects(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	defects, err := h.maintenanceService.GetVehicleDefects(c.Request.Context(), id, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get defects: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, defects)
+}
+
+// GetVehicleChecklist handles fetching the checklist a vehicle is inspected against
+// @Summary Get a vehicle's inspection checklist
+// @Description Returns the active checklist for the vehicle's type
+// @Tags maintenance
+// @Produce json
+// @Param id path int true "Vehicle ID"
+// @Success 200 {object} models.InspectionChecklist
+// @Failure 404 {object} map[string]string
+// @Router /maintenance/vehicles/{id}/checklist [get]
+func (h *MaintenanceHandler) GetVehicleChecklist(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	checklist, err := h.maintenanceService.GetVehicleChecklist(c.Request.Context(), id)
+	if err != nil {
+		dvirError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, checklist)
+}
+
+// GetInspectionChecklists handles listing inspection checklists
+// @Summary List inspection checklists
+// @Description Returns the inspection checklist of each vehicle type
+// @Tags maintenance
+// @Produce json
+// @Param vehicle_type query string false "Vehicle type"
+// @Success 200 {array} models.InspectionChecklist
+// @Router /maintenance/checklists [get]
+func (h *MaintenanceHandler) GetInspectionChecklists(c *gin.Context) {
+	checklists, err := h.maintenanceService.GetInspectionChecklists(c.Request.Context(), c.Query("vehicle_type"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get checklists: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, checklists)
+}
+
+// SaveInspectionChecklist handles setting a vehicle type's checklist
+// @Summary Set an inspection checklist
+// @Description Creates or replaces the checklist for a vehicle type; items keep their IDs by code (admin only)
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Param checklist body dto.InspectionChecklistRequest true "Checklist"
+// @Success 200 {object} models.InspectionChecklist
+// @Failure 400 {object} map[string]string
+// @Router /maintenance/checklists [put]
+func (h *MaintenanceHandler) SaveInspectionChecklist(c *gin.Context) {
+	var req dto.InspectionChecklistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	checklist := models.InspectionChecklist{
+		VehicleType: models.VehicleType(req.VehicleType),
+		Name:        req.Name,
+		IsActive:    req.IsActive == nil || *req.IsActive,
+		CreatedBy:   currentUserRef(c),
+	}
+	for _, item := range req.Items {
+		checklist.Items = append(checklist.Items, models.InspectionChecklistItem{
+			Code:     item.Code,
+			Category: item.Category,
+			Label:    item.Label,
+			Severity: models.DefectSeverity(item.Severity),
+		})
+	}
+
+	saved, err := h.maintenanceService.SaveInspectionChecklist(c.Request.Context(), &checklist)
+	if err != nil {
+		dvirError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
+
+// CertifyDefect handles a mechanic's sign-off on a defect
+// @Summary Certify a defect
+// @Description A mechanic certifies that a defect was corrected or needs no repair; the next driver acknowledges it on their pre-trip
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Param id path int true "Defect ID"
+// @Param certification body dto.CertifyDefectRequest true "Certification"
+// @Success 200 {object} models.DVIRDefect
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /maintenance/defects/{id}/certify [post]
+func (h *MaintenanceHandler) CertifyDefect(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.CertifyDefectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	mechanicID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	defect, err := h.maintenanceService.CertifyDefect(c.Request.Context(), id, mechanicID,
+		models.DVIRDefectResolution(req.Resolution), req.Notes, req.MechanicSign)
+	if err != nil {
+		dvirError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, defect)
+}
+
+// LinkDefectWorkOrder handles moving a defect onto another work order
+// @Summary Link a defect to a work order
+// @Description Moves a defect onto another open work order of the same vehicle
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Param id path int true "Defect ID"
+// @Param link body dto.LinkDefectWorkOrderRequest true "Work order"
+// @Success 200 {object} models.DVIRDefect
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /maintenance/defects/{id}/work-order [put]
+func (h *MaintenanceHandler) LinkDefectWorkOrder(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.LinkDefectWorkOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	defect, err := h.maintenanceService.LinkDefectWorkOrder(c.Request.Context(), id, req.WorkOrderID)
+	if err != nil {
+		dvirError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, defect)
+}
+
+// AttachDefectPhotos handles adding photos to a defect
+// @Summary Attach photos to a defect
+// @Description Links uploaded images to a defect
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Param id path int true "Defect ID"
+// @Param photos body dto.AttachDefectPhotosRequest true "Uploads"
+// @Success 200 {object} models.DVIRDefect
+// @Failure 400 {object} map[string]string
+// @Router /maintenance/defects/{id}/photos [post]
+func (h *MaintenanceHandler) AttachDefectPhotos(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.AttachDefectPhotosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	defect, err := h.maintenanceService.AttachDefectPhotos(c.Request.Context(), id, req.UploadIDs)
+	if err != nil {
+		dvirError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, defect)
+}
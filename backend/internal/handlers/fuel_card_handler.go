@@ -0,0 +1,529 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/fleetflow/backend/internal/middleware"
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxFuelCardStatementSize bounds uploaded statement files
+const maxFuelCardStatementSize = 10 << 20
+
+// fuelCardError maps fuel card service errors to API responses
+func fuelCardError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidFuelCardProvider),
+		errors.Is(err, services.ErrInvalidFuelCard),
+		errors.Is(err, services.ErrInvalidFuelCardStatement):
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+	case errors.Is(err, services.ErrFuelCardExists):
+		c.JSON(http.StatusConflict, dto.APIError{
+			Error:   "card_exists",
+			Message: err.Error(),
+			Code:    http.StatusConflict,
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, dto.APIError{
+			Error:   "not_found",
+			Message: "Fuel card, provider, vehicle or driver not found",
+			Code:    http.StatusNotFound,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "fuel_card_failed",
+			Message: "Failed to process fuel card request",
+			Code:    http.StatusInternalServerError,
+		})
+	}
+}
+
+// parseIDParam reads a numeric path parameter, answering 400 when it is not one
+func parseIDParam(c *gin.Context, name, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "invalid_id",
+			Message: message,
+			Code:    http.StatusBadRequest,
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// GetFuelCardProviders lists fuel-card providers and their statement formats
+// @Summary Get Fuel Card Providers
+// @Description List fuel-card providers with their CSV column mappings (admin only)
+// @Tags fuel
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /fuel/card-providers [get]
+func (h *FuelHandler) GetFuelCardProviders(c *gin.Context) {
+	providers, err := h.services.FuelService.GetFuelCardProviders()
+	if err != nil {
+		fuelCardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"providers": providers})
+}
+
+// CreateFuelCardProvider registers a fuel-card provider
+// @Summary Create Fuel Card Provider
+// @Description Register a provider and map its statement CSV headers to card_number/license_plate, transacted_at (or date and time), litres, amount and optional fields (admin only)
+// @Tags fuel
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateFuelCardProviderRequest true "Provider"
+// @Success 201 {object} models.FuelCardProvider
+// @Failure 400 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/card-providers [post]
+func (h *FuelHandler) CreateFuelCardProvider(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUserID(c)
+
+	var req dto.CreateFuelCardProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+			Details: map[string]string{"validation": err.Error()},
+		})
+		return
+	}
+
+	provider := &models.FuelCardProvider{
+		Name:       strings.TrimSpace(req.Name),
+		Columns:    fuelCardColumns(req.Columns),
+		Delimiter:  req.Delimiter,
+		DateFormat: req.DateFormat,
+		Timezone:   req.Timezone,
+		IsActive:   req.IsActive == nil || *req.IsActive,
+	}
+	if err := h.services.FuelService.CreateFuelCardProvider(provider, userID); err != nil {
+		fuelCardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, provider)
+}
+
+// UpdateFuelCardProvider changes a provider's statement format
+// @Summary Update Fuel Card Provider
+// @Description Change a provider's name, column mapping, delimiter, date format, timezone or active state (admin only)
+// @Tags fuel
+// @Accept json
+// @Produce json
+// @Param id path int true "Provider ID"
+// @Param request body dto.UpdateFuelCardProviderRequest true "Changes"
+// @Success 200 {object} models.FuelCardProvider
+// @Failure 400 {object} dto.APIError
+// @Failure 404 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/card-providers/{id} [put]
+func (h *FuelHandler) UpdateFuelCardProvider(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUserID(c)
+
+	id, ok := parseIDParam(c, "id", "Invalid provider ID")
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateFuelCardProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+			Details: map[string]string{"validation": err.Error()},
+		})
+		return
+	}
+
+	provider, err := h.services.FuelService.GetFuelCardProvider(id)
+	if err != nil {
+		fuelCardError(c, err)
+		return
+	}
+	previous := *provider
+	if req.Name != nil {
+		provider.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Columns != nil {
+		provider.Columns = fuelCardColumns(req.Columns)
+	}
+	if req.Delimiter != nil {
+		provider.Delimiter = *req.Delimiter
+	}
+	if req.DateFormat != nil {
+		provider.DateFormat = *req.DateFormat
+	}
+	if req.Timezone != nil {
+		provider.Timezone = *req.Timezone
+	}
+	if req.IsActive != nil {
+		provider.IsActive = *req.IsActive
+	}
+
+	if err := h.services.FuelService.UpdateFuelCardProvider(provider, previous, userID); err != nil {
+		fuelCardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, provider)
+}
+
+// fuelCardColumns converts a request mapping to canonical fields
+func fuelCardColumns(columns map[string]string) map[models.FuelCardField]string {
+	mapped := make(map[models.FuelCardField]string, len(columns))
+	for field, header := range columns {
+		mapped[models.FuelCardField(strings.ToLower(strings.TrimSpace(field)))] = header
+	}
+	return mapped
+}
+
+// GetFuelCards lists fuel cards and the vehicles they are assigned to
+// @Summary Get Fuel Cards
+// @Description List fuel cards with their provider and vehicle (admin only)
+// @Tags fuel
+// @Produce json
+// @Param provider_id query int false "Filter by provider ID"
+// @Param vehicle_id query int false "Filter by vehicle ID"
+// @Success 200 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /fuel/cards [get]
+func (h *FuelHandler) GetFuelCards(c *gin.Context) {
+	filters := make(map[string]interface{})
+	for _, key := range []string{"provider_id", "vehicle_id"} {
+		if value := c.Query(key); value != "" {
+			if id, err := strconv.ParseUint(value, 10, 32); err == nil {
+				filters[key] = uint(id)
+			}
+		}
+	}
+
+	cards, err := h.services.FuelService.GetFuelCards(filters)
+	if err != nil {
+		fuelCardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cards": cards})
+}
+
+// CreateFuelCard assigns a fuel card to a vehicle
+// @Summary Create Fuel Card
+// @Description Register a provider's card and the vehicle (and usual driver) it belongs to (admin only)
+// @Tags fuel
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateFuelCardRequest true "Card"
+// @Success 201 {object} models.FuelCard
+// @Failure 400 {object} dto.APIError
+// @Failure 404 {object} dto.APIError
+// @Failure 409 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/cards [post]
+func (h *FuelHandler) CreateFuelCard(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUserID(c)
+
+	var req dto.CreateFuelCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+			Details: map[string]string{"validation": err.Error()},
+		})
+		return
+	}
+
+	card := &models.FuelCard{
+		ProviderID: req.ProviderID,
+		CardNumber: req.CardNumber,
+		VehicleID:  req.VehicleID,
+		DriverID:   req.DriverID,
+		IsActive:   req.IsActive == nil || *req.IsActive,
+	}
+	if err := h.services.FuelService.CreateFuelCard(card, userID); err != nil {
+		fuelCardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, card)
+}
+
+// UpdateFuelCard reassigns or deactivates a fuel card
+// @Summary Update Fuel Card
+// @Description Move a card to another vehicle or driver, or deactivate it (admin only)
+// @Tags fuel
+// @Accept json
+// @Produce json
+// @Param id path int true "Card ID"
+// @Param request body dto.UpdateFuelCardRequest true "Changes"
+// @Success 200 {object} models.FuelCard
+// @Failure 400 {object} dto.APIError
+// @Failure 404 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/cards/{id} [put]
+func (h *FuelHandler) UpdateFuelCard(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUserID(c)
+
+	id, ok := parseIDParam(c, "id", "Invalid card ID")
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateFuelCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+			Details: map[string]string{"validation": err.Error()},
+		})
+		return
+	}
+
+	card, err := h.services.FuelService.GetFuelCard(id)
+	if err != nil {
+		fuelCardError(c, err)
+		return
+	}
+	previous := *card
+	if req.VehicleID != nil {
+		card.VehicleID = req.VehicleID
+	}
+	if req.DriverID != nil {
+		card.DriverID = req.DriverID
+	}
+	if req.IsActive != nil {
+		card.IsActive = *req.IsActive
+	}
+
+	if err := h.services.FuelService.UpdateFuelCard(card, previous, userID); err != nil {
+		fuelCardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, card)
+}
+
+// ImportFuelCardStatement uploads and reconciles a fuel-card statement
+// @Summary Import Fuel Card Statement
+// @Description Import a provider's CSV statement. Each transaction is matched to a vehicle by card or plate, checked against GPS presence at a fuel station and the tank capacity, then verifies the matching fuel event or creates one. Flagged transactions raise CARD_MISMATCH alerts (admin only).
+// @Tags fuel
+// @Accept multipart/form-data
+// @Produce json
+// @Param provider_id formData int true "Provider ID"
+// @Param file formData file true "Statement CSV"
+// @Success 201 {object} services.FuelCardImportResult
+// @Failure 400 {object} dto.APIError
+// @Failure 404 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/card-imports [post]
+func (h *FuelHandler) ImportFuelCardStatement(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUserID(c)
+
+	providerID, err := strconv.ParseUint(c.PostForm("provider_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "provider_id is required",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Statement file is required",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+	if header.Size > maxFuelCardStatementSize {
+		c.JSON(http.StatusRequestEntityTooLarge, dto.APIError{
+			Error:   "file_too_large",
+			Message: "Statement files are limited to 10 MB",
+			Code:    http.StatusRequestEntityTooLarge,
+		})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		fuelCardError(c, err)
+		return
+	}
+	defer file.Close()
+
+	result, err := h.services.FuelService.ImportFuelCardStatement(uint(providerID), header.Filename, file, userID)
+	if err != nil {
+		fuelCardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// GetFuelCardImports lists imported statements
+// @Summary Get Fuel Card Imports
+// @Description List imported fuel-card statements with their reconciliation counts (admin only)
+// @Tags fuel
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/card-imports [get]
+func (h *FuelHandler) GetFuelCardImports(c *gin.Context) {
+	var pagination dto.PaginationParams
+	if err := c.ShouldBindQuery(&pagination); err != nil || pagination.Page < 1 || pagination.Limit < 1 || pagination.Limit > 100 {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid pagination parameters",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	imports, total, err := h.services.FuelService.GetFuelCardImports(pagination.Page, pagination.Limit)
+	if err != nil {
+		fuelCardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"imports":     imports,
+		"total":       total,
+		"page":        pagination.Page,
+		"limit":       pagination.Limit,
+		"total_pages": (total + int64(pagination.Limit) - 1) / int64(pagination.Limit),
+	})
+}
+
+// GetFuelCardImport returns one imported statement
+// @Summary Get Fuel Card Import
+// @Description Get an import's counts and row errors (admin only)
+// @Tags fuel
+// @Produce json
+// @Param id path int true "Import ID"
+// @Success 200 {object} models.FuelCardImport
+// @Failure 404 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/card-imports/{id} [get]
+func (h *FuelHandler) GetFuelCardImport(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Invalid import ID")
+	if !ok {
+		return
+	}
+
+	batch, err := h.services.FuelService.GetFuelCardImport(id)
+	if err != nil {
+		fuelCardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+// GetFuelCardTransactions lists imported card transactions
+// @Summary Get Fuel Card Transactions
+// @Description List card transactions with their match status and flags
+// @Tags fuel
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Param import_id query int false "Filter by import ID"
+// @Param vehicle_id query int false "Filter by vehicle ID"
+// @Param status query string false "Filter by status (RECONCILED, CREATED, UNMATCHED)"
+// @Param flagged query bool false "Only transactions that need review"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/card-transactions [get]
+func (h *FuelHandler) GetFuelCardTransactions(c *gin.Context) {
+	var pagination dto.PaginationParams
+	if err := c.ShouldBindQuery(&pagination); err != nil || pagination.Page < 1 || pagination.Limit < 1 || pagination.Limit > 100 {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid pagination parameters",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	filters := make(map[string]interface{})
+	for _, key := range []string{"import_id", "vehicle_id"} {
+		if value := c.Query(key); value != "" {
+			if id, err := strconv.ParseUint(value, 10, 32); err == nil {
+				filters[key] = uint(id)
+			}
+		}
+	}
+	if status := c.Query("status"); status != "" {
+		filters["status"] = strings.ToUpper(status)
+	}
+	if flagged, err := strconv.ParseBool(c.Query("flagged")); err == nil {
+		filters["flagged"] = flagged
+	}
+
+	transactions, total, err := h.services.FuelService.GetFuelCardTransactions(pagination.Page, pagination.Limit, filters)
+	if err != nil {
+		fuelCardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transactions": transactions,
+		"total":        total,
+		"page":         pagination.Page,
+		"limit":        pagination.Limit,
+		"total_pages":  (total + int64(pagination.Limit) - 1) / int64(pagination.Limit),
+	})
+}
+
+// ReconcileFuelCardTransaction re-runs matching for one transaction
+// @Summary Reconcile Fuel Card Transaction
+// @Description Re-run vehicle, GPS and fuel event matching, e.g. after assigning an unknown card (admin only)
+// @Tags fuel
+// @Produce json
+// @Param id path int true "Transaction ID"
+// @Success 200 {object} models.FuelCardTransaction
+// @Failure 404 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/card-transactions/{id}/reconcile [post]
+func (h *FuelHandler) ReconcileFuelCardTransaction(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUserID(c)
+
+	id, ok := parseIDParam(c, "id", "Invalid transaction ID")
+	if !ok {
+		return
+	}
+
+	txn, err := h.services.FuelService.ReconcileFuelCardTransaction(id, userID)
+	if err != nil {
+		fuelCardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, txn)
+}
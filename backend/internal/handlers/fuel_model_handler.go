@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetFuelAnomalyModels lists trained fuel anomaly model versions
+// @Summary Get Fuel Anomaly Models
+// @Description List isolation forest versions per vehicle class with their training size, threshold and hold-out precision/recall (admin only)
+// @Tags fuel
+// @Produce json
+// @Param vehicle_type query string false "Filter by vehicle class"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/models [get]
+func (h *FuelHandler) GetFuelAnomalyModels(c *gin.Context) {
+	vehicleType := models.VehicleType(strings.ToUpper(c.Query("vehicle_type")))
+	result, err := h.services.FuelService.GetFuelAnomalyModels(vehicleType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch fuel anomaly models",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"models": result})
+}
+
+// TrainFuelAnomalyModels trains new anomaly model versions on verified fuel events
+// @Summary Train Fuel Anomaly Models
+// @Description Train and activate a new isolation forest version for one vehicle class, or for every class with enough verified fuel events (admin only)
+// @Tags fuel
+// @Accept json
+// @Produce json
+// @Param request body dto.FuelModelTrainRequest false "Vehicle class to train"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} dto.APIError
+// @Failure 422 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/models/train [post]
+func (h *FuelHandler) TrainFuelAnomalyModels(c *gin.Context) {
+	var req dto.FuelModelTrainRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, dto.APIError{
+				Error:   "validation_failed",
+				Message: "Invalid request data",
+				Code:    http.StatusBadRequest,
+				Details: map[string]string{"validation": err.Error()},
+			})
+			return
+		}
+	}
+
+	if req.VehicleType == "" {
+		trained, err := h.services.FuelService.TrainFuelAnomalyModels()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, dto.APIError{
+				Error:   "training_failed",
+				Message: err.Error(),
+				Code:    http.StatusInternalServerError,
+			})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"models": trained})
+		return
+	}
+
+	model, err := h.services.FuelService.TrainFuelAnomalyModel(models.VehicleType(strings.ToUpper(req.VehicleType)))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInsufficientTrainingData) {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, dto.APIError{
+			Error:   "training_failed",
+			Message: err.Error(),
+			Code:    status,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"models": []models.FuelAnomalyModel{*model}})
+}
+
+// EvaluateFuelAnomalyModel scores a model version against admin decisions
+// @Summary Evaluate Fuel Anomaly Model
+// @Description Report precision, recall and AUC of a model version against admin-rejected events and verified events it was not trained on (admin only)
+// @Tags fuel
+// @Produce json
+// @Param vehicle_type query string true "Vehicle class"
+// @Param version query int false "Model version (default: active)"
+// @Param threshold query number false "Override the model's anomaly threshold"
+// @Success 200 {object} services.FuelModelEvaluation
+// @Failure 400 {object} dto.APIError
+// @Failure 404 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/models/evaluation [get]
+func (h *FuelHandler) EvaluateFuelAnomalyModel(c *gin.Context) {
+	vehicleType := strings.ToUpper(c.Query("vehicle_type"))
+	version, versionErr := strconv.Atoi(c.DefaultQuery("version", "0"))
+	threshold, thresholdErr := strconv.ParseFloat(c.DefaultQuery("threshold", "0"), 64)
+	if vehicleType == "" || versionErr != nil || thresholdErr != nil || threshold < 0 || threshold > 1 {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "vehicle_type is required; version must be an integer and threshold between 0 and 1",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	evaluation, err := h.services.FuelService.EvaluateFuelAnomalyModel(models.VehicleType(vehicleType), version, threshold)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, dto.APIError{
+				Error:   "not_found",
+				Message: "Fuel anomaly model not found",
+				Code:    http.StatusNotFound,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "evaluation_failed",
+			Message: err.Error(),
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, evaluation)
+}
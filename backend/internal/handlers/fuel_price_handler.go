@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/fleetflow/backend/internal/middleware"
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// parseDateQuery reads an optional YYYY-MM-DD query parameter
+func parseDateQuery(c *gin.Context, name string) (*time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid " + name + ", expected YYYY-MM-DD",
+			Code:    http.StatusBadRequest,
+		})
+		return nil, false
+	}
+	return &parsed, true
+}
+
+// GetFuelPrices lists daily price history
+// @Summary Get Fuel Prices
+// @Description List daily fuel prices per station, region and nationally, from verified fuel events (source EVENTS) and imported feeds
+// @Tags fuel
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Param region query string false "State; empty string for national rows"
+// @Param fuel_type query string false "Fuel type" default(DIESEL)
+// @Param fuel_station_id query int false "Filter by station ID"
+// @Param source query string false "EVENTS or a feed name"
+// @Param from query string false "From date (YYYY-MM-DD)"
+// @Param to query string false "To date (YYYY-MM-DD)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/prices [get]
+func (h *FuelHandler) GetFuelPrices(c *gin.Context) {
+	var pagination dto.PaginationParams
+	if err := c.ShouldBindQuery(&pagination); err != nil || pagination.Page < 1 || pagination.Limit < 1 || pagination.Limit > 100 {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid pagination parameters",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	filters := make(map[string]interface{})
+	if region, ok := c.GetQuery("region"); ok {
+		filters["region"] = region
+	}
+	for _, key := range []string{"fuel_type", "source"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+	if value := c.Query("fuel_station_id"); value != "" {
+		if id, err := strconv.ParseUint(value, 10, 32); err == nil {
+			filters["fuel_station_id"] = uint(id)
+		}
+	}
+	for _, key := range []string{"from", "to"} {
+		date, ok := parseDateQuery(c, key)
+		if !ok {
+			return
+		}
+		if date != nil {
+			filters[key] = *date
+		}
+	}
+
+	prices, total, err := h.services.FuelService.GetFuelPrices(pagination.Page, pagination.Limit, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch fuel prices",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"prices":      prices,
+		"total":       total,
+		"page":        pagination.Page,
+		"limit":       pagination.Limit,
+		"total_pages": (total + int64(pagination.Limit) - 1) / int64(pagination.Limit),
+	})
+}
+
+// GetFuelPriceTrend returns a daily price series
+// @Summary Get Fuel Price Trend
+// @Description Daily median price with min, max and day-on-day change for a station, a region, or nationally when neither is given. Defaults to the last 30 days.
+// @Tags fuel
+// @Produce json
+// @Param region query string false "State"
+// @Param fuel_station_id query int false "Station ID, takes precedence over region"
+// @Param fuel_type query string false "Fuel type" default(DIESEL)
+// @Param from query string false "From date (YYYY-MM-DD)"
+// @Param to query string false "To date (YYYY-MM-DD)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/prices/trend [get]
+func (h *FuelHandler) GetFuelPriceTrend(c *gin.Context) {
+	to := time.Now()
+	if date, ok := parseDateQuery(c, "to"); !ok {
+		return
+	} else if date != nil {
+		to = *date
+	}
+	from := to.AddDate(0, 0, -30)
+	if date, ok := parseDateQuery(c, "from"); !ok {
+		return
+	} else if date != nil {
+		from = *date
+	}
+
+	var stationID *uint
+	if value := c.Query("fuel_station_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.APIError{
+				Error:   "validation_failed",
+				Message: "Invalid fuel_station_id",
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+		station := uint(id)
+		stationID = &station
+	}
+
+	region, fuelType := c.Query("region"), c.DefaultQuery("fuel_type", "DIESEL")
+	trend, err := h.services.FuelService.FuelPriceTrend(region, fuelType, stationID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "fetch_failed",
+			Message: "Failed to compute fuel price trend",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"region":          region,
+		"fuel_station_id": stationID,
+		"fuel_type":       fuelType,
+		"from":            from,
+		"to":              to,
+		"trend":           trend,
+	})
+}
+
+// ImportFuelPriceFeed stores published fuel prices
+// @Summary Import Fuel Price Feed
+// @Description Import daily prices from an external feed; they join verified fills in the regional medians receipts are checked against (admin only)
+// @Tags fuel
+// @Accept json
+// @Produce json
+// @Param request body dto.FuelPriceFeedRequest true "Feed prices"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/prices/feed [post]
+func (h *FuelHandler) ImportFuelPriceFeed(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUserID(c)
+
+	var req dto.FuelPriceFeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+			Details: map[string]string{"validation": err.Error()},
+		})
+		return
+	}
+
+	entries := make([]models.FuelPrice, 0, len(req.Prices))
+	for _, price := range req.Prices {
+		date, err := time.Parse("2006-01-02", price.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.APIError{
+				Error:   "validation_failed",
+				Message: "Invalid date " + price.Date + ", expected YYYY-MM-DD",
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+		entries = append(entries, models.FuelPrice{
+			Date:          date,
+			Region:        price.Region,
+			FuelType:      price.FuelType,
+			PricePerLiter: price.PricePerLiter,
+			FuelStationID: price.FuelStationID,
+		})
+	}
+
+	imported, err := h.services.FuelService.ImportFuelPriceFeed(req.Source, entries, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFuelPrice) {
+			c.JSON(http.StatusBadRequest, dto.APIError{
+				Error:   "validation_failed",
+				Message: err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "import_failed",
+			Message: "Failed to import fuel prices",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"imported": imported, "prices": entries})
+}
+
+// RebuildFuelPriceHistory recomputes price history from verified fuel events
+// @Summary Rebuild Fuel Price History
+// @Description Recompute the daily EVENTS price rows for a window from verified fuel events; imported feed prices are kept (admin only)
+// @Tags fuel
+// @Accept json
+// @Produce json
+// @Param request body dto.FuelPriceRebuildRequest false "Window, defaults to the last 7 days"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/prices/rebuild [post]
+func (h *FuelHandler) RebuildFuelPriceHistory(c *gin.Context) {
+	var req dto.FuelPriceRebuildRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, dto.APIError{
+				Error:   "validation_failed",
+				Message: "Invalid request data",
+				Code:    http.StatusBadRequest,
+				Details: map[string]string{"validation": err.Error()},
+			})
+			return
+		}
+	}
+
+	to := time.Now()
+	if req.To != nil {
+		to = *req.To
+	}
+	from := to.AddDate(0, 0, -7)
+	if req.From != nil {
+		from = *req.From
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "from must not be after to",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	rows, err := h.services.FuelService.RebuildFuelPriceHistory(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "rebuild_failed",
+			Message: "Failed to rebuild fuel price history",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"from": from, "to": to, "rows": rows})
+}
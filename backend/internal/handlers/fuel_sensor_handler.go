@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AnalyzeFuelLevel runs sensor-based fuel theft and refuel detection for a vehicle
+// @Summary Analyze Tank Level Telemetry
+// @Description Detect refuels and sudden drops in a vehicle's tank-level telemetry, reconcile refuels against fuel events and raise THEFT_SUSPECTED alerts (admin only). Defaults to the last 24 hours.
+// @Tags fuel
+// @Accept json
+// @Produce json
+// @Param request body dto.FuelSensorAnalysisRequest true "Vehicle and time window"
+// @Success 200 {object} services.FuelLevelAnalysis
+// @Failure 400 {object} dto.APIError
+// @Failure 404 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/sensor-analysis [post]
+func (h *FuelHandler) AnalyzeFuelLevel(c *gin.Context) {
+	var req dto.FuelSensorAnalysisRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+			Details: map[string]string{"validation": err.Error()},
+		})
+		return
+	}
+
+	to := time.Now()
+	if req.To != nil {
+		to = *req.To
+	}
+	from := to.Add(-24 * time.Hour)
+	if req.From != nil {
+		from = *req.From
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "from must be before to",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	analysis, err := h.services.FuelService.AnalyzeFuelLevel(req.VehicleID, from, to)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, dto.APIError{
+				Error:   "not_found",
+				Message: "Vehicle not found",
+				Code:    http.StatusNotFound,
+			})
+			return
+		}
+		c.JSON(http.StatusUnprocessableEntity, dto.APIError{
+			Error:   "analysis_failed",
+			Message: err.Error(),
+			Code:    http.StatusUnprocessableEntity,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
+}
+
+// GetFuelLevelEvents lists refuels and drops detected from tank-level telemetry
+// @Summary Get Tank Level Events
+// @Description List refuels and drops detected from tank-level telemetry with their reconciliation status
+// @Tags fuel
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Param vehicle_id query int false "Filter by vehicle ID"
+// @Param event_type query string false "Filter by type (REFUEL, DROP)"
+// @Param status query string false "Filter by status (RECONCILED, MISMATCH, UNREPORTED, THEFT_SUSPECTED)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/level-events [get]
+func (h *FuelHandler) GetFuelLevelEvents(c *gin.Context) {
+	var pagination dto.PaginationParams
+	if err := c.ShouldBindQuery(&pagination); err != nil || pagination.Page < 1 || pagination.Limit < 1 || pagination.Limit > 100 {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid pagination parameters",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	filters := make(map[string]interface{})
+	for _, key := range []string{"event_type", "status"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+	if value := c.Query("vehicle_id"); value != "" {
+		if id, err := strconv.ParseUint(value, 10, 32); err == nil {
+			filters["vehicle_id"] = uint(id)
+		}
+	}
+
+	events, total, err := h.services.FuelService.GetFuelLevelEvents(pagination.Page, pagination.Limit, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch fuel level events",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":      events,
+		"total":       total,
+		"page":        pagination.Page,
+		"limit":       pagination.Limit,
+		"total_pages": (total + int64(pagination.Limit) - 1) / int64(pagination.Limit),
+	})
+}
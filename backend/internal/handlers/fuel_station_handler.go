@@ -0,0 +1,514 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/fleetflow/backend/internal/middleware"
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxFuelStationRegistrySize bounds uploaded station registry files
+const maxFuelStationRegistrySize = 10 << 20
+
+// fuelStationError maps fuel station service errors to API responses
+func fuelStationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidFuelStation):
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+	case errors.Is(err, services.ErrTripHasNoRoute):
+		c.JSON(http.StatusUnprocessableEntity, dto.APIError{
+			Error:   "no_route",
+			Message: err.Error(),
+			Code:    http.StatusUnprocessableEntity,
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, dto.APIError{
+			Error:   "not_found",
+			Message: "Fuel station, fleet or trip not found",
+			Code:    http.StatusNotFound,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "fuel_station_failed",
+			Message: "Failed to process fuel station request",
+			Code:    http.StatusInternalServerError,
+		})
+	}
+}
+
+// parseFuelStationSearch reads the filters shared by radius and along-route searches
+func parseFuelStationSearch(c *gin.Context) (services.FuelStationSearch, bool) {
+	search := services.FuelStationSearch{
+		Brand:       c.Query("brand"),
+		FuelType:    c.Query("fuel_type"),
+		Open24Hours: c.Query("open_24_hours") == "true",
+		Limit:       50,
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > 200 {
+			c.JSON(http.StatusBadRequest, dto.APIError{
+				Error:   "validation_failed",
+				Message: "limit must be between 1 and 200",
+				Code:    http.StatusBadRequest,
+			})
+			return search, false
+		}
+		search.Limit = limit
+	}
+	if value := c.Query("fleet_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.APIError{
+				Error:   "validation_failed",
+				Message: "Invalid fleet_id",
+				Code:    http.StatusBadRequest,
+			})
+			return search, false
+		}
+		fleetID := uint(id)
+		search.FleetID = &fleetID
+	}
+	return search, true
+}
+
+// fuelStationFromRequest builds a station from a create request
+func fuelStationFromRequest(req dto.CreateFuelStationRequest) models.FuelStation {
+	return models.FuelStation{
+		ExternalID: req.ExternalID,
+		Name:       req.Name,
+		Brand:      req.Brand,
+		Address:    req.Address,
+		City:       req.City,
+		State:      req.State,
+		Pincode:    req.Pincode,
+		Latitude:   req.Latitude,
+		Longitude:  req.Longitude,
+		Phone:      req.Phone,
+		FuelTypes:  req.FuelTypes,
+		Amenities:  req.Amenities,
+		Is24Hours:  req.Is24Hours,
+		IsActive:   req.IsActive == nil || *req.IsActive,
+	}
+}
+
+// GetNearbyFuelStations returns nearby fuel stations
+// @Summary Get Nearby Fuel Stations
+// @Description Get active registered fuel stations near a location, nearest first, with each station's latest price for the fuel type and whether it is on the fleet's preferred list
+// @Tags fuel
+// @Produce json
+// @Param lat query float64 true "Latitude"
+// @Param lng query float64 true "Longitude"
+// @Param radius query number false "Search radius in km, at most 100" default(10)
+// @Param brand query string false "Brand, e.g. HP, BPCL, IOC"
+// @Param fuel_type query string false "Fuel type sold" default(DIESEL)
+// @Param open_24_hours query bool false "Only stations open around the clock"
+// @Param fleet_id query int false "Mark this fleet's preferred stations"
+// @Param limit query int false "Maximum results" default(50)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/stations [get]
+func (h *FuelHandler) GetNearbyFuelStations(c *gin.Context) {
+	lat, latErr := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, lngErr := strconv.ParseFloat(c.Query("lng"), 64)
+	if latErr != nil || lngErr != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "lat and lng are required",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+	radius, err := strconv.ParseFloat(c.DefaultQuery("radius", "10"), 64)
+	if err != nil || radius <= 0 {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid radius",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+	search, ok := parseFuelStationSearch(c)
+	if !ok {
+		return
+	}
+
+	stations, err := h.services.FuelService.NearbyFuelStations(lat, lng, radius, search)
+	if err != nil {
+		fuelStationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stations": stations, "count": len(stations), "radius_km": radius})
+}
+
+// GetFuelStationsAlongTrip returns fuel stations along a trip's planned route
+// @Summary Get Fuel Stations Along Trip
+// @Description Get active registered fuel stations within a corridor of the trip's route polyline, in the order the route passes them
+// @Tags fuel
+// @Produce json
+// @Param trip_id path int true "Trip ID"
+// @Param corridor query number false "Maximum distance off the route in meters, at most 20000" default(2000)
+// @Param brand query string false "Brand, e.g. HP, BPCL, IOC"
+// @Param fuel_type query string false "Fuel type sold" default(DIESEL)
+// @Param open_24_hours query bool false "Only stations open around the clock"
+// @Param fleet_id query int false "Mark this fleet's preferred stations"
+// @Param limit query int false "Maximum results" default(50)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} dto.APIError
+// @Failure 404 {object} dto.APIError
+// @Failure 422 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/stations/along-trip/{trip_id} [get]
+func (h *FuelHandler) GetFuelStationsAlongTrip(c *gin.Context) {
+	tripID, ok := parseIDParam(c, "trip_id", "Invalid trip ID")
+	if !ok {
+		return
+	}
+	corridor, err := strconv.ParseFloat(c.DefaultQuery("corridor", "2000"), 64)
+	if err != nil || corridor <= 0 {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid corridor",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+	search, ok := parseFuelStationSearch(c)
+	if !ok {
+		return
+	}
+
+	stations, err := h.services.FuelService.FuelStationsAlongTrip(tripID, corridor, search)
+	if err != nil {
+		fuelStationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"trip_id": tripID, "stations": stations, "count": len(stations), "corridor_meters": corridor})
+}
+
+// GetFuelStation gets a fuel station by ID
+// @Summary Get Fuel Station
+// @Description Get a registered fuel station
+// @Tags fuel
+// @Produce json
+// @Param id path int true "Fuel station ID"
+// @Success 200 {object} models.FuelStation
+// @Failure 404 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/stations/{id} [get]
+func (h *FuelHandler) GetFuelStation(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Invalid fuel station ID")
+	if !ok {
+		return
+	}
+
+	station, err := h.services.FuelService.GetFuelStation(id)
+	if err != nil {
+		fuelStationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, station)
+}
+
+// CreateFuelStation creates a new fuel station
+// @Summary Create Fuel Station
+// @Description Register a fuel station with its brand, fuel types and amenities (admin only)
+// @Tags fuel
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateFuelStationRequest true "Fuel station data"
+// @Success 201 {object} models.FuelStation
+// @Failure 400 {object} dto.APIError
+// @Failure 401 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/stations [post]
+func (h *FuelHandler) CreateFuelStation(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUserID(c)
+
+	var req dto.CreateFuelStationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+			Details: map[string]string{"validation": err.Error()},
+		})
+		return
+	}
+
+	station := fuelStationFromRequest(req)
+	if err := h.services.FuelService.CreateFuelStation(&station, userID); err != nil {
+		fuelStationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, station)
+}
+
+// UpdateFuelStation updates a fuel station
+// @Summary Update Fuel Station
+// @Description Change a registered fuel station or deactivate it (admin only)
+// @Tags fuel
+// @Accept json
+// @Produce json
+// @Param id path int true "Fuel station ID"
+// @Param request body dto.UpdateFuelStationRequest true "Changes"
+// @Success 200 {object} models.FuelStation
+// @Failure 400 {object} dto.APIError
+// @Failure 404 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/stations/{id} [put]
+func (h *FuelHandler) UpdateFuelStation(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUserID(c)
+
+	id, ok := parseIDParam(c, "id", "Invalid fuel station ID")
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateFuelStationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+			Details: map[string]string{"validation": err.Error()},
+		})
+		return
+	}
+
+	station, err := h.services.FuelService.GetFuelStation(id)
+	if err != nil {
+		fuelStationError(c, err)
+		return
+	}
+	previous := *station
+	for field, value := range map[*string]*string{
+		&station.ExternalID: req.ExternalID, &station.Name: req.Name, &station.Brand: req.Brand,
+		&station.Address: req.Address, &station.City: req.City, &station.State: req.State,
+		&station.Pincode: req.Pincode, &station.Phone: req.Phone,
+	} {
+		if value != nil {
+			*field = *value
+		}
+	}
+	if req.Latitude != nil {
+		station.Latitude = *req.Latitude
+	}
+	if req.Longitude != nil {
+		station.Longitude = *req.Longitude
+	}
+	if req.FuelTypes != nil {
+		station.FuelTypes = req.FuelTypes
+	}
+	if req.Amenities != nil {
+		station.Amenities = req.Amenities
+	}
+	if req.Is24Hours != nil {
+		station.Is24Hours = *req.Is24Hours
+	}
+	if req.IsActive != nil {
+		station.IsActive = *req.IsActive
+	}
+
+	if err := h.services.FuelService.UpdateFuelStation(station, previous, userID); err != nil {
+		fuelStationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, station)
+}
+
+// ImportFuelStations bulk-loads the fuel station registry
+// @Summary Import Fuel Stations
+// @Description Bulk-load stations as JSON, or as a CSV file whose headers are the JSON field names (fuel_types and amenities separated by semicolons). Entries with the external ID of a registered station, or its name within 300 m, update it; the rest are created. An invalid entry rejects the whole import (admin only).
+// @Tags fuel
+// @Accept json,multipart/form-data
+// @Produce json
+// @Param request body dto.ImportFuelStationsRequest false "Stations as JSON"
+// @Param file formData file false "Stations as CSV"
+// @Success 201 {object} services.FuelStationImportResult
+// @Failure 400 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/stations/import [post]
+func (h *FuelHandler) ImportFuelStations(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUserID(c)
+
+	var stations []models.FuelStation
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.APIError{
+				Error:   "validation_failed",
+				Message: "Registry file is required",
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+		if header.Size > maxFuelStationRegistrySize {
+			c.JSON(http.StatusRequestEntityTooLarge, dto.APIError{
+				Error:   "file_too_large",
+				Message: "Registry files are limited to 10 MB",
+				Code:    http.StatusRequestEntityTooLarge,
+			})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			fuelStationError(c, err)
+			return
+		}
+		defer file.Close()
+
+		if stations, err = services.ReadFuelStationCSV(file); err != nil {
+			fuelStationError(c, err)
+			return
+		}
+	} else {
+		var req dto.ImportFuelStationsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, dto.APIError{
+				Error:   "validation_failed",
+				Message: "Invalid request data",
+				Code:    http.StatusBadRequest,
+				Details: map[string]string{"validation": err.Error()},
+			})
+			return
+		}
+		for _, entry := range req.Stations {
+			stations = append(stations, fuelStationFromRequest(entry))
+		}
+	}
+
+	result, err := h.services.FuelService.ImportFuelStations(stations, userID)
+	if err != nil {
+		fuelStationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// GetFleetPreferredStations lists a fleet's preferred fuel stations
+// @Summary Get Fleet Preferred Stations
+// @Description List the fuel stations a fleet's drivers should use, most preferred first. Fills at registered stations off the list add to a fuel event's fraud score.
+// @Tags fuel
+// @Produce json
+// @Param fleet_id path int true "Fleet ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/fleets/{fleet_id}/preferred-stations [get]
+func (h *FuelHandler) GetFleetPreferredStations(c *gin.Context) {
+	fleetID, ok := parseIDParam(c, "fleet_id", "Invalid fleet ID")
+	if !ok {
+		return
+	}
+
+	preferred, err := h.services.FuelService.GetFleetPreferredStations(fleetID)
+	if err != nil {
+		fuelStationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fleet_id": fleetID, "stations": preferred})
+}
+
+// SetFleetPreferredStation adds a station to a fleet's preferred list
+// @Summary Set Fleet Preferred Station
+// @Description Add an active station to a fleet's preferred list, or change its priority and notes (admin only)
+// @Tags fuel
+// @Accept json
+// @Produce json
+// @Param fleet_id path int true "Fleet ID"
+// @Param station_id path int true "Fuel station ID"
+// @Param request body dto.FleetPreferredStationRequest true "Priority and notes"
+// @Success 200 {object} models.FleetPreferredStation
+// @Failure 400 {object} dto.APIError
+// @Failure 404 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/fleets/{fleet_id}/preferred-stations/{station_id} [put]
+func (h *FuelHandler) SetFleetPreferredStation(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUserID(c)
+
+	fleetID, ok := parseIDParam(c, "fleet_id", "Invalid fleet ID")
+	if !ok {
+		return
+	}
+	stationID, ok := parseIDParam(c, "station_id", "Invalid fuel station ID")
+	if !ok {
+		return
+	}
+
+	var req dto.FleetPreferredStationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, dto.APIError{
+				Error:   "validation_failed",
+				Message: "Invalid request data",
+				Code:    http.StatusBadRequest,
+				Details: map[string]string{"validation": err.Error()},
+			})
+			return
+		}
+	}
+
+	entry := &models.FleetPreferredStation{
+		FleetID:       fleetID,
+		FuelStationID: stationID,
+		Priority:      req.Priority,
+		Notes:         req.Notes,
+	}
+	if err := h.services.FuelService.SetFleetPreferredStation(entry, userID); err != nil {
+		fuelStationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// RemoveFleetPreferredStation takes a station off a fleet's preferred list
+// @Summary Remove Fleet Preferred Station
+// @Description Remove a station from a fleet's preferred list (admin only)
+// @Tags fuel
+// @Produce json
+// @Param fleet_id path int true "Fleet ID"
+// @Param station_id path int true "Fuel station ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/fleets/{fleet_id}/preferred-stations/{station_id} [delete]
+func (h *FuelHandler) RemoveFleetPreferredStation(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUserID(c)
+
+	fleetID, ok := parseIDParam(c, "fleet_id", "Invalid fleet ID")
+	if !ok {
+		return
+	}
+	stationID, ok := parseIDParam(c, "station_id", "Invalid fuel station ID")
+	if !ok {
+		return
+	}
+
+	if err := h.services.FuelService.RemoveFleetPreferredStation(fleetID, stationID, userID); err != nil {
+		fuelStationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Preferred station removed"})
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/fleetflow/backend/internal/middleware"
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// fuelThresholdError maps threshold service errors to API responses
+func fuelThresholdError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidFuelThreshold):
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+	case errors.Is(err, services.ErrFuelThresholdExists):
+		c.JSON(http.StatusConflict, dto.APIError{
+			Error:   "threshold_exists",
+			Message: err.Error(),
+			Code:    http.StatusConflict,
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, dto.APIError{
+			Error:   "not_found",
+			Message: "Fuel threshold or vehicle not found",
+			Code:    http.StatusNotFound,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "threshold_failed",
+			Message: "Failed to save fuel threshold",
+			Code:    http.StatusInternalServerError,
+		})
+	}
+}
+
+// GetFuelThresholds lists configured fuel rules
+// @Summary Get Fuel Thresholds
+// @Description List global fuel rules and per-vehicle overrides
+// @Tags fuel
+// @Produce json
+// @Param vehicle_id query string false "Vehicle ID, or 'global' for rules without a vehicle"
+// @Param threshold_type query string false "CONSUMPTION_VARIANCE, EFFICIENCY_DROP, REFUEL_FREQUENCY or PRICE_VARIANCE"
+// @Param is_active query bool false "Filter by active state"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/thresholds [get]
+func (h *FuelHandler) GetFuelThresholds(c *gin.Context) {
+	filters := make(map[string]interface{})
+	if vehicleID := c.Query("vehicle_id"); vehicleID == "global" {
+		filters["vehicle_id"] = nil
+	} else if vehicleID != "" {
+		id, err := strconv.ParseUint(vehicleID, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.APIError{
+				Error:   "validation_failed",
+				Message: "Invalid vehicle_id",
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+		filters["vehicle_id"] = uint(id)
+	}
+	if thresholdType := c.Query("threshold_type"); thresholdType != "" {
+		filters["threshold_type"] = strings.ToUpper(thresholdType)
+	}
+	if isActive, err := strconv.ParseBool(c.Query("is_active")); err == nil {
+		filters["is_active"] = isActive
+	}
+
+	thresholds, err := h.services.FuelService.GetFuelThresholds(filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch fuel thresholds",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"thresholds": thresholds})
+}
+
+// GetEffectiveFuelThresholds shows which rule of each type applies to a vehicle
+// @Summary Get Effective Fuel Thresholds
+// @Description Resolve the active rule of each type for a vehicle: its override when set, the global rule otherwise
+// @Tags fuel
+// @Produce json
+// @Param vehicle_id path int true "Vehicle ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/thresholds/effective/{vehicle_id} [get]
+func (h *FuelHandler) GetEffectiveFuelThresholds(c *gin.Context) {
+	vehicleID, err := strconv.ParseUint(c.Param("vehicle_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "invalid_vehicle_id",
+			Message: "Invalid vehicle ID",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	thresholds, err := h.services.FuelService.EffectiveFuelThresholds(uint(vehicleID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "fetch_failed",
+			Message: "Failed to resolve fuel thresholds",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"vehicle_id": vehicleID, "thresholds": thresholds})
+}
+
+// CreateFuelThreshold adds a fuel rule
+// @Summary Create Fuel Threshold
+// @Description Add a global fuel rule, or a per-vehicle override when vehicle_id is set (admin only)
+// @Tags fuel
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateFuelThresholdRequest true "Threshold"
+// @Success 201 {object} models.FuelThreshold
+// @Failure 400 {object} dto.APIError
+// @Failure 404 {object} dto.APIError
+// @Failure 409 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/thresholds [post]
+func (h *FuelHandler) CreateFuelThreshold(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUserID(c)
+
+	var req dto.CreateFuelThresholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+			Details: map[string]string{"validation": err.Error()},
+		})
+		return
+	}
+
+	threshold := &models.FuelThreshold{
+		VehicleID:     req.VehicleID,
+		ThresholdType: models.FuelThresholdType(req.ThresholdType),
+		Value:         req.Value,
+		Unit:          models.FuelThresholdUnit(strings.ToUpper(req.Unit)),
+		Severity:      models.AlertSeverity(strings.ToUpper(req.Severity)),
+		IsActive:      req.IsActive == nil || *req.IsActive,
+	}
+	if err := h.services.FuelService.CreateFuelThreshold(threshold, userID); err != nil {
+		fuelThresholdError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, threshold)
+}
+
+// UpdateFuelThreshold changes a fuel rule
+// @Summary Update Fuel Threshold
+// @Description Change a rule's value, unit, severity or active state (admin only)
+// @Tags fuel
+// @Accept json
+// @Produce json
+// @Param id path int true "Threshold ID"
+// @Param request body dto.UpdateFuelThresholdRequest true "Changes"
+// @Success 200 {object} models.FuelThreshold
+// @Failure 400 {object} dto.APIError
+// @Failure 404 {object} dto.APIError
+// @Failure 409 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/thresholds/{id} [put]
+func (h *FuelHandler) UpdateFuelThreshold(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUserID(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "invalid_id",
+			Message: "Invalid threshold ID",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	var req dto.UpdateFuelThresholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+			Details: map[string]string{"validation": err.Error()},
+		})
+		return
+	}
+
+	threshold, err := h.services.FuelService.GetFuelThreshold(uint(id))
+	if err != nil {
+		fuelThresholdError(c, err)
+		return
+	}
+	previous := *threshold
+	if req.Value != nil {
+		threshold.Value = *req.Value
+	}
+	if req.Unit != nil {
+		threshold.Unit = models.FuelThresholdUnit(strings.ToUpper(*req.Unit))
+	}
+	if req.Severity != nil {
+		threshold.Severity = models.AlertSeverity(strings.ToUpper(*req.Severity))
+	}
+	if req.IsActive != nil {
+		threshold.IsActive = *req.IsActive
+	}
+
+	if err := h.services.FuelService.UpdateFuelThreshold(threshold, previous, userID); err != nil {
+		fuelThresholdError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, threshold)
+}
+
+// DeleteFuelThreshold removes a fuel rule
+// @Summary Delete Fuel Threshold
+// @Description Remove a fuel rule; a vehicle without an override falls back to the global rule (admin only)
+// @Tags fuel
+// @Param id path int true "Threshold ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} dto.APIError
+// @Failure 404 {object} dto.APIError
+// @Security BearerAuth
+// @Router /fuel/thresholds/{id} [delete]
+func (h *FuelHandler) DeleteFuelThreshold(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUserID(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "invalid_id",
+			Message: "Invalid threshold ID",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	if err := h.services.FuelService.DeleteFuelThreshold(uint(id), userID); err != nil {
+		fuelThresholdError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Fuel threshold deleted"})
+}
+
+// EvaluateFuelThresholds runs the nightly fuel rule evaluation immediately
+// @Summary Evaluate Fuel Thresholds
+// @Description Apply every vehicle's effective fuel rules to the last day and raise alerts for breaches (admin only)
+// @Tags fuel
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /fuel/thresholds/evaluate [post]
+func (h *FuelHandler) EvaluateFuelThresholds(c *gin.Context) {
+	alerts, err := h.services.FuelService.RunFuelRuleEvaluation(time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "evaluation_failed",
+			Message: "Failed to evaluate fuel thresholds",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts})
+}
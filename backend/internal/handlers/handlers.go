@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -13,6 +14,7 @@ import (
 	"github.com/fleetflow/backend/internal/services"
 	"github.com/fleetflow/backend/internal/utils"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // AuthHandler handles authentication endpoints
@@ -131,6 +133,15 @@ func (h *AuthHandler) SendOTP(c *gin.Context) {
 	ipAddress := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
 
+	// Refuse blocked phones/IPs and score the request before an SMS is spent on it
+	if h.rejectIfRiskBlocked(c, req.Phone) {
+		return
+	}
+	if assessment := h.assessRisk(c, models.SecurityEventOTPRequest, req.Phone, nil, true); assessment.Blocked {
+		respondRiskBlocked(c, assessment.BlockedUntil)
+		return
+	}
+
 	// Send OTP
 	otpVerification, err := h.services.AuthService.SendOTP(req.Phone, ipAddress, userAgent)
 	if err != nil {
@@ -181,9 +192,17 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 	ipAddress := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
 
+	if h.rejectIfRiskBlocked(c, req.Phone) {
+		return
+	}
+
 	// Verify OTP
 	user, err := h.services.AuthService.VerifyOTP(req.Phone, req.OTP, ipAddress, userAgent)
 	if err != nil {
+		if assessment := h.assessRisk(c, models.SecurityEventOTPVerify, req.Phone, nil, false); assessment.Blocked {
+			respondRiskBlocked(c, assessment.BlockedUntil)
+			return
+		}
 		c.JSON(http.StatusUnauthorized, dto.APIError{
 			Error:   "otp_verification_failed",
 			Message: err.Error(),
@@ -192,53 +211,12 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT tokens
-	accessToken, err := h.services.JWTService.GenerateToken(user)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, dto.APIError{
-			Error:   "token_generation_failed",
-			Message: "Failed to generate access token",
-			Code:    http.StatusInternalServerError,
-		})
+	if assessment := h.assessRisk(c, models.SecurityEventOTPVerify, req.Phone, &user.ID, true); assessment.Blocked {
+		respondRiskBlocked(c, assessment.BlockedUntil)
 		return
 	}
 
-	refreshToken, err := h.services.JWTService.GenerateRefreshToken(user.ID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, dto.APIError{
-			Error:   "token_generation_failed",
-			Message: "Failed to generate refresh token",
-			Code:    http.StatusInternalServerError,
-		})
-		return
-	}
-
-	// Prepare user info
-	userInfo := &dto.UserInfo{
-		ID:       user.ID,
-		Phone:    user.Phone,
-		Role:     user.Role,
-		IsActive: user.IsActive,
-		DriverID: user.DriverID,
-	}
-
-	// Add driver info if available
-	if user.Driver != nil {
-		userInfo.Driver = &dto.DriverInfo{
-			ID:     user.Driver.ID,
-			Name:   user.Driver.Name,
-			Status: string(user.Driver.Status),
-			Rating: user.Driver.Rating,
-		}
-	}
-
-	c.JSON(http.StatusOK, dto.VerifyOTPResponse{
-		Message:      "Login successful",
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken.Token,
-		ExpiresIn:    int(h.services.Config.JWTExpirationTime.Seconds()),
-		User:         userInfo,
-	})
+	h.respondWithLoginTokens(c, user, "otp", false)
 }
 
 // RefreshToken refreshes access token using refresh token
@@ -264,11 +242,19 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	}
 
 	// Refresh tokens
-	newAccessToken, newRefreshToken, err := h.services.JWTService.RefreshToken(req.RefreshToken)
+	newAccessToken, newRefreshToken, err := h.services.JWTService.RefreshToken(req.RefreshToken, sessionContextFromRequest(c, ""))
 	if err != nil {
+		message := "Invalid or expired refresh token"
+		switch {
+		case errors.Is(err, services.ErrRefreshTokenReuse),
+			errors.Is(err, services.ErrDeviceMismatch),
+			errors.Is(err, services.ErrSessionExpired),
+			errors.Is(err, services.ErrSessionRevoked):
+			message = err.Error()
+		}
 		c.JSON(http.StatusUnauthorized, dto.APIError{
 			Error:   "token_refresh_failed",
-			Message: "Invalid or expired refresh token",
+			Message: message,
 			Code:    http.StatusUnauthorized,
 		})
 		return
@@ -305,12 +291,17 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	var req dto.LogoutRequest
 	_ = c.ShouldBindJSON(&req) // Optional body, ignore error
 
-	// Revoke specific refresh token or all user tokens
+	// Revoke the given refresh token's session, the current session, or all user tokens
 	if req.RefreshToken != "" {
 		err := h.services.JWTService.RevokeRefreshToken(req.RefreshToken)
 		if err != nil {
 			log.Printf("⚠️ Failed to revoke specific refresh token: %v", err)
 		}
+	} else if sessionID, ok := middleware.GetCurrentSessionID(c); ok {
+		err := h.services.JWTService.RevokeSession(userID, sessionID, models.SessionRevokedLogout)
+		if err != nil {
+			log.Printf("⚠️ Failed to revoke current session: %v", err)
+		}
 	} else {
 		// Revoke all user tokens
 		err := h.services.JWTService.RevokeAllUserTokens(userID)
@@ -368,13 +359,16 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 	}
 
 	response := dto.UserResponse{
-		ID:        user.ID,
-		Phone:     user.Phone,
-		Role:      user.Role,
-		IsActive:  user.IsActive,
-		LastLogin: user.LastLogin,
-		CreatedAt: user.CreatedAt,
-		DriverID:  user.DriverID,
+		ID:            user.ID,
+		Phone:         user.Phone,
+		Email:         user.Email,
+		PendingEmail:  user.PendingEmail,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Role:          user.Role,
+		IsActive:      user.IsActive,
+		LastLogin:     user.LastLogin,
+		CreatedAt:     user.CreatedAt,
+		DriverID:      user.DriverID,
 	}
 
 	if user.Driver != nil {
@@ -391,14 +385,15 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 
 // UpdateProfile updates current user profile
 // @Summary Update Profile
-// @Description Update current user profile information
+// @Description Update current user profile information. A new email is held as pending until it is verified
 // @Tags auth
 // @Accept json
 // @Produce json
-// @Param request body map[string]interface{} true "Profile updates"
-// @Success 200 {object} map[string]interface{}
+// @Param request body dto.UpdateProfileRequest true "Profile updates"
+// @Success 200 {object} dto.UserResponse
 // @Failure 400 {object} dto.APIError
 // @Failure 401 {object} dto.APIError
+// @Failure 409 {object} dto.APIError
 // @Security BearerAuth
 // @Router /auth/profile [put]
 func (h *AuthHandler) UpdateProfile(c *gin.Context) {
@@ -422,8 +417,27 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	// For now, OTP-based auth has limited profile updates
-	// This is a placeholder for future enhancements
+	// Email is the only account field editable here; it enables password login and SSO linking, so
+	// a new address only takes effect once the token sent to it is confirmed
+	if req.Email != "" {
+		if _, err := h.services.AuthService.RequestEmailChange(userID, req.Email); err != nil {
+			if errors.Is(err, services.ErrEmailInUse) {
+				c.JSON(http.StatusConflict, dto.APIError{
+					Error:   "email_in_use",
+					Message: err.Error(),
+					Code:    http.StatusConflict,
+				})
+				return
+			}
+			c.JSON(http.StatusBadRequest, dto.APIError{
+				Error:   "profile_update_failed",
+				Message: "Failed to update profile",
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+	}
+
 	user, err := h.services.AuthService.GetUserByID(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dto.APIError{
@@ -435,13 +449,16 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 	}
 
 	response := dto.UserResponse{
-		ID:        user.ID,
-		Phone:     user.Phone,
-		Role:      user.Role,
-		IsActive:  user.IsActive,
-		LastLogin: user.LastLogin,
-		CreatedAt: user.CreatedAt,
-		DriverID:  user.DriverID,
+		ID:            user.ID,
+		Phone:         user.Phone,
+		Email:         user.Email,
+		PendingEmail:  user.PendingEmail,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Role:          user.Role,
+		IsActive:      user.IsActive,
+		LastLogin:     user.LastLogin,
+		CreatedAt:     user.CreatedAt,
+		DriverID:      user.DriverID,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -1484,6 +1501,7 @@ func (h *VehicleHandler) CreateVehicle(c *gin.Context) {
 		FuelType:     req.FuelType,
 		FuelCapacity: req.FuelCapacity,
 		LoadCapacity: req.LoadCapacity,
+		FleetID:      req.FleetID,
 		Status:       models.VehicleStatusActive,
 		IsActive:     true,
 	}
@@ -1507,13 +1525,57 @@ func (h *VehicleHandler) CreateVehicle(c *gin.Context) {
 // @Tags vehicles
 // @Produce json
 // @Param id path int true "Vehicle ID"
-// @Success 200 {object} map[string]interface{}
+// @Success 200 {object} vehicleDetail
 // @Failure 400 {object} dto.APIError
 // @Failure 404 {object} dto.APIError
 // @Security BearerAuth
 // @Router /vehicles/{id} [get]
 func (h *VehicleHandler) GetVehicle(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Vehicle data"})
+	vehicleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "invalid_vehicle_id",
+			Message: "Invalid vehicle ID",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	vehicle, err := h.services.VehicleService.GetVehicleByID(uint(vehicleID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, dto.APIError{
+				Error:   "vehicle_not_found",
+				Message: "Vehicle not found",
+				Code:    http.StatusNotFound,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "vehicle_fetch_failed",
+			Message: err.Error(),
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	health, err := h.services.TelemetryService.GetVehicleHealth(c.Request.Context(), vehicle.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "vehicle_health_failed",
+			Message: err.Error(),
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, vehicleDetail{Vehicle: vehicle, Health: health})
+}
+
+// vehicleDetail is a vehicle with its current health score
+type vehicleDetail struct {
+	*models.Vehicle
+	Health *services.VehicleHealth `json:"health"`
 }
 
 // UpdateVehicle updates vehicle information
@@ -1848,9 +1910,44 @@ func (h *TripHandler) AssignTrip(c *gin.Context) {
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} dto.APIError
 // @Failure 404 {object} dto.APIError
+// @Failure 409 {object} dto.APIError "Vehicle has an unresolved out-of-service defect"
 // @Security BearerAuth
 // @Router /trips/{id}/start [post]
 func (h *TripHandler) StartTrip(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "invalid_id",
+			Message: "Invalid trip ID",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	if err := h.services.TripService.StartTrip(c.Request.Context(), uint(id)); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, dto.APIError{
+				Error:   "not_found",
+				Message: "Trip not found",
+				Code:    http.StatusNotFound,
+			})
+		case errors.Is(err, services.ErrVehicleOutOfService):
+			c.JSON(http.StatusConflict, dto.APIError{
+				Error:   "vehicle_out_of_service",
+				Message: err.Error(),
+				Code:    http.StatusConflict,
+			})
+		default:
+			c.JSON(http.StatusBadRequest, dto.APIError{
+				Error:   "trip_start_failed",
+				Message: err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+		}
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Trip started"})
 }
 
@@ -2114,13 +2211,60 @@ func (h *FuelHandler) RejectFuelEvent(c *gin.Context) {
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(20)
+// @Param vehicle_id query int false "Filter by vehicle ID"
+// @Param alert_type query string false "Filter by alert type (THEFT_SUSPECTED, FRAUD_DETECTED, ...)"
+// @Param severity query string false "Filter by severity"
+// @Param is_resolved query bool false "Filter by resolution state"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} dto.APIError
 // @Failure 401 {object} dto.APIError
 // @Security BearerAuth
 // @Router /fuel/alerts [get]
 func (h *FuelHandler) GetFuelAlerts(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Fuel alerts list"})
+	var pagination dto.PaginationParams
+	if err := c.ShouldBindQuery(&pagination); err != nil || pagination.Page < 1 || pagination.Limit < 1 || pagination.Limit > 100 {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid pagination parameters",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	filters := make(map[string]interface{})
+	for _, key := range []string{"alert_type", "severity"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+	if value := c.Query("vehicle_id"); value != "" {
+		if id, err := strconv.ParseUint(value, 10, 32); err == nil {
+			filters["vehicle_id"] = uint(id)
+		}
+	}
+	if value := c.Query("is_resolved"); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			filters["is_resolved"] = b
+		}
+	}
+
+	alerts, total, err := h.services.FuelService.GetFuelAlerts(pagination.Page, pagination.Limit, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch fuel alerts",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"alerts":      alerts,
+		"total":       total,
+		"page":        pagination.Page,
+		"limit":       pagination.Limit,
+		"total_pages": (total + int64(pagination.Limit) - 1) / int64(pagination.Limit),
+	})
 }
 
 // GetFuelAlert returns fuel alert details by ID
@@ -2145,13 +2289,55 @@ func (h *FuelHandler) GetFuelAlert(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Fuel Alert ID"
-// @Success 200 {object} map[string]interface{}
+// @Param request body dto.ResolveFuelAlertRequest true "Resolution notes"
+// @Success 200 {object} models.FuelAlert
 // @Failure 400 {object} dto.APIError
 // @Failure 404 {object} dto.APIError
 // @Security BearerAuth
 // @Router /fuel/alerts/{id}/resolve [post]
 func (h *FuelHandler) ResolveFuelAlert(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Fuel alert resolved"})
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, dto.APIError{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "invalid_id",
+			Message: "Invalid fuel alert ID",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	var req dto.ResolveFuelAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+			Details: map[string]string{"validation": err.Error()},
+		})
+		return
+	}
+
+	alert, err := h.services.FuelService.ResolveFuelAlert(uint(id), userID, req.Notes)
+	if err != nil {
+		c.JSON(http.StatusNotFound, dto.APIError{
+			Error:   "not_found",
+			Message: "Fuel alert not found",
+			Code:    http.StatusNotFound,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
 }
 
 // GetFuelAnalytics returns fuel consumption analytics
@@ -2159,14 +2345,67 @@ func (h *FuelHandler) ResolveFuelAlert(c *gin.Context) {
 // @Description Get fuel consumption and efficiency analytics
 // @Tags fuel
 // @Produce json
-// @Param period query string false "Time period" default("month")
-// @Success 200 {object} map[string]interface{}
+// @Param period query string false "Time period (day, week, month, year) ending now" default("month")
+// @Param start_date query string false "Start date (YYYY-MM-DD), overrides period"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Success 200 {object} models.FuelAnalytics
 // @Failure 400 {object} dto.APIError
 // @Failure 401 {object} dto.APIError
 // @Security BearerAuth
 // @Router /fuel/analytics [get]
 func (h *FuelHandler) GetFuelAnalytics(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Fuel analytics"})
+	period := strings.ToLower(c.DefaultQuery("period", "month"))
+	endDate := time.Now()
+	var startDate time.Time
+	switch period {
+	case "day":
+		startDate = endDate.AddDate(0, 0, -1)
+	case "week":
+		startDate = endDate.AddDate(0, 0, -7)
+	case "month":
+		startDate = endDate.AddDate(0, -1, 0)
+	case "year":
+		startDate = endDate.AddDate(-1, 0, 0)
+	default:
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "period must be day, week, month or year",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	for param, target := range map[string]*time.Time{"start_date": &startDate, "end_date": &endDate} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.APIError{
+				Error:   "validation_failed",
+				Message: "Invalid " + param + ", expected YYYY-MM-DD",
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+		if param == "end_date" {
+			parsed = parsed.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		*target = parsed
+	}
+
+	analytics, err := h.services.FuelService.GetFuelAnalytics(period, startDate, endDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "analytics_failed",
+			Message: "Failed to compute fuel analytics",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, analytics)
 }
 
 // GetVehicleFuelAnalytics returns vehicle-specific fuel analytics
@@ -2185,38 +2424,6 @@ func (h *FuelHandler) GetVehicleFuelAnalytics(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Vehicle fuel analytics"})
 }
 
-// GetNearbyFuelStations returns nearby fuel stations
-// @Summary Get Nearby Fuel Stations
-// @Description Get fuel stations near a location
-// @Tags fuel
-// @Produce json
-// @Param lat query float64 true "Latitude"
-// @Param lng query float64 true "Longitude"
-// @Param radius query int false "Search radius in km" default(10)
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} dto.APIError
-// @Security BearerAuth
-// @Router /fuel/stations [get]
-func (h *FuelHandler) GetNearbyFuelStations(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Nearby fuel stations"})
-}
-
-// CreateFuelStation creates a new fuel station
-// @Summary Create Fuel Station
-// @Description Create a new fuel station (admin only)
-// @Tags fuel
-// @Accept json
-// @Produce json
-// @Param request body map[string]interface{} true "Fuel station data"
-// @Success 201 {object} map[string]interface{}
-// @Failure 400 {object} dto.APIError
-// @Failure 401 {object} dto.APIError
-// @Security BearerAuth
-// @Router /fuel/stations [post]
-func (h *FuelHandler) CreateFuelStation(c *gin.Context) {
-	c.JSON(http.StatusCreated, gin.H{"message": "Fuel station created"})
-}
-
 // ExportFuelEvents exports fuel events to CSV
 // @Summary Export Fuel Events
 // @Description Export fuel events data to CSV format
@@ -2720,6 +2927,9 @@ func (h *AnalyticsHandler) UpdateSystemSettings(c *gin.Context) {
 // @Param limit query int false "Items per page" default(20)
 // @Param from query string false "Start date (RFC3339)"
 // @Param to query string false "End date (RFC3339)"
+// @Param action query string false "Filter by action"
+// @Param severity query string false "Filter by severity"
+// @Param organization_id query int false "Filter by organization chain"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} dto.APIError
 // @Failure 401 {object} dto.APIError
@@ -2727,7 +2937,61 @@ func (h *AnalyticsHandler) UpdateSystemSettings(c *gin.Context) {
 // @Security BearerAuth
 // @Router /admin/audit-logs [get]
 func (h *AnalyticsHandler) GetAuditLogs(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Audit logs"})
+	var pagination dto.PaginationParams
+	if err := c.ShouldBindQuery(&pagination); err != nil || pagination.Page < 1 || pagination.Limit < 1 || pagination.Limit > 100 {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid pagination parameters",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	filters := make(map[string]interface{})
+	for _, key := range []string{"action", "severity", "table_name"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+	for _, key := range []string{"user_id", "organization_id"} {
+		if value := c.Query(key); value != "" {
+			if id, err := strconv.ParseUint(value, 10, 32); err == nil {
+				filters[key] = uint(id)
+			}
+		}
+	}
+	for param, key := range map[string]string{"from": "from_date", "to": "to_date"} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, dto.APIError{
+					Error:   "validation_failed",
+					Message: "Dates must be RFC3339",
+					Code:    http.StatusBadRequest,
+				})
+				return
+			}
+			filters[key] = t
+		}
+	}
+
+	logs, total, err := h.services.AuditService.GetAuditLogs(pagination.Page, pagination.Limit, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch audit logs",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"logs":        logs,
+		"total":       total,
+		"page":        pagination.Page,
+		"limit":       pagination.Limit,
+		"total_pages": (total + int64(pagination.Limit) - 1) / int64(pagination.Limit),
+	})
 }
 
 // GetSecurityEvents returns security events
@@ -2738,6 +3002,10 @@ func (h *AnalyticsHandler) GetAuditLogs(c *gin.Context) {
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(20)
 // @Param severity query string false "Filter by severity"
+// @Param status query string false "Filter by status (SUCCESS, FAILED, BLOCKED)"
+// @Param event_type query string false "Filter by event type"
+// @Param is_investigated query bool false "Filter by investigation state"
+// @Param is_blocked query bool false "Filter by blocked events"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} dto.APIError
 // @Failure 401 {object} dto.APIError
@@ -2745,7 +3013,47 @@ func (h *AnalyticsHandler) GetAuditLogs(c *gin.Context) {
 // @Security BearerAuth
 // @Router /admin/security-events [get]
 func (h *AnalyticsHandler) GetSecurityEvents(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Security events"})
+	var pagination dto.PaginationParams
+	if err := c.ShouldBindQuery(&pagination); err != nil || pagination.Page < 1 || pagination.Limit < 1 || pagination.Limit > 100 {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid pagination parameters",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	filters := make(map[string]interface{})
+	for _, key := range []string{"severity", "status", "event_type"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+	for _, key := range []string{"is_investigated", "is_blocked"} {
+		if value := c.Query(key); value != "" {
+			if b, err := strconv.ParseBool(value); err == nil {
+				filters[key] = b
+			}
+		}
+	}
+
+	events, total, err := h.services.AuditService.GetSecurityEvents(pagination.Page, pagination.Limit, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch security events",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":      events,
+		"total":       total,
+		"page":        pagination.Page,
+		"limit":       pagination.Limit,
+		"total_pages": (total + int64(pagination.Limit) - 1) / int64(pagination.Limit),
+	})
 }
 
 // WhatsApp Handler Methods
@@ -5,6 +5,8 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/fleetflow/backend/internal/middleware"
 	"github.com/fleetflow/backend/internal/models"
 	"github.com/fleetflow/backend/internal/services"
 	"github.com/gin-gonic/gin"
@@ -24,44 +26,71 @@ func NewMaintenanceHandler(maintenanceService *services.MaintenanceService) *Mai
 
 // SubmitDVIR handles DVIR submission
 // @Summary Submit a Driver Vehicle Inspection Report
-// @Description Drivers submit pre-trip or post-trip inspection reports
+// @Description Drivers submit pre-trip or post-trip inspection reports. Each defect is recorded and linked to a work order;
+// @Description an out-of-service defect takes the vehicle off the road. A pre-trip report must acknowledge every defect
+// @Description a mechanic has certified since the last inspection.
 // @Tags maintenance
 // @Accept json
 // @Produce json
-// @Param dvir body models.DVIR true "DVIR Data"
-// @Success 201 {object} map[string]string
+// @Param dvir body dto.SubmitDVIRRequest true "DVIR Data"
+// @Success 201 {object} models.DVIR
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Router /maintenance/dvir [post]
 func (h *MaintenanceHandler) SubmitDVIR(c *gin.Context) {
-	var dvir models.DVIR
-	if err := c.ShouldBindJSON(&dvir); err != nil {
+	var req dto.SubmitDVIRRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Set metadata
-	dvir.CreatedAt = time.Now()
-	
-	// In a real app, we'd get DriverID from the JWT token
-	// dvir.DriverID = c.GetUint("userID")
+	dvir := models.DVIR{
+		VehicleID:  req.VehicleID,
+		DriverID:   req.DriverID,
+		Type:       req.Type,
+		Odometer:   req.Odometer,
+		Location:   req.Location,
+		Defects:    req.Defects,
+		DriverSign: req.DriverSign,
+		SignedAt:   time.Now(),
+	}
+	// Drivers always report as themselves
+	if driverID, ok := middleware.GetCurrentDriverID(c); ok {
+		dvir.DriverID = driverID
+	}
+	if dvir.DriverID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "driver_id is required"})
+		return
+	}
+	for _, defect := range req.DefectItems {
+		dvir.DefectRecords = append(dvir.DefectRecords, models.DVIRDefect{
+			ChecklistItemID: defect.ChecklistItemID,
+			Category:        defect.Category,
+			Description:     defect.Description,
+			Severity:        models.DefectSeverity(defect.Severity),
+			PhotoUploadIDs:  defect.PhotoUploadIDs,
+		})
+	}
 
-	if err := h.maintenanceService.SubmitDVIR(c.Request.Context(), &dvir); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit DVIR: " + err.Error()})
+	if err := h.maintenanceService.SubmitDVIR(c.Request.Context(), &dvir, req.AcknowledgedDefectIDs); err != nil {
+		dvirError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "DVIR submitted successfully", "status": dvir.Status})
+	c.JSON(http.StatusCreated, dvir)
 }
 
 // GetMaintenanceDue handles listing vehicles due for maintenance
 // @Summary Get vehicles due for maintenance
-// @Description Returns a list of service schedules that are due
+// @Description Returns service schedules that are due by mileage, engine hours or date, whichever comes first
 // @Tags maintenance
 // @Produce json
+// @Param include_due_soon query bool false "Also list schedules inside their due-soon window"
 // @Success 200 {array} models.ServiceSchedule
 // @Router /maintenance/due [get]
 func (h *MaintenanceHandler) GetMaintenanceDue(c *gin.Context) {
-	dueSchedules, err := h.maintenanceService.CheckMaintenanceDue(c.Request.Context())
+	includeDueSoon, _ := strconv.ParseBool(c.Query("include_due_soon"))
+	dueSchedules, err := h.maintenanceService.DueServiceSchedules(c.Request.Context(), includeDueSoon)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check maintenance due: " + err.Error()})
 		return
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/fleetflow/backend/internal/middleware"
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// workOrderItemError maps parts, labour and stock errors onto responses
+func workOrderItemError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInsufficientStock), errors.Is(err, services.ErrWorkOrderClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidWorkOrderItem):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// uintParam parses a numeric path parameter, answering 400 when it is not one
+func uintParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// currentUserRef is the current user's ID for audit fields, nil when unauthenticated
+func currentUserRef(c *gin.Context) *uint {
+	if userID, ok := middleware.GetCurrentUserID(c); ok {
+		return &userID
+	}
+	return nil
+}
+
+// GetWorkOrder handles fetching a work order with its parts and labour
+// @Summary Get a work order
+// @Description Returns a work order with its reserved and consumed parts and labour entries
+// @Tags maintenance
+// @Produce json
+// @Param id path int true "Work Order ID"
+// @Success 200 {object} models.WorkOrder
+// @Failure 404 {object} map[string]string
+// @Router /maintenance/work-order/{id} [get]
+func (h *MaintenanceHandler) GetWorkOrder(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	workOrder, err := h.maintenanceService.GetWorkOrder(c.Request.Context(), id)
+	if err != nil {
+		workOrderItemError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, workOrder)
+}
+
+// AddWorkOrderPart handles reserving a part for a work order
+// @Summary Add a part to a work order
+// @Description Reserves stock of an inventory item; it is consumed when the work order is resolved
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Param id path int true "Work Order ID"
+// @Param part body dto.AddWorkOrderPartRequest true "Part"
+// @Success 201 {object} models.WorkOrderPart
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /maintenance/work-order/{id}/parts [post]
+func (h *MaintenanceHandler) AddWorkOrderPart(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.AddWorkOrderPartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	part, err := h.maintenanceService.AddWorkOrderPart(c.Request.Context(), id, req.InventoryItemID, req.Quantity, currentUserRef(c))
+	if err != nil {
+		workOrderItemError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, part)
+}
+
+// ReleaseWorkOrderPart handles returning a reserved part to stock
+// @Summary Remove a part from a work order
+// @Description Releases a reserved part back to stock
+// @Tags maintenance
+// @Produce json
+// @Param id path int true "Work Order ID"
+// @Param part_id path int true "Part ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /maintenance/work-order/{id}/parts/{part_id} [delete]
+func (h *MaintenanceHandler) ReleaseWorkOrderPart(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	partID, ok := uintParam(c, "part_id")
+	if !ok {
+		return
+	}
+
+	if err := h.maintenanceService.ReleaseWorkOrderPart(c.Request.Context(), id, partID); err != nil {
+		workOrderItemError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Part released to stock"})
+}
+
+// ConsumeWorkOrderPart handles taking a reserved part out of stock
+// @Summary Consume a work order part
+// @Description Takes a reserved part out of stock before the work order is resolved
+// @Tags maintenance
+// @Produce json
+// @Param id path int true "Work Order ID"
+// @Param part_id path int true "Part ID"
+// @Success 200 {object} models.WorkOrderPart
+// @Failure 404 {object} map[string]string
+// @Router /maintenance/work-order/{id}/parts/{part_id}/consume [post]
+func (h *MaintenanceHandler) ConsumeWorkOrderPart(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	partID, ok := uintParam(c, "part_id")
+	if !ok {
+		return
+	}
+
+	part, err := h.maintenanceService.ConsumeWorkOrderPart(c.Request.Context(), id, partID)
+	if err != nil {
+		workOrderItemError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, part)
+}
+
+// AddWorkOrderLabor handles recording a mechanic's time
+// @Summary Add labour to a work order
+// @Description Records a mechanic's hours at their standard or the given hourly rate
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Param id path int true "Work Order ID"
+// @Param labor body dto.AddWorkOrderLaborRequest true "Labour"
+// @Success 201 {object} models.WorkOrderLabor
+// @Failure 400 {object} map[string]string
+// @Router /maintenance/work-order/{id}/labor [post]
+func (h *MaintenanceHandler) AddWorkOrderLabor(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.AddWorkOrderLaborRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	labor, err := h.maintenanceService.AddWorkOrderLabor(c.Request.Context(), id, req.MechanicID, req.Hours, req.HourlyRate, req.Description, req.PerformedAt)
+	if err != nil {
+		workOrderItemError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, labor)
+}
+
+// SetMechanicRate handles setting a mechanic's hourly rate
+// @Summary Set a mechanic's hourly rate
+// @Description Sets the standard rate used for the mechanic's labour entries (admin only)
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Param id path int true "Mechanic user ID"
+// @Param rate body dto.MechanicRateRequest true "Rate"
+// @Success 200 {object} models.MechanicRate
+// @Failure 400 {object} map[string]string
+// @Router /maintenance/mechanics/{id}/rate [put]
+func (h *MaintenanceHandler) SetMechanicRate(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.MechanicRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rate, err := h.maintenanceService.SetMechanicRate(c.Request.Context(), id, req.HourlyRate, currentUserRef(c))
+	if err != nil {
+		workOrderItemError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
+
+// GetVehiclePartsHistory handles listing parts fitted to a vehicle
+// @Summary Get a vehicle's parts history
+// @Description Returns the parts consumed on the vehicle's work orders, newest first
+// @Tags maintenance
+// @Produce json
+// @Param id path int true "Vehicle ID"
+// @Success 200 {object} services.VehiclePartsHistory
+// @Failure 404 {object} map[string]string
+// @Router /maintenance/vehicles/{id}/parts-history [get]
+func (h *MaintenanceHandler) GetVehiclePartsHistory(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	history, err := h.maintenanceService.GetVehiclePartsHistory(c.Request.Context(), id)
+	if err != nil {
+		workOrderItemError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// GetPurchaseSuggestions handles listing restocking suggestions
+// @Summary List purchase-order suggestions
+// @Description Returns suggestions raised when an item's available stock fell below its minimum
+// @Tags maintenance
+// @Produce json
+// @Param status query string false "OPEN (default), ORDERED, RECEIVED or DISMISSED"
+// @Success 200 {array} models.PurchaseOrderSuggestion
+// @Router /maintenance/purchase-suggestions [get]
+func (h *MaintenanceHandler) GetPurchaseSuggestions(c *gin.Context) {
+	suggestions, err := h.maintenanceService.GetPurchaseOrderSuggestions(c.Request.Context(), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get purchase suggestions: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// UpdatePurchaseSuggestion handles marking a suggestion ordered or dismissed
+// @Summary Update a purchase-order suggestion
+// @Description Marks a suggestion ordered or dismissed; restocking the item closes it as received (admin only)
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Param id path int true "Suggestion ID"
+// @Param update body dto.UpdatePurchaseSuggestionRequest true "Status"
+// @Success 200 {object} models.PurchaseOrderSuggestion
+// @Failure 400 {object} map[string]string
+// @Router /maintenance/purchase-suggestions/{id} [put]
+func (h *MaintenanceHandler) UpdatePurchaseSuggestion(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.UpdatePurchaseSuggestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	suggestion, err := h.maintenanceService.UpdatePurchaseOrderSuggestion(c.Request.Context(), id,
+		models.PurchaseOrderSuggestionStatus(req.Status), currentUserRef(c))
+	if err != nil {
+		workOrderItemError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestion)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetMaintenancePredictions handles listing component failure forecasts
+// @Summary List predicted component failures
+// @Description Returns failure forecasts built from telemetry trends, recurring fault codes and fuel-efficiency degradation, riskiest first
+// @Tags maintenance
+// @Produce json
+// @Param vehicle_id query int false "Vehicle ID"
+// @Param status query string false "ACTIVE (default), CLEARED, RESOLVED or ALL"
+// @Param risk_level query string false "LOW, MEDIUM, HIGH or CRITICAL"
+// @Param component query string false "Component, e.g. COOLING_SYSTEM"
+// @Success 200 {array} models.MaintenancePrediction
+// @Failure 400 {object} map[string]string
+// @Router /maintenance/predictions [get]
+func (h *MaintenanceHandler) GetMaintenancePredictions(c *gin.Context) {
+	filters := map[string]interface{}{
+		"status":     string(models.MaintenancePredictionActive),
+		"risk_level": c.Query("risk_level"),
+		"component":  c.Query("component"),
+	}
+	if status := c.Query("status"); status == "ALL" {
+		delete(filters, "status")
+	} else if status != "" {
+		filters["status"] = status
+	}
+	if vehicleIDStr := c.Query("vehicle_id"); vehicleIDStr != "" {
+		vehicleID, err := strconv.ParseUint(vehicleIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle ID"})
+			return
+		}
+		filters["vehicle_id"] = uint(vehicleID)
+	}
+
+	predictions, err := h.maintenanceService.GetMaintenancePredictions(c.Request.Context(), filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get maintenance predictions: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, predictions)
+}
+
+// RunMaintenanceForecast handles running the failure forecast on demand
+// @Summary Run the predictive maintenance forecast
+// @Description Re-scores every active vehicle, or one vehicle, recommends work orders for high-risk components and publishes vehicle_maintenance_due events (admin only)
+// @Tags maintenance
+// @Produce json
+// @Param vehicle_id query int false "Vehicle ID"
+// @Success 200 {object} services.MaintenanceForecastResult
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /maintenance/predictions/run [post]
+func (h *MaintenanceHandler) RunMaintenanceForecast(c *gin.Context) {
+	var vehicleID *uint
+	if vehicleIDStr := c.Query("vehicle_id"); vehicleIDStr != "" {
+		id, err := strconv.ParseUint(vehicleIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle ID"})
+			return
+		}
+		v := uint(id)
+		vehicleID = &v
+	}
+
+	result, err := h.maintenanceService.RunMaintenanceForecast(c.Request.Context(), vehicleID, time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run maintenance forecast: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/fleetflow/backend/internal/middleware"
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// serviceScheduleError maps schedule and template errors onto responses
+func serviceScheduleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidServiceSchedule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save service schedule: " + err.Error()})
+	}
+}
+
+// GetServiceSchedules handles listing service schedules
+// @Summary List service schedules
+// @Description Returns active service schedules with their mileage, engine-hour and calendar limits
+// @Tags maintenance
+// @Produce json
+// @Param vehicle_id query int false "Vehicle ID"
+// @Success 200 {array} models.ServiceSchedule
+// @Failure 400 {object} map[string]string
+// @Router /maintenance/schedules [get]
+func (h *MaintenanceHandler) GetServiceSchedules(c *gin.Context) {
+	var vehicleID uint64
+	if vehicleIDStr := c.Query("vehicle_id"); vehicleIDStr != "" {
+		var err error
+		if vehicleID, err = strconv.ParseUint(vehicleIDStr, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle ID"})
+			return
+		}
+	}
+
+	schedules, err := h.maintenanceService.GetServiceSchedules(c.Request.Context(), uint(vehicleID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get service schedules: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedules)
+}
+
+// CreateServiceSchedule handles scheduling a maintenance task for a vehicle
+// @Summary Create a service schedule
+// @Description Schedules a task by mileage, months and/or engine hours, whichever comes first (admin only)
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Param schedule body dto.CreateServiceScheduleRequest true "Schedule"
+// @Success 201 {object} models.ServiceSchedule
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /maintenance/schedules [post]
+func (h *MaintenanceHandler) CreateServiceSchedule(c *gin.Context) {
+	var req dto.CreateServiceScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule := &models.ServiceSchedule{
+		VehicleID:           req.VehicleID,
+		MaintenanceTaskID:   req.MaintenanceTaskID,
+		IntervalMileage:     req.IntervalMileage,
+		IntervalMonths:      req.IntervalMonths,
+		IntervalEngineHours: req.IntervalEngineHours,
+		DueSoonMileage:      req.DueSoonMileage,
+		DueSoonDays:         req.DueSoonDays,
+		DueSoonEngineHours:  req.DueSoonEngineHours,
+	}
+	if err := h.maintenanceService.CreateServiceSchedule(c.Request.Context(), schedule); err != nil {
+		serviceScheduleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// RunServiceScheduler handles running the service scheduler on demand
+// @Summary Run the service scheduler
+// @Description Records due states, opens work orders for due services and notifies fleet managers (admin only)
+// @Tags maintenance
+// @Produce json
+// @Success 200 {object} services.ServiceSchedulerResult
+// @Router /maintenance/schedules/run [post]
+func (h *MaintenanceHandler) RunServiceScheduler(c *gin.Context) {
+	result, err := h.maintenanceService.RunServiceScheduler(c.Request.Context(), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run service scheduler: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetServiceScheduleTemplates handles listing schedule templates
+// @Summary List service schedule templates
+// @Description Returns the standard service schedules applied to new vehicles of each type
+// @Tags maintenance
+// @Produce json
+// @Param vehicle_type query string false "Vehicle type"
+// @Success 200 {array} models.ServiceScheduleTemplate
+// @Router /maintenance/schedule-templates [get]
+func (h *MaintenanceHandler) GetServiceScheduleTemplates(c *gin.Context) {
+	templates, err := h.maintenanceService.GetServiceScheduleTemplates(c.Request.Context(), c.Query("vehicle_type"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get schedule templates: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// SaveServiceScheduleTemplate handles creating or updating the template for a vehicle type and task
+// @Summary Save a service schedule template
+// @Description Sets the standard schedule of a task for a vehicle type; it applies to vehicles created afterwards (admin only)
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Param template body dto.ServiceScheduleTemplateRequest true "Template"
+// @Success 200 {object} models.ServiceScheduleTemplate
+// @Failure 400 {object} map[string]string
+// @Router /maintenance/schedule-templates [put]
+func (h *MaintenanceHandler) SaveServiceScheduleTemplate(c *gin.Context) {
+	var req dto.ServiceScheduleTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template := &models.ServiceScheduleTemplate{
+		VehicleType:         models.VehicleType(req.VehicleType),
+		MaintenanceTaskID:   req.MaintenanceTaskID,
+		IntervalMileage:     req.IntervalMileage,
+		IntervalMonths:      req.IntervalMonths,
+		IntervalEngineHours: req.IntervalEngineHours,
+		DueSoonMileage:      req.DueSoonMileage,
+		DueSoonDays:         req.DueSoonDays,
+		DueSoonEngineHours:  req.DueSoonEngineHours,
+		IsActive:            req.IsActive == nil || *req.IsActive,
+	}
+	if userID, ok := middleware.GetCurrentUserID(c); ok {
+		template.CreatedBy = &userID
+	}
+
+	saved, err := h.maintenanceService.SaveServiceScheduleTemplate(c.Request.Context(), template)
+	if err != nil {
+		serviceScheduleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/fleetflow/backend/internal/models"
 	"github.com/fleetflow/backend/internal/services"
@@ -45,6 +46,12 @@ func (h *OrganizationHandler) RegisterOrganization(c *gin.Context) {
 		ContactPhone: req.AdminPhone,
 	}
 
+	// Enforce the configured password policy
+	if err := h.authService.ValidatePasswordPolicy(req.AdminPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Hash the admin password using bcrypt
 	hashedPassword, err := h.authService.HashPassword(req.AdminPassword)
 	if err != nil {
@@ -55,7 +62,7 @@ func (h *OrganizationHandler) RegisterOrganization(c *gin.Context) {
 	// Create admin user account
 	adminUser := models.UserAccount{
 		Phone:    req.AdminPhone,
-		Email:    req.AdminEmail,
+		Email:    strings.ToLower(req.AdminEmail),
 		Password: hashedPassword,
 		Role:     models.RoleAdmin,
 		IsActive: true,
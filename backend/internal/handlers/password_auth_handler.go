@@ -0,0 +1,509 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/fleetflow/backend/internal/middleware"
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordLogin authenticates a web user with email and password
+// @Summary Password Login
+// @Description Log in with email and password. Returns tokens, or an MFA challenge when TOTP is enabled
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.PasswordLoginRequest true "Email and password"
+// @Success 200 {object} dto.VerifyOTPResponse
+// @Success 202 {object} dto.TwoFactorChallengeResponse
+// @Failure 400 {object} dto.APIError
+// @Failure 401 {object} dto.APIError
+// @Failure 423 {object} dto.APIError
+// @Router /auth/password/login [post]
+func (h *AuthHandler) PasswordLogin(c *gin.Context) {
+	var req dto.PasswordLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+			Details: map[string]string{"validation": err.Error()},
+		})
+		return
+	}
+
+	identifier := strings.ToLower(strings.TrimSpace(req.Email))
+	if h.rejectIfRiskBlocked(c, identifier) {
+		return
+	}
+
+	result, err := h.services.AuthService.LoginWithPassword(req.Email, req.Password, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		if assessment := h.assessRisk(c, models.SecurityEventLoginAttempt, identifier, nil, false); assessment.Blocked {
+			respondRiskBlocked(c, assessment.BlockedUntil)
+			return
+		}
+		respondLoginError(c, err)
+		return
+	}
+
+	if result.MFARequired {
+		mfaToken, err := h.services.JWTService.GenerateMFAToken(result.User.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, dto.APIError{
+				Error:   "token_generation_failed",
+				Message: "Failed to generate MFA token",
+				Code:    http.StatusInternalServerError,
+			})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, dto.TwoFactorChallengeResponse{
+			Message:     "Two-factor authentication required",
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			ExpiresIn:   int(h.services.JWTService.MFATokenExpiry().Seconds()),
+		})
+		return
+	}
+
+	if assessment := h.assessRisk(c, models.SecurityEventLoginAttempt, identifier, &result.User.ID, true); assessment.Blocked {
+		respondRiskBlocked(c, assessment.BlockedUntil)
+		return
+	}
+
+	h.respondWithLoginTokens(c, result.User, "password", result.TwoFactorSetupRequired)
+}
+
+// VerifyTwoFactor completes a password login with a TOTP or recovery code
+// @Summary Verify Two-Factor Code
+// @Description Exchange an MFA challenge token and a TOTP or recovery code for access tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.TwoFactorVerifyRequest true "MFA token and code"
+// @Success 200 {object} dto.VerifyOTPResponse
+// @Failure 400 {object} dto.APIError
+// @Failure 401 {object} dto.APIError
+// @Failure 423 {object} dto.APIError
+// @Router /auth/password/verify-2fa [post]
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req dto.TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	userID, err := h.services.JWTService.ValidateMFAToken(req.MFAToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.APIError{
+			Error:   "mfa_token_invalid",
+			Message: "Invalid or expired MFA token",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
+	// Second-factor attempts are scored against the account's email so code guessing trips the brute-force factor
+	identifier := ""
+	if account, err := h.services.AuthService.GetUserByID(userID); err == nil {
+		identifier = strings.ToLower(account.Email)
+	}
+	if h.rejectIfRiskBlocked(c, identifier) {
+		return
+	}
+
+	user, err := h.services.AuthService.VerifySecondFactor(userID, req.Code, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		if assessment := h.assessRisk(c, models.SecurityEventMFAVerify, identifier, &userID, false); assessment.Blocked {
+			respondRiskBlocked(c, assessment.BlockedUntil)
+			return
+		}
+		respondLoginError(c, err)
+		return
+	}
+
+	if assessment := h.assessRisk(c, models.SecurityEventMFAVerify, identifier, &user.ID, true); assessment.Blocked {
+		respondRiskBlocked(c, assessment.BlockedUntil)
+		return
+	}
+
+	h.respondWithLoginTokens(c, user, "password", false)
+}
+
+// ChangePassword sets or changes the current user's password
+// @Summary Change Password
+// @Description Change the current user's password. The current password is required once one has been set
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.APIError
+// @Failure 401 {object} dto.APIError
+// @Security BearerAuth
+// @Router /auth/password [put]
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, dto.APIError{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	if err := h.services.AuthService.ChangePassword(userID, req.CurrentPassword, req.NewPassword); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCredentials):
+			c.JSON(http.StatusUnauthorized, dto.APIError{
+				Error:   "invalid_password",
+				Message: "Current password is incorrect",
+				Code:    http.StatusUnauthorized,
+			})
+		case errors.Is(err, services.ErrPasswordPolicy):
+			c.JSON(http.StatusBadRequest, dto.APIError{
+				Error:   "password_policy_violation",
+				Message: err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, dto.APIError{
+				Error:   "password_change_failed",
+				Message: "Failed to change password",
+				Code:    http.StatusInternalServerError,
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{
+		Message: "Password changed successfully",
+	})
+}
+
+// VerifyEmail confirms a new email address
+// @Summary Verify Email
+// @Description Confirm a pending email address with the token sent to it, making it the account's email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.VerifyEmailRequest true "Verification token"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.APIError
+// @Failure 409 {object} dto.APIError
+// @Router /auth/email/verify [post]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req dto.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	if _, err := h.services.AuthService.VerifyEmail(req.Token); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidEmailVerification):
+			c.JSON(http.StatusBadRequest, dto.APIError{
+				Error:   "invalid_token",
+				Message: err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+		case errors.Is(err, services.ErrEmailInUse):
+			c.JSON(http.StatusConflict, dto.APIError{
+				Error:   "email_in_use",
+				Message: err.Error(),
+				Code:    http.StatusConflict,
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, dto.APIError{
+				Error:   "email_verification_failed",
+				Message: "Failed to verify email",
+				Code:    http.StatusInternalServerError,
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{
+		Message: "Email verified successfully",
+	})
+}
+
+// SetupTOTP starts TOTP enrollment for the current user
+// @Summary Set Up TOTP
+// @Description Generate a TOTP secret and provisioning URI. 2FA is enabled after confirming a code
+// @Tags auth
+// @Produce json
+// @Success 200 {object} dto.TOTPSetupResponse
+// @Failure 400 {object} dto.APIError
+// @Failure 401 {object} dto.APIError
+// @Security BearerAuth
+// @Router /auth/2fa/totp/setup [post]
+func (h *AuthHandler) SetupTOTP(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, dto.APIError{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
+	setup, err := h.services.AuthService.SetupTOTP(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "totp_setup_failed",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.TOTPSetupResponse{
+		Secret:          setup.Secret,
+		ProvisioningURI: setup.ProvisioningURI,
+	})
+}
+
+// EnableTOTP confirms TOTP enrollment and returns recovery codes
+// @Summary Enable TOTP
+// @Description Confirm TOTP enrollment with a code from the authenticator app
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.TOTPCodeRequest true "TOTP code"
+// @Success 200 {object} dto.RecoveryCodesResponse
+// @Failure 400 {object} dto.APIError
+// @Failure 401 {object} dto.APIError
+// @Security BearerAuth
+// @Router /auth/2fa/totp/enable [post]
+func (h *AuthHandler) EnableTOTP(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, dto.APIError{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
+	var req dto.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	codes, err := h.services.AuthService.EnableTOTP(userID, req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "totp_enable_failed",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{
+		Message:       "Two-factor authentication enabled. Store these recovery codes in a safe place",
+		RecoveryCodes: codes,
+	})
+}
+
+// DisableTOTP turns off TOTP for the current user
+// @Summary Disable TOTP
+// @Description Disable two-factor authentication after confirming the password
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.DisableTOTPRequest true "Current password"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.APIError
+// @Failure 401 {object} dto.APIError
+// @Security BearerAuth
+// @Router /auth/2fa/totp/disable [post]
+func (h *AuthHandler) DisableTOTP(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, dto.APIError{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
+	var req dto.DisableTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	if err := h.services.AuthService.DisableTOTP(userID, req.Password); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			status = http.StatusUnauthorized
+		}
+		c.JSON(status, dto.APIError{
+			Error:   "totp_disable_failed",
+			Message: err.Error(),
+			Code:    status,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{
+		Message: "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes
+// @Summary Regenerate Recovery Codes
+// @Description Invalidate existing recovery codes and issue new ones
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.TOTPCodeRequest true "TOTP code"
+// @Success 200 {object} dto.RecoveryCodesResponse
+// @Failure 400 {object} dto.APIError
+// @Failure 401 {object} dto.APIError
+// @Security BearerAuth
+// @Router /auth/2fa/recovery-codes [post]
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, dto.APIError{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
+	var req dto.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	codes, err := h.services.AuthService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "recovery_codes_failed",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{
+		Message:       "Store these recovery codes in a safe place",
+		RecoveryCodes: codes,
+	})
+}
+
+// respondWithLoginTokens issues access and refresh tokens and writes the login response
+func (h *AuthHandler) respondWithLoginTokens(c *gin.Context, user *models.UserAccount, authMethod string, twoFactorSetupRequired bool) {
+	session, refreshToken, err := h.services.JWTService.CreateSession(user.ID, sessionContextFromRequest(c, authMethod))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "token_generation_failed",
+			Message: "Failed to generate refresh token",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	accessToken, err := h.services.JWTService.GenerateSessionToken(user, session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "token_generation_failed",
+			Message: "Failed to generate access token",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	// Prepare user info
+	userInfo := &dto.UserInfo{
+		ID:       user.ID,
+		Phone:    user.Phone,
+		Role:     user.Role,
+		IsActive: user.IsActive,
+		DriverID: user.DriverID,
+	}
+
+	// Add driver info if available
+	if user.Driver != nil {
+		userInfo.Driver = &dto.DriverInfo{
+			ID:     user.Driver.ID,
+			Name:   user.Driver.Name,
+			Status: string(user.Driver.Status),
+			Rating: user.Driver.Rating,
+		}
+	}
+
+	c.JSON(http.StatusOK, dto.VerifyOTPResponse{
+		Message:                "Login successful",
+		AccessToken:            accessToken,
+		RefreshToken:           refreshToken.Token,
+		ExpiresIn:              int(h.services.Config.JWTExpirationTime.Seconds()),
+		User:                   userInfo,
+		TwoFactorSetupRequired: twoFactorSetupRequired,
+	})
+}
+
+// respondLoginError maps password/2FA login errors to HTTP responses
+func respondLoginError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAccountLocked):
+		c.JSON(http.StatusLocked, dto.APIError{
+			Error:   "account_locked",
+			Message: err.Error(),
+			Code:    http.StatusLocked,
+		})
+	default:
+		c.JSON(http.StatusUnauthorized, dto.APIError{
+			Error:   "login_failed",
+			Message: err.Error(),
+			Code:    http.StatusUnauthorized,
+		})
+	}
+}
@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/fleetflow/backend/internal/dto"
 	"github.com/fleetflow/backend/internal/models"
 	"github.com/fleetflow/backend/internal/services"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type SafetyHandler struct {
@@ -22,46 +27,408 @@ func NewSafetyHandler(safetyService *services.SafetyService) *SafetyHandler {
 
 // GetSafetyEvents handles fetching safety events
 // @Summary Get safety events
-// @Description List safety events with optional filtering
+// @Description List safety events, newest first, with their linked clip and its detections, filtered by vehicle, driver, trip, type, severity, review state, footage and time
 // @Tags safety
 // @Produce json
 // @Param vehicle_id query int false "Vehicle ID"
-// @Param type query string false "Event Type"
+// @Param driver_id query int false "Driver ID"
+// @Param trip_id query int false "Trip ID"
+// @Param type query string false "Event Type; comma separated for several"
+// @Param severity query string false "Severity (LOW, MEDIUM, HIGH, CRITICAL)"
+// @Param is_viewed query bool false "Review state"
+// @Param has_video query bool false "Only events with (or without) a linked clip"
 // @Param start_date query string false "Start Date (RFC3339)"
 // @Param end_date query string false "End Date (RFC3339)"
-// @Success 200 {array} models.SafetyEvent
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Router /safety/events [get]
 func (h *SafetyHandler) GetSafetyEvents(c *gin.Context) {
-	// This would typically call a service method to query DB with filters
-	// For now, we'll return a mock response or implement basic DB query if service exposed it
-	// In a real implementation, we'd add a GetEvents method to SafetyService
+	var pagination dto.PaginationParams
+	if err := c.ShouldBindQuery(&pagination); err != nil || pagination.Page < 1 || pagination.Limit < 1 || pagination.Limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+
+	var filter services.SafetyEventFilter
+	for param, target := range map[string]**uint{"vehicle_id": &filter.VehicleID, "driver_id": &filter.DriverID, "trip_id": &filter.TripID} {
+		if raw := c.Query(param); raw != "" {
+			id, err := strconv.ParseUint(raw, 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param})
+				return
+			}
+			value := uint(id)
+			*target = &value
+		}
+	}
+	if raw := c.Query("type"); raw != "" {
+		for _, eventType := range strings.Split(raw, ",") {
+			filter.Types = append(filter.Types, models.SafetyEventType(strings.ToUpper(strings.TrimSpace(eventType))))
+		}
+	}
+	filter.Severity = models.SafetyEventSeverity(strings.ToUpper(c.Query("severity")))
+	if raw := c.Query("is_viewed"); raw != "" {
+		viewed, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid is_viewed"})
+			return
+		}
+		filter.IsViewed = &viewed
+	}
+	if raw := c.Query("has_video"); raw != "" {
+		hasVideo, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid has_video"})
+			return
+		}
+		filter.HasVideo = &hasVideo
+	}
+	for param, target := range map[string]**time.Time{"start_date": &filter.From, "end_date": &filter.To} {
+		if raw := c.Query(param); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Dates must be RFC3339"})
+				return
+			}
+			*target = &t
+		}
+	}
+
+	events, total, err := h.safetyService.GetSafetyEvents(c.Request.Context(), filter, pagination.Page, pagination.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":      events,
+		"total":       total,
+		"page":        pagination.Page,
+		"limit":       pagination.Limit,
+		"total_pages": (total + int64(pagination.Limit) - 1) / int64(pagination.Limit),
+	})
+}
+
+// GetSpeedingEpisodes handles fetching speeding episodes
+// @Summary Get speeding episodes
+// @Description List continuous stretches of speeding with their duration, top speed and worst excess over the limit, newest first
+// @Tags safety
+// @Produce json
+// @Param vehicle_id query int false "Vehicle ID"
+// @Param driver_id query int false "Driver ID"
+// @Param trip_id query int false "Trip ID"
+// @Param min_over query number false "Only episodes at least this far over the limit (km/h)"
+// @Param start_date query string false "Start Date (RFC3339)"
+// @Param end_date query string false "End Date (RFC3339)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /safety/speeding-episodes [get]
+func (h *SafetyHandler) GetSpeedingEpisodes(c *gin.Context) {
+	var pagination dto.PaginationParams
+	if err := c.ShouldBindQuery(&pagination); err != nil || pagination.Page < 1 || pagination.Limit < 1 || pagination.Limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+
+	var filter services.SpeedingEpisodeFilter
+	for param, target := range map[string]**uint{"vehicle_id": &filter.VehicleID, "driver_id": &filter.DriverID, "trip_id": &filter.TripID} {
+		if raw := c.Query(param); raw != "" {
+			id, err := strconv.ParseUint(raw, 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param})
+				return
+			}
+			value := uint(id)
+			*target = &value
+		}
+	}
+	if raw := c.Query("min_over"); raw != "" {
+		minOver, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_over"})
+			return
+		}
+		filter.MinOver = minOver
+	}
+	for param, target := range map[string]**time.Time{"start_date": &filter.From, "end_date": &filter.To} {
+		if raw := c.Query(param); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Dates must be RFC3339"})
+				return
+			}
+			*target = &t
+		}
+	}
+
+	episodes, total, err := h.safetyService.GetSpeedingEpisodes(c.Request.Context(), filter, pagination.Page, pagination.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	c.JSON(http.StatusOK, []models.SafetyEvent{})
+	c.JSON(http.StatusOK, gin.H{
+		"episodes":    episodes,
+		"total":       total,
+		"page":        pagination.Page,
+		"limit":       pagination.Limit,
+		"total_pages": (total + int64(pagination.Limit) - 1) / int64(pagination.Limit),
+	})
 }
 
 // GetDriverScore handles fetching driver safety score
 // @Summary Get driver safety score
-// @Description Get calculated safety score for a driver
+// @Description Get a driver's safety score over a rolling window, with braking, acceleration, cornering and speeding sub-scores normalised per 100 km and per hour driven, and their fleet percentile
 // @Tags safety
 // @Produce json
 // @Param driver_id query int true "Driver ID"
+// @Param window query int false "Rolling window in days (7, 30 or 90)" default(30)
 // @Success 200 {object} models.DriverScore
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
 // @Router /safety/score [get]
 func (h *SafetyHandler) GetDriverScore(c *gin.Context) {
 	driverIDStr := c.Query("driver_id")
-	_, err := strconv.ParseUint(driverIDStr, 10, 32)
+	driverID, err := strconv.ParseUint(driverIDStr, 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Driver ID"})
 		return
 	}
+	window, ok := scoreWindowQuery(c)
+	if !ok {
+		return
+	}
 
-	// Mock response for now
-	score := models.DriverScore{
-		OverallScore:  95,
-		BrakingScore:  98,
-		AccelScore:    92,
-		SpeedingScore: 95,
+	score, err := h.safetyService.GetDriverScore(c.Request.Context(), uint(driverID), window)
+	if err != nil {
+		scoreError(c, err)
+		return
 	}
 
 	c.JSON(http.StatusOK, score)
 }
+
+// GetFleetDriverScores handles listing driver safety scores across the fleet
+// @Summary Get fleet driver scores
+// @Description Lists drivers' scores for a rolling window, safest first; drivers with too little driving to rank come last
+// @Tags safety
+// @Produce json
+// @Param window query int false "Rolling window in days (7, 30 or 90)" default(30)
+// @Success 200 {array} models.DriverScore
+// @Failure 400 {object} map[string]string
+// @Router /safety/scores [get]
+func (h *SafetyHandler) GetFleetDriverScores(c *gin.Context) {
+	window, ok := scoreWindowQuery(c)
+	if !ok {
+		return
+	}
+
+	scores, err := h.safetyService.GetFleetDriverScores(c.Request.Context(), window)
+	if err != nil {
+		scoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, scores)
+}
+
+// RecalculateDriverScores handles recalculating driver safety scores
+// @Summary Recalculate driver scores
+// @Description Rescores every driver and re-ranks the fleet for a window, or for every window when none is given (admin only)
+// @Tags safety
+// @Produce json
+// @Param window query int false "Rolling window in days (7, 30 or 90)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /safety/scores/recalculate [post]
+func (h *SafetyHandler) RecalculateDriverScores(c *gin.Context) {
+	windows := services.DriverScoreWindows
+	if c.Query("window") != "" {
+		window, ok := scoreWindowQuery(c)
+		if !ok {
+			return
+		}
+		windows = []int{window}
+	}
+
+	scored := make(map[string]int)
+	for _, window := range windows {
+		scores, err := h.safetyService.RecalculateDriverScores(c.Request.Context(), window, time.Now())
+		if err != nil {
+			scoreError(c, err)
+			return
+		}
+		scored[strconv.Itoa(window)] = len(scores)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Driver scores recalculated", "drivers_scored": scored})
+}
+
+// IngestIMU handles a batch of accelerometer and gyro samples
+// @Summary Ingest IMU samples
+// @Description Orients accelerometer and gyro samples to the vehicle frame and detects harsh cornering and impacts; impacts record a sensor trace and raise an emergency alert
+// @Tags telemetry
+// @Accept json
+// @Produce json
+// @Param batch body dto.IMUBatchRequest true "IMU samples"
+// @Success 200 {object} services.IMUAnalysis
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /telemetry/imu [post]
+func (h *SafetyHandler) IngestIMU(c *gin.Context) {
+	var req dto.IMUBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	batch := services.IMUBatch{
+		VehicleID: req.VehicleID,
+		DriverID:  req.DriverID,
+		Frame:     req.Frame,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		Samples:   make([]services.IMUSample, len(req.Samples)),
+	}
+	for i, sample := range req.Samples {
+		batch.Samples[i] = services.IMUSample{
+			Timestamp: sample.Timestamp,
+			AX:        sample.AX,
+			AY:        sample.AY,
+			AZ:        sample.AZ,
+			GX:        sample.GX,
+			GY:        sample.GY,
+			GZ:        sample.GZ,
+			Speed:     sample.Speed,
+		}
+	}
+
+	analysis, err := h.safetyService.IngestIMU(c.Request.Context(), &batch)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidIMUBatch):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
+}
+
+// ResetIMUCalibration handles forgetting a vehicle's sensor mounting
+// @Summary Reset IMU calibration
+// @Description Forgets how a vehicle's accelerometer is mounted so it is learned again, e.g. after the unit is refitted (admin only)
+// @Tags telemetry
+// @Produce json
+// @Param vehicle_id path int true "Vehicle ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /telemetry/imu/calibration/{vehicle_id} [delete]
+func (h *SafetyHandler) ResetIMUCalibration(c *gin.Context) {
+	vehicleID, err := strconv.ParseUint(c.Param("vehicle_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle ID"})
+		return
+	}
+
+	if err := h.safetyService.ResetIMUCalibration(c.Request.Context(), uint(vehicleID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "IMU calibration reset"})
+}
+
+// GetSafetyEvent handles fetching a single safety event
+// @Summary Get safety event
+// @Description Returns a safety event with its driver, vehicle, trip and the linked dashcam clip with its AI detections
+// @Tags safety
+// @Produce json
+// @Param id path int true "Safety event ID"
+// @Success 200 {object} models.SafetyEvent
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /safety/events/{id} [get]
+func (h *SafetyHandler) GetSafetyEvent(c *gin.Context) {
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	event, err := h.safetyService.GetSafetyEvent(c.Request.Context(), uint(eventID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Safety event not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+// GetSafetyEventTrace handles fetching the sensor trace around an impact
+// @Summary Get safety event trace
+// @Description Returns the accelerometer and gyro trace in the vehicle frame recorded before and after an impact
+// @Tags safety
+// @Produce json
+// @Param id path int true "Safety event ID"
+// @Success 200 {object} models.SafetyEventTrace
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /safety/events/{id}/trace [get]
+func (h *SafetyHandler) GetSafetyEventTrace(c *gin.Context) {
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	trace, err := h.safetyService.GetSafetyEventTrace(c.Request.Context(), uint(eventID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No trace recorded for this event"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, trace)
+}
+
+// scoreWindowQuery reads the optional window query parameter; 0 means the default window
+func scoreWindowQuery(c *gin.Context) (int, bool) {
+	raw := c.Query("window")
+	if raw == "" {
+		return 0, true
+	}
+	window, err := strconv.Atoi(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid window"})
+		return 0, false
+	}
+	return window, true
+}
+
+// scoreError maps score window and driver lookup errors onto responses
+func scoreError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidScoreWindow):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Driver not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
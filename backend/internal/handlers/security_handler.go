@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/fleetflow/backend/internal/middleware"
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// rejectIfRiskBlocked writes a 429 response when the identifier or client IP is under a risk block
+func (h *AuthHandler) rejectIfRiskBlocked(c *gin.Context, identifier string) bool {
+	event, err := h.services.RiskService.CheckBlocked(identifier, c.ClientIP())
+	if err != nil || event == nil {
+		return false
+	}
+	respondRiskBlocked(c, event.BlockedUntil)
+	return true
+}
+
+// assessRisk scores an authentication attempt made by the current request
+func (h *AuthHandler) assessRisk(c *gin.Context, eventType, identifier string, userID *uint, success bool) *services.RiskAssessment {
+	sc := sessionContextFromRequest(c, "")
+	attempt := &services.RiskAttempt{
+		EventType:  eventType,
+		Identifier: identifier,
+		UserID:     userID,
+		Success:    success,
+		IPAddress:  sc.IPAddress,
+		UserAgent:  sc.UserAgent,
+		DeviceID:   sc.DeviceID,
+		Location:   sc.Location,
+	}
+
+	assessment, err := h.services.RiskService.Assess(attempt)
+	if err != nil {
+		// Risk scoring must never lock everyone out; fall through to normal handling
+		return &services.RiskAssessment{}
+	}
+	return assessment
+}
+
+// respondRiskBlocked writes the response for an attempt stopped by the risk engine
+func respondRiskBlocked(c *gin.Context, blockedUntil *time.Time) {
+	if blockedUntil != nil {
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(*blockedUntil).Seconds())+1))
+	}
+	c.JSON(http.StatusTooManyRequests, dto.APIError{
+		Error:   "login_blocked",
+		Message: services.ErrLoginBlocked.Error(),
+		Code:    http.StatusTooManyRequests,
+	})
+}
+
+// GetSecurityEvent returns a single security event
+// @Summary Get Security Event
+// @Description Get a security event with its risk factors and investigation status (admin only)
+// @Tags admin
+// @Produce json
+// @Param id path int true "Security event ID"
+// @Success 200 {object} models.SecurityEvent
+// @Failure 400 {object} dto.APIError
+// @Failure 404 {object} dto.APIError
+// @Security BearerAuth
+// @Router /admin/security-events/{id} [get]
+func (h *AnalyticsHandler) GetSecurityEvent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "invalid_id",
+			Message: "Invalid security event ID",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	event, err := h.services.RiskService.GetSecurityEvent(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, dto.APIError{
+			Error:   "not_found",
+			Message: "Security event not found",
+			Code:    http.StatusNotFound,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+// InvestigateSecurityEvent records investigation notes and optionally lifts a block
+// @Summary Investigate Security Event
+// @Description Mark a security event as investigated with notes, optionally lifting its automatic block (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Security event ID"
+// @Param request body dto.InvestigateSecurityEventRequest true "Investigation notes"
+// @Success 200 {object} models.SecurityEvent
+// @Failure 400 {object} dto.APIError
+// @Failure 404 {object} dto.APIError
+// @Security BearerAuth
+// @Router /admin/security-events/{id}/investigate [post]
+func (h *AnalyticsHandler) InvestigateSecurityEvent(c *gin.Context) {
+	adminID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, dto.APIError{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "invalid_id",
+			Message: "Invalid security event ID",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	var req dto.InvestigateSecurityEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+			Details: map[string]string{"validation": err.Error()},
+		})
+		return
+	}
+
+	event, err := h.services.RiskService.Investigate(uint(id), adminID, strings.TrimSpace(req.Notes), req.LiftBlock,
+		&models.AuditContext{UserID: &adminID, IPAddress: c.ClientIP(), UserAgent: c.GetHeader("User-Agent")})
+	if err != nil {
+		c.JSON(http.StatusNotFound, dto.APIError{
+			Error:   "not_found",
+			Message: fmt.Sprintf("Security event %d not found", id),
+			Code:    http.StatusNotFound,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/fleetflow/backend/internal/middleware"
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ListSessions returns the current user's active sessions
+// @Summary List Sessions
+// @Description List the devices the current user is logged in on, with last-used time and location
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.SessionsListResponse
+// @Failure 401 {object} dto.APIError
+// @Router /auth/sessions [get]
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, dto.APIError{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
+	sessions, err := h.services.JWTService.ListSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "sessions_fetch_failed",
+			Message: "Failed to fetch sessions",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	currentID, _ := middleware.GetCurrentSessionID(c)
+	response := dto.SessionsListResponse{Sessions: make([]dto.SessionResponse, 0, len(sessions))}
+	for _, session := range sessions {
+		response.Sessions = append(response.Sessions, dto.SessionResponse{
+			ID:            session.ID,
+			DeviceName:    session.DeviceName,
+			UserAgent:     session.UserAgent,
+			IPAddress:     session.IPAddress,
+			LastIPAddress: session.LastIPAddress,
+			Location:      session.Location,
+			AuthMethod:    session.AuthMethod,
+			CreatedAt:     session.CreatedAt,
+			LastUsedAt:    session.LastUsedAt,
+			ExpiresAt:     session.ExpiresAt,
+			Current:       session.ID == currentID,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeSession logs out one of the current user's sessions
+// @Summary Revoke Session
+// @Description Revoke a session and every refresh token issued to it, e.g. for a lost phone
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Session ID"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.APIError
+// @Failure 404 {object} dto.APIError
+// @Router /auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, dto.APIError{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "invalid_id",
+			Message: "Invalid session ID",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	if err := h.services.JWTService.RevokeSession(userID, uint(sessionID), models.SessionRevokedByUser); err != nil {
+		c.JSON(http.StatusNotFound, dto.APIError{
+			Error:   "session_not_found",
+			Message: services.ErrSessionNotFound.Error(),
+			Code:    http.StatusNotFound,
+		})
+		return
+	}
+
+	_ = h.services.AuditService.LogAction(models.AuditActionUserLogout, models.AuditSeverityInfo,
+		fmt.Sprintf("Session %d revoked by user", sessionID), nil, nil, h.auditContext(c))
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Session revoked"})
+}
+
+// RevokeOtherSessions logs out every session except the current one
+// @Summary Revoke Other Sessions
+// @Description Revoke all of the current user's sessions except the one making the request
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 401 {object} dto.APIError
+// @Router /auth/sessions [delete]
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, dto.APIError{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
+	currentID, _ := middleware.GetCurrentSessionID(c)
+	count, err := h.services.JWTService.RevokeOtherSessions(userID, currentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.APIError{
+			Error:   "revocation_failed",
+			Message: "Failed to revoke sessions",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	_ = h.services.AuditService.LogAction(models.AuditActionUserLogout, models.AuditSeverityInfo,
+		fmt.Sprintf("%d other sessions revoked by user", count), nil, nil, h.auditContext(c))
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{
+		Message: "Other sessions revoked",
+		Data:    gin.H{"revoked": count},
+	})
+}
+
+// sessionContextFromRequest collects the device details a session is bound to.
+// Location comes from edge geo headers when the deployment sits behind a CDN that sets them.
+func sessionContextFromRequest(c *gin.Context, authMethod string) *services.SessionContext {
+	location := c.GetHeader("X-Client-Location")
+	if location == "" {
+		parts := make([]string, 0, 2)
+		if city := c.GetHeader("CF-IPCity"); city != "" {
+			parts = append(parts, city)
+		}
+		if country := c.GetHeader("CF-IPCountry"); country != "" && country != "XX" {
+			parts = append(parts, country)
+		}
+		location = strings.Join(parts, ", ")
+	}
+
+	return &services.SessionContext{
+		DeviceID:   c.GetHeader("X-Device-ID"),
+		DeviceName: c.GetHeader("X-Device-Name"),
+		UserAgent:  c.GetHeader("User-Agent"),
+		IPAddress:  c.ClientIP(),
+		Location:   location,
+		AuthMethod: authMethod,
+	}
+}
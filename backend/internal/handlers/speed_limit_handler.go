@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxRoadDataSize bounds an uploaded OpenStreetMap extract
+const maxRoadDataSize = 200 << 20
+
+// SpeedLimitHandler manages road speed limit data, vehicle type overrides and geofence caps
+type SpeedLimitHandler struct {
+	speedLimits *services.SpeedLimitService
+}
+
+// NewSpeedLimitHandler creates a new speed limit handler
+func NewSpeedLimitHandler(speedLimits *services.SpeedLimitService) *SpeedLimitHandler {
+	return &SpeedLimitHandler{
+		speedLimits: speedLimits,
+	}
+}
+
+// GetSpeedLimit handles looking up the speed limit at a position
+// @Summary Get the speed limit at a position
+// @Description Resolves the limit at a position for a vehicle or vehicle type: a geofence cap, else the matched road's posted or class limit, lowered by heavy vehicle limits and vehicle type overrides
+// @Tags safety
+// @Produce json
+// @Param latitude query number true "Latitude"
+// @Param longitude query number true "Longitude"
+// @Param vehicle_id query int false "Vehicle ID"
+// @Param vehicle_type query string false "Vehicle type, when no vehicle is given"
+// @Success 200 {object} services.SpeedLimit
+// @Failure 400 {object} map[string]string
+// @Router /safety/speed-limits [get]
+func (h *SpeedLimitHandler) GetSpeedLimit(c *gin.Context) {
+	latitude, errLat := strconv.ParseFloat(c.Query("latitude"), 64)
+	longitude, errLon := strconv.ParseFloat(c.Query("longitude"), 64)
+	if errLat != nil || errLon != nil || latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid latitude or longitude"})
+		return
+	}
+
+	var limit *services.SpeedLimit
+	var err error
+	if raw := c.Query("vehicle_id"); raw != "" {
+		vehicleID, parseErr := strconv.ParseUint(raw, 10, 32)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle_id"})
+			return
+		}
+		limit, err = h.speedLimits.LimitForVehicle(c.Request.Context(), uint(vehicleID), latitude, longitude)
+	} else {
+		vehicleType := models.VehicleType(strings.ToUpper(c.Query("vehicle_type")))
+		limit, err = h.speedLimits.LimitAt(c.Request.Context(), latitude, longitude, vehicleType)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, limit)
+}
+
+// ImportRoadData handles importing OpenStreetMap road data
+// @Summary Import road speed limits
+// @Description Imports the drivable ways and maxspeed tags of an OpenStreetMap XML extract; ways already imported are replaced (admin only)
+// @Tags safety
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "OSM XML extract"
+// @Success 200 {object} services.OSMImportResult
+// @Failure 400 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Router /safety/speed-limits/import [post]
+func (h *SpeedLimitHandler) ImportRoadData(c *gin.Context) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An OSM XML file is required"})
+		return
+	}
+	if header.Size > maxRoadDataSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Road data files are limited to 200 MB"})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	result, err := h.speedLimits.ImportOSM(c.Request.Context(), file)
+	if err != nil {
+		speedLimitError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetSpeedLimitOverrides handles listing vehicle type overrides
+// @Summary List speed limit overrides
+// @Description Lists the speed caps set for vehicle types, by road class or on every road
+// @Tags safety
+// @Produce json
+// @Success 200 {array} models.SpeedLimitOverride
+// @Router /safety/speed-limits/overrides [get]
+func (h *SpeedLimitHandler) GetSpeedLimitOverrides(c *gin.Context) {
+	overrides, err := h.speedLimits.GetOverrides(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, overrides)
+}
+
+// SaveSpeedLimitOverride handles setting a vehicle type override
+// @Summary Set a speed limit override
+// @Description Caps the limit for a vehicle type on a road class, or on every road; replaces any cap for the same type and class (admin only)
+// @Tags safety
+// @Accept json
+// @Produce json
+// @Param override body dto.SpeedLimitOverrideRequest true "Override"
+// @Success 200 {object} models.SpeedLimitOverride
+// @Failure 400 {object} map[string]string
+// @Router /safety/speed-limits/overrides [put]
+func (h *SpeedLimitHandler) SaveSpeedLimitOverride(c *gin.Context) {
+	var req dto.SpeedLimitOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	override := &models.SpeedLimitOverride{
+		VehicleType: models.VehicleType(req.VehicleType),
+		Highway:     strings.ToLower(req.Highway),
+		MaxSpeed:    req.MaxSpeed,
+	}
+	if err := h.speedLimits.SaveOverride(c.Request.Context(), override); err != nil {
+		speedLimitError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, override)
+}
+
+// DeleteSpeedLimitOverride handles removing a vehicle type override
+// @Summary Delete a speed limit override
+// @Description Removes a vehicle type speed cap (admin only)
+// @Tags safety
+// @Param id path int true "Override ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /safety/speed-limits/overrides/{id} [delete]
+func (h *SpeedLimitHandler) DeleteSpeedLimitOverride(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.speedLimits.DeleteOverride(c.Request.Context(), id); err != nil {
+		speedLimitError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Speed limit override deleted"})
+}
+
+// SetGeofenceSpeedLimit handles setting a geofence's speed cap
+// @Summary Set a geofence speed cap
+// @Description Sets the speed cap inside a geofence, which takes precedence over road limits; null removes it (admin only)
+// @Tags safety
+// @Accept json
+// @Produce json
+// @Param id path int true "Geofence ID"
+// @Param cap body dto.GeofenceSpeedLimitRequest true "Speed cap"
+// @Success 200 {object} models.Geofence
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /safety/speed-limits/geofences/{id} [put]
+func (h *SpeedLimitHandler) SetGeofenceSpeedLimit(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.GeofenceSpeedLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	geofence, err := h.speedLimits.SetGeofenceSpeedLimit(c.Request.Context(), id, req.SpeedLimit)
+	if err != nil {
+		speedLimitError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, geofence)
+}
+
+// speedLimitError maps speed limit validation and lookup errors onto responses
+func speedLimitError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidSpeedLimit):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
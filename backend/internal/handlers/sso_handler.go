@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/fleetflow/backend/internal/middleware"
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// StartSSOLogin begins an OpenID Connect login for an organization
+// @Summary Start SSO Login
+// @Description Start an authorization code flow with PKCE. The client navigates to authorization_url and posts the returned code and state to /auth/sso/callback
+// @Tags auth
+// @Produce json
+// @Param org_code path string true "Organization code"
+// @Success 200 {object} services.SSOLogin
+// @Failure 404 {object} dto.APIError
+// @Failure 502 {object} dto.APIError
+// @Router /auth/sso/{org_code}/login [get]
+func (h *AuthHandler) StartSSOLogin(c *gin.Context) {
+	login, err := h.services.SSOService.BeginLogin(c.Request.Context(), c.Param("org_code"))
+	if err != nil {
+		if errors.Is(err, services.ErrSSONotConfigured) {
+			c.JSON(http.StatusNotFound, dto.APIError{
+				Error:   "sso_not_configured",
+				Message: err.Error(),
+				Code:    http.StatusNotFound,
+			})
+			return
+		}
+		c.JSON(http.StatusBadGateway, dto.APIError{
+			Error:   "sso_provider_error",
+			Message: "Failed to contact identity provider",
+			Code:    http.StatusBadGateway,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, login)
+}
+
+// SSOCallback completes an OpenID Connect login
+// @Summary Complete SSO Login
+// @Description Exchange the authorization code for FleetFlow access and refresh tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.SSOCallbackRequest true "Authorization code and state"
+// @Success 200 {object} dto.VerifyOTPResponse
+// @Failure 400 {object} dto.APIError
+// @Failure 401 {object} dto.APIError
+// @Failure 403 {object} dto.APIError
+// @Router /auth/sso/callback [post]
+func (h *AuthHandler) SSOCallback(c *gin.Context) {
+	var req dto.SSOCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+			Details: map[string]string{"validation": err.Error()},
+		})
+		return
+	}
+
+	user, err := h.services.SSOService.CompleteLogin(c.Request.Context(), req.State, req.Code, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSSOInvalidState):
+			c.JSON(http.StatusBadRequest, dto.APIError{
+				Error:   "invalid_state",
+				Message: err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+		case errors.Is(err, services.ErrSSODomainNotAllowed),
+			errors.Is(err, services.ErrSSOAccountNotFound),
+			errors.Is(err, services.ErrSSOAccountConflict),
+			errors.Is(err, services.ErrSSOLinkRequired),
+			errors.Is(err, services.ErrSSOAccountDisabled):
+			c.JSON(http.StatusForbidden, dto.APIError{
+				Error:   "sso_access_denied",
+				Message: err.Error(),
+				Code:    http.StatusForbidden,
+			})
+		default:
+			c.JSON(http.StatusUnauthorized, dto.APIError{
+				Error:   "sso_login_failed",
+				Message: "Single sign-on failed",
+				Code:    http.StatusUnauthorized,
+			})
+		}
+		return
+	}
+
+	h.respondWithLoginTokens(c, user, "sso", false)
+}
+
+// GetSSOConfig returns the OIDC settings of the current user's organization
+// @Summary Get SSO Configuration
+// @Description Get the OpenID Connect settings of the caller's organization
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.OIDCConfig
+// @Failure 404 {object} dto.APIError
+// @Router /organizations/me/sso [get]
+func (h *AuthHandler) GetSSOConfig(c *gin.Context) {
+	orgID, ok := h.currentOrganizationID(c)
+	if !ok {
+		return
+	}
+
+	cfg, err := h.services.SSOService.GetConfig(orgID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, dto.APIError{
+			Error:   "sso_not_configured",
+			Message: err.Error(),
+			Code:    http.StatusNotFound,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, cfg)
+}
+
+// UpdateSSOConfig creates or replaces the OIDC settings of the current user's organization
+// @Summary Update SSO Configuration
+// @Description Configure OpenID Connect single sign-on. The issuer's discovery document must be reachable
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.OIDCConfigRequest true "OIDC settings"
+// @Success 200 {object} models.OIDCConfig
+// @Failure 400 {object} dto.APIError
+// @Router /organizations/me/sso [put]
+func (h *AuthHandler) UpdateSSOConfig(c *gin.Context) {
+	orgID, ok := h.currentOrganizationID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.OIDCConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+			Details: map[string]string{"validation": err.Error()},
+		})
+		return
+	}
+
+	cfg := &models.OIDCConfig{
+		Issuer:              req.Issuer,
+		ClientID:            req.ClientID,
+		ClientSecret:        req.ClientSecret,
+		RedirectURL:         req.RedirectURL,
+		Scopes:              req.Scopes,
+		RoleClaim:           req.RoleClaim,
+		RoleMapping:         req.RoleMapping,
+		DefaultRole:         req.DefaultRole,
+		AllowedEmailDomains: req.AllowedEmailDomains,
+		AutoProvision:       req.AutoProvision == nil || *req.AutoProvision,
+		Enabled:             req.Enabled == nil || *req.Enabled,
+	}
+
+	saved, err := h.services.SSOService.SaveConfig(c.Request.Context(), orgID, cfg, h.auditContext(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "sso_config_invalid",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
+
+// DeleteSSOConfig removes the OIDC settings of the current user's organization
+// @Summary Delete SSO Configuration
+// @Description Disable OpenID Connect single sign-on for the caller's organization
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 404 {object} dto.APIError
+// @Router /organizations/me/sso [delete]
+func (h *AuthHandler) DeleteSSOConfig(c *gin.Context) {
+	orgID, ok := h.currentOrganizationID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.services.SSOService.DeleteConfig(orgID, h.auditContext(c)); err != nil {
+		c.JSON(http.StatusNotFound, dto.APIError{
+			Error:   "sso_not_configured",
+			Message: err.Error(),
+			Code:    http.StatusNotFound,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{Message: "SSO configuration removed"})
+}
+
+// LinkSSOIdentity links an IdP identity to an account of the current user's organization
+// @Summary Link SSO Identity
+// @Description Link an identity at the organization's identity provider to an existing account, for accounts SSO could not link by verified email
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.LinkSSOIdentityRequest true "Account and IdP subject"
+// @Success 201 {object} models.UserIdentity
+// @Failure 400 {object} dto.APIError
+// @Failure 404 {object} dto.APIError
+// @Failure 409 {object} dto.APIError
+// @Router /organizations/me/sso/identities [post]
+func (h *AuthHandler) LinkSSOIdentity(c *gin.Context) {
+	orgID, ok := h.currentOrganizationID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.LinkSSOIdentityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "validation_failed",
+			Message: "Invalid request data",
+			Code:    http.StatusBadRequest,
+			Details: map[string]string{"validation": err.Error()},
+		})
+		return
+	}
+
+	identity, err := h.services.SSOService.LinkIdentity(orgID, req.UserID, req.Subject, h.auditContext(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSSONotConfigured), errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, dto.APIError{
+				Error:   "not_found",
+				Message: "SSO is not configured or the user is not in this organization",
+				Code:    http.StatusNotFound,
+			})
+		case errors.Is(err, services.ErrSSOIdentityLinked):
+			c.JSON(http.StatusConflict, dto.APIError{
+				Error:   "identity_linked",
+				Message: err.Error(),
+				Code:    http.StatusConflict,
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, dto.APIError{
+				Error:   "sso_link_failed",
+				Message: "Failed to link identity",
+				Code:    http.StatusInternalServerError,
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, identity)
+}
+
+// currentOrganizationID resolves the caller's organization, writing an error response when there is none
+func (h *AuthHandler) currentOrganizationID(c *gin.Context) (uint, bool) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, dto.APIError{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+			Code:    http.StatusUnauthorized,
+		})
+		return 0, false
+	}
+
+	user, err := h.services.AuthService.GetUserByID(userID)
+	if err != nil || user.OrganizationID == nil {
+		c.JSON(http.StatusBadRequest, dto.APIError{
+			Error:   "no_organization",
+			Message: "User does not belong to an organization",
+			Code:    http.StatusBadRequest,
+		})
+		return 0, false
+	}
+	return *user.OrganizationID, true
+}
+
+func (h *AuthHandler) auditContext(c *gin.Context) *models.AuditContext {
+	auditCtx := &models.AuditContext{IPAddress: c.ClientIP(), UserAgent: c.GetHeader("User-Agent")}
+	if userID, ok := middleware.GetCurrentUserID(c); ok {
+		auditCtx.UserID = &userID
+	}
+	return auditCtx
+}
@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/fleetflow/backend/internal/dto"
 	"github.com/fleetflow/backend/internal/models"
 	"github.com/fleetflow/backend/internal/services"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type TelemetryHandler struct {
@@ -22,41 +26,315 @@ func NewTelemetryHandler(telemetryService *services.TelemetryService) *Telemetry
 
 // GetLatestTelemetry handles fetching the latest telemetry for a vehicle
 // @Summary Get latest telemetry
-// @Description Get the most recent sensor data for a vehicle
+// @Description Get the most recent sensor data for a vehicle with its health score
 // @Tags telemetry
 // @Produce json
 // @Param vehicle_id query int true "Vehicle ID"
-// @Success 200 {object} models.TelemetryLog
+// @Success 200 {object} services.LatestTelemetry
+// @Failure 404 {object} map[string]string
 // @Router /telemetry/latest [get]
 func (h *TelemetryHandler) GetLatestTelemetry(c *gin.Context) {
 	vehicleIDStr := c.Query("vehicle_id")
-	_, err := strconv.ParseUint(vehicleIDStr, 10, 32)
+	vehicleID, err := strconv.ParseUint(vehicleIDStr, 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Vehicle ID"})
 		return
 	}
 
-	// In a real implementation, we'd call h.telemetryService.GetLatest(vehicleID)
-	// For now, returning a mock or empty response to satisfy the interface
-	c.JSON(http.StatusOK, models.TelemetryLog{})
+	latest, err := h.telemetryService.GetLatestTelemetry(c.Request.Context(), uint(vehicleID))
+	if err != nil {
+		dtcError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, latest)
+}
+
+// GetTelemetryAnomalies handles listing a vehicle's telemetry anomalies
+// @Summary Get telemetry anomalies
+// @Description Lists readings outside thresholds, changing too fast or far from the vehicle's baseline
+// @Tags telemetry
+// @Produce json
+// @Param vehicle_id query int true "Vehicle ID"
+// @Param include_resolved query bool false "Include resolved anomalies"
+// @Success 200 {array} models.TelemetryAnomaly
+// @Router /telemetry/anomalies [get]
+func (h *TelemetryHandler) GetTelemetryAnomalies(c *gin.Context) {
+	vehicleID, err := strconv.ParseUint(c.Query("vehicle_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Vehicle ID"})
+		return
+	}
+
+	anomalies, err := h.telemetryService.GetTelemetryAnomalies(c.Request.Context(), uint(vehicleID), c.Query("include_resolved") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get anomalies: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, anomalies)
+}
+
+// GetTelemetryThresholds handles listing telemetry threshold rules
+// @Summary Get telemetry thresholds
+// @Description Lists configured rules, or the rules in effect for a vehicle including built-in defaults
+// @Tags telemetry
+// @Produce json
+// @Param vehicle_id query int false "Vehicle ID"
+// @Success 200 {array} models.TelemetryThreshold
+// @Router /telemetry/thresholds [get]
+func (h *TelemetryHandler) GetTelemetryThresholds(c *gin.Context) {
+	var vehicleID *uint
+	if raw := c.Query("vehicle_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Vehicle ID"})
+			return
+		}
+		vid := uint(id)
+		vehicleID = &vid
+	}
+
+	rules, err := h.telemetryService.GetTelemetryThresholds(c.Request.Context(), vehicleID)
+	if err != nil {
+		dtcError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// SaveTelemetryThreshold handles setting a telemetry threshold rule
+// @Summary Set a telemetry threshold
+// @Description Creates or replaces a signal's limits and rate-of-change rules fleet-wide or for a vehicle make and model (admin only)
+// @Tags telemetry
+// @Accept json
+// @Produce json
+// @Param threshold body dto.TelemetryThresholdRequest true "Threshold"
+// @Success 200 {object} models.TelemetryThreshold
+// @Failure 400 {object} map[string]string
+// @Router /telemetry/thresholds [put]
+func (h *TelemetryHandler) SaveTelemetryThreshold(c *gin.Context) {
+	var req dto.TelemetryThresholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.telemetryService.SaveTelemetryThreshold(c.Request.Context(), &models.TelemetryThreshold{
+		VehicleMake:   req.VehicleMake,
+		VehicleModel:  req.VehicleModel,
+		Signal:        models.TelemetrySignal(req.Signal),
+		Min:           req.Min,
+		Max:           req.Max,
+		CriticalMin:   req.CriticalMin,
+		CriticalMax:   req.CriticalMax,
+		MaxRisePerMin: req.MaxRisePerMin,
+		MaxFallPerMin: req.MaxFallPerMin,
+		Severity:      models.DiagnosticCodeSeverity(req.Severity),
+		IsActive:      req.IsActive == nil || *req.IsActive,
+		UpdatedBy:     currentUserRef(c),
+	})
+	if err != nil {
+		dtcError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteTelemetryThreshold handles removing a telemetry threshold rule
+// @Summary Delete a telemetry threshold
+// @Description Removes a configured rule so the next most specific rule applies (admin only)
+// @Tags telemetry
+// @Param id path int true "Threshold ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /telemetry/thresholds/{id} [delete]
+func (h *TelemetryHandler) DeleteTelemetryThreshold(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.telemetryService.DeleteTelemetryThreshold(c.Request.Context(), id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Threshold not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Telemetry threshold deleted"})
+}
+
+// dtcError maps fault code, threshold and vehicle lookup errors onto responses
+func dtcError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidDTC), errors.Is(err, services.ErrInvalidThreshold):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
 
 // GetActiveDTCs handles fetching active diagnostic trouble codes
 // @Summary Get active DTCs
-// @Description Get list of active fault codes for a vehicle
+// @Description Get list of active fault codes for a vehicle, with their descriptions and recommended actions
 // @Tags telemetry
 // @Produce json
 // @Param vehicle_id query int true "Vehicle ID"
+// @Param include_cleared query bool false "Include codes that have cleared"
 // @Success 200 {array} models.DiagnosticCode
 // @Router /telemetry/dtc [get]
 func (h *TelemetryHandler) GetActiveDTCs(c *gin.Context) {
 	vehicleIDStr := c.Query("vehicle_id")
-	_, err := strconv.ParseUint(vehicleIDStr, 10, 32)
+	vehicleID, err := strconv.ParseUint(vehicleIDStr, 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Vehicle ID"})
 		return
 	}
 
-	// Mock response
-	c.JSON(http.StatusOK, []models.DiagnosticCode{})
+	codes, err := h.telemetryService.GetDiagnosticCodes(c.Request.Context(), uint(vehicleID), c.Query("include_cleared") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get diagnostic codes: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, codes)
+}
+
+// ReportDiagnostics handles a vehicle's current fault codes
+// @Summary Report diagnostic codes
+// @Description Records the OBD-II or J1939 codes a vehicle currently reports, as codes and/or a DM1 payload; codes absent from enough reports are cleared
+// @Tags telemetry
+// @Accept json
+// @Produce json
+// @Param report body dto.DiagnosticReportRequest true "Diagnostic report"
+// @Success 200 {object} services.DiagnosticReportResult
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /telemetry/dtc [post]
+func (h *TelemetryHandler) ReportDiagnostics(c *gin.Context) {
+	var req dto.DiagnosticReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report := services.DiagnosticReport{VehicleID: req.VehicleID, Codes: req.Codes, DM1: req.DM1, Timestamp: time.Now()}
+	if req.Codes == nil && req.DM1 == "" {
+		report.Codes = []string{}
+	}
+	if req.Timestamp != nil {
+		report.Timestamp = *req.Timestamp
+	}
+
+	result, err := h.telemetryService.ProcessDiagnostics(c.Request.Context(), &report)
+	if err != nil {
+		dtcError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// DecodeDTC handles describing a fault code or DM1 payload
+// @Summary Decode a DTC
+// @Description Describes an OBD-II code or J1939 SPN/FMI pair, or decodes a DM1 payload into its lamps and faults
+// @Tags telemetry
+// @Produce json
+// @Param code query string false "Fault code, e.g. P0300 or SPN110-FMI0"
+// @Param dm1 query string false "DM1 payload in hex"
+// @Success 200 {object} services.DTCDefinition
+// @Failure 400 {object} map[string]string
+// @Router /telemetry/dtc/decode [get]
+func (h *TelemetryHandler) DecodeDTC(c *gin.Context) {
+	ctx := c.Request.Context()
+	if payload := c.Query("dm1"); payload != "" {
+		message, err := services.DecodeDM1(payload)
+		if err != nil {
+			dtcError(c, err)
+			return
+		}
+		definitions := make([]*services.DTCDefinition, 0, len(message.DTCs))
+		for _, fault := range message.DTCs {
+			definition, err := h.telemetryService.LookupDTC(ctx, fault.Code)
+			if err != nil {
+				dtcError(c, err)
+				return
+			}
+			definitions = append(definitions, definition)
+		}
+		c.JSON(http.StatusOK, gin.H{"lamps": message.Lamps, "dtcs": message.DTCs, "definitions": definitions})
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code or dm1 is required"})
+		return
+	}
+	definition, err := h.telemetryService.LookupDTC(ctx, code)
+	if err != nil {
+		dtcError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, definition)
+}
+
+// GetDTCCatalog handles listing catalogue overrides
+// @Summary List DTC catalogue entries
+// @Description Lists the fleet's fault code descriptions, which take precedence over the built-in catalogue
+// @Tags telemetry
+// @Produce json
+// @Param source query string false "OBDII or J1939"
+// @Param search query string false "Code or description"
+// @Success 200 {array} models.DTCCatalogEntry
+// @Router /telemetry/dtc/catalog [get]
+func (h *TelemetryHandler) GetDTCCatalog(c *gin.Context) {
+	entries, err := h.telemetryService.GetDTCCatalog(c.Request.Context(), c.Query("source"), c.Query("search"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get DTC catalogue: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// SaveDTCCatalogEntry handles describing a fault code
+// @Summary Set a DTC catalogue entry
+// @Description Creates or replaces the description, severity and recommended action of a code or J1939 SPN (admin only)
+// @Tags telemetry
+// @Accept json
+// @Produce json
+// @Param entry body dto.DTCCatalogEntryRequest true "Catalogue entry"
+// @Success 200 {object} models.DTCCatalogEntry
+// @Failure 400 {object} map[string]string
+// @Router /telemetry/dtc/catalog [put]
+func (h *TelemetryHandler) SaveDTCCatalogEntry(c *gin.Context) {
+	var req dto.DTCCatalogEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := h.telemetryService.SaveDTCCatalogEntry(c.Request.Context(), &models.DTCCatalogEntry{
+		Code:              req.Code,
+		Description:       req.Description,
+		Severity:          models.DiagnosticCodeSeverity(req.Severity),
+		RecommendedAction: req.RecommendedAction,
+		Component:         models.MaintenanceComponent(req.Component),
+		UpdatedBy:         currentUserRef(c),
+	})
+	if err != nil {
+		dtcError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
 }
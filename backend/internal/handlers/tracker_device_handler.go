@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TrackerDeviceHandler manages the IMEI to vehicle registry of the tracker gateway
+type TrackerDeviceHandler struct {
+	gateway *services.DeviceGatewayService
+}
+
+// NewTrackerDeviceHandler creates a new tracker device handler
+func NewTrackerDeviceHandler(gateway *services.DeviceGatewayService) *TrackerDeviceHandler {
+	return &TrackerDeviceHandler{
+		gateway: gateway,
+	}
+}
+
+// GetTrackerDevices handles listing tracker devices
+// @Summary List tracker devices
+// @Description Lists registered telematics units with when they last reported, optionally for one vehicle or only units awaiting assignment
+// @Tags telemetry
+// @Produce json
+// @Param vehicle_id query int false "Vehicle ID"
+// @Param unassigned query bool false "Only units that reported before being assigned to a vehicle"
+// @Success 200 {array} models.TrackerDevice
+// @Router /telemetry/devices [get]
+func (h *TrackerDeviceHandler) GetTrackerDevices(c *gin.Context) {
+	var vehicleID *uint
+	if raw := c.Query("vehicle_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Vehicle ID"})
+			return
+		}
+		vid := uint(id)
+		vehicleID = &vid
+	}
+
+	devices, err := h.gateway.GetTrackerDevices(c.Request.Context(), vehicleID, c.Query("unassigned") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, devices)
+}
+
+// SaveTrackerDevice handles registering a tracker device
+// @Summary Register a tracker device
+// @Description Registers a telematics unit by IMEI or reassigns it to another vehicle (admin only)
+// @Tags telemetry
+// @Accept json
+// @Produce json
+// @Param device body dto.TrackerDeviceRequest true "Tracker device"
+// @Success 200 {object} models.TrackerDevice
+// @Failure 400 {object} map[string]string
+// @Router /telemetry/devices [put]
+func (h *TrackerDeviceHandler) SaveTrackerDevice(c *gin.Context) {
+	var req dto.TrackerDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	device, err := h.gateway.SaveTrackerDevice(c.Request.Context(), &models.TrackerDevice{
+		IMEI:      req.IMEI,
+		Protocol:  req.Protocol,
+		VehicleID: req.VehicleID,
+		Model:     req.Model,
+		IsActive:  req.IsActive == nil || *req.IsActive,
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTrackerDevice) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, device)
+}
+
+// DeleteTrackerDevice handles removing a tracker device
+// @Summary Delete a tracker device
+// @Description Removes a telematics unit; if it reports again it is recorded as unassigned (admin only)
+// @Tags telemetry
+// @Param id path int true "Tracker device ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /telemetry/devices/{id} [delete]
+func (h *TrackerDeviceHandler) DeleteTrackerDevice(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.gateway.DeleteTrackerDevice(c.Request.Context(), id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tracker device not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tracker device deleted"})
+}
+
+// DecodeTrackerPayload handles decoding a captured tracker payload
+// @Summary Decode a tracker payload
+// @Description Decodes a hex capture of a tracker's TCP stream or UDP packet without storing it, for diagnosing devices
+// @Tags telemetry
+// @Accept json
+// @Produce json
+// @Param payload body dto.TrackerDecodeRequest true "Captured payload"
+// @Success 200 {array} services.DecodedFrame
+// @Failure 400 {object} map[string]string
+// @Router /telemetry/devices/decode [post]
+func (h *TrackerDeviceHandler) DecodeTrackerPayload(c *gin.Context) {
+	var req dto.TrackerDecodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payload := []byte(req.Payload)
+	if req.Protocol != services.DeviceProtocolQueclink {
+		decoded, err := hex.DecodeString(strings.Join(strings.Fields(req.Payload), ""))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Payload must be hex encoded"})
+			return
+		}
+		payload = decoded
+	}
+
+	frames, err := services.DecodeDeviceFrames(req.Protocol, payload, req.UDP)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, frames)
+}
@@ -1,13 +1,21 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/fleetflow/backend/internal/dto"
+	"github.com/fleetflow/backend/internal/middleware"
 	"github.com/fleetflow/backend/internal/models"
 	"github.com/fleetflow/backend/internal/services"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // VideoHandler handles video-related requests
@@ -51,7 +59,7 @@ func (h *VideoHandler) RegisterCamera(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param event body struct{Serial string; Type string; Timestamp time.Time; Detections []models.AIDetection} true "Event"
-// @Success 200 {object} map[string]string
+// @Success 200 {object} map[string]interface{}
 // @Router /video/events [post]
 func (h *VideoHandler) ProcessAIEvent(c *gin.Context) {
 	var req struct {
@@ -66,12 +74,14 @@ func (h *VideoHandler) ProcessAIEvent(c *gin.Context) {
 		return
 	}
 
-	if err := h.videoService.ProcessAIEvent(req.Serial, req.Type, req.Timestamp, req.Detections); err != nil {
+	clip, err := h.videoService.ProcessAIEvent(req.Serial, req.Type, req.Timestamp, req.Detections)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "processed"})
+	// The camera uploads the footage against the clip
+	c.JSON(http.StatusOK, gin.H{"status": "processed", "clip_id": clip.ID})
 }
 
 // GetClips returns video clips
@@ -94,3 +104,477 @@ func (h *VideoHandler) GetClips(c *gin.Context) {
 
 	c.JSON(http.StatusOK, clips)
 }
+
+// StartClipUpload handles opening a resumable clip upload
+// @Summary Start a clip upload
+// @Description Opens a resumable upload of a clip's footage from a camera. The file follows in chunks sent to the returned upload.
+// @Tags video
+// @Accept json
+// @Produce json
+// @Param upload body dto.ClipUploadRequest true "Clip metadata"
+// @Success 201 {object} models.VideoUpload
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /video/uploads [post]
+func (h *VideoHandler) StartClipUpload(c *gin.Context) {
+	var req dto.ClipUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	upload, err := h.videoService.StartClipUpload(c.Request.Context(), services.ClipUploadRequest{
+		CameraSerial:       req.CameraSerial,
+		EventType:          models.VideoEventType(req.EventType),
+		StartTime:          req.StartTime,
+		EndTime:            req.EndTime,
+		ContentType:        req.ContentType,
+		FileSize:           req.FileSize,
+		SHA256:             req.SHA256,
+		ClipID:             req.ClipID,
+		RetrievalRequestID: req.RetrievalRequestID,
+	})
+	if err != nil {
+		videoError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, upload)
+}
+
+// WriteClipChunk handles a chunk of a clip upload
+// @Summary Upload a clip chunk
+// @Description Appends the raw request body to an upload at the given offset, which must be where the upload left off. On a 409 resume from received_bytes. An empty body at the end of the file retries storing a clip whose last chunk failed.
+// @Tags video
+// @Accept octet-stream
+// @Produce json
+// @Param upload_id path string true "Upload ID"
+// @Param offset query int false "Offset of the chunk; the Upload-Offset header may be used instead"
+// @Success 200 {object} models.VideoUpload
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
+// @Router /video/uploads/{upload_id} [put]
+func (h *VideoHandler) WriteClipChunk(c *gin.Context) {
+	rawOffset := c.Query("offset")
+	if rawOffset == "" {
+		rawOffset = c.GetHeader("Upload-Offset")
+	}
+	offset, err := strconv.ParseInt(rawOffset, 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, services.MaxClipChunkSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read chunk"})
+		return
+	}
+
+	// The last chunk stores the whole clip, which must not stop halfway if the camera disconnects
+	upload, err := h.videoService.WriteClipChunk(context.WithoutCancel(c.Request.Context()), c.Param("upload_id"), offset, data)
+	if err != nil {
+		if errors.Is(err, services.ErrVideoConflict) && upload != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "received_bytes": upload.ReceivedBytes, "status": upload.Status})
+			return
+		}
+		videoError(c, err)
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(upload.ReceivedBytes, 10))
+	c.JSON(http.StatusOK, upload)
+}
+
+// GetClipUpload handles fetching an upload's progress
+// @Summary Get a clip upload
+// @Description Returns how much of an upload has been received, to resume from
+// @Tags video
+// @Produce json
+// @Param upload_id path string true "Upload ID"
+// @Success 200 {object} models.VideoUpload
+// @Failure 404 {object} map[string]string
+// @Router /video/uploads/{upload_id} [get]
+func (h *VideoHandler) GetClipUpload(c *gin.Context) {
+	upload, err := h.videoService.GetClipUpload(c.Request.Context(), c.Param("upload_id"))
+	if err != nil {
+		videoError(c, err)
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(upload.ReceivedBytes, 10))
+	c.JSON(http.StatusOK, upload)
+}
+
+// AbortClipUpload handles giving up on an upload
+// @Summary Abort a clip upload
+// @Description Discards an open upload's chunks and marks its clip failed
+// @Tags video
+// @Produce json
+// @Param upload_id path string true "Upload ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /video/uploads/{upload_id} [delete]
+func (h *VideoHandler) AbortClipUpload(c *gin.Context) {
+	if err := h.videoService.AbortClipUpload(c.Request.Context(), c.Param("upload_id")); err != nil {
+		videoError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Upload aborted"})
+}
+
+// GetPlaybackURLs handles signing playback links for a clip
+// @Summary Get clip playback URLs
+// @Description Returns signed, time-limited links to an uploaded clip's footage and thumbnail. Drivers only get links to their own clips.
+// @Tags video
+// @Produce json
+// @Param id path int true "Clip ID"
+// @Success 200 {object} services.PlaybackURLs
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /video/clips/{id}/playback [get]
+func (h *VideoHandler) GetPlaybackURLs(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	clip, err := h.videoService.GetClip(c.Request.Context(), id)
+	if err != nil {
+		videoError(c, err)
+		return
+	}
+	if middleware.IsDriver(c) {
+		if driverID, ok := middleware.GetCurrentDriverID(c); !ok || clip.DriverID == nil || *clip.DriverID != driverID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+	}
+
+	urls, err := h.videoService.GetPlaybackURLs(c.Request.Context(), clip, time.Now())
+	if err != nil {
+		videoError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, urls)
+}
+
+// ServeSignedMedia handles playback through a signed URL
+// @Summary Play a clip
+// @Description Streams a clip's footage or thumbnail when the URL's signature is valid and unexpired. Supports range requests.
+// @Tags video
+// @Produce octet-stream
+// @Param id path int true "Clip ID"
+// @Param kind path string true "video or thumbnail"
+// @Param expires query int true "Unix expiry"
+// @Param signature query string true "URL signature"
+// @Success 200 {file} file
+// @Failure 403 {object} map[string]string
+// @Failure 410 {object} map[string]string
+// @Router /video/media/{id}/{kind} [get]
+func (h *VideoHandler) ServeSignedMedia(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	kind := c.Param("kind")
+	if kind != services.MediaKindVideo && kind != services.MediaKindThumbnail {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": services.ErrInvalidMediaSignature.Error()})
+		return
+	}
+
+	clip, data, contentType, err := h.videoService.OpenSignedMedia(c.Request.Context(), id, kind, expires, c.Query("signature"), time.Now())
+	if err != nil {
+		if errors.Is(err, services.ErrClipUnavailable) {
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+			return
+		}
+		videoError(c, err)
+		return
+	}
+
+	var modified time.Time
+	if clip.UploadedAt != nil {
+		modified = *clip.UploadedAt
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Cache-Control", "private, max-age="+strconv.FormatInt(max(expires-time.Now().Unix(), 0), 10))
+	http.ServeContent(c.Writer, c.Request, "", modified, bytes.NewReader(data))
+}
+
+// RequestFootage handles asking a camera for footage
+// @Summary Request footage from a camera
+// @Description Asks a camera, or the camera on a vehicle, to upload the footage of a window of up to 10 minutes. The request is pushed over MQTT and listed for the camera to poll.
+// @Tags video
+// @Accept json
+// @Produce json
+// @Param request body dto.FootageRetrievalRequest true "Window to retrieve"
+// @Success 201 {object} models.VideoRetrievalRequest
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /video/retrievals [post]
+func (h *VideoHandler) RequestFootage(c *gin.Context) {
+	var req dto.FootageRetrievalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	retrieval, err := h.videoService.RequestFootage(c.Request.Context(), services.FootageRequest{
+		CameraID:      req.CameraID,
+		VehicleID:     req.VehicleID,
+		StartTime:     req.StartTime,
+		EndTime:       req.EndTime,
+		EventType:     models.VideoEventType(req.EventType),
+		Reason:        req.Reason,
+		RequestedByID: currentUserRef(c),
+	}, time.Now())
+	if err != nil {
+		videoError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, retrieval)
+}
+
+// GetRetrievalRequests handles listing footage requests
+// @Summary List footage requests
+// @Description Lists footage requests oldest first. Cameras poll with their serial and status=PENDING,SENT.
+// @Tags video
+// @Produce json
+// @Param camera_serial query string false "Camera serial number"
+// @Param vehicle_id query int false "Vehicle ID"
+// @Param status query string false "Comma separated statuses (PENDING, SENT, FULFILLED, FAILED, EXPIRED)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /video/retrievals [get]
+func (h *VideoHandler) GetRetrievalRequests(c *gin.Context) {
+	filter := services.VideoRetrievalFilter{CameraSerial: c.Query("camera_serial")}
+	if raw := c.Query("vehicle_id"); raw != "" {
+		vehicleID, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle_id"})
+			return
+		}
+		id := uint(vehicleID)
+		filter.VehicleID = &id
+	}
+	if raw := c.Query("status"); raw != "" {
+		for _, status := range strings.Split(raw, ",") {
+			filter.Statuses = append(filter.Statuses, models.VideoRetrievalStatus(strings.ToUpper(strings.TrimSpace(status))))
+		}
+	}
+
+	requests, err := h.videoService.GetRetrievalRequests(c.Request.Context(), filter)
+	if err != nil {
+		videoError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"retrievals": requests})
+}
+
+// FailRetrievalRequest handles a camera declining a footage request
+// @Summary Decline a footage request
+// @Description Records that a camera no longer holds the footage it was asked for
+// @Tags video
+// @Accept json
+// @Produce json
+// @Param id path int true "Retrieval request ID"
+// @Param request body dto.FailRetrievalRequest true "Why the footage is unavailable"
+// @Success 200 {object} models.VideoRetrievalRequest
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /video/retrievals/{id}/fail [post]
+func (h *VideoHandler) FailRetrievalRequest(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	var req dto.FailRetrievalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	retrieval, err := h.videoService.FailRetrievalRequest(c.Request.Context(), id, req.Reason)
+	if err != nil {
+		videoError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, retrieval)
+}
+
+// GetRetentionPolicies handles listing clip retention
+// @Summary Get clip retention
+// @Description Returns how many days clips of each event type are kept. Starred clips and clips awaiting coaching are kept past their retention.
+// @Tags video
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /video/retention [get]
+func (h *VideoHandler) GetRetentionPolicies(c *gin.Context) {
+	policies, err := h.videoService.GetRetentionPolicies(c.Request.Context())
+	if err != nil {
+		videoError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policies": policies})
+}
+
+// SetRetentionPolicy handles changing an event type's retention
+// @Summary Set clip retention
+// @Description Sets how many days clips of an event type are kept
+// @Tags video
+// @Accept json
+// @Produce json
+// @Param policy body dto.VideoRetentionRequest true "Retention"
+// @Success 200 {object} models.VideoRetentionPolicy
+// @Failure 400 {object} map[string]string
+// @Router /video/retention [put]
+func (h *VideoHandler) SetRetentionPolicy(c *gin.Context) {
+	var req dto.VideoRetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.videoService.SetRetentionPolicy(c.Request.Context(), models.VideoEventType(req.EventType), req.RetentionDays)
+	if err != nil {
+		videoError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// ApplyRetention handles running clip retention now
+// @Summary Apply clip retention
+// @Description Deletes footage past its retention, abandons stalled uploads and times out unanswered footage requests
+// @Tags video
+// @Produce json
+// @Success 200 {object} services.RetentionResult
+// @Router /video/retention/apply [post]
+func (h *VideoHandler) ApplyRetention(c *gin.Context) {
+	result, err := h.videoService.ApplyRetention(c.Request.Context(), time.Now())
+	if err != nil {
+		videoError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RecordHeartbeat handles a camera heartbeat
+// @Summary Record a camera heartbeat
+// @Description Records a dashcam's health report for cameras that cannot publish over MQTT. Reported faults such as an SD card failure or an obstructed lens mark the camera malfunctioning.
+// @Tags video
+// @Accept json
+// @Produce json
+// @Param heartbeat body dto.CameraHeartbeatRequest true "Heartbeat"
+// @Success 200 {object} models.Camera
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /video/cameras/heartbeat [post]
+func (h *VideoHandler) RecordHeartbeat(c *gin.Context) {
+	var req dto.CameraHeartbeatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	camera, err := h.videoService.ProcessHeartbeat(c.Request.Context(), &services.CameraHeartbeat{
+		Serial:         req.Serial,
+		FirmwareVer:    req.FirmwareVer,
+		Timestamp:      req.Timestamp,
+		Ignition:       req.Ignition,
+		Recording:      req.Recording,
+		SDCardStatus:   req.SDCardStatus,
+		LensObstructed: req.LensObstructed,
+		Errors:         req.Errors,
+	}, time.Now())
+	if err != nil {
+		videoError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, camera)
+}
+
+// GetCameraHealthReport handles the camera fleet health report
+// @Summary Camera fleet health
+// @Description Reports each camera's status and faults with its incidents, downtime and availability over the window, unhealthy cameras first. Cameras on parked vehicles are on standby, which is not downtime.
+// @Tags video
+// @Produce json
+// @Param days query int false "Window in days" default(7)
+// @Success 200 {object} services.CameraHealthReport
+// @Failure 400 {object} map[string]string
+// @Router /video/cameras/health [get]
+func (h *VideoHandler) GetCameraHealthReport(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days < 1 || days > 90 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 90"})
+		return
+	}
+
+	now := time.Now()
+	report, err := h.videoService.GetCameraHealthReport(c.Request.Context(), now.AddDate(0, 0, -days), now)
+	if err != nil {
+		videoError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetCameraStatusHistory handles listing a camera's status changes
+// @Summary Camera status history
+// @Description Lists a camera's status changes with their reasons and faults, newest first
+// @Tags video
+// @Produce json
+// @Param id path int true "Camera ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /video/cameras/{id}/health [get]
+func (h *VideoHandler) GetCameraStatusHistory(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	changes, err := h.videoService.GetCameraStatusHistory(c.Request.Context(), id, 100)
+	if err != nil {
+		videoError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"changes": changes})
+}
+
+// videoError maps video service errors to responses
+func videoError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidVideoRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidMediaSignature):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrVideoConflict), errors.Is(err, services.ErrClipUnavailable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
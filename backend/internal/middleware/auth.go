@@ -37,6 +37,16 @@ func JWTMiddleware(jwtService *services.JWTService) gin.HandlerFunc {
 			return
 		}
 
+		// Tokens bound to a session stop working as soon as the session is revoked or idles out
+		if claims.SessionID != 0 {
+			if err := jwtService.ValidateSession(claims.SessionID, c.ClientIP()); err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				c.Abort()
+				return
+			}
+			c.Set("session_id", claims.SessionID)
+		}
+
 		// Set user information in context
 		c.Set("user_id", claims.UserID)
 		c.Set("phone", claims.Phone)
@@ -134,6 +144,16 @@ func GetCurrentUserID(c *gin.Context) (uint, bool) {
 	return 0, false
 }
 
+// GetCurrentSessionID gets the current session ID from context
+func GetCurrentSessionID(c *gin.Context) (uint, bool) {
+	if sessionID, exists := c.Get("session_id"); exists {
+		if id, ok := sessionID.(uint); ok {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
 // GetCurrentUserRole gets the current user role from context
 func GetCurrentUserRole(c *gin.Context) (models.Role, bool) {
 	if role, exists := c.Get("role"); exists {
@@ -9,8 +9,19 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// RiskSignal reports how risky recent authentication traffic from an IP has been
+type RiskSignal interface {
+	IPRisk(ipAddress string) (score float64, blockedUntil time.Time)
+}
+
 // RateLimiter creates a rate limiting middleware using token bucket algorithm
 func RateLimiter(requestsPerMinute int) gin.HandlerFunc {
+	return riskAwareRateLimiter(requestsPerMinute, nil)
+}
+
+// riskAwareRateLimiter is RateLimiter where risky IPs spend extra tokens per request
+// (up to 5x at risk score 100) and IPs under a risk block are rejected outright
+func riskAwareRateLimiter(requestsPerMinute int, risk RiskSignal) gin.HandlerFunc {
 	type client struct {
 		tokens     float64
 		lastUpdate time.Time
@@ -44,6 +55,20 @@ func RateLimiter(requestsPerMinute int) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		clientIP := c.ClientIP()
 
+		cost := 1.0
+		if risk != nil {
+			score, blockedUntil := risk.IPRisk(clientIP)
+			if time.Now().Before(blockedUntil) {
+				c.Header("Retry-After", fmt.Sprintf("%d", int(time.Until(blockedUntil).Seconds())+1))
+				c.JSON(http.StatusTooManyRequests, gin.H{
+					"error": "Too many suspicious requests. Please try again later.",
+				})
+				c.Abort()
+				return
+			}
+			cost += score / 25
+		}
+
 		clientsMu.Lock()
 		cl, exists := clients[clientIP]
 		if !exists {
@@ -66,8 +91,8 @@ func RateLimiter(requestsPerMinute int) gin.HandlerFunc {
 		}
 		cl.lastUpdate = now
 
-		if cl.tokens >= 1 {
-			cl.tokens--
+		if cl.tokens >= cost {
+			cl.tokens -= cost
 			c.Next()
 		} else {
 			c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", requestsPerMinute))
@@ -81,14 +106,16 @@ func RateLimiter(requestsPerMinute int) gin.HandlerFunc {
 	}
 }
 
-// PublicRateLimiter creates a rate limiter for public endpoints (60 req/min, 1000 in test)
-func AuthRateLimiter() gin.HandlerFunc {
+// AuthRateLimiter creates a rate limiter for auth endpoints (10 req/min) that tightens for risky IPs
+func AuthRateLimiter(risk RiskSignal) gin.HandlerFunc {
 	limit := 10
 	if gin.Mode() == gin.TestMode {
 		limit = 1000000 // Effectively infinite for tests to avoid interference
 	}
-	return RateLimiter(limit)
+	return riskAwareRateLimiter(limit, risk)
 }
+
+// PublicRateLimiter creates a rate limiter for public endpoints (60 req/min, 1000 in test)
 func PublicRateLimiter() gin.HandlerFunc {
 	limit := 60
 	if gin.Mode() == gin.TestMode {
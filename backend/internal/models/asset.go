@@ -76,18 +76,25 @@ type YardEvent struct {
 
 // InventoryItem represents parts or goods in inventory
 type InventoryItem struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	Name        string         `json:"name" gorm:"type:varchar(100);not null"`
-	SKU         string         `json:"sku" gorm:"uniqueIndex;not null"`
-	Category    string         `json:"category"`
-	Quantity    int            `json:"quantity" gorm:"default:0"`
-	MinQuantity int            `json:"min_quantity" gorm:"default:10"`
-	UnitCost    float64        `json:"unit_cost"`
-	Location    string         `json:"location"` // Shelf/Bin location
-	YardID      *uint          `json:"yard_id" gorm:"index"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID               uint           `json:"id" gorm:"primaryKey"`
+	Name             string         `json:"name" gorm:"type:varchar(100);not null"`
+	SKU              string         `json:"sku" gorm:"uniqueIndex;not null"`
+	Category         string         `json:"category"`
+	Quantity         int            `json:"quantity" gorm:"default:0"`
+	MinQuantity      int            `json:"min_quantity" gorm:"default:10"`
+	ReservedQuantity int            `json:"reserved_quantity" gorm:"default:0"` // Held for open work orders
+	ReorderQuantity  int            `json:"reorder_quantity"`                   // Preferred purchase quantity, 0 to refill to twice MinQuantity
+	UnitCost         float64        `json:"unit_cost"`
+	Location         string         `json:"location"` // Shelf/Bin location
+	YardID           *uint          `json:"yard_id" gorm:"index"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
 
 	Yard *Yard `json:"yard,omitempty" gorm:"foreignKey:YardID"`
 }
+
+// AvailableQuantity is the stock not held for open work orders
+func (i *InventoryItem) AvailableQuantity() int {
+	return i.Quantity - i.ReservedQuantity
+}
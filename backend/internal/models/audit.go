@@ -39,11 +39,14 @@ const (
 	AuditActionTripCancelled AuditAction = "TRIP_CANCELLED"
 
 	// Fuel actions
-	AuditActionFuelEventCreated  AuditAction = "FUEL_EVENT_CREATED"
-	AuditActionFuelEventVerified AuditAction = "FUEL_EVENT_VERIFIED"
-	AuditActionFuelEventRejected AuditAction = "FUEL_EVENT_REJECTED"
-	AuditActionFuelAlertCreated  AuditAction = "FUEL_ALERT_CREATED"
-	AuditActionFuelAlertResolved AuditAction = "FUEL_ALERT_RESOLVED"
+	AuditActionFuelEventCreated   AuditAction = "FUEL_EVENT_CREATED"
+	AuditActionFuelEventVerified  AuditAction = "FUEL_EVENT_VERIFIED"
+	AuditActionFuelEventRejected  AuditAction = "FUEL_EVENT_REJECTED"
+	AuditActionFuelAlertCreated   AuditAction = "FUEL_ALERT_CREATED"
+	AuditActionFuelAlertResolved  AuditAction = "FUEL_ALERT_RESOLVED"
+	AuditActionFuelAlertEscalated AuditAction = "FUEL_ALERT_ESCALATED"
+	AuditActionFuelModelTrained   AuditAction = "FUEL_MODEL_TRAINED"
+	AuditActionFuelCardImported   AuditAction = "FUEL_CARD_IMPORTED"
 
 	// Upload actions
 	AuditActionFileUploaded AuditAction = "FILE_UPLOADED"
@@ -97,9 +100,15 @@ type AuditLog struct {
 	Metadata string `json:"metadata,omitempty"` // JSON blob for additional context
 	Tags     string `json:"tags,omitempty"`     // Comma-separated tags
 
+	// Hash chain (per organization; 0 is the platform chain). Rows are append-only:
+	// there is no soft delete, old rows leave the table only through archival.
+	OrganizationID uint   `json:"organization_id" gorm:"index:idx_audit_chain_sequence,priority:1"`
+	Sequence       uint64 `json:"sequence" gorm:"index:idx_audit_chain_sequence,priority:2"`
+	PrevHash       string `json:"prev_hash,omitempty" gorm:"type:varchar(64)"`
+	Hash           string `json:"hash,omitempty" gorm:"type:varchar(64)"`
+
 	// Timing
-	CreatedAt time.Time      `json:"created_at" gorm:"index"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
 
 	// Foreign keys - all nullable to support various scenarios
 	UserID    *uint `json:"user_id,omitempty" gorm:"index"`
@@ -136,11 +145,16 @@ type SecurityEvent struct {
 	Location   string `json:"location,omitempty"` // Derived from IP
 	DeviceInfo string `json:"device_info,omitempty"`
 
+	// Subject of the attempt (phone or email), used for per-identifier velocity and blocks
+	Identifier string `json:"identifier,omitempty" gorm:"index"`
+
 	// Risk assessment
-	RiskScore   float64 `json:"risk_score" gorm:"type:decimal(5,2);default:0"`
-	RiskFactors string  `json:"risk_factors,omitempty"` // JSON array
-	IsBlocked   bool    `json:"is_blocked" gorm:"default:false"`
-	BlockReason string  `json:"block_reason,omitempty"`
+	RiskScore    float64    `json:"risk_score" gorm:"type:decimal(5,2);default:0"`
+	RiskFactors  string     `json:"risk_factors,omitempty"` // JSON array
+	IsBlocked    bool       `json:"is_blocked" gorm:"default:false"`
+	BlockReason  string     `json:"block_reason,omitempty"`
+	BlockScope   string     `json:"block_scope,omitempty"` // IDENTIFIER or IP
+	BlockedUntil *time.Time `json:"blocked_until,omitempty" gorm:"index"`
 
 	// Response
 	IsInvestigated     bool       `json:"is_investigated" gorm:"default:false"`
@@ -161,6 +175,27 @@ type SecurityEvent struct {
 	InvestigatedByUser *UserAccount `json:"investigated_by_user,omitempty" gorm:"foreignKey:InvestigatedBy"`
 }
 
+// Security event types scored by the risk engine
+const (
+	SecurityEventLoginAttempt = "LOGIN_ATTEMPT"
+	SecurityEventOTPRequest   = "OTP_REQUEST"
+	SecurityEventOTPVerify    = "OTP_VERIFY"
+	SecurityEventMFAVerify    = "MFA_VERIFY"
+)
+
+// Security event statuses
+const (
+	SecurityEventStatusSuccess = "SUCCESS"
+	SecurityEventStatusFailed  = "FAILED"
+	SecurityEventStatusBlocked = "BLOCKED"
+)
+
+// Block scopes
+const (
+	BlockScopeIdentifier = "IDENTIFIER"
+	BlockScopeIP         = "IP"
+)
+
 // IsInfo checks if the audit log is informational
 func (al *AuditLog) IsInfo() bool {
 	return al.Severity == AuditSeverityInfo
@@ -271,6 +306,11 @@ func (se *SecurityEvent) Block(reason string) {
 	se.BlockReason = reason
 }
 
+// IsBlockActive checks if the block recorded on this event is still in force
+func (se *SecurityEvent) IsBlockActive() bool {
+	return se.IsBlocked && se.BlockedUntil != nil && time.Now().Before(*se.BlockedUntil)
+}
+
 // Investigate marks the security event as investigated
 func (se *SecurityEvent) Investigate(investigatedBy uint, notes string) {
 	se.IsInvestigated = true
@@ -282,14 +322,15 @@ func (se *SecurityEvent) Investigate(investigatedBy uint, notes string) {
 
 // AuditContext represents context information for audit logging
 type AuditContext struct {
-	UserID       *uint  `json:"user_id,omitempty"`
-	DriverID     *uint  `json:"driver_id,omitempty"`
-	VehicleID    *uint  `json:"vehicle_id,omitempty"`
-	TripID       *uint  `json:"trip_id,omitempty"`
-	IPAddress    string `json:"ip_address,omitempty"`
-	UserAgent    string `json:"user_agent,omitempty"`
-	RequestID    string `json:"request_id,omitempty"`
-	Endpoint     string `json:"endpoint,omitempty"`
-	HTTPMethod   string `json:"http_method,omitempty"`
-	ResponseCode *int   `json:"response_code,omitempty"`
+	OrganizationID *uint  `json:"organization_id,omitempty"` // Chain to append to; derived from UserID when nil
+	UserID         *uint  `json:"user_id,omitempty"`
+	DriverID       *uint  `json:"driver_id,omitempty"`
+	VehicleID      *uint  `json:"vehicle_id,omitempty"`
+	TripID         *uint  `json:"trip_id,omitempty"`
+	IPAddress      string `json:"ip_address,omitempty"`
+	UserAgent      string `json:"user_agent,omitempty"`
+	RequestID      string `json:"request_id,omitempty"`
+	Endpoint       string `json:"endpoint,omitempty"`
+	HTTPMethod     string `json:"http_method,omitempty"`
+	ResponseCode   *int   `json:"response_code,omitempty"`
 }
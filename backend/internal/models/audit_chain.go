@@ -0,0 +1,125 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AuditGenesisHash is the PrevHash of the first record in every audit chain
+var AuditGenesisHash = strings.Repeat("0", 64)
+
+// AuditChainHead tracks the latest record of an organization's audit chain.
+// Appends lock this row so sequences are assigned without gaps or duplicates.
+type AuditChainHead struct {
+	OrganizationID uint      `json:"organization_id" gorm:"primaryKey;autoIncrement:false"`
+	Sequence       uint64    `json:"sequence"`
+	Hash           string    `json:"hash" gorm:"type:varchar(64)"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// AuditCheckpoint is a signed statement of an audit chain's head at a point in time.
+// Anyone holding the public key can prove later rows were not rewritten.
+type AuditCheckpoint struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID uint      `json:"organization_id" gorm:"index"`
+	Sequence       uint64    `json:"sequence" gorm:"index"`
+	Hash           string    `json:"hash" gorm:"type:varchar(64);not null"`
+	KeyID          string    `json:"key_id" gorm:"type:varchar(32)"`
+	Signature      string    `json:"signature" gorm:"not null"` // Base64 Ed25519 signature of SigningPayload
+	SignedAt       time.Time `json:"signed_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// AuditArchive records a contiguous range of an audit chain moved to cold storage
+type AuditArchive struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID uint      `json:"organization_id" gorm:"index"`
+	FromSequence   uint64    `json:"from_sequence"`
+	ToSequence     uint64    `json:"to_sequence" gorm:"index"`
+	RecordCount    int       `json:"record_count"`
+	PrevHash       string    `json:"prev_hash" gorm:"type:varchar(64)"` // Hash the first archived record links to
+	LastHash       string    `json:"last_hash" gorm:"type:varchar(64)"`
+	ContentSHA256  string    `json:"content_sha256" gorm:"type:varchar(64)"`
+	StorageKey     string    `json:"storage_key" gorm:"not null"`
+	StorageURL     string    `json:"storage_url,omitempty"`
+	OldestRecordAt time.Time `json:"oldest_record_at"`
+	NewestRecordAt time.Time `json:"newest_record_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// auditHashInput is the canonical, order-stable form of an AuditLog that is hashed
+type auditHashInput struct {
+	OrganizationID uint          `json:"organization_id"`
+	Sequence       uint64        `json:"sequence"`
+	PrevHash       string        `json:"prev_hash"`
+	Action         AuditAction   `json:"action"`
+	Severity       AuditSeverity `json:"severity"`
+	Description    string        `json:"description"`
+	TableName      string        `json:"table_name"`
+	RecordID       *uint         `json:"record_id"`
+	OldValues      string        `json:"old_values"`
+	NewValues      string        `json:"new_values"`
+	IPAddress      string        `json:"ip_address"`
+	UserAgent      string        `json:"user_agent"`
+	RequestID      string        `json:"request_id"`
+	Endpoint       string        `json:"endpoint"`
+	HTTPMethod     string        `json:"http_method"`
+	ResponseCode   *int          `json:"response_code"`
+	Metadata       string        `json:"metadata"`
+	Tags           string        `json:"tags"`
+	CreatedAt      string        `json:"created_at"`
+	UserID         *uint         `json:"user_id"`
+	DriverID       *uint         `json:"driver_id"`
+	VehicleID      *uint         `json:"vehicle_id"`
+	TripID         *uint         `json:"trip_id"`
+}
+
+// ComputeChainHash returns the SHA-256 over the record's content and its link to the previous record.
+// CreatedAt is hashed at microsecond precision in UTC so the value survives a database round trip.
+func (al *AuditLog) ComputeChainHash() string {
+	input := auditHashInput{
+		OrganizationID: al.OrganizationID,
+		Sequence:       al.Sequence,
+		PrevHash:       al.PrevHash,
+		Action:         al.Action,
+		Severity:       al.Severity,
+		Description:    al.Description,
+		TableName:      al.TableName,
+		RecordID:       al.RecordID,
+		OldValues:      al.OldValues,
+		NewValues:      al.NewValues,
+		IPAddress:      al.IPAddress,
+		UserAgent:      al.UserAgent,
+		RequestID:      al.RequestID,
+		Endpoint:       al.Endpoint,
+		HTTPMethod:     al.HTTPMethod,
+		ResponseCode:   al.ResponseCode,
+		Metadata:       al.Metadata,
+		Tags:           al.Tags,
+		CreatedAt:      al.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+		UserID:         al.UserID,
+		DriverID:       al.DriverID,
+		VehicleID:      al.VehicleID,
+		TripID:         al.TripID,
+	}
+
+	// Marshalling a struct of strings and numbers cannot fail
+	data, _ := json.Marshal(input)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// IsChained reports whether the record was written after hash chaining was introduced
+func (al *AuditLog) IsChained() bool {
+	return al.Sequence > 0
+}
+
+// SigningPayload returns the exact bytes covered by the checkpoint signature
+func (cp *AuditCheckpoint) SigningPayload() []byte {
+	return []byte(fmt.Sprintf("fleetflow-audit-checkpoint|%d|%d|%s|%s",
+		cp.OrganizationID, cp.Sequence, cp.Hash, cp.SignedAt.UTC().Format(time.RFC3339)))
+}
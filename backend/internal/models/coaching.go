@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// CoachingStatus is where a coaching session stands in the queue
+type CoachingStatus string
+
+const (
+	CoachingStatusPending       CoachingStatus = "PENDING"        // Waiting for the coach
+	CoachingStatusCoached       CoachingStatus = "COACHED"        // Gone over with the driver
+	CoachingStatusDismissed     CoachingStatus = "DISMISSED"      // Real, but not worth coaching
+	CoachingStatusFalsePositive CoachingStatus = "FALSE_POSITIVE" // Not what was detected; no longer counts against the driver
+)
+
+// CoachingSession queues a safety event or video clip for a coach to go over with the driver.
+// Once coached, the driver's score over the month before is compared with the month after.
+type CoachingSession struct {
+	ID                   uint           `json:"id" gorm:"primaryKey"`
+	DriverID             uint           `json:"driver_id" gorm:"not null;index"`
+	SafetyEventID        *uint          `json:"safety_event_id,omitempty" gorm:"uniqueIndex"` // The event or the clip being coached, never both
+	VideoClipID          *uint          `json:"video_clip_id,omitempty" gorm:"uniqueIndex"`
+	CoachID              *uint          `json:"coach_id,omitempty" gorm:"index"` // User account
+	AssignedByID         *uint          `json:"assigned_by_id,omitempty"`
+	Status               CoachingStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	DueAt                *time.Time     `json:"due_at,omitempty"`
+	ResolvedAt           *time.Time     `json:"resolved_at,omitempty"`
+	ResolvedByID         *uint          `json:"resolved_by_id,omitempty"`
+	DriverAcknowledgedAt *time.Time     `json:"driver_acknowledged_at,omitempty"`
+	DriverComment        string         `json:"driver_comment,omitempty" gorm:"type:text"`
+	ScoreBefore          *int           `json:"score_before,omitempty"` // Overall score over the 30 days before coaching
+	ScoreAfter           *int           `json:"score_after,omitempty"`  // and the 30 days after, once they have passed
+	ScoreChange          *int           `json:"score_change,omitempty"`
+	EffectMeasuredAt     *time.Time     `json:"effect_measured_at,omitempty"`
+	CreatedAt            time.Time      `json:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at"`
+
+	// Associations
+	Driver      *Driver        `json:"driver,omitempty" gorm:"foreignKey:DriverID"`
+	Coach       *UserAccount   `json:"coach,omitempty" gorm:"foreignKey:CoachID"`
+	SafetyEvent *SafetyEvent   `json:"safety_event,omitempty" gorm:"foreignKey:SafetyEventID"`
+	VideoClip   *VideoClip     `json:"video_clip,omitempty" gorm:"foreignKey:VideoClipID"`
+	Notes       []CoachingNote `json:"notes,omitempty" gorm:"foreignKey:SessionID"`
+}
+
+// CoachingNote is a remark a coach or manager left on a session
+type CoachingNote struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	SessionID uint      `json:"session_id" gorm:"not null;index"`
+	AuthorID  *uint     `json:"author_id,omitempty"`
+	Body      string    `json:"body" gorm:"type:text;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Diagnostic code sources
+const (
+	DTCSourceOBDII = "OBDII"
+	DTCSourceJ1939 = "J1939"
+)
+
+// DTCCatalogEntry describes a diagnostic trouble code. OBD-II entries are keyed by code
+// ("P0300"); J1939 entries by SPN ("SPN110"), or by SPN and FMI ("SPN110-FMI0") when a
+// failure mode needs its own description or severity. Entries here override the built-in catalogue.
+type DTCCatalogEntry struct {
+	ID                uint                   `json:"id" gorm:"primaryKey"`
+	Code              string                 `json:"code" gorm:"type:varchar(20);not null;uniqueIndex"`
+	Source            string                 `json:"source" gorm:"type:varchar(10);not null;index"`
+	SPN               *int                   `json:"spn,omitempty" gorm:"index"`
+	FMI               *int                   `json:"fmi,omitempty"`
+	Description       string                 `json:"description" gorm:"not null"`
+	Severity          DiagnosticCodeSeverity `json:"severity" gorm:"type:varchar(20);not null"`
+	RecommendedAction string                 `json:"recommended_action,omitempty"`
+	Component         MaintenanceComponent   `json:"component,omitempty" gorm:"type:varchar(30)"`
+	UpdatedBy         *uint                  `json:"updated_by,omitempty"`
+	CreatedAt         time.Time              `json:"created_at"`
+	UpdatedAt         time.Time              `json:"updated_at"`
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DVIR inspection types and outcomes
+const (
+	DVIRTypePreTrip  = "PRE_TRIP"
+	DVIRTypePostTrip = "POST_TRIP"
+
+	DVIRStatusSafe          = "SAFE"
+	DVIRStatusRepairsNeeded = "REPAIRS_NEEDED"
+	DVIRStatusUnsafe        = "UNSAFE"
+)
+
+// DefectSeverity is how serious a reported defect is
+type DefectSeverity string
+
+const (
+	DefectSeverityMinor        DefectSeverity = "MINOR"
+	DefectSeverityMajor        DefectSeverity = "MAJOR"
+	DefectSeverityOutOfService DefectSeverity = "OUT_OF_SERVICE" // Vehicle may not be driven until certified
+)
+
+// Rank orders severities so the more serious of two can be chosen
+func (s DefectSeverity) Rank() int {
+	switch s {
+	case DefectSeverityOutOfService:
+		return 3
+	case DefectSeverityMajor:
+		return 2
+	case DefectSeverityMinor:
+		return 1
+	}
+	return 0
+}
+
+// DVIRDefectStatus tracks a defect from report to the next driver's acknowledgement
+type DVIRDefectStatus string
+
+const (
+	DVIRDefectOpen         DVIRDefectStatus = "OPEN"         // Reported, not yet certified by a mechanic
+	DVIRDefectCertified    DVIRDefectStatus = "CERTIFIED"    // Mechanic certified the repair (or that none is needed)
+	DVIRDefectAcknowledged DVIRDefectStatus = "ACKNOWLEDGED" // Next driver reviewed the certification on a pre-trip
+)
+
+// DVIRDefectResolution is what the mechanic certified
+type DVIRDefectResolution string
+
+const (
+	DefectResolutionCorrected      DVIRDefectResolution = "CORRECTED"
+	DefectResolutionNoRepairNeeded DVIRDefectResolution = "NO_REPAIR_NEEDED"
+)
+
+// InspectionChecklist is the list of items a driver inspects on a vehicle type
+type InspectionChecklist struct {
+	ID          uint        `json:"id" gorm:"primaryKey"`
+	VehicleType VehicleType `json:"vehicle_type" gorm:"type:varchar(20);not null;uniqueIndex"`
+	Name        string      `json:"name" gorm:"not null"`
+	IsActive    bool        `json:"is_active"`
+	CreatedBy   *uint       `json:"created_by,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+
+	Items []InspectionChecklistItem `json:"items" gorm:"foreignKey:ChecklistID"`
+}
+
+// InspectionChecklistItem is one item on a checklist. Removed items are soft-deleted so
+// defects reported against them keep their reference.
+type InspectionChecklistItem struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	ChecklistID uint           `json:"checklist_id" gorm:"not null;index"`
+	Code        string         `json:"code" gorm:"type:varchar(50);not null"` // Stable key, e.g. "BRAKES_SERVICE"
+	Category    string         `json:"category"`                              // e.g. "Brakes", "Lights", "Tyres"
+	Label       string         `json:"label" gorm:"not null"`
+	Severity    DefectSeverity `json:"severity" gorm:"type:varchar(20);not null"` // Least severity of a failure on this item
+	SortOrder   int            `json:"sort_order"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// DVIRDefect is one defect reported on an inspection, tracked until a mechanic certifies it
+// and the next driver acknowledges the certification
+type DVIRDefect struct {
+	ID                 uint                 `json:"id" gorm:"primaryKey"`
+	DVIRID             uint                 `json:"dvir_id" gorm:"not null;index"`
+	VehicleID          uint                 `json:"vehicle_id" gorm:"not null;index"`
+	ChecklistItemID    *uint                `json:"checklist_item_id,omitempty" gorm:"index"`
+	Category           string               `json:"category,omitempty"`
+	Description        string               `json:"description" gorm:"not null"`
+	Severity           DefectSeverity       `json:"severity" gorm:"type:varchar(20);not null;index"`
+	Status             DVIRDefectStatus     `json:"status" gorm:"type:varchar(20);not null;index"`
+	WorkOrderID        *uint                `json:"work_order_id,omitempty" gorm:"index"`
+	PhotoUploadIDs     []uint               `json:"photo_upload_ids,omitempty" gorm:"-"` // Uploads to attach when reporting
+	Resolution         DVIRDefectResolution `json:"resolution,omitempty" gorm:"type:varchar(20)"`
+	RepairNotes        string               `json:"repair_notes,omitempty"`
+	CertifiedBy        *uint                `json:"certified_by,omitempty"` // Mechanic user
+	MechanicSign       string               `json:"mechanic_sign,omitempty"`
+	CertifiedAt        *time.Time           `json:"certified_at,omitempty"`
+	AcknowledgedBy     *uint                `json:"acknowledged_by,omitempty"`      // Driver on the next pre-trip
+	AcknowledgedDVIRID *uint                `json:"acknowledged_dvir_id,omitempty"` // That pre-trip DVIR
+	AcknowledgedAt     *time.Time           `json:"acknowledged_at,omitempty"`
+	CreatedAt          time.Time            `json:"created_at"`
+	UpdatedAt          time.Time            `json:"updated_at"`
+
+	// Associations
+	Photos []Upload `json:"photos,omitempty" gorm:"foreignKey:DVIRDefectID"`
+}
+
+// IsOutOfService reports whether the defect keeps the vehicle off the road
+func (d *DVIRDefect) IsOutOfService() bool {
+	return d.Severity == DefectSeverityOutOfService && d.Status == DVIRDefectOpen
+}
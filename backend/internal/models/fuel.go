@@ -15,6 +15,10 @@ const (
 	FuelAlertTypeTheftSuspected    FuelAlertType = "THEFT_SUSPECTED"
 	FuelAlertTypeEfficiencyAnomaly FuelAlertType = "EFFICIENCY_ANOMALY"
 	FuelAlertTypePriceAnomaly      FuelAlertType = "PRICE_ANOMALY"
+	FuelAlertTypeExcessConsumption FuelAlertType = "EXCESS_CONSUMPTION"
+	FuelAlertTypeEfficiencyDrop    FuelAlertType = "EFFICIENCY_DROP"
+	FuelAlertTypeRefuelFrequency   FuelAlertType = "REFUEL_FREQUENCY"
+	FuelAlertTypeCardMismatch      FuelAlertType = "CARD_MISMATCH"
 
 	AlertSeverityLow      AlertSeverity = "LOW"
 	AlertSeverityMedium   AlertSeverity = "MEDIUM"
@@ -53,8 +57,9 @@ type FuelEvent struct {
 	StationID    string `json:"station_id,omitempty"`
 
 	// Receipt information
-	ReceiptNumber   string `json:"receipt_number,omitempty"`
-	ReceiptPhotoURL string `json:"receipt_photo_url,omitempty"`
+	ReceiptNumber   string     `json:"receipt_number,omitempty"`
+	ReceiptPhotoURL string     `json:"receipt_photo_url,omitempty"`
+	FueledAt        *time.Time `json:"fueled_at,omitempty" gorm:"index"` // Time of purchase on the receipt; CreatedAt is when it was entered
 
 	// Verification
 	VerifiedBy        *uint      `json:"verified_by,omitempty" gorm:"index"`
@@ -120,6 +125,9 @@ type FuelAlert struct {
 	EscalationLevel  int        `json:"escalation_level" gorm:"default:0"`
 	LastEscalatedAt  *time.Time `json:"last_escalated_at,omitempty"`
 
+	// Rule that raised the alert, if any
+	FuelThresholdID *uint `json:"fuel_threshold_id,omitempty" gorm:"index"`
+
 	// Audit fields
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
@@ -139,17 +147,93 @@ type FuelAlert struct {
 	ResolvedByUser *UserAccount `json:"resolved_by_user,omitempty" gorm:"foreignKey:ResolvedBy"`
 }
 
+// FuelLevelEventType classifies a change detected in tank-level telemetry
+type FuelLevelEventType string
+
+const (
+	FuelLevelEventRefuel FuelLevelEventType = "REFUEL"
+	FuelLevelEventDrop   FuelLevelEventType = "DROP"
+)
+
+// FuelLevelEventStatus is the outcome of reconciling a detected change
+type FuelLevelEventStatus string
+
+const (
+	FuelLevelStatusReconciled     FuelLevelEventStatus = "RECONCILED"      // Refuel matches a reported FuelEvent
+	FuelLevelStatusMismatch       FuelLevelEventStatus = "MISMATCH"        // Reported litres differ from what reached the tank
+	FuelLevelStatusUnreported     FuelLevelEventStatus = "UNREPORTED"      // Refuel with no FuelEvent
+	FuelLevelStatusTheftSuspected FuelLevelEventStatus = "THEFT_SUSPECTED" // Drop while parked beyond idle burn
+)
+
+// FuelLevelEvent is a refuel or sudden drop detected from TelemetryLog.FuelLevel
+type FuelLevelEvent struct {
+	ID        uint                 `json:"id" gorm:"primaryKey"`
+	VehicleID uint                 `json:"vehicle_id" gorm:"not null;uniqueIndex:idx_fuel_level_event,priority:1"`
+	EventType FuelLevelEventType   `json:"event_type" gorm:"type:varchar(20);not null;uniqueIndex:idx_fuel_level_event,priority:2"`
+	StartedAt time.Time            `json:"started_at" gorm:"not null;uniqueIndex:idx_fuel_level_event,priority:3"`
+	EndedAt   time.Time            `json:"ended_at"`
+	Status    FuelLevelEventStatus `json:"status" gorm:"type:varchar(20);index"`
+
+	// Smoothed tank contents in litres
+	LitresBefore   float64 `json:"litres_before" gorm:"type:decimal(8,2)"`
+	LitresAfter    float64 `json:"litres_after" gorm:"type:decimal(8,2)"`
+	ChangeLitres   float64 `json:"change_litres" gorm:"type:decimal(8,2)"`   // Positive for refuels, negative for drops
+	ExpectedLitres float64 `json:"expected_litres" gorm:"type:decimal(8,2)"` // Level expected after the period (idle burn only)
+	EngineOn       bool    `json:"engine_on"`
+
+	// Reconciliation against receipts
+	ReportedLitres *float64 `json:"reported_litres,omitempty" gorm:"type:decimal(8,2)"`
+	FuelEventID    *uint    `json:"fuel_event_id,omitempty" gorm:"index"`
+	FuelAlertID    *uint    `json:"fuel_alert_id,omitempty" gorm:"index"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// Associations
+	Vehicle   *Vehicle   `json:"vehicle,omitempty" gorm:"foreignKey:VehicleID"`
+	FuelEvent *FuelEvent `json:"fuel_event,omitempty" gorm:"foreignKey:FuelEventID"`
+}
+
+// FuelThresholdType is the rule a FuelThreshold configures
+type FuelThresholdType string
+
+const (
+	FuelThresholdConsumptionVariance FuelThresholdType = "CONSUMPTION_VARIANCE" // Litres bought above the vehicle's usual L/km
+	FuelThresholdEfficiencyDrop      FuelThresholdType = "EFFICIENCY_DROP"      // Last week's km/L against the previous month
+	FuelThresholdRefuelFrequency     FuelThresholdType = "REFUEL_FREQUENCY"     // Refuels within 24 hours
+	FuelThresholdPriceVariance       FuelThresholdType = "PRICE_VARIANCE"       // Receipt price above the regional median
+)
+
+// FuelThresholdUnit is the unit a threshold value is expressed in
+type FuelThresholdUnit string
+
+const (
+	FuelThresholdUnitPercentage  FuelThresholdUnit = "PERCENTAGE"
+	FuelThresholdUnitLiters      FuelThresholdUnit = "LITERS"
+	FuelThresholdUnitKmPerLiter  FuelThresholdUnit = "KM_PER_LITER"
+	FuelThresholdUnitCount       FuelThresholdUnit = "COUNT"
+	FuelThresholdUnitINRPerLiter FuelThresholdUnit = "INR_PER_LITER"
+)
+
+// FuelThresholdUnits lists the units each threshold type accepts
+var FuelThresholdUnits = map[FuelThresholdType][]FuelThresholdUnit{
+	FuelThresholdConsumptionVariance: {FuelThresholdUnitPercentage, FuelThresholdUnitLiters},
+	FuelThresholdEfficiencyDrop:      {FuelThresholdUnitPercentage, FuelThresholdUnitKmPerLiter},
+	FuelThresholdRefuelFrequency:     {FuelThresholdUnitCount},
+	FuelThresholdPriceVariance:       {FuelThresholdUnitPercentage, FuelThresholdUnitINRPerLiter},
+}
+
 // FuelThreshold represents configurable thresholds for fuel monitoring
 type FuelThreshold struct {
-	ID            uint           `json:"id" gorm:"primaryKey"`
-	VehicleID     *uint          `json:"vehicle_id,omitempty" gorm:"index"` // NULL means global threshold
-	ThresholdType string         `json:"threshold_type" gorm:"not null"`    // CONSUMPTION_VARIANCE, EFFICIENCY_DROP, REFUEL_FREQUENCY
-	Value         float64        `json:"value" gorm:"type:decimal(10,2);not null"`
-	Unit          string         `json:"unit" gorm:"not null"` // PERCENTAGE, LITERS, KM_PER_LITER
-	IsActive      bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+	ID            uint              `json:"id" gorm:"primaryKey"`
+	VehicleID     *uint             `json:"vehicle_id,omitempty" gorm:"index"`               // NULL means global threshold
+	ThresholdType FuelThresholdType `json:"threshold_type" gorm:"type:varchar(30);not null"` // CONSUMPTION_VARIANCE, EFFICIENCY_DROP, REFUEL_FREQUENCY
+	Value         float64           `json:"value" gorm:"type:decimal(10,2);not null"`
+	Unit          FuelThresholdUnit `json:"unit" gorm:"type:varchar(20);not null"` // PERCENTAGE, LITERS, KM_PER_LITER, COUNT
+	Severity      AlertSeverity     `json:"severity" gorm:"type:varchar(20)"`      // Severity of raised alerts, MEDIUM when empty
+	IsActive      bool              `json:"is_active"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt    `json:"-" gorm:"index"`
 
 	// Associations
 	Vehicle *Vehicle `json:"vehicle,omitempty" gorm:"foreignKey:VehicleID"`
@@ -157,21 +241,88 @@ type FuelThreshold struct {
 
 // FuelStation represents a known fuel station
 type FuelStation struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Name      string         `json:"name" gorm:"not null"`
-	Brand     string         `json:"brand"` // HP, BPCL, IOC, Shell, etc.
-	Address   string         `json:"address"`
-	City      string         `json:"city"`
-	State     string         `json:"state"`
-	Pincode   string         `json:"pincode"`
-	Latitude  float64        `json:"latitude" gorm:"type:decimal(10,8);not null"`
-	Longitude float64        `json:"longitude" gorm:"type:decimal(11,8);not null"`
-	IsActive  bool           `json:"is_active" gorm:"default:true"`
+	ID         uint     `json:"id" gorm:"primaryKey"`
+	ExternalID string   `json:"external_id,omitempty" gorm:"type:varchar(100);index"` // Outlet code from the brand or an imported registry
+	Name       string   `json:"name" gorm:"not null"`
+	Brand      string   `json:"brand"` // HP, BPCL, IOC, Shell, etc.
+	Address    string   `json:"address"`
+	City       string   `json:"city"`
+	State      string   `json:"state"`
+	Pincode    string   `json:"pincode"`
+	Latitude   float64  `json:"latitude" gorm:"type:decimal(10,8);not null;index"`
+	Longitude  float64  `json:"longitude" gorm:"type:decimal(11,8);not null;index"`
+	Phone      string   `json:"phone,omitempty"`
+	FuelTypes  []string `json:"fuel_types,omitempty" gorm:"serializer:json;type:text"` // DIESEL, PETROL, CNG, ...
+	Amenities  []string `json:"amenities,omitempty" gorm:"serializer:json;type:text"`  // Parking, restroom, food, ...
+	Is24Hours  bool     `json:"is_24_hours"`
+	IsActive   bool     `json:"is_active"`
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// FuelStationMatch is a station found by a radius or along-route search
+type FuelStationMatch struct {
+	FuelStation
+	DistanceMeters    float64    `json:"distance_meters"`               // From the search point, or off the route
+	RouteOffsetMeters *float64   `json:"route_offset_meters,omitempty"` // How far along the route, for route searches
+	Preferred         bool       `json:"preferred"`
+	PreferredPriority *int       `json:"preferred_priority,omitempty"`
+	LatestPrice       *FuelPrice `json:"latest_price,omitempty"`
+}
+
+// FleetPreferredStation puts a station on a fleet's preferred list; a lower Priority is preferred first
+type FleetPreferredStation struct {
+	ID            uint   `json:"id" gorm:"primaryKey"`
+	FleetID       uint   `json:"fleet_id" gorm:"not null;uniqueIndex:idx_fleet_preferred_station"`
+	FuelStationID uint   `json:"fuel_station_id" gorm:"not null;uniqueIndex:idx_fleet_preferred_station"`
+	Priority      int    `json:"priority"`
+	Notes         string `json:"notes,omitempty"`
+	CreatedBy     *uint  `json:"created_by,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Associations
+	FuelStation *FuelStation `json:"fuel_station,omitempty" gorm:"foreignKey:FuelStationID"`
+}
+
+// FuelPriceSourceEvents marks history rows computed from verified fuel events; other sources name an imported feed
+const FuelPriceSourceEvents = "EVENTS"
+
+// FuelPrice is one day's price for a fuel type at a station, in a region (State), or nationally
+// when both are empty
+type FuelPrice struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	Date          time.Time `json:"date" gorm:"not null;index:idx_fuel_price_day,priority:1"` // UTC midnight
+	Region        string    `json:"region" gorm:"type:varchar(50);index:idx_fuel_price_day,priority:2"`
+	FuelType      string    `json:"fuel_type" gorm:"type:varchar(20);not null;index:idx_fuel_price_day,priority:3"`
+	FuelStationID *uint     `json:"fuel_station_id,omitempty" gorm:"index"`
+	Source        string    `json:"source" gorm:"type:varchar(50);not null;index"` // EVENTS or the feed name
+
+	PricePerLiter float64 `json:"price_per_liter" gorm:"type:decimal(8,2);not null"` // Median for EVENTS rows
+	MinPrice      float64 `json:"min_price" gorm:"type:decimal(8,2)"`
+	MaxPrice      float64 `json:"max_price" gorm:"type:decimal(8,2)"`
+	Samples       int     `json:"samples"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Associations
+	FuelStation *FuelStation `json:"fuel_station,omitempty" gorm:"foreignKey:FuelStationID"`
+}
+
+// FuelPricePoint is one day of a price trend
+type FuelPricePoint struct {
+	Date          time.Time `json:"date"`
+	PricePerLiter float64   `json:"price_per_liter"` // Median of the day's prices
+	MinPrice      float64   `json:"min_price"`
+	MaxPrice      float64   `json:"max_price"`
+	Samples       int       `json:"samples"`
+	ChangePercent *float64  `json:"change_percent,omitempty"` // Against the previous point
+}
+
 // FuelAnalytics represents fuel consumption analytics
 type FuelAnalytics struct {
 	VehicleID               uint             `json:"vehicle_id"`
@@ -196,6 +347,8 @@ type FuelAnalytics struct {
 	CostSavings             float64          `json:"cost_savings"` // Alias for theft_savings
 	TopEfficientVehicles    []TopPerformer   `json:"top_efficient_vehicles"`
 	DailyTrends             []DailyFuelTrend `json:"daily_trends"`
+	AveragePricePerLiter    float64          `json:"average_price_per_liter"`
+	PriceTrend              []FuelPricePoint `json:"price_trend"` // National daily median of verified fills and feeds
 }
 
 // DailyFuelTrend represents daily fuel consumption trend
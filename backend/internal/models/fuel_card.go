@@ -0,0 +1,163 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FuelCardField is a canonical statement column a provider's CSV header is mapped to
+type FuelCardField string
+
+const (
+	FuelCardFieldCardNumber     FuelCardField = "card_number"
+	FuelCardFieldLicensePlate   FuelCardField = "license_plate"
+	FuelCardFieldTransactionRef FuelCardField = "transaction_ref"
+	FuelCardFieldTransactedAt   FuelCardField = "transacted_at"
+	FuelCardFieldDate           FuelCardField = "date" // Used with FuelCardFieldTime when a statement splits date and time
+	FuelCardFieldTime           FuelCardField = "time"
+	FuelCardFieldLitres         FuelCardField = "litres"
+	FuelCardFieldAmount         FuelCardField = "amount"
+	FuelCardFieldPricePerLitre  FuelCardField = "price_per_litre"
+	FuelCardFieldFuelType       FuelCardField = "fuel_type"
+	FuelCardFieldStationName    FuelCardField = "station_name"
+	FuelCardFieldStationBrand   FuelCardField = "station_brand"
+	FuelCardFieldLocation       FuelCardField = "location"
+	FuelCardFieldLatitude       FuelCardField = "latitude"
+	FuelCardFieldLongitude      FuelCardField = "longitude"
+	FuelCardFieldOdometer       FuelCardField = "odometer"
+)
+
+// FuelCardTransactionStatus is the outcome of reconciling a card transaction
+type FuelCardTransactionStatus string
+
+const (
+	FuelCardTxnReconciled FuelCardTransactionStatus = "RECONCILED" // Matched an existing FuelEvent
+	FuelCardTxnCreated    FuelCardTransactionStatus = "CREATED"    // A FuelEvent was created from the transaction
+	FuelCardTxnUnmatched  FuelCardTransactionStatus = "UNMATCHED"  // No vehicle for the card or plate
+)
+
+// FuelCardFlag marks a reason a card transaction needs review
+type FuelCardFlag string
+
+const (
+	FuelCardFlagVehicleElsewhere FuelCardFlag = "VEHICLE_ELSEWHERE" // GPS puts the vehicle away from the station
+	FuelCardFlagExceedsCapacity  FuelCardFlag = "EXCEEDS_CAPACITY"  // More litres than the tank holds
+	FuelCardFlagLitresMismatch   FuelCardFlag = "LITRES_MISMATCH"   // Differs from the FuelEvent entered by hand
+	FuelCardFlagNoGPS            FuelCardFlag = "NO_GPS"            // No location pings around the transaction
+	FuelCardFlagUnknownCard      FuelCardFlag = "UNKNOWN_CARD"      // Card not assigned to a vehicle
+)
+
+// FuelCardProvider describes one fuel-card issuer's statement format
+type FuelCardProvider struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name" gorm:"uniqueIndex;not null"` // e.g. "IOCL XTRAPOWER", "BPCL SmartFleet"
+
+	// Columns maps canonical fields to the provider's CSV header, e.g. {"litres": "Qty (Ltr)"}
+	Columns    map[FuelCardField]string `json:"columns" gorm:"serializer:json;type:text"`
+	Delimiter  string                   `json:"delimiter" gorm:"type:varchar(1)"`    // Comma when empty
+	DateFormat string                   `json:"date_format" gorm:"type:varchar(50)"` // Go layout; common formats are tried when empty
+	Timezone   string                   `json:"timezone" gorm:"type:varchar(50)"`    // IANA zone of statement times, UTC when empty
+	IsActive   bool                     `json:"is_active"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// FuelCard assigns a provider's card to a vehicle (and optionally its usual driver)
+type FuelCard struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	ProviderID uint   `json:"provider_id" gorm:"not null;uniqueIndex:idx_fuel_card_number,priority:1"`
+	CardNumber string `json:"card_number" gorm:"not null;uniqueIndex:idx_fuel_card_number,priority:2"`
+	VehicleID  *uint  `json:"vehicle_id,omitempty" gorm:"index"`
+	DriverID   *uint  `json:"driver_id,omitempty" gorm:"index"`
+	IsActive   bool   `json:"is_active"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Associations
+	Provider *FuelCardProvider `json:"provider,omitempty" gorm:"foreignKey:ProviderID"`
+	Vehicle  *Vehicle          `json:"vehicle,omitempty" gorm:"foreignKey:VehicleID"`
+	Driver   *Driver           `json:"driver,omitempty" gorm:"foreignKey:DriverID"`
+}
+
+// FuelCardImport records one uploaded statement file and its outcome
+type FuelCardImport struct {
+	ID         uint     `json:"id" gorm:"primaryKey"`
+	ProviderID uint     `json:"provider_id" gorm:"not null;index"`
+	FileName   string   `json:"file_name"`
+	ImportedBy uint     `json:"imported_by" gorm:"index"`
+	Rows       int      `json:"rows"`
+	Imported   int      `json:"imported"`
+	Duplicates int      `json:"duplicates"`
+	Failed     int      `json:"failed"`
+	Reconciled int      `json:"reconciled"` // Matched existing FuelEvents
+	Created    int      `json:"created"`    // New FuelEvents
+	Unmatched  int      `json:"unmatched"`
+	Flagged    int      `json:"flagged"`
+	Errors     []string `json:"errors,omitempty" gorm:"serializer:json;type:text"` // "row N: reason"
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// Associations
+	Provider *FuelCardProvider `json:"provider,omitempty" gorm:"foreignKey:ProviderID"`
+}
+
+// FuelCardTransaction is one statement line and how it was reconciled
+type FuelCardTransaction struct {
+	ID             uint                      `json:"id" gorm:"primaryKey"`
+	ImportID       uint                      `json:"import_id" gorm:"not null;index"`
+	ProviderID     uint                      `json:"provider_id" gorm:"not null;uniqueIndex:idx_fuel_card_txn_ref,priority:1"`
+	TransactionRef string                    `json:"transaction_ref" gorm:"not null;uniqueIndex:idx_fuel_card_txn_ref,priority:2"`
+	CardNumber     string                    `json:"card_number" gorm:"index"`
+	LicensePlate   string                    `json:"license_plate,omitempty"` // As printed on the statement
+	TransactedAt   time.Time                 `json:"transacted_at" gorm:"not null;index"`
+	Status         FuelCardTransactionStatus `json:"status" gorm:"type:varchar(20);index"`
+	Flags          []FuelCardFlag            `json:"flags,omitempty" gorm:"serializer:json;type:text"`
+	Flagged        bool                      `json:"flagged" gorm:"index"` // Any flag other than NO_GPS; needs review
+
+	// Purchase
+	Litres        float64 `json:"litres" gorm:"type:decimal(8,2);not null"`
+	AmountINR     float64 `json:"amount_inr" gorm:"type:decimal(10,2);not null"`
+	PricePerLiter float64 `json:"price_per_liter" gorm:"type:decimal(8,2)"`
+	FuelType      string  `json:"fuel_type,omitempty"`
+	OdometerKm    float64 `json:"odometer_km,omitempty" gorm:"type:decimal(10,2)"`
+
+	// Station as reported by the provider
+	StationName  string   `json:"station_name,omitempty"`
+	StationBrand string   `json:"station_brand,omitempty"`
+	Location     string   `json:"location,omitempty"`
+	Latitude     *float64 `json:"latitude,omitempty" gorm:"type:decimal(10,8)"`
+	Longitude    *float64 `json:"longitude,omitempty" gorm:"type:decimal(11,8)"`
+
+	// GPS check: the station the vehicle was seen at, or how far it was from the reported one
+	FuelStationID  *uint    `json:"fuel_station_id,omitempty" gorm:"index"`
+	DistanceMeters *float64 `json:"distance_meters,omitempty" gorm:"type:decimal(10,1)"`
+
+	// Matches
+	VehicleID   *uint `json:"vehicle_id,omitempty" gorm:"index"`
+	DriverID    *uint `json:"driver_id,omitempty" gorm:"index"`
+	FuelEventID *uint `json:"fuel_event_id,omitempty" gorm:"index"`
+	FuelAlertID *uint `json:"fuel_alert_id,omitempty" gorm:"index"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Associations
+	Vehicle     *Vehicle     `json:"vehicle,omitempty" gorm:"foreignKey:VehicleID"`
+	FuelEvent   *FuelEvent   `json:"fuel_event,omitempty" gorm:"foreignKey:FuelEventID"`
+	FuelStation *FuelStation `json:"fuel_station,omitempty" gorm:"foreignKey:FuelStationID"`
+}
+
+// HasFlag reports whether the transaction carries flag
+func (t *FuelCardTransaction) HasFlag(flag FuelCardFlag) bool {
+	for _, f := range t.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"math"
 	"time"
 
 	"gorm.io/gorm"
@@ -95,6 +96,9 @@ type Geofence struct {
 	// For polygon geofences
 	Coordinates Coordinates `json:"coordinates,omitempty" gorm:"type:text"` // Array of lat/lng points
 
+	// Speed cap inside the geofence, taking precedence over road limits
+	SpeedLimit *float64 `json:"speed_limit,omitempty"` // km/h
+
 	// Configuration
 	IsActive     bool           `json:"is_active" gorm:"default:true"`
 	AlertOnEnter bool           `json:"alert_on_enter" gorm:"default:false"`
@@ -150,32 +154,57 @@ type RouteDeviation struct {
 	Driver  *Driver  `json:"driver,omitempty" gorm:"foreignKey:DriverID"`
 }
 
-// CalculateDistance calculates distance between two GPS points using Haversine formula
+// CalculateDistance calculates distance between two GPS points in kilometers
 func CalculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
-	const earthRadius = 6371 // Earth's radius in kilometers
-
-	// Convert degrees to radians
-	lat1Rad := lat1 * 3.14159265359 / 180
-	lon1Rad := lon1 * 3.14159265359 / 180
-	lat2Rad := lat2 * 3.14159265359 / 180
-	lon2Rad := lon2 * 3.14159265359 / 180
-
-	// Haversine formula
-	dlat := lat2Rad - lat1Rad
-	dlon := lon2Rad - lon1Rad
-
-	a := 0.5 - 0.5*(dlat*dlat+(1-lat1Rad*lat1Rad)*dlon*dlon)
-	return earthRadius * 2 * (a * a)
+	return HaversineMeters(lat1, lon1, lat2, lon2) / 1000
 }
 
-// IsInGeofence checks if a point is within a circular geofence
+// IsInGeofence checks if a point is within the geofence. Circles use the center and radius
+// fields; polygons are latitude, longitude pairs and rectangles min and max latitude and longitude.
 func (g *Geofence) IsInGeofence(latitude, longitude float64) bool {
-	if g.Type != "CIRCLE" || g.CenterLatitude == nil || g.CenterLongitude == nil || g.Radius == nil {
+	switch g.ShapeType {
+	case GeofenceShapeTypeCircle:
+		if g.CenterLatitude == nil || g.CenterLongitude == nil || g.Radius == nil {
+			return false
+		}
+		return HaversineMeters(*g.CenterLatitude, *g.CenterLongitude, latitude, longitude) <= *g.Radius
+	case GeofenceShapeTypeRectangle:
+		if len(g.Coordinates) < 4 {
+			return false
+		}
+		return latitude >= g.Coordinates[0] && latitude <= g.Coordinates[2] &&
+			longitude >= g.Coordinates[1] && longitude <= g.Coordinates[3]
+	case GeofenceShapeTypePolygon:
+		points := g.Coordinates
+		if len(points) < 6 {
+			return false
+		}
+		// Ray casting
+		inside := false
+		j := len(points) - 2
+		for i := 0; i+1 < len(points); i += 2 {
+			if (points[i+1] > longitude) != (points[j+1] > longitude) &&
+				latitude < (points[j]-points[i])*(longitude-points[i+1])/(points[j+1]-points[i+1])+points[i] {
+				inside = !inside
+			}
+			j = i
+		}
+		return inside
+	default:
 		return false
 	}
+}
+
+// earthRadiusMeters is the mean Earth radius used for great-circle distances
+const earthRadiusMeters = 6371000
 
-	distance := CalculateDistance(*g.CenterLatitude, *g.CenterLongitude, latitude, longitude) * 1000 // Convert to meters
-	return distance <= *g.Radius
+// HaversineMeters returns the great-circle distance between two coordinates in meters
+func HaversineMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLon := (lon2 - lon1) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return earthRadiusMeters * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
 }
 
 // GetLastKnownLocation returns the latest location for a vehicle
@@ -17,21 +17,32 @@ type MaintenanceTask struct {
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
-// ServiceSchedule defines when a task should be performed for a vehicle
+// ServiceSchedule defines when a task should be performed for a vehicle. The service is due
+// when the first of its mileage, calendar and engine-hour intervals is reached.
 type ServiceSchedule struct {
-	ID                uint           `json:"id" gorm:"primaryKey"`
-	VehicleID         uint           `json:"vehicle_id" gorm:"index"`
-	MaintenanceTaskID uint           `json:"maintenance_task_id" gorm:"index"`
-	IntervalMileage   int            `json:"interval_mileage"` // e.g., every 10,000 km
-	IntervalMonths    int            `json:"interval_months"`  // e.g., every 6 months
-	LastPerformedAt   *time.Time     `json:"last_performed_at"`
-	LastMileage       int            `json:"last_mileage"`
-	NextDueDate       *time.Time     `json:"next_due_date"`
-	NextDueMileage    int            `json:"next_due_mileage"`
-	Status            string         `json:"status" gorm:"default:'ACTIVE'"` // ACTIVE, INACTIVE
-	CreatedAt         time.Time      `json:"created_at"`
-	UpdatedAt         time.Time      `json:"updated_at"`
-	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                  uint           `json:"id" gorm:"primaryKey"`
+	VehicleID           uint           `json:"vehicle_id" gorm:"index"`
+	MaintenanceTaskID   uint           `json:"maintenance_task_id" gorm:"index"`
+	TemplateID          *uint          `json:"template_id,omitempty" gorm:"index"` // Template the schedule was created from
+	IntervalMileage     int            `json:"interval_mileage"`                   // e.g., every 10,000 km
+	IntervalMonths      int            `json:"interval_months"`                    // e.g., every 6 months
+	IntervalEngineHours float64        `json:"interval_engine_hours"`              // e.g., every 500 engine hours
+	DueSoonMileage      int            `json:"due_soon_mileage"`                   // Warn this many km before due (default 500)
+	DueSoonDays         int            `json:"due_soon_days"`                      // Warn this many days before due (default 14)
+	DueSoonEngineHours  float64        `json:"due_soon_engine_hours"`              // Warn this many hours before due (default 25)
+	LastPerformedAt     *time.Time     `json:"last_performed_at"`
+	LastMileage         int            `json:"last_mileage"`
+	LastEngineHours     float64        `json:"last_engine_hours"`
+	NextDueDate         *time.Time     `json:"next_due_date"`
+	NextDueMileage      int            `json:"next_due_mileage"`
+	NextDueEngineHours  float64        `json:"next_due_engine_hours"`
+	DueStatus           string         `json:"due_status" gorm:"type:varchar(20);default:'OK'"` // OK, DUE_SOON, DUE
+	DueReason           string         `json:"due_reason,omitempty"`                            // MILEAGE, TIME, ENGINE_HOURS; comma-separated
+	WorkOrderID         *uint          `json:"work_order_id,omitempty" gorm:"index"`            // Work order opened for the current service
+	Status              string         `json:"status" gorm:"default:'ACTIVE'"`                  // ACTIVE, INACTIVE
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `json:"-" gorm:"index"`
 
 	Vehicle         Vehicle         `json:"vehicle" gorm:"foreignKey:VehicleID"`
 	MaintenanceTask MaintenanceTask `json:"maintenance_task" gorm:"foreignKey:MaintenanceTaskID"`
@@ -43,7 +54,7 @@ type WorkOrder struct {
 	VehicleID         uint           `json:"vehicle_id" gorm:"index"`
 	MaintenanceTaskID *uint          `json:"maintenance_task_id" gorm:"index"` // Optional, can be ad-hoc
 	Description       string         `json:"description"`
-	Status            string         `json:"status" gorm:"default:'OPEN'"` // OPEN, IN_PROGRESS, COMPLETED, CANCELLED
+	Status            string         `json:"status" gorm:"default:'OPEN'"` // RECOMMENDED, OPEN, IN_PROGRESS, COMPLETED, CANCELLED
 	Priority          string         `json:"priority" gorm:"default:'MEDIUM'"`
 	AssignedTo        string         `json:"assigned_to"` // Mechanic name or ID
 	ScheduledDate     *time.Time     `json:"scheduled_date"`
@@ -58,6 +69,8 @@ type WorkOrder struct {
 
 	Vehicle         Vehicle          `json:"vehicle" gorm:"foreignKey:VehicleID"`
 	MaintenanceTask *MaintenanceTask `json:"maintenance_task,omitempty" gorm:"foreignKey:MaintenanceTaskID"`
+	Parts           []WorkOrderPart  `json:"parts,omitempty" gorm:"foreignKey:WorkOrderID"`
+	Labor           []WorkOrderLabor `json:"labor,omitempty" gorm:"foreignKey:WorkOrderID"`
 }
 
 // DVIR represents a Driver Vehicle Inspection Report
@@ -69,26 +82,90 @@ type DVIR struct {
 	Odometer     int            `json:"odometer"`
 	Location     string         `json:"location"`
 	Status       string         `json:"status"` // SAFE, UNSAFE, REPAIRS_NEEDED
-	Defects      string         `json:"defects" gorm:"type:jsonb"` // JSON array of defects found
-	MechanicSign string         `json:"mechanic_sign"`             // Name of mechanic who reviewed
+	ChecklistID  *uint          `json:"checklist_id,omitempty"`    // Checklist the inspection followed
+	Defects      string         `json:"defects" gorm:"type:jsonb"` // JSON array of defect descriptions
+	MechanicSign string         `json:"mechanic_sign"`             // Mechanic who certified the last defect
 	DriverSign   string         `json:"driver_sign"`               // Digital signature/name
 	SignedAt     time.Time      `json:"signed_at"`
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
 
-	Vehicle Vehicle `json:"vehicle" gorm:"foreignKey:VehicleID"`
-	Driver  Driver  `json:"driver" gorm:"foreignKey:DriverID"`
+	Vehicle       Vehicle      `json:"vehicle" gorm:"foreignKey:VehicleID"`
+	Driver        Driver       `json:"driver" gorm:"foreignKey:DriverID"`
+	DefectRecords []DVIRDefect `json:"defect_records,omitempty" gorm:"foreignKey:DVIRID"`
 }
 
-// IsDue checks if the service schedule is due based on current mileage and time
-func (s *ServiceSchedule) IsDue(currentMileage int) bool {
+// Service schedule due states
+const (
+	ServiceDueOK      = "OK"
+	ServiceDueSoon    = "DUE_SOON"
+	ServiceDueNow     = "DUE"
+	ServiceDueMileage = "MILEAGE"
+	ServiceDueTime    = "TIME"
+	ServiceDueHours   = "ENGINE_HOURS"
+)
+
+// Due-soon windows used when a schedule does not set its own
+const (
+	DefaultDueSoonMileage     = 500
+	DefaultDueSoonDays        = 14
+	DefaultDueSoonEngineHours = 25.0
+)
+
+// DueState reports whether the schedule is due, due soon or OK, and which intervals made it so.
+// Whichever interval comes first decides.
+func (s *ServiceSchedule) DueState(currentMileage int, currentEngineHours float64, now time.Time) (string, []string) {
 	if s.Status != "ACTIVE" {
-		return false
+		return ServiceDueOK, nil
 	}
 
-	mileageDue := s.NextDueMileage > 0 && currentMileage >= s.NextDueMileage
-	timeDue := s.NextDueDate != nil && time.Now().After(*s.NextDueDate)
+	soonMileage, soonDays, soonHours := s.DueSoonMileage, s.DueSoonDays, s.DueSoonEngineHours
+	if soonMileage <= 0 {
+		soonMileage = DefaultDueSoonMileage
+	}
+	if soonDays <= 0 {
+		soonDays = DefaultDueSoonDays
+	}
+	if soonHours <= 0 {
+		soonHours = DefaultDueSoonEngineHours
+	}
+
+	var due, soon []string
+	if s.NextDueMileage > 0 {
+		if currentMileage >= s.NextDueMileage {
+			due = append(due, ServiceDueMileage)
+		} else if currentMileage >= s.NextDueMileage-soonMileage {
+			soon = append(soon, ServiceDueMileage)
+		}
+	}
+	if s.NextDueEngineHours > 0 && currentEngineHours > 0 {
+		if currentEngineHours >= s.NextDueEngineHours {
+			due = append(due, ServiceDueHours)
+		} else if currentEngineHours >= s.NextDueEngineHours-soonHours {
+			soon = append(soon, ServiceDueHours)
+		}
+	}
+	if s.NextDueDate != nil {
+		if !now.Before(*s.NextDueDate) {
+			due = append(due, ServiceDueTime)
+		} else if !now.Before(s.NextDueDate.AddDate(0, 0, -soonDays)) {
+			soon = append(soon, ServiceDueTime)
+		}
+	}
+
+	switch {
+	case len(due) > 0:
+		return ServiceDueNow, due
+	case len(soon) > 0:
+		return ServiceDueSoon, soon
+	default:
+		return ServiceDueOK, nil
+	}
+}
 
-	return mileageDue || timeDue
+// IsDue checks if the service schedule is due based on current mileage, engine hours and time
+func (s *ServiceSchedule) IsDue(currentMileage int, currentEngineHours float64) bool {
+	state, _ := s.DueState(currentMileage, currentEngineHours, time.Now())
+	return state == ServiceDueNow
 }
@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+// MaintenanceComponent is the vehicle system a failure forecast is about
+type MaintenanceComponent string
+
+const (
+	ComponentCoolingSystem MaintenanceComponent = "COOLING_SYSTEM"
+	ComponentBattery       MaintenanceComponent = "BATTERY"
+	ComponentEngine        MaintenanceComponent = "ENGINE"
+	ComponentFuelSystem    MaintenanceComponent = "FUEL_SYSTEM"
+	ComponentEmissions     MaintenanceComponent = "EMISSIONS"
+	ComponentTransmission  MaintenanceComponent = "TRANSMISSION"
+	ComponentBrakes        MaintenanceComponent = "BRAKES"
+	ComponentElectrical    MaintenanceComponent = "ELECTRICAL"
+)
+
+// MaintenanceRiskLevel buckets a prediction's risk score
+type MaintenanceRiskLevel string
+
+const (
+	MaintenanceRiskLow      MaintenanceRiskLevel = "LOW"
+	MaintenanceRiskMedium   MaintenanceRiskLevel = "MEDIUM"
+	MaintenanceRiskHigh     MaintenanceRiskLevel = "HIGH"
+	MaintenanceRiskCritical MaintenanceRiskLevel = "CRITICAL"
+)
+
+// MaintenancePredictionStatus tracks a forecast through repair
+type MaintenancePredictionStatus string
+
+const (
+	MaintenancePredictionActive   MaintenancePredictionStatus = "ACTIVE"   // Latest forecast for the component
+	MaintenancePredictionCleared  MaintenancePredictionStatus = "CLEARED"  // Signals went back to normal
+	MaintenancePredictionResolved MaintenancePredictionStatus = "RESOLVED" // Its work order was completed
+)
+
+// WorkOrderStatusRecommended marks work orders raised by failure forecasts; they do not take the
+// vehicle off the road until someone opens them
+const WorkOrderStatusRecommended = "RECOMMENDED"
+
+// MaintenanceSignal is one piece of evidence behind a prediction
+type MaintenanceSignal struct {
+	Source      string   `json:"source"` // TELEMETRY, DTC, FUEL_EFFICIENCY
+	Name        string   `json:"name"`   // e.g. coolant_temp, P0217
+	Value       float64  `json:"value"`
+	Limit       float64  `json:"limit,omitempty"`
+	SlopePerDay *float64 `json:"slope_per_day,omitempty"`
+	Score       float64  `json:"score"` // 0-1 contribution before combining
+	Detail      string   `json:"detail"`
+}
+
+// MaintenancePrediction is the failure forecast for one component of a vehicle
+type MaintenancePrediction struct {
+	ID                 uint                        `json:"id" gorm:"primaryKey"`
+	VehicleID          uint                        `json:"vehicle_id" gorm:"not null;index"`
+	Component          MaintenanceComponent        `json:"component" gorm:"type:varchar(30);not null;index"`
+	Status             MaintenancePredictionStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	RiskScore          float64                     `json:"risk_score" gorm:"type:decimal(5,4)"` // 0-1
+	RiskLevel          MaintenanceRiskLevel        `json:"risk_level" gorm:"type:varchar(20);index"`
+	PredictedFailureAt *time.Time                  `json:"predicted_failure_at,omitempty"` // When a trend crosses its limit
+	Signals            []MaintenanceSignal         `json:"signals" gorm:"serializer:json;type:text"`
+	Recommendation     string                      `json:"recommendation"`
+	WorkOrderID        *uint                       `json:"work_order_id,omitempty" gorm:"index"`
+	WindowStart        time.Time                   `json:"window_start"`
+	WindowEnd          time.Time                   `json:"window_end"`
+	NotifiedLevel      MaintenanceRiskLevel        `json:"notified_level,omitempty" gorm:"type:varchar(20)"` // Highest level a due event was published for
+	ResolvedAt         *time.Time                  `json:"resolved_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Associations
+	Vehicle   *Vehicle   `json:"vehicle,omitempty" gorm:"foreignKey:VehicleID"`
+	WorkOrder *WorkOrder `json:"work_order,omitempty" gorm:"foreignKey:WorkOrderID"`
+}
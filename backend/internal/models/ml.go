@@ -63,3 +63,37 @@ type FuelAnomaly struct {
 	SuggestedAction string    `json:"suggested_action"`
 	Confidence      float64   `json:"confidence"`
 }
+
+// FuelAnomalyModel is an isolation forest trained on verified fuel events of one vehicle class.
+// Every training run stores a new version; the newest version of a class is the active one.
+type FuelAnomalyModel struct {
+	ID          uint        `json:"id" gorm:"primaryKey"`
+	VehicleType VehicleType `json:"vehicle_type" gorm:"type:varchar(20);not null;uniqueIndex:idx_fuel_model_version"`
+	Version     int         `json:"version" gorm:"not null;uniqueIndex:idx_fuel_model_version"`
+	Algorithm   string      `json:"algorithm" gorm:"type:varchar(30);default:'ISOLATION_FOREST'"`
+	IsActive    bool        `json:"is_active" gorm:"index"`
+
+	// Serialized model
+	FeatureNames  []string  `json:"feature_names" gorm:"type:text;serializer:json"`
+	Imputation    []float64 `json:"imputation" gorm:"type:text;serializer:json"` // Training medians substituted for missing features
+	Forest        string    `json:"-" gorm:"type:text;not null"`                 // JSON-encoded trees
+	TreeCount     int       `json:"tree_count"`
+	SampleSize    int       `json:"sample_size"`
+	Threshold     float64   `json:"threshold"`     // Anomaly score at or above which an event is flagged
+	Contamination float64   `json:"contamination"` // Share of training events expected above the threshold
+
+	// Training data
+	TrainingSamples int       `json:"training_samples"`
+	TrainedThrough  time.Time `json:"trained_through"` // Newest verified event used for training
+
+	// Hold-out evaluation against admin decisions (nil when no labelled events were available)
+	EvaluationSamples int      `json:"evaluation_samples"`
+	Precision         *float64 `json:"precision,omitempty"`
+	Recall            *float64 `json:"recall,omitempty"`
+	Accuracy          *float64 `json:"accuracy,omitempty"`
+	AUC               *float64 `json:"auc,omitempty"`
+
+	TrainedAt time.Time `json:"trained_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -45,26 +45,40 @@ type SafetyEvent struct {
 	Timestamp time.Time           `json:"timestamp" gorm:"index;not null"`
 	Address   string              `json:"address,omitempty"`
 	IsViewed  bool                `json:"is_viewed" gorm:"default:false"`
+	VideoClipID *uint             `json:"video_clip_id,omitempty" gorm:"index"` // Dashcam footage covering the event
 	CreatedAt time.Time           `json:"created_at"`
 	UpdatedAt time.Time           `json:"updated_at"`
 	DeletedAt gorm.DeletedAt      `json:"-" gorm:"index"`
 
-	Vehicle *Vehicle `json:"vehicle,omitempty" gorm:"foreignKey:VehicleID"`
-	Driver  *Driver  `json:"driver,omitempty" gorm:"foreignKey:DriverID"`
-	Trip    *Trip    `json:"trip,omitempty" gorm:"foreignKey:TripID"`
+	Vehicle   *Vehicle   `json:"vehicle,omitempty" gorm:"foreignKey:VehicleID"`
+	Driver    *Driver    `json:"driver,omitempty" gorm:"foreignKey:DriverID"`
+	Trip      *Trip      `json:"trip,omitempty" gorm:"foreignKey:TripID"`
+	VideoClip *VideoClip `json:"video_clip,omitempty" gorm:"foreignKey:VideoClipID"`
 }
 
-// DriverScore represents the calculated safety score for a driver
+// DriverScore represents the calculated safety score for a driver over a rolling window.
+// Event rates are weighted by severity and normalised by distance, or by time for speeding.
 type DriverScore struct {
 	ID              uint      `json:"id" gorm:"primaryKey"`
-	DriverID        uint      `json:"driver_id" gorm:"uniqueIndex"`
+	DriverID        uint      `json:"driver_id" gorm:"uniqueIndex:idx_driver_score_window"`
+	WindowDays      int       `json:"window_days" gorm:"uniqueIndex:idx_driver_score_window"` // 7, 30 or 90
+	PeriodStart     time.Time `json:"period_start"`
+	PeriodEnd       time.Time `json:"period_end"`
 	OverallScore    int       `json:"overall_score"` // 0-100
 	BrakingScore    int       `json:"braking_score"`
 	AccelScore      int       `json:"accel_score"`
 	CorneringScore  int       `json:"cornering_score"`
 	SpeedingScore   int       `json:"speeding_score"`
+	BrakingEvents   int       `json:"braking_events"`
+	AccelEvents     int       `json:"accel_events"`
+	CorneringEvents int       `json:"cornering_events"`
+	SpeedingEvents  int       `json:"speeding_events"`
 	TotalDistance   float64   `json:"total_distance"` // km analyzed
 	TotalDriveTime  float64   `json:"total_drive_time"` // hours analyzed
+	Provisional     bool      `json:"provisional"`      // Too little driving in the window to rank
+	Percentile      *float64  `json:"percentile,omitempty"` // Share of ranked drivers scoring at or below this one
+	FleetRank       *int      `json:"fleet_rank,omitempty"` // 1 is the safest ranked driver
+	FleetSize       int       `json:"fleet_size"`           // Drivers ranked in the window
 	LastCalculated  time.Time `json:"last_calculated"`
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// IMUCalibration is how a vehicle's accelerometer is mounted, learned from its own samples: the
+// direction gravity holds it up, and the direction it is pushed when the vehicle speeds up
+type IMUCalibration struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	VehicleID      uint      `json:"vehicle_id" gorm:"not null;uniqueIndex"`
+	UpX            float64   `json:"up_x"` // Unit vector in the sensor's frame
+	UpY            float64   `json:"up_y"`
+	UpZ            float64   `json:"up_z"`
+	ForwardX       float64   `json:"forward_x"` // Unit vector in the sensor's frame, perpendicular to up
+	ForwardY       float64   `json:"forward_y"`
+	ForwardZ       float64   `json:"forward_z"`
+	UpSamples      int       `json:"up_samples"`      // Stationary samples averaged into up
+	ForwardSamples int       `json:"forward_samples"` // Straight-line speed changes averaged into forward
+	Calibrated     bool      `json:"calibrated"`      // Forward is known, so braking and cornering can be told apart
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// IMUTraceSample is one accelerometer and gyro sample in the vehicle's frame, gravity removed
+type IMUTraceSample struct {
+	Timestamp    time.Time `json:"timestamp"`
+	Longitudinal float64   `json:"longitudinal"` // m/s², positive forward
+	Lateral      float64   `json:"lateral"`      // m/s², positive left
+	Vertical     float64   `json:"vertical"`     // m/s², positive up
+	YawRate      float64   `json:"yaw_rate"`     // deg/s, positive turning left
+	Magnitude    float64   `json:"magnitude"`    // g
+}
+
+// SafetyEventTrace is the sensor trace recorded around an impact
+type SafetyEventTrace struct {
+	ID            uint             `json:"id" gorm:"primaryKey"`
+	SafetyEventID uint             `json:"safety_event_id" gorm:"not null;uniqueIndex"`
+	VehicleID     uint             `json:"vehicle_id" gorm:"not null;index"`
+	TriggeredAt   time.Time        `json:"triggered_at"`
+	PreSeconds    float64          `json:"pre_seconds"`
+	PostSeconds   float64          `json:"post_seconds"`
+	Samples       []IMUTraceSample `json:"samples" gorm:"type:text;serializer:json"`
+	Complete      bool             `json:"complete"` // False until samples covering the whole post window have arrived
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// ServiceScheduleTemplate is a standard service interval for a vehicle type. Active templates
+// become service schedules when a vehicle of that type is created.
+type ServiceScheduleTemplate struct {
+	ID                  uint        `json:"id" gorm:"primaryKey"`
+	VehicleType         VehicleType `json:"vehicle_type" gorm:"type:varchar(20);not null;uniqueIndex:idx_schedule_template_task"`
+	MaintenanceTaskID   uint        `json:"maintenance_task_id" gorm:"not null;uniqueIndex:idx_schedule_template_task"`
+	IntervalMileage     int         `json:"interval_mileage"`
+	IntervalMonths      int         `json:"interval_months"`
+	IntervalEngineHours float64     `json:"interval_engine_hours"`
+	DueSoonMileage      int         `json:"due_soon_mileage"`
+	DueSoonDays         int         `json:"due_soon_days"`
+	DueSoonEngineHours  float64     `json:"due_soon_engine_hours"`
+	IsActive            bool        `json:"is_active" gorm:"index"`
+	CreatedBy           *uint       `json:"created_by,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Associations
+	MaintenanceTask *MaintenanceTask `json:"maintenance_task,omitempty" gorm:"foreignKey:MaintenanceTaskID"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+)
+
+// Session revocation reasons
+const (
+	SessionRevokedLogout         = "logout"
+	SessionRevokedByUser         = "revoked_by_user"
+	SessionRevokedAll            = "revoke_all"
+	SessionRevokedIdleTimeout    = "idle_timeout"
+	SessionRevokedTokenReuse     = "refresh_token_reuse"
+	SessionRevokedDeviceMismatch = "device_mismatch"
+)
+
+// UserSession is a logged-in device. Every refresh token issued by rotation from the
+// same login belongs to the session, so the session doubles as the refresh-token family.
+type UserSession struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	UserID            uint       `json:"user_id" gorm:"not null;index"`
+	DeviceID          string     `json:"device_id,omitempty"`   // Client-supplied install ID (X-Device-ID)
+	DeviceName        string     `json:"device_name,omitempty"` // e.g. "Pixel 7" (X-Device-Name)
+	DeviceFingerprint string     `json:"-" gorm:"index"`        // SHA-256 of device ID, or user agent when absent
+	UserAgent         string     `json:"user_agent,omitempty"`
+	IPAddress         string     `json:"ip_address,omitempty"` // IP at login
+	LastIPAddress     string     `json:"last_ip_address,omitempty"`
+	Location          string     `json:"location,omitempty"`    // Best-effort, from edge geo headers
+	AuthMethod        string     `json:"auth_method,omitempty"` // otp, password, sso
+	LastUsedAt        time.Time  `json:"last_used_at" gorm:"not null;index"`
+	ExpiresAt         time.Time  `json:"expires_at" gorm:"not null"` // Moved forward with each refresh token rotation
+	RevokedAt         *time.Time `json:"revoked_at,omitempty" gorm:"index"`
+	RevokedReason     string     `json:"revoked_reason,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	// Associations
+	User *UserAccount `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// IsActive checks if the session can still be used
+func (s *UserSession) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// IsIdle checks if the session has been unused for longer than timeout
+func (s *UserSession) IsIdle(timeout time.Duration) bool {
+	return timeout > 0 && time.Since(s.LastUsedAt) > timeout
+}
@@ -19,9 +19,9 @@ type SystemSettings struct {
 	DistanceUnit         string                `json:"distance_unit" gorm:"default:'KM'"`
 	FuelUnit             string                `json:"fuel_unit" gorm:"default:'LITERS'"`
 	DefaultLanguage      string                `json:"default_language" gorm:"default:'en'"`
-	AlertSettings        *AlertSettings        `json:"alert_settings" gorm:"type:json"`
-	NotificationSettings *NotificationSettings `json:"notification_settings" gorm:"type:json"`
-	SecuritySettings     *SecuritySettings     `json:"security_settings" gorm:"type:json"`
+	AlertSettings        *AlertSettings        `json:"alert_settings" gorm:"type:json;serializer:json"`
+	NotificationSettings *NotificationSettings `json:"notification_settings" gorm:"type:json;serializer:json"`
+	SecuritySettings     *SecuritySettings     `json:"security_settings" gorm:"type:json;serializer:json"`
 	MaintenanceReminders bool                  `json:"maintenance_reminders" gorm:"default:true"`
 	ComplianceReminders  bool                  `json:"compliance_reminders" gorm:"default:true"`
 	AutoBackup           bool                  `json:"auto_backup" gorm:"default:true"`
@@ -55,7 +55,42 @@ type SecuritySettings struct {
 	PasswordRequireLower  bool `json:"password_require_lower"`
 	PasswordRequireDigit  bool `json:"password_require_digit"`
 	PasswordRequireSymbol bool `json:"password_require_symbol"`
-	SessionTimeoutMinutes int  `json:"session_timeout_minutes"`
+	SessionTimeoutMinutes int  `json:"session_timeout_minutes"` // Idle minutes before a session is revoked; 0 keeps sessions until their refresh token expires
 	MaxLoginAttempts      int  `json:"max_login_attempts"`
 	TwoFactorEnabled      bool `json:"two_factor_enabled"`
+	RiskBlockThreshold    int  `json:"risk_block_threshold"` // Attempts scoring at or above this (0-100) are blocked
+}
+
+// DefaultSecuritySettings returns the security policy used when none is configured
+func DefaultSecuritySettings() *SecuritySettings {
+	return &SecuritySettings{
+		PasswordMinLength:     8,
+		PasswordRequireUpper:  true,
+		PasswordRequireLower:  true,
+		PasswordRequireDigit:  true,
+		PasswordRequireSymbol: false,
+		SessionTimeoutMinutes: 0,
+		MaxLoginAttempts:      5,
+		TwoFactorEnabled:      false,
+		RiskBlockThreshold:    70,
+	}
+}
+
+// WithDefaults fills unset numeric limits from the default policy
+func (s *SecuritySettings) WithDefaults() *SecuritySettings {
+	defaults := DefaultSecuritySettings()
+	if s == nil {
+		return defaults
+	}
+	merged := *s
+	if merged.PasswordMinLength <= 0 {
+		merged.PasswordMinLength = defaults.PasswordMinLength
+	}
+	if merged.MaxLoginAttempts <= 0 {
+		merged.MaxLoginAttempts = defaults.MaxLoginAttempts
+	}
+	if merged.RiskBlockThreshold <= 0 {
+		merged.RiskBlockThreshold = defaults.RiskBlockThreshold
+	}
+	return &merged
 }
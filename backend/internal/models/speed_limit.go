@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// RoadSegment is an OpenStreetMap way with the speed limit posted on it
+type RoadSegment struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	OSMWayID     int64     `json:"osm_way_id" gorm:"not null;uniqueIndex"`
+	Name         string    `json:"name,omitempty"`
+	Highway      string    `json:"highway" gorm:"type:varchar(40);index"`     // OSM road class: motorway, trunk, primary, residential...
+	MaxSpeed     *float64  `json:"max_speed,omitempty"`                       // km/h; unset where the way is untagged and its class default applies
+	MaxSpeedHGV  *float64  `json:"max_speed_hgv,omitempty"`                   // km/h for heavy goods vehicles (maxspeed:hgv)
+	Geometry     []float64 `json:"geometry" gorm:"type:text;serializer:json"` // Latitude, longitude pairs along the way
+	MinLatitude  float64   `json:"min_latitude" gorm:"index:idx_road_segment_bbox"`
+	MaxLatitude  float64   `json:"max_latitude" gorm:"index:idx_road_segment_bbox"`
+	MinLongitude float64   `json:"min_longitude" gorm:"index:idx_road_segment_bbox"`
+	MaxLongitude float64   `json:"max_longitude" gorm:"index:idx_road_segment_bbox"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// SpeedLimitOverride caps the speed limit for a vehicle type, on one road class or on every road
+type SpeedLimitOverride struct {
+	ID          uint        `json:"id" gorm:"primaryKey"`
+	VehicleType VehicleType `json:"vehicle_type" gorm:"type:varchar(20);not null;uniqueIndex:idx_speed_limit_override"`
+	Highway     string      `json:"highway,omitempty" gorm:"type:varchar(40);uniqueIndex:idx_speed_limit_override"` // Empty for every road
+	MaxSpeed    float64     `json:"max_speed" gorm:"not null"`                                                      // km/h
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// SpeedingEpisode is a continuous stretch of driving over the limit
+type SpeedingEpisode struct {
+	ID              uint                `json:"id" gorm:"primaryKey"`
+	VehicleID       uint                `json:"vehicle_id" gorm:"not null;index"`
+	DriverID        *uint               `json:"driver_id,omitempty" gorm:"index"`
+	TripID          *uint               `json:"trip_id,omitempty" gorm:"index"`
+	SafetyEventID   *uint               `json:"safety_event_id,omitempty"`
+	StartedAt       time.Time           `json:"started_at" gorm:"not null;index"`
+	EndedAt         time.Time           `json:"ended_at"` // The last position over the limit
+	DurationSeconds float64             `json:"duration_seconds"`
+	Pings           int                 `json:"pings"`
+	MaxSpeed        float64             `json:"max_speed"`    // km/h
+	MaxOver         float64             `json:"max_over"`     // km/h over the limit at its worst
+	AverageOver     float64             `json:"average_over"` // km/h
+	SpeedLimit      float64             `json:"speed_limit"`  // km/h where the excess was greatest
+	LimitSource     string              `json:"limit_source" gorm:"type:varchar(20)"`
+	RoadSegmentID   *uint               `json:"road_segment_id,omitempty"`
+	GeofenceID      *uint               `json:"geofence_id,omitempty"`
+	Severity        SafetyEventSeverity `json:"severity" gorm:"type:varchar(20)"`
+	StartLatitude   float64             `json:"start_latitude"`
+	StartLongitude  float64             `json:"start_longitude"`
+	EndLatitude     float64             `json:"end_latitude"`
+	EndLongitude    float64             `json:"end_longitude"`
+	CreatedAt       time.Time           `json:"created_at"`
+
+	// Associations
+	Vehicle *Vehicle `json:"vehicle,omitempty" gorm:"foreignKey:VehicleID"`
+	Driver  *Driver  `json:"driver,omitempty" gorm:"foreignKey:DriverID"`
+}
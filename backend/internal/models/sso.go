@@ -0,0 +1,107 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OIDCConfig holds an organization's OpenID Connect single sign-on settings
+type OIDCConfig struct {
+	ID             uint   `json:"id" gorm:"primaryKey"`
+	OrganizationID uint   `json:"organization_id" gorm:"uniqueIndex;not null"`
+	Issuer         string `json:"issuer" gorm:"not null"` // e.g. https://login.example.com/realms/acme
+	ClientID       string `json:"client_id" gorm:"not null"`
+	ClientSecret   string `json:"-"`                            // Optional; public clients rely on PKCE alone
+	RedirectURL    string `json:"redirect_url" gorm:"not null"` // Frontend page that receives code and state
+	Scopes         string `json:"scopes" gorm:"default:'openid email profile'"`
+
+	// Claim-to-role mapping
+	RoleClaim   string          `json:"role_claim" gorm:"default:'groups'"` // Claim holding group/role names
+	RoleMapping map[string]Role `json:"role_mapping" gorm:"type:text;serializer:json"`
+	DefaultRole Role            `json:"default_role" gorm:"type:varchar(20);default:'VIEWER'"`
+
+	// Provisioning
+	AllowedEmailDomains string `json:"allowed_email_domains"` // Comma-separated, empty allows any
+	AutoProvision       bool   `json:"auto_provision"`
+	Enabled             bool   `json:"enabled"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Associations
+	Organization *Organization `json:"-" gorm:"foreignKey:OrganizationID"`
+}
+
+// OIDCAuthRequest tracks an in-flight authorization code flow
+type OIDCAuthRequest struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	State          string     `json:"state" gorm:"uniqueIndex;not null"`
+	Nonce          string     `json:"-" gorm:"not null"`
+	CodeVerifier   string     `json:"-" gorm:"not null"` // PKCE verifier (RFC 7636)
+	OrganizationID uint       `json:"organization_id" gorm:"not null;index"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"not null;index"`
+	ConsumedAt     *time.Time `json:"consumed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// UserIdentity links a UserAccount to an external identity provider subject
+type UserIdentity struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	Issuer      string     `json:"issuer" gorm:"not null;uniqueIndex:idx_identity_issuer_subject"`
+	Subject     string     `json:"subject" gorm:"not null;uniqueIndex:idx_identity_issuer_subject"`
+	Email       string     `json:"email,omitempty"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// Associations
+	User *UserAccount `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// TableName returns the table name for OIDCConfig
+func (OIDCConfig) TableName() string {
+	return "oidc_configs"
+}
+
+// TableName returns the table name for OIDCAuthRequest
+func (OIDCAuthRequest) TableName() string {
+	return "oidc_auth_requests"
+}
+
+// IsExpired checks if the authorization request can no longer be completed
+func (r *OIDCAuthRequest) IsExpired() bool {
+	return time.Now().After(r.ExpiresAt)
+}
+
+// ScopeList returns the configured scopes, always including openid
+func (c *OIDCConfig) ScopeList() []string {
+	scopes := strings.Fields(c.Scopes)
+	for _, scope := range scopes {
+		if scope == "openid" {
+			return scopes
+		}
+	}
+	return append([]string{"openid"}, scopes...)
+}
+
+// IsEmailDomainAllowed checks an email against the allowed domain list
+func (c *OIDCConfig) IsEmailDomainAllowed(email string) bool {
+	if strings.TrimSpace(c.AllowedEmailDomains) == "" {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range strings.Split(c.AllowedEmailDomains, ",") {
+		if strings.ToLower(strings.TrimSpace(allowed)) == domain {
+			return true
+		}
+	}
+	return false
+}
@@ -50,12 +50,20 @@ type DiagnosticCode struct {
 	Code        string                 `json:"code" gorm:"type:varchar(20);not null;index"` // e.g., P0300
 	Description string                 `json:"description,omitempty"`
 	Severity    DiagnosticCodeSeverity `json:"severity" gorm:"default:'MEDIUM'"`
+
+	// J1939 codes
+	SPN             *int `json:"spn,omitempty"`              // Suspect parameter number
+	FMI             *int `json:"fmi,omitempty"`              // Failure mode identifier
+	OccurrenceCount int  `json:"occurrence_count,omitempty"` // As counted by the ECU
+
+	RecommendedAction string `json:"recommended_action,omitempty"`
 	
 	// Status
 	IsActive   bool      `json:"is_active" gorm:"default:true"`
 	FirstSeen  time.Time `json:"first_seen"`
 	LastSeen   time.Time `json:"last_seen"`
 	ClearTime  *time.Time `json:"clear_time,omitempty"`
+	MissedReports int     `json:"-"` // Consecutive diagnostic reports without this code
 	
 	// Metadata
 	Source    string         `json:"source,omitempty"` // OBDII, J1939, OEM
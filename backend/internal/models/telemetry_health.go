@@ -0,0 +1,85 @@
+package models
+
+import "time"
+
+// TelemetrySignal names a TelemetryLog signal
+type TelemetrySignal string
+
+const (
+	SignalEngineRPM      TelemetrySignal = "ENGINE_RPM"
+	SignalSpeed          TelemetrySignal = "SPEED"
+	SignalCoolantTemp    TelemetrySignal = "COOLANT_TEMP"
+	SignalEngineLoad     TelemetrySignal = "ENGINE_LOAD"
+	SignalThrottlePos    TelemetrySignal = "THROTTLE_POS"
+	SignalFuelLevel      TelemetrySignal = "FUEL_LEVEL"
+	SignalBatteryVoltage TelemetrySignal = "BATTERY_VOLTAGE"
+	SignalOdometer       TelemetrySignal = "ODOMETER"
+	SignalEngineHours    TelemetrySignal = "ENGINE_HOURS"
+	SignalFuelUsed       TelemetrySignal = "FUEL_USED"
+)
+
+// TelemetryAnomalyKind is the rule a telemetry reading broke
+type TelemetryAnomalyKind string
+
+const (
+	AnomalyThreshold  TelemetryAnomalyKind = "THRESHOLD"      // Outside the signal's limits
+	AnomalyRateChange TelemetryAnomalyKind = "RATE_OF_CHANGE" // Rising or falling faster than allowed
+	AnomalyDeviation  TelemetryAnomalyKind = "DEVIATION"      // Far from the vehicle's own baseline
+)
+
+// TelemetryThreshold sets limits for a signal. Rules without a make apply fleet-wide, rules
+// with a make but no model apply to every model of that make; the most specific rule wins.
+// Rates are per minute.
+type TelemetryThreshold struct {
+	ID            uint                   `json:"id" gorm:"primaryKey"`
+	VehicleMake   string                 `json:"vehicle_make,omitempty" gorm:"type:varchar(50);uniqueIndex:idx_telemetry_threshold_rule"`
+	VehicleModel  string                 `json:"vehicle_model,omitempty" gorm:"type:varchar(50);uniqueIndex:idx_telemetry_threshold_rule"`
+	Signal        TelemetrySignal        `json:"signal" gorm:"type:varchar(30);not null;uniqueIndex:idx_telemetry_threshold_rule"`
+	Min           *float64               `json:"min,omitempty"`
+	Max           *float64               `json:"max,omitempty"`
+	CriticalMin   *float64               `json:"critical_min,omitempty"`
+	CriticalMax   *float64               `json:"critical_max,omitempty"`
+	MaxRisePerMin *float64               `json:"max_rise_per_min,omitempty"`
+	MaxFallPerMin *float64               `json:"max_fall_per_min,omitempty"`
+	Severity      DiagnosticCodeSeverity `json:"severity" gorm:"type:varchar(20);not null"` // For readings outside Min/Max and rate breaches
+	IsActive      bool                   `json:"is_active"`
+	UpdatedBy     *uint                  `json:"updated_by,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+}
+
+// TelemetryBaseline is a vehicle's exponentially weighted running mean and variance of a signal,
+// with its last reading for rate-of-change rules
+type TelemetryBaseline struct {
+	ID         uint            `json:"id" gorm:"primaryKey"`
+	VehicleID  uint            `json:"vehicle_id" gorm:"not null;uniqueIndex:idx_telemetry_baseline_signal"`
+	Signal     TelemetrySignal `json:"signal" gorm:"type:varchar(30);not null;uniqueIndex:idx_telemetry_baseline_signal"`
+	Mean       float64         `json:"mean"`
+	Variance   float64         `json:"variance"`
+	Samples    int             `json:"samples"`
+	LastValue  float64         `json:"last_value"`
+	LastSeenAt time.Time       `json:"last_seen_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// TelemetryAnomaly is a run of readings breaking one rule for one signal. Repeated breaches
+// update the open anomaly instead of raising new alerts.
+type TelemetryAnomaly struct {
+	ID           uint                   `json:"id" gorm:"primaryKey"`
+	VehicleID    uint                   `json:"vehicle_id" gorm:"not null;index"`
+	Signal       TelemetrySignal        `json:"signal" gorm:"type:varchar(30);not null"`
+	Kind         TelemetryAnomalyKind   `json:"kind" gorm:"type:varchar(20);not null"`
+	Severity     DiagnosticCodeSeverity `json:"severity" gorm:"type:varchar(20);not null"`
+	Message      string                 `json:"message"`
+	Value        float64                `json:"value"`                  // Latest offending reading
+	PeakValue    float64                `json:"peak_value"`             // Furthest reading from Expected
+	Expected     float64                `json:"expected"`               // The limit or baseline mean broken
+	Occurrences  int                    `json:"occurrences"`            // Offending readings
+	NormalStreak int                    `json:"-"`                      // Consecutive normal readings since the last breach
+	IsActive     bool                   `json:"is_active" gorm:"index"` // False once resolved
+	FirstSeen    time.Time              `json:"first_seen"`
+	LastSeen     time.Time              `json:"last_seen"`
+	ResolvedAt   *time.Time             `json:"resolved_at,omitempty"`
+	CreatedAt    time.Time              `json:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// TrackerDevice maps a telematics unit's IMEI to the vehicle it is fitted to. Units that
+// connect before they are registered are recorded without a vehicle so they can be assigned.
+type TrackerDevice struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	IMEI           string     `json:"imei" gorm:"type:varchar(20);not null;uniqueIndex"`
+	VehicleID      *uint      `json:"vehicle_id,omitempty" gorm:"index"`
+	Protocol       string     `json:"protocol" gorm:"type:varchar(20);not null"` // teltonika, gt06, queclink
+	Model          string     `json:"model,omitempty"`
+	IsActive       bool       `json:"is_active"`
+	LastSeenAt     *time.Time `json:"last_seen_at,omitempty"`
+	LastRemoteAddr string     `json:"last_remote_addr,omitempty"`
+	Ignition       *bool      `json:"ignition,omitempty"` // As last reported
+	IgnitionAt     *time.Time `json:"ignition_at,omitempty"`
+	FramesReceived int64      `json:"frames_received"`
+	FramesRejected int64      `json:"frames_rejected"` // Undecodable, or from an unassigned device
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	Vehicle *Vehicle `json:"vehicle,omitempty" gorm:"foreignKey:VehicleID"`
+}
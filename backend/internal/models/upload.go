@@ -17,6 +17,7 @@ const (
 	UploadTypeDriverDocument UploadType = "DRIVER_DOCUMENT"
 	UploadTypeCompliance     UploadType = "COMPLIANCE"
 	UploadTypeIncident       UploadType = "INCIDENT"
+	UploadTypeDVIRDefect     UploadType = "DVIR_DEFECT"
 	UploadTypeOther          UploadType = "OTHER"
 )
 
@@ -73,11 +74,12 @@ type Upload struct {
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Foreign keys - nullable to support various entity associations
-	TripID      *uint `json:"trip_id,omitempty" gorm:"index"`
-	VehicleID   *uint `json:"vehicle_id,omitempty" gorm:"index"`
-	DriverID    *uint `json:"driver_id,omitempty" gorm:"index"`
-	FuelEventID *uint `json:"fuel_event_id,omitempty" gorm:"index"`
-	UploadedBy  uint  `json:"uploaded_by" gorm:"not null;index"`
+	TripID       *uint `json:"trip_id,omitempty" gorm:"index"`
+	VehicleID    *uint `json:"vehicle_id,omitempty" gorm:"index"`
+	DriverID     *uint `json:"driver_id,omitempty" gorm:"index"`
+	FuelEventID  *uint `json:"fuel_event_id,omitempty" gorm:"index"`
+	DVIRDefectID *uint `json:"dvir_defect_id,omitempty" gorm:"index"`
+	UploadedBy   uint  `json:"uploaded_by" gorm:"not null;index"`
 
 	// Associations
 	Trip           *Trip        `json:"trip,omitempty" gorm:"foreignKey:TripID"`
@@ -29,6 +29,28 @@ type UserAccount struct {
 	IsActive  bool       `json:"is_active" gorm:"default:true"`
 	LastLogin *time.Time `json:"last_login,omitempty"`
 
+	// Where the last successful login came from, for impossible-travel checks
+	LastLoginIP        string     `json:"-"`
+	LastLoginLatitude  *float64   `json:"-"`
+	LastLoginLongitude *float64   `json:"-"`
+	LastLoginLocatedAt *time.Time `json:"-"` // When the login at that position happened; LastLogin is already overwritten while scoring
+
+	// Password login lockout
+	FailedLoginAttempts int        `json:"-" gorm:"default:0"`
+	LockedUntil         *time.Time `json:"locked_until,omitempty"`
+	PasswordChangedAt   *time.Time `json:"-"`
+
+	// Email verification; a changed address waits in PendingEmail until its token is confirmed
+	EmailVerifiedAt            *time.Time `json:"email_verified_at,omitempty"`
+	PendingEmail               string     `json:"pending_email,omitempty"`
+	EmailVerificationHash      string     `json:"-" gorm:"index"`
+	EmailVerificationExpiresAt *time.Time `json:"-"`
+
+	// TOTP second factor; TOTPLastStep is the time step of the last accepted code, so codes are single-use
+	TOTPSecret   string `json:"-"`
+	TOTPEnabled  bool   `json:"totp_enabled" gorm:"default:false"`
+	TOTPLastStep int64  `json:"-" gorm:"default:0"`
+
 	// Multi-tenancy
 	OrganizationID *uint         `json:"organization_id,omitempty" gorm:"index"`
 	Organization   *Organization `json:"organization,omitempty" gorm:"foreignKey:OrganizationID"`
@@ -53,6 +75,7 @@ type RefreshToken struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
 	Token     string         `json:"token" gorm:"uniqueIndex;not null"`
 	UserID    uint           `json:"user_id" gorm:"not null;index"`
+	SessionID *uint          `json:"session_id,omitempty" gorm:"index"` // Token family; nil for tokens issued before sessions
 	ExpiresAt time.Time      `json:"expires_at" gorm:"not null"`
 	Revoked   bool           `json:"revoked" gorm:"default:false"`
 	CreatedAt time.Time      `json:"created_at"`
@@ -78,6 +101,16 @@ type OTPVerification struct {
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// RecoveryCode represents a single-use code that can replace a TOTP code
+type RecoveryCode struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	UserID    uint           `json:"user_id" gorm:"not null;index"`
+	CodeHash  string         `json:"-" gorm:"not null"` // bcrypt hash of the code
+	UsedAt    *time.Time     `json:"used_at,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
 // IsExpired checks if the OTP has expired
 func (o *OTPVerification) IsExpired() bool {
 	return time.Now().After(o.ExpiresAt)
@@ -103,6 +136,11 @@ func (u *UserAccount) IsDriver() bool {
 	return u.Role == RoleDriver
 }
 
+// IsLocked checks if the account is locked out after failed login attempts
+func (u *UserAccount) IsLocked() bool {
+	return u.LockedUntil != nil && time.Now().Before(*u.LockedUntil)
+}
+
 // BeforeCreate hashes the password before creating a new user
 func (u *UserAccount) BeforeCreate(tx *gorm.DB) error {
 	// Hash password if it's set and not already hashed
@@ -41,6 +41,7 @@ type Vehicle struct {
 	CurrentFuelLevel float64        `json:"current_fuel_level" gorm:"type:decimal(5,2);default:100"`
 	Mileage          float64        `json:"mileage" gorm:"type:decimal(10,2);default:0"`
 	IsActive         bool           `json:"is_active" gorm:"default:true"`
+	FleetID          *uint          `json:"fleet_id,omitempty" gorm:"index"`
 	PurchasedAt      *time.Time     `json:"purchased_at,omitempty"`
 	CreatedAt        time.Time      `json:"created_at"`
 	UpdatedAt        time.Time      `json:"updated_at"`
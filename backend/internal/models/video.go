@@ -13,26 +13,52 @@ const (
 	CameraStatusOnline      CameraStatus = "ONLINE"
 	CameraStatusOffline     CameraStatus = "OFFLINE"
 	CameraStatusMalfunction CameraStatus = "MALFUNCTION"
+	CameraStatusStandby     CameraStatus = "STANDBY" // Silent while the vehicle's ignition is off
+)
+
+// Faults a camera reports in its heartbeat
+const (
+	CameraFaultSDCardFailure  = "SD_CARD_FAILURE"
+	CameraFaultSDCardMissing  = "SD_CARD_MISSING"
+	CameraFaultSDCardFull     = "SD_CARD_FULL"
+	CameraFaultLensObstructed = "LENS_OBSTRUCTED"
+	CameraFaultNotRecording   = "NOT_RECORDING"
 )
 
 // Camera represents a dashcam or site camera
 type Camera struct {
-	ID            uint           `json:"id" gorm:"primaryKey"`
-	VehicleID     *uint          `json:"vehicle_id" gorm:"index"` // Nullable if site camera
-	YardID        *uint          `json:"yard_id" gorm:"index"`    // Nullable if vehicle camera
-	SerialNumber  string         `json:"serial_number" gorm:"uniqueIndex;not null"`
-	Model         string         `json:"model"`
-	Status        CameraStatus   `json:"status" gorm:"default:'OFFLINE'"`
-	LastHeartbeat time.Time      `json:"last_heartbeat"`
-	FirmwareVer   string         `json:"firmware_ver"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	VehicleID       *uint          `json:"vehicle_id" gorm:"index"` // Nullable if site camera
+	YardID          *uint          `json:"yard_id" gorm:"index"`    // Nullable if vehicle camera
+	SerialNumber    string         `json:"serial_number" gorm:"uniqueIndex;not null"`
+	Model           string         `json:"model"`
+	Status          CameraStatus   `json:"status" gorm:"default:'OFFLINE'"`
+	LastHeartbeat   time.Time      `json:"last_heartbeat"`
+	FirmwareVer     string         `json:"firmware_ver"`
+	Faults          []string       `json:"faults,omitempty" gorm:"type:text;serializer:json"` // As of the last heartbeat
+	Ignition        *bool          `json:"ignition,omitempty"`                                // As of the last heartbeat
+	StatusReason    string         `json:"status_reason,omitempty"`
+	StatusChangedAt *time.Time     `json:"status_changed_at,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
 
 	Vehicle *Vehicle `json:"vehicle,omitempty" gorm:"foreignKey:VehicleID"`
 	Yard    *Yard    `json:"yard,omitempty" gorm:"foreignKey:YardID"`
 }
 
+// CameraStatusChange records a camera moving between health states
+type CameraStatusChange struct {
+	ID         uint         `json:"id" gorm:"primaryKey"`
+	CameraID   uint         `json:"camera_id" gorm:"not null;index:idx_camera_status_changes,priority:1"`
+	FromStatus CameraStatus `json:"from_status" gorm:"type:varchar(20)"`
+	ToStatus   CameraStatus `json:"to_status" gorm:"type:varchar(20);not null"`
+	Reason     string       `json:"reason,omitempty"`
+	Faults     []string     `json:"faults,omitempty" gorm:"type:text;serializer:json"`
+	ChangedAt  time.Time    `json:"changed_at" gorm:"not null;index:idx_camera_status_changes,priority:2"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
 // VideoEventType represents the trigger for the video
 type VideoEventType string
 
@@ -43,29 +69,54 @@ const (
 	VideoEventManual       VideoEventType = "MANUAL"
 	VideoEventDistraction  VideoEventType = "DISTRACTION" // AI Detected
 	VideoEventDrowsiness   VideoEventType = "DROWSINESS"  // AI Detected
+
+	VideoEventHarshAcceleration VideoEventType = "HARSH_ACCELERATION"
+	VideoEventHarshCornering    VideoEventType = "HARSH_CORNERING"
+)
+
+// VideoClipStatus tracks a clip's footage from the camera to storage and out again
+type VideoClipStatus string
+
+const (
+	VideoClipPendingUpload VideoClipStatus = "PENDING_UPLOAD" // Recorded by the camera, not yet sent
+	VideoClipUploading     VideoClipStatus = "UPLOADING"
+	VideoClipAvailable     VideoClipStatus = "AVAILABLE"
+	VideoClipFailed        VideoClipStatus = "FAILED"
+	VideoClipExpired       VideoClipStatus = "EXPIRED" // Footage deleted under the retention policy
 )
 
 // VideoClip represents a recorded video segment
 type VideoClip struct {
-	ID           uint           `json:"id" gorm:"primaryKey"`
-	CameraID     uint           `json:"camera_id" gorm:"index;not null"`
-	VehicleID    *uint          `json:"vehicle_id" gorm:"index"`
-	DriverID     *uint          `json:"driver_id" gorm:"index"`
-	EventType    VideoEventType `json:"event_type" gorm:"index"`
-	StartTime    time.Time      `json:"start_time"`
-	EndTime      time.Time      `json:"end_time"`
-	DurationSec  int            `json:"duration_sec"`
-	StorageURL   string         `json:"storage_url"` // S3 URL
-	ThumbnailURL string         `json:"thumbnail_url"`
-	IsViewed     bool           `json:"is_viewed" gorm:"default:false"`
-	IsStarred    bool           `json:"is_starred" gorm:"default:false"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
-
-	Camera  Camera   `json:"camera" gorm:"foreignKey:CameraID"`
-	Vehicle *Vehicle `json:"vehicle,omitempty" gorm:"foreignKey:VehicleID"`
-	Driver  *Driver  `json:"driver,omitempty" gorm:"foreignKey:DriverID"`
+	ID           uint            `json:"id" gorm:"primaryKey"`
+	CameraID     uint            `json:"camera_id" gorm:"index;not null"`
+	VehicleID    *uint           `json:"vehicle_id" gorm:"index"`
+	DriverID     *uint           `json:"driver_id" gorm:"index"` // From the trip or duty log the vehicle was on
+	TripID       *uint           `json:"trip_id,omitempty" gorm:"index"`
+	EventType    VideoEventType  `json:"event_type" gorm:"index"`
+	StartTime    time.Time       `json:"start_time"`
+	EndTime      time.Time       `json:"end_time"`
+	DurationSec  int             `json:"duration_sec"`
+	Status       VideoClipStatus `json:"status" gorm:"type:varchar(20);index;default:'PENDING_UPLOAD'"`
+	StorageKey   string          `json:"-"`           // Object key with the storage provider
+	StorageURL   string          `json:"storage_url"` // Unsigned; play clips through their signed playback URL
+	ThumbnailKey string          `json:"-"`
+	ThumbnailURL string          `json:"thumbnail_url"`
+	ContentType  string          `json:"content_type,omitempty"`
+	SizeBytes    int64           `json:"size_bytes,omitempty"`
+	SHA256       string          `json:"sha256,omitempty"`
+	UploadedAt   *time.Time      `json:"uploaded_at,omitempty"`
+	ExpiredAt    *time.Time      `json:"expired_at,omitempty"`
+	IsViewed     bool            `json:"is_viewed" gorm:"default:false"`
+	IsStarred    bool            `json:"is_starred" gorm:"default:false"` // Starred clips are kept past their retention
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt  `json:"-" gorm:"index"`
+
+	Camera     Camera        `json:"camera" gorm:"foreignKey:CameraID"`
+	Vehicle    *Vehicle      `json:"vehicle,omitempty" gorm:"foreignKey:VehicleID"`
+	Driver     *Driver       `json:"driver,omitempty" gorm:"foreignKey:DriverID"`
+	Trip       *Trip         `json:"trip,omitempty" gorm:"foreignKey:TripID"`
+	Detections []AIDetection `json:"detections,omitempty" gorm:"foreignKey:VideoClipID"`
 }
 
 // AIDetection represents objects or behaviors detected in a video
@@ -78,5 +129,73 @@ type AIDetection struct {
 	BoundingBox string    `json:"bounding_box,omitempty"` // JSON string of coordinates
 	CreatedAt   time.Time `json:"created_at"`
 
-	VideoClip VideoClip `json:"video_clip" gorm:"foreignKey:VideoClipID"`
+	VideoClip *VideoClip `json:"video_clip,omitempty" gorm:"foreignKey:VideoClipID"`
+}
+
+// VideoUploadStatus is where a resumable clip upload stands
+type VideoUploadStatus string
+
+const (
+	VideoUploadOpen     VideoUploadStatus = "OPEN"
+	VideoUploadComplete VideoUploadStatus = "COMPLETE"
+	VideoUploadFailed   VideoUploadStatus = "FAILED"
+)
+
+// VideoUpload is a resumable, chunked upload of a clip from its camera. Chunks are staged with
+// the storage provider at their offsets and joined once the whole file has arrived.
+type VideoUpload struct {
+	ID            uint              `json:"-" gorm:"primaryKey"`
+	UploadID      string            `json:"upload_id" gorm:"type:varchar(64);uniqueIndex;not null"`
+	VideoClipID   uint              `json:"clip_id" gorm:"not null;index"`
+	CameraID      uint              `json:"camera_id" gorm:"not null;index"`
+	ContentType   string            `json:"content_type"`
+	TotalBytes    int64             `json:"total_bytes"`
+	ReceivedBytes int64             `json:"received_bytes"`
+	SHA256        string            `json:"sha256,omitempty"`                              // Expected digest of the whole file
+	PartKeys      []string          `json:"-" gorm:"type:text;serializer:json"`            // Storage keys of the staged chunks, in order
+	Status        VideoUploadStatus `json:"status" gorm:"type:varchar(20);not null;index"` // OPEN until the last byte arrives
+	Error         string            `json:"error,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// VideoRetrievalStatus is where a request for footage from a camera stands
+type VideoRetrievalStatus string
+
+const (
+	VideoRetrievalPending   VideoRetrievalStatus = "PENDING"   // Waiting for the camera to pick it up
+	VideoRetrievalSent      VideoRetrievalStatus = "SENT"      // Pushed to the camera
+	VideoRetrievalFulfilled VideoRetrievalStatus = "FULFILLED" // The footage is uploaded
+	VideoRetrievalFailed    VideoRetrievalStatus = "FAILED"    // The camera no longer has it
+	VideoRetrievalExpired   VideoRetrievalStatus = "EXPIRED"   // The camera never answered
+)
+
+// VideoRetrievalRequest asks a camera to upload the footage it holds for a window
+type VideoRetrievalRequest struct {
+	ID            uint                 `json:"id" gorm:"primaryKey"`
+	CameraID      uint                 `json:"camera_id" gorm:"not null;index"`
+	VehicleID     *uint                `json:"vehicle_id,omitempty" gorm:"index"`
+	StartTime     time.Time            `json:"start_time" gorm:"not null"`
+	EndTime       time.Time            `json:"end_time" gorm:"not null"`
+	EventType     VideoEventType       `json:"event_type" gorm:"type:varchar(20)"` // What the resulting clip is filed under
+	Reason        string               `json:"reason,omitempty"`
+	Status        VideoRetrievalStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	RequestedByID *uint                `json:"requested_by_id,omitempty"`
+	VideoClipID   *uint                `json:"clip_id,omitempty"`
+	Error         string               `json:"error,omitempty"`
+	SentAt        *time.Time           `json:"sent_at,omitempty"`
+	FulfilledAt   *time.Time           `json:"fulfilled_at,omitempty"`
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+
+	Camera *Camera `json:"camera,omitempty" gorm:"foreignKey:CameraID"`
+}
+
+// VideoRetentionPolicy is how long clips of an event type are kept
+type VideoRetentionPolicy struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	EventType     VideoEventType `json:"event_type" gorm:"type:varchar(20);uniqueIndex;not null"`
+	RetentionDays int            `json:"retention_days" gorm:"not null"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
 }
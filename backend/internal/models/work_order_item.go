@@ -0,0 +1,93 @@
+package models
+
+import "time"
+
+// WorkOrderPartStatus tracks a part from reservation to use
+type WorkOrderPartStatus string
+
+const (
+	WorkOrderPartReserved WorkOrderPartStatus = "RESERVED" // Held in stock for the work order
+	WorkOrderPartConsumed WorkOrderPartStatus = "CONSUMED" // Taken out of stock and fitted
+	WorkOrderPartReleased WorkOrderPartStatus = "RELEASED" // Returned to stock unused
+)
+
+// WorkOrderPart is an inventory part used on a work order
+type WorkOrderPart struct {
+	ID              uint                `json:"id" gorm:"primaryKey"`
+	WorkOrderID     uint                `json:"work_order_id" gorm:"not null;index"`
+	VehicleID       uint                `json:"vehicle_id" gorm:"not null;index"` // For the vehicle's parts history
+	InventoryItemID uint                `json:"inventory_item_id" gorm:"not null;index"`
+	Quantity        int                 `json:"quantity" gorm:"not null"`
+	UnitCost        float64             `json:"unit_cost" gorm:"type:decimal(10,2)"` // Item cost when reserved
+	TotalCost       float64             `json:"total_cost" gorm:"type:decimal(10,2)"`
+	Status          WorkOrderPartStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	ConsumedAt      *time.Time          `json:"consumed_at,omitempty"`
+	CreatedBy       *uint               `json:"created_by,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Associations
+	InventoryItem *InventoryItem `json:"inventory_item,omitempty" gorm:"foreignKey:InventoryItemID"`
+}
+
+// WorkOrderLabor is time a mechanic spent on a work order
+type WorkOrderLabor struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	WorkOrderID uint      `json:"work_order_id" gorm:"not null;index"`
+	MechanicID  uint      `json:"mechanic_id" gorm:"not null;index"`
+	Hours       float64   `json:"hours" gorm:"type:decimal(6,2);not null"`
+	HourlyRate  float64   `json:"hourly_rate" gorm:"type:decimal(10,2)"`
+	TotalCost   float64   `json:"total_cost" gorm:"type:decimal(10,2)"`
+	Description string    `json:"description,omitempty"`
+	PerformedAt time.Time `json:"performed_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Associations
+	Mechanic *UserAccount `json:"mechanic,omitempty" gorm:"foreignKey:MechanicID"`
+}
+
+// MechanicRate is a mechanic's standard hourly rate, used for labour entries that do not give one
+type MechanicRate struct {
+	ID         uint    `json:"id" gorm:"primaryKey"`
+	MechanicID uint    `json:"mechanic_id" gorm:"not null;uniqueIndex"`
+	HourlyRate float64 `json:"hourly_rate" gorm:"type:decimal(10,2);not null"`
+	UpdatedBy  *uint   `json:"updated_by,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PurchaseOrderSuggestionStatus tracks a restocking suggestion
+type PurchaseOrderSuggestionStatus string
+
+const (
+	PurchaseSuggestionOpen      PurchaseOrderSuggestionStatus = "OPEN"
+	PurchaseSuggestionOrdered   PurchaseOrderSuggestionStatus = "ORDERED"
+	PurchaseSuggestionReceived  PurchaseOrderSuggestionStatus = "RECEIVED"
+	PurchaseSuggestionDismissed PurchaseOrderSuggestionStatus = "DISMISSED"
+)
+
+// PurchaseOrderSuggestion proposes restocking an item whose available stock fell below its minimum
+type PurchaseOrderSuggestion struct {
+	ID                uint                          `json:"id" gorm:"primaryKey"`
+	InventoryItemID   uint                          `json:"inventory_item_id" gorm:"not null;index"`
+	Status            PurchaseOrderSuggestionStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	AvailableQuantity int                           `json:"available_quantity"` // Stock not held for work orders when last updated
+	MinQuantity       int                           `json:"min_quantity"`
+	SuggestedQuantity int                           `json:"suggested_quantity"`
+	UnitCost          float64                       `json:"unit_cost" gorm:"type:decimal(10,2)"`
+	EstimatedCost     float64                       `json:"estimated_cost" gorm:"type:decimal(12,2)"`
+	WorkOrderID       *uint                         `json:"work_order_id,omitempty"` // Work order whose parts triggered it
+	OrderedAt         *time.Time                    `json:"ordered_at,omitempty"`
+	ClosedAt          *time.Time                    `json:"closed_at,omitempty"`
+	UpdatedBy         *uint                         `json:"updated_by,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Associations
+	InventoryItem *InventoryItem `json:"inventory_item,omitempty" gorm:"foreignKey:InventoryItemID"`
+}
@@ -19,6 +19,16 @@ type AuthRepository interface {
 	UpdateUser(user *models.UserAccount) error
 	GetUserByID(id uint) (*models.UserAccount, error)
 	UpdateUserFields(userID uint, updates map[string]interface{}) error
+	GetUserByEmail(email string) (*models.UserAccount, error)
+	GetUserByEmailVerification(tokenHash string) (*models.UserAccount, error)
+
+	// Password & Two-Factor Operations
+	GetSecuritySettings() (*models.SecuritySettings, error)
+	ReplaceRecoveryCodes(userID uint, codes []models.RecoveryCode) error
+	GetUnusedRecoveryCodes(userID uint) ([]models.RecoveryCode, error)
+	MarkRecoveryCodeUsed(id uint) (bool, error)
+	IncrementFailedLoginAttempts(userID uint) (int, error)
+	AdvanceTOTPStep(userID uint, step int64) (bool, error)
 }
 
 // DriverRepository defines the interface for driver-related data operations
@@ -82,3 +82,84 @@ func (r *PostgresAuthRepository) GetUserByID(id uint) (*models.UserAccount, erro
 func (r *PostgresAuthRepository) UpdateUserFields(userID uint, updates map[string]interface{}) error {
 	return r.db.Model(&models.UserAccount{}).Where("id = ?", userID).Updates(updates).Error
 }
+
+func (r *PostgresAuthRepository) GetUserByEmail(email string) (*models.UserAccount, error) {
+	var user models.UserAccount
+	if err := r.db.Where("LOWER(email) = LOWER(?)", email).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *PostgresAuthRepository) GetUserByEmailVerification(tokenHash string) (*models.UserAccount, error) {
+	var user models.UserAccount
+	if err := r.db.Where("email_verification_hash = ?", tokenHash).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// --- Password & Two-Factor Operations ---
+
+func (r *PostgresAuthRepository) GetSecuritySettings() (*models.SecuritySettings, error) {
+	var settings models.SystemSettings
+	if err := r.db.Order("id").First(&settings).Error; err != nil {
+		return nil, err
+	}
+	return settings.SecuritySettings, nil
+}
+
+func (r *PostgresAuthRepository) ReplaceRecoveryCodes(userID uint, codes []models.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *PostgresAuthRepository) GetUnusedRecoveryCodes(userID uint) ([]models.RecoveryCode, error) {
+	var codes []models.RecoveryCode
+	if err := r.db.Where("user_id = ? AND used_at IS NULL", userID).Find(&codes).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// MarkRecoveryCodeUsed spends a recovery code. It reports false when the code was already used,
+// so concurrent logins cannot both spend it.
+func (r *PostgresAuthRepository) MarkRecoveryCodeUsed(id uint) (bool, error) {
+	result := r.db.Model(&models.RecoveryCode{}).Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// IncrementFailedLoginAttempts counts a failed login in the database, so concurrent attempts are
+// all counted, and returns the new total. A lockout that has expired is cleared and the count
+// starts again from this attempt.
+func (r *PostgresAuthRepository) IncrementFailedLoginAttempts(userID uint) (int, error) {
+	var attempts int
+	now := time.Now()
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserAccount{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"failed_login_attempts": gorm.Expr("CASE WHEN locked_until <= ? THEN 1 ELSE failed_login_attempts + 1 END", now),
+			"locked_until":          gorm.Expr("CASE WHEN locked_until <= ? THEN NULL ELSE locked_until END", now),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.UserAccount{}).Where("id = ?", userID).
+			Select("failed_login_attempts").Scan(&attempts).Error
+	})
+	return attempts, err
+}
+
+// AdvanceTOTPStep records the time step of an accepted TOTP code. It reports false when that step
+// or a later one was already used.
+func (r *PostgresAuthRepository) AdvanceTOTPStep(userID uint, step int64) (bool, error) {
+	result := r.db.Model(&models.UserAccount{}).Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
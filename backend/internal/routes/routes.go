@@ -3,6 +3,7 @@ package routes
 import (
 	"github.com/fleetflow/backend/internal/handlers"
 	"github.com/fleetflow/backend/internal/middleware"
+	"github.com/fleetflow/backend/internal/models"
 	"github.com/fleetflow/backend/internal/services"
 	"github.com/gin-gonic/gin"
 )
@@ -20,6 +21,7 @@ func RegisterRoutes(router *gin.RouterGroup, container *services.Container) {
 	locationHandler := handlers.NewLocationHandler(container)
 	analyticsHandler := handlers.NewAnalyticsHandler(container)
 	whatsappHandler := handlers.NewWhatsAppHandler(container)
+	videoHandler := handlers.NewVideoHandler(container.VideoService)
 
 	// JWT Middleware
 	jwtMiddleware := middleware.JWTMiddleware(container.JWTService)
@@ -33,15 +35,27 @@ func RegisterRoutes(router *gin.RouterGroup, container *services.Container) {
 
 		// Authentication routes - WITH VALIDATION
 		auth := public.Group("/auth")
-		auth.Use(middleware.ValidationMiddleware())      // Double-ensure security
-		auth.Use(middleware.PhoneValidationMiddleware()) // Double-ensure phone validation
-		auth.Use(middleware.AuthRateLimiter())           // Stricter rate limiting for auth
+		auth.Use(middleware.ValidationMiddleware())                 // Double-ensure security
+		auth.Use(middleware.PhoneValidationMiddleware())            // Double-ensure phone validation
+		auth.Use(middleware.AuthRateLimiter(container.RiskService)) // Stricter rate limiting for auth, tightened by risk score
 		{
 			auth.POST("/otp/send", authHandler.SendOTP)
 			auth.POST("/otp/verify", authHandler.VerifyOTP)
 			auth.POST("/refresh", authHandler.RefreshToken)
+
+			// Email + password login for web users, with optional TOTP second factor
+			auth.POST("/password/login", authHandler.PasswordLogin)
+			auth.POST("/password/verify-2fa", authHandler.VerifyTwoFactor)
+			auth.POST("/email/verify", authHandler.VerifyEmail)
+
+			// OpenID Connect single sign-on (authorization code + PKCE)
+			auth.GET("/sso/:org_code/login", authHandler.StartSSOLogin)
+			auth.POST("/sso/callback", authHandler.SSOCallback)
 		}
 
+		// Signed clip playback; the URL's signature is the authorization
+		public.GET("/video/media/:id/:kind", videoHandler.ServeSignedMedia)
+
 		// Public tracking (for customers)
 		tracking := public.Group("/tracking")
 		{
@@ -89,22 +103,68 @@ func RegisterRoutes(router *gin.RouterGroup, container *services.Container) {
 			auth.POST("/revoke", revokeHandler.RevokeToken) // NEW: Token revocation
 			auth.GET("/profile", authHandler.GetProfile)
 			auth.PUT("/profile", authHandler.UpdateProfile)
+			auth.PUT("/password", authHandler.ChangePassword)
+
+			// Sessions (logged-in devices)
+			auth.GET("/sessions", authHandler.ListSessions)
+			auth.DELETE("/sessions", authHandler.RevokeOtherSessions)
+			auth.DELETE("/sessions/:id", authHandler.RevokeSession)
+
+			// Two-factor authentication (TOTP)
+			auth.POST("/2fa/totp/setup", authHandler.SetupTOTP)
+			auth.POST("/2fa/totp/enable", authHandler.EnableTOTP)
+			auth.POST("/2fa/totp/disable", authHandler.DisableTOTP)
+			auth.POST("/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
 		}
 
 		// Organization Management
 		org := protected.Group("/organizations")
 		{
 			org.GET("/me", orgHandler.GetMyOrganization)
+
+			// Single sign-on configuration (organization admins)
+			orgSSO := org.Group("/me/sso")
+			orgSSO.Use(middleware.RequireRole(models.RoleAdmin, models.RoleOrgAdmin))
+			{
+				orgSSO.GET("", authHandler.GetSSOConfig)
+				orgSSO.PUT("", authHandler.UpdateSSOConfig)
+				orgSSO.DELETE("", authHandler.DeleteSSOConfig)
+				orgSSO.POST("/identities", authHandler.LinkSSOIdentity)
+			}
 		}
 
 		// Maintenance
-		maintenanceHandler := handlers.NewMaintenanceHandler(services.NewMaintenanceService(container.DB))
+		maintenanceHandler := handlers.NewMaintenanceHandler(container.MaintenanceService)
 		maintenance := protected.Group("/maintenance")
 		{
 			maintenance.POST("/dvir", maintenanceHandler.SubmitDVIR)
+			maintenance.GET("/dvir/:id", maintenanceHandler.GetDVIR)
+			maintenance.GET("/checklists", maintenanceHandler.GetInspectionChecklists)
+			maintenance.PUT("/checklists", middleware.RequireAdmin(), maintenanceHandler.SaveInspectionChecklist)
+			maintenance.GET("/vehicles/:id/checklist", maintenanceHandler.GetVehicleChecklist)
+			maintenance.GET("/vehicles/:id/defects", maintenanceHandler.GetVehicleDefects)
+			maintenance.POST("/defects/:id/certify", middleware.RequireRole(models.RoleMechanic, models.RoleAdmin), maintenanceHandler.CertifyDefect)
+			maintenance.PUT("/defects/:id/work-order", middleware.RequireRole(models.RoleMechanic, models.RoleAdmin), maintenanceHandler.LinkDefectWorkOrder)
+			maintenance.POST("/defects/:id/photos", maintenanceHandler.AttachDefectPhotos)
 			maintenance.GET("/due", maintenanceHandler.GetMaintenanceDue)
 			maintenance.POST("/work-order", maintenanceHandler.CreateWorkOrder)
 			maintenance.POST("/work-order/:id/resolve", maintenanceHandler.ResolveWorkOrder)
+			maintenance.GET("/work-order/:id", maintenanceHandler.GetWorkOrder)
+			maintenance.POST("/work-order/:id/parts", maintenanceHandler.AddWorkOrderPart)
+			maintenance.DELETE("/work-order/:id/parts/:part_id", maintenanceHandler.ReleaseWorkOrderPart)
+			maintenance.POST("/work-order/:id/parts/:part_id/consume", maintenanceHandler.ConsumeWorkOrderPart)
+			maintenance.POST("/work-order/:id/labor", maintenanceHandler.AddWorkOrderLabor)
+			maintenance.PUT("/mechanics/:id/rate", middleware.RequireAdmin(), maintenanceHandler.SetMechanicRate)
+			maintenance.GET("/vehicles/:id/parts-history", maintenanceHandler.GetVehiclePartsHistory)
+			maintenance.GET("/purchase-suggestions", maintenanceHandler.GetPurchaseSuggestions)
+			maintenance.PUT("/purchase-suggestions/:id", middleware.RequireAdmin(), maintenanceHandler.UpdatePurchaseSuggestion)
+			maintenance.GET("/schedules", maintenanceHandler.GetServiceSchedules)
+			maintenance.POST("/schedules", middleware.RequireAdmin(), maintenanceHandler.CreateServiceSchedule)
+			maintenance.POST("/schedules/run", middleware.RequireAdmin(), maintenanceHandler.RunServiceScheduler)
+			maintenance.GET("/schedule-templates", maintenanceHandler.GetServiceScheduleTemplates)
+			maintenance.PUT("/schedule-templates", middleware.RequireAdmin(), maintenanceHandler.SaveServiceScheduleTemplate)
+			maintenance.GET("/predictions", maintenanceHandler.GetMaintenancePredictions)
+			maintenance.POST("/predictions/run", middleware.RequireAdmin(), maintenanceHandler.RunMaintenanceForecast)
 		}
 
 		// ELD & HOS
@@ -116,19 +176,64 @@ func RegisterRoutes(router *gin.RouterGroup, container *services.Container) {
 		}
 
 		// Safety
-		safetyHandler := handlers.NewSafetyHandler(services.NewSafetyService(container.DB, container.MQTTService))
+		safetyHandler := handlers.NewSafetyHandler(container.SafetyService)
 		safety := protected.Group("/safety")
 		{
 			safety.GET("/events", safetyHandler.GetSafetyEvents)
+			safety.GET("/events/:id", safetyHandler.GetSafetyEvent)
+			safety.GET("/events/:id/trace", safetyHandler.GetSafetyEventTrace)
 			safety.GET("/score", safetyHandler.GetDriverScore)
+			safety.GET("/scores", safetyHandler.GetFleetDriverScores)
+			safety.POST("/scores/recalculate", middleware.RequireAdmin(), safetyHandler.RecalculateDriverScores)
+			safety.GET("/speeding-episodes", safetyHandler.GetSpeedingEpisodes)
+
+			// Coaching queue; drivers follow their own sessions from the app
+			coaches := middleware.RequireRole(services.CoachRoles...)
+			coachesAndDrivers := middleware.RequireRole(append([]models.Role{models.RoleDriver}, services.CoachRoles...)...)
+			safety.GET("/coaching", coachesAndDrivers, safetyHandler.GetCoachingQueue)
+			safety.POST("/coaching", coaches, safetyHandler.CreateCoachingSession)
+			safety.GET("/coaching/effectiveness", coaches, safetyHandler.GetCoachingEffectiveness)
+			safety.GET("/coaching/:id", coachesAndDrivers, safetyHandler.GetCoachingSession)
+			safety.PUT("/coaching/:id/assign", coaches, safetyHandler.AssignCoach)
+			safety.POST("/coaching/:id/notes", coaches, safetyHandler.AddCoachingNote)
+			safety.POST("/coaching/:id/resolve", coaches, safetyHandler.ResolveCoachingSession)
+			safety.POST("/coaching/:id/acknowledge", middleware.RequireDriver(), safetyHandler.AcknowledgeCoaching)
+
+			// Speed limits
+			speedLimitHandler := handlers.NewSpeedLimitHandler(container.SpeedLimitService)
+			safety.GET("/speed-limits", speedLimitHandler.GetSpeedLimit)
+			safety.POST("/speed-limits/import", middleware.RequireAdmin(), speedLimitHandler.ImportRoadData)
+			safety.GET("/speed-limits/overrides", speedLimitHandler.GetSpeedLimitOverrides)
+			safety.PUT("/speed-limits/overrides", middleware.RequireAdmin(), speedLimitHandler.SaveSpeedLimitOverride)
+			safety.DELETE("/speed-limits/overrides/:id", middleware.RequireAdmin(), speedLimitHandler.DeleteSpeedLimitOverride)
+			safety.PUT("/speed-limits/geofences/:id", middleware.RequireAdmin(), speedLimitHandler.SetGeofenceSpeedLimit)
 		}
 
 		// Telemetry
-		telemetryHandler := handlers.NewTelemetryHandler(services.NewTelemetryService(container.DB, container.MQTTService))
+		telemetryHandler := handlers.NewTelemetryHandler(container.TelemetryService)
 		telemetry := protected.Group("/telemetry")
 		{
 			telemetry.GET("/latest", telemetryHandler.GetLatestTelemetry)
 			telemetry.GET("/dtc", telemetryHandler.GetActiveDTCs)
+			telemetry.POST("/dtc", telemetryHandler.ReportDiagnostics)
+			telemetry.GET("/dtc/decode", telemetryHandler.DecodeDTC)
+			telemetry.GET("/dtc/catalog", telemetryHandler.GetDTCCatalog)
+			telemetry.PUT("/dtc/catalog", middleware.RequireAdmin(), telemetryHandler.SaveDTCCatalogEntry)
+			telemetry.GET("/anomalies", telemetryHandler.GetTelemetryAnomalies)
+			telemetry.GET("/thresholds", telemetryHandler.GetTelemetryThresholds)
+			telemetry.PUT("/thresholds", middleware.RequireAdmin(), telemetryHandler.SaveTelemetryThreshold)
+			telemetry.DELETE("/thresholds/:id", middleware.RequireAdmin(), telemetryHandler.DeleteTelemetryThreshold)
+
+			// Tracker devices
+			trackerHandler := handlers.NewTrackerDeviceHandler(container.DeviceGateway)
+			telemetry.GET("/devices", trackerHandler.GetTrackerDevices)
+			telemetry.PUT("/devices", middleware.RequireAdmin(), trackerHandler.SaveTrackerDevice)
+			telemetry.POST("/devices/decode", middleware.RequireAdmin(), trackerHandler.DecodeTrackerPayload)
+			telemetry.DELETE("/devices/:id", middleware.RequireAdmin(), trackerHandler.DeleteTrackerDevice)
+
+			// Accelerometer and gyro samples feed the safety detectors
+			telemetry.POST("/imu", safetyHandler.IngestIMU)
+			telemetry.DELETE("/imu/calibration/:vehicle_id", middleware.RequireAdmin(), safetyHandler.ResetIMUCalibration)
 		}
 
 		// Navigation
@@ -156,12 +261,30 @@ func RegisterRoutes(router *gin.RouterGroup, container *services.Container) {
 		}
 
 		// Video & Vision AI
-		videoHandler := handlers.NewVideoHandler(services.NewVideoService(container.DB))
 		video := protected.Group("/video")
 		{
 			video.POST("/cameras", videoHandler.RegisterCamera)
+			video.POST("/cameras/heartbeat", videoHandler.RecordHeartbeat)
+			video.GET("/cameras/health", videoHandler.GetCameraHealthReport)
+			video.GET("/cameras/:id/health", videoHandler.GetCameraStatusHistory)
 			video.POST("/events", videoHandler.ProcessAIEvent)
 			video.GET("/clips", videoHandler.GetClips)
+			video.GET("/clips/:id/playback", videoHandler.GetPlaybackURLs)
+
+			// Resumable clip uploads from cameras
+			video.POST("/uploads", videoHandler.StartClipUpload)
+			video.GET("/uploads/:upload_id", videoHandler.GetClipUpload)
+			video.PUT("/uploads/:upload_id", videoHandler.WriteClipChunk)
+			video.DELETE("/uploads/:upload_id", videoHandler.AbortClipUpload)
+
+			// Footage requests pushed to cameras, which poll for the ones they missed
+			video.POST("/retrievals", middleware.RequireRole(models.RoleAdmin, models.RoleOrgAdmin, models.RoleDispatcher), videoHandler.RequestFootage)
+			video.GET("/retrievals", videoHandler.GetRetrievalRequests)
+			video.POST("/retrievals/:id/fail", videoHandler.FailRetrievalRequest)
+
+			video.GET("/retention", videoHandler.GetRetentionPolicies)
+			video.PUT("/retention", middleware.RequireAdmin(), videoHandler.SetRetentionPolicy)
+			video.POST("/retention/apply", middleware.RequireAdmin(), videoHandler.ApplyRetention)
 		}
 
 		// Driver routes
@@ -242,6 +365,42 @@ func RegisterRoutes(router *gin.RouterGroup, container *services.Container) {
 			fuel.GET("/alerts/:id", fuelHandler.GetFuelAlert)
 			fuel.POST("/alerts/:id/resolve", middleware.RequireAdmin(), fuelHandler.ResolveFuelAlert)
 
+			// Tank-level sensor analysis
+			fuel.GET("/level-events", fuelHandler.GetFuelLevelEvents)
+			fuel.POST("/sensor-analysis", middleware.RequireAdmin(), fuelHandler.AnalyzeFuelLevel)
+
+			// Fuel thresholds
+			fuel.GET("/thresholds", fuelHandler.GetFuelThresholds)
+			fuel.GET("/thresholds/effective/:vehicle_id", fuelHandler.GetEffectiveFuelThresholds)
+			fuel.POST("/thresholds", middleware.RequireAdmin(), fuelHandler.CreateFuelThreshold)
+			fuel.POST("/thresholds/evaluate", middleware.RequireAdmin(), fuelHandler.EvaluateFuelThresholds)
+			fuel.PUT("/thresholds/:id", middleware.RequireAdmin(), fuelHandler.UpdateFuelThreshold)
+			fuel.DELETE("/thresholds/:id", middleware.RequireAdmin(), fuelHandler.DeleteFuelThreshold)
+
+			// Fuel anomaly models
+			fuel.GET("/models", middleware.RequireAdmin(), fuelHandler.GetFuelAnomalyModels)
+			fuel.POST("/models/train", middleware.RequireAdmin(), fuelHandler.TrainFuelAnomalyModels)
+			fuel.GET("/models/evaluation", middleware.RequireAdmin(), fuelHandler.EvaluateFuelAnomalyModel)
+
+			// Fuel cards
+			fuel.GET("/card-providers", middleware.RequireAdmin(), fuelHandler.GetFuelCardProviders)
+			fuel.POST("/card-providers", middleware.RequireAdmin(), fuelHandler.CreateFuelCardProvider)
+			fuel.PUT("/card-providers/:id", middleware.RequireAdmin(), fuelHandler.UpdateFuelCardProvider)
+			fuel.GET("/cards", middleware.RequireAdmin(), fuelHandler.GetFuelCards)
+			fuel.POST("/cards", middleware.RequireAdmin(), fuelHandler.CreateFuelCard)
+			fuel.PUT("/cards/:id", middleware.RequireAdmin(), fuelHandler.UpdateFuelCard)
+			fuel.GET("/card-imports", middleware.RequireAdmin(), fuelHandler.GetFuelCardImports)
+			fuel.POST("/card-imports", middleware.RequireAdmin(), fuelHandler.ImportFuelCardStatement)
+			fuel.GET("/card-imports/:id", middleware.RequireAdmin(), fuelHandler.GetFuelCardImport)
+			fuel.GET("/card-transactions", fuelHandler.GetFuelCardTransactions)
+			fuel.POST("/card-transactions/:id/reconcile", middleware.RequireAdmin(), fuelHandler.ReconcileFuelCardTransaction)
+
+			// Fuel prices
+			fuel.GET("/prices", fuelHandler.GetFuelPrices)
+			fuel.GET("/prices/trend", fuelHandler.GetFuelPriceTrend)
+			fuel.POST("/prices/feed", middleware.RequireAdmin(), fuelHandler.ImportFuelPriceFeed)
+			fuel.POST("/prices/rebuild", middleware.RequireAdmin(), fuelHandler.RebuildFuelPriceHistory)
+
 			// Fuel analytics
 			fuel.GET("/analytics", fuelHandler.GetFuelAnalytics)
 			fuel.GET("/analytics/:vehicle_id", fuelHandler.GetVehicleFuelAnalytics)
@@ -249,6 +408,13 @@ func RegisterRoutes(router *gin.RouterGroup, container *services.Container) {
 			// Fuel stations
 			fuel.GET("/stations", fuelHandler.GetNearbyFuelStations)
 			fuel.POST("/stations", middleware.RequireAdmin(), fuelHandler.CreateFuelStation)
+			fuel.POST("/stations/import", middleware.RequireAdmin(), fuelHandler.ImportFuelStations)
+			fuel.GET("/stations/along-trip/:trip_id", fuelHandler.GetFuelStationsAlongTrip)
+			fuel.GET("/stations/:id", fuelHandler.GetFuelStation)
+			fuel.PUT("/stations/:id", middleware.RequireAdmin(), fuelHandler.UpdateFuelStation)
+			fuel.GET("/fleets/:fleet_id/preferred-stations", fuelHandler.GetFleetPreferredStations)
+			fuel.PUT("/fleets/:fleet_id/preferred-stations/:station_id", middleware.RequireAdmin(), fuelHandler.SetFleetPreferredStation)
+			fuel.DELETE("/fleets/:fleet_id/preferred-stations/:station_id", middleware.RequireAdmin(), fuelHandler.RemoveFleetPreferredStation)
 		}
 
 		// Location and tracking routes
@@ -334,7 +500,13 @@ func RegisterRoutes(router *gin.RouterGroup, container *services.Container) {
 
 			// Audit logs
 			admin.GET("/audit-logs", analyticsHandler.GetAuditLogs)
+			admin.GET("/audit-logs/verify", analyticsHandler.VerifyAuditChain)
+			admin.GET("/audit-logs/checkpoints", analyticsHandler.GetAuditCheckpoints)
+			admin.POST("/audit-logs/checkpoints", analyticsHandler.CreateAuditCheckpoints)
+			admin.GET("/audit-logs/archives", analyticsHandler.GetAuditArchives)
 			admin.GET("/security-events", analyticsHandler.GetSecurityEvents)
+			admin.GET("/security-events/:id", analyticsHandler.GetSecurityEvent)
+			admin.POST("/security-events/:id/investigate", analyticsHandler.InvestigateSecurityEvent)
 		}
 
 		// MQTT routes for real-time communication
@@ -123,17 +123,29 @@ func (s *AssetService) UpdateInventory(itemID uint, quantityChange int) error {
 	}
 
 	newQuantity := item.Quantity + quantityChange
-	if newQuantity < 0 {
+	if newQuantity < item.ReservedQuantity {
 		return errors.New("insufficient inventory")
 	}
 
 	item.Quantity = newQuantity
-	return s.db.Save(&item).Error
+	if err := s.db.Save(&item).Error; err != nil {
+		return err
+	}
+
+	// Restocked items no longer need their purchase suggestion
+	if item.AvailableQuantity() >= item.MinQuantity {
+		now := time.Now()
+		return s.db.Model(&models.PurchaseOrderSuggestion{}).
+			Where("inventory_item_id = ? AND status IN ?", item.ID,
+				[]models.PurchaseOrderSuggestionStatus{models.PurchaseSuggestionOpen, models.PurchaseSuggestionOrdered}).
+			Updates(map[string]interface{}{"status": models.PurchaseSuggestionReceived, "closed_at": now}).Error
+	}
+	return nil
 }
 
-// GetInventoryLowStock returns items below minimum quantity
+// GetInventoryLowStock returns items whose stock not held for work orders is below minimum quantity
 func (s *AssetService) GetInventoryLowStock() ([]models.InventoryItem, error) {
 	var items []models.InventoryItem
-	err := s.db.Where("quantity < min_quantity").Find(&items).Error
+	err := s.db.Where("quantity - reserved_quantity < min_quantity").Find(&items).Error
 	return items, err
 }
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// vehicleAttribution is the trip a vehicle was on at a moment and the driver at the wheel: the
+// trip's driver, or else the driver whose duty log had them driving or on duty in the vehicle.
+// Either is nil when nothing places them.
+func vehicleAttribution(db *gorm.DB, vehicleID uint, at time.Time) (*models.Trip, *uint, error) {
+	trip, err := activeTrip(db, vehicleID, at)
+	if err != nil {
+		return nil, nil, err
+	}
+	if trip != nil && trip.DriverID != nil {
+		return trip, trip.DriverID, nil
+	}
+
+	var logs []models.DutyStatusLog
+	if err := db.Where("vehicle_id = ? AND status IN ? AND start_time <= ?", vehicleID,
+		[]models.DutyStatus{models.DutyStatusDriving, models.DutyStatusOnDuty}, at).
+		Where("end_time IS NULL OR end_time >= ?", at).
+		Order("start_time DESC").Limit(1).Find(&logs).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(logs) == 0 {
+		return trip, nil, nil
+	}
+	return trip, &logs[0].DriverID, nil
+}
+
+// activeTrip is the trip a vehicle was on at a moment, nil when it was on none
+func activeTrip(db *gorm.DB, vehicleID uint, at time.Time) (*models.Trip, error) {
+	var trips []models.Trip
+	if err := db.Where("vehicle_id = ? AND actual_pickup_time IS NOT NULL AND actual_pickup_time <= ?", vehicleID, at).
+		Where("(actual_arrival IS NULL OR actual_arrival >= ?) AND status <> ?", at, models.TripStatusCancelled).
+		Order("actual_pickup_time DESC").Limit(1).Find(&trips).Error; err != nil {
+		return nil, err
+	}
+	if len(trips) == 0 {
+		return nil, nil
+	}
+	return &trips[0], nil
+}
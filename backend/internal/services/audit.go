@@ -1,13 +1,20 @@
 package services
 
 import (
+	"crypto/ed25519"
+	"sync"
+
 	"github.com/fleetflow/backend/internal/models"
 	"gorm.io/gorm"
 )
 
-// AuditService handles audit logging operations
+// AuditService handles audit logging operations. Every AuditLog is appended to its
+// organization's hash chain; see audit_chain.go for verification, checkpoints and archival.
 type AuditService struct {
-	db *gorm.DB
+	db             *gorm.DB
+	chainMu        sync.Mutex
+	signingKey     ed25519.PrivateKey
+	archiveStorage StorageProvider
 }
 
 // NewAuditService creates a new audit service
@@ -34,6 +41,11 @@ func (s *AuditService) LogAction(
 
 	// Set context information if provided
 	if context != nil {
+		if context.OrganizationID != nil {
+			auditLog.OrganizationID = *context.OrganizationID
+		} else {
+			auditLog.OrganizationID = s.organizationForUser(context.UserID)
+		}
 		auditLog.UserID = context.UserID
 		auditLog.DriverID = context.DriverID
 		auditLog.VehicleID = context.VehicleID
@@ -59,7 +71,7 @@ func (s *AuditService) LogAction(
 		}
 	}
 
-	return s.db.Create(auditLog).Error
+	return s.appendLog(auditLog)
 }
 
 // LogSecurityEvent logs a security event
@@ -106,6 +118,8 @@ func (s *AuditService) GetAuditLogs(page, limit int, filters map[string]interfac
 			query = query.Where("severity = ?", value)
 		case "user_id":
 			query = query.Where("user_id = ?", value)
+		case "organization_id":
+			query = query.Where("organization_id = ?", value)
 		case "table_name":
 			query = query.Where("table_name = ?", value)
 		case "from_date":
@@ -152,6 +166,8 @@ func (s *AuditService) GetSecurityEvents(page, limit int, filters map[string]int
 			query = query.Where("user_id = ?", value)
 		case "is_investigated":
 			query = query.Where("is_investigated = ?", value)
+		case "is_blocked":
+			query = query.Where("is_blocked = ?", value)
 		case "from_date":
 			query = query.Where("created_at >= ?", value)
 		case "to_date":
@@ -197,6 +213,7 @@ func (s *AuditService) LogUserAction(userID uint, action models.AuditAction, des
 		Description: description,
 		UserID:      &userID,
 	}
+	auditLog.OrganizationID = s.organizationForUser(&userID)
 
 	if metadata != nil {
 		if err := auditLog.SetMetadata(metadata); err != nil {
@@ -204,7 +221,7 @@ func (s *AuditService) LogUserAction(userID uint, action models.AuditAction, des
 		}
 	}
 
-	return s.db.Create(auditLog).Error
+	return s.appendLog(auditLog)
 }
 
 // LogSystemAction logs system-level actions
@@ -221,7 +238,7 @@ func (s *AuditService) LogSystemAction(action models.AuditAction, description st
 		}
 	}
 
-	return s.db.Create(auditLog).Error
+	return s.appendLog(auditLog)
 }
 
 // LogEntityChange logs changes to database entities
@@ -241,6 +258,7 @@ func (s *AuditService) LogEntityChange(
 		RecordID:    &recordID,
 		UserID:      userID,
 	}
+	auditLog.OrganizationID = s.organizationForUser(userID)
 
 	if oldValues != nil {
 		if err := auditLog.SetOldValues(oldValues); err != nil {
@@ -254,18 +272,7 @@ func (s *AuditService) LogEntityChange(
 		}
 	}
 
-	return s.db.Create(auditLog).Error
-}
-
-// CleanupOldAuditLogs removes audit logs older than specified days
-func (s *AuditService) CleanupOldAuditLogs(daysToKeep int) error {
-	// This should be run as a scheduled job
-	cutoffDate := func() string {
-		// Calculate cutoff date
-		return "30 days ago" // Placeholder
-	}()
-
-	return s.db.Where("created_at < ?", cutoffDate).Delete(&models.AuditLog{}).Error
+	return s.appendLog(auditLog)
 }
 
 // GetUserActivitySummary gets activity summary for a specific user
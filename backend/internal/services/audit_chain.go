@@ -0,0 +1,528 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// auditVerifyBatchSize bounds memory while walking a chain
+	auditVerifyBatchSize = 500
+
+	// auditArchiveMaxRecords caps the size of a single archive object
+	auditArchiveMaxRecords = 10000
+)
+
+// Kinds of problems reported by chain verification
+const (
+	AuditIssueGap                = "gap"
+	AuditIssueDuplicateSequence  = "duplicate_sequence"
+	AuditIssueBrokenLink         = "broken_link"
+	AuditIssueHashMismatch       = "hash_mismatch"
+	AuditIssueTruncated          = "truncated"
+	AuditIssueCheckpointMismatch = "checkpoint_mismatch"
+	AuditIssueCheckpointSig      = "checkpoint_signature"
+	AuditIssueArchiveMismatch    = "archive_mismatch"
+)
+
+// ErrAuditChainBroken is returned when archival is refused because the range fails verification
+var ErrAuditChainBroken = errors.New("audit chain failed verification")
+
+// AuditChainIssue describes one verification failure
+type AuditChainIssue struct {
+	Kind     string `json:"kind"`
+	Sequence uint64 `json:"sequence"`
+	LogID    uint   `json:"log_id,omitempty"`
+	Detail   string `json:"detail"`
+}
+
+// AuditChainReport is the result of verifying one organization's audit chain
+type AuditChainReport struct {
+	OrganizationID      uint              `json:"organization_id"`
+	Valid               bool              `json:"valid"`
+	VerifiedRecords     int64             `json:"verified_records"`
+	LegacyRecords       int64             `json:"legacy_records"`
+	ArchivedThrough     uint64            `json:"archived_through"`
+	ArchivesVerified    int               `json:"archives_verified"`
+	LastSequence        uint64            `json:"last_sequence"`
+	HeadHash            string            `json:"head_hash"`
+	CheckpointsVerified int               `json:"checkpoints_verified"`
+	Issues              []AuditChainIssue `json:"issues"`
+	PublicKey           string            `json:"public_key,omitempty"`
+	VerifiedAt          time.Time         `json:"verified_at"`
+}
+
+func (r *AuditChainReport) addIssue(kind string, sequence uint64, logID uint, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, AuditChainIssue{Kind: kind, Sequence: sequence, LogID: logID, Detail: fmt.Sprintf(format, args...)})
+}
+
+// chainWalker checks records one by one against the expected sequence and previous hash
+type chainWalker struct {
+	report      *AuditChainReport
+	checkpoints map[uint64]models.AuditCheckpoint
+	nextSeq     uint64
+	prevHash    string
+}
+
+func (w *chainWalker) visit(entry *models.AuditLog) {
+	switch {
+	case entry.Sequence < w.nextSeq:
+		w.report.addIssue(AuditIssueDuplicateSequence, entry.Sequence, entry.ID, "sequence %d appears more than once", entry.Sequence)
+		return
+	case entry.Sequence > w.nextSeq:
+		w.report.addIssue(AuditIssueGap, w.nextSeq, entry.ID, "records %d-%d are missing", w.nextSeq, entry.Sequence-1)
+	}
+
+	if entry.PrevHash != w.prevHash {
+		w.report.addIssue(AuditIssueBrokenLink, entry.Sequence, entry.ID, "prev_hash does not match the hash of record %d", entry.Sequence-1)
+	}
+	if computed := entry.ComputeChainHash(); computed != entry.Hash {
+		w.report.addIssue(AuditIssueHashMismatch, entry.Sequence, entry.ID, "record content does not match its hash")
+	}
+	if cp, ok := w.checkpoints[entry.Sequence]; ok {
+		if cp.Hash != entry.Hash {
+			w.report.addIssue(AuditIssueCheckpointMismatch, entry.Sequence, entry.ID, "checkpoint %d signed a different hash for this record", cp.ID)
+		}
+		w.report.CheckpointsVerified++
+	}
+
+	w.nextSeq = entry.Sequence + 1
+	w.prevHash = entry.Hash
+	w.report.LastSequence = entry.Sequence
+}
+
+// SetCheckpointSigningKey derives the Ed25519 key used to sign checkpoints from a secret
+func (s *AuditService) SetCheckpointSigningKey(secret string) {
+	seed := sha256.Sum256([]byte("fleetflow-audit-checkpoint:" + secret))
+	s.signingKey = ed25519.NewKeyFromSeed(seed[:])
+}
+
+// SetArchiveStorage sets the cold storage used by ArchiveOldAuditLogs
+func (s *AuditService) SetArchiveStorage(storage StorageProvider) {
+	s.archiveStorage = storage
+}
+
+// CheckpointPublicKey returns the base64 public key auditors use to verify checkpoints
+func (s *AuditService) CheckpointPublicKey() string {
+	if s.signingKey == nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(s.signingKey.Public().(ed25519.PublicKey))
+}
+
+func (s *AuditService) checkpointKeyID() string {
+	sum := sha256.Sum256(s.signingKey.Public().(ed25519.PublicKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+// appendLog assigns the next sequence in the record's chain, links and hashes it, and stores it
+func (s *AuditService) appendLog(auditLog *models.AuditLog) error {
+	if auditLog.Severity == "" {
+		auditLog.Severity = models.AuditSeverityInfo
+	}
+
+	s.chainMu.Lock()
+	defer s.chainMu.Unlock()
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		head := models.AuditChainHead{OrganizationID: auditLog.OrganizationID, Hash: models.AuditGenesisHash}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&head).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&head, "organization_id = ?", auditLog.OrganizationID).Error; err != nil {
+			return err
+		}
+
+		auditLog.Sequence = head.Sequence + 1
+		auditLog.PrevHash = head.Hash
+		auditLog.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		auditLog.Hash = auditLog.ComputeChainHash()
+
+		if err := tx.Create(auditLog).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.AuditChainHead{}).
+			Where("organization_id = ?", auditLog.OrganizationID).
+			Updates(map[string]interface{}{
+				"sequence":   auditLog.Sequence,
+				"hash":       auditLog.Hash,
+				"updated_at": time.Now(),
+			}).Error
+	})
+}
+
+// organizationForUser returns the chain a user's actions belong to (0 for platform users)
+func (s *AuditService) organizationForUser(userID *uint) uint {
+	if userID == nil {
+		return 0
+	}
+	var orgID *uint
+	s.db.Model(&models.UserAccount{}).Select("organization_id").Where("id = ?", *userID).Scan(&orgID)
+	if orgID == nil {
+		return 0
+	}
+	return *orgID
+}
+
+// ListAuditChains returns the head of every audit chain
+func (s *AuditService) ListAuditChains() ([]models.AuditChainHead, error) {
+	var heads []models.AuditChainHead
+	err := s.db.Order("organization_id").Find(&heads).Error
+	return heads, err
+}
+
+// VerifyAuditChain walks an organization's chain and reports gaps, edits, truncation and
+// checkpoint mismatches. With includeArchives it also re-verifies archived ranges from storage.
+func (s *AuditService) VerifyAuditChain(organizationID uint, includeArchives bool) (*AuditChainReport, error) {
+	report := &AuditChainReport{
+		OrganizationID: organizationID,
+		PublicKey:      s.CheckpointPublicKey(),
+		VerifiedAt:     time.Now(),
+	}
+
+	var checkpoints []models.AuditCheckpoint
+	if err := s.db.Where("organization_id = ?", organizationID).Order("sequence").Find(&checkpoints).Error; err != nil {
+		return nil, err
+	}
+	walker := &chainWalker{
+		report:      report,
+		checkpoints: make(map[uint64]models.AuditCheckpoint, len(checkpoints)),
+		nextSeq:     1,
+		prevHash:    models.AuditGenesisHash,
+	}
+	for _, cp := range checkpoints {
+		if !s.verifyCheckpointSignature(&cp) {
+			report.addIssue(AuditIssueCheckpointSig, cp.Sequence, 0, "checkpoint %d has an invalid signature", cp.ID)
+			continue
+		}
+		walker.checkpoints[cp.Sequence] = cp
+	}
+
+	var archives []models.AuditArchive
+	if err := s.db.Where("organization_id = ?", organizationID).Order("from_sequence").Find(&archives).Error; err != nil {
+		return nil, err
+	}
+	for _, archive := range archives {
+		if archive.FromSequence != walker.nextSeq || archive.PrevHash != walker.prevHash {
+			report.addIssue(AuditIssueArchiveMismatch, archive.FromSequence, 0,
+				"archive %d does not continue the chain at record %d", archive.ID, walker.nextSeq)
+		}
+		if includeArchives {
+			if err := s.verifyArchive(&archive, walker); err != nil {
+				report.addIssue(AuditIssueArchiveMismatch, archive.FromSequence, 0, "archive %d: %v", archive.ID, err)
+			}
+			report.ArchivesVerified++
+		} else {
+			// Trust the archive manifest; its boundary is covered by a checkpoint
+			if cp, ok := walker.checkpoints[archive.ToSequence]; ok && cp.Hash != archive.LastHash {
+				report.addIssue(AuditIssueCheckpointMismatch, archive.ToSequence, 0, "checkpoint %d disagrees with archive %d", cp.ID, archive.ID)
+			}
+		}
+		walker.nextSeq = archive.ToSequence + 1
+		walker.prevHash = archive.LastHash
+		report.ArchivedThrough = archive.ToSequence
+		report.LastSequence = archive.ToSequence
+	}
+
+	lastSeen := uint64(0)
+	for {
+		var batch []models.AuditLog
+		if err := s.db.Where("organization_id = ? AND sequence > ?", organizationID, lastSeen).
+			Order("sequence, id").Limit(auditVerifyBatchSize).Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		for i := range batch {
+			walker.visit(&batch[i])
+			report.VerifiedRecords++
+		}
+		if len(batch) < auditVerifyBatchSize {
+			break
+		}
+		lastSeen = batch[len(batch)-1].Sequence
+	}
+	report.HeadHash = walker.prevHash
+
+	if err := s.db.Model(&models.AuditLog{}).
+		Where("organization_id = ? AND (sequence = 0 OR sequence IS NULL)", organizationID).
+		Count(&report.LegacyRecords).Error; err != nil {
+		return nil, err
+	}
+
+	// Rows removed from the end leave no gap, but the head and checkpoints still remember them
+	var head models.AuditChainHead
+	if err := s.db.First(&head, "organization_id = ?", organizationID).Error; err == nil && head.Sequence > report.LastSequence {
+		report.addIssue(AuditIssueTruncated, report.LastSequence+1, 0,
+			"chain head is at record %d but the chain ends at %d", head.Sequence, report.LastSequence)
+	}
+	if len(checkpoints) > 0 {
+		if last := checkpoints[len(checkpoints)-1]; last.Sequence > report.LastSequence {
+			report.addIssue(AuditIssueTruncated, report.LastSequence+1, 0,
+				"checkpoint %d covers record %d but the chain ends at %d", last.ID, last.Sequence, report.LastSequence)
+		}
+	}
+
+	report.Valid = len(report.Issues) == 0
+	return report, nil
+}
+
+// verifyArchive downloads an archive and walks its records as part of the chain
+func (s *AuditService) verifyArchive(archive *models.AuditArchive, walker *chainWalker) error {
+	if s.archiveStorage == nil {
+		return errors.New("archive storage is not configured")
+	}
+	data, err := s.archiveStorage.DownloadFile(archive.StorageKey)
+	if err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != archive.ContentSHA256 {
+		return errors.New("content checksum does not match the manifest")
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	count := 0
+	for scanner.Scan() {
+		var entry models.AuditLog
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("record %d is not valid JSON: %w", count+1, err)
+		}
+		walker.visit(&entry)
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if count != archive.RecordCount || walker.prevHash != archive.LastHash {
+		return fmt.Errorf("archive holds %d records ending in %s, manifest says %d ending in %s",
+			count, walker.prevHash, archive.RecordCount, archive.LastHash)
+	}
+	return nil
+}
+
+// CreateCheckpoint signs the current head of an organization's chain.
+// Returns the existing checkpoint when the head has not moved since the last one.
+func (s *AuditService) CreateCheckpoint(organizationID uint) (*models.AuditCheckpoint, error) {
+	var head models.AuditChainHead
+	if err := s.db.First(&head, "organization_id = ?", organizationID).Error; err != nil {
+		return nil, err
+	}
+	return s.signCheckpoint(organizationID, head.Sequence, head.Hash)
+}
+
+// CreateCheckpoints signs the head of every chain that advanced since its last checkpoint
+func (s *AuditService) CreateCheckpoints() ([]models.AuditCheckpoint, error) {
+	heads, err := s.ListAuditChains()
+	if err != nil {
+		return nil, err
+	}
+
+	var created []models.AuditCheckpoint
+	for _, head := range heads {
+		if head.Sequence == 0 {
+			continue
+		}
+		cp, err := s.signCheckpoint(head.OrganizationID, head.Sequence, head.Hash)
+		if err != nil {
+			return created, err
+		}
+		created = append(created, *cp)
+	}
+	return created, nil
+}
+
+// GetCheckpoints lists an organization's checkpoints, newest first
+func (s *AuditService) GetCheckpoints(organizationID uint, limit int) ([]models.AuditCheckpoint, error) {
+	var checkpoints []models.AuditCheckpoint
+	err := s.db.Where("organization_id = ?", organizationID).Order("sequence DESC").Limit(limit).Find(&checkpoints).Error
+	return checkpoints, err
+}
+
+func (s *AuditService) signCheckpoint(organizationID uint, sequence uint64, hash string) (*models.AuditCheckpoint, error) {
+	if s.signingKey == nil {
+		return nil, errors.New("audit checkpoint signing key is not configured")
+	}
+
+	var existing models.AuditCheckpoint
+	err := s.db.Where("organization_id = ? AND sequence = ?", organizationID, sequence).First(&existing).Error
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	cp := &models.AuditCheckpoint{
+		OrganizationID: organizationID,
+		Sequence:       sequence,
+		Hash:           hash,
+		KeyID:          s.checkpointKeyID(),
+		SignedAt:       time.Now().UTC().Truncate(time.Second),
+	}
+	cp.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(s.signingKey, cp.SigningPayload()))
+	if err := s.db.Create(cp).Error; err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+func (s *AuditService) verifyCheckpointSignature(cp *models.AuditCheckpoint) bool {
+	if s.signingKey == nil {
+		return false
+	}
+	signature, err := base64.StdEncoding.DecodeString(cp.Signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(s.signingKey.Public().(ed25519.PublicKey), cp.SigningPayload(), signature)
+}
+
+// GetAuditArchives lists archived ranges, optionally for one organization
+func (s *AuditService) GetAuditArchives(organizationID *uint) ([]models.AuditArchive, error) {
+	var archives []models.AuditArchive
+	query := s.db.Order("organization_id, from_sequence")
+	if organizationID != nil {
+		query = query.Where("organization_id = ?", *organizationID)
+	}
+	err := query.Find(&archives).Error
+	return archives, err
+}
+
+// ArchiveOldAuditLogs moves chained records older than daysToKeep to cold storage.
+// Each chain is archived as contiguous ranges from its start; a range is only removed from
+// the database after it verifies, uploads, and its last record is covered by a signed checkpoint.
+func (s *AuditService) ArchiveOldAuditLogs(daysToKeep int) ([]models.AuditArchive, error) {
+	if s.archiveStorage == nil {
+		return nil, errors.New("archive storage is not configured")
+	}
+	cutoff := time.Now().AddDate(0, 0, -daysToKeep)
+
+	heads, err := s.ListAuditChains()
+	if err != nil {
+		return nil, err
+	}
+
+	var archived []models.AuditArchive
+	for _, head := range heads {
+		for {
+			archive, err := s.archiveNextRange(head.OrganizationID, cutoff)
+			if err != nil {
+				return archived, fmt.Errorf("organization %d: %w", head.OrganizationID, err)
+			}
+			if archive == nil {
+				break
+			}
+			archived = append(archived, *archive)
+		}
+	}
+	return archived, nil
+}
+
+// archiveNextRange archives up to auditArchiveMaxRecords records following the last archive
+func (s *AuditService) archiveNextRange(organizationID uint, cutoff time.Time) (*models.AuditArchive, error) {
+	fromSeq, prevHash := uint64(1), models.AuditGenesisHash
+	var last models.AuditArchive
+	if err := s.db.Where("organization_id = ?", organizationID).Order("to_sequence DESC").First(&last).Error; err == nil {
+		fromSeq, prevHash = last.ToSequence+1, last.LastHash
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var records []models.AuditLog
+	if err := s.db.Where("organization_id = ? AND sequence >= ? AND created_at < ?", organizationID, fromSeq, cutoff).
+		Order("sequence").Limit(auditArchiveMaxRecords).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	// Refuse to archive (and so delete) anything that does not verify: it is evidence
+	report := &AuditChainReport{}
+	walker := &chainWalker{report: report, nextSeq: fromSeq, prevHash: prevHash}
+	var buf bytes.Buffer
+	for i := range records {
+		walker.visit(&records[i])
+		line, err := json.Marshal(&records[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if len(report.Issues) > 0 {
+		return nil, fmt.Errorf("%w at record %d: %s", ErrAuditChainBroken, report.Issues[0].Sequence, report.Issues[0].Detail)
+	}
+
+	first, lastRecord := records[0], records[len(records)-1]
+	if _, err := s.signCheckpoint(organizationID, lastRecord.Sequence, lastRecord.Hash); err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("audit-archive/org-%d/%020d-%020d.jsonl", organizationID, first.Sequence, lastRecord.Sequence)
+	url, err := s.archiveStorage.UploadFile(key, buf.Bytes(), "application/x-ndjson")
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload archive: %w", err)
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	archive := &models.AuditArchive{
+		OrganizationID: organizationID,
+		FromSequence:   first.Sequence,
+		ToSequence:     lastRecord.Sequence,
+		RecordCount:    len(records),
+		PrevHash:       prevHash,
+		LastHash:       lastRecord.Hash,
+		ContentSHA256:  hex.EncodeToString(sum[:]),
+		StorageKey:     key,
+		StorageURL:     url,
+		OldestRecordAt: first.CreatedAt,
+		NewestRecordAt: lastRecord.CreatedAt,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(archive).Error; err != nil {
+			return err
+		}
+		return tx.Where("organization_id = ? AND sequence BETWEEN ? AND ?", organizationID, first.Sequence, lastRecord.Sequence).
+			Delete(&models.AuditLog{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("🗄️ Archived audit records %d-%d of organization %d to %s", first.Sequence, lastRecord.Sequence, organizationID, key)
+	return archive, nil
+}
+
+// StartChainMaintenance periodically signs checkpoints and archives records past retention
+func (s *AuditService) StartChainMaintenance(interval time.Duration, retentionDays int) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := s.CreateCheckpoints(); err != nil {
+				log.Printf("❌ Failed to create audit checkpoints: %v", err)
+			}
+			if retentionDays > 0 && s.archiveStorage != nil {
+				if _, err := s.ArchiveOldAuditLogs(retentionDays); err != nil {
+					log.Printf("❌ Failed to archive audit logs: %v", err)
+				}
+			}
+		}
+	}()
+}
@@ -216,13 +216,6 @@ func (s *AuthService) UpdateUserProfile(userID uint, updates map[string]interfac
 	return updatedUser, nil
 }
 
-// ChangePassword changes user password (if password auth is implemented)
-func (s *AuthService) ChangePassword(userID uint, oldPassword, newPassword string) error {
-	// This is a placeholder for password-based authentication
-	// Currently, the system uses OTP-based auth only
-	return errors.New("password authentication not implemented")
-}
-
 // DeactivateUser deactivates a user account
 func (s *AuthService) DeactivateUser(userID, deactivatedBy uint) error {
 	user, err := s.repo.GetUserByID(userID)
@@ -77,6 +77,58 @@ func (m *MockAuthRepository) UpdateUserFields(userID uint, updates map[string]in
 	return args.Error(0)
 }
 
+func (m *MockAuthRepository) GetUserByEmail(email string) (*models.UserAccount, error) {
+	args := m.Called(email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserAccount), args.Error(1)
+}
+
+func (m *MockAuthRepository) GetSecuritySettings() (*models.SecuritySettings, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SecuritySettings), args.Error(1)
+}
+
+func (m *MockAuthRepository) ReplaceRecoveryCodes(userID uint, codes []models.RecoveryCode) error {
+	args := m.Called(userID, codes)
+	return args.Error(0)
+}
+
+func (m *MockAuthRepository) GetUnusedRecoveryCodes(userID uint) ([]models.RecoveryCode, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.RecoveryCode), args.Error(1)
+}
+
+func (m *MockAuthRepository) MarkRecoveryCodeUsed(id uint) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthRepository) GetUserByEmailVerification(tokenHash string) (*models.UserAccount, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserAccount), args.Error(1)
+}
+
+func (m *MockAuthRepository) IncrementFailedLoginAttempts(userID uint) (int, error) {
+	args := m.Called(userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAuthRepository) AdvanceTOTPStep(userID uint, step int64) (bool, error) {
+	args := m.Called(userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
 func TestAuthService_SendOTP(t *testing.T) {
 	// Setup
 	mockRepo := new(MockAuthRepository)
@@ -0,0 +1,380 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// defaultHeartbeatTimeout is how long a camera may stay silent with the ignition on before it is offline
+const defaultHeartbeatTimeout = 5 * time.Minute
+
+// CameraHeartbeat is a dashcam's periodic health report
+type CameraHeartbeat struct {
+	Serial         string    `json:"serial"`
+	FirmwareVer    string    `json:"firmware_ver,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+	Ignition       *bool     `json:"ignition,omitempty"`       // ACC line, when the camera is wired to it
+	Recording      *bool     `json:"recording,omitempty"`      // Whether footage is being written
+	SDCardStatus   string    `json:"sd_card_status,omitempty"` // OK, MISSING, FULL, ERROR
+	LensObstructed bool      `json:"lens_obstructed,omitempty"`
+	Errors         []string  `json:"errors,omitempty"` // Other faults, by code
+}
+
+// SetHeartbeatTimeout changes how long a camera may stay silent before it is offline
+func (s *VideoService) SetHeartbeatTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		s.heartbeatTimeout = timeout
+	}
+}
+
+// Start listens for camera heartbeats over MQTT
+func (s *VideoService) Start() error {
+	if err := s.mqttService.SubscribeToCameraHeartbeats(s.handleHeartbeat); err != nil {
+		return fmt.Errorf("failed to subscribe to camera heartbeats: %w", err)
+	}
+
+	log.Println("🎥 Video Service started: Monitoring camera health")
+	return nil
+}
+
+func (s *VideoService) handleHeartbeat(heartbeat *CameraHeartbeat) {
+	if _, err := s.ProcessHeartbeat(context.Background(), heartbeat, time.Now()); err != nil {
+		log.Printf("❌ Failed to process heartbeat from camera %s: %v", heartbeat.Serial, err)
+	}
+}
+
+// ProcessHeartbeat records a camera's heartbeat. A heartbeat reporting faults marks the camera
+// malfunctioning; a clean one brings it back online.
+func (s *VideoService) ProcessHeartbeat(ctx context.Context, heartbeat *CameraHeartbeat, now time.Time) (*models.Camera, error) {
+	camera, err := s.GetCameraBySerial(heartbeat.Serial)
+	if err != nil {
+		return nil, err
+	}
+	at := heartbeat.Timestamp
+	if at.IsZero() || at.After(now) {
+		at = now
+	}
+	if at.Before(camera.LastHeartbeat) {
+		// Delivered late; a newer heartbeat already set the camera's state
+		return camera, nil
+	}
+
+	faults := heartbeatFaults(heartbeat)
+	fields := []string{"LastHeartbeat", "Faults"}
+	if heartbeat.FirmwareVer != "" {
+		camera.FirmwareVer = heartbeat.FirmwareVer
+		fields = append(fields, "FirmwareVer")
+	}
+	if heartbeat.Ignition != nil {
+		camera.Ignition = heartbeat.Ignition
+		fields = append(fields, "Ignition")
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		status, reason := models.CameraStatusOnline, "heartbeat received"
+		if len(faults) > 0 {
+			status, reason = models.CameraStatusMalfunction, "reported "+strings.Join(faults, ", ")
+		}
+		if err := s.setCameraStatus(tx, camera, status, reason, faults, at); err != nil {
+			return err
+		}
+		camera.LastHeartbeat, camera.Faults = at, faults
+		return tx.Model(camera).Select(fields).Updates(camera).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return camera, nil
+}
+
+// heartbeatFaults gathers the faults a heartbeat reports, sorted and without repeats
+func heartbeatFaults(heartbeat *CameraHeartbeat) []string {
+	seen := make(map[string]bool)
+	switch strings.ToUpper(heartbeat.SDCardStatus) {
+	case "MISSING":
+		seen[models.CameraFaultSDCardMissing] = true
+	case "FULL":
+		seen[models.CameraFaultSDCardFull] = true
+	case "ERROR", "FAILED", "FAILURE":
+		seen[models.CameraFaultSDCardFailure] = true
+	}
+	if heartbeat.LensObstructed {
+		seen[models.CameraFaultLensObstructed] = true
+	}
+	// A camera on a parked vehicle is allowed to stop recording
+	if heartbeat.Recording != nil && !*heartbeat.Recording && (heartbeat.Ignition == nil || *heartbeat.Ignition) {
+		seen[models.CameraFaultNotRecording] = true
+	}
+	for _, code := range heartbeat.Errors {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			seen[code] = true
+		}
+	}
+	faults := make([]string, 0, len(seen))
+	for code := range seen {
+		faults = append(faults, code)
+	}
+	sort.Strings(faults)
+	return faults
+}
+
+// setCameraStatus moves a camera to a status, recording the change and alerting dispatch when
+// the camera stops working. A malfunctioning camera reporting different faults is a change too.
+func (s *VideoService) setCameraStatus(tx *gorm.DB, camera *models.Camera, status models.CameraStatus, reason string, faults []string, at time.Time) error {
+	if camera.Status == status && (status != models.CameraStatusMalfunction || strings.Join(camera.Faults, ",") == strings.Join(faults, ",")) {
+		return nil
+	}
+	change := models.CameraStatusChange{
+		CameraID:   camera.ID,
+		FromStatus: camera.Status,
+		ToStatus:   status,
+		Reason:     reason,
+		Faults:     faults,
+		ChangedAt:  at,
+	}
+	if err := tx.Create(&change).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(camera).Updates(map[string]interface{}{
+		"status":            status,
+		"status_reason":     reason,
+		"status_changed_at": at,
+	}).Error; err != nil {
+		return err
+	}
+	camera.Status, camera.StatusReason, camera.StatusChangedAt = status, reason, &at
+
+	if status == models.CameraStatusOffline || status == models.CameraStatusMalfunction {
+		s.sendCameraAlert(camera, fmt.Sprintf("Camera %s is %s: %s", camera.SerialNumber, status, reason))
+	}
+	return nil
+}
+
+func (s *VideoService) sendCameraAlert(camera *models.Camera, message string) {
+	if s.mqttService == nil || !s.mqttService.IsEnabled() {
+		return
+	}
+	alert := &FleetAlert{
+		Type:      "CAMERA_HEALTH",
+		Severity:  "MEDIUM",
+		VehicleID: camera.VehicleID,
+		Message:   message,
+		Timestamp: time.Now(),
+	}
+	if err := s.mqttService.PublishFleetAlert(alert); err != nil {
+		log.Printf("❌ Failed to publish camera alert: %v", err)
+	}
+}
+
+// CheckCameraHealth marks cameras that stopped sending heartbeats offline, or on standby when
+// their vehicle's ignition is off. A camera whose vehicle was just switched on gets the heartbeat
+// timeout from then to wake up. Returns how many cameras changed status.
+func (s *VideoService) CheckCameraHealth(ctx context.Context, now time.Time) (int, error) {
+	db := s.db.WithContext(ctx)
+	cutoff := now.Add(-s.heartbeatTimeout)
+	var cameras []models.Camera
+	// Offline vehicle cameras are checked again in case their vehicle has since been parked
+	if err := db.Where("last_heartbeat < ?", cutoff).
+		Where("status <> ? OR vehicle_id IS NOT NULL", models.CameraStatusOffline).
+		Find(&cameras).Error; err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for i := range cameras {
+		camera := &cameras[i]
+		status, reason := models.CameraStatusOffline, "no heartbeat since "+camera.LastHeartbeat.UTC().Format(time.RFC3339)
+		if camera.LastHeartbeat.IsZero() {
+			reason = "no heartbeat received"
+		}
+		if camera.VehicleID != nil {
+			ignition, ignitionAt, err := s.vehicleIgnition(ctx, camera)
+			if err != nil {
+				return changed, err
+			}
+			switch {
+			case ignition != nil && !*ignition:
+				status, reason = models.CameraStatusStandby, "vehicle ignition off"
+			case ignition != nil && ignitionAt.After(cutoff):
+				// Switched on moments ago; the camera is still booting
+				continue
+			}
+		}
+		if camera.Status == status {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return s.setCameraStatus(tx, camera, status, reason, camera.Faults, now)
+		})
+		if err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// vehicleIgnition is the latest ignition state known for a camera's vehicle, from the camera's
+// own heartbeats or the vehicle's tracker, with when it was reported
+func (s *VideoService) vehicleIgnition(ctx context.Context, camera *models.Camera) (*bool, time.Time, error) {
+	ignition, at := camera.Ignition, camera.LastHeartbeat
+	var trackers []models.TrackerDevice
+	if err := s.db.WithContext(ctx).Where("vehicle_id = ? AND ignition IS NOT NULL AND ignition_at IS NOT NULL", *camera.VehicleID).
+		Order("ignition_at DESC").Limit(1).Find(&trackers).Error; err != nil {
+		return nil, time.Time{}, err
+	}
+	if len(trackers) > 0 && (ignition == nil || trackers[0].IgnitionAt.After(at)) {
+		ignition, at = trackers[0].Ignition, *trackers[0].IgnitionAt
+	}
+	return ignition, at, nil
+}
+
+// StartCameraHealthMonitor checks for silent cameras on an interval
+func (s *VideoService) StartCameraHealthMonitor(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			changed, err := s.CheckCameraHealth(context.Background(), time.Now())
+			if err != nil {
+				log.Printf("❌ Camera health check failed: %v", err)
+				continue
+			}
+			if changed > 0 {
+				log.Printf("🎥 Camera health: %d camera(s) changed status", changed)
+			}
+		}
+	}()
+}
+
+// CameraHealth is one camera's line in the fleet health report
+type CameraHealth struct {
+	CameraID        uint                `json:"camera_id"`
+	SerialNumber    string              `json:"serial_number"`
+	VehicleID       *uint               `json:"vehicle_id,omitempty"`
+	Status          models.CameraStatus `json:"status"`
+	StatusReason    string              `json:"status_reason,omitempty"`
+	StatusChangedAt *time.Time          `json:"status_changed_at,omitempty"`
+	Faults          []string            `json:"faults,omitempty"`
+	FirmwareVer     string              `json:"firmware_ver,omitempty"`
+	LastHeartbeat   time.Time           `json:"last_heartbeat"`
+	Incidents       int                 `json:"incidents"`        // Times it went offline or malfunctioned in the window
+	DowntimeMinutes int                 `json:"downtime_minutes"` // Time offline or malfunctioning in the window
+	AvailabilityPct float64             `json:"availability_pct"`
+}
+
+// CameraHealthReport summarises the health of the camera fleet over a window
+type CameraHealthReport struct {
+	From     time.Time                   `json:"from"`
+	To       time.Time                   `json:"to"`
+	Total    int                         `json:"total"`
+	ByStatus map[models.CameraStatus]int `json:"by_status"`
+	ByFault  map[string]int              `json:"by_fault"`
+	Firmware map[string]int              `json:"firmware"`
+	Cameras  []CameraHealth              `json:"cameras"`
+}
+
+// GetCameraHealthReport reports each camera's current status and faults with its incidents and
+// downtime since from, unhealthy cameras first. Time on standby does not count as downtime.
+func (s *VideoService) GetCameraHealthReport(ctx context.Context, from, to time.Time) (*CameraHealthReport, error) {
+	db := s.db.WithContext(ctx)
+	var cameras []models.Camera
+	if err := db.Order("serial_number").Find(&cameras).Error; err != nil {
+		return nil, err
+	}
+	var changes []models.CameraStatusChange
+	if err := db.Where("changed_at >= ? AND changed_at < ?", from, to).Order("changed_at, id").Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	byCamera := make(map[uint][]models.CameraStatusChange)
+	for _, change := range changes {
+		byCamera[change.CameraID] = append(byCamera[change.CameraID], change)
+	}
+
+	report := &CameraHealthReport{
+		From:     from,
+		To:       to,
+		Total:    len(cameras),
+		ByStatus: make(map[models.CameraStatus]int),
+		ByFault:  make(map[string]int),
+		Firmware: make(map[string]int),
+		Cameras:  make([]CameraHealth, 0, len(cameras)),
+	}
+	for _, camera := range cameras {
+		report.ByStatus[camera.Status]++
+		for _, fault := range camera.Faults {
+			report.ByFault[fault]++
+		}
+		if camera.FirmwareVer != "" {
+			report.Firmware[camera.FirmwareVer]++
+		}
+
+		health := CameraHealth{
+			CameraID:        camera.ID,
+			SerialNumber:    camera.SerialNumber,
+			VehicleID:       camera.VehicleID,
+			Status:          camera.Status,
+			StatusReason:    camera.StatusReason,
+			StatusChangedAt: camera.StatusChangedAt,
+			Faults:          camera.Faults,
+			FirmwareVer:     camera.FirmwareVer,
+			LastHeartbeat:   camera.LastHeartbeat,
+		}
+		// Walk the window from the status the camera had at its start
+		start := from
+		if camera.CreatedAt.After(start) {
+			start = camera.CreatedAt
+		}
+		status, since := camera.Status, start
+		if cameraChanges := byCamera[camera.ID]; len(cameraChanges) > 0 {
+			status = cameraChanges[0].FromStatus
+		}
+		var down time.Duration
+		for _, change := range byCamera[camera.ID] {
+			if cameraDown(status) && change.ChangedAt.After(since) {
+				down += change.ChangedAt.Sub(since)
+			}
+			if cameraDown(change.ToStatus) {
+				health.Incidents++
+			}
+			status = change.ToStatus
+			if change.ChangedAt.After(since) {
+				since = change.ChangedAt
+			}
+		}
+		if cameraDown(status) {
+			down += to.Sub(since)
+		}
+		health.DowntimeMinutes = int(down.Minutes())
+		health.AvailabilityPct = 100
+		if window := to.Sub(start); window > 0 && down > 0 {
+			health.AvailabilityPct = roundTo(100*(1-down.Seconds()/window.Seconds()), 1)
+		}
+		report.Cameras = append(report.Cameras, health)
+	}
+	sort.SliceStable(report.Cameras, func(i, j int) bool {
+		return cameraDown(report.Cameras[i].Status) && !cameraDown(report.Cameras[j].Status)
+	})
+	return report, nil
+}
+
+func cameraDown(status models.CameraStatus) bool {
+	return status == models.CameraStatusOffline || status == models.CameraStatusMalfunction
+}
+
+// GetCameraStatusHistory lists a camera's status changes, newest first
+func (s *VideoService) GetCameraStatusHistory(ctx context.Context, cameraID uint, limit int) ([]models.CameraStatusChange, error) {
+	if err := s.db.WithContext(ctx).Select("id").First(&models.Camera{}, cameraID).Error; err != nil {
+		return nil, err
+	}
+	var changes []models.CameraStatusChange
+	err := s.db.WithContext(ctx).Where("camera_id = ?", cameraID).Order("changed_at DESC, id DESC").Limit(limit).Find(&changes).Error
+	return changes, err
+}
@@ -0,0 +1,451 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidCoaching is returned for coaching requests that are malformed or name the wrong people
+	ErrInvalidCoaching = errors.New("invalid coaching request")
+	// ErrCoachingConflict is returned when a session is not in a state that allows the change
+	ErrCoachingConflict = errors.New("coaching session conflict")
+	// ErrCoachingForbidden is returned when a driver acts on another driver's session
+	ErrCoachingForbidden = errors.New("coaching session belongs to another driver")
+)
+
+// coachingEffectDays is the period either side of coaching whose scores are compared
+const coachingEffectDays = 30
+
+// CoachRoles are the roles that may coach drivers and work the coaching queue
+var CoachRoles = []models.Role{models.RoleAdmin, models.RoleOrgAdmin, models.RoleDispatcher}
+
+// CoachingRequest queues a safety event or a video clip for coaching
+type CoachingRequest struct {
+	SafetyEventID *uint
+	VideoClipID   *uint
+	DriverID      *uint // Overrides the driver the event or clip is attributed to
+	CoachID       *uint
+	DueAt         *time.Time
+	Note          string
+	AssignedByID  *uint
+}
+
+// CoachingFilter narrows the coaching queue; zero values are ignored
+type CoachingFilter struct {
+	DriverID   *uint
+	CoachID    *uint
+	Status     models.CoachingStatus
+	Unassigned bool
+}
+
+// CoachingEffectiveness sums up how the sessions matching a filter were resolved and how the
+// scores of coached drivers moved in the month after
+type CoachingEffectiveness struct {
+	Pending        int      `json:"pending"`
+	Coached        int      `json:"coached"`
+	Dismissed      int      `json:"dismissed"`
+	FalsePositives int      `json:"false_positives"`
+	Acknowledged   int      `json:"acknowledged"` // Coached sessions the driver has acknowledged
+	Measured       int      `json:"measured"`     // Coached sessions a month or more old, with scores either side
+	Improved       int      `json:"improved"`
+	Declined       int      `json:"declined"`
+	AverageChange  *float64 `json:"average_change,omitempty"` // Mean overall score change of the measured sessions
+}
+
+// CreateCoachingSession queues an event or clip for coaching, marking it viewed
+func (s *SafetyService) CreateCoachingSession(ctx context.Context, req CoachingRequest) (*models.CoachingSession, error) {
+	if (req.SafetyEventID == nil) == (req.VideoClipID == nil) {
+		return nil, fmt.Errorf("%w: give either a safety event or a video clip", ErrInvalidCoaching)
+	}
+	db := s.db.WithContext(ctx)
+
+	session := models.CoachingSession{
+		SafetyEventID: req.SafetyEventID,
+		VideoClipID:   req.VideoClipID,
+		Status:        models.CoachingStatusPending,
+		DueAt:         req.DueAt,
+	}
+	var driverID *uint
+	var item interface{}
+	if req.SafetyEventID != nil {
+		var event models.SafetyEvent
+		if err := db.First(&event, *req.SafetyEventID).Error; err != nil {
+			return nil, err
+		}
+		driverID, item = event.DriverID, &event
+	} else {
+		var clip models.VideoClip
+		if err := db.First(&clip, *req.VideoClipID).Error; err != nil {
+			return nil, err
+		}
+		driverID, item = clip.DriverID, &clip
+		if driverID == nil && clip.VehicleID != nil {
+			_, attributed, err := vehicleAttribution(db, *clip.VehicleID, clip.StartTime)
+			if err != nil {
+				return nil, err
+			}
+			driverID = attributed
+		}
+	}
+	if req.DriverID != nil {
+		driverID = req.DriverID
+	}
+	if driverID == nil {
+		return nil, fmt.Errorf("%w: no driver is attributed, give driver_id", ErrInvalidCoaching)
+	}
+	var drivers int64
+	if err := db.Model(&models.Driver{}).Where("id = ?", *driverID).Count(&drivers).Error; err != nil {
+		return nil, err
+	}
+	if drivers == 0 {
+		return nil, fmt.Errorf("%w: driver %d not found", ErrInvalidCoaching, *driverID)
+	}
+	session.DriverID = *driverID
+
+	var existing int64
+	query := db.Model(&models.CoachingSession{})
+	if req.SafetyEventID != nil {
+		query = query.Where("safety_event_id = ?", *req.SafetyEventID)
+	} else {
+		query = query.Where("video_clip_id = ?", *req.VideoClipID)
+	}
+	if err := query.Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, fmt.Errorf("%w: already queued for coaching", ErrCoachingConflict)
+	}
+
+	if req.CoachID != nil {
+		if err := s.validateCoach(ctx, *req.CoachID); err != nil {
+			return nil, err
+		}
+		session.CoachID = req.CoachID
+		session.AssignedByID = req.AssignedByID
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Driver", "Coach", "SafetyEvent", "VideoClip", "Notes").Create(&session).Error; err != nil {
+			return err
+		}
+		if note := strings.TrimSpace(req.Note); note != "" {
+			if err := tx.Create(&models.CoachingNote{SessionID: session.ID, AuthorID: req.AssignedByID, Body: note}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(item).Update("is_viewed", true).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCoachingSession(ctx, session.ID)
+}
+
+// validateCoach checks that a user account exists, is active and may coach
+func (s *SafetyService) validateCoach(ctx context.Context, coachID uint) error {
+	var users []models.UserAccount
+	if err := s.db.WithContext(ctx).Where("id = ?", coachID).Limit(1).Find(&users).Error; err != nil {
+		return err
+	}
+	if len(users) == 0 || !users[0].IsActive {
+		return fmt.Errorf("%w: coach %d not found", ErrInvalidCoaching, coachID)
+	}
+	for _, role := range CoachRoles {
+		if users[0].Role == role {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: a %s cannot coach", ErrInvalidCoaching, users[0].Role)
+}
+
+// GetCoachingSession returns a session with its event or clip, coach and notes
+func (s *SafetyService) GetCoachingSession(ctx context.Context, id uint) (*models.CoachingSession, error) {
+	var session models.CoachingSession
+	if err := s.db.WithContext(ctx).Preload("Driver").Preload("Coach").Preload("SafetyEvent").Preload("VideoClip").
+		Preload("Notes", func(db *gorm.DB) *gorm.DB { return db.Order("created_at, id") }).
+		First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetCoachingQueue lists coaching sessions, oldest due first within pending work
+func (s *SafetyService) GetCoachingQueue(ctx context.Context, filter CoachingFilter, page, limit int) ([]models.CoachingSession, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.CoachingSession{})
+	if filter.DriverID != nil {
+		query = query.Where("driver_id = ?", *filter.DriverID)
+	}
+	if filter.CoachID != nil {
+		query = query.Where("coach_id = ?", *filter.CoachID)
+	}
+	if filter.Unassigned {
+		query = query.Where("coach_id IS NULL")
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var sessions []models.CoachingSession
+	err := query.Preload("Driver").Preload("Coach").Preload("SafetyEvent").Preload("VideoClip").
+		Order("CASE WHEN status = 'PENDING' THEN 0 ELSE 1 END, due_at IS NULL, due_at, created_at DESC").
+		Offset((page - 1) * limit).Limit(limit).Find(&sessions).Error
+	return sessions, total, err
+}
+
+// pendingCoachingSession loads a session that must still be waiting for its coach
+func (s *SafetyService) pendingCoachingSession(ctx context.Context, id uint) (*models.CoachingSession, error) {
+	var session models.CoachingSession
+	if err := s.db.WithContext(ctx).First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	if session.Status != models.CoachingStatusPending {
+		return nil, fmt.Errorf("%w: session is already %s", ErrCoachingConflict, session.Status)
+	}
+	return &session, nil
+}
+
+// AssignCoach hands a pending session to a coach
+func (s *SafetyService) AssignCoach(ctx context.Context, id, coachID uint, dueAt *time.Time, assignedByID *uint) (*models.CoachingSession, error) {
+	session, err := s.pendingCoachingSession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateCoach(ctx, coachID); err != nil {
+		return nil, err
+	}
+	updates := map[string]interface{}{"coach_id": coachID, "assigned_by_id": assignedByID}
+	if dueAt != nil {
+		updates["due_at"] = *dueAt
+	}
+	if err := s.db.WithContext(ctx).Model(session).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return s.GetCoachingSession(ctx, id)
+}
+
+// AddCoachingNote attaches a note to a session
+func (s *SafetyService) AddCoachingNote(ctx context.Context, id uint, authorID *uint, body string) (*models.CoachingNote, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, fmt.Errorf("%w: the note is empty", ErrInvalidCoaching)
+	}
+	var session models.CoachingSession
+	if err := s.db.WithContext(ctx).First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	note := models.CoachingNote{SessionID: id, AuthorID: authorID, Body: body}
+	if err := s.db.WithContext(ctx).Create(&note).Error; err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// ResolveCoachingSession closes a pending session. Coaching records the driver's score over the
+// month before, to compare with the month after; a false positive stops the event counting
+// against the driver from the next score recalculation.
+func (s *SafetyService) ResolveCoachingSession(ctx context.Context, id uint, status models.CoachingStatus, note string, resolvedByID *uint, now time.Time) (*models.CoachingSession, error) {
+	switch status {
+	case models.CoachingStatusCoached, models.CoachingStatusDismissed, models.CoachingStatusFalsePositive:
+	default:
+		return nil, fmt.Errorf("%w: cannot resolve a session as %q", ErrInvalidCoaching, status)
+	}
+	session, err := s.pendingCoachingSession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{"status": status, "resolved_at": now, "resolved_by_id": resolvedByID}
+	if status == models.CoachingStatusCoached {
+		before, err := s.scoreDriverPeriod(ctx, session.DriverID, now.AddDate(0, 0, -coachingEffectDays), now)
+		if err != nil {
+			return nil, err
+		}
+		updates["score_before"] = before.OverallScore
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Only one of two coaches resolving the same session at once gets to close it
+		result := tx.Model(&models.CoachingSession{}).
+			Where("id = ? AND status = ?", id, models.CoachingStatusPending).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: session was resolved meanwhile", ErrCoachingConflict)
+		}
+		if note = strings.TrimSpace(note); note != "" {
+			return tx.Create(&models.CoachingNote{SessionID: id, AuthorID: resolvedByID, Body: note}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCoachingSession(ctx, id)
+}
+
+// AcknowledgeCoaching records the driver confirming, from the app, that they were coached
+func (s *SafetyService) AcknowledgeCoaching(ctx context.Context, id, driverID uint, comment string, now time.Time) (*models.CoachingSession, error) {
+	var session models.CoachingSession
+	if err := s.db.WithContext(ctx).First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	if session.DriverID != driverID {
+		return nil, ErrCoachingForbidden
+	}
+	if session.Status != models.CoachingStatusCoached {
+		return nil, fmt.Errorf("%w: only coached sessions can be acknowledged, this one is %s", ErrCoachingConflict, session.Status)
+	}
+	if session.DriverAcknowledgedAt != nil {
+		return nil, fmt.Errorf("%w: already acknowledged", ErrCoachingConflict)
+	}
+	if err := s.db.WithContext(ctx).Model(&session).Updates(map[string]interface{}{
+		"driver_acknowledged_at": now,
+		"driver_comment":         strings.TrimSpace(comment),
+	}).Error; err != nil {
+		return nil, err
+	}
+	return s.GetCoachingSession(ctx, id)
+}
+
+// MeasureCoachingEffectiveness scores the month after each coached session once it has passed,
+// recording the change from the month before
+func (s *SafetyService) MeasureCoachingEffectiveness(ctx context.Context, now time.Time) (int, error) {
+	var sessions []models.CoachingSession
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND effect_measured_at IS NULL AND resolved_at <= ?", models.CoachingStatusCoached, now.AddDate(0, 0, -coachingEffectDays)).
+		Find(&sessions).Error; err != nil {
+		return 0, err
+	}
+	for _, session := range sessions {
+		coachedAt := *session.ResolvedAt
+		before := session.ScoreBefore
+		if before == nil {
+			score, err := s.scoreDriverPeriod(ctx, session.DriverID, coachedAt.AddDate(0, 0, -coachingEffectDays), coachedAt)
+			if err != nil {
+				return 0, err
+			}
+			before = &score.OverallScore
+		}
+		after, err := s.scoreDriverPeriod(ctx, session.DriverID, coachedAt, coachedAt.AddDate(0, 0, coachingEffectDays))
+		if err != nil {
+			return 0, err
+		}
+		if err := s.db.WithContext(ctx).Model(&session).Updates(map[string]interface{}{
+			"score_before":       *before,
+			"score_after":        after.OverallScore,
+			"score_change":       after.OverallScore - *before,
+			"effect_measured_at": now,
+		}).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(sessions), nil
+}
+
+// GetCoachingEffectiveness summarises the sessions matching the filter. Outcomes appear once the
+// background job has measured them.
+func (s *SafetyService) GetCoachingEffectiveness(ctx context.Context, filter CoachingFilter) (*CoachingEffectiveness, error) {
+	query := s.db.WithContext(ctx).Model(&models.CoachingSession{})
+	if filter.DriverID != nil {
+		query = query.Where("driver_id = ?", *filter.DriverID)
+	}
+	if filter.CoachID != nil {
+		query = query.Where("coach_id = ?", *filter.CoachID)
+	}
+	var sessions []models.CoachingSession
+	if err := query.Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	summary := &CoachingEffectiveness{}
+	var changes int
+	for _, session := range sessions {
+		switch session.Status {
+		case models.CoachingStatusPending:
+			summary.Pending++
+		case models.CoachingStatusDismissed:
+			summary.Dismissed++
+		case models.CoachingStatusFalsePositive:
+			summary.FalsePositives++
+		case models.CoachingStatusCoached:
+			summary.Coached++
+			if session.DriverAcknowledgedAt != nil {
+				summary.Acknowledged++
+			}
+			if session.ScoreChange == nil {
+				continue
+			}
+			summary.Measured++
+			changes += *session.ScoreChange
+			switch {
+			case *session.ScoreChange > 0:
+				summary.Improved++
+			case *session.ScoreChange < 0:
+				summary.Declined++
+			}
+		}
+	}
+	if summary.Measured > 0 {
+		average := math.Round(float64(changes)/float64(summary.Measured)*10) / 10
+		summary.AverageChange = &average
+	}
+	return summary, nil
+}
+
+// scoreDriverPeriod scores one driver's driving between two moments, outside the rolling windows
+func (s *SafetyService) scoreDriverPeriod(ctx context.Context, driverID uint, from, to time.Time) (models.DriverScore, error) {
+	var trips []models.Trip
+	if err := s.db.WithContext(ctx).Where("driver_id = ? AND vehicle_id IS NOT NULL AND actual_pickup_time IS NOT NULL", driverID).
+		Where("actual_pickup_time < ? AND (actual_arrival IS NULL OR actual_arrival > ?)", to, from).
+		Where("status <> ?", models.TripStatusCancelled).
+		Find(&trips).Error; err != nil {
+		return models.DriverScore{}, err
+	}
+	var events []models.SafetyEvent
+	if err := s.scoredEvents(ctx).Where("driver_id = ? AND timestamp >= ? AND timestamp < ?", driverID, from, to).
+		Find(&events).Error; err != nil {
+		return models.DriverScore{}, err
+	}
+	km, hours, err := s.driverExposure(ctx, driverID, trips, from, to)
+	if err != nil {
+		return models.DriverScore{}, err
+	}
+	score := scoreDriver(events, km, hours)
+	score.DriverID = driverID
+	score.PeriodStart = from
+	score.PeriodEnd = to
+	return score, nil
+}
+
+// scoredEvents queries the safety events that count towards scores: all but false positives
+func (s *SafetyService) scoredEvents(ctx context.Context) *gorm.DB {
+	falsePositives := s.db.Model(&models.CoachingSession{}).Select("safety_event_id").
+		Where("status = ? AND safety_event_id IS NOT NULL", models.CoachingStatusFalsePositive)
+	return s.db.WithContext(ctx).Where("id NOT IN (?)", falsePositives)
+}
+
+// measureCoaching measures coaching effectiveness on the score recalculation schedule
+func (s *SafetyService) measureCoaching() {
+	measured, err := s.MeasureCoachingEffectiveness(context.Background(), time.Now())
+	if err != nil {
+		log.Printf("❌ Coaching effectiveness measurement failed: %v", err)
+		return
+	}
+	if measured > 0 {
+		log.Printf("🛡️ Measured the effect of %d coaching session(s)", measured)
+	}
+}
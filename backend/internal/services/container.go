@@ -1,6 +1,8 @@
 package services
 
 import (
+	"log"
+
 	"github.com/fleetflow/backend/internal/config"
 	"github.com/fleetflow/backend/internal/repositories"
 	"googlemaps.github.io/maps"
@@ -34,6 +36,8 @@ type Container struct {
 	AnalyticsService    *AnalyticsService
 	NotificationService *NotificationService
 	AuditService        *AuditService
+	RiskService         *RiskService
+	SSOService          *SSOService
 
 	// External services
 	SMSService        SMSProvider
@@ -44,10 +48,14 @@ type Container struct {
 	MQTTService       *MQTTService
 	IngestionService  *IngestionService
 	TelemetryService  *TelemetryService
+	DeviceGateway     *DeviceGatewayService
 	NavigationService *NavigationService
 	AssetService      *AssetService
 	VideoService      *VideoService
 	SafetyService     *SafetyService
+	SpeedLimitService *SpeedLimitService
+
+	MaintenanceService *MaintenanceService
 }
 
 // NewContainer creates a new service container with all dependencies
@@ -65,13 +73,14 @@ func NewContainer(db *gorm.DB, cfg *config.Config) *Container {
 	container.UploadRepo = repositories.NewPostgresUploadRepository(db)
 
 	// Initialize core services
-	container.JWTService = NewJWTService(cfg, db)
 	container.AuditService = NewAuditService(db)
+	container.JWTService = NewJWTService(cfg, db, container.AuditService)
 
 	// Create AuthService and others with Repository
 	container.AuthService = NewAuthService(container.AuthRepo, cfg, container.AuditService)
-	// Re-inject AuthService into JWTService if needed (assuming JWTService has a field for it, though NewJWTService doesn't take it currently)
-	// container.JWTService.authService = container.AuthService
+	// Session idle timeout follows the security policy managed through AuthService
+	container.JWTService.SetSecuritySettingsSource(container.AuthService.GetSecuritySettings)
+	container.RiskService = NewRiskService(db, container.AuditService, container.AuthService.GetSecuritySettings)
 
 	container.DriverService = NewDriverService(container.DriverRepo, container.AuditService)
 	container.VehicleService = NewVehicleService(container.VehicleRepo, container.AuditService)
@@ -84,6 +93,12 @@ func NewContainer(db *gorm.DB, cfg *config.Config) *Container {
 	container.UploadService = NewUploadService(db, cfg, container.AuditService)
 	container.AnalyticsService = NewAnalyticsService(db)
 	container.NotificationService = NewNotificationService(cfg)
+	container.FuelService.SetNotificationService(container.NotificationService)
+	oidcClient := NewOIDCClient(nil)
+	if cfg.OIDCAllowPrivateIssuers && !cfg.IsProduction() {
+		oidcClient.AllowPrivateIssuers()
+	}
+	container.SSOService = NewSSOService(db, oidcClient, container.AuditService)
 
 	// Initialize external services
 	if cfg.IsDevelopment() {
@@ -94,6 +109,24 @@ func NewContainer(db *gorm.DB, cfg *config.Config) *Container {
 		container.StorageService = NewS3StorageService(cfg)
 	}
 
+	// Audit chain checkpoints are signed with a dedicated key when one is configured
+	auditSigningKey := cfg.AuditSigningKey
+	if auditSigningKey == "" {
+		auditSigningKey = cfg.JWTSecret
+	}
+	container.AuditService.SetCheckpointSigningKey(auditSigningKey)
+	container.AuditService.SetArchiveStorage(container.StorageService)
+
+	// Login positions for impossible travel scoring are resolved from the client IP
+	if cfg.GeoIPDatabasePath != "" {
+		locator, err := LoadIPRangeLocator(cfg.GeoIPDatabasePath)
+		if err != nil {
+			log.Printf("⚠️ GeoIP table %s not loaded, impossible travel detection disabled: %v", cfg.GeoIPDatabasePath, err)
+		} else {
+			container.RiskService.SetGeoIPLocator(locator)
+		}
+	}
+
 	// Initialize Google Maps Client
 	if cfg.GoogleMapsAPIKey != "" {
 		mapsClient, err := maps.NewClient(maps.WithAPIKey(cfg.GoogleMapsAPIKey))
@@ -131,10 +164,36 @@ func NewContainer(db *gorm.DB, cfg *config.Config) *Container {
 	container.AssetService = NewAssetService(db)
 
 	// Initialize Video service
-	container.VideoService = NewVideoService(db)
+	container.VideoService = NewVideoService(db, container.StorageService, container.MQTTService)
+	videoURLSecret := cfg.VideoURLSecret
+	if videoURLSecret == "" {
+		videoURLSecret = cfg.JWTSecret
+	}
+	container.VideoService.SetURLSigningKey(videoURLSecret, cfg.VideoURLExpiry)
+	container.VideoService.SetHeartbeatTimeout(cfg.CameraHeartbeatTimeout)
 
 	// Initialize Safety service (connects to core)
 	container.SafetyService = NewSafetyService(db, container.MQTTService)
+	container.SafetyService.SetVideoService(container.VideoService)
+
+	// Speed limits from imported road data, vehicle type overrides and geofence caps
+	container.SpeedLimitService = NewSpeedLimitService(db)
+	container.SafetyService.SetSpeedLimitService(container.SpeedLimitService)
+	container.LocationService.SetSpeedLimitService(container.SpeedLimitService)
+
+	// Initialize Maintenance service (publishes due events over MQTT)
+	container.MaintenanceService = NewMaintenanceService(db, container.MQTTService)
+	container.MaintenanceService.SetNotificationService(container.NotificationService)
+	container.VehicleService.SetMaintenanceService(container.MaintenanceService)
+	container.TripService.SetMaintenanceService(container.MaintenanceService)
+
+	// Fault codes are routed to maintenance managers and drivers by severity
+	container.TelemetryService.SetMaintenanceService(container.MaintenanceService)
+	container.TelemetryService.SetNotificationService(container.NotificationService)
+	container.TelemetryService.SetDTCClearAfterReports(cfg.DTCClearAfterReports)
+
+	// Initialize tracker gateway (decodes device protocols into location and telemetry updates)
+	container.DeviceGateway = NewDeviceGatewayService(db, container.MQTTService, container.TelemetryService)
 
 	return container
 }
@@ -199,6 +258,11 @@ func (c *Container) Close() error {
 		c.MQTTService.Disconnect()
 	}
 
+	// Close tracker listeners
+	if c.DeviceGateway != nil {
+		c.DeviceGateway.Stop()
+	}
+
 	// Close Ingestion service
 	if c.IngestionService != nil {
 		c.IngestionService.Stop()
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GT06 protocol numbers
+const (
+	gt06Login       = 0x01
+	gt06Location    = 0x12
+	gt06Heartbeat   = 0x13
+	gt06Alarm       = 0x16
+	gt06LocationExt = 0x22 // Concox location with ACC state and mileage
+)
+
+// gt06Decoder decodes the GT06 binary protocol spoken by Concox and many compatible trackers.
+// Frames start 0x7878 with a one byte length (0x7979 with two), carry a protocol number,
+// content, a serial number and a CRC-16/X-25, and end 0x0D0A.
+type gt06Decoder struct{}
+
+// Split frames the stream, skipping bytes before a start marker
+func (gt06Decoder) Split(data []byte, atEOF bool) (int, []byte, error) {
+	start := bytes.Index(data, []byte{0x78, 0x78})
+	if long := bytes.Index(data, []byte{0x79, 0x79}); long >= 0 && (start < 0 || long < start) {
+		start = long
+	}
+	if start < 0 {
+		if len(data) > 1 {
+			return len(data) - 1, nil, nil
+		}
+		return 0, nil, nil
+	}
+	// Noise ahead of the marker is skipped along with the frame; a scanner only asks again once it has read more
+	frame := data[start:]
+	var size int
+	if frame[0] == 0x78 {
+		if len(frame) < 3 {
+			return start, nil, nil
+		}
+		size = 3 + int(frame[2]) + 2
+	} else {
+		if len(frame) < 4 {
+			return start, nil, nil
+		}
+		size = 4 + int(binary.BigEndian.Uint16(frame[2:4])) + 2
+	}
+	if len(frame) < size {
+		return start, nil, nil
+	}
+	if frame[size-2] != 0x0D || frame[size-1] != 0x0A {
+		return 0, nil, fmt.Errorf("%w: GT06 frame without stop bits", ErrMalformedFrame)
+	}
+	return start + size, frame[:size], nil
+}
+
+// Decode decodes a login, location, alarm or heartbeat frame
+func (gt06Decoder) Decode(frame []byte, session *DeviceSession) (*DecodedFrame, error) {
+	header := 3
+	if frame[0] == 0x79 {
+		header = 4
+	}
+	if len(frame) < header+1+2+2+2 {
+		return nil, fmt.Errorf("%w: GT06 frame of %d bytes", ErrMalformedFrame, len(frame))
+	}
+	checked := frame[2 : len(frame)-4]
+	if want, got := binary.BigEndian.Uint16(frame[len(frame)-4:]), crc16ITU(checked); want != got {
+		return nil, fmt.Errorf("%w: GT06 CRC %04X, expected %04X", ErrMalformedFrame, got, want)
+	}
+	protocol := frame[header]
+	content := frame[header+1 : len(frame)-6]
+	serial := frame[len(frame)-6 : len(frame)-4]
+
+	switch protocol {
+	case gt06Login:
+		if len(content) < 8 {
+			return nil, fmt.Errorf("%w: GT06 login of %d bytes", ErrMalformedFrame, len(content))
+		}
+		imei := strings.TrimLeft(fmt.Sprintf("%X", content[:8]), "0")
+		if !validIMEI(imei) {
+			return nil, fmt.Errorf("%w: GT06 IMEI %q", ErrMalformedFrame, imei)
+		}
+		return &DecodedFrame{IMEI: imei, Login: true, Ack: gt06Response(protocol, serial)}, nil
+
+	case gt06Location, gt06LocationExt, gt06Alarm:
+		record, err := decodeGT06Position(content)
+		if err != nil {
+			return nil, err
+		}
+		// Concox extended locations follow the cell tower with ACC, upload mode, re-upload flag and mileage
+		if protocol == gt06LocationExt && len(content) >= 27 {
+			on := content[26] != 0
+			record.Ignition = &on
+			if len(content) >= 33 {
+				record.Odometer = floatValue(float64(binary.BigEndian.Uint32(content[29:33])) / 1000)
+			}
+		}
+		decoded := &DecodedFrame{Records: []DeviceRecord{*record}}
+		if protocol == gt06Alarm {
+			decoded.Ack = gt06Response(protocol, serial)
+		}
+		return decoded, nil
+
+	case gt06Heartbeat:
+		if len(content) < 1 {
+			return nil, fmt.Errorf("%w: empty GT06 heartbeat", ErrMalformedFrame)
+		}
+		on := content[0]&0x02 != 0
+		return &DecodedFrame{
+			Records: []DeviceRecord{{Timestamp: time.Now().UTC(), Ignition: &on}},
+			Ack:     gt06Response(protocol, serial),
+		}, nil
+	}
+
+	// Other messages (status, commands, LBS-only) carry nothing we map
+	return &DecodedFrame{}, nil
+}
+
+// decodeGT06Position decodes the date, GPS and course/status block shared by location and alarm frames
+func decodeGT06Position(content []byte) (*DeviceRecord, error) {
+	r := &frameReader{data: content}
+	date := r.next(6)
+	gps := r.u8()
+	latitude := float64(r.u32()) / 1800000
+	longitude := float64(r.u32()) / 1800000
+	speed := r.u8()
+	courseStatus := r.u16()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	record := &DeviceRecord{
+		Timestamp: time.Date(2000+int(date[0]), time.Month(date[1]), int(date[2]),
+			int(date[3]), int(date[4]), int(date[5]), 0, time.UTC),
+		Satellites:  int(gps & 0x0F),
+		Speed:       float64(speed),
+		Heading:     float64(courseStatus & 0x03FF),
+		HasPosition: courseStatus&0x1000 != 0,
+		Latitude:    latitude,
+		Longitude:   longitude,
+	}
+	if courseStatus&0x0400 == 0 {
+		record.Latitude = -latitude
+	}
+	if courseStatus&0x0800 != 0 {
+		record.Longitude = -longitude
+	}
+	return record, nil
+}
+
+// gt06Response is the acknowledgement a GT06 device expects for a protocol number and serial
+func gt06Response(protocol byte, serial []byte) []byte {
+	body := []byte{0x05, protocol, serial[0], serial[1]}
+	crc := crc16ITU(body)
+	response := append([]byte{0x78, 0x78}, body...)
+	return append(response, byte(crc>>8), byte(crc), 0x0D, 0x0A)
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrMalformedFrame is returned for tracker payloads that cannot be decoded
+var ErrMalformedFrame = errors.New("malformed tracker frame")
+
+// maxDeviceFrame bounds a single tracker frame so a corrupt length cannot exhaust memory
+const maxDeviceFrame = 64 * 1024
+
+// DeviceRecord is one position and sensor snapshot decoded from a tracker, in our units
+type DeviceRecord struct {
+	Timestamp   time.Time `json:"timestamp"`
+	HasPosition bool      `json:"has_position"` // False for records without a GPS fix
+	Latitude    float64   `json:"latitude"`
+	Longitude   float64   `json:"longitude"`
+	Altitude    float64   `json:"altitude"`   // Metres
+	Speed       float64   `json:"speed"`      // GPS km/h
+	Heading     float64   `json:"heading"`    // Degrees
+	Satellites  int       `json:"satellites"` // In use
+	Accuracy    float64   `json:"accuracy"`   // Metres, or the device's HDOP when it reports no estimate
+
+	Ignition       *bool    `json:"ignition,omitempty"`
+	EngineRPM      *int     `json:"engine_rpm,omitempty"`
+	VehicleSpeed   *float64 `json:"vehicle_speed,omitempty"` // From the CAN bus or OBD port
+	CoolantTemp    *float64 `json:"coolant_temp,omitempty"`
+	EngineLoad     *float64 `json:"engine_load,omitempty"`
+	ThrottlePos    *float64 `json:"throttle_pos,omitempty"`
+	FuelLevel      *float64 `json:"fuel_level,omitempty"`
+	BatteryVoltage *float64 `json:"battery_voltage,omitempty"` // Vehicle supply
+	Odometer       *float64 `json:"odometer,omitempty"`        // km
+	EngineHours    *float64 `json:"engine_hours,omitempty"`
+	FuelUsed       *float64 `json:"fuel_used,omitempty"` // Litres
+
+	IO map[int]int64 `json:"io,omitempty"` // Raw I/O elements by the vendor's IDs
+}
+
+// hasTelemetry reports whether a record carries any engine or vehicle data
+func (r *DeviceRecord) hasTelemetry() bool {
+	return r.EngineRPM != nil || r.VehicleSpeed != nil || r.CoolantTemp != nil || r.EngineLoad != nil ||
+		r.ThrottlePos != nil || r.FuelLevel != nil || r.BatteryVoltage != nil || r.Odometer != nil ||
+		r.EngineHours != nil || r.FuelUsed != nil
+}
+
+// DecodedFrame is what a tracker sent in one frame and what to answer it with
+type DecodedFrame struct {
+	IMEI    string         `json:"imei,omitempty"` // Set by frames that identify the device
+	Login   bool           `json:"login"`          // The frame only identifies the device
+	Records []DeviceRecord `json:"records"`
+	Ack     []byte         `json:"-"` // Reply when the frame is accepted
+	Reject  []byte         `json:"-"` // Reply when the device is not registered; nil closes the connection silently
+}
+
+// DeviceSession is what a connection has learned about its device
+type DeviceSession struct {
+	IMEI string
+}
+
+// DeviceDecoder decodes one tracker protocol
+type DeviceDecoder interface {
+	// Split frames a TCP stream, in the manner of bufio.SplitFunc
+	Split(data []byte, atEOF bool) (advance int, token []byte, err error)
+	// Decode decodes a frame produced by Split
+	Decode(frame []byte, session *DeviceSession) (*DecodedFrame, error)
+}
+
+// DatagramDecoder is implemented by protocols that also report over UDP
+type DatagramDecoder interface {
+	// DecodeDatagram decodes a UDP packet, which carries its own IMEI
+	DecodeDatagram(packet []byte) (*DecodedFrame, error)
+}
+
+// Tracker protocols
+const (
+	DeviceProtocolTeltonika = "teltonika"
+	DeviceProtocolGT06      = "gt06"
+	DeviceProtocolQueclink  = "queclink"
+)
+
+var (
+	deviceDecodersMu sync.RWMutex
+	deviceDecoders   = map[string]DeviceDecoder{
+		DeviceProtocolTeltonika: teltonikaDecoder{},
+		DeviceProtocolGT06:      gt06Decoder{},
+		DeviceProtocolQueclink:  queclinkDecoder{},
+	}
+)
+
+// RegisterDeviceDecoder adds or replaces the decoder for a protocol
+func RegisterDeviceDecoder(protocol string, decoder DeviceDecoder) {
+	deviceDecodersMu.Lock()
+	defer deviceDecodersMu.Unlock()
+	deviceDecoders[strings.ToLower(protocol)] = decoder
+}
+
+// DeviceDecoderFor returns the decoder for a protocol
+func DeviceDecoderFor(protocol string) (DeviceDecoder, bool) {
+	deviceDecodersMu.RLock()
+	defer deviceDecodersMu.RUnlock()
+	decoder, ok := deviceDecoders[strings.ToLower(protocol)]
+	return decoder, ok
+}
+
+// DeviceProtocols lists the protocols with a decoder
+func DeviceProtocols() []string {
+	deviceDecodersMu.RLock()
+	defer deviceDecodersMu.RUnlock()
+	protocols := make([]string, 0, len(deviceDecoders))
+	for protocol := range deviceDecoders {
+		protocols = append(protocols, protocol)
+	}
+	sort.Strings(protocols)
+	return protocols
+}
+
+// frameReader reads big-endian fields from a frame, remembering the first overrun
+type frameReader struct {
+	data []byte
+	off  int
+	err  error
+}
+
+func (r *frameReader) next(n int) []byte {
+	if r.err != nil {
+		return make([]byte, n)
+	}
+	if n < 0 || r.off+n > len(r.data) {
+		r.err = fmt.Errorf("%w: truncated at byte %d", ErrMalformedFrame, r.off)
+		return make([]byte, n)
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *frameReader) u8() uint8   { return r.next(1)[0] }
+func (r *frameReader) u16() uint16 { return binary.BigEndian.Uint16(r.next(2)) }
+func (r *frameReader) u32() uint32 { return binary.BigEndian.Uint32(r.next(4)) }
+func (r *frameReader) u64() uint64 { return binary.BigEndian.Uint64(r.next(8)) }
+
+// crc16IBM is the CRC-16/IBM (ARC) checksum Teltonika frames carry
+func crc16IBM(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// crc16ITU is the CRC-16/X-25 checksum GT06 frames carry
+func crc16ITU(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return ^crc
+}
+
+func floatValue(v float64) *float64 { return &v }
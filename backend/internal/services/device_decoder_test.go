@@ -0,0 +1,188 @@
+package services
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadTrackerFixture reads a capture from testdata/tracker; .hex files are hex dumps
+func loadTrackerFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", "tracker", name))
+	require.NoError(t, err)
+	if !strings.HasSuffix(name, ".hex") {
+		return raw
+	}
+	payload, err := hex.DecodeString(strings.Join(strings.Fields(string(raw)), ""))
+	require.NoError(t, err)
+	return payload
+}
+
+// TestTeltonikaCodec8 decodes the Codec 8 example packet from the protocol documentation
+func TestTeltonikaCodec8(t *testing.T) {
+	frames, err := DecodeDeviceFrames(DeviceProtocolTeltonika, loadTrackerFixture(t, "teltonika_codec8.hex"), false)
+	require.NoError(t, err)
+	require.Len(t, frames, 1)
+	assert.Equal(t, []byte{0, 0, 0, 1}, frames[0].Ack)
+	require.Len(t, frames[0].Records, 1)
+
+	record := frames[0].Records[0]
+	assert.Equal(t, time.UnixMilli(1560161086000).UTC(), record.Timestamp)
+	assert.False(t, record.HasPosition)
+	assert.Equal(t, map[int]int64{21: 3, 1: 1, 66: 24079, 241: 24602, 78: 0}, record.IO)
+	require.NotNil(t, record.BatteryVoltage)
+	assert.InDelta(t, 24.079, *record.BatteryVoltage, 1e-9)
+}
+
+// TestTeltonikaCodec8EStream decodes an IMEI handshake followed by a Codec 8 Extended packet with OBD data
+func TestTeltonikaCodec8EStream(t *testing.T) {
+	frames, err := DecodeDeviceFrames(DeviceProtocolTeltonika, loadTrackerFixture(t, "teltonika_codec8e_stream.hex"), false)
+	require.NoError(t, err)
+	require.Len(t, frames, 2)
+
+	login := frames[0]
+	assert.True(t, login.Login)
+	assert.Equal(t, "356307042441013", login.IMEI)
+	assert.Equal(t, []byte{0x01}, login.Ack)
+	assert.Equal(t, []byte{0x00}, login.Reject)
+
+	assert.Equal(t, []byte{0, 0, 0, 2}, frames[1].Ack)
+	require.Len(t, frames[1].Records, 2)
+	moving, parked := frames[1].Records[0], frames[1].Records[1]
+
+	assert.Equal(t, time.UnixMilli(1760000000000).UTC(), moving.Timestamp)
+	assert.True(t, moving.HasPosition)
+	assert.InDelta(t, 18.5204, moving.Latitude, 1e-7)
+	assert.InDelta(t, 73.8567, moving.Longitude, 1e-7)
+	assert.Equal(t, 560.0, moving.Altitude)
+	assert.Equal(t, 270.0, moving.Heading)
+	assert.Equal(t, 11, moving.Satellites)
+	assert.Equal(t, 42.0, moving.Speed)
+	assert.InDelta(t, 0.9, moving.Accuracy, 1e-9) // HDOP from I/O 182
+	assert.Equal(t, int64(9), moving.IO[182])
+	require.NotNil(t, moving.Ignition)
+	assert.True(t, *moving.Ignition)
+	require.NotNil(t, moving.EngineRPM)
+	assert.Equal(t, 1450, *moving.EngineRPM)
+	assert.Equal(t, 92.0, *moving.CoolantTemp)
+	assert.Equal(t, 63.0, *moving.FuelLevel)
+	assert.InDelta(t, 27.65, *moving.BatteryVoltage, 1e-9)
+	assert.InDelta(t, 123456.789, *moving.Odometer, 1e-9)
+	assert.True(t, moving.hasTelemetry())
+
+	// No fix, ignition off and a sub-zero coolant reading
+	assert.False(t, parked.HasPosition)
+	assert.False(t, *parked.Ignition)
+	assert.Equal(t, -10.0, *parked.CoolantTemp)
+	assert.InDelta(t, 12.4, *parked.BatteryVoltage, 1e-9)
+
+	// A corrupted payload fails its CRC
+	corrupt := loadTrackerFixture(t, "teltonika_codec8e_stream.hex")
+	corrupt[40] ^= 0xFF
+	_, err = DecodeDeviceFrames(DeviceProtocolTeltonika, corrupt, false)
+	assert.ErrorIs(t, err, ErrMalformedFrame)
+}
+
+// TestTeltonikaUDP decodes the UDP example packet, which carries its IMEI and is acknowledged by packet ID
+func TestTeltonikaUDP(t *testing.T) {
+	frames, err := DecodeDeviceFrames(DeviceProtocolTeltonika, loadTrackerFixture(t, "teltonika_udp.hex"), true)
+	require.NoError(t, err)
+	require.Len(t, frames, 1)
+	assert.Equal(t, "352093086403655", frames[0].IMEI)
+	assert.Equal(t, []byte{0x00, 0x05, 0xCA, 0xFE, 0x01, 0x05, 0x01}, frames[0].Ack)
+	require.Len(t, frames[0].Records, 1)
+	record := frames[0].Records[0]
+	assert.Equal(t, map[int]int64{21: 3, 1: 1, 66: 0x5DBC}, record.IO)
+	assert.InDelta(t, 23.996, *record.BatteryVoltage, 1e-9)
+
+	// A truncated packet is rejected rather than read past its end
+	packet := loadTrackerFixture(t, "teltonika_udp.hex")
+	_, err = DecodeDeviceFrames(DeviceProtocolTeltonika, packet[:len(packet)-5], true)
+	assert.ErrorIs(t, err, ErrMalformedFrame)
+}
+
+// TestGT06 decodes a login, Concox and standard locations and a heartbeat, resynchronizing past leading noise
+func TestGT06(t *testing.T) {
+	frames, err := DecodeDeviceFrames(DeviceProtocolGT06, loadTrackerFixture(t, "gt06_stream.hex"), false)
+	require.NoError(t, err)
+	require.Len(t, frames, 4)
+
+	login := frames[0]
+	assert.True(t, login.Login)
+	assert.Equal(t, "123456789012345", login.IMEI)
+	assert.Equal(t, "787805010001D9DC0D0A", strings.ToUpper(hex.EncodeToString(login.Ack)))
+
+	// Locations are not acknowledged
+	concox := frames[1]
+	assert.Nil(t, concox.Ack)
+	require.Len(t, concox.Records, 1)
+	record := concox.Records[0]
+	assert.Equal(t, time.Date(2024, 3, 1, 6, 30, 15, 0, time.UTC), record.Timestamp)
+	assert.True(t, record.HasPosition)
+	assert.InDelta(t, 18.5204, record.Latitude, 1e-6)
+	assert.InDelta(t, 73.8567, record.Longitude, 1e-6)
+	assert.Equal(t, 9, record.Satellites)
+	assert.Equal(t, 38.0, record.Speed)
+	assert.Equal(t, 135.0, record.Heading)
+	require.NotNil(t, record.Ignition)
+	assert.True(t, *record.Ignition)
+	assert.InDelta(t, 45.678, *record.Odometer, 1e-9)
+
+	// Southern and western hemispheres, without a fix
+	standard := frames[2].Records[0]
+	assert.False(t, standard.HasPosition)
+	assert.InDelta(t, -33.8688, standard.Latitude, 1e-6)
+	assert.InDelta(t, -151.2093, standard.Longitude, 1e-6)
+	assert.Nil(t, standard.Ignition)
+
+	heartbeat := frames[3]
+	require.Len(t, heartbeat.Records, 1)
+	assert.True(t, *heartbeat.Records[0].Ignition)
+	assert.Equal(t, []byte{0x78, 0x78, 0x05, 0x13, 0x00, 0x04}, heartbeat.Ack[:6])
+
+	// A corrupted frame fails its CRC
+	corrupt := loadTrackerFixture(t, "gt06_login.hex")
+	corrupt[6] ^= 0x01
+	_, err = DecodeDeviceFrames(DeviceProtocolGT06, corrupt, false)
+	assert.ErrorIs(t, err, ErrMalformedFrame)
+}
+
+// TestQueclink decodes a fixed report, an ignition off report and a heartbeat
+func TestQueclink(t *testing.T) {
+	frames, err := DecodeDeviceFrames(DeviceProtocolQueclink, loadTrackerFixture(t, "queclink_gtfri.txt"), false)
+	require.NoError(t, err)
+	require.Len(t, frames, 3)
+
+	report := frames[0]
+	assert.Equal(t, "135790246811220", report.IMEI)
+	require.Len(t, report.Records, 1)
+	record := report.Records[0]
+	assert.True(t, record.HasPosition)
+	assert.Equal(t, time.Date(2009, 2, 14, 1, 32, 54, 0, time.UTC), record.Timestamp)
+	assert.InDelta(t, 31.222073, record.Latitude, 1e-9)
+	assert.InDelta(t, 121.354335, record.Longitude, 1e-9)
+	assert.Equal(t, 4.3, record.Speed)
+	assert.Equal(t, 92.0, record.Heading)
+	assert.Equal(t, 70.0, record.Altitude)
+	assert.Equal(t, 2000.0, *record.Odometer)
+
+	ignitionOff := frames[1].Records[0]
+	assert.False(t, *ignitionOff.Ignition)
+	assert.InDelta(t, 18.5204, ignitionOff.Latitude, 1e-9)
+	assert.InDelta(t, 12.5, *ignitionOff.EngineHours, 1e-9)
+	assert.Equal(t, 45678.9, *ignitionOff.Odometer)
+
+	heartbeat := frames[2]
+	assert.True(t, heartbeat.Login)
+	assert.Equal(t, "+SACK:GTHBD,060228,0A1C$", string(heartbeat.Ack))
+
+	_, err = DecodeDeviceFrames(DeviceProtocolQueclink, []byte("+RESP:GTFRI,02010B,not-an-imei$"), false)
+	assert.ErrorIs(t, err, ErrMalformedFrame)
+}
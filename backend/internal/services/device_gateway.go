@@ -0,0 +1,489 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidTrackerDevice is returned for tracker registrations that cannot be saved
+var ErrInvalidTrackerDevice = errors.New("invalid tracker device")
+
+// deviceIdleTimeout closes tracker connections that have gone quiet
+const deviceIdleTimeout = 15 * time.Minute
+
+// DeviceGatewayService accepts binary and ASCII tracker protocols on TCP and UDP listeners and
+// relayed over MQTT, maps each device's IMEI to its vehicle and feeds the decoded positions and
+// sensor data into the location and telemetry pipelines
+type DeviceGatewayService struct {
+	db          *gorm.DB
+	mqttService *MQTTService
+	telemetry   *TelemetryService
+
+	mu        sync.Mutex
+	listeners []io.Closer
+}
+
+// NewDeviceGatewayService creates a new tracker gateway
+func NewDeviceGatewayService(db *gorm.DB, mqttService *MQTTService, telemetry *TelemetryService) *DeviceGatewayService {
+	return &DeviceGatewayService{
+		db:          db,
+		mqttService: mqttService,
+		telemetry:   telemetry,
+	}
+}
+
+// Start opens the listeners in a comma separated list of protocol:network:port entries, such as
+// "teltonika:tcp:5027,teltonika:udp:5027,gt06:tcp:5023", and subscribes to relayed frames when MQTT is enabled
+func (s *DeviceGatewayService) Start(listeners string) error {
+	for _, spec := range strings.Split(listeners, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		parts := strings.Split(spec, ":")
+		if len(parts) != 3 {
+			return fmt.Errorf("invalid tracker listener %q, expected protocol:network:port", spec)
+		}
+		protocol, network, address := strings.ToLower(parts[0]), strings.ToLower(parts[1]), ":"+parts[2]
+		decoder, ok := DeviceDecoderFor(protocol)
+		if !ok {
+			return fmt.Errorf("no decoder for tracker protocol %q", protocol)
+		}
+
+		switch network {
+		case "tcp":
+			listener, err := net.Listen("tcp", address)
+			if err != nil {
+				return fmt.Errorf("failed to listen for %s trackers on %s: %w", protocol, address, err)
+			}
+			s.track(listener)
+			go s.acceptConnections(listener, protocol)
+		case "udp":
+			if _, ok := decoder.(DatagramDecoder); !ok {
+				return fmt.Errorf("tracker protocol %q does not support UDP", protocol)
+			}
+			conn, err := net.ListenPacket("udp", address)
+			if err != nil {
+				return fmt.Errorf("failed to listen for %s trackers on %s: %w", protocol, address, err)
+			}
+			s.track(conn)
+			go s.serveDatagrams(conn, protocol)
+		default:
+			return fmt.Errorf("invalid tracker listener network %q", network)
+		}
+		log.Printf("📟 Listening for %s trackers on %s%s", protocol, network, address)
+	}
+
+	if s.mqttService != nil && s.mqttService.IsEnabled() {
+		if err := s.mqttService.SubscribeToDeviceFrames(s.handleRelayedFrames); err != nil {
+			return fmt.Errorf("failed to subscribe to tracker frames: %w", err)
+		}
+	}
+	return nil
+}
+
+// Stop closes the listeners; open connections end at their next read
+func (s *DeviceGatewayService) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, listener := range s.listeners {
+		listener.Close()
+	}
+	s.listeners = nil
+}
+
+func (s *DeviceGatewayService) track(listener io.Closer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *DeviceGatewayService) acceptConnections(listener net.Listener, protocol string) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("❌ Tracker listener for %s stopped: %v", protocol, err)
+			}
+			return
+		}
+		go s.ServeConn(conn, protocol)
+	}
+}
+
+func (s *DeviceGatewayService) serveDatagrams(conn net.PacketConn, protocol string) {
+	buffer := make([]byte, maxDeviceFrame)
+	for {
+		n, addr, err := conn.ReadFrom(buffer)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("❌ Tracker UDP listener for %s stopped: %v", protocol, err)
+			}
+			return
+		}
+		packet := append([]byte(nil), buffer[:n]...)
+		if reply := s.HandleDatagram(protocol, packet, addr.String()); len(reply) > 0 {
+			if _, err := conn.WriteTo(reply, addr); err != nil {
+				log.Printf("⚠️ Failed to acknowledge %s tracker at %s: %v", protocol, addr, err)
+			}
+		}
+	}
+}
+
+// ServeConn decodes a tracker's TCP stream until it disconnects, goes quiet or is rejected
+func (s *DeviceGatewayService) ServeConn(conn net.Conn, protocol string) {
+	defer conn.Close()
+	decoder, ok := DeviceDecoderFor(protocol)
+	if !ok {
+		return
+	}
+
+	remote := conn.RemoteAddr().String()
+	session := &DeviceSession{}
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), maxDeviceFrame+8)
+	scanner.Split(decoder.Split)
+	for {
+		conn.SetReadDeadline(time.Now().Add(deviceIdleTimeout))
+		if !scanner.Scan() {
+			break
+		}
+		decoded, err := decoder.Decode(scanner.Bytes(), session)
+		if err != nil {
+			log.Printf("⚠️ Dropped %s frame from %s (IMEI %q): %v", protocol, remote, session.IMEI, err)
+			s.countRejected(session.IMEI)
+			continue
+		}
+		reply, accepted := s.accept(protocol, session, decoded, remote)
+		if len(reply) > 0 {
+			if _, err := conn.Write(reply); err != nil {
+				log.Printf("⚠️ Failed to acknowledge %s tracker %s: %v", protocol, session.IMEI, err)
+				return
+			}
+		}
+		if !accepted {
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, net.ErrClosed) && !errors.Is(err, io.EOF) {
+		log.Printf("⚠️ Closed %s tracker connection from %s (IMEI %q): %v", protocol, remote, session.IMEI, err)
+	}
+}
+
+// HandleDatagram decodes a UDP packet and returns the acknowledgement to send back, if any
+func (s *DeviceGatewayService) HandleDatagram(protocol string, packet []byte, remote string) []byte {
+	decoder, ok := DeviceDecoderFor(protocol)
+	if !ok {
+		return nil
+	}
+	datagrams, ok := decoder.(DatagramDecoder)
+	if !ok {
+		return nil
+	}
+	decoded, err := datagrams.DecodeDatagram(packet)
+	if err != nil {
+		log.Printf("⚠️ Dropped %s datagram from %s: %v", protocol, remote, err)
+		return nil
+	}
+	reply, accepted := s.accept(protocol, &DeviceSession{}, decoded, remote)
+	if !accepted {
+		return nil
+	}
+	return reply
+}
+
+// handleRelayedFrames decodes frames a broker relayed for a known IMEI; MQTT delivery needs no acknowledgement
+func (s *DeviceGatewayService) handleRelayedFrames(protocol, imei string, payload []byte) {
+	decoder, ok := DeviceDecoderFor(protocol)
+	if !ok {
+		log.Printf("⚠️ No decoder for relayed %s frames from %s", protocol, imei)
+		return
+	}
+	session := &DeviceSession{IMEI: imei}
+	for len(payload) > 0 {
+		advance, frame, err := decoder.Split(payload, true)
+		if err != nil || advance == 0 {
+			if err != nil {
+				log.Printf("⚠️ Dropped relayed %s frames from %s: %v", protocol, imei, err)
+			}
+			return
+		}
+		payload = payload[advance:]
+		if frame == nil {
+			continue
+		}
+		decoded, err := decoder.Decode(frame, session)
+		if err != nil {
+			log.Printf("⚠️ Dropped relayed %s frame from %s: %v", protocol, imei, err)
+			s.countRejected(imei)
+			continue
+		}
+		if _, accepted := s.accept(protocol, session, decoded, "mqtt"); !accepted {
+			return
+		}
+	}
+}
+
+// accept resolves the frame's device and, when it is fitted to a vehicle, delivers its records.
+// It returns the reply for the device and whether to keep listening to it.
+func (s *DeviceGatewayService) accept(protocol string, session *DeviceSession, decoded *DecodedFrame, remote string) ([]byte, bool) {
+	if decoded.IMEI != "" {
+		session.IMEI = decoded.IMEI
+	}
+	if session.IMEI == "" {
+		log.Printf("⚠️ %s tracker at %s sent data before identifying itself", protocol, remote)
+		return nil, false
+	}
+
+	device, err := s.resolveDevice(protocol, session.IMEI)
+	if err != nil {
+		log.Printf("❌ Failed to look up tracker %s: %v", session.IMEI, err)
+		return nil, false
+	}
+	if device.VehicleID == nil || !device.IsActive {
+		log.Printf("⚠️ Rejected unassigned %s tracker %s from %s", protocol, session.IMEI, remote)
+		s.countRejected(session.IMEI)
+		return decoded.Reject, false
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"last_seen_at":     now,
+		"last_remote_addr": remote,
+		"frames_received":  gorm.Expr("frames_received + 1"),
+	}
+	for i := range decoded.Records {
+		record := &decoded.Records[i]
+		if record.Timestamp.IsZero() {
+			record.Timestamp = now
+		}
+		if record.Ignition != nil {
+			updates["ignition"] = *record.Ignition
+			updates["ignition_at"] = record.Timestamp
+		}
+		s.deliver(*device.VehicleID, record)
+	}
+	if err := s.db.Model(&models.TrackerDevice{}).Where("id = ?", device.ID).Updates(updates).Error; err != nil {
+		log.Printf("⚠️ Failed to update tracker %s: %v", session.IMEI, err)
+	}
+	return decoded.Ack, true
+}
+
+// resolveDevice finds a tracker by IMEI, recording unknown ones for an admin to assign
+func (s *DeviceGatewayService) resolveDevice(protocol, imei string) (*models.TrackerDevice, error) {
+	var devices []models.TrackerDevice
+	if err := s.db.Where("imei = ?", imei).Limit(1).Find(&devices).Error; err != nil {
+		return nil, err
+	}
+	if len(devices) > 0 {
+		return &devices[0], nil
+	}
+	device := models.TrackerDevice{IMEI: imei, Protocol: protocol}
+	if err := s.db.Create(&device).Error; err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (s *DeviceGatewayService) countRejected(imei string) {
+	if imei == "" {
+		return
+	}
+	s.db.Model(&models.TrackerDevice{}).Where("imei = ?", imei).
+		Update("frames_rejected", gorm.Expr("frames_rejected + 1"))
+}
+
+// deliver emits a record as normalized location and telemetry updates: over MQTT to the ingestion
+// and telemetry subscribers when it is enabled, otherwise straight into storage
+func (s *DeviceGatewayService) deliver(vehicleID uint, record *DeviceRecord) {
+	var location *LocationUpdate
+	if record.HasPosition {
+		location = &LocationUpdate{
+			VehicleID: vehicleID,
+			Latitude:  record.Latitude,
+			Longitude: record.Longitude,
+			Speed:     record.Speed,
+			Heading:   record.Heading,
+			Accuracy:  record.Accuracy,
+			Timestamp: record.Timestamp,
+		}
+	}
+	var telemetry *TelemetryUpdate
+	if record.hasTelemetry() {
+		telemetry = &TelemetryUpdate{
+			VehicleID:      vehicleID,
+			Timestamp:      record.Timestamp,
+			EngineRPM:      record.EngineRPM,
+			Speed:          record.VehicleSpeed,
+			CoolantTemp:    record.CoolantTemp,
+			EngineLoad:     record.EngineLoad,
+			ThrottlePos:    record.ThrottlePos,
+			FuelLevel:      record.FuelLevel,
+			BatteryVoltage: record.BatteryVoltage,
+			Odometer:       record.Odometer,
+			EngineHours:    record.EngineHours,
+			FuelUsed:       record.FuelUsed,
+		}
+	}
+
+	if s.mqttService != nil && s.mqttService.IsEnabled() {
+		if location != nil {
+			if err := s.mqttService.PublishLocationUpdate(vehicleID, location); err != nil {
+				log.Printf("❌ Failed to publish tracker location for vehicle %d: %v", vehicleID, err)
+			}
+		}
+		if telemetry != nil {
+			if err := s.mqttService.PublishTelemetryUpdate(vehicleID, telemetry); err != nil {
+				log.Printf("❌ Failed to publish tracker telemetry for vehicle %d: %v", vehicleID, err)
+			}
+		}
+		return
+	}
+
+	if location != nil {
+		speed, heading, altitude := location.Speed, location.Heading, record.Altitude
+		ping := models.LocationPing{
+			VehicleID: &vehicleID,
+			Latitude:  location.Latitude,
+			Longitude: location.Longitude,
+			Speed:     &speed,
+			Heading:   &heading,
+			Altitude:  &altitude,
+			Accuracy:  location.Accuracy,
+			Timestamp: location.Timestamp,
+			CreatedAt: time.Now(),
+			Source:    "GPS_DEVICE",
+		}
+		if err := s.db.Create(&ping).Error; err != nil {
+			log.Printf("❌ Failed to save tracker location for vehicle %d: %v", vehicleID, err)
+		}
+	}
+	if telemetry != nil && s.telemetry != nil {
+		if _, err := s.telemetry.IngestTelemetry(context.Background(), telemetry); err != nil {
+			log.Printf("❌ Failed to ingest tracker telemetry for vehicle %d: %v", vehicleID, err)
+		}
+	}
+}
+
+// SaveTrackerDevice registers a tracker or reassigns it by IMEI
+func (s *DeviceGatewayService) SaveTrackerDevice(ctx context.Context, device *models.TrackerDevice) (*models.TrackerDevice, error) {
+	device.IMEI = strings.TrimSpace(device.IMEI)
+	device.Protocol = strings.ToLower(strings.TrimSpace(device.Protocol))
+	if !validIMEI(device.IMEI) {
+		return nil, fmt.Errorf("%w: IMEI %q", ErrInvalidTrackerDevice, device.IMEI)
+	}
+	if _, ok := DeviceDecoderFor(device.Protocol); !ok {
+		return nil, fmt.Errorf("%w: unsupported protocol %q, expected one of %s",
+			ErrInvalidTrackerDevice, device.Protocol, strings.Join(DeviceProtocols(), ", "))
+	}
+	if device.VehicleID != nil {
+		var vehicle models.Vehicle
+		if err := s.db.First(&vehicle, *device.VehicleID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: vehicle %d not found", ErrInvalidTrackerDevice, *device.VehicleID)
+			}
+			return nil, err
+		}
+	}
+
+	var existing []models.TrackerDevice
+	if err := s.db.Where("imei = ?", device.IMEI).Limit(1).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		current := existing[0]
+		current.VehicleID = device.VehicleID
+		current.Protocol = device.Protocol
+		current.Model = device.Model
+		current.IsActive = device.IsActive
+		device = &current
+	}
+	if err := s.db.Omit("Vehicle").Save(device).Error; err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// GetTrackerDevices lists trackers, optionally for one vehicle or only those awaiting assignment
+func (s *DeviceGatewayService) GetTrackerDevices(ctx context.Context, vehicleID *uint, unassigned bool) ([]models.TrackerDevice, error) {
+	query := s.db.Model(&models.TrackerDevice{})
+	if vehicleID != nil {
+		query = query.Where("vehicle_id = ?", *vehicleID)
+	}
+	if unassigned {
+		query = query.Where("vehicle_id IS NULL")
+	}
+	var devices []models.TrackerDevice
+	if err := query.Order("imei").Find(&devices).Error; err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// DeleteTrackerDevice removes a tracker; if it reports again it is recorded as unassigned
+func (s *DeviceGatewayService) DeleteTrackerDevice(ctx context.Context, id uint) error {
+	result := s.db.Delete(&models.TrackerDevice{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DecodeDeviceFrames decodes a captured tracker payload, a TCP stream or a single UDP packet,
+// without delivering it, for diagnosing devices
+func DecodeDeviceFrames(protocol string, payload []byte, udp bool) ([]DecodedFrame, error) {
+	decoder, ok := DeviceDecoderFor(protocol)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported protocol %q", ErrMalformedFrame, protocol)
+	}
+	if udp {
+		datagrams, ok := decoder.(DatagramDecoder)
+		if !ok {
+			return nil, fmt.Errorf("%w: protocol %q does not support UDP", ErrMalformedFrame, protocol)
+		}
+		decoded, err := datagrams.DecodeDatagram(payload)
+		if err != nil {
+			return nil, err
+		}
+		return []DecodedFrame{*decoded}, nil
+	}
+
+	session := &DeviceSession{}
+	var frames []DecodedFrame
+	for len(payload) > 0 {
+		advance, frame, err := decoder.Split(payload, true)
+		if err != nil {
+			return nil, err
+		}
+		if advance == 0 {
+			return nil, fmt.Errorf("%w: %d trailing bytes", ErrMalformedFrame, len(payload))
+		}
+		payload = payload[advance:]
+		if frame == nil {
+			continue
+		}
+		decoded, err := decoder.Decode(frame, session)
+		if err != nil {
+			return nil, err
+		}
+		if decoded.IMEI != "" {
+			session.IMEI = decoded.IMEI
+		}
+		frames = append(frames, *decoded)
+	}
+	return frames, nil
+}
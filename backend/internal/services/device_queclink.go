@@ -0,0 +1,156 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queclinkPositionFields is the width of a position block in an @Track report
+const queclinkPositionFields = 12
+
+// queclinkDecoder decodes the Queclink @Track ASCII protocol: comma separated reports such as
+// +RESP:GTFRI,<version>,<IMEI>,... terminated by '$'. Fixed reports, buffered reports and
+// ignition on/off reports are mapped; heartbeats are acknowledged.
+type queclinkDecoder struct{}
+
+// Split frames '$' terminated messages, skipping line breaks between them
+func (queclinkDecoder) Split(data []byte, atEOF bool) (int, []byte, error) {
+	start := 0
+	for start < len(data) && (data[start] == '\r' || data[start] == '\n' || data[start] == ' ') {
+		start++
+	}
+	end := bytes.IndexByte(data[start:], '$')
+	if end < 0 {
+		if len(data)-start > maxDeviceFrame {
+			return 0, nil, fmt.Errorf("%w: queclink message without terminator", ErrMalformedFrame)
+		}
+		return start, nil, nil
+	}
+	return start + end + 1, data[start : start+end+1], nil
+}
+
+// Decode decodes a report or heartbeat
+func (queclinkDecoder) Decode(frame []byte, session *DeviceSession) (*DecodedFrame, error) {
+	return decodeQueclink(frame)
+}
+
+// DecodeDatagram decodes a report sent over UDP, which has the same format
+func (queclinkDecoder) DecodeDatagram(packet []byte) (*DecodedFrame, error) {
+	return decodeQueclink(bytes.TrimSpace(packet))
+}
+
+func decodeQueclink(frame []byte) (*DecodedFrame, error) {
+	message := strings.TrimSuffix(string(frame), "$")
+	fields := strings.Split(message, ",")
+	kind := strings.SplitN(fields[0], ":", 2)
+	if len(kind) != 2 || len(fields) < 3 {
+		return nil, fmt.Errorf("%w: queclink message %q", ErrMalformedFrame, message)
+	}
+	imei := fields[2]
+	if !validIMEI(imei) {
+		return nil, fmt.Errorf("%w: queclink IMEI %q", ErrMalformedFrame, imei)
+	}
+	decoded := &DecodedFrame{IMEI: imei, Records: []DeviceRecord{}}
+
+	switch kind[1] {
+	case "GTHBD":
+		// Heartbeats are answered with the protocol version and count number
+		decoded.Login = true
+		if kind[0] == "+ACK" && len(fields) >= 6 {
+			decoded.Ack = []byte(fmt.Sprintf("+SACK:GTHBD,%s,%s$", fields[1], fields[len(fields)-1]))
+		}
+
+	case "GTFRI", "GTGEO", "GTSPD", "GTSOS", "GTRTL", "GTPNL", "GTNMR", "GTDIS", "GTDOG", "GTIGL":
+		// <report id>,<report type>,<number> then <number> position blocks and the mileage
+		if len(fields) < 7 {
+			return nil, fmt.Errorf("%w: short queclink %s report", ErrMalformedFrame, kind[1])
+		}
+		count, err := strconv.Atoi(fields[6])
+		if err != nil || count < 1 || len(fields) < 7+count*queclinkPositionFields {
+			return nil, fmt.Errorf("%w: queclink %s report with %q positions", ErrMalformedFrame, kind[1], fields[6])
+		}
+		var odometer *float64
+		if mileage := 7 + count*queclinkPositionFields; mileage < len(fields) {
+			if km, err := strconv.ParseFloat(fields[mileage], 64); err == nil {
+				odometer = &km
+			}
+		}
+		for i := 0; i < count; i++ {
+			record, err := decodeQueclinkPosition(fields[7+i*queclinkPositionFields:])
+			if err != nil {
+				return nil, err
+			}
+			record.Odometer = odometer
+			decoded.Records = append(decoded.Records, *record)
+		}
+
+	case "GTIGN", "GTIGF":
+		// <duration of previous state> then one position block, the hour meter and the mileage
+		if len(fields) < 5+queclinkPositionFields {
+			return nil, fmt.Errorf("%w: short queclink %s report", ErrMalformedFrame, kind[1])
+		}
+		record, err := decodeQueclinkPosition(fields[5:])
+		if err != nil {
+			return nil, err
+		}
+		on := kind[1] == "GTIGN"
+		record.Ignition = &on
+		rest := fields[5+queclinkPositionFields:]
+		if len(rest) > 0 {
+			if hours, ok := parseQueclinkHourMeter(rest[0]); ok {
+				record.EngineHours = &hours
+			}
+		}
+		if len(rest) > 1 {
+			if km, err := strconv.ParseFloat(rest[1], 64); err == nil {
+				record.Odometer = &km
+			}
+		}
+		decoded.Records = append(decoded.Records, *record)
+	}
+
+	return decoded, nil
+}
+
+// decodeQueclinkPosition decodes GPS accuracy, speed, azimuth, altitude, longitude, latitude and UTC time
+func decodeQueclinkPosition(fields []string) (*DeviceRecord, error) {
+	record := &DeviceRecord{}
+	if fields[6] != "" {
+		at, err := time.Parse("20060102150405", fields[6])
+		if err != nil {
+			return nil, fmt.Errorf("%w: queclink time %q", ErrMalformedFrame, fields[6])
+		}
+		record.Timestamp = at
+	}
+	accuracy, _ := strconv.ParseFloat(fields[0], 64)
+	record.Accuracy = accuracy
+	record.Speed, _ = strconv.ParseFloat(fields[1], 64)
+	record.Heading, _ = strconv.ParseFloat(fields[2], 64)
+	record.Altitude, _ = strconv.ParseFloat(fields[3], 64)
+	longitude, lonErr := strconv.ParseFloat(fields[4], 64)
+	latitude, latErr := strconv.ParseFloat(fields[5], 64)
+	// An accuracy of 0 means the device had no fix and repeated its last position
+	record.HasPosition = lonErr == nil && latErr == nil && accuracy > 0 && !record.Timestamp.IsZero()
+	record.Longitude, record.Latitude = longitude, latitude
+	return record, nil
+}
+
+// parseQueclinkHourMeter reads an HHHHH:MM:SS hour meter
+func parseQueclinkHourMeter(value string) (float64, bool) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	var total float64
+	for i, scale := range []float64{1, 1.0 / 60, 1.0 / 3600} {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0, false
+		}
+		total += float64(n) * scale
+	}
+	return total, true
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Teltonika codec IDs
+const (
+	teltonikaCodec8  = 0x08
+	teltonikaCodec8E = 0x8E
+)
+
+// teltonikaDecoder decodes Teltonika FMx Codec 8 and Codec 8 Extended AVL data. Over TCP a
+// device first sends its IMEI, then AVL packets framed by a zero preamble, a length and a
+// CRC-16/IBM; over UDP each packet carries its IMEI and no CRC.
+type teltonikaDecoder struct{}
+
+// Split frames the IMEI handshake and AVL packets
+func (teltonikaDecoder) Split(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) < 2 {
+		return 0, nil, nil
+	}
+	size := 0
+	if len(data) >= 4 && binary.BigEndian.Uint32(data[:4]) == 0 {
+		if len(data) < 8 {
+			return 0, nil, nil
+		}
+		size = 8 + int(binary.BigEndian.Uint32(data[4:8])) + 4
+	} else {
+		imeiLength := int(binary.BigEndian.Uint16(data[:2]))
+		if imeiLength == 0 || imeiLength > 20 {
+			return 0, nil, fmt.Errorf("%w: unexpected teltonika header % X", ErrMalformedFrame, data[:2])
+		}
+		size = 2 + imeiLength
+	}
+	if size > maxDeviceFrame {
+		return 0, nil, fmt.Errorf("%w: teltonika frame of %d bytes", ErrMalformedFrame, size)
+	}
+	if len(data) < size {
+		return 0, nil, nil
+	}
+	return size, data[:size], nil
+}
+
+// Decode decodes an IMEI handshake or an AVL packet
+func (teltonikaDecoder) Decode(frame []byte, session *DeviceSession) (*DecodedFrame, error) {
+	if len(frame) >= 4 && binary.BigEndian.Uint32(frame[:4]) != 0 {
+		imei := string(frame[2:])
+		if !validIMEI(imei) {
+			return nil, fmt.Errorf("%w: teltonika IMEI %q", ErrMalformedFrame, imei)
+		}
+		return &DecodedFrame{IMEI: imei, Login: true, Ack: []byte{0x01}, Reject: []byte{0x00}}, nil
+	}
+
+	if len(frame) < 12 {
+		return nil, fmt.Errorf("%w: teltonika packet of %d bytes", ErrMalformedFrame, len(frame))
+	}
+	data := frame[8 : len(frame)-4]
+	if want, got := binary.BigEndian.Uint32(frame[len(frame)-4:]), crc16IBM(data); want != uint32(got) {
+		return nil, fmt.Errorf("%w: teltonika CRC %04X, expected %04X", ErrMalformedFrame, got, want)
+	}
+	records, err := decodeTeltonikaAVL(&frameReader{data: data})
+	if err != nil {
+		return nil, err
+	}
+	ack := make([]byte, 4)
+	binary.BigEndian.PutUint32(ack, uint32(len(records)))
+	return &DecodedFrame{Records: records, Ack: ack}, nil
+}
+
+// DecodeDatagram decodes a UDP packet: length, packet ID, AVL packet ID and IMEI ahead of the AVL data
+func (teltonikaDecoder) DecodeDatagram(packet []byte) (*DecodedFrame, error) {
+	r := &frameReader{data: packet}
+	length := int(r.u16())
+	packetID := r.next(2)
+	r.u8() // Not usable byte
+	avlPacketID := r.u8()
+	imei := string(r.next(int(r.u16())))
+	if r.err != nil {
+		return nil, r.err
+	}
+	if length != len(packet)-2 {
+		return nil, fmt.Errorf("%w: teltonika UDP length %d for %d bytes", ErrMalformedFrame, length, len(packet)-2)
+	}
+	if !validIMEI(imei) {
+		return nil, fmt.Errorf("%w: teltonika IMEI %q", ErrMalformedFrame, imei)
+	}
+	records, err := decodeTeltonikaAVL(r)
+	if err != nil {
+		return nil, err
+	}
+	ack := []byte{0x00, 0x05, packetID[0], packetID[1], 0x01, avlPacketID, byte(len(records))}
+	return &DecodedFrame{IMEI: imei, Records: records, Ack: ack}, nil
+}
+
+// decodeTeltonikaAVL decodes the codec ID, record count, records and closing record count
+func decodeTeltonikaAVL(r *frameReader) ([]DeviceRecord, error) {
+	codec := r.u8()
+	count := int(r.u8())
+	if r.err != nil {
+		return nil, r.err
+	}
+	if codec != teltonikaCodec8 && codec != teltonikaCodec8E {
+		return nil, fmt.Errorf("%w: unsupported teltonika codec %#02x", ErrMalformedFrame, codec)
+	}
+	extended := codec == teltonikaCodec8E
+
+	records := make([]DeviceRecord, 0, count)
+	for i := 0; i < count; i++ {
+		record := DeviceRecord{IO: make(map[int]int64)}
+		record.Timestamp = time.UnixMilli(int64(r.u64())).UTC()
+		r.u8() // Priority
+		longitude := int32(r.u32())
+		latitude := int32(r.u32())
+		record.Altitude = float64(int16(r.u16()))
+		record.Heading = float64(r.u16())
+		record.Satellites = int(r.u8())
+		record.Speed = float64(r.u16())
+		record.Longitude = float64(longitude) / 1e7
+		record.Latitude = float64(latitude) / 1e7
+		record.HasPosition = record.Satellites > 0 && (longitude != 0 || latitude != 0)
+
+		width := func() int {
+			if extended {
+				return int(r.u16())
+			}
+			return int(r.u8())
+		}
+		width() // Event I/O ID
+		width() // Total I/O count
+		for _, size := range []int{1, 2, 4, 8} {
+			n := width()
+			for j := 0; j < n && r.err == nil; j++ {
+				id := width()
+				raw := r.next(size)
+				var value int64
+				switch size {
+				case 1:
+					value = int64(raw[0])
+				case 2:
+					value = int64(binary.BigEndian.Uint16(raw))
+				case 4:
+					value = int64(binary.BigEndian.Uint32(raw))
+				default:
+					value = int64(binary.BigEndian.Uint64(raw))
+				}
+				record.IO[id] = value
+				applyTeltonikaIO(&record, id, size, value)
+			}
+		}
+		if extended {
+			// Variable length elements carry no values we map
+			n := int(r.u16())
+			for j := 0; j < n && r.err == nil; j++ {
+				r.u16()
+				r.next(int(r.u16()))
+			}
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		records = append(records, record)
+	}
+
+	if closing := int(r.u8()); r.err == nil && closing != count {
+		return nil, fmt.Errorf("%w: teltonika record counts %d and %d differ", ErrMalformedFrame, count, closing)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return records, nil
+}
+
+// applyTeltonikaIO maps FMx I/O elements, including OBD and LV-CAN values, onto a record
+func applyTeltonikaIO(record *DeviceRecord, id, size int, value int64) {
+	switch id {
+	case 239: // Ignition
+		on := value != 0
+		record.Ignition = &on
+	case 66: // External voltage, mV
+		record.BatteryVoltage = floatValue(float64(value) / 1000)
+	case 16, 87: // Total odometer, LV-CAN total mileage; metres
+		record.Odometer = floatValue(float64(value) / 1000)
+	case 36, 85: // OBD engine RPM, LV-CAN engine RPM
+		rpm := int(value)
+		record.EngineRPM = &rpm
+	case 37, 81: // OBD vehicle speed, LV-CAN vehicle speed
+		record.VehicleSpeed = floatValue(float64(value))
+	case 32: // OBD coolant temperature, signed °C
+		record.CoolantTemp = floatValue(float64(signedIO(value, size)))
+	case 115: // LV-CAN engine temperature, 0.1 °C
+		record.CoolantTemp = floatValue(float64(signedIO(value, size)) / 10)
+	case 31: // OBD engine load
+		record.EngineLoad = floatValue(float64(value))
+	case 41: // OBD throttle position
+		record.ThrottlePos = floatValue(float64(value))
+	case 48, 89: // OBD fuel level, LV-CAN fuel level; percent
+		record.FuelLevel = floatValue(float64(value))
+	case 102: // LV-CAN engine worktime, minutes
+		record.EngineHours = floatValue(float64(value) / 60)
+	case 83: // LV-CAN fuel consumed, 0.1 L
+		record.FuelUsed = floatValue(float64(value) / 10)
+	case 182: // GNSS HDOP, 0.1; 181 is PDOP, which includes the vertical error
+		record.Accuracy = float64(value) / 10
+	}
+}
+
+// signedIO reads a two's complement I/O value of the given byte width
+func signedIO(value int64, size int) int64 {
+	switch size {
+	case 1:
+		return int64(int8(value))
+	case 2:
+		return int64(int16(value))
+	case 4:
+		return int64(int32(value))
+	default:
+		return value
+	}
+}
+
+// validIMEI reports whether s looks like a 15 digit IMEI
+func validIMEI(s string) bool {
+	if len(s) < 14 || len(s) > 17 {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,465 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidScoreWindow is returned for score windows other than the supported rolling windows
+var ErrInvalidScoreWindow = errors.New("invalid score window")
+
+// DriverScoreWindows are the rolling windows, in days, scores are kept for
+var DriverScoreWindows = []int{7, 30, 90}
+
+const (
+	// defaultScoreWindow is the window reported when none is asked for
+	defaultScoreWindow = 30
+	// driverScoreStaleAfter is how old a stored score may be before a request recalculates it
+	driverScoreStaleAfter = time.Hour
+	// minRankedDistanceKm is the driving a window needs before the driver is ranked against the fleet
+	minRankedDistanceKm = 50
+	// maxPingGap splits a vehicle's track; longer gaps are not counted as driving
+	maxPingGap = 5 * time.Minute
+	// movingSpeedKmh is the speed above which time between pings counts as driving
+	movingSpeedKmh = 5
+	// minScoreExposure floors the exposure event rates divide by: 10 km, or 6 minutes for speeding
+	minScoreExposure = 0.1
+)
+
+// scoreCategory is how one category's events become a sub-score. The rate is severity weighted
+// events per 100 km, or per hour for speeding, and the sub-score halves every halfRate.
+type scoreCategory struct {
+	eventType models.SafetyEventType
+	perHour   bool
+	halfRate  float64
+	weight    float64
+}
+
+var scoreCategories = []scoreCategory{
+	{models.SafetyEventHarshBraking, false, 4, 0.3},
+	{models.SafetyEventHarshAcceleration, false, 4, 0.2},
+	{models.SafetyEventHarshCornering, false, 4, 0.2},
+	{models.SafetyEventSpeeding, true, 2, 0.3},
+}
+
+// severityWeights counts a critical event as eight low ones
+var severityWeights = map[models.SafetyEventSeverity]float64{
+	models.SeverityLow:      0.5,
+	models.SeverityMedium:   1,
+	models.SeverityHigh:     2,
+	models.SeverityCritical: 4,
+}
+
+// SafetyEventFilter narrows a safety event query; zero values are ignored
+type SafetyEventFilter struct {
+	VehicleID *uint
+	DriverID  *uint
+	TripID    *uint
+	Types     []models.SafetyEventType
+	Severity  models.SafetyEventSeverity
+	IsViewed  *bool
+	HasVideo  *bool
+	From      *time.Time
+	To        *time.Time
+}
+
+// GetSafetyEvents lists safety events, newest first, with the total matching the filter
+func (s *SafetyService) GetSafetyEvents(ctx context.Context, filter SafetyEventFilter, page, limit int) ([]models.SafetyEvent, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.SafetyEvent{})
+	if filter.VehicleID != nil {
+		query = query.Where("vehicle_id = ?", *filter.VehicleID)
+	}
+	if filter.DriverID != nil {
+		query = query.Where("driver_id = ?", *filter.DriverID)
+	}
+	if filter.TripID != nil {
+		query = query.Where("trip_id = ?", *filter.TripID)
+	}
+	if len(filter.Types) > 0 {
+		query = query.Where("type IN ?", filter.Types)
+	}
+	if filter.Severity != "" {
+		query = query.Where("severity = ?", filter.Severity)
+	}
+	if filter.IsViewed != nil {
+		query = query.Where("is_viewed = ?", *filter.IsViewed)
+	}
+	if filter.HasVideo != nil {
+		if *filter.HasVideo {
+			query = query.Where("video_clip_id IS NOT NULL")
+		} else {
+			query = query.Where("video_clip_id IS NULL")
+		}
+	}
+	if filter.From != nil {
+		query = query.Where("timestamp >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("timestamp <= ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []models.SafetyEvent
+	offset := (page - 1) * limit
+	if err := preloadEventFootage(query.Preload("Driver").Preload("Vehicle")).
+		Order("timestamp DESC").
+		Offset(offset).Limit(limit).
+		Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// GetSafetyEvent returns an event with its driver, vehicle, trip and the linked clip's detections
+func (s *SafetyService) GetSafetyEvent(ctx context.Context, id uint) (*models.SafetyEvent, error) {
+	var event models.SafetyEvent
+	if err := preloadEventFootage(s.db.WithContext(ctx).Preload("Driver").Preload("Vehicle").Preload("Trip")).
+		First(&event, id).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// preloadEventFootage loads the clip linked to events along with what was detected in it
+func preloadEventFootage(query *gorm.DB) *gorm.DB {
+	return query.Preload("VideoClip").Preload("VideoClip.Camera").
+		Preload("VideoClip.Detections", func(db *gorm.DB) *gorm.DB {
+			return db.Order("timestamp ASC")
+		})
+}
+
+// GetDriverScore returns a driver's score for a window, recalculating the fleet's scores for
+// that window when the stored one is missing or stale
+func (s *SafetyService) GetDriverScore(ctx context.Context, driverID uint, windowDays int) (*models.DriverScore, error) {
+	if windowDays == 0 {
+		windowDays = defaultScoreWindow
+	}
+	if !validScoreWindow(windowDays) {
+		return nil, fmt.Errorf("%w: %d days, expected one of %v", ErrInvalidScoreWindow, windowDays, DriverScoreWindows)
+	}
+	var driver models.Driver
+	if err := s.db.WithContext(ctx).First(&driver, driverID).Error; err != nil {
+		return nil, err
+	}
+
+	score, err := s.storedDriverScore(ctx, driverID, windowDays)
+	if err != nil {
+		return nil, err
+	}
+	if score == nil || time.Since(score.LastCalculated) > driverScoreStaleAfter {
+		if _, err := s.RecalculateDriverScores(ctx, windowDays, time.Now()); err != nil {
+			return nil, err
+		}
+		if score, err = s.storedDriverScore(ctx, driverID, windowDays); err != nil {
+			return nil, err
+		}
+	}
+	if score == nil {
+		// Drivers with no trips or events in the window have a clean, unranked score
+		score = &models.DriverScore{
+			DriverID: driverID, WindowDays: windowDays, Provisional: true,
+			OverallScore: 100, BrakingScore: 100, AccelScore: 100, CorneringScore: 100, SpeedingScore: 100,
+			PeriodStart: time.Now().AddDate(0, 0, -windowDays), PeriodEnd: time.Now(), LastCalculated: time.Now(),
+		}
+	}
+	score.Driver = driver
+	return score, nil
+}
+
+// GetFleetDriverScores lists the stored scores for a window, safest first
+func (s *SafetyService) GetFleetDriverScores(ctx context.Context, windowDays int) ([]models.DriverScore, error) {
+	if windowDays == 0 {
+		windowDays = defaultScoreWindow
+	}
+	if !validScoreWindow(windowDays) {
+		return nil, fmt.Errorf("%w: %d days, expected one of %v", ErrInvalidScoreWindow, windowDays, DriverScoreWindows)
+	}
+	var scores []models.DriverScore
+	if err := s.db.WithContext(ctx).Preload("Driver").
+		Where("window_days = ?", windowDays).
+		Order("provisional, overall_score DESC, total_distance DESC").
+		Find(&scores).Error; err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+func (s *SafetyService) storedDriverScore(ctx context.Context, driverID uint, windowDays int) (*models.DriverScore, error) {
+	var scores []models.DriverScore
+	if err := s.db.WithContext(ctx).Where("driver_id = ? AND window_days = ?", driverID, windowDays).
+		Limit(1).Find(&scores).Error; err != nil {
+		return nil, err
+	}
+	if len(scores) == 0 {
+		return nil, nil
+	}
+	return &scores[0], nil
+}
+
+// RecalculateDriverScores scores every driver with trips or events in the window ending at now
+// and ranks them against each other
+func (s *SafetyService) RecalculateDriverScores(ctx context.Context, windowDays int, now time.Time) ([]models.DriverScore, error) {
+	if !validScoreWindow(windowDays) {
+		return nil, fmt.Errorf("%w: %d days, expected one of %v", ErrInvalidScoreWindow, windowDays, DriverScoreWindows)
+	}
+	from := now.AddDate(0, 0, -windowDays)
+	db := s.db.WithContext(ctx)
+
+	var trips []models.Trip
+	if err := db.Where("driver_id IS NOT NULL AND vehicle_id IS NOT NULL AND actual_pickup_time IS NOT NULL").
+		Where("actual_pickup_time < ? AND (actual_arrival IS NULL OR actual_arrival > ?)", now, from).
+		Where("status <> ?", models.TripStatusCancelled).
+		Find(&trips).Error; err != nil {
+		return nil, err
+	}
+	var events []models.SafetyEvent
+	if err := s.scoredEvents(ctx).Where("driver_id IS NOT NULL AND timestamp >= ? AND timestamp < ?", from, now).
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	tripsByDriver := make(map[uint][]models.Trip)
+	for _, trip := range trips {
+		tripsByDriver[*trip.DriverID] = append(tripsByDriver[*trip.DriverID], trip)
+	}
+	eventsByDriver := make(map[uint][]models.SafetyEvent)
+	for _, event := range events {
+		eventsByDriver[*event.DriverID] = append(eventsByDriver[*event.DriverID], event)
+	}
+	drivers := make(map[uint]bool)
+	for id := range tripsByDriver {
+		drivers[id] = true
+	}
+	for id := range eventsByDriver {
+		drivers[id] = true
+	}
+
+	scores := make([]models.DriverScore, 0, len(drivers))
+	for driverID := range drivers {
+		km, hours, err := s.driverExposure(ctx, driverID, tripsByDriver[driverID], from, now)
+		if err != nil {
+			return nil, err
+		}
+		score := scoreDriver(eventsByDriver[driverID], km, hours)
+		score.DriverID = driverID
+		score.WindowDays = windowDays
+		score.PeriodStart = from
+		score.PeriodEnd = now
+		score.LastCalculated = time.Now()
+		scores = append(scores, score)
+	}
+	rankDriverScores(scores)
+
+	for i := range scores {
+		existing, err := s.storedDriverScore(ctx, scores[i].DriverID, windowDays)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			scores[i].ID = existing.ID
+			scores[i].CreatedAt = existing.CreatedAt
+		}
+		if err := db.Omit("Driver").Save(&scores[i]).Error; err != nil {
+			return nil, err
+		}
+	}
+	// Drivers who no longer have activity in the window drop out of it
+	stale := db.Where("window_days = ?", windowDays)
+	if len(scores) > 0 {
+		ids := make([]uint, len(scores))
+		for i, score := range scores {
+			ids[i] = score.DriverID
+		}
+		stale = stale.Where("driver_id NOT IN ?", ids)
+	}
+	if err := stale.Unscoped().Delete(&models.DriverScore{}).Error; err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+// StartDriverScoreRecalculation refreshes every window's scores on an interval, and measures
+// the effect of coaching that has had a month to show
+func (s *SafetyService) StartDriverScoreRecalculation(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			for _, window := range DriverScoreWindows {
+				scores, err := s.RecalculateDriverScores(context.Background(), window, time.Now())
+				if err != nil {
+					log.Printf("❌ Driver score recalculation for %d days failed: %v", window, err)
+					continue
+				}
+				log.Printf("🛡️ Scored %d driver(s) over %d days", len(scores), window)
+			}
+			s.measureCoaching()
+		}
+	}()
+}
+
+// driverExposure is the distance and time a driver drove in the window: the tracks of the
+// vehicles on their trips, plus pings tagged with the driver outside any trip
+func (s *SafetyService) driverExposure(ctx context.Context, driverID uint, trips []models.Trip, from, to time.Time) (float64, float64, error) {
+	db := s.db.WithContext(ctx)
+	seen := make(map[uint]bool)
+	tracks := make(map[uint][]models.LocationPing)
+	add := func(pings []models.LocationPing) {
+		for _, ping := range pings {
+			if seen[ping.ID] || ping.VehicleID == nil {
+				continue
+			}
+			seen[ping.ID] = true
+			tracks[*ping.VehicleID] = append(tracks[*ping.VehicleID], ping)
+		}
+	}
+
+	for _, trip := range trips {
+		start, end := *trip.ActualPickupTime, to
+		if trip.ActualArrival != nil && trip.ActualArrival.Before(end) {
+			end = *trip.ActualArrival
+		}
+		if start.Before(from) {
+			start = from
+		}
+		var pings []models.LocationPing
+		if err := db.Where("vehicle_id = ? AND timestamp >= ? AND timestamp <= ?", *trip.VehicleID, start, end).
+			Find(&pings).Error; err != nil {
+			return 0, 0, err
+		}
+		add(pings)
+	}
+	var tagged []models.LocationPing
+	if err := db.Where("driver_id = ? AND trip_id IS NULL AND timestamp >= ? AND timestamp <= ?", driverID, from, to).
+		Find(&tagged).Error; err != nil {
+		return 0, 0, err
+	}
+	add(tagged)
+
+	var km, hours float64
+	for _, track := range tracks {
+		sort.Slice(track, func(i, j int) bool { return track[i].Timestamp.Before(track[j].Timestamp) })
+		for i := 1; i < len(track); i++ {
+			gap := track[i].Timestamp.Sub(track[i-1].Timestamp)
+			if gap <= 0 || gap > maxPingGap {
+				continue
+			}
+			meters := models.HaversineMeters(track[i-1].Latitude, track[i-1].Longitude, track[i].Latitude, track[i].Longitude)
+			moving := meters / 1000 / gap.Hours()
+			if track[i].Speed != nil && *track[i].Speed > moving {
+				moving = *track[i].Speed
+			}
+			km += meters / 1000
+			if moving >= movingSpeedKmh {
+				hours += gap.Hours()
+			}
+		}
+	}
+	return km, hours, nil
+}
+
+// scoreDriver turns a window's events and exposure into sub-scores and an overall score
+func scoreDriver(events []models.SafetyEvent, km, hours float64) models.DriverScore {
+	weighted := make(map[models.SafetyEventType]float64)
+	counts := make(map[models.SafetyEventType]int)
+	for _, event := range events {
+		weight, ok := severityWeights[event.Severity]
+		if !ok {
+			weight = 1
+		}
+		weighted[event.Type] += weight
+		counts[event.Type]++
+	}
+
+	score := models.DriverScore{
+		TotalDistance:   math.Round(km*10) / 10,
+		TotalDriveTime:  math.Round(hours*100) / 100,
+		Provisional:     km < minRankedDistanceKm,
+		BrakingEvents:   counts[models.SafetyEventHarshBraking],
+		AccelEvents:     counts[models.SafetyEventHarshAcceleration],
+		CorneringEvents: counts[models.SafetyEventHarshCornering],
+		SpeedingEvents:  counts[models.SafetyEventSpeeding],
+	}
+	var overall float64
+	for _, category := range scoreCategories {
+		exposure := km / 100
+		if category.perHour {
+			exposure = hours
+		}
+		sub := 100.0
+		if total := weighted[category.eventType]; total > 0 {
+			// With barely any driving recorded, events count as if over a tenth of a unit
+			rate := total / math.Max(exposure, minScoreExposure)
+			sub = 100 * math.Pow(0.5, rate/category.halfRate)
+		}
+		overall += sub * category.weight
+
+		switch category.eventType {
+		case models.SafetyEventHarshBraking:
+			score.BrakingScore = int(math.Round(sub))
+		case models.SafetyEventHarshAcceleration:
+			score.AccelScore = int(math.Round(sub))
+		case models.SafetyEventHarshCornering:
+			score.CorneringScore = int(math.Round(sub))
+		case models.SafetyEventSpeeding:
+			score.SpeedingScore = int(math.Round(sub))
+		}
+	}
+	score.OverallScore = int(math.Round(overall))
+	return score
+}
+
+// rankDriverScores sets fleet rank and percentile among drivers with enough driving to compare
+func rankDriverScores(scores []models.DriverScore) {
+	var ranked []*models.DriverScore
+	for i := range scores {
+		scores[i].FleetRank, scores[i].Percentile = nil, nil
+		if !scores[i].Provisional {
+			ranked = append(ranked, &scores[i])
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].OverallScore > ranked[j].OverallScore })
+
+	for i, score := range ranked {
+		score.FleetSize = len(ranked)
+		// Tied scores share the better rank
+		rank := i + 1
+		if i > 0 && ranked[i-1].OverallScore == score.OverallScore {
+			rank = *ranked[i-1].FleetRank
+		}
+		score.FleetRank = &rank
+		atOrBelow := 0
+		for _, other := range ranked {
+			if other.OverallScore <= score.OverallScore {
+				atOrBelow++
+			}
+		}
+		percentile := math.Round(float64(atOrBelow)/float64(len(ranked))*1000) / 10
+		score.Percentile = &percentile
+	}
+	for i := range scores {
+		scores[i].FleetSize = len(ranked)
+	}
+}
+
+func validScoreWindow(days int) bool {
+	for _, window := range DriverScoreWindows {
+		if window == days {
+			return true
+		}
+	}
+	return false
+}
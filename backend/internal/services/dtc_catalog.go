@@ -0,0 +1,361 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fleetflow/backend/internal/models"
+)
+
+// ErrInvalidDTC is returned for fault codes and DM1 payloads that cannot be decoded
+var ErrInvalidDTC = errors.New("invalid diagnostic trouble code")
+
+// DTCDefinition is what a fault code means and what to do about it
+type DTCDefinition struct {
+	Code              string                        `json:"code"`
+	Source            string                        `json:"source"`
+	SPN               *int                          `json:"spn,omitempty"`
+	FMI               *int                          `json:"fmi,omitempty"`
+	Description       string                        `json:"description"`
+	Severity          models.DiagnosticCodeSeverity `json:"severity"`
+	RecommendedAction string                        `json:"recommended_action,omitempty"`
+	Component         models.MaintenanceComponent   `json:"component"`
+	Catalogued        bool                          `json:"catalogued"` // False when only the code family is known
+}
+
+// obdiiCatalog holds common OBD-II codes
+var obdiiCatalog = map[string]DTCDefinition{
+	"P0087": {Description: "Fuel rail/system pressure too low", Severity: models.DTCSeverityHigh, RecommendedAction: "Check fuel filter, lift pump and rail pressure sensor", Component: models.ComponentFuelSystem},
+	"P0101": {Description: "Mass air flow sensor range/performance", Severity: models.DTCSeverityMedium, RecommendedAction: "Inspect air intake for leaks and clean or replace the MAF sensor", Component: models.ComponentFuelSystem},
+	"P0115": {Description: "Engine coolant temperature circuit malfunction", Severity: models.DTCSeverityMedium, RecommendedAction: "Check coolant temperature sensor and wiring", Component: models.ComponentCoolingSystem},
+	"P0117": {Description: "Engine coolant temperature circuit low", Severity: models.DTCSeverityMedium, RecommendedAction: "Check coolant temperature sensor and wiring", Component: models.ComponentCoolingSystem},
+	"P0118": {Description: "Engine coolant temperature circuit high", Severity: models.DTCSeverityMedium, RecommendedAction: "Check coolant temperature sensor and wiring", Component: models.ComponentCoolingSystem},
+	"P0128": {Description: "Coolant thermostat below regulating temperature", Severity: models.DTCSeverityLow, RecommendedAction: "Replace the thermostat at the next service", Component: models.ComponentCoolingSystem},
+	"P0171": {Description: "System too lean (bank 1)", Severity: models.DTCSeverityMedium, RecommendedAction: "Check for vacuum leaks, fuel pressure and injectors", Component: models.ComponentFuelSystem},
+	"P0172": {Description: "System too rich (bank 1)", Severity: models.DTCSeverityMedium, RecommendedAction: "Check injectors, fuel pressure regulator and O2 sensors", Component: models.ComponentFuelSystem},
+	"P0217": {Description: "Engine overheat condition", Severity: models.DTCSeverityCritical, RecommendedAction: "Stop the vehicle safely and inspect the cooling system before driving on", Component: models.ComponentCoolingSystem},
+	"P0300": {Description: "Random/multiple cylinder misfire detected", Severity: models.DTCSeverityHigh, RecommendedAction: "Inspect ignition, injectors and compression; avoid heavy loads", Component: models.ComponentEngine},
+	"P0301": {Description: "Cylinder 1 misfire detected", Severity: models.DTCSeverityHigh, RecommendedAction: "Inspect cylinder 1 ignition and injector", Component: models.ComponentEngine},
+	"P0335": {Description: "Crankshaft position sensor circuit malfunction", Severity: models.DTCSeverityHigh, RecommendedAction: "Replace crankshaft position sensor; engine may stall", Component: models.ComponentEngine},
+	"P0401": {Description: "Exhaust gas recirculation flow insufficient", Severity: models.DTCSeverityMedium, RecommendedAction: "Clean or replace the EGR valve and passages", Component: models.ComponentEmissions},
+	"P0420": {Description: "Catalyst system efficiency below threshold (bank 1)", Severity: models.DTCSeverityLow, RecommendedAction: "Inspect catalytic converter and downstream O2 sensor", Component: models.ComponentEmissions},
+	"P0480": {Description: "Cooling fan relay 1 control circuit", Severity: models.DTCSeverityHigh, RecommendedAction: "Check cooling fan relay and motor", Component: models.ComponentCoolingSystem},
+	"P0520": {Description: "Engine oil pressure sensor/switch circuit malfunction", Severity: models.DTCSeverityHigh, RecommendedAction: "Check oil level and pressure sensor before driving on", Component: models.ComponentEngine},
+	"P0524": {Description: "Engine oil pressure too low", Severity: models.DTCSeverityCritical, RecommendedAction: "Stop the engine immediately and check oil level and pressure", Component: models.ComponentEngine},
+	"P0562": {Description: "System voltage low", Severity: models.DTCSeverityMedium, RecommendedAction: "Test battery and alternator output", Component: models.ComponentBattery},
+	"P0563": {Description: "System voltage high", Severity: models.DTCSeverityMedium, RecommendedAction: "Test the voltage regulator", Component: models.ComponentBattery},
+	"P0620": {Description: "Generator control circuit malfunction", Severity: models.DTCSeverityHigh, RecommendedAction: "Test alternator and its control wiring", Component: models.ComponentBattery},
+	"P0700": {Description: "Transmission control system malfunction", Severity: models.DTCSeverityHigh, RecommendedAction: "Read transmission controller codes and inspect the gearbox", Component: models.ComponentTransmission},
+	"P0730": {Description: "Incorrect gear ratio", Severity: models.DTCSeverityHigh, RecommendedAction: "Check transmission fluid level and clutch packs", Component: models.ComponentTransmission},
+	"P2002": {Description: "Diesel particulate filter efficiency below threshold (bank 1)", Severity: models.DTCSeverityMedium, RecommendedAction: "Run a forced DPF regeneration and inspect the filter", Component: models.ComponentEmissions},
+	"C0035": {Description: "Left front wheel speed sensor circuit", Severity: models.DTCSeverityHigh, RecommendedAction: "Inspect wheel speed sensor and ABS wiring; ABS may be disabled", Component: models.ComponentBrakes},
+	"C0265": {Description: "ABS actuator relay circuit open", Severity: models.DTCSeverityHigh, RecommendedAction: "Inspect ABS module relay; ABS is disabled", Component: models.ComponentBrakes},
+	"B1000": {Description: "Body control module internal fault", Severity: models.DTCSeverityLow, RecommendedAction: "Read body module codes at the next service", Component: models.ComponentElectrical},
+	"U0100": {Description: "Lost communication with engine control module", Severity: models.DTCSeverityCritical, RecommendedAction: "Inspect CAN bus wiring and ECM power; engine may shut down", Component: models.ComponentElectrical},
+	"U0101": {Description: "Lost communication with transmission control module", Severity: models.DTCSeverityHigh, RecommendedAction: "Inspect CAN bus wiring and TCM power", Component: models.ComponentElectrical},
+}
+
+// j1939SPNCatalog holds common J1939 suspect parameters
+var j1939SPNCatalog = map[int]DTCDefinition{
+	84:   {Description: "Wheel-based vehicle speed", Severity: models.DTCSeverityMedium, RecommendedAction: "Inspect wheel speed and tachograph sensors", Component: models.ComponentElectrical},
+	91:   {Description: "Accelerator pedal position", Severity: models.DTCSeverityHigh, RecommendedAction: "Inspect accelerator pedal sensor; engine may be limited to idle", Component: models.ComponentEngine},
+	94:   {Description: "Engine fuel delivery pressure", Severity: models.DTCSeverityHigh, RecommendedAction: "Replace fuel filters and check the lift pump", Component: models.ComponentFuelSystem},
+	97:   {Description: "Water in fuel indicator", Severity: models.DTCSeverityMedium, RecommendedAction: "Drain the water separator", Component: models.ComponentFuelSystem},
+	100:  {Description: "Engine oil pressure", Severity: models.DTCSeverityCritical, RecommendedAction: "Stop the engine and check oil level and pressure", Component: models.ComponentEngine},
+	102:  {Description: "Engine intake manifold boost pressure", Severity: models.DTCSeverityMedium, RecommendedAction: "Inspect turbocharger, intercooler and boost hoses", Component: models.ComponentEngine},
+	105:  {Description: "Engine intake manifold temperature", Severity: models.DTCSeverityMedium, RecommendedAction: "Inspect intercooler and intake temperature sensor", Component: models.ComponentCoolingSystem},
+	110:  {Description: "Engine coolant temperature", Severity: models.DTCSeverityHigh, RecommendedAction: "Check coolant level, fan and thermostat", Component: models.ComponentCoolingSystem},
+	111:  {Description: "Engine coolant level", Severity: models.DTCSeverityHigh, RecommendedAction: "Top up coolant and check for leaks", Component: models.ComponentCoolingSystem},
+	158:  {Description: "Keyswitch battery potential", Severity: models.DTCSeverityMedium, RecommendedAction: "Inspect ignition feed and battery terminals", Component: models.ComponentBattery},
+	168:  {Description: "Battery potential / power input", Severity: models.DTCSeverityMedium, RecommendedAction: "Test battery and alternator output", Component: models.ComponentBattery},
+	175:  {Description: "Engine oil temperature", Severity: models.DTCSeverityHigh, RecommendedAction: "Check oil level and oil cooler", Component: models.ComponentEngine},
+	190:  {Description: "Engine speed", Severity: models.DTCSeverityHigh, RecommendedAction: "Inspect crankshaft and camshaft speed sensors", Component: models.ComponentEngine},
+	521:  {Description: "Brake pedal position", Severity: models.DTCSeverityHigh, RecommendedAction: "Inspect brake pedal sensor and switches", Component: models.ComponentBrakes},
+	629:  {Description: "Controller #1 internal fault", Severity: models.DTCSeverityCritical, RecommendedAction: "Have the engine controller diagnosed before further use", Component: models.ComponentElectrical},
+	639:  {Description: "J1939 network #1 (CAN bus)", Severity: models.DTCSeverityHigh, RecommendedAction: "Inspect CAN bus wiring and terminating resistors", Component: models.ComponentElectrical},
+	1761: {Description: "Aftertreatment diesel exhaust fluid tank level", Severity: models.DTCSeverityMedium, RecommendedAction: "Refill diesel exhaust fluid; engine will derate when empty", Component: models.ComponentEmissions},
+	3226: {Description: "Aftertreatment outlet NOx", Severity: models.DTCSeverityMedium, RecommendedAction: "Inspect SCR system and NOx sensor", Component: models.ComponentEmissions},
+	3251: {Description: "Aftertreatment DPF differential pressure", Severity: models.DTCSeverityMedium, RecommendedAction: "Run a forced DPF regeneration and inspect the filter", Component: models.ComponentEmissions},
+	4364: {Description: "Aftertreatment SCR conversion efficiency", Severity: models.DTCSeverityMedium, RecommendedAction: "Check DEF quality and the SCR catalyst", Component: models.ComponentEmissions},
+}
+
+// j1939FMIDescriptions are the standard J1939-73 failure modes
+var j1939FMIDescriptions = map[int]string{
+	0:  "data valid but above normal operating range, most severe level",
+	1:  "data valid but below normal operating range, most severe level",
+	2:  "data erratic, intermittent or incorrect",
+	3:  "voltage above normal or shorted to high source",
+	4:  "voltage below normal or shorted to low source",
+	5:  "current below normal or open circuit",
+	6:  "current above normal or grounded circuit",
+	7:  "mechanical system not responding or out of adjustment",
+	8:  "abnormal frequency, pulse width or period",
+	9:  "abnormal update rate",
+	10: "abnormal rate of change",
+	11: "root cause not known",
+	12: "bad intelligent device or component",
+	13: "out of calibration",
+	14: "special instructions",
+	15: "data valid but above normal operating range, least severe level",
+	16: "data valid but above normal operating range, moderately severe level",
+	17: "data valid but below normal operating range, least severe level",
+	18: "data valid but below normal operating range, moderately severe level",
+	19: "received network data in error",
+	20: "data drifted high",
+	21: "data drifted low",
+	31: "condition exists",
+}
+
+// obdiiFamilies describe uncatalogued OBD-II codes by their first two characters
+var obdiiFamilies = map[string]DTCDefinition{
+	"P0": {Description: "Generic powertrain fault", Severity: models.DTCSeverityMedium},
+	"P1": {Description: "Manufacturer-specific powertrain fault", Severity: models.DTCSeverityMedium},
+	"P2": {Description: "Generic powertrain fault", Severity: models.DTCSeverityMedium},
+	"P3": {Description: "Powertrain fault", Severity: models.DTCSeverityMedium},
+	"C0": {Description: "Generic chassis fault", Severity: models.DTCSeverityMedium},
+	"C1": {Description: "Manufacturer-specific chassis fault", Severity: models.DTCSeverityMedium},
+	"B0": {Description: "Generic body fault", Severity: models.DTCSeverityLow},
+	"B1": {Description: "Manufacturer-specific body fault", Severity: models.DTCSeverityLow},
+	"U0": {Description: "Generic network communication fault", Severity: models.DTCSeverityHigh},
+	"U1": {Description: "Manufacturer-specific network communication fault", Severity: models.DTCSeverityMedium},
+}
+
+var (
+	obdiiCodePattern = regexp.MustCompile(`^[PCBU][0-3][0-9A-F]{3}$`)
+	j1939CodePattern = regexp.MustCompile(`^(?:SPN)?[\s:]*(\d{1,6})[\s]*(?:[-/:,]|FMI)[\s:-]*(?:FMI)?[\s:]*(\d{1,2})$`)
+	j1939SPNPattern  = regexp.MustCompile(`^SPN[\s:]*(\d{1,6})$`)
+)
+
+// j1939Code formats an SPN and FMI as the stored code
+func j1939Code(spn, fmi int) string {
+	return fmt.Sprintf("SPN%d-FMI%d", spn, fmi)
+}
+
+// ParseDTC normalises a reported fault code. OBD-II codes look like "P0300"; J1939 codes may be
+// given as "SPN110-FMI0", "SPN 110 FMI 0" or "110/0".
+func ParseDTC(raw string) (code, source string, spn, fmi *int, err error) {
+	normalized := strings.ToUpper(strings.TrimSpace(raw))
+	if obdiiCodePattern.MatchString(normalized) {
+		return normalized, models.DTCSourceOBDII, nil, nil, nil
+	}
+	if match := j1939CodePattern.FindStringSubmatch(normalized); match != nil {
+		s, _ := strconv.Atoi(match[1])
+		f, _ := strconv.Atoi(match[2])
+		if s > 0x7FFFF || f > 31 {
+			return "", "", nil, nil, fmt.Errorf("%w: %q is out of range", ErrInvalidDTC, raw)
+		}
+		return j1939Code(s, f), models.DTCSourceJ1939, &s, &f, nil
+	}
+	return "", "", nil, nil, fmt.Errorf("%w: %q", ErrInvalidDTC, raw)
+}
+
+// J1939Lamps is the lamp status at the start of a DM1 message
+type J1939Lamps struct {
+	MalfunctionIndicator bool `json:"malfunction_indicator"`
+	RedStop              bool `json:"red_stop"`
+	AmberWarning         bool `json:"amber_warning"`
+	Protect              bool `json:"protect"`
+}
+
+// J1939DTC is one fault in a DM1 message
+type J1939DTC struct {
+	Code            string `json:"code"`
+	SPN             int    `json:"spn"`
+	FMI             int    `json:"fmi"`
+	OccurrenceCount int    `json:"occurrence_count"`
+	Raw             string `json:"raw"` // The four DTC bytes in hex
+}
+
+// DM1Message is a decoded J1939 DM1 (active diagnostic trouble codes) payload
+type DM1Message struct {
+	Lamps J1939Lamps `json:"lamps"`
+	DTCs  []J1939DTC `json:"dtcs"`
+}
+
+// DecodeDM1 decodes a hex DM1 payload: two lamp status bytes followed by four bytes per fault
+// (SPN low 16 bits, SPN high 3 bits with FMI, conversion method with occurrence count).
+// Frames are padded with 0xFF to at least eight bytes, so a trailing partial group and all-0xFF
+// groups are padding. A single all-zero fault means no faults are active.
+func DecodeDM1(payload string) (*DM1Message, error) {
+	cleaned := strings.NewReplacer(" ", "", ":", "", "-", "").Replace(strings.TrimSpace(payload))
+	cleaned = strings.TrimPrefix(strings.TrimPrefix(cleaned, "0x"), "0X")
+	data, err := hex.DecodeString(cleaned)
+	if err != nil {
+		return nil, fmt.Errorf("%w: DM1 payload is not hex: %v", ErrInvalidDTC, err)
+	}
+	if len(data) < 2 {
+		return nil, fmt.Errorf("%w: DM1 payload has %d bytes", ErrInvalidDTC, len(data))
+	}
+
+	lamp := func(shift uint) bool { return (data[0]>>shift)&0x03 == 0x01 }
+	message := &DM1Message{
+		Lamps: J1939Lamps{
+			MalfunctionIndicator: lamp(6),
+			RedStop:              lamp(4),
+			AmberWarning:         lamp(2),
+			Protect:              lamp(0),
+		},
+		DTCs: []J1939DTC{},
+	}
+
+	for offset := 2; offset+4 <= len(data); offset += 4 {
+		b := data[offset : offset+4]
+		if b[0] == 0xFF && b[1] == 0xFF && b[2] == 0xFF && b[3] == 0xFF {
+			continue
+		}
+		spn := int(b[0]) | int(b[1])<<8 | int(b[2]&0xE0)<<11
+		fmi := int(b[2] & 0x1F)
+		if spn == 0 && fmi == 0 {
+			continue
+		}
+		message.DTCs = append(message.DTCs, J1939DTC{
+			Code:            j1939Code(spn, fmi),
+			SPN:             spn,
+			FMI:             fmi,
+			OccurrenceCount: int(b[3] & 0x7F),
+			Raw:             strings.ToUpper(hex.EncodeToString(b)),
+		})
+	}
+	return message, nil
+}
+
+// raiseDTCSeverity returns the more severe of two severities
+func raiseDTCSeverity(current, floor models.DiagnosticCodeSeverity) models.DiagnosticCodeSeverity {
+	if dtcSeverityWeight[floor] > dtcSeverityWeight[current] {
+		return floor
+	}
+	return current
+}
+
+// LookupDTC describes a fault code from the catalogue, falling back to the built-in definitions
+// and then to the code family
+func (s *TelemetryService) LookupDTC(ctx context.Context, raw string) (*DTCDefinition, error) {
+	code, source, spn, fmi, err := ParseDTC(raw)
+	if err != nil {
+		return nil, err
+	}
+	return s.lookupDTC(code, source, spn, fmi)
+}
+
+func (s *TelemetryService) lookupDTC(code, source string, spn, fmi *int) (*DTCDefinition, error) {
+	keys := []string{code}
+	if source == models.DTCSourceJ1939 {
+		keys = append(keys, fmt.Sprintf("SPN%d", *spn))
+	}
+	var entries []models.DTCCatalogEntry
+	if err := s.db.Where("code IN ?", keys).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	// An entry for the exact code wins over one for the whole SPN
+	definition, found, exact := DTCDefinition{}, false, false
+	for i, key := range keys {
+		for _, entry := range entries {
+			if entry.Code == key && !found {
+				definition = DTCDefinition{
+					Description:       entry.Description,
+					Severity:          entry.Severity,
+					RecommendedAction: entry.RecommendedAction,
+					Component:         entry.Component,
+				}
+				found, exact = true, i == 0
+			}
+		}
+	}
+
+	if !found {
+		switch source {
+		case models.DTCSourceOBDII:
+			definition, found = obdiiCatalog[code]
+			if !found {
+				definition = obdiiFamilies[code[:2]]
+			}
+		case models.DTCSourceJ1939:
+			definition, found = j1939SPNCatalog[*spn]
+			if !found {
+				definition = DTCDefinition{Description: fmt.Sprintf("SPN %d", *spn), Severity: models.DTCSeverityMedium}
+			}
+		}
+	}
+
+	definition.Code, definition.Source, definition.SPN, definition.FMI = code, source, spn, fmi
+	definition.Catalogued = found
+	if definition.Component == "" {
+		definition.Component = dtcComponent(code)
+	}
+	if source == models.DTCSourceJ1939 && !exact {
+		if mode, ok := j1939FMIDescriptions[*fmi]; ok {
+			definition.Description = fmt.Sprintf("%s: %s", definition.Description, mode)
+		}
+		// The most severe out-of-range failure modes are one step more serious than the parameter
+		if *fmi == 0 || *fmi == 1 {
+			definition.Severity = escalateDTCSeverity(definition.Severity)
+		}
+	}
+	return &definition, nil
+}
+
+// escalateDTCSeverity returns the next severity up
+func escalateDTCSeverity(severity models.DiagnosticCodeSeverity) models.DiagnosticCodeSeverity {
+	switch severity {
+	case models.DTCSeverityLow:
+		return models.DTCSeverityMedium
+	case models.DTCSeverityMedium:
+		return models.DTCSeverityHigh
+	default:
+		return models.DTCSeverityCritical
+	}
+}
+
+// SaveDTCCatalogEntry creates or replaces a catalogue entry
+func (s *TelemetryService) SaveDTCCatalogEntry(ctx context.Context, entry *models.DTCCatalogEntry) (*models.DTCCatalogEntry, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(entry.Code))
+	if match := j1939SPNPattern.FindStringSubmatch(normalized); match != nil {
+		spn, _ := strconv.Atoi(match[1])
+		entry.Code, entry.Source, entry.SPN, entry.FMI = fmt.Sprintf("SPN%d", spn), models.DTCSourceJ1939, &spn, nil
+	} else {
+		code, source, spn, fmi, err := ParseDTC(normalized)
+		if err != nil {
+			return nil, err
+		}
+		entry.Code, entry.Source, entry.SPN, entry.FMI = code, source, spn, fmi
+	}
+	if _, ok := dtcSeverityWeight[entry.Severity]; !ok {
+		return nil, fmt.Errorf("%w: unknown severity %q", ErrInvalidDTC, entry.Severity)
+	}
+
+	var existing []models.DTCCatalogEntry
+	if err := s.db.Where("code = ?", entry.Code).Limit(1).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		entry.ID = existing[0].ID
+		entry.CreatedAt = existing[0].CreatedAt
+	}
+	if err := s.db.Save(entry).Error; err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// GetDTCCatalog lists catalogue entries, optionally for one source or matching a code or description
+func (s *TelemetryService) GetDTCCatalog(ctx context.Context, source, search string) ([]models.DTCCatalogEntry, error) {
+	query := s.db.Model(&models.DTCCatalogEntry{})
+	if source != "" {
+		query = query.Where("source = ?", strings.ToUpper(source))
+	}
+	if search != "" {
+		like := "%" + strings.ToLower(search) + "%"
+		query = query.Where("LOWER(code) LIKE ? OR LOWER(description) LIKE ?", like, like)
+	}
+	var entries []models.DTCCatalogEntry
+	if err := query.Order("code").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,464 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidDVIR is returned for inspection reports, defects and checklists that cannot be accepted
+	ErrInvalidDVIR = errors.New("invalid inspection report")
+	// ErrDefectsNotAcknowledged is returned when a pre-trip report leaves certified defects unreviewed
+	ErrDefectsNotAcknowledged = errors.New("certified defects must be acknowledged")
+	// ErrVehicleOutOfService is returned when a vehicle with an uncertified out-of-service defect is dispatched
+	ErrVehicleOutOfService = errors.New("vehicle is out of service")
+)
+
+// SaveInspectionChecklist creates or replaces the checklist of a vehicle type. Items are matched
+// by code so their IDs survive edits; items no longer listed are removed.
+func (s *MaintenanceService) SaveInspectionChecklist(ctx context.Context, checklist *models.InspectionChecklist) (*models.InspectionChecklist, error) {
+	codes := make(map[string]bool, len(checklist.Items))
+	for i := range checklist.Items {
+		item := &checklist.Items[i]
+		item.Code = strings.ToUpper(strings.TrimSpace(item.Code))
+		if codes[item.Code] {
+			return nil, fmt.Errorf("%w: duplicate checklist item %s", ErrInvalidDVIR, item.Code)
+		}
+		codes[item.Code] = true
+		if item.Severity == "" {
+			item.Severity = models.DefectSeverityMinor
+		}
+		item.SortOrder = i + 1
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []models.InspectionChecklist
+		if err := tx.Where("vehicle_type = ?", checklist.VehicleType).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			checklist.ID = existing[0].ID
+			checklist.CreatedAt = existing[0].CreatedAt
+			checklist.CreatedBy = existing[0].CreatedBy
+		}
+		if err := tx.Omit("Items").Save(checklist).Error; err != nil {
+			return err
+		}
+
+		var current []models.InspectionChecklistItem
+		if err := tx.Where("checklist_id = ?", checklist.ID).Find(&current).Error; err != nil {
+			return err
+		}
+		byCode := make(map[string]models.InspectionChecklistItem, len(current))
+		for _, item := range current {
+			if !codes[item.Code] {
+				if err := tx.Delete(&item).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			byCode[item.Code] = item
+		}
+		for i := range checklist.Items {
+			item := &checklist.Items[i]
+			item.ChecklistID = checklist.ID
+			if previous, ok := byCode[item.Code]; ok {
+				item.ID = previous.ID
+				item.CreatedAt = previous.CreatedAt
+			}
+			if err := tx.Save(item).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return checklist, nil
+}
+
+// GetInspectionChecklists lists checklists, optionally for one vehicle type
+func (s *MaintenanceService) GetInspectionChecklists(ctx context.Context, vehicleType string) ([]models.InspectionChecklist, error) {
+	query := s.db.WithContext(ctx).Model(&models.InspectionChecklist{})
+	if vehicleType != "" {
+		query = query.Where("vehicle_type = ?", strings.ToUpper(vehicleType))
+	}
+	var checklists []models.InspectionChecklist
+	if err := query.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order") }).
+		Order("vehicle_type").Find(&checklists).Error; err != nil {
+		return nil, err
+	}
+	return checklists, nil
+}
+
+// GetVehicleChecklist returns the active checklist for a vehicle's type
+func (s *MaintenanceService) GetVehicleChecklist(ctx context.Context, vehicleID uint) (*models.InspectionChecklist, error) {
+	db := s.db.WithContext(ctx)
+	var vehicle models.Vehicle
+	if err := db.First(&vehicle, vehicleID).Error; err != nil {
+		return nil, err
+	}
+	return s.vehicleChecklist(db, vehicle.VehicleType)
+}
+
+// vehicleChecklist returns the active checklist for a vehicle type, or gorm.ErrRecordNotFound
+func (s *MaintenanceService) vehicleChecklist(tx *gorm.DB, vehicleType models.VehicleType) (*models.InspectionChecklist, error) {
+	var checklist models.InspectionChecklist
+	if err := tx.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order") }).
+		Where("vehicle_type = ? AND is_active = ?", vehicleType, true).First(&checklist).Error; err != nil {
+		return nil, err
+	}
+	return &checklist, nil
+}
+
+// SubmitDVIR records a driver vehicle inspection report and a defect record for each defect found.
+// Defects are linked to one new work order; an out-of-service defect takes the vehicle off the road.
+// A pre-trip report must acknowledge every defect certified since the vehicle was last inspected.
+func (s *MaintenanceService) SubmitDVIR(ctx context.Context, dvir *models.DVIR, acknowledgedDefectIDs []uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var vehicle models.Vehicle
+		if err := tx.First(&vehicle, dvir.VehicleID).Error; err != nil {
+			return err
+		}
+
+		// Legacy reports carry a JSON array of descriptions only
+		if len(dvir.DefectRecords) == 0 && strings.TrimSpace(dvir.Defects) != "" {
+			var descriptions []string
+			if err := json.Unmarshal([]byte(dvir.Defects), &descriptions); err != nil {
+				return fmt.Errorf("%w: failed to parse defects: %v", ErrInvalidDVIR, err)
+			}
+			severity := models.DefectSeverityMajor
+			if dvir.Status == models.DVIRStatusUnsafe {
+				severity = models.DefectSeverityOutOfService
+			}
+			for _, description := range descriptions {
+				dvir.DefectRecords = append(dvir.DefectRecords, models.DVIRDefect{Description: description, Severity: severity})
+			}
+		}
+
+		if err := s.prepareDefects(tx, dvir, vehicle.VehicleType); err != nil {
+			return err
+		}
+
+		var pending []models.DVIRDefect
+		if dvir.Type == models.DVIRTypePreTrip {
+			var err error
+			if pending, err = s.pendingAcknowledgements(tx, dvir.VehicleID, acknowledgedDefectIDs); err != nil {
+				return err
+			}
+		} else if len(acknowledgedDefectIDs) > 0 {
+			return fmt.Errorf("%w: defects are acknowledged on a pre-trip inspection", ErrInvalidDVIR)
+		}
+
+		// The report's outcome follows its most serious defect
+		descriptions := make([]string, 0, len(dvir.DefectRecords))
+		dvir.Status = models.DVIRStatusSafe
+		for _, defect := range dvir.DefectRecords {
+			descriptions = append(descriptions, defect.Description)
+			if defect.Severity == models.DefectSeverityOutOfService {
+				dvir.Status = models.DVIRStatusUnsafe
+			} else if dvir.Status == models.DVIRStatusSafe {
+				dvir.Status = models.DVIRStatusRepairsNeeded
+			}
+		}
+		summary, err := json.Marshal(descriptions)
+		if err != nil {
+			return err
+		}
+		dvir.Defects = string(summary)
+		if dvir.SignedAt.IsZero() {
+			dvir.SignedAt = time.Now()
+		}
+
+		defects := dvir.DefectRecords
+		if err := tx.Omit("Vehicle", "Driver", "DefectRecords").Create(dvir).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for i := range pending {
+			if err := tx.Model(&pending[i]).Updates(map[string]interface{}{
+				"status":               models.DVIRDefectAcknowledged,
+				"acknowledged_by":      dvir.DriverID,
+				"acknowledged_dvir_id": dvir.ID,
+				"acknowledged_at":      now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(defects) == 0 {
+			return nil
+		}
+
+		if dvir.Status == models.DVIRStatusUnsafe {
+			if err := tx.Model(&models.Vehicle{}).Where("id = ?", dvir.VehicleID).
+				Update("status", models.VehicleStatusMaintenance).Error; err != nil {
+				return err
+			}
+		}
+
+		workOrder := models.WorkOrder{
+			VehicleID:   dvir.VehicleID,
+			Description: fmt.Sprintf("Defects reported in DVIR #%d: %s", dvir.ID, strings.Join(descriptions, "; ")),
+			Status:      "OPEN",
+			Priority:    "HIGH",
+		}
+		if err := tx.Omit("Vehicle", "MaintenanceTask", "Parts", "Labor").Create(&workOrder).Error; err != nil {
+			return err
+		}
+
+		for i := range defects {
+			defect := &defects[i]
+			defect.DVIRID = dvir.ID
+			defect.VehicleID = dvir.VehicleID
+			defect.Status = models.DVIRDefectOpen
+			defect.WorkOrderID = &workOrder.ID
+			if err := tx.Omit("Photos").Create(defect).Error; err != nil {
+				return err
+			}
+			if err := attachDefectPhotos(tx, defect, defect.PhotoUploadIDs); err != nil {
+				return err
+			}
+		}
+		dvir.DefectRecords = defects
+		return nil
+	})
+}
+
+// prepareDefects checks the reported defects against the vehicle type's checklist, filling in
+// category, description and the least severity from the checklist item
+func (s *MaintenanceService) prepareDefects(tx *gorm.DB, dvir *models.DVIR, vehicleType models.VehicleType) error {
+	checklist, err := s.vehicleChecklist(tx, vehicleType)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	items := make(map[uint]models.InspectionChecklistItem)
+	if checklist != nil {
+		dvir.ChecklistID = &checklist.ID
+		for _, item := range checklist.Items {
+			items[item.ID] = item
+		}
+	}
+
+	for i := range dvir.DefectRecords {
+		defect := &dvir.DefectRecords[i]
+		if defect.ChecklistItemID != nil {
+			item, ok := items[*defect.ChecklistItemID]
+			if !ok {
+				return fmt.Errorf("%w: checklist item %d is not on this vehicle's checklist", ErrInvalidDVIR, *defect.ChecklistItemID)
+			}
+			if defect.Category == "" {
+				defect.Category = item.Category
+			}
+			if defect.Description == "" {
+				defect.Description = item.Label
+			}
+			if defect.Severity.Rank() < item.Severity.Rank() {
+				defect.Severity = item.Severity
+			}
+		}
+		if strings.TrimSpace(defect.Description) == "" {
+			return fmt.Errorf("%w: defect %d needs a description or checklist item", ErrInvalidDVIR, i+1)
+		}
+		if defect.Severity == "" {
+			defect.Severity = models.DefectSeverityMinor
+		}
+	}
+	return nil
+}
+
+// pendingAcknowledgements returns the vehicle's certified defects, failing unless every one of
+// them is among the acknowledged IDs
+func (s *MaintenanceService) pendingAcknowledgements(tx *gorm.DB, vehicleID uint, acknowledged []uint) ([]models.DVIRDefect, error) {
+	var pending []models.DVIRDefect
+	if err := tx.Where("vehicle_id = ? AND status = ?", vehicleID, models.DVIRDefectCertified).
+		Order("id").Find(&pending).Error; err != nil {
+		return nil, err
+	}
+
+	given := make(map[uint]bool, len(acknowledged))
+	for _, id := range acknowledged {
+		given[id] = true
+	}
+	var missing []string
+	for _, defect := range pending {
+		if !given[defect.ID] {
+			missing = append(missing, fmt.Sprintf("%d", defect.ID))
+		}
+		delete(given, defect.ID)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: defects %s", ErrDefectsNotAcknowledged, strings.Join(missing, ", "))
+	}
+	for id := range given {
+		return nil, fmt.Errorf("%w: defect %d is not awaiting acknowledgement on this vehicle", ErrInvalidDVIR, id)
+	}
+	return pending, nil
+}
+
+// attachDefectPhotos links uploaded photos to a defect
+func attachDefectPhotos(tx *gorm.DB, defect *models.DVIRDefect, uploadIDs []uint) error {
+	if len(uploadIDs) == 0 {
+		return nil
+	}
+	var uploads []models.Upload
+	if err := tx.Where("id IN ?", uploadIDs).Find(&uploads).Error; err != nil {
+		return err
+	}
+	if len(uploads) != len(uploadIDs) {
+		return fmt.Errorf("%w: photo uploads not found", ErrInvalidDVIR)
+	}
+	for _, upload := range uploads {
+		if !upload.IsImage() {
+			return fmt.Errorf("%w: upload %d is not an image", ErrInvalidDVIR, upload.ID)
+		}
+		if upload.DVIRDefectID != nil && *upload.DVIRDefectID != defect.ID {
+			return fmt.Errorf("%w: upload %d belongs to another defect", ErrInvalidDVIR, upload.ID)
+		}
+	}
+	return tx.Model(&models.Upload{}).Where("id IN ?", uploadIDs).Updates(map[string]interface{}{
+		"dvir_defect_id": defect.ID,
+		"vehicle_id":     defect.VehicleID,
+	}).Error
+}
+
+// GetDVIR returns an inspection report with its defects and their photos
+func (s *MaintenanceService) GetDVIR(ctx context.Context, dvirID uint) (*models.DVIR, error) {
+	var dvir models.DVIR
+	if err := s.db.WithContext(ctx).Preload("DefectRecords", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("DefectRecords.Photos").First(&dvir, dvirID).Error; err != nil {
+		return nil, err
+	}
+	return &dvir, nil
+}
+
+// GetVehicleDefects lists a vehicle's defects. By default only those still open or awaiting
+// the next driver's acknowledgement are returned; "ALL" returns every defect.
+func (s *MaintenanceService) GetVehicleDefects(ctx context.Context, vehicleID uint, status string) ([]models.DVIRDefect, error) {
+	query := s.db.WithContext(ctx).Where("vehicle_id = ?", vehicleID)
+	switch strings.ToUpper(status) {
+	case "":
+		query = query.Where("status IN ?", []models.DVIRDefectStatus{models.DVIRDefectOpen, models.DVIRDefectCertified})
+	case "ALL":
+	default:
+		query = query.Where("status = ?", strings.ToUpper(status))
+	}
+	var defects []models.DVIRDefect
+	if err := query.Preload("Photos").Order("created_at DESC, id DESC").Find(&defects).Error; err != nil {
+		return nil, err
+	}
+	return defects, nil
+}
+
+// CertifyDefect records a mechanic's certification that a defect was corrected or needs no repair.
+// Once all of a report's defects are certified the report carries the mechanic's signature.
+func (s *MaintenanceService) CertifyDefect(ctx context.Context, defectID, mechanicID uint, resolution models.DVIRDefectResolution, notes, signature string) (*models.DVIRDefect, error) {
+	var defect models.DVIRDefect
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&defect, defectID).Error; err != nil {
+			return err
+		}
+		if defect.Status != models.DVIRDefectOpen {
+			return fmt.Errorf("%w: defect %d is already %s", ErrInvalidDVIR, defect.ID, defect.Status)
+		}
+
+		now := time.Now()
+		defect.Status = models.DVIRDefectCertified
+		defect.Resolution = resolution
+		defect.RepairNotes = notes
+		defect.CertifiedBy = &mechanicID
+		defect.MechanicSign = signature
+		defect.CertifiedAt = &now
+		if err := tx.Omit("Photos").Save(&defect).Error; err != nil {
+			return err
+		}
+
+		var open int64
+		if err := tx.Model(&models.DVIRDefect{}).Where("dvir_id = ? AND status = ?", defect.DVIRID, models.DVIRDefectOpen).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return nil
+		}
+		return tx.Model(&models.DVIR{}).Where("id = ?", defect.DVIRID).Update("mechanic_sign", signature).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &defect, nil
+}
+
+// LinkDefectWorkOrder moves a defect onto another open work order of the same vehicle
+func (s *MaintenanceService) LinkDefectWorkOrder(ctx context.Context, defectID, workOrderID uint) (*models.DVIRDefect, error) {
+	db := s.db.WithContext(ctx)
+	var defect models.DVIRDefect
+	if err := db.First(&defect, defectID).Error; err != nil {
+		return nil, err
+	}
+	workOrder, err := openWorkOrder(db, workOrderID)
+	if err != nil {
+		return nil, err
+	}
+	if workOrder.VehicleID != defect.VehicleID {
+		return nil, fmt.Errorf("%w: work order %d is for another vehicle", ErrInvalidDVIR, workOrder.ID)
+	}
+	if err := db.Model(&defect).Update("work_order_id", workOrder.ID).Error; err != nil {
+		return nil, err
+	}
+	return &defect, nil
+}
+
+// AttachDefectPhotos links further uploaded photos to a defect
+func (s *MaintenanceService) AttachDefectPhotos(ctx context.Context, defectID uint, uploadIDs []uint) (*models.DVIRDefect, error) {
+	var defect models.DVIRDefect
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&defect, defectID).Error; err != nil {
+			return err
+		}
+		if err := attachDefectPhotos(tx, &defect, uploadIDs); err != nil {
+			return err
+		}
+		return tx.Preload("Photos").First(&defect, defectID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &defect, nil
+}
+
+// OutOfServiceDefects returns a vehicle's out-of-service defects that no mechanic has certified
+func (s *MaintenanceService) OutOfServiceDefects(ctx context.Context, vehicleID uint) ([]models.DVIRDefect, error) {
+	var defects []models.DVIRDefect
+	if err := s.db.WithContext(ctx).Where("vehicle_id = ? AND severity = ? AND status = ?", vehicleID,
+		models.DefectSeverityOutOfService, models.DVIRDefectOpen).Order("id").Find(&defects).Error; err != nil {
+		return nil, err
+	}
+	return defects, nil
+}
+
+// CheckVehicleInService fails with ErrVehicleOutOfService while the vehicle has an uncertified
+// out-of-service defect
+func (s *MaintenanceService) CheckVehicleInService(ctx context.Context, vehicleID uint) error {
+	defects, err := s.OutOfServiceDefects(ctx, vehicleID)
+	if err != nil {
+		return err
+	}
+	if len(defects) == 0 {
+		return nil
+	}
+	descriptions := make([]string, 0, len(defects))
+	for _, defect := range defects {
+		descriptions = append(descriptions, fmt.Sprintf("#%d %s", defect.ID, defect.Description))
+	}
+	return fmt.Errorf("%w: unresolved defects %s", ErrVehicleOutOfService, strings.Join(descriptions, "; "))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"sync"
 	"time"
 
 	"github.com/fleetflow/backend/internal/models"
@@ -14,6 +15,10 @@ import (
 type FuelService struct {
 	db           *gorm.DB
 	auditService *AuditService
+	notifications *NotificationService // Optional; escalated alerts are only logged without it
+
+	modelMu       sync.RWMutex
+	anomalyModels map[models.VehicleType]*fuelAnomalyModel // Decoded active models by vehicle class
 }
 
 // NewFuelService creates a new fuel service
@@ -69,6 +74,16 @@ func (s *FuelService) CreateFuelEvent(event *models.FuelEvent) (*models.FuelEven
 			nil, nil, nil)
 	}
 
+	// A receipt entered after the tank sensor saw the refuel reconciles it now
+	if err := s.reconcileReceipt(event); err != nil {
+		log.Printf("⚠️ Refuel reconciliation failed for fuel event %d: %v", event.ID, err)
+	}
+
+	// Apply the vehicle's consumption and refuel-frequency thresholds
+	if _, err := s.EvaluateFuelEventRules(event); err != nil {
+		log.Printf("⚠️ Fuel threshold evaluation failed for event %d: %v", event.ID, err)
+	}
+
 	// Log warnings if any
 	for _, warning := range warnings {
 		log.Printf("⚠️ Fuel event %d warning: %s", event.ID, warning)
@@ -141,6 +156,39 @@ func (s *FuelService) GetFuelEvents(page, limit int, filters map[string]interfac
 	return events, total, nil
 }
 
+// GetFuelAlerts gets paginated fuel alerts
+func (s *FuelService) GetFuelAlerts(page, limit int, filters map[string]interface{}) ([]models.FuelAlert, int64, error) {
+	var alerts []models.FuelAlert
+	var total int64
+
+	query := s.db.Model(&models.FuelAlert{})
+
+	// Apply filters
+	if vehicleID, ok := filters["vehicle_id"].(uint); ok && vehicleID > 0 {
+		query = query.Where("vehicle_id = ?", vehicleID)
+	}
+	if alertType, ok := filters["alert_type"].(string); ok && alertType != "" {
+		query = query.Where("alert_type = ?", alertType)
+	}
+	if severity, ok := filters["severity"].(string); ok && severity != "" {
+		query = query.Where("severity = ?", severity)
+	}
+	if isResolved, ok := filters["is_resolved"].(bool); ok {
+		query = query.Where("is_resolved = ?", isResolved)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Preload("Vehicle").Order("detected_at DESC").Offset(offset).Limit(limit).Find(&alerts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return alerts, total, nil
+}
+
 // VerifyFuelEvent verifies a fuel event
 func (s *FuelService) VerifyFuelEvent(eventID, verifierID uint, notes string) error {
 	now := time.Now()
@@ -163,6 +211,12 @@ func (s *FuelService) VerifyFuelEvent(eventID, verifierID uint, notes string) er
 		fmt.Sprintf("Fuel event %d verified by user %d: %s", eventID, verifierID, notes),
 		nil, nil, &models.AuditContext{UserID: &verifierID})
 
+	// Verified prices feed the price history of the fill's day
+	var event models.FuelEvent
+	if err := s.db.Select("id", "created_at").First(&event, eventID).Error; err == nil {
+		s.refreshFuelPriceDay(event.CreatedAt)
+	}
+
 	return nil
 }
 
@@ -214,6 +268,16 @@ func (s *FuelService) GetFuelAnalytics(period string, startDate, endDate time.Ti
 	analytics.TotalFuelConsumed = totalFuel
 	analytics.TotalFuelCost = totalCost
 	analytics.TotalEvents = int(eventCount)
+	if totalFuel > 0 {
+		analytics.AveragePricePerLiter = round2(totalCost / totalFuel)
+	}
+
+	// National diesel price trend from verified fills and price feeds
+	trend, err := s.FuelPriceTrend("", defaultFuelType, nil, startDate, endDate)
+	if err != nil {
+		log.Printf("⚠️ Failed to load fuel price trend: %v", err)
+	}
+	analytics.PriceTrend = trend
 
 	// Calculate average efficiency
 	if totalFuel > 0 {
@@ -303,6 +367,19 @@ func (s *FuelService) RunFraudDetection(event *models.FuelEvent) (fraudScore flo
 	}
 	warnings = append(warnings, stateWarnings...)
 
+	// 6. Isolation forest trained on verified events of the vehicle class (30% weight when available)
+	var explanation string
+	anomaly, err := s.scoreFuelEvent(event)
+	if err != nil {
+		log.Printf("⚠️ Fuel anomaly model unavailable for vehicle %d: %v", event.VehicleID, err)
+	} else if anomaly != nil {
+		totalScore = totalScore*(1-fuelModelWeight) + anomaly.Risk()*fuelModelWeight
+		if anomaly.Anomalous() {
+			reasons = append(reasons, "Unlike verified fills of this vehicle class")
+		}
+		explanation = anomaly.Explanation()
+	}
+
 	// Final fraud assessment
 	fraudScore = math.Min(totalScore, 1.0)
 
@@ -315,6 +392,9 @@ func (s *FuelService) RunFraudDetection(event *models.FuelEvent) (fraudScore flo
 	} else {
 		reason = "Normal fuel event"
 	}
+	if explanation != "" {
+		reason = fmt.Sprintf("%s [%s]", reason, explanation)
+	}
 
 	log.Printf("🔍 Fraud detection result - Score: %.2f, Reason: %s", fraudScore, reason)
 	return fraudScore, reason, warnings
@@ -388,8 +468,7 @@ func (s *FuelService) analyzeGeographicPattern(event *models.FuelEvent) (score f
 		warnings = append(warnings, "Invalid GPS coordinates")
 	}
 
-	// Check if fueling at known gas stations (mock implementation)
-	// In production, would have database of legitimate fuel stations
+	// Check if fueling at a registered station
 	isKnownStation := s.isKnownFuelStation(lat, lon)
 	if !isKnownStation {
 		score += 0.6
@@ -402,6 +481,12 @@ func (s *FuelService) analyzeGeographicPattern(event *models.FuelEvent) (score f
 		warnings = append(warnings, "Fueling in remote area")
 	}
 
+	// Check the station is on the fleet's preferred list, when it keeps one
+	if isKnownStation && !s.isPreferredFuelStation(event.VehicleID, lat, lon) {
+		score += 0.2
+		warnings = append(warnings, "Fueling outside the fleet's preferred stations")
+	}
+
 	return score, warnings
 }
 
@@ -468,20 +553,17 @@ func (s *FuelService) analyzeQuantityPattern(event *models.FuelEvent) (score flo
 		warnings = append(warnings, "Unusually small fuel quantity (<5L)")
 	}
 
-	// Check fuel price reasonableness
-	if event.AmountINR > 0 && quantity > 0 {
-		pricePerLiter := event.AmountINR / quantity
-
-		// Typical fuel price range (mock values - would be region-specific)
-		minPrice := 1.20 // Minimum expected price per liter
-		maxPrice := 2.50 // Maximum expected price per liter
-
-		if pricePerLiter < minPrice {
-			score += 0.7
-			warnings = append(warnings, fmt.Sprintf("Unusually low fuel price: $%.2f/L", pricePerLiter))
-		} else if pricePerLiter > maxPrice {
-			score += 0.4
-			warnings = append(warnings, fmt.Sprintf("Unusually high fuel price: $%.2f/L", pricePerLiter))
+	// Compare the price with what verified fills in the region cost recently
+	if price := fuelEventPrice(event); price > 0 {
+		if reference, ok, err := s.fuelEventPriceReference(event); err == nil && ok {
+			if price > reference.price*1.10 {
+				score += 0.4
+				warnings = append(warnings, fmt.Sprintf("Fuel price ₹%.2f/L is above the %s median of ₹%.2f/L", price, reference.scope(), reference.price))
+			} else if price < reference.price*0.80 {
+				// Far below market usually means the litres or amount on the receipt were altered
+				score += 0.7
+				warnings = append(warnings, fmt.Sprintf("Fuel price ₹%.2f/L is far below the %s median of ₹%.2f/L", price, reference.scope(), reference.price))
+			}
 		}
 	}
 
@@ -527,16 +609,46 @@ func (s *FuelService) analyzeVehicleState(event *models.FuelEvent) (score float6
 
 // Helper functions
 
+// isKnownFuelStation checks for an active registered station within fuelStationRadiusMeters.
+// With an empty registry there is nothing to compare against, so every location passes.
 func (s *FuelService) isKnownFuelStation(lat, lon float64) bool {
-	// Mock implementation - in production would check against database of registered fuel stations
-	// For now, assume locations near major highways or cities are legitimate
-	return true // Simplified for demo
+	station, _, registered, err := s.nearestActiveFuelStation(lat, lon, fuelStationRadiusMeters)
+	if err != nil || !registered {
+		return true
+	}
+	return station != nil
 }
 
+// isRemoteLocation checks whether the nearest registered station is over fuelStationRemoteMeters away
 func (s *FuelService) isRemoteLocation(lat, lon float64) bool {
-	// Mock implementation - in production would use geographic databases
-	// Check if location is far from major roads/cities
-	return false // Simplified for demo
+	station, _, registered, err := s.nearestActiveFuelStation(lat, lon, fuelStationRemoteMeters)
+	if err != nil || !registered {
+		return false
+	}
+	return station == nil
+}
+
+// isPreferredFuelStation checks the station at a location is on the preferred list of the
+// vehicle's fleet. Vehicles without a fleet, or fleets without a list, always pass.
+func (s *FuelService) isPreferredFuelStation(vehicleID uint, lat, lon float64) bool {
+	var vehicles []models.Vehicle
+	if err := s.db.Select("id", "fleet_id").Where("id = ?", vehicleID).Limit(1).Find(&vehicles).Error; err != nil ||
+		len(vehicles) == 0 || vehicles[0].FleetID == nil {
+		return true
+	}
+
+	var preferred []models.FleetPreferredStation
+	if err := s.db.Where("fleet_id = ?", *vehicles[0].FleetID).Preload("FuelStation").Find(&preferred).Error; err != nil ||
+		len(preferred) == 0 {
+		return true
+	}
+	for _, entry := range preferred {
+		if entry.FuelStation != nil &&
+			models.HaversineMeters(lat, lon, entry.FuelStation.Latitude, entry.FuelStation.Longitude) <= fuelStationRadiusMeters {
+			return true
+		}
+	}
+	return false
 }
 
 func joinReasons(reasons []string) string {
@@ -619,11 +731,8 @@ func (s *FuelService) calculateMLEfficiencyPrediction(vehicleID uint) (*models.M
 	// ML Algorithm 1: Time Series Analysis for Efficiency Trends
 	efficiency := s.analyzeEfficiencyTimeSeries(events)
 
-	// ML Algorithm 2: Anomaly Detection using Isolation Forest principles
-	_ = s.detectAnomaliesIsolationForest(events) // Unused for now but calculated
-
-	// ML Algorithm 3: Predictive Maintenance using Linear Regression
-	maintenancePredict := s.predictMaintenanceNeeds(events)
+	// ML Algorithm 3: Predictive Maintenance from component failure forecasts
+	maintenancePredict := s.predictMaintenanceNeeds(vehicleID, events)
 
 	// ML Algorithm 4: Driver Behavior Analysis
 	driverInsights := s.analyzeDriverBehaviorML(events)
@@ -641,10 +750,19 @@ func (s *FuelService) calculateMLEfficiencyPrediction(vehicleID uint) (*models.M
 		NextMaintenanceKm:     maintenancePredict.nextMaintenanceKm,
 	}
 
-	prediction := &models.MLPrediction{
-		Accuracy:   efficiency.accuracy,
-		Confidence: efficiency.confidence,
-		Model:      "ENSEMBLE_ML",
+	// Report the measured hold-out performance of the vehicle class's anomaly model
+	prediction := &models.MLPrediction{Model: "ENSEMBLE_ML"}
+	var vehicle models.Vehicle
+	if err := s.db.Select("vehicle_type").First(&vehicle, vehicleID).Error; err == nil {
+		if model, err := s.activeFuelModel(vehicle.VehicleType); err == nil {
+			prediction.Model = fmt.Sprintf("ENSEMBLE_ML+ISOLATION_FOREST_%s_V%d", model.record.VehicleType, model.record.Version)
+			if model.record.Accuracy != nil {
+				prediction.Accuracy = *model.record.Accuracy
+			}
+			if model.record.AUC != nil {
+				prediction.Confidence = *model.record.AUC
+			}
+		}
 	}
 
 	return metrics, prediction, nil
@@ -652,14 +770,14 @@ func (s *FuelService) calculateMLEfficiencyPrediction(vehicleID uint) (*models.M
 
 // ML Algorithm 1: Time Series Analysis for Efficiency Trends
 func (s *FuelService) analyzeEfficiencyTimeSeries(events []models.FuelEvent) struct {
-	current, predicted, optimal, costSavings, co2Reduction, accuracy, confidence float64
-	trend                                                                        string
+	current, predicted, optimal, costSavings, co2Reduction float64
+	trend                                                  string
 } {
 	if len(events) < 3 {
 		return struct {
-			current, predicted, optimal, costSavings, co2Reduction, accuracy, confidence float64
-			trend                                                                        string
-		}{0, 0, 0, 0, 0, 0, 0, "UNKNOWN"}
+			current, predicted, optimal, costSavings, co2Reduction float64
+			trend                                                  string
+		}{0, 0, 0, 0, 0, "UNKNOWN"}
 	}
 
 	// Simple moving average and linear regression for trend analysis
@@ -702,116 +820,51 @@ func (s *FuelService) analyzeEfficiencyTimeSeries(events []models.FuelEvent) str
 	co2Reduction := (current - optimal) * 2.3        // kg CO2 reduction per 100km
 
 	return struct {
-		current, predicted, optimal, costSavings, co2Reduction, accuracy, confidence float64
-		trend                                                                        string
-	}{current, predicted, optimal, costSavings, co2Reduction, 0.85, 0.78, trend}
+		current, predicted, optimal, costSavings, co2Reduction float64
+		trend                                                  string
+	}{current, predicted, optimal, costSavings, co2Reduction, trend}
 }
 
-// ML Algorithm 2: Anomaly Detection using Isolation Forest principles
+// ML Algorithm 2: Anomaly Detection with the vehicle class's isolation forest.
+// Returns the highest anomaly score among the events, or 0 when no model is trained.
 func (s *FuelService) detectAnomaliesIsolationForest(events []models.FuelEvent) float64 {
-	if len(events) < 5 {
-		return 0.0
-	}
-
-	// Simplified isolation forest - calculate isolation score
-	var features [][]float64
-	for _, event := range events {
-		feature := []float64{
-			event.Liters,
-			event.AmountINR,
-			float64(event.CreatedAt.Hour()),
-			event.OdometerKm,
-		}
-		features = append(features, feature)
-	}
-
-	// Calculate average path length (simplified)
-	var avgPathLength float64
-	for i := range features {
-		pathLength := s.calculateIsolationPath(features[i], features)
-		avgPathLength += pathLength
-	}
-	avgPathLength /= float64(len(features))
-
-	// Anomaly score (0 = normal, 1 = highly anomalous)
-	// In real implementation, would use proper isolation forest algorithm
-	normalizedScore := math.Max(0, math.Min(1, (avgPathLength-2)/8)) // Simplified normalization
-
-	return normalizedScore
-}
-
-// calculateIsolationPath calculates isolation path length for a point
-func (s *FuelService) calculateIsolationPath(point []float64, dataset [][]float64) float64 {
-	// Simplified path length calculation
-	// In real implementation, would build actual isolation trees
-	var distances []float64
-
-	for _, dataPoint := range dataset {
-		if len(dataPoint) != len(point) {
+	var maxScore float64
+	for i := range events {
+		anomaly, err := s.scoreFuelEvent(&events[i])
+		if err != nil || anomaly == nil {
 			continue
 		}
-
-		var distance float64
-		for i := range point {
-			distance += math.Abs(point[i] - dataPoint[i])
-		}
-		distances = append(distances, distance)
+		maxScore = math.Max(maxScore, anomaly.Score)
 	}
-
-	if len(distances) == 0 {
-		return 0
-	}
-
-	// Return average distance as proxy for isolation path
-	var sum float64
-	for _, d := range distances {
-		sum += d
-	}
-	return sum / float64(len(distances))
+	return maxScore
 }
 
-// ML Algorithm 3: Predictive Maintenance using Linear Regression
-func (s *FuelService) predictMaintenanceNeeds(events []models.FuelEvent) struct {
+// ML Algorithm 3: Predictive Maintenance from the vehicle's component failure forecasts
+func (s *FuelService) predictMaintenanceNeeds(vehicleID uint, events []models.FuelEvent) struct {
 	required          bool
 	nextMaintenanceKm float64
 } {
-	if len(events) == 0 {
-		return struct {
-			required          bool
-			nextMaintenanceKm float64
-		}{false, 0}
-	}
-
-	// Analyze efficiency degradation over time
-	var efficiencyTrend []float64
-	for _, event := range events {
-		// Mock efficiency metric
-		efficiency := event.Liters / math.Max(1, event.OdometerKm/100) // L/100km approximation
-		efficiencyTrend = append(efficiencyTrend, efficiency)
-	}
-
-	if len(efficiencyTrend) < 3 {
-		return struct {
-			required          bool
-			nextMaintenanceKm float64
-		}{false, 0}
+	// Required once any component is forecast at high risk or worse
+	var highRisk int64
+	s.db.Model(&models.MaintenancePrediction{}).
+		Where("vehicle_id = ? AND status = ? AND risk_level IN ?", vehicleID, models.MaintenancePredictionActive,
+			[]models.MaintenanceRiskLevel{models.MaintenanceRiskHigh, models.MaintenanceRiskCritical}).
+		Count(&highRisk)
+	required := highRisk > 0
+
+	// Next maintenance is the earliest mileage-based service due, else 5000km on (1000km when required)
+	var schedules []models.ServiceSchedule
+	s.db.Where("vehicle_id = ? AND status = ? AND next_due_mileage > 0", vehicleID, "ACTIVE").
+		Order("next_due_mileage").Limit(1).Find(&schedules)
+	nextMaintenanceKm := 0.0
+	if len(events) > 0 {
+		nextMaintenanceKm = events[len(events)-1].OdometerKm + 5000
+		if required {
+			nextMaintenanceKm = events[len(events)-1].OdometerKm + 1000
+		}
 	}
-
-	// Simple linear regression to detect degradation
-	recentEfficiency := efficiencyTrend[len(efficiencyTrend)-1]
-	baselineEfficiency := efficiencyTrend[0]
-
-	degradation := (recentEfficiency - baselineEfficiency) / baselineEfficiency
-
-	// Predict maintenance need
-	required := degradation > 0.15 // 15% efficiency degradation
-
-	// Predict next maintenance KM (simplified)
-	lastEvent := events[len(events)-1]
-	nextMaintenanceKm := lastEvent.OdometerKm + 5000 // Next service in 5000km
-
-	if required {
-		nextMaintenanceKm = lastEvent.OdometerKm + 1000 // Urgent - 1000km
+	if len(schedules) > 0 && (nextMaintenanceKm == 0 || float64(schedules[0].NextDueMileage) < nextMaintenanceKm) {
+		nextMaintenanceKm = float64(schedules[0].NextDueMileage)
 	}
 
 	return struct {
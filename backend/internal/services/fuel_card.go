@@ -0,0 +1,929 @@
+package services
+
+import (
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+)
+
+const (
+	// fuelCardGPSWindow is how far from the transaction time location pings are considered
+	fuelCardGPSWindow = 30 * time.Minute
+
+	// fuelStationRadiusMeters is how close a ping must be to count as presence at a station
+	fuelStationRadiusMeters = 300.0
+
+	// fuelCardLitresTolerance is the relative difference allowed against a hand-entered FuelEvent
+	fuelCardLitresTolerance = 0.05
+
+	// fuelCardMaxErrors caps the row errors kept on an import
+	fuelCardMaxErrors = 100
+)
+
+var (
+	// ErrInvalidFuelCardProvider is returned for provider configs without the columns an import needs
+	ErrInvalidFuelCardProvider = errors.New("invalid fuel card provider")
+	// ErrInvalidFuelCard is returned for cards without a number
+	ErrInvalidFuelCard = errors.New("invalid fuel card")
+	// ErrFuelCardExists is returned when the provider already has a card with that number
+	ErrFuelCardExists = errors.New("fuel card already registered for this provider")
+	// ErrInvalidFuelCardStatement is returned when a statement file cannot be read with the provider's mapping
+	ErrInvalidFuelCardStatement = errors.New("invalid fuel card statement")
+)
+
+// fuelCardFields lists every column a provider may map
+var fuelCardFields = map[models.FuelCardField]bool{
+	models.FuelCardFieldCardNumber: true, models.FuelCardFieldLicensePlate: true,
+	models.FuelCardFieldTransactionRef: true, models.FuelCardFieldTransactedAt: true,
+	models.FuelCardFieldDate: true, models.FuelCardFieldTime: true,
+	models.FuelCardFieldLitres: true, models.FuelCardFieldAmount: true,
+	models.FuelCardFieldPricePerLitre: true, models.FuelCardFieldFuelType: true,
+	models.FuelCardFieldStationName: true, models.FuelCardFieldStationBrand: true,
+	models.FuelCardFieldLocation: true, models.FuelCardFieldLatitude: true,
+	models.FuelCardFieldLongitude: true, models.FuelCardFieldOdometer: true,
+}
+
+// fuelCardDateLayouts are tried in order when a provider has no DateFormat
+var fuelCardDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+	"02-01-2006 15:04:05",
+	"02-01-2006 15:04",
+	"02-Jan-2006 15:04:05",
+	"02-Jan-2006 15:04",
+	"2006-01-02",
+	"02/01/2006",
+}
+
+// FuelCardImportResult is an import summary with the transactions it stored
+type FuelCardImportResult struct {
+	Import       models.FuelCardImport        `json:"import"`
+	Transactions []models.FuelCardTransaction `json:"transactions"`
+}
+
+// validateFuelCardProvider checks the column mapping covers what reconciliation needs
+func validateFuelCardProvider(provider *models.FuelCardProvider) error {
+	if strings.TrimSpace(provider.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidFuelCardProvider)
+	}
+	for field, column := range provider.Columns {
+		if !fuelCardFields[field] {
+			return fmt.Errorf("%w: unknown field %q", ErrInvalidFuelCardProvider, field)
+		}
+		if strings.TrimSpace(column) == "" {
+			return fmt.Errorf("%w: field %q has no column", ErrInvalidFuelCardProvider, field)
+		}
+	}
+	has := func(field models.FuelCardField) bool { return provider.Columns[field] != "" }
+	switch {
+	case !has(models.FuelCardFieldCardNumber) && !has(models.FuelCardFieldLicensePlate):
+		return fmt.Errorf("%w: card_number or license_plate must be mapped", ErrInvalidFuelCardProvider)
+	case !has(models.FuelCardFieldTransactedAt) && !has(models.FuelCardFieldDate):
+		return fmt.Errorf("%w: transacted_at or date must be mapped", ErrInvalidFuelCardProvider)
+	case !has(models.FuelCardFieldLitres) || !has(models.FuelCardFieldAmount):
+		return fmt.Errorf("%w: litres and amount must be mapped", ErrInvalidFuelCardProvider)
+	case len(provider.Delimiter) > 1:
+		return fmt.Errorf("%w: delimiter must be a single character", ErrInvalidFuelCardProvider)
+	}
+	if provider.Timezone != "" {
+		if _, err := time.LoadLocation(provider.Timezone); err != nil {
+			return fmt.Errorf("%w: unknown timezone %q", ErrInvalidFuelCardProvider, provider.Timezone)
+		}
+	}
+	return nil
+}
+
+// CreateFuelCardProvider registers a provider's statement format
+func (s *FuelService) CreateFuelCardProvider(provider *models.FuelCardProvider, userID uint) error {
+	if err := validateFuelCardProvider(provider); err != nil {
+		return err
+	}
+	if err := s.db.Create(provider).Error; err != nil {
+		return fmt.Errorf("failed to create fuel card provider: %w", err)
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionConfigChanged, models.AuditSeverityInfo,
+		fmt.Sprintf("Fuel card provider %d (%s) created", provider.ID, provider.Name),
+		nil, provider, &models.AuditContext{UserID: &userID})
+	return nil
+}
+
+// UpdateFuelCardProvider saves changes to a provider's statement format
+func (s *FuelService) UpdateFuelCardProvider(provider *models.FuelCardProvider, previous models.FuelCardProvider, userID uint) error {
+	if err := validateFuelCardProvider(provider); err != nil {
+		return err
+	}
+	if err := s.db.Save(provider).Error; err != nil {
+		return fmt.Errorf("failed to update fuel card provider: %w", err)
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionConfigChanged, models.AuditSeverityInfo,
+		fmt.Sprintf("Fuel card provider %d (%s) updated", provider.ID, provider.Name),
+		previous, provider, &models.AuditContext{UserID: &userID})
+	return nil
+}
+
+// GetFuelCardProvider gets a provider by ID
+func (s *FuelService) GetFuelCardProvider(id uint) (*models.FuelCardProvider, error) {
+	var provider models.FuelCardProvider
+	if err := s.db.First(&provider, id).Error; err != nil {
+		return nil, err
+	}
+	return &provider, nil
+}
+
+// GetFuelCardProviders lists providers by name
+func (s *FuelService) GetFuelCardProviders() ([]models.FuelCardProvider, error) {
+	var providers []models.FuelCardProvider
+	if err := s.db.Order("name").Find(&providers).Error; err != nil {
+		return nil, err
+	}
+	return providers, nil
+}
+
+// CreateFuelCard assigns a card to a vehicle
+func (s *FuelService) CreateFuelCard(card *models.FuelCard, userID uint) error {
+	card.CardNumber = normalizeCardNumber(card.CardNumber)
+	if err := s.checkFuelCardReferences(card); err != nil {
+		return err
+	}
+
+	var existing int64
+	if err := s.db.Model(&models.FuelCard{}).Where("provider_id = ? AND card_number = ?", card.ProviderID, card.CardNumber).
+		Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		return ErrFuelCardExists
+	}
+	if err := s.db.Create(card).Error; err != nil {
+		return fmt.Errorf("failed to create fuel card: %w", err)
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionConfigChanged, models.AuditSeverityInfo,
+		fmt.Sprintf("Fuel card %s registered with provider %d", maskCardNumber(card.CardNumber), card.ProviderID),
+		nil, card, &models.AuditContext{UserID: &userID, VehicleID: card.VehicleID})
+	return nil
+}
+
+// UpdateFuelCard reassigns or deactivates a card
+func (s *FuelService) UpdateFuelCard(card *models.FuelCard, previous models.FuelCard, userID uint) error {
+	if err := s.checkFuelCardReferences(card); err != nil {
+		return err
+	}
+	if err := s.db.Omit("Provider", "Vehicle", "Driver").Save(card).Error; err != nil {
+		return fmt.Errorf("failed to update fuel card: %w", err)
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionConfigChanged, models.AuditSeverityInfo,
+		fmt.Sprintf("Fuel card %s updated", maskCardNumber(card.CardNumber)),
+		previous, card, &models.AuditContext{UserID: &userID, VehicleID: card.VehicleID})
+	return nil
+}
+
+// checkFuelCardReferences makes sure the provider, vehicle and driver exist
+func (s *FuelService) checkFuelCardReferences(card *models.FuelCard) error {
+	if card.CardNumber == "" {
+		return fmt.Errorf("%w: card number is required", ErrInvalidFuelCard)
+	}
+	if _, err := s.GetFuelCardProvider(card.ProviderID); err != nil {
+		return err
+	}
+	if card.VehicleID != nil {
+		if err := s.db.Select("id").First(&models.Vehicle{}, *card.VehicleID).Error; err != nil {
+			return err
+		}
+	}
+	if card.DriverID != nil {
+		if err := s.db.Select("id").First(&models.Driver{}, *card.DriverID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetFuelCard gets a card by ID
+func (s *FuelService) GetFuelCard(id uint) (*models.FuelCard, error) {
+	var card models.FuelCard
+	if err := s.db.First(&card, id).Error; err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// GetFuelCards lists cards, optionally for one provider or vehicle
+func (s *FuelService) GetFuelCards(filters map[string]interface{}) ([]models.FuelCard, error) {
+	query := s.db.Model(&models.FuelCard{})
+	if providerID, ok := filters["provider_id"].(uint); ok && providerID > 0 {
+		query = query.Where("provider_id = ?", providerID)
+	}
+	if vehicleID, ok := filters["vehicle_id"].(uint); ok && vehicleID > 0 {
+		query = query.Where("vehicle_id = ?", vehicleID)
+	}
+
+	var cards []models.FuelCard
+	if err := query.Preload("Provider").Preload("Vehicle").Order("provider_id, card_number").Find(&cards).Error; err != nil {
+		return nil, err
+	}
+	return cards, nil
+}
+
+// ImportFuelCardStatement reads a provider's CSV statement, stores each new transaction and
+// reconciles it against vehicles, GPS presence at fuel stations and FuelEvents. Rows already
+// imported (same provider and transaction reference) are counted as duplicates and skipped.
+func (s *FuelService) ImportFuelCardStatement(providerID uint, fileName string, statement io.Reader, userID uint) (*FuelCardImportResult, error) {
+	provider, err := s.GetFuelCardProvider(providerID)
+	if err != nil {
+		return nil, err
+	}
+	if !provider.IsActive {
+		return nil, fmt.Errorf("%w: provider %s is inactive", ErrInvalidFuelCardProvider, provider.Name)
+	}
+	location := time.UTC
+	if provider.Timezone != "" {
+		if location, err = time.LoadLocation(provider.Timezone); err != nil {
+			return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidFuelCardProvider, provider.Timezone)
+		}
+	}
+
+	reader := csv.NewReader(statement)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if provider.Delimiter != "" {
+		reader.Comma = rune(provider.Delimiter[0])
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot read header: %v", ErrInvalidFuelCardStatement, err)
+	}
+	columns, err := mapFuelCardColumns(provider, header)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := models.FuelCardImport{ProviderID: provider.ID, FileName: fileName, ImportedBy: userID}
+	if err := s.db.Create(&batch).Error; err != nil {
+		return nil, fmt.Errorf("failed to create fuel card import: %w", err)
+	}
+	result := &FuelCardImportResult{}
+
+	rowError := func(row int, err error) {
+		batch.Failed++
+		if len(batch.Errors) < fuelCardMaxErrors {
+			batch.Errors = append(batch.Errors, fmt.Sprintf("row %d: %v", row, err))
+		}
+	}
+
+	seen := make(map[string]bool)
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			batch.Rows++
+			rowError(row, err)
+			continue
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+		batch.Rows++
+
+		txn, err := parseFuelCardRecord(record, columns, provider, location)
+		if err != nil {
+			rowError(row, err)
+			continue
+		}
+		txn.ImportID = batch.ID
+		txn.ProviderID = provider.ID
+
+		var existing int64
+		if err := s.db.Model(&models.FuelCardTransaction{}).
+			Where("provider_id = ? AND transaction_ref = ?", provider.ID, txn.TransactionRef).
+			Count(&existing).Error; err != nil {
+			return nil, err
+		}
+		if existing > 0 || seen[txn.TransactionRef] {
+			batch.Duplicates++
+			continue
+		}
+		seen[txn.TransactionRef] = true
+
+		if err := s.db.Create(txn).Error; err != nil {
+			rowError(row, err)
+			continue
+		}
+		if err := s.reconcileFuelCardTransaction(txn, provider, userID); err != nil {
+			rowError(row, fmt.Errorf("stored but not reconciled: %w", err))
+		}
+
+		batch.Imported++
+		switch txn.Status {
+		case models.FuelCardTxnReconciled:
+			batch.Reconciled++
+		case models.FuelCardTxnCreated:
+			batch.Created++
+		case models.FuelCardTxnUnmatched:
+			batch.Unmatched++
+		}
+		if txn.Flagged {
+			batch.Flagged++
+		}
+		result.Transactions = append(result.Transactions, *txn)
+	}
+
+	if err := s.db.Save(&batch).Error; err != nil {
+		return nil, fmt.Errorf("failed to save fuel card import: %w", err)
+	}
+	result.Import = batch
+
+	severity := models.AuditSeverityInfo
+	if batch.Flagged > 0 || batch.Unmatched > 0 {
+		severity = models.AuditSeverityWarning
+	}
+	_ = s.auditService.LogAction(models.AuditActionFuelCardImported, severity,
+		fmt.Sprintf("Fuel card statement %q from %s: %d imported (%d reconciled, %d created, %d unmatched, %d flagged), %d duplicates, %d failed",
+			fileName, provider.Name, batch.Imported, batch.Reconciled, batch.Created, batch.Unmatched, batch.Flagged, batch.Duplicates, batch.Failed),
+		nil, nil, &models.AuditContext{UserID: &userID})
+
+	return result, nil
+}
+
+// mapFuelCardColumns resolves the provider's mapped headers to column indexes
+func mapFuelCardColumns(provider *models.FuelCardProvider, header []string) (map[models.FuelCardField]int, error) {
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		positions[name] = i
+	}
+
+	columns := make(map[models.FuelCardField]int, len(provider.Columns))
+	for field, name := range provider.Columns {
+		i, ok := positions[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("%w: column %q (%s) not found in header", ErrInvalidFuelCardStatement, name, field)
+		}
+		columns[field] = i
+	}
+	return columns, nil
+}
+
+// parseFuelCardRecord converts one statement row into a transaction
+func parseFuelCardRecord(record []string, columns map[models.FuelCardField]int, provider *models.FuelCardProvider, location *time.Location) (*models.FuelCardTransaction, error) {
+	value := func(field models.FuelCardField) string {
+		i, ok := columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	txn := &models.FuelCardTransaction{
+		CardNumber:     normalizeCardNumber(value(models.FuelCardFieldCardNumber)),
+		LicensePlate:   value(models.FuelCardFieldLicensePlate),
+		TransactionRef: value(models.FuelCardFieldTransactionRef),
+		FuelType:       strings.ToUpper(value(models.FuelCardFieldFuelType)),
+		StationName:    value(models.FuelCardFieldStationName),
+		StationBrand:   value(models.FuelCardFieldStationBrand),
+		Location:       value(models.FuelCardFieldLocation),
+	}
+	if txn.CardNumber == "" && txn.LicensePlate == "" {
+		return nil, errors.New("no card number or license plate")
+	}
+
+	when := value(models.FuelCardFieldTransactedAt)
+	if when == "" {
+		when = strings.TrimSpace(value(models.FuelCardFieldDate) + " " + value(models.FuelCardFieldTime))
+	}
+	at, err := parseFuelCardTime(when, provider.DateFormat, location)
+	if err != nil {
+		return nil, err
+	}
+	txn.TransactedAt = at
+
+	if txn.Litres, err = parseStatementNumber(value(models.FuelCardFieldLitres)); err != nil || txn.Litres <= 0 {
+		return nil, fmt.Errorf("invalid litres %q", value(models.FuelCardFieldLitres))
+	}
+	if txn.AmountINR, err = parseStatementNumber(value(models.FuelCardFieldAmount)); err != nil || txn.AmountINR <= 0 {
+		return nil, fmt.Errorf("invalid amount %q", value(models.FuelCardFieldAmount))
+	}
+	if price := value(models.FuelCardFieldPricePerLitre); price != "" {
+		if txn.PricePerLiter, err = parseStatementNumber(price); err != nil {
+			return nil, fmt.Errorf("invalid price per litre %q", price)
+		}
+	} else {
+		txn.PricePerLiter = round2(txn.AmountINR / txn.Litres)
+	}
+	if odometer := value(models.FuelCardFieldOdometer); odometer != "" {
+		if txn.OdometerKm, err = parseStatementNumber(odometer); err != nil {
+			return nil, fmt.Errorf("invalid odometer %q", odometer)
+		}
+	}
+
+	lat, lon := value(models.FuelCardFieldLatitude), value(models.FuelCardFieldLongitude)
+	if lat != "" && lon != "" {
+		latitude, latErr := strconv.ParseFloat(lat, 64)
+		longitude, lonErr := strconv.ParseFloat(lon, 64)
+		if latErr != nil || lonErr != nil || math.Abs(latitude) > 90 || math.Abs(longitude) > 180 {
+			return nil, fmt.Errorf("invalid coordinates %q, %q", lat, lon)
+		}
+		txn.Latitude, txn.Longitude = &latitude, &longitude
+	}
+
+	if txn.TransactionRef == "" {
+		// Statements without a reference are keyed on what identifies the purchase
+		sum := sha1.Sum([]byte(fmt.Sprintf("%s|%s|%s|%.2f|%.2f", txn.CardNumber, txn.LicensePlate,
+			txn.TransactedAt.UTC().Format(time.RFC3339), txn.Litres, txn.AmountINR)))
+		txn.TransactionRef = "auto-" + hex.EncodeToString(sum[:8])
+	}
+	return txn, nil
+}
+
+// parseFuelCardTime parses a statement timestamp with the provider's layout, or common ones
+func parseFuelCardTime(value, layout string, location *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("missing transaction time")
+	}
+	layouts := fuelCardDateLayouts
+	if layout != "" {
+		layouts = []string{layout}
+	}
+	for _, l := range layouts {
+		if at, err := time.ParseInLocation(l, value, location); err == nil {
+			return at, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised transaction time %q", value)
+}
+
+// parseStatementNumber accepts amounts like "₹ 9,150.00" or "INR 9150"
+func parseStatementNumber(value string) (float64, error) {
+	cleaned := strings.NewReplacer(",", "", "₹", "", "INR", "", "Rs.", "", " ", "").Replace(value)
+	return strconv.ParseFloat(cleaned, 64)
+}
+
+// normalizeCardNumber strips the spaces and dashes statements print card numbers with
+func normalizeCardNumber(number string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(number)))
+}
+
+// maskCardNumber keeps the last four characters for logs
+func maskCardNumber(number string) string {
+	if len(number) <= 4 {
+		return number
+	}
+	return strings.Repeat("*", len(number)-4) + number[len(number)-4:]
+}
+
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ReconcileFuelCardTransaction re-runs matching for a stored transaction, e.g. after its card was assigned
+func (s *FuelService) ReconcileFuelCardTransaction(id, userID uint) (*models.FuelCardTransaction, error) {
+	var txn models.FuelCardTransaction
+	if err := s.db.First(&txn, id).Error; err != nil {
+		return nil, err
+	}
+	provider, err := s.GetFuelCardProvider(txn.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.reconcileFuelCardTransaction(&txn, provider, userID); err != nil {
+		return nil, err
+	}
+	return &txn, nil
+}
+
+// fuelCardReview collects the flags raised while reconciling and why
+type fuelCardReview struct {
+	txn     *models.FuelCardTransaction
+	reasons []string
+}
+
+func (r *fuelCardReview) flag(flag models.FuelCardFlag, reason string) {
+	r.txn.Flags = append(r.txn.Flags, flag)
+	if flag != models.FuelCardFlagNoGPS {
+		r.txn.Flagged = true
+		r.reasons = append(r.reasons, reason)
+	}
+}
+
+// reconcileFuelCardTransaction matches a transaction to a vehicle, checks GPS presence and
+// capacity, then verifies the matching FuelEvent or creates one. Flagged transactions mark the
+// event SUSPICIOUS and raise a CARD_MISMATCH alert.
+func (s *FuelService) reconcileFuelCardTransaction(txn *models.FuelCardTransaction, provider *models.FuelCardProvider, userID uint) error {
+	txn.Flags, txn.Flagged = nil, false
+	txn.FuelStationID, txn.DistanceMeters = nil, nil
+	review := &fuelCardReview{txn: txn}
+
+	vehicle, driverID, err := s.matchFuelCardVehicle(txn)
+	if err != nil {
+		return err
+	}
+	if vehicle == nil {
+		txn.Status = models.FuelCardTxnUnmatched
+		review.flag(models.FuelCardFlagUnknownCard, "No vehicle is assigned to this card or plate")
+		return s.db.Omit("Vehicle", "FuelEvent", "FuelStation").Save(txn).Error
+	}
+	txn.VehicleID = &vehicle.ID
+	if txn.DriverID == nil {
+		txn.DriverID = driverID
+	}
+
+	if vehicle.FuelCapacity > 0 && txn.Litres > vehicle.FuelCapacity {
+		review.flag(models.FuelCardFlagExceedsCapacity,
+			fmt.Sprintf("%.1f L billed but the tank holds %.0f L", txn.Litres, vehicle.FuelCapacity))
+	}
+
+	station, err := s.checkFuelCardPresence(review, vehicle.ID)
+	if err != nil {
+		return err
+	}
+
+	event, err := s.matchFuelCardEvent(review, vehicle, station)
+	if err != nil {
+		return err
+	}
+	if err := s.applyFuelCardVerdict(review, event, provider, userID); err != nil {
+		return err
+	}
+
+	if txn.Flagged && txn.FuelAlertID == nil {
+		alert, err := s.raiseFuelCardAlert(review, vehicle, event)
+		if err != nil {
+			return err
+		}
+		txn.FuelAlertID = &alert.ID
+	}
+	return s.db.Omit("Vehicle", "FuelEvent", "FuelStation").Save(txn).Error
+}
+
+// matchFuelCardVehicle finds the vehicle by the card assignment, falling back to the printed plate
+func (s *FuelService) matchFuelCardVehicle(txn *models.FuelCardTransaction) (*models.Vehicle, *uint, error) {
+	var driverID *uint
+	var vehicles []models.Vehicle
+
+	if txn.CardNumber != "" {
+		var cards []models.FuelCard
+		if err := s.db.Where("provider_id = ? AND card_number = ? AND is_active = ?", txn.ProviderID, txn.CardNumber, true).
+			Limit(1).Find(&cards).Error; err != nil {
+			return nil, nil, err
+		}
+		if len(cards) == 1 && cards[0].VehicleID != nil {
+			driverID = cards[0].DriverID
+			if err := s.db.Limit(1).Find(&vehicles, *cards[0].VehicleID).Error; err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if len(vehicles) == 0 && txn.LicensePlate != "" {
+		plate := strings.NewReplacer(" ", "", "-", "").Replace(strings.ToUpper(txn.LicensePlate))
+		if err := s.db.Where("REPLACE(REPLACE(UPPER(license_plate), ' ', ''), '-', '') = ?", plate).
+			Limit(1).Find(&vehicles).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(vehicles) == 0 {
+		return nil, nil, nil
+	}
+	return &vehicles[0], driverID, nil
+}
+
+// checkFuelCardPresence compares the vehicle's GPS pings around the transaction with the
+// station the provider reported (by coordinates or name), or with any known station when the
+// statement has no location. Returns the station the vehicle was seen at.
+func (s *FuelService) checkFuelCardPresence(review *fuelCardReview, vehicleID uint) (*models.FuelStation, error) {
+	txn := review.txn
+
+	var pings []models.LocationPing
+	if err := s.db.Where("vehicle_id = ? AND timestamp BETWEEN ? AND ?", vehicleID,
+		txn.TransactedAt.Add(-fuelCardGPSWindow), txn.TransactedAt.Add(fuelCardGPSWindow)).
+		Find(&pings).Error; err != nil {
+		return nil, err
+	}
+	if len(pings) == 0 {
+		review.flag(models.FuelCardFlagNoGPS, "No GPS pings around the transaction")
+		return nil, nil
+	}
+
+	var stations []models.FuelStation
+	if err := s.db.Where("is_active = ?", true).Find(&stations).Error; err != nil {
+		return nil, err
+	}
+
+	// nearestPing is the closest any ping came to a point
+	nearestPing := func(lat, lon float64) float64 {
+		best := math.Inf(1)
+		for _, ping := range pings {
+			best = math.Min(best, models.HaversineMeters(ping.Latitude, ping.Longitude, lat, lon))
+		}
+		return best
+	}
+	// nearestStation is the closest known station to a point
+	nearestStation := func(lat, lon float64) (*models.FuelStation, float64) {
+		var found *models.FuelStation
+		best := math.Inf(1)
+		for i := range stations {
+			if d := models.HaversineMeters(stations[i].Latitude, stations[i].Longitude, lat, lon); d < best {
+				found, best = &stations[i], d
+			}
+		}
+		return found, best
+	}
+
+	// Where the provider says the purchase happened
+	var reported *models.FuelStation
+	var refLat, refLon float64
+	hasReference := false
+	if txn.Latitude != nil && txn.Longitude != nil {
+		refLat, refLon, hasReference = *txn.Latitude, *txn.Longitude, true
+		if station, d := nearestStation(refLat, refLon); station != nil && d <= fuelStationRadiusMeters {
+			reported = station
+		}
+	} else if txn.StationName != "" {
+		for i := range stations {
+			if strings.EqualFold(stations[i].Name, txn.StationName) {
+				if reported != nil {
+					reported = nil // Ambiguous name; fall back to any station
+					break
+				}
+				reported = &stations[i]
+			}
+		}
+		if reported != nil {
+			refLat, refLon, hasReference = reported.Latitude, reported.Longitude, true
+		}
+	}
+
+	if hasReference {
+		distance := nearestPing(refLat, refLon)
+		txn.DistanceMeters = &distance
+		if distance > fuelStationRadiusMeters {
+			review.flag(models.FuelCardFlagVehicleElsewhere,
+				fmt.Sprintf("GPS puts the vehicle %.1f km from the station at the time of purchase", distance/1000))
+			return nil, nil
+		}
+		if reported != nil {
+			txn.FuelStationID = &reported.ID
+		}
+		return reported, nil
+	}
+
+	// No reported location: the vehicle should have been at some known station
+	if len(stations) == 0 {
+		return nil, nil // Nothing to check against
+	}
+	var seenAt *models.FuelStation
+	best := math.Inf(1)
+	for _, ping := range pings {
+		if station, d := nearestStation(ping.Latitude, ping.Longitude); d < best {
+			seenAt, best = station, d
+		}
+	}
+	txn.DistanceMeters = &best
+	if best > fuelStationRadiusMeters {
+		review.flag(models.FuelCardFlagVehicleElsewhere,
+			fmt.Sprintf("GPS shows the vehicle was not at any known fuel station (nearest %.1f km away)", best/1000))
+		return nil, nil
+	}
+	txn.FuelStationID = &seenAt.ID
+	return seenAt, nil
+}
+
+// matchFuelCardEvent links the transaction to the nearest unlinked FuelEvent of the vehicle,
+// or creates one from the statement line
+func (s *FuelService) matchFuelCardEvent(review *fuelCardReview, vehicle *models.Vehicle, station *models.FuelStation) (*models.FuelEvent, error) {
+	txn := review.txn
+	if txn.FuelEventID != nil {
+		var event models.FuelEvent
+		if err := s.db.First(&event, *txn.FuelEventID).Error; err != nil {
+			return nil, err
+		}
+		if txn.Status == models.FuelCardTxnReconciled {
+			s.checkFuelCardLitres(review, &event)
+		}
+		return &event, nil
+	}
+
+	var candidates []models.FuelEvent
+	if err := s.db.Where("vehicle_id = ? AND created_at BETWEEN ? AND ?", vehicle.ID,
+		txn.TransactedAt.Add(-refuelReconcileWindow), txn.TransactedAt.Add(refuelReconcileWindow)).
+		Where("id NOT IN (?)", s.db.Model(&models.FuelCardTransaction{}).Select("fuel_event_id").Where("fuel_event_id IS NOT NULL")).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	if len(candidates) > 0 {
+		sort.Slice(candidates, func(i, j int) bool {
+			return absDuration(candidates[i].CreatedAt.Sub(txn.TransactedAt)) < absDuration(candidates[j].CreatedAt.Sub(txn.TransactedAt))
+		})
+		event := candidates[0]
+		txn.FuelEventID = &event.ID
+		txn.Status = models.FuelCardTxnReconciled
+		s.checkFuelCardLitres(review, &event)
+		return &event, nil
+	}
+
+	event := &models.FuelEvent{
+		VehicleID:     vehicle.ID,
+		DriverID:      txn.DriverID,
+		Liters:        txn.Litres,
+		AmountINR:     txn.AmountINR,
+		PricePerLiter: txn.PricePerLiter,
+		OdometerKm:    txn.OdometerKm,
+		FuelType:      txn.FuelType,
+		Location:      txn.Location,
+		Latitude:      txn.Latitude,
+		Longitude:     txn.Longitude,
+		StationName:   txn.StationName,
+		StationBrand:  txn.StationBrand,
+		ReceiptNumber: txn.TransactionRef,
+		FueledAt:      &txn.TransactedAt,
+		CreatedAt:     txn.TransactedAt,
+	}
+	if station != nil {
+		lat, lon := station.Latitude, station.Longitude
+		event.StationID = strconv.FormatUint(uint64(station.ID), 10)
+		if event.Latitude == nil {
+			event.Latitude, event.Longitude = &lat, &lon
+		}
+		if event.StationName == "" {
+			event.StationName, event.StationBrand = station.Name, station.Brand
+		}
+	}
+	created, err := s.CreateFuelEvent(event)
+	if err != nil {
+		return nil, err
+	}
+	txn.FuelEventID = &created.ID
+	txn.Status = models.FuelCardTxnCreated
+	return created, nil
+}
+
+// checkFuelCardLitres flags a hand-entered event whose litres differ from the statement
+func (s *FuelService) checkFuelCardLitres(review *fuelCardReview, event *models.FuelEvent) {
+	tolerance := math.Max(1, fuelCardLitresTolerance*review.txn.Litres)
+	if math.Abs(event.Liters-review.txn.Litres) > tolerance {
+		review.flag(models.FuelCardFlagLitresMismatch,
+			fmt.Sprintf("Fuel event %d reports %.1f L but the card was charged for %.1f L", event.ID, event.Liters, review.txn.Litres))
+	}
+}
+
+// applyFuelCardVerdict verifies the event when the transaction is clean and marks it
+// SUSPICIOUS when flagged. Events an admin rejected are left alone; without GPS the card alone
+// does not verify an event.
+func (s *FuelService) applyFuelCardVerdict(review *fuelCardReview, event *models.FuelEvent, provider *models.FuelCardProvider, userID uint) error {
+	if event.Status == models.FuelEventStatusRejected && event.VerifiedBy != nil {
+		return nil
+	}
+
+	if review.txn.Flagged {
+		return s.db.Model(event).Updates(map[string]interface{}{
+			"status":       models.FuelEventStatusSuspicious,
+			"fraud_reason": "Fuel card: " + strings.Join(review.reasons, "; "),
+		}).Error
+	}
+	if review.txn.HasFlag(models.FuelCardFlagNoGPS) {
+		return nil
+	}
+
+	now := time.Now()
+	if err := s.db.Model(event).Updates(map[string]interface{}{
+		"status":             models.FuelEventStatusVerified,
+		"is_authorized":      true,
+		"verified_by":        userID,
+		"verified_at":        now,
+		"verification_notes": fmt.Sprintf("Matched %s transaction %s with GPS presence at the station", provider.Name, review.txn.TransactionRef),
+	}).Error; err != nil {
+		return err
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionFuelEventVerified, models.AuditSeverityInfo,
+		fmt.Sprintf("Fuel event %d verified from fuel card transaction %s", event.ID, review.txn.TransactionRef),
+		nil, nil, &models.AuditContext{UserID: &userID, VehicleID: &event.VehicleID})
+	s.refreshFuelPriceDay(event.CreatedAt)
+	return nil
+}
+
+// raiseFuelCardAlert records one CARD_MISMATCH alert covering every flag on the transaction
+func (s *FuelService) raiseFuelCardAlert(review *fuelCardReview, vehicle *models.Vehicle, event *models.FuelEvent) (*models.FuelAlert, error) {
+	txn := review.txn
+	severity := models.AlertSeverityMedium
+	if txn.HasFlag(models.FuelCardFlagVehicleElsewhere) || txn.HasFlag(models.FuelCardFlagExceedsCapacity) {
+		severity = models.AlertSeverityHigh
+	}
+
+	alert := &models.FuelAlert{
+		VehicleID:   vehicle.ID,
+		DriverID:    txn.DriverID,
+		FuelEventID: &event.ID,
+		AlertType:   models.FuelAlertTypeCardMismatch,
+		Severity:    severity,
+		Title:       "Fuel card transaction needs review",
+		Description: fmt.Sprintf("Card transaction %s for %.1f L (₹%.2f) at %s: %s", txn.TransactionRef, txn.Litres, txn.AmountINR,
+			txn.TransactedAt.Format(time.RFC3339), strings.Join(review.reasons, "; ")),
+		Message:    review.reasons[0],
+		DetectedAt: txn.TransactedAt,
+		Latitude:   txn.Latitude,
+		Longitude:  txn.Longitude,
+		Location:   txn.Location,
+	}
+	litres := txn.Litres
+	alert.ActualValue = &litres
+	switch {
+	case txn.HasFlag(models.FuelCardFlagExceedsCapacity):
+		capacity := vehicle.FuelCapacity
+		alert.ExpectedValue = &capacity
+	case txn.HasFlag(models.FuelCardFlagLitresMismatch):
+		reported := event.Liters
+		alert.ExpectedValue = &reported
+	case txn.HasFlag(models.FuelCardFlagVehicleElsewhere):
+		radius := fuelStationRadiusMeters
+		alert.ActualValue, alert.ThresholdValue = txn.DistanceMeters, &radius
+	}
+	if err := s.db.Create(alert).Error; err != nil {
+		return nil, err
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionFuelAlertCreated, models.AuditSeverityWarning,
+		fmt.Sprintf("%s alert for vehicle %s: %s", alert.AlertType, vehicle.LicensePlate, alert.Description),
+		nil, alert, &models.AuditContext{VehicleID: &vehicle.ID})
+	return alert, nil
+}
+
+// GetFuelCardImports lists statement imports, newest first
+func (s *FuelService) GetFuelCardImports(page, limit int) ([]models.FuelCardImport, int64, error) {
+	var imports []models.FuelCardImport
+	var total int64
+
+	query := s.db.Model(&models.FuelCardImport{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * limit
+	if err := query.Preload("Provider").Order("created_at DESC").Offset(offset).Limit(limit).Find(&imports).Error; err != nil {
+		return nil, 0, err
+	}
+	return imports, total, nil
+}
+
+// GetFuelCardImport gets an import by ID
+func (s *FuelService) GetFuelCardImport(id uint) (*models.FuelCardImport, error) {
+	var batch models.FuelCardImport
+	if err := s.db.Preload("Provider").First(&batch, id).Error; err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// GetFuelCardTransactions lists card transactions, newest first
+func (s *FuelService) GetFuelCardTransactions(page, limit int, filters map[string]interface{}) ([]models.FuelCardTransaction, int64, error) {
+	var transactions []models.FuelCardTransaction
+	var total int64
+
+	query := s.db.Model(&models.FuelCardTransaction{})
+	if importID, ok := filters["import_id"].(uint); ok && importID > 0 {
+		query = query.Where("import_id = ?", importID)
+	}
+	if vehicleID, ok := filters["vehicle_id"].(uint); ok && vehicleID > 0 {
+		query = query.Where("vehicle_id = ?", vehicleID)
+	}
+	if status, ok := filters["status"].(string); ok && status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if flagged, ok := filters["flagged"].(bool); ok {
+		query = query.Where("flagged = ?", flagged)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * limit
+	if err := query.Preload("FuelEvent").Preload("FuelStation").Order("transacted_at DESC").
+		Offset(offset).Limit(limit).Find(&transactions).Error; err != nil {
+		return nil, 0, err
+	}
+	return transactions, total, nil
+}
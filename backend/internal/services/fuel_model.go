@@ -0,0 +1,598 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	fuelModelTrees         = 100
+	fuelModelSampleSize    = 256
+	fuelModelContamination = 0.05                 // Share of verified events expected to score above the threshold
+	fuelModelMinSamples    = 30                   // Minimum verified training events per vehicle class
+	fuelModelHoldoutModulo = 5                    // Every fifth verified event is held out for evaluation
+	fuelModelHistory       = 365 * 24 * time.Hour // Training window
+	fuelModelWeight        = 0.3                  // Share of the fraud score taken by the model when one is active
+)
+
+// fuelModelFeatures are the isolation forest inputs, in column order
+var fuelModelFeatures = []string{
+	"litres_pct_tank",       // Fill size relative to tank capacity
+	"price_per_litre",       // Billed price
+	"hour_of_day",           // Local hour the event was recorded
+	"km_since_last_fill",    // Odometer distance from the previous fill
+	"litres_per_100km",      // Litres bought per 100 km driven since the previous fill
+	"hours_since_last_fill", // Time since the previous fill
+}
+
+// ErrInsufficientTrainingData is returned when a vehicle class has too few verified events to train on
+var ErrInsufficientTrainingData = errors.New("not enough verified fuel events to train an anomaly model")
+
+// fuelAnomalyModel is an active model with its forest decoded
+type fuelAnomalyModel struct {
+	record models.FuelAnomalyModel
+	forest *IsolationForest
+}
+
+// fuelAnomalyResult is the model's verdict on one fuel event
+type fuelAnomalyResult struct {
+	Model         *models.FuelAnomalyModel
+	Score         float64
+	Features      []float64 // After imputation
+	Missing       []bool
+	Contributions []float64
+}
+
+// fuelModelSample is a fuel event with its feature row
+type fuelModelSample struct {
+	event    models.FuelEvent
+	features []float64 // NaN marks a missing value
+}
+
+// FuelModelEvaluation reports how well a model's flags agree with admin decisions.
+// Rejected events are positives, verified events the model was not trained on are negatives.
+type FuelModelEvaluation struct {
+	VehicleType    models.VehicleType `json:"vehicle_type"`
+	ModelVersion   int                `json:"model_version"`
+	Threshold      float64            `json:"threshold"`
+	Samples        int                `json:"samples"`
+	Positives      int                `json:"positives"`
+	Negatives      int                `json:"negatives"`
+	TruePositives  int                `json:"true_positives"`
+	FalsePositives int                `json:"false_positives"`
+	FalseNegatives int                `json:"false_negatives"`
+	TrueNegatives  int                `json:"true_negatives"`
+	Precision      float64            `json:"precision"`
+	Recall         float64            `json:"recall"`
+	Accuracy       float64            `json:"accuracy"`
+	AUC            float64            `json:"auc"` // Probability a rejected event outscores a verified one
+}
+
+// fuelEventFeatures builds the feature row of an event given the vehicle's previous fill
+func fuelEventFeatures(event, prev *models.FuelEvent, tankCapacity float64) []float64 {
+	missing := math.NaN()
+	row := []float64{missing, missing, float64(event.CreatedAt.Hour()), missing, missing, missing}
+
+	if tankCapacity > 0 {
+		row[0] = event.Liters / tankCapacity * 100
+	}
+	if event.PricePerLiter > 0 {
+		row[1] = event.PricePerLiter
+	} else if event.Liters > 0 {
+		row[1] = event.AmountINR / event.Liters
+	}
+	if prev != nil {
+		row[5] = event.CreatedAt.Sub(prev.CreatedAt).Hours()
+		if event.OdometerKm > 0 && prev.OdometerKm > 0 && event.OdometerKm > prev.OdometerKm {
+			km := event.OdometerKm - prev.OdometerKm
+			row[3] = km
+			row[4] = event.Liters / km * 100
+		}
+	}
+	return row
+}
+
+// imputeFeatures replaces missing values with the training medians
+func imputeFeatures(row, medians []float64) ([]float64, []bool) {
+	out := make([]float64, len(row))
+	missing := make([]bool, len(row))
+	for i, v := range row {
+		if math.IsNaN(v) {
+			out[i] = medians[i]
+			missing[i] = true
+		} else {
+			out[i] = v
+		}
+	}
+	return out, missing
+}
+
+// featureMedians returns the per-column median of the non-missing values (0 when a column is empty)
+func featureMedians(rows [][]float64, width int) []float64 {
+	medians := make([]float64, width)
+	for f := 0; f < width; f++ {
+		var values []float64
+		for _, row := range rows {
+			if !math.IsNaN(row[f]) {
+				values = append(values, row[f])
+			}
+		}
+		if len(values) > 0 {
+			medians[f] = median(values)
+		}
+	}
+	return medians
+}
+
+// loadFuelModelSamples loads a vehicle class's fuel events since the given time with their feature rows.
+// The previous fill of an event is the vehicle's last event before it that admins did not reject.
+func (s *FuelService) loadFuelModelSamples(vehicleType models.VehicleType, since time.Time) ([]fuelModelSample, error) {
+	var events []models.FuelEvent
+	if err := s.db.Preload("Vehicle").
+		Joins("JOIN vehicles ON vehicles.id = fuel_events.vehicle_id").
+		Where("vehicles.vehicle_type = ? AND fuel_events.created_at >= ?", vehicleType, since).
+		Order("fuel_events.vehicle_id, fuel_events.created_at, fuel_events.id").
+		Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to load fuel events: %w", err)
+	}
+
+	samples := make([]fuelModelSample, 0, len(events))
+	var prev *models.FuelEvent
+	for i := range events {
+		event := &events[i]
+		if prev != nil && prev.VehicleID != event.VehicleID {
+			prev = nil
+		}
+		var capacity float64
+		if event.Vehicle != nil {
+			capacity = event.Vehicle.FuelCapacity
+		}
+		samples = append(samples, fuelModelSample{event: *event, features: fuelEventFeatures(event, prev, capacity)})
+		if event.Status != models.FuelEventStatusRejected {
+			prev = event
+		}
+	}
+	return samples, nil
+}
+
+// isAdminRejected reports whether an admin rejected the event, as opposed to the automatic fraud cut-off
+func isAdminRejected(event *models.FuelEvent) bool {
+	return event.Status == models.FuelEventStatusRejected && event.VerifiedBy != nil
+}
+
+// fuelModelSeed derives a reproducible training seed for a class and version
+func fuelModelSeed(vehicleType models.VehicleType, version int) int64 {
+	h := fnv.New64a()
+	_, _ = fmt.Fprintf(h, "%s:%d", vehicleType, version)
+	return int64(h.Sum64() >> 1)
+}
+
+// TrainFuelAnomalyModel trains a new model version for a vehicle class on its verified fuel events
+// and makes it the active one. One in five verified events is held out and scored together with
+// admin-rejected events to record precision and recall on the model.
+func (s *FuelService) TrainFuelAnomalyModel(vehicleType models.VehicleType) (*models.FuelAnomalyModel, error) {
+	samples, err := s.loadFuelModelSamples(vehicleType, time.Now().Add(-fuelModelHistory))
+	if err != nil {
+		return nil, err
+	}
+
+	var training [][]float64
+	var trainedThrough time.Time
+	var holdout []fuelModelSample
+	for _, sample := range samples {
+		switch {
+		case sample.event.Status == models.FuelEventStatusVerified && sample.event.ID%fuelModelHoldoutModulo == 0:
+			holdout = append(holdout, sample)
+		case sample.event.Status == models.FuelEventStatusVerified:
+			training = append(training, sample.features)
+			if sample.event.CreatedAt.After(trainedThrough) {
+				trainedThrough = sample.event.CreatedAt
+			}
+		case isAdminRejected(&sample.event):
+			holdout = append(holdout, sample)
+		}
+	}
+	if len(training) < fuelModelMinSamples {
+		return nil, fmt.Errorf("%w: %s has %d, needs %d", ErrInsufficientTrainingData, vehicleType, len(training), fuelModelMinSamples)
+	}
+
+	var version int
+	if err := s.db.Model(&models.FuelAnomalyModel{}).Where("vehicle_type = ?", vehicleType).
+		Select("COALESCE(MAX(version), 0) + 1").Scan(&version).Error; err != nil {
+		return nil, fmt.Errorf("failed to load model versions: %w", err)
+	}
+
+	medians := featureMedians(training, len(fuelModelFeatures))
+	rows := make([][]float64, len(training))
+	for i, row := range training {
+		rows[i], _ = imputeFeatures(row, medians)
+	}
+	rng := rand.New(rand.NewSource(fuelModelSeed(vehicleType, version)))
+	forest, err := TrainIsolationForest(rows, fuelModelTrees, fuelModelSampleSize, rng)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]float64, len(rows))
+	for i, row := range rows {
+		scores[i] = forest.Score(row)
+	}
+	threshold := scoreQuantile(scores, 1-fuelModelContamination)
+
+	encoded, err := json.Marshal(forest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize model: %w", err)
+	}
+
+	model := &models.FuelAnomalyModel{
+		VehicleType:     vehicleType,
+		Version:         version,
+		Algorithm:       "ISOLATION_FOREST",
+		IsActive:        true,
+		FeatureNames:    fuelModelFeatures,
+		Imputation:      medians,
+		Forest:          string(encoded),
+		TreeCount:       len(forest.Trees),
+		SampleSize:      forest.SampleSize,
+		Threshold:       round4(threshold),
+		Contamination:   fuelModelContamination,
+		TrainingSamples: len(training),
+		TrainedThrough:  trainedThrough,
+		TrainedAt:       time.Now(),
+	}
+
+	evaluation := evaluateFuelModel(forest, model, holdout)
+	model.EvaluationSamples = evaluation.Samples
+	if evaluation.Positives > 0 && evaluation.Negatives > 0 {
+		model.Precision = &evaluation.Precision
+		model.Recall = &evaluation.Recall
+		model.Accuracy = &evaluation.Accuracy
+		model.AUC = &evaluation.AUC
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.FuelAnomalyModel{}).
+			Where("vehicle_type = ? AND is_active = ?", vehicleType, true).
+			Update("is_active", false).Error; err != nil {
+			return err
+		}
+		return tx.Create(model).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save model: %w", err)
+	}
+
+	s.modelMu.Lock()
+	if s.anomalyModels == nil {
+		s.anomalyModels = make(map[models.VehicleType]*fuelAnomalyModel)
+	}
+	s.anomalyModels[vehicleType] = &fuelAnomalyModel{record: *model, forest: forest}
+	s.modelMu.Unlock()
+
+	_ = s.auditService.LogAction(models.AuditActionFuelModelTrained, models.AuditSeverityInfo,
+		fmt.Sprintf("Fuel anomaly model %s v%d trained on %d verified events", vehicleType, version, len(training)),
+		nil, model, nil)
+
+	log.Printf("🌲 Trained fuel anomaly model %s v%d on %d events (threshold %.3f)", vehicleType, version, len(training), threshold)
+	return model, nil
+}
+
+// TrainFuelAnomalyModels trains a new version for every vehicle class with enough verified events.
+// Classes without enough data are skipped.
+func (s *FuelService) TrainFuelAnomalyModels() ([]models.FuelAnomalyModel, error) {
+	var vehicleTypes []models.VehicleType
+	if err := s.db.Model(&models.Vehicle{}).Distinct().Order("vehicle_type").Pluck("vehicle_type", &vehicleTypes).Error; err != nil {
+		return nil, fmt.Errorf("failed to list vehicle classes: %w", err)
+	}
+
+	trained := []models.FuelAnomalyModel{}
+	for _, vehicleType := range vehicleTypes {
+		model, err := s.TrainFuelAnomalyModel(vehicleType)
+		if errors.Is(err, ErrInsufficientTrainingData) {
+			continue
+		}
+		if err != nil {
+			return trained, err
+		}
+		trained = append(trained, *model)
+	}
+	return trained, nil
+}
+
+// GetFuelAnomalyModels lists model versions, newest first, optionally for one vehicle class
+func (s *FuelService) GetFuelAnomalyModels(vehicleType models.VehicleType) ([]models.FuelAnomalyModel, error) {
+	query := s.db.Omit("forest").Order("vehicle_type, version DESC")
+	if vehicleType != "" {
+		query = query.Where("vehicle_type = ?", vehicleType)
+	}
+	var result []models.FuelAnomalyModel
+	if err := query.Find(&result).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// StartFuelModelRetraining retrains each vehicle class periodically once admins have verified new events
+func (s *FuelService) StartFuelModelRetraining(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			var vehicleTypes []models.VehicleType
+			if err := s.db.Model(&models.Vehicle{}).Distinct().Pluck("vehicle_type", &vehicleTypes).Error; err != nil {
+				log.Printf("❌ Failed to list vehicle classes for model retraining: %v", err)
+				continue
+			}
+
+			for _, vehicleType := range vehicleTypes {
+				var active models.FuelAnomalyModel
+				err := s.db.Omit("forest").Where("vehicle_type = ? AND is_active = ?", vehicleType, true).First(&active).Error
+				if err == nil {
+					var fresh int64
+					s.db.Model(&models.FuelEvent{}).
+						Joins("JOIN vehicles ON vehicles.id = fuel_events.vehicle_id").
+						Where("vehicles.vehicle_type = ? AND fuel_events.status IN ? AND fuel_events.verified_at > ?",
+							vehicleType, []models.FuelEventStatus{models.FuelEventStatusVerified, models.FuelEventStatusRejected}, active.TrainedAt).
+						Count(&fresh)
+					if fresh == 0 {
+						continue
+					}
+				}
+
+				if _, err := s.TrainFuelAnomalyModel(vehicleType); err != nil && !errors.Is(err, ErrInsufficientTrainingData) {
+					log.Printf("⚠️ Fuel anomaly model retraining failed for %s: %v", vehicleType, err)
+				}
+			}
+		}
+	}()
+}
+
+// activeFuelModel returns the active model of a vehicle class, decoding the forest only when the version changed
+func (s *FuelService) activeFuelModel(vehicleType models.VehicleType) (*fuelAnomalyModel, error) {
+	var record models.FuelAnomalyModel
+	if err := s.db.Omit("forest").Where("vehicle_type = ? AND is_active = ?", vehicleType, true).First(&record).Error; err != nil {
+		return nil, err
+	}
+
+	s.modelMu.RLock()
+	cached := s.anomalyModels[vehicleType]
+	s.modelMu.RUnlock()
+	if cached != nil && cached.record.ID == record.ID {
+		return cached, nil
+	}
+
+	return s.loadFuelModel(record.ID)
+}
+
+// loadFuelModel decodes a stored model version and caches it when it is active
+func (s *FuelService) loadFuelModel(id uint) (*fuelAnomalyModel, error) {
+	var record models.FuelAnomalyModel
+	if err := s.db.First(&record, id).Error; err != nil {
+		return nil, err
+	}
+	var forest IsolationForest
+	if err := json.Unmarshal([]byte(record.Forest), &forest); err != nil {
+		return nil, fmt.Errorf("failed to decode fuel anomaly model %d: %w", id, err)
+	}
+	if forest.Features != len(record.FeatureNames) || len(record.Imputation) != len(record.FeatureNames) {
+		return nil, fmt.Errorf("fuel anomaly model %d does not match its feature list", id)
+	}
+
+	loaded := &fuelAnomalyModel{record: record, forest: &forest}
+	if record.IsActive {
+		s.modelMu.Lock()
+		if s.anomalyModels == nil {
+			s.anomalyModels = make(map[models.VehicleType]*fuelAnomalyModel)
+		}
+		s.anomalyModels[record.VehicleType] = loaded
+		s.modelMu.Unlock()
+	}
+	return loaded, nil
+}
+
+// scoreFuelEvent scores an event with the active model of its vehicle class.
+// Returns nil without error when the class has no trained model.
+func (s *FuelService) scoreFuelEvent(event *models.FuelEvent) (*fuelAnomalyResult, error) {
+	vehicle := event.Vehicle
+	if vehicle == nil {
+		vehicle = &models.Vehicle{}
+		if err := s.db.First(vehicle, event.VehicleID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	model, err := s.activeFuelModel(vehicle.VehicleType)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	scored := *event
+	if scored.CreatedAt.IsZero() {
+		scored.CreatedAt = time.Now()
+	}
+	var prev models.FuelEvent
+	prevQuery := s.db.Where("vehicle_id = ? AND created_at < ? AND status <> ?", event.VehicleID, scored.CreatedAt, models.FuelEventStatusRejected)
+	if event.ID != 0 {
+		prevQuery = prevQuery.Where("id <> ?", event.ID)
+	}
+	var prevEvent *models.FuelEvent
+	if err := prevQuery.Order("created_at DESC").First(&prev).Error; err == nil {
+		prevEvent = &prev
+	}
+
+	return model.score(fuelEventFeatures(&scored, prevEvent, vehicle.FuelCapacity)), nil
+}
+
+// score imputes a raw feature row and explains its anomaly score
+func (m *fuelAnomalyModel) score(row []float64) *fuelAnomalyResult {
+	features, missing := imputeFeatures(row, m.record.Imputation)
+	score, contributions := m.forest.Explain(features)
+	return &fuelAnomalyResult{
+		Model:         &m.record,
+		Score:         score,
+		Features:      features,
+		Missing:       missing,
+		Contributions: contributions,
+	}
+}
+
+// Anomalous reports whether the score reaches the model's threshold
+func (r *fuelAnomalyResult) Anomalous() bool {
+	return r.Score >= r.Model.Threshold
+}
+
+// Risk maps the anomaly score onto the fraud scale: 0 at the neutral score of 0.5, 1 at the threshold
+func (r *fuelAnomalyResult) Risk() float64 {
+	if r.Model.Threshold <= 0.5 {
+		if r.Anomalous() {
+			return 1
+		}
+		return 0
+	}
+	return math.Max(0, math.Min(1, (r.Score-0.5)/(r.Model.Threshold-0.5)))
+}
+
+// Explanation lists the features that drove the score, e.g.
+// "isolation forest TRUCK v3: score 0.71 (threshold 0.62); litres_pct_tank=96.0 48%, hour_of_day=3.0 27%"
+func (r *fuelAnomalyResult) Explanation() string {
+	order := make([]int, len(r.Contributions))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return r.Contributions[order[a]] > r.Contributions[order[b]] })
+
+	var parts []string
+	for _, f := range order {
+		if len(parts) == 3 || r.Contributions[f] < 0.05 {
+			break
+		}
+		value := fmt.Sprintf("%.1f", r.Features[f])
+		if r.Missing[f] {
+			value = "n/a"
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s %.0f%%", r.Model.FeatureNames[f], value, r.Contributions[f]*100))
+	}
+
+	return fmt.Sprintf("isolation forest %s v%d: score %.2f (threshold %.2f); %s",
+		r.Model.VehicleType, r.Model.Version, r.Score, r.Model.Threshold, strings.Join(parts, ", "))
+}
+
+// evaluateFuelModel scores labelled samples: admin-rejected events are positives, the rest negatives
+func evaluateFuelModel(forest *IsolationForest, model *models.FuelAnomalyModel, samples []fuelModelSample) FuelModelEvaluation {
+	evaluation := FuelModelEvaluation{
+		VehicleType:  model.VehicleType,
+		ModelVersion: model.Version,
+		Threshold:    model.Threshold,
+	}
+
+	var positives, negatives []float64
+	for _, sample := range samples {
+		row, _ := imputeFeatures(sample.features, model.Imputation)
+		score := forest.Score(row)
+		flagged := score >= model.Threshold
+		if isAdminRejected(&sample.event) {
+			positives = append(positives, score)
+			if flagged {
+				evaluation.TruePositives++
+			} else {
+				evaluation.FalseNegatives++
+			}
+		} else {
+			negatives = append(negatives, score)
+			if flagged {
+				evaluation.FalsePositives++
+			} else {
+				evaluation.TrueNegatives++
+			}
+		}
+	}
+
+	evaluation.Positives = len(positives)
+	evaluation.Negatives = len(negatives)
+	evaluation.Samples = len(samples)
+	if flagged := evaluation.TruePositives + evaluation.FalsePositives; flagged > 0 {
+		evaluation.Precision = round4(float64(evaluation.TruePositives) / float64(flagged))
+	}
+	if evaluation.Positives > 0 {
+		evaluation.Recall = round4(float64(evaluation.TruePositives) / float64(evaluation.Positives))
+	}
+	if evaluation.Samples > 0 {
+		evaluation.Accuracy = round4(float64(evaluation.TruePositives+evaluation.TrueNegatives) / float64(evaluation.Samples))
+	}
+
+	// Mann–Whitney estimate of the area under the ROC curve
+	if len(positives) > 0 && len(negatives) > 0 {
+		var wins float64
+		for _, p := range positives {
+			for _, n := range negatives {
+				switch {
+				case p > n:
+					wins++
+				case p == n:
+					wins += 0.5
+				}
+			}
+		}
+		evaluation.AUC = round4(wins / float64(len(positives)*len(negatives)))
+	}
+	return evaluation
+}
+
+// EvaluateFuelAnomalyModel scores a stored model version (0 for the active one) against admin decisions.
+// Negatives are verified events the version never trained on: the hold-out share and anything
+// newer than its training data. A positive threshold overrides the model's own.
+func (s *FuelService) EvaluateFuelAnomalyModel(vehicleType models.VehicleType, version int, threshold float64) (*FuelModelEvaluation, error) {
+	query := s.db.Select("id").Where("vehicle_type = ?", vehicleType)
+	if version > 0 {
+		query = query.Where("version = ?", version)
+	} else {
+		query = query.Where("is_active = ?", true)
+	}
+	var record models.FuelAnomalyModel
+	if err := query.First(&record).Error; err != nil {
+		return nil, err
+	}
+	model, err := s.loadFuelModel(record.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	samples, err := s.loadFuelModelSamples(vehicleType, model.record.TrainedThrough.Add(-fuelModelHistory))
+	if err != nil {
+		return nil, err
+	}
+	var labelled []fuelModelSample
+	for _, sample := range samples {
+		event := &sample.event
+		unseen := event.ID%fuelModelHoldoutModulo == 0 || event.CreatedAt.After(model.record.TrainedThrough)
+		if isAdminRejected(event) || (event.Status == models.FuelEventStatusVerified && unseen) {
+			labelled = append(labelled, sample)
+		}
+	}
+
+	evaluated := model.record
+	if threshold > 0 {
+		evaluated.Threshold = threshold
+	}
+	evaluation := evaluateFuelModel(model.forest, &evaluated, labelled)
+	return &evaluation, nil
+}
+
+func round4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
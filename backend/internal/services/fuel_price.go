@@ -0,0 +1,418 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// fuelPriceWindow is the trailing window the reference median is taken over
+	fuelPriceWindow = 7 * 24 * time.Hour
+
+	// fuelPriceMinSamples is how many prices a reference needs before receipts are judged against it
+	fuelPriceMinSamples = 3
+
+	// defaultFuelType is what FuelEvent.FuelType defaults to
+	defaultFuelType = "DIESEL"
+)
+
+// ErrInvalidFuelPrice is returned for feed entries without a date, fuel type or positive price
+var ErrInvalidFuelPrice = errors.New("invalid fuel price")
+
+// fuelPriceReference is the median a receipt is compared with
+type fuelPriceReference struct {
+	price   float64
+	samples int
+	region  string // Empty when the national median was used
+}
+
+// scope describes where the reference comes from, for alert texts
+func (r fuelPriceReference) scope() string {
+	if r.region == "" {
+		return "national"
+	}
+	return r.region
+}
+
+func normalizeFuelType(fuelType string) string {
+	if fuelType = strings.ToUpper(strings.TrimSpace(fuelType)); fuelType == "" {
+		return defaultFuelType
+	}
+	return fuelType
+}
+
+// fuelDay truncates a time to its UTC calendar day
+func fuelDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// fuelEventPrice is the receipt's price per litre, derived from the amount when not recorded
+func fuelEventPrice(event *models.FuelEvent) float64 {
+	if event.PricePerLiter > 0 {
+		return event.PricePerLiter
+	}
+	if event.Liters > 0 {
+		return event.AmountINR / event.Liters
+	}
+	return 0
+}
+
+// resolveFuelStation finds the registered station a fuel event happened at: by its station ID,
+// then by coordinates within fuelStationRadiusMeters, then by a unique name match
+func resolveFuelStation(event *models.FuelEvent, stations []models.FuelStation) *models.FuelStation {
+	if id, err := strconv.ParseUint(event.StationID, 10, 32); err == nil {
+		for i := range stations {
+			if stations[i].ID == uint(id) {
+				return &stations[i]
+			}
+		}
+	}
+	if event.Latitude != nil && event.Longitude != nil {
+		var nearest *models.FuelStation
+		best := fuelStationRadiusMeters
+		for i := range stations {
+			if d := models.HaversineMeters(stations[i].Latitude, stations[i].Longitude, *event.Latitude, *event.Longitude); d <= best {
+				nearest, best = &stations[i], d
+			}
+		}
+		if nearest != nil {
+			return nearest
+		}
+	}
+	if event.StationName != "" {
+		var match *models.FuelStation
+		for i := range stations {
+			if strings.EqualFold(stations[i].Name, event.StationName) {
+				if match != nil {
+					return nil
+				}
+				match = &stations[i]
+			}
+		}
+		return match
+	}
+	return nil
+}
+
+func stationRegion(station *models.FuelStation) string {
+	if station == nil {
+		return ""
+	}
+	return strings.ToUpper(strings.TrimSpace(station.State))
+}
+
+func (s *FuelService) loadFuelStations() ([]models.FuelStation, error) {
+	var stations []models.FuelStation
+	if err := s.db.Find(&stations).Error; err != nil {
+		return nil, err
+	}
+	return stations, nil
+}
+
+// fuelPriceKey groups prices into one history row
+type fuelPriceKey struct {
+	day       time.Time
+	region    string
+	fuelType  string
+	stationID uint // 0 for region and national rows
+}
+
+// RebuildFuelPriceHistory recomputes the EVENTS rows for every day in [from, to] from verified
+// fuel events: one row per station, per region (station State) and nationally. Feed rows are kept.
+func (s *FuelService) RebuildFuelPriceHistory(from, to time.Time) (int, error) {
+	start, end := fuelDay(from), fuelDay(to).AddDate(0, 0, 1)
+
+	var events []models.FuelEvent
+	if err := s.db.Where("status = ? AND created_at >= ? AND created_at < ? AND liters > 0",
+		models.FuelEventStatusVerified, start, end).Find(&events).Error; err != nil {
+		return 0, err
+	}
+	stations, err := s.loadFuelStations()
+	if err != nil {
+		return 0, err
+	}
+
+	prices := make(map[fuelPriceKey][]float64)
+	for i := range events {
+		price := fuelEventPrice(&events[i])
+		if price <= 0 {
+			continue
+		}
+		day, fuelType := fuelDay(events[i].CreatedAt), normalizeFuelType(events[i].FuelType)
+		national := fuelPriceKey{day: day, fuelType: fuelType}
+		prices[national] = append(prices[national], price)
+
+		station := resolveFuelStation(&events[i], stations)
+		if region := stationRegion(station); region != "" {
+			key := fuelPriceKey{day: day, region: region, fuelType: fuelType}
+			prices[key] = append(prices[key], price)
+		}
+		if station != nil {
+			key := fuelPriceKey{day: day, region: stationRegion(station), fuelType: fuelType, stationID: station.ID}
+			prices[key] = append(prices[key], price)
+		}
+	}
+
+	rows := make([]models.FuelPrice, 0, len(prices))
+	for key, values := range prices {
+		row := models.FuelPrice{
+			Date:          key.day,
+			Region:        key.region,
+			FuelType:      key.fuelType,
+			Source:        models.FuelPriceSourceEvents,
+			PricePerLiter: round2(median(values)),
+			MinPrice:      round2(values[0]),
+			MaxPrice:      round2(values[0]),
+			Samples:       len(values),
+		}
+		for _, v := range values {
+			row.MinPrice, row.MaxPrice = round2(math.Min(row.MinPrice, v)), round2(math.Max(row.MaxPrice, v))
+		}
+		if key.stationID != 0 {
+			stationID := key.stationID
+			row.FuelStationID = &stationID
+		}
+		rows = append(rows, row)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source = ? AND date >= ? AND date < ?", models.FuelPriceSourceEvents, start, end).
+			Delete(&models.FuelPrice{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to rebuild fuel price history: %w", err)
+	}
+	return len(rows), nil
+}
+
+// refreshFuelPriceDay rebuilds the history of the day a fuel event was verified for
+func (s *FuelService) refreshFuelPriceDay(at time.Time) {
+	if _, err := s.RebuildFuelPriceHistory(at, at); err != nil {
+		log.Printf("⚠️ Fuel price history refresh for %s failed: %v", fuelDay(at).Format("2006-01-02"), err)
+	}
+}
+
+// ImportFuelPriceFeed stores published prices from an external feed. Re-importing a day
+// replaces the feed's earlier price for the same region, station and fuel type.
+func (s *FuelService) ImportFuelPriceFeed(source string, entries []models.FuelPrice, userID uint) (int, error) {
+	source = strings.ToUpper(strings.TrimSpace(source))
+	if source == "" || source == models.FuelPriceSourceEvents {
+		return 0, fmt.Errorf("%w: source must name the feed", ErrInvalidFuelPrice)
+	}
+	stations, err := s.loadFuelStations()
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		if entry.Date.IsZero() || entry.PricePerLiter <= 0 {
+			return 0, fmt.Errorf("%w: entry %d needs a date and a positive price", ErrInvalidFuelPrice, i+1)
+		}
+		entry.Date = fuelDay(entry.Date)
+		entry.Source = source
+		entry.FuelType = normalizeFuelType(entry.FuelType)
+		entry.Region = strings.ToUpper(strings.TrimSpace(entry.Region))
+		entry.PricePerLiter = round2(entry.PricePerLiter)
+		entry.MinPrice, entry.MaxPrice, entry.Samples = entry.PricePerLiter, entry.PricePerLiter, 1
+		if entry.FuelStationID != nil {
+			var station *models.FuelStation
+			for j := range stations {
+				if stations[j].ID == *entry.FuelStationID {
+					station = &stations[j]
+				}
+			}
+			if station == nil {
+				return 0, fmt.Errorf("%w: entry %d refers to unknown station %d", ErrInvalidFuelPrice, i+1, *entry.FuelStationID)
+			}
+			if entry.Region == "" {
+				entry.Region = stationRegion(station)
+			}
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for i := range entries {
+			entry := &entries[i]
+			query := tx.Where("source = ? AND date = ? AND region = ? AND fuel_type = ?",
+				entry.Source, entry.Date, entry.Region, entry.FuelType)
+			if entry.FuelStationID != nil {
+				query = query.Where("fuel_station_id = ?", *entry.FuelStationID)
+			} else {
+				query = query.Where("fuel_station_id IS NULL")
+			}
+			var existing []models.FuelPrice
+			if err := query.Limit(1).Find(&existing).Error; err != nil {
+				return err
+			}
+			if len(existing) == 1 {
+				entry.ID, entry.CreatedAt = existing[0].ID, existing[0].CreatedAt
+			}
+			if err := tx.Save(entry).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to import fuel prices: %w", err)
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionConfigChanged, models.AuditSeverityInfo,
+		fmt.Sprintf("Imported %d fuel price(s) from feed %s", len(entries), source),
+		nil, nil, &models.AuditContext{UserID: &userID})
+	return len(entries), nil
+}
+
+// GetFuelPrices lists history rows, newest first
+func (s *FuelService) GetFuelPrices(page, limit int, filters map[string]interface{}) ([]models.FuelPrice, int64, error) {
+	var prices []models.FuelPrice
+	var total int64
+
+	query := s.db.Model(&models.FuelPrice{})
+	if region, ok := filters["region"].(string); ok {
+		query = query.Where("region = ?", strings.ToUpper(region))
+	}
+	if fuelType, ok := filters["fuel_type"].(string); ok && fuelType != "" {
+		query = query.Where("fuel_type = ?", normalizeFuelType(fuelType))
+	}
+	if stationID, ok := filters["fuel_station_id"].(uint); ok && stationID > 0 {
+		query = query.Where("fuel_station_id = ?", stationID)
+	}
+	if source, ok := filters["source"].(string); ok && source != "" {
+		query = query.Where("source = ?", strings.ToUpper(source))
+	}
+	if from, ok := filters["from"].(time.Time); ok {
+		query = query.Where("date >= ?", fuelDay(from))
+	}
+	if to, ok := filters["to"].(time.Time); ok {
+		query = query.Where("date <= ?", fuelDay(to))
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * limit
+	if err := query.Order("date DESC, region, fuel_type").Offset(offset).Limit(limit).Find(&prices).Error; err != nil {
+		return nil, 0, err
+	}
+	return prices, total, nil
+}
+
+// FuelPriceTrend returns one point per day for a station, a region, or nationally when both are
+// empty. Each point is the median of that day's event and feed prices.
+func (s *FuelService) FuelPriceTrend(region, fuelType string, stationID *uint, from, to time.Time) ([]models.FuelPricePoint, error) {
+	query := s.db.Where("fuel_type = ? AND date >= ? AND date <= ?", normalizeFuelType(fuelType), fuelDay(from), fuelDay(to))
+	if stationID != nil {
+		query = query.Where("fuel_station_id = ?", *stationID)
+	} else {
+		query = query.Where("region = ? AND fuel_station_id IS NULL", strings.ToUpper(strings.TrimSpace(region)))
+	}
+	var rows []models.FuelPrice
+	if err := query.Order("date").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	points := []models.FuelPricePoint{}
+	for i := 0; i < len(rows); {
+		day := fuelDay(rows[i].Date)
+		point := models.FuelPricePoint{Date: day, MinPrice: rows[i].MinPrice, MaxPrice: rows[i].MaxPrice}
+		var values []float64
+		for ; i < len(rows) && fuelDay(rows[i].Date).Equal(day); i++ {
+			values = append(values, rows[i].PricePerLiter)
+			point.MinPrice = math.Min(point.MinPrice, rows[i].MinPrice)
+			point.MaxPrice = math.Max(point.MaxPrice, rows[i].MaxPrice)
+			point.Samples += rows[i].Samples
+		}
+		point.PricePerLiter = round2(median(values))
+		if n := len(points); n > 0 && points[n-1].PricePerLiter > 0 {
+			change := round2((point.PricePerLiter - points[n-1].PricePerLiter) / points[n-1].PricePerLiter * 100)
+			point.ChangePercent = &change
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+// referenceFuelPrice is the median of the region's daily prices over the trailing window, or the
+// national median when the region is unknown or has too few prices
+func (s *FuelService) referenceFuelPrice(region, fuelType string, at time.Time) (fuelPriceReference, bool, error) {
+	day := fuelDay(at)
+	scopes := []string{""}
+	if region != "" {
+		scopes = []string{region, ""}
+	}
+
+	for _, scope := range scopes {
+		var rows []models.FuelPrice
+		if err := s.db.Where("region = ? AND fuel_type = ? AND fuel_station_id IS NULL AND date > ? AND date <= ?",
+			scope, normalizeFuelType(fuelType), day.Add(-fuelPriceWindow), day).Find(&rows).Error; err != nil {
+			return fuelPriceReference{}, false, err
+		}
+		values := make([]float64, 0, len(rows))
+		samples := 0
+		for _, row := range rows {
+			values = append(values, row.PricePerLiter)
+			samples += row.Samples
+		}
+		if samples >= fuelPriceMinSamples {
+			return fuelPriceReference{price: round2(median(values)), samples: samples, region: scope}, true, nil
+		}
+	}
+	return fuelPriceReference{}, false, nil
+}
+
+// fuelEventPriceReference resolves the event's region and its reference price
+func (s *FuelService) fuelEventPriceReference(event *models.FuelEvent) (fuelPriceReference, bool, error) {
+	stations, err := s.loadFuelStations()
+	if err != nil {
+		return fuelPriceReference{}, false, err
+	}
+	at := event.CreatedAt
+	if at.IsZero() {
+		at = time.Now() // Not stored yet
+	}
+	return s.referenceFuelPrice(stationRegion(resolveFuelStation(event, stations)), event.FuelType, at)
+}
+
+// checkPriceVariance raises a PRICE_ANOMALY alert when a receipt is priced above the regional
+// median by more than the rule allows (a percentage or INR per litre)
+func (s *FuelService) checkPriceVariance(threshold models.FuelThreshold, event *models.FuelEvent) (*models.FuelAlert, error) {
+	price := fuelEventPrice(event)
+	if price <= 0 {
+		return nil, nil
+	}
+	reference, ok, err := s.fuelEventPriceReference(event)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	margin := threshold.Value
+	if threshold.Unit == models.FuelThresholdUnitPercentage {
+		margin = reference.price * threshold.Value / 100
+	}
+	if price <= reference.price+margin {
+		return nil, nil
+	}
+
+	variance := (price - reference.price) / reference.price * 100
+	description := fmt.Sprintf("₹%.2f/L paid against a %s median of ₹%.2f/L over the last %d days (%+.1f%%, %d prices)",
+		price, reference.scope(), reference.price, int(fuelPriceWindow.Hours()/24), variance, reference.samples)
+	return s.raiseRuleAlert(threshold, event.VehicleID, event.DriverID, &event.ID, event.CreatedAt,
+		models.FuelAlertTypePriceAnomaly, "Fuel price above regional median", description, reference.price, price, variance)
+}
@@ -0,0 +1,583 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// stationarySpeedKmh is the speed below which a vehicle counts as parked
+	stationarySpeedKmh = 3.0
+
+	// fuelSettleTime skips readings right after stopping while fuel is still sloshing
+	fuelSettleTime = 60 * time.Second
+
+	// fuelSmoothingWindow is the rolling-median width used to suppress slosh spikes
+	fuelSmoothingWindow = 5
+
+	// fuelMinChangeLitres and fuelMinChangeFraction set the detection floor: the larger wins
+	fuelMinChangeLitres   = 5.0
+	fuelMinChangeFraction = 0.03
+
+	// idleBurnLitresPerHour is the consumption allowed for while parked with the engine running
+	idleBurnLitresPerHour = 2.5
+
+	// refuelReconcileWindow is how far a receipt's purchase time may be from a detected refuel to match it
+	refuelReconcileWindow = 2 * time.Hour
+
+	// receiptEntryDelay is how long after a refuel a receipt without a purchase time may be entered
+	// and still match it by entry time
+	receiptEntryDelay = 24 * time.Hour
+
+	// fuelLevelLookback is the telemetry window re-scanned by the periodic monitor
+	fuelLevelLookback = 6 * time.Hour
+)
+
+// FuelLevelAnalysis summarises one run of sensor-based fuel detection
+type FuelLevelAnalysis struct {
+	VehicleID uint                    `json:"vehicle_id"`
+	From      time.Time               `json:"from"`
+	To        time.Time               `json:"to"`
+	Samples   int                     `json:"samples"`
+	Events    []models.FuelLevelEvent `json:"events"`
+	Alerts    []models.FuelAlert      `json:"alerts"`
+}
+
+// fuelSample is one tank-level reading converted to litres
+type fuelSample struct {
+	at       time.Time
+	litres   float64
+	moving   bool
+	engineOn bool
+	odometer *float64
+}
+
+// fuelLevelChange is a refuel or drop found inside one parked episode
+type fuelLevelChange struct {
+	eventType models.FuelLevelEventType
+	startedAt time.Time
+	endedAt   time.Time
+	before    float64
+	after     float64
+	expected  float64
+	engineOn  bool
+}
+
+// AnalyzeFuelLevel scans a vehicle's tank-level telemetry for refuels and sudden drops.
+// Refuels are reconciled against reported FuelEvents; drops while parked beyond idle burn
+// raise THEFT_SUSPECTED alerts. Re-running over the same window does not duplicate events.
+func (s *FuelService) AnalyzeFuelLevel(vehicleID uint, from, to time.Time) (*FuelLevelAnalysis, error) {
+	var vehicle models.Vehicle
+	if err := s.db.First(&vehicle, vehicleID).Error; err != nil {
+		return nil, err
+	}
+	if vehicle.FuelCapacity <= 0 {
+		return nil, fmt.Errorf("vehicle %d has no fuel capacity configured", vehicleID)
+	}
+
+	var logs []models.TelemetryLog
+	if err := s.db.Where("vehicle_id = ? AND timestamp BETWEEN ? AND ? AND fuel_level IS NOT NULL", vehicleID, from, to).
+		Order("timestamp").Find(&logs).Error; err != nil {
+		return nil, err
+	}
+
+	analysis := &FuelLevelAnalysis{VehicleID: vehicleID, From: from, To: to, Samples: len(logs)}
+	threshold := math.Max(fuelMinChangeLitres, fuelMinChangeFraction*vehicle.FuelCapacity)
+	noise := math.Max(0.5, 0.005*vehicle.FuelCapacity)
+
+	for _, episode := range parkedEpisodes(toFuelSamples(logs, vehicle.FuelCapacity)) {
+		for _, change := range detectLevelChanges(episode, threshold, noise) {
+			event, alert, err := s.recordLevelChange(&vehicle, change, threshold)
+			if err != nil {
+				return analysis, err
+			}
+			if event == nil {
+				continue // Already recorded by an earlier run
+			}
+			analysis.Events = append(analysis.Events, *event)
+			if alert != nil {
+				analysis.Alerts = append(analysis.Alerts, *alert)
+			}
+		}
+	}
+
+	return analysis, nil
+}
+
+// GetFuelLevelEvents lists detected tank-level events, newest first
+func (s *FuelService) GetFuelLevelEvents(page, limit int, filters map[string]interface{}) ([]models.FuelLevelEvent, int64, error) {
+	var events []models.FuelLevelEvent
+	var total int64
+
+	query := s.db.Model(&models.FuelLevelEvent{})
+	if vehicleID, ok := filters["vehicle_id"].(uint); ok && vehicleID > 0 {
+		query = query.Where("vehicle_id = ?", vehicleID)
+	}
+	if eventType, ok := filters["event_type"].(string); ok && eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+	if status, ok := filters["status"].(string); ok && status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Preload("FuelEvent").Order("started_at DESC").Offset(offset).Limit(limit).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// StartFuelLevelMonitor periodically analyses recent tank-level telemetry for every reporting vehicle
+func (s *FuelService) StartFuelLevelMonitor(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			now := time.Now()
+			var vehicleIDs []uint
+			if err := s.db.Model(&models.TelemetryLog{}).
+				Where("timestamp >= ? AND fuel_level IS NOT NULL", now.Add(-fuelLevelLookback)).
+				Distinct().Pluck("vehicle_id", &vehicleIDs).Error; err != nil {
+				log.Printf("❌ Failed to list vehicles for fuel level analysis: %v", err)
+				continue
+			}
+			for _, vehicleID := range vehicleIDs {
+				if _, err := s.AnalyzeFuelLevel(vehicleID, now.Add(-fuelLevelLookback), now); err != nil {
+					log.Printf("⚠️ Fuel level analysis failed for vehicle %d: %v", vehicleID, err)
+				}
+			}
+		}
+	}()
+}
+
+// recordLevelChange stores a detected change once, reconciles refuels and raises alerts.
+// Returns a nil event when the change was already recorded.
+func (s *FuelService) recordLevelChange(vehicle *models.Vehicle, change fuelLevelChange, threshold float64) (*models.FuelLevelEvent, *models.FuelAlert, error) {
+	event := &models.FuelLevelEvent{
+		VehicleID:      vehicle.ID,
+		EventType:      change.eventType,
+		StartedAt:      change.startedAt,
+		EndedAt:        change.endedAt,
+		LitresBefore:   round2(change.before),
+		LitresAfter:    round2(change.after),
+		ChangeLitres:   round2(change.after - change.before),
+		ExpectedLitres: round2(change.expected),
+		EngineOn:       change.engineOn,
+	}
+
+	var alert *models.FuelAlert
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// A sliding window can see the same change with a slightly different start
+		var overlapping int64
+		if err := tx.Model(&models.FuelLevelEvent{}).
+			Where("vehicle_id = ? AND event_type = ? AND started_at <= ? AND ended_at >= ?",
+				vehicle.ID, change.eventType, change.endedAt, change.startedAt).
+			Count(&overlapping).Error; err != nil {
+			return err
+		}
+		if overlapping > 0 {
+			event = nil
+			return nil
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			event = nil
+			return nil
+		}
+
+		var err error
+		if change.eventType == models.FuelLevelEventRefuel {
+			alert, err = s.reconcileRefuel(tx, vehicle, event)
+		} else {
+			alert, err = s.raiseTheftAlert(tx, vehicle, event, threshold)
+		}
+		if err != nil {
+			return err
+		}
+		if alert != nil {
+			event.FuelAlertID = &alert.ID
+		}
+		return tx.Save(event).Error
+	})
+	if err != nil || event == nil {
+		return nil, nil, err
+	}
+
+	s.logFuelAlert(vehicle, alert)
+	return event, alert, nil
+}
+
+func (s *FuelService) logFuelAlert(vehicle *models.Vehicle, alert *models.FuelAlert) {
+	if alert == nil {
+		return
+	}
+	_ = s.auditService.LogAction(models.AuditActionFuelAlertCreated, models.AuditSeverityWarning,
+		fmt.Sprintf("%s alert for vehicle %s: %s", alert.AlertType, vehicle.LicensePlate, alert.Description),
+		nil, alert, &models.AuditContext{VehicleID: &vehicle.ID})
+}
+
+// fuelPurchaseTime is when a receipt's fuel was bought, or when it was entered if unknown
+func fuelPurchaseTime(event *models.FuelEvent) time.Time {
+	if event.FueledAt != nil {
+		return *event.FueledAt
+	}
+	return event.CreatedAt
+}
+
+// reconcileRefuel matches a detected refuel to the nearest unmatched FuelEvent by purchase time.
+// Receipts without one are matched by when they were entered, up to receiptEntryDelay later.
+func (s *FuelService) reconcileRefuel(tx *gorm.DB, vehicle *models.Vehicle, event *models.FuelLevelEvent) (*models.FuelAlert, error) {
+	from, to := event.StartedAt.Add(-refuelReconcileWindow), event.EndedAt.Add(refuelReconcileWindow)
+	var candidates []models.FuelEvent
+	if err := tx.Where("vehicle_id = ?", vehicle.ID).
+		Where("(fueled_at BETWEEN ? AND ?) OR (fueled_at IS NULL AND created_at BETWEEN ? AND ?)",
+			from, to, from, event.EndedAt.Add(receiptEntryDelay)).
+		Where("id NOT IN (?)", tx.Model(&models.FuelLevelEvent{}).Select("fuel_event_id").Where("fuel_event_id IS NOT NULL")).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		event.Status = models.FuelLevelStatusUnreported
+		return nil, nil
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return absDuration(fuelPurchaseTime(&candidates[i]).Sub(event.EndedAt)) < absDuration(fuelPurchaseTime(&candidates[j]).Sub(event.EndedAt))
+	})
+	return s.matchRefuelReceipt(tx, vehicle, event, &candidates[0])
+}
+
+// reconcileReceipt matches a newly entered receipt to the nearest refuel the tank sensor already
+// recorded as unreported, so a receipt entered after the analysis ran still reconciles
+func (s *FuelService) reconcileReceipt(receipt *models.FuelEvent) error {
+	purchased := fuelPurchaseTime(receipt)
+	from := purchased.Add(-refuelReconcileWindow)
+	if receipt.FueledAt == nil {
+		from = receipt.CreatedAt.Add(-receiptEntryDelay)
+	}
+
+	var refuels []models.FuelLevelEvent
+	if err := s.db.Where("vehicle_id = ? AND event_type = ? AND status = ? AND fuel_event_id IS NULL",
+		receipt.VehicleID, models.FuelLevelEventRefuel, models.FuelLevelStatusUnreported).
+		Where("ended_at BETWEEN ? AND ?", from, purchased.Add(refuelReconcileWindow)).
+		Find(&refuels).Error; err != nil {
+		return err
+	}
+	if len(refuels) == 0 {
+		return nil
+	}
+	sort.Slice(refuels, func(i, j int) bool {
+		return absDuration(refuels[i].EndedAt.Sub(purchased)) < absDuration(refuels[j].EndedAt.Sub(purchased))
+	})
+	refuel := &refuels[0]
+
+	var vehicle models.Vehicle
+	if err := s.db.First(&vehicle, receipt.VehicleID).Error; err != nil {
+		return err
+	}
+	var alert *models.FuelAlert
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		alert, err = s.matchRefuelReceipt(tx, &vehicle, refuel, receipt)
+		if err != nil {
+			return err
+		}
+		if alert != nil {
+			refuel.FuelAlertID = &alert.ID
+		}
+		return tx.Save(refuel).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.logFuelAlert(&vehicle, alert)
+	return nil
+}
+
+// matchRefuelReceipt records a receipt against a detected refuel and raises a fraud alert when it
+// claims more litres than the tank received
+func (s *FuelService) matchRefuelReceipt(tx *gorm.DB, vehicle *models.Vehicle, event *models.FuelLevelEvent, receipt *models.FuelEvent) (*models.FuelAlert, error) {
+	reported := receipt.Liters
+	event.FuelEventID = &receipt.ID
+	event.ReportedLitres = &reported
+
+	tolerance := math.Max(fuelMinChangeLitres, 0.05*reported)
+	if math.Abs(reported-event.ChangeLitres) <= tolerance {
+		event.Status = models.FuelLevelStatusReconciled
+		return nil, nil
+	}
+	event.Status = models.FuelLevelStatusMismatch
+	if reported < event.ChangeLitres {
+		// More fuel reached the tank than was billed; worth a look but not fraud
+		return nil, nil
+	}
+
+	// The receipt claims more litres than the tank received
+	missing := reported - event.ChangeLitres
+	variance := -missing
+	alert := &models.FuelAlert{
+		VehicleID:      vehicle.ID,
+		DriverID:       receipt.DriverID,
+		TripID:         receipt.TripID,
+		FuelEventID:    &receipt.ID,
+		AlertType:      models.FuelAlertTypeFraudDetected,
+		Severity:       severityForLitres(missing),
+		Title:          "Refuel does not match receipt",
+		Description:    fmt.Sprintf("Receipt %d reports %.1f L but the tank sensor measured %.1f L", receipt.ID, reported, event.ChangeLitres),
+		Message:        fmt.Sprintf("%.1f L billed but not received", missing),
+		DetectedAt:     event.EndedAt,
+		ExpectedValue:  &reported,
+		ActualValue:    &event.ChangeLitres,
+		Variance:       &variance,
+		ThresholdValue: &tolerance,
+		Latitude:       receipt.Latitude,
+		Longitude:      receipt.Longitude,
+		Location:       receipt.Location,
+	}
+	if err := tx.Create(alert).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Model(receipt).Updates(map[string]interface{}{
+		"status":       models.FuelEventStatusSuspicious,
+		"fraud_reason": fmt.Sprintf("Tank sensor measured %.1f L of %.1f L reported", event.ChangeLitres, reported),
+	}).Error; err != nil {
+		return nil, err
+	}
+	return alert, nil
+}
+
+// raiseTheftAlert records a THEFT_SUSPECTED alert for a drop while parked
+func (s *FuelService) raiseTheftAlert(tx *gorm.DB, vehicle *models.Vehicle, event *models.FuelLevelEvent, threshold float64) (*models.FuelAlert, error) {
+	event.Status = models.FuelLevelStatusTheftSuspected
+	missing := event.ExpectedLitres - event.LitresAfter
+	variance := -missing
+
+	state := "engine off"
+	if event.EngineOn {
+		state = "engine idling"
+	}
+	alert := &models.FuelAlert{
+		VehicleID:      vehicle.ID,
+		AlertType:      models.FuelAlertTypeTheftSuspected,
+		Severity:       severityForLitres(missing),
+		Title:          "Suspected fuel theft",
+		Description:    fmt.Sprintf("Tank level fell from %.1f L to %.1f L while parked (%s) between %s and %s; %.1f L expected", event.LitresBefore, event.LitresAfter, state, event.StartedAt.Format(time.RFC3339), event.EndedAt.Format(time.RFC3339), event.ExpectedLitres),
+		Message:        fmt.Sprintf("%.1f L unaccounted for while parked", missing),
+		DetectedAt:     event.EndedAt,
+		ExpectedValue:  &event.ExpectedLitres,
+		ActualValue:    &event.LitresAfter,
+		Variance:       &variance,
+		ThresholdValue: &threshold,
+		Latitude:       vehicle.LastKnownLatitude,
+		Longitude:      vehicle.LastKnownLongitude,
+	}
+	if err := tx.Create(alert).Error; err != nil {
+		return nil, err
+	}
+
+	log.Printf("🚨 Suspected fuel theft: vehicle %d lost %.1f L while parked", vehicle.ID, missing)
+	return alert, nil
+}
+
+// toFuelSamples converts telemetry to litres and classifies each reading as moving or parked
+func toFuelSamples(logs []models.TelemetryLog, capacity float64) []fuelSample {
+	samples := make([]fuelSample, 0, len(logs))
+	for i, entry := range logs {
+		level := math.Min(math.Max(*entry.FuelLevel, 0), 100)
+		sample := fuelSample{
+			at:       entry.Timestamp,
+			litres:   level / 100 * capacity,
+			engineOn: entry.EngineRPM != nil && *entry.EngineRPM > 0,
+			odometer: entry.Odometer,
+		}
+		switch {
+		case entry.Speed != nil:
+			sample.moving = *entry.Speed > stationarySpeedKmh
+		case i > 0 && entry.Odometer != nil && logs[i-1].Odometer != nil:
+			sample.moving = *entry.Odometer-*logs[i-1].Odometer > 0.05
+		}
+		samples = append(samples, sample)
+	}
+	return samples
+}
+
+// parkedEpisodes splits readings into runs where the vehicle stayed in one place. Readings during
+// the settle time after a stop are dropped so sloshing is not mistaken for a change, and an
+// episode ends if the odometer moved across a reporting gap. Comparing levels only within one
+// spot also cancels the constant offset a parked slope adds to the sensor reading.
+func parkedEpisodes(samples []fuelSample) [][]fuelSample {
+	var episodes [][]fuelSample
+	var current []fuelSample
+	var settleUntil time.Time
+	wasMoving := false
+
+	flush := func() {
+		if len(current) >= 3 {
+			episodes = append(episodes, current)
+		}
+		current = nil
+	}
+
+	for _, sample := range samples {
+		if sample.moving {
+			flush()
+			wasMoving = true
+			continue
+		}
+		if wasMoving {
+			settleUntil = sample.at.Add(fuelSettleTime)
+			wasMoving = false
+		}
+		if sample.at.Before(settleUntil) {
+			continue
+		}
+		if len(current) > 0 {
+			prev := current[len(current)-1]
+			if prev.odometer != nil && sample.odometer != nil && *sample.odometer-*prev.odometer > 0.2 {
+				flush()
+			}
+		}
+		current = append(current, sample)
+	}
+	flush()
+	return episodes
+}
+
+// detectLevelChanges finds step changes in a parked episode's smoothed level.
+// Drops are measured against idle burn when the engine was running.
+func detectLevelChanges(episode []fuelSample, threshold, noise float64) []fuelLevelChange {
+	values := make([]float64, len(episode))
+	engineOnCount := 0
+	for i, sample := range episode {
+		values[i] = sample.litres
+		if sample.engineOn {
+			engineOnCount++
+		}
+	}
+	smoothed := rollingMedian(values, fuelSmoothingWindow)
+	engineOn := engineOnCount*2 > len(episode)
+
+	idleBurn := func(from, to time.Time) float64 {
+		if !engineOn {
+			return 0
+		}
+		return to.Sub(from).Hours() * idleBurnLitresPerHour
+	}
+
+	var changes []fuelLevelChange
+	ref, refIdx, lastNear := smoothed[0], 0, 0
+	for i := 1; i < len(smoothed); i++ {
+		delta := smoothed[i] - ref
+		if math.Abs(delta) <= noise {
+			lastNear = i
+		}
+
+		burn := idleBurn(episode[refIdx].at, episode[i].at)
+		isRefuel := delta >= threshold
+		isDrop := delta+burn <= -threshold
+		if !isRefuel && !isDrop {
+			continue
+		}
+
+		// Follow the change until the level stops moving in the same direction
+		direction := 1.0
+		if isDrop {
+			direction = -1
+		}
+		j := i
+		for j+1 < len(smoothed) && (smoothed[j+1]-smoothed[j])*direction > noise {
+			j++
+		}
+		// Wait for readings after the change to confirm the new level
+		if j+2 >= len(smoothed) {
+			break
+		}
+		after := median(smoothed[j+1 : j+3])
+
+		change := fuelLevelChange{
+			startedAt: episode[lastNear].at,
+			endedAt:   episode[j].at,
+			before:    ref,
+			after:     after,
+			engineOn:  engineOn,
+		}
+		if isRefuel {
+			change.eventType = models.FuelLevelEventRefuel
+			change.expected = after
+		} else {
+			change.eventType = models.FuelLevelEventDrop
+			change.expected = ref - idleBurn(episode[refIdx].at, episode[j].at)
+		}
+		changes = append(changes, change)
+
+		ref, refIdx, lastNear = after, j+1, j+1
+		i = j + 1
+	}
+	return changes
+}
+
+// rollingMedian smooths values with a centred median filter
+func rollingMedian(values []float64, window int) []float64 {
+	half := window / 2
+	smoothed := make([]float64, len(values))
+	for i := range values {
+		lo, hi := i-half, i+half+1
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > len(values) {
+			hi = len(values)
+		}
+		smoothed[i] = median(values[lo:hi])
+	}
+	return smoothed
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+func severityForLitres(litres float64) models.AlertSeverity {
+	switch {
+	case litres >= 50:
+		return models.AlertSeverityCritical
+	case litres >= 20:
+		return models.AlertSeverityHigh
+	default:
+		return models.AlertSeverityMedium
+	}
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
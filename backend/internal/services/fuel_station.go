@@ -0,0 +1,574 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fleetflow/backend/internal/models"
+	"googlemaps.github.io/maps"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultFuelStationRadiusKm is the radius searched when the caller gives none
+	defaultFuelStationRadiusKm = 10.0
+
+	// maxFuelStationRadiusKm bounds radius searches
+	maxFuelStationRadiusKm = 100.0
+
+	// defaultRouteCorridorMeters is how far off a trip's route a station may be
+	defaultRouteCorridorMeters = 2000.0
+
+	// maxRouteCorridorMeters bounds along-route searches
+	maxRouteCorridorMeters = 20000.0
+
+	// fuelStationRemoteMeters is the distance from the nearest known station beyond which a fill
+	// counts as being in a remote area
+	fuelStationRemoteMeters = 25000.0
+
+	// metersPerDegreeLat is the length of one degree of latitude
+	metersPerDegreeLat = 111320.0
+)
+
+var (
+	// ErrInvalidFuelStation is returned for stations without a name or with impossible coordinates
+	ErrInvalidFuelStation = errors.New("invalid fuel station")
+	// ErrTripHasNoRoute is returned when an along-route search targets a trip without a usable polyline
+	ErrTripHasNoRoute = errors.New("trip has no route polyline")
+)
+
+// FuelStationSearch narrows radius and along-route searches
+type FuelStationSearch struct {
+	Brand       string
+	FuelType    string // Also selects the price attached to each match; DIESEL when empty
+	Open24Hours bool
+	FleetID     *uint // Marks the fleet's preferred stations
+	Limit       int
+}
+
+// FuelStationImportResult summarizes a bulk station import
+type FuelStationImportResult struct {
+	Created  int                  `json:"created"`
+	Updated  int                  `json:"updated"`
+	Stations []models.FuelStation `json:"stations"`
+}
+
+// normalizeFuelStation tidies free-text fields and checks the station can be placed on a map
+func normalizeFuelStation(station *models.FuelStation) error {
+	station.Name = strings.TrimSpace(station.Name)
+	station.Brand = strings.ToUpper(strings.TrimSpace(station.Brand))
+	station.ExternalID = strings.TrimSpace(station.ExternalID)
+	station.FuelTypes = normalizeStationList(station.FuelTypes)
+	station.Amenities = normalizeStationList(station.Amenities)
+
+	switch {
+	case station.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidFuelStation)
+	case station.Latitude < -90 || station.Latitude > 90 || station.Longitude < -180 || station.Longitude > 180:
+		return fmt.Errorf("%w: coordinates out of range", ErrInvalidFuelStation)
+	case station.Latitude == 0 && station.Longitude == 0:
+		return fmt.Errorf("%w: coordinates are required", ErrInvalidFuelStation)
+	}
+	return nil
+}
+
+// normalizeStationList upper-cases, trims and de-duplicates fuel types and amenities
+func normalizeStationList(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if value != "" && !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// CreateFuelStation registers a station
+func (s *FuelService) CreateFuelStation(station *models.FuelStation, userID uint) error {
+	if err := normalizeFuelStation(station); err != nil {
+		return err
+	}
+	if err := s.db.Create(station).Error; err != nil {
+		return fmt.Errorf("failed to create fuel station: %w", err)
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionConfigChanged, models.AuditSeverityInfo,
+		fmt.Sprintf("Fuel station %d (%s) registered", station.ID, station.Name),
+		nil, station, &models.AuditContext{UserID: &userID})
+	return nil
+}
+
+// UpdateFuelStation saves changes to a station
+func (s *FuelService) UpdateFuelStation(station *models.FuelStation, previous models.FuelStation, userID uint) error {
+	if err := normalizeFuelStation(station); err != nil {
+		return err
+	}
+	if err := s.db.Save(station).Error; err != nil {
+		return fmt.Errorf("failed to update fuel station: %w", err)
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionConfigChanged, models.AuditSeverityInfo,
+		fmt.Sprintf("Fuel station %d (%s) updated", station.ID, station.Name),
+		previous, station, &models.AuditContext{UserID: &userID})
+	return nil
+}
+
+// GetFuelStation gets a station by ID
+func (s *FuelService) GetFuelStation(id uint) (*models.FuelStation, error) {
+	var station models.FuelStation
+	if err := s.db.First(&station, id).Error; err != nil {
+		return nil, err
+	}
+	return &station, nil
+}
+
+// ImportFuelStations bulk-loads a station registry in one transaction. An entry updates the
+// registered station with the same external ID, or with the same name within
+// fuelStationRadiusMeters; otherwise it creates one. Any invalid entry rejects the whole batch.
+func (s *FuelService) ImportFuelStations(stations []models.FuelStation, userID uint) (*FuelStationImportResult, error) {
+	for i := range stations {
+		if err := normalizeFuelStation(&stations[i]); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i+1, err)
+		}
+	}
+
+	existing, err := s.loadFuelStations()
+	if err != nil {
+		return nil, err
+	}
+	byExternalID := make(map[string]int)
+	for i := range existing {
+		if existing[i].ExternalID != "" {
+			byExternalID[existing[i].ExternalID] = i
+		}
+	}
+	// sameStation finds a registered station an entry describes
+	sameStation := func(station *models.FuelStation) *models.FuelStation {
+		if station.ExternalID != "" {
+			if i, ok := byExternalID[station.ExternalID]; ok {
+				return &existing[i]
+			}
+		}
+		for i := range existing {
+			if strings.EqualFold(existing[i].Name, station.Name) &&
+				models.HaversineMeters(existing[i].Latitude, existing[i].Longitude, station.Latitude, station.Longitude) <= fuelStationRadiusMeters {
+				return &existing[i]
+			}
+		}
+		return nil
+	}
+
+	result := &FuelStationImportResult{Stations: make([]models.FuelStation, 0, len(stations))}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for i := range stations {
+			station := stations[i]
+			if match := sameStation(&station); match != nil {
+				station.ID, station.CreatedAt = match.ID, match.CreatedAt
+				if err := tx.Save(&station).Error; err != nil {
+					return fmt.Errorf("failed to update fuel station %d: %w", match.ID, err)
+				}
+				*match = station
+				result.Updated++
+			} else {
+				if err := tx.Create(&station).Error; err != nil {
+					return fmt.Errorf("failed to create fuel station %q: %w", station.Name, err)
+				}
+				existing = append(existing, station)
+				if station.ExternalID != "" {
+					byExternalID[station.ExternalID] = len(existing) - 1
+				}
+				result.Created++
+			}
+			result.Stations = append(result.Stations, station)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionConfigChanged, models.AuditSeverityInfo,
+		fmt.Sprintf("Fuel station registry import: %d created, %d updated", result.Created, result.Updated),
+		nil, map[string]int{"created": result.Created, "updated": result.Updated},
+		&models.AuditContext{UserID: &userID})
+	return result, nil
+}
+
+// ReadFuelStationCSV reads a station registry CSV whose headers use the JSON field names
+// (name, latitude and longitude are required). fuel_types and amenities are separated by
+// semicolons or pipes; is_active defaults to true.
+func ReadFuelStationCSV(r io.Reader) ([]models.FuelStation, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot read header: %v", ErrInvalidFuelStation, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"name", "latitude", "longitude"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", ErrInvalidFuelStation, required)
+		}
+	}
+
+	var stations []models.FuelStation
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidFuelStation, row, err)
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		flag := func(name string, fallback bool) (bool, error) {
+			if value := field(name); value != "" {
+				return strconv.ParseBool(value)
+			}
+			return fallback, nil
+		}
+		list := func(name string) []string {
+			return strings.FieldsFunc(field(name), func(r rune) bool { return r == ';' || r == '|' })
+		}
+
+		lat, latErr := strconv.ParseFloat(field("latitude"), 64)
+		lon, lonErr := strconv.ParseFloat(field("longitude"), 64)
+		if latErr != nil || lonErr != nil {
+			return nil, fmt.Errorf("%w: row %d: invalid coordinates", ErrInvalidFuelStation, row)
+		}
+		open24, err := flag("is_24_hours", false)
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: invalid is_24_hours", ErrInvalidFuelStation, row)
+		}
+		active, err := flag("is_active", true)
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: invalid is_active", ErrInvalidFuelStation, row)
+		}
+
+		stations = append(stations, models.FuelStation{
+			ExternalID: field("external_id"),
+			Name:       field("name"),
+			Brand:      field("brand"),
+			Address:    field("address"),
+			City:       field("city"),
+			State:      field("state"),
+			Pincode:    field("pincode"),
+			Latitude:   lat,
+			Longitude:  lon,
+			Phone:      field("phone"),
+			FuelTypes:  list("fuel_types"),
+			Amenities:  list("amenities"),
+			Is24Hours:  open24,
+			IsActive:   active,
+		})
+	}
+	if len(stations) == 0 {
+		return nil, fmt.Errorf("%w: no stations in file", ErrInvalidFuelStation)
+	}
+	return stations, nil
+}
+
+// activeStationsWithin loads active stations inside a bounding box around the given points,
+// padded by marginMeters. The box is a cheap prefilter; callers measure exact distances.
+func (s *FuelService) activeStationsWithin(points []maps.LatLng, marginMeters float64, search FuelStationSearch) ([]models.FuelStation, error) {
+	minLat, maxLat := math.Inf(1), math.Inf(-1)
+	minLon, maxLon := math.Inf(1), math.Inf(-1)
+	for _, p := range points {
+		minLat, maxLat = math.Min(minLat, p.Lat), math.Max(maxLat, p.Lat)
+		minLon, maxLon = math.Min(minLon, p.Lng), math.Max(maxLon, p.Lng)
+	}
+	latPad := marginMeters / metersPerDegreeLat
+	// Longitude degrees shrink towards the poles; pad for the widest latitude in the box
+	cosLat := math.Max(math.Cos(math.Max(math.Abs(minLat), math.Abs(maxLat))*math.Pi/180), 0.01)
+	lonPad := latPad / cosLat
+
+	query := s.db.Where("is_active = ?", true).
+		Where("latitude BETWEEN ? AND ?", minLat-latPad, maxLat+latPad).
+		Where("longitude BETWEEN ? AND ?", minLon-lonPad, maxLon+lonPad)
+	if search.Brand != "" {
+		query = query.Where("UPPER(brand) = ?", strings.ToUpper(search.Brand))
+	}
+	if search.Open24Hours {
+		query = query.Where("is_24_hours = ?", true)
+	}
+
+	var stations []models.FuelStation
+	if err := query.Find(&stations).Error; err != nil {
+		return nil, err
+	}
+
+	if search.FuelType == "" {
+		return stations, nil
+	}
+	// Stations that do not list their fuel types are assumed to sell the common ones
+	fuelType := normalizeFuelType(search.FuelType)
+	filtered := stations[:0]
+	for _, station := range stations {
+		if len(station.FuelTypes) == 0 || containsString(station.FuelTypes, fuelType) {
+			filtered = append(filtered, station)
+		}
+	}
+	return filtered, nil
+}
+
+// NearbyFuelStations finds active stations within radiusKm of a point, nearest first
+func (s *FuelService) NearbyFuelStations(lat, lon, radiusKm float64, search FuelStationSearch) ([]models.FuelStationMatch, error) {
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return nil, fmt.Errorf("%w: coordinates out of range", ErrInvalidFuelStation)
+	}
+	if radiusKm <= 0 {
+		radiusKm = defaultFuelStationRadiusKm
+	}
+	radiusMeters := math.Min(radiusKm, maxFuelStationRadiusKm) * 1000
+
+	stations, err := s.activeStationsWithin([]maps.LatLng{{Lat: lat, Lng: lon}}, radiusMeters, search)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]models.FuelStationMatch, 0, len(stations))
+	for _, station := range stations {
+		if d := models.HaversineMeters(lat, lon, station.Latitude, station.Longitude); d <= radiusMeters {
+			matches = append(matches, models.FuelStationMatch{FuelStation: station, DistanceMeters: math.Round(d)})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].DistanceMeters < matches[j].DistanceMeters })
+
+	return s.decorateFuelStationMatches(matches, search)
+}
+
+// FuelStationsAlongTrip finds active stations within corridorMeters of a trip's planned route,
+// in the order the route passes them
+func (s *FuelService) FuelStationsAlongTrip(tripID uint, corridorMeters float64, search FuelStationSearch) ([]models.FuelStationMatch, error) {
+	var trip models.Trip
+	if err := s.db.Select("id", "route_polyline").First(&trip, tripID).Error; err != nil {
+		return nil, err
+	}
+	if trip.RoutePolyline == "" {
+		return nil, ErrTripHasNoRoute
+	}
+	path, err := maps.DecodePolyline(trip.RoutePolyline)
+	if err != nil || len(path) == 0 {
+		return nil, fmt.Errorf("%w: cannot decode polyline", ErrTripHasNoRoute)
+	}
+
+	if corridorMeters <= 0 {
+		corridorMeters = defaultRouteCorridorMeters
+	}
+	corridorMeters = math.Min(corridorMeters, maxRouteCorridorMeters)
+
+	stations, err := s.activeStationsWithin(path, corridorMeters, search)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]models.FuelStationMatch, 0, len(stations))
+	for _, station := range stations {
+		distance, offset := distanceToPath(path, station.Latitude, station.Longitude)
+		if distance <= corridorMeters {
+			offset = math.Round(offset)
+			matches = append(matches, models.FuelStationMatch{
+				FuelStation:       station,
+				DistanceMeters:    math.Round(distance),
+				RouteOffsetMeters: &offset,
+			})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return *matches[i].RouteOffsetMeters < *matches[j].RouteOffsetMeters })
+
+	return s.decorateFuelStationMatches(matches, search)
+}
+
+// distanceToPath returns how far a point is from the nearest segment of a path and how far
+// along the path that nearest point lies, both in meters. Each segment is projected onto a
+// local flat plane around the point, which is accurate at corridor scale.
+func distanceToPath(path []maps.LatLng, lat, lon float64) (distance, offset float64) {
+	if len(path) == 1 {
+		return models.HaversineMeters(lat, lon, path[0].Lat, path[0].Lng), 0
+	}
+
+	metersPerDegreeLon := metersPerDegreeLat * math.Cos(lat*math.Pi/180)
+	project := func(p maps.LatLng) (float64, float64) {
+		return (p.Lng - lon) * metersPerDegreeLon, (p.Lat - lat) * metersPerDegreeLat
+	}
+
+	distance = math.Inf(1)
+	travelled := 0.0
+	for i := 1; i < len(path); i++ {
+		ax, ay := project(path[i-1])
+		bx, by := project(path[i])
+		dx, dy := bx-ax, by-ay
+		length := math.Hypot(dx, dy)
+
+		// Fraction along the segment of the point closest to the station (at the origin)
+		t := 0.0
+		if length > 0 {
+			t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/(length*length)))
+		}
+		if d := math.Hypot(ax+t*dx, ay+t*dy); d < distance {
+			distance, offset = d, travelled+t*length
+		}
+		travelled += length
+	}
+	return distance, offset
+}
+
+// decorateFuelStationMatches marks the fleet's preferred stations and attaches each station's
+// latest price for the searched fuel type, then applies the result limit
+func (s *FuelService) decorateFuelStationMatches(matches []models.FuelStationMatch, search FuelStationSearch) ([]models.FuelStationMatch, error) {
+	if search.Limit > 0 && len(matches) > search.Limit {
+		matches = matches[:search.Limit]
+	}
+	if len(matches) == 0 {
+		return matches, nil
+	}
+
+	ids := make([]uint, len(matches))
+	for i := range matches {
+		ids[i] = matches[i].ID
+	}
+
+	if search.FleetID != nil {
+		var preferred []models.FleetPreferredStation
+		if err := s.db.Where("fleet_id = ? AND fuel_station_id IN ?", *search.FleetID, ids).Find(&preferred).Error; err != nil {
+			return nil, err
+		}
+		priorities := make(map[uint]int, len(preferred))
+		for _, entry := range preferred {
+			priorities[entry.FuelStationID] = entry.Priority
+		}
+		for i := range matches {
+			if priority, ok := priorities[matches[i].ID]; ok {
+				matches[i].Preferred, matches[i].PreferredPriority = true, &priority
+			}
+		}
+	}
+
+	var prices []models.FuelPrice
+	if err := s.db.Where("fuel_station_id IN ? AND fuel_type = ?", ids, normalizeFuelType(search.FuelType)).
+		Order("date DESC, id DESC").Find(&prices).Error; err != nil {
+		return nil, err
+	}
+	latest := make(map[uint]*models.FuelPrice, len(prices))
+	for i := range prices {
+		if _, ok := latest[*prices[i].FuelStationID]; !ok {
+			latest[*prices[i].FuelStationID] = &prices[i]
+		}
+	}
+	for i := range matches {
+		matches[i].LatestPrice = latest[matches[i].ID]
+	}
+	return matches, nil
+}
+
+// nearestActiveFuelStation finds the closest active station within radiusMeters. registered
+// reports whether the registry has any active station at all.
+func (s *FuelService) nearestActiveFuelStation(lat, lon, radiusMeters float64) (station *models.FuelStation, distance float64, registered bool, err error) {
+	var count int64
+	if err := s.db.Model(&models.FuelStation{}).Where("is_active = ?", true).Count(&count).Error; err != nil {
+		return nil, 0, false, err
+	}
+	if count == 0 {
+		return nil, 0, false, nil
+	}
+
+	stations, err := s.activeStationsWithin([]maps.LatLng{{Lat: lat, Lng: lon}}, radiusMeters, FuelStationSearch{})
+	if err != nil {
+		return nil, 0, true, err
+	}
+	distance = math.Inf(1)
+	for i := range stations {
+		if d := models.HaversineMeters(lat, lon, stations[i].Latitude, stations[i].Longitude); d <= radiusMeters && d < distance {
+			station, distance = &stations[i], d
+		}
+	}
+	return station, distance, true, nil
+}
+
+// GetFleetPreferredStations lists a fleet's preferred stations, most preferred first
+func (s *FuelService) GetFleetPreferredStations(fleetID uint) ([]models.FleetPreferredStation, error) {
+	if err := s.db.Select("id").First(&models.Fleet{}, fleetID).Error; err != nil {
+		return nil, err
+	}
+	var preferred []models.FleetPreferredStation
+	if err := s.db.Where("fleet_id = ?", fleetID).Preload("FuelStation").
+		Order("priority, id").Find(&preferred).Error; err != nil {
+		return nil, err
+	}
+	return preferred, nil
+}
+
+// SetFleetPreferredStation adds a station to a fleet's preferred list, or updates its priority
+// and notes when it is already there
+func (s *FuelService) SetFleetPreferredStation(entry *models.FleetPreferredStation, userID uint) error {
+	if err := s.db.Select("id").First(&models.Fleet{}, entry.FleetID).Error; err != nil {
+		return err
+	}
+	station, err := s.GetFuelStation(entry.FuelStationID)
+	if err != nil {
+		return err
+	}
+	if !station.IsActive {
+		return fmt.Errorf("%w: station %d is inactive", ErrInvalidFuelStation, station.ID)
+	}
+
+	var existing []models.FleetPreferredStation
+	if err := s.db.Where("fleet_id = ? AND fuel_station_id = ?", entry.FleetID, entry.FuelStationID).
+		Limit(1).Find(&existing).Error; err != nil {
+		return err
+	}
+	var previous interface{}
+	if len(existing) > 0 {
+		previous = existing[0]
+		entry.ID, entry.CreatedAt, entry.CreatedBy = existing[0].ID, existing[0].CreatedAt, existing[0].CreatedBy
+	} else {
+		entry.CreatedBy = &userID
+	}
+	if err := s.db.Omit("FuelStation").Save(entry).Error; err != nil {
+		return fmt.Errorf("failed to save preferred station: %w", err)
+	}
+	entry.FuelStation = station
+
+	_ = s.auditService.LogAction(models.AuditActionConfigChanged, models.AuditSeverityInfo,
+		fmt.Sprintf("Fuel station %d (%s) preferred for fleet %d at priority %d", station.ID, station.Name, entry.FleetID, entry.Priority),
+		previous, entry, &models.AuditContext{UserID: &userID})
+	return nil
+}
+
+// RemoveFleetPreferredStation takes a station off a fleet's preferred list
+func (s *FuelService) RemoveFleetPreferredStation(fleetID, stationID, userID uint) error {
+	var entry models.FleetPreferredStation
+	if err := s.db.Where("fleet_id = ? AND fuel_station_id = ?", fleetID, stationID).First(&entry).Error; err != nil {
+		return err
+	}
+	if err := s.db.Delete(&entry).Error; err != nil {
+		return fmt.Errorf("failed to remove preferred station: %w", err)
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionConfigChanged, models.AuditSeverityInfo,
+		fmt.Sprintf("Fuel station %d removed from fleet %d preferred stations", stationID, fleetID),
+		entry, nil, &models.AuditContext{UserID: &userID})
+	return nil
+}
@@ -0,0 +1,653 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+)
+
+const (
+	fuelRuleBaselineWindow = 30 * 24 * time.Hour // History defining a vehicle's usual consumption
+	fuelRuleRecentWindow   = 7 * 24 * time.Hour  // Window compared against the baseline for efficiency drops
+	fuelRuleRefuelWindow   = 24 * time.Hour      // Window REFUEL_FREQUENCY counts refuels in
+	fuelRuleMinIntervals   = 2                   // Fill-to-fill intervals needed before a baseline is trusted
+	fuelAlertMaxEscalation = 3
+)
+
+var (
+	// ErrFuelThresholdExists is returned when an active rule of the same type already covers the vehicle (or globally)
+	ErrFuelThresholdExists = errors.New("an active threshold of this type already exists for this scope")
+	// ErrInvalidFuelThreshold is returned for unknown types, units that do not fit the type, or non-positive values
+	ErrInvalidFuelThreshold = errors.New("invalid fuel threshold")
+)
+
+// fuelEscalationRoles is who gets notified at each escalation level
+var fuelEscalationRoles = map[int]models.Role{
+	1: models.RoleDispatcher,
+	2: models.RoleOrgAdmin,
+	3: models.RoleAdmin,
+}
+
+// fillInterval is the distance driven between two fills and the litres bought at the second
+type fillInterval struct {
+	endedAt   time.Time
+	km        float64
+	litres    float64
+	eventID   uint
+	driverID  *uint
+	vehicleID uint
+}
+
+// SetNotificationService enables SMS notifications when fuel alerts escalate
+func (s *FuelService) SetNotificationService(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// validateFuelThreshold checks the type, its unit and the value
+func validateFuelThreshold(threshold *models.FuelThreshold) error {
+	units, ok := models.FuelThresholdUnits[threshold.ThresholdType]
+	if !ok {
+		return fmt.Errorf("%w: unknown threshold type %q", ErrInvalidFuelThreshold, threshold.ThresholdType)
+	}
+	if threshold.Unit == "" {
+		threshold.Unit = units[0]
+	}
+	unitOK := false
+	for _, unit := range units {
+		unitOK = unitOK || unit == threshold.Unit
+	}
+	if !unitOK {
+		return fmt.Errorf("%w: %s does not accept unit %q", ErrInvalidFuelThreshold, threshold.ThresholdType, threshold.Unit)
+	}
+	if threshold.Value <= 0 {
+		return fmt.Errorf("%w: value must be positive", ErrInvalidFuelThreshold)
+	}
+	switch threshold.Severity {
+	case "", models.AlertSeverityLow, models.AlertSeverityMedium, models.AlertSeverityHigh, models.AlertSeverityCritical:
+	default:
+		return fmt.Errorf("%w: unknown severity %q", ErrInvalidFuelThreshold, threshold.Severity)
+	}
+	return nil
+}
+
+// checkFuelThresholdScope rejects a second active rule of the same type for the same vehicle (or globally)
+func (s *FuelService) checkFuelThresholdScope(threshold *models.FuelThreshold) error {
+	if threshold.VehicleID != nil {
+		var vehicle models.Vehicle
+		if err := s.db.Select("id").First(&vehicle, *threshold.VehicleID).Error; err != nil {
+			return err
+		}
+	}
+	if !threshold.IsActive {
+		return nil
+	}
+
+	query := s.db.Model(&models.FuelThreshold{}).
+		Where("threshold_type = ? AND is_active = ? AND id <> ?", threshold.ThresholdType, true, threshold.ID)
+	if threshold.VehicleID != nil {
+		query = query.Where("vehicle_id = ?", *threshold.VehicleID)
+	} else {
+		query = query.Where("vehicle_id IS NULL")
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrFuelThresholdExists
+	}
+	return nil
+}
+
+// CreateFuelThreshold adds a global rule or a per-vehicle override
+func (s *FuelService) CreateFuelThreshold(threshold *models.FuelThreshold, userID uint) error {
+	if err := validateFuelThreshold(threshold); err != nil {
+		return err
+	}
+	if err := s.checkFuelThresholdScope(threshold); err != nil {
+		return err
+	}
+	if err := s.db.Create(threshold).Error; err != nil {
+		return fmt.Errorf("failed to create fuel threshold: %w", err)
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionConfigChanged, models.AuditSeverityInfo,
+		fmt.Sprintf("Fuel threshold %d (%s) created", threshold.ID, threshold.ThresholdType),
+		nil, threshold, &models.AuditContext{UserID: &userID})
+	return nil
+}
+
+// UpdateFuelThreshold saves changes to an existing rule
+func (s *FuelService) UpdateFuelThreshold(threshold *models.FuelThreshold, previous models.FuelThreshold, userID uint) error {
+	if err := validateFuelThreshold(threshold); err != nil {
+		return err
+	}
+	if err := s.checkFuelThresholdScope(threshold); err != nil {
+		return err
+	}
+	if err := s.db.Omit("Vehicle").Save(threshold).Error; err != nil {
+		return fmt.Errorf("failed to update fuel threshold: %w", err)
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionConfigChanged, models.AuditSeverityInfo,
+		fmt.Sprintf("Fuel threshold %d (%s) updated", threshold.ID, threshold.ThresholdType),
+		previous, threshold, &models.AuditContext{UserID: &userID})
+	return nil
+}
+
+// DeleteFuelThreshold removes a rule; alerts it raised keep their reference
+func (s *FuelService) DeleteFuelThreshold(id, userID uint) error {
+	threshold, err := s.GetFuelThreshold(id)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Delete(threshold).Error; err != nil {
+		return fmt.Errorf("failed to delete fuel threshold: %w", err)
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionConfigChanged, models.AuditSeverityWarning,
+		fmt.Sprintf("Fuel threshold %d (%s) deleted", threshold.ID, threshold.ThresholdType),
+		threshold, nil, &models.AuditContext{UserID: &userID})
+	return nil
+}
+
+// GetFuelThreshold gets a rule by ID
+func (s *FuelService) GetFuelThreshold(id uint) (*models.FuelThreshold, error) {
+	var threshold models.FuelThreshold
+	if err := s.db.First(&threshold, id).Error; err != nil {
+		return nil, err
+	}
+	return &threshold, nil
+}
+
+// GetFuelThresholds lists rules. A vehicle_id filter of nil selects global rules.
+func (s *FuelService) GetFuelThresholds(filters map[string]interface{}) ([]models.FuelThreshold, error) {
+	query := s.db.Model(&models.FuelThreshold{})
+	if vehicleID, ok := filters["vehicle_id"]; ok {
+		if vehicleID == nil {
+			query = query.Where("vehicle_id IS NULL")
+		} else {
+			query = query.Where("vehicle_id = ?", vehicleID)
+		}
+	}
+	if thresholdType, ok := filters["threshold_type"]; ok {
+		query = query.Where("threshold_type = ?", thresholdType)
+	}
+	if isActive, ok := filters["is_active"]; ok {
+		query = query.Where("is_active = ?", isActive)
+	}
+
+	thresholds := []models.FuelThreshold{}
+	if err := query.Order("threshold_type, vehicle_id, id").Find(&thresholds).Error; err != nil {
+		return nil, err
+	}
+	return thresholds, nil
+}
+
+// EffectiveFuelThresholds returns the active rule of each type that applies to a vehicle:
+// its own override when one exists, the global rule otherwise
+func (s *FuelService) EffectiveFuelThresholds(vehicleID uint) (map[models.FuelThresholdType]models.FuelThreshold, error) {
+	var thresholds []models.FuelThreshold
+	if err := s.db.Where("is_active = ? AND (vehicle_id = ? OR vehicle_id IS NULL)", true, vehicleID).
+		Find(&thresholds).Error; err != nil {
+		return nil, err
+	}
+
+	effective := make(map[models.FuelThresholdType]models.FuelThreshold)
+	for _, threshold := range thresholds {
+		if current, ok := effective[threshold.ThresholdType]; ok && current.VehicleID != nil {
+			continue
+		}
+		effective[threshold.ThresholdType] = threshold
+	}
+	return effective, nil
+}
+
+// loadFillIntervals returns the fill-to-fill intervals of a vehicle ending within [from, to].
+// Rejected events are ignored, and intervals need increasing odometer readings on both fills.
+func (s *FuelService) loadFillIntervals(vehicleID uint, from, to time.Time) ([]fillInterval, error) {
+	var events []models.FuelEvent
+	if err := s.db.Where("vehicle_id = ? AND created_at <= ? AND status <> ?", vehicleID, to, models.FuelEventStatusRejected).
+		Where("(created_at >= ? OR id = (?))", from, s.db.Model(&models.FuelEvent{}).Select("id").
+			Where("vehicle_id = ? AND created_at < ? AND status <> ?", vehicleID, from, models.FuelEventStatusRejected).
+			Order("created_at DESC").Limit(1)).
+		Order("created_at, id").Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	var intervals []fillInterval
+	for i := 1; i < len(events); i++ {
+		prev, event := events[i-1], events[i]
+		if event.CreatedAt.Before(from) || prev.OdometerKm <= 0 || event.OdometerKm <= prev.OdometerKm {
+			continue
+		}
+		intervals = append(intervals, fillInterval{
+			endedAt:   event.CreatedAt,
+			km:        event.OdometerKm - prev.OdometerKm,
+			litres:    event.Liters,
+			eventID:   event.ID,
+			driverID:  event.DriverID,
+			vehicleID: vehicleID,
+		})
+	}
+	return intervals, nil
+}
+
+// sumIntervals totals distance and litres
+func sumIntervals(intervals []fillInterval) (km, litres float64) {
+	for _, interval := range intervals {
+		km += interval.km
+		litres += interval.litres
+	}
+	return km, litres
+}
+
+// consumptionBaseline returns the vehicle's usual litres per km over the baseline window before a time
+func (s *FuelService) consumptionBaseline(vehicleID uint, before time.Time) (float64, bool, error) {
+	intervals, err := s.loadFillIntervals(vehicleID, before.Add(-fuelRuleBaselineWindow), before.Add(-time.Nanosecond))
+	if err != nil {
+		return 0, false, err
+	}
+	km, litres := sumIntervals(intervals)
+	if len(intervals) < fuelRuleMinIntervals || km <= 0 {
+		return 0, false, nil
+	}
+	return litres / km, true, nil
+}
+
+// EvaluateFuelEventRules applies the vehicle's consumption, refuel-frequency and price rules to a new fuel event
+func (s *FuelService) EvaluateFuelEventRules(event *models.FuelEvent) ([]models.FuelAlert, error) {
+	thresholds, err := s.EffectiveFuelThresholds(event.VehicleID)
+	if err != nil || len(thresholds) == 0 {
+		return nil, err
+	}
+
+	var alerts []models.FuelAlert
+	if threshold, ok := thresholds[models.FuelThresholdConsumptionVariance]; ok {
+		intervals, err := s.loadFillIntervals(event.VehicleID, event.CreatedAt, event.CreatedAt)
+		if err != nil {
+			return alerts, err
+		}
+		for _, interval := range intervals {
+			if interval.eventID != event.ID {
+				continue
+			}
+			alert, err := s.checkConsumptionVariance(threshold, []fillInterval{interval}, event.CreatedAt, &event.ID)
+			if err != nil {
+				return alerts, err
+			}
+			if alert != nil {
+				alerts = append(alerts, *alert)
+			}
+		}
+	}
+
+	if threshold, ok := thresholds[models.FuelThresholdRefuelFrequency]; ok {
+		alert, err := s.checkRefuelFrequency(threshold, event.VehicleID, event.DriverID, event.CreatedAt, &event.ID)
+		if err != nil {
+			return alerts, err
+		}
+		if alert != nil {
+			alerts = append(alerts, *alert)
+		}
+	}
+
+	if threshold, ok := thresholds[models.FuelThresholdPriceVariance]; ok {
+		alert, err := s.checkPriceVariance(threshold, event)
+		if err != nil {
+			return alerts, err
+		}
+		if alert != nil {
+			alerts = append(alerts, *alert)
+		}
+	}
+	return alerts, nil
+}
+
+// checkConsumptionVariance compares litres bought over some intervals with what the vehicle's baseline predicts
+func (s *FuelService) checkConsumptionVariance(threshold models.FuelThreshold, intervals []fillInterval, at time.Time, eventID *uint) (*models.FuelAlert, error) {
+	if len(intervals) == 0 {
+		return nil, nil
+	}
+	first := intervals[0]
+	for _, interval := range intervals {
+		if interval.endedAt.Before(first.endedAt) {
+			first = interval
+		}
+	}
+	baseline, ok, err := s.consumptionBaseline(first.vehicleID, first.endedAt)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	km, litres := sumIntervals(intervals)
+	expected := km * baseline
+	variance := (litres - expected) / expected * 100
+	excess := litres - expected
+	breached := variance > threshold.Value
+	if threshold.Unit == models.FuelThresholdUnitLiters {
+		breached = excess > threshold.Value
+	}
+	if !breached {
+		return nil, nil
+	}
+
+	last := intervals[len(intervals)-1]
+	return s.raiseRuleAlert(threshold, first.vehicleID, last.driverID, eventID, at, models.FuelAlertTypeExcessConsumption,
+		"Fuel consumption above threshold",
+		fmt.Sprintf("%.1f L bought for %.0f km where %.1f L were expected (%+.1f%%, %.1f L excess)", litres, km, expected, variance, excess),
+		expected, litres, variance)
+}
+
+// checkRefuelFrequency counts refuels in the 24 hours up to a time
+func (s *FuelService) checkRefuelFrequency(threshold models.FuelThreshold, vehicleID uint, driverID *uint, at time.Time, eventID *uint) (*models.FuelAlert, error) {
+	var count int64
+	if err := s.db.Model(&models.FuelEvent{}).
+		Where("vehicle_id = ? AND created_at > ? AND created_at <= ? AND status <> ?",
+			vehicleID, at.Add(-fuelRuleRefuelWindow), at, models.FuelEventStatusRejected).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if float64(count) <= threshold.Value {
+		return nil, nil
+	}
+
+	return s.raiseRuleAlert(threshold, vehicleID, driverID, eventID, at, models.FuelAlertTypeRefuelFrequency,
+		"Refuelled too often",
+		fmt.Sprintf("%d refuels in 24 hours (limit %.0f)", count, threshold.Value),
+		threshold.Value, float64(count), float64(count)-threshold.Value)
+}
+
+// checkEfficiencyDrop compares km/L over the recent window with the baseline window before it
+func (s *FuelService) checkEfficiencyDrop(threshold models.FuelThreshold, vehicleID uint, at time.Time) (*models.FuelAlert, error) {
+	recentStart := at.Add(-fuelRuleRecentWindow)
+	recent, err := s.loadFillIntervals(vehicleID, recentStart, at)
+	if err != nil || len(recent) == 0 {
+		return nil, err
+	}
+	recentKm, recentLitres := sumIntervals(recent)
+	if recentLitres <= 0 {
+		return nil, nil
+	}
+	recentKmpl := recentKm / recentLitres
+
+	baselineLpk, ok, err := s.consumptionBaseline(vehicleID, recentStart)
+	if err != nil || !ok {
+		return nil, err
+	}
+	baselineKmpl := 1 / baselineLpk
+	drop := (baselineKmpl - recentKmpl) / baselineKmpl * 100
+
+	breached := drop > threshold.Value
+	description := fmt.Sprintf("%.2f km/L over the last 7 days against %.2f km/L in the 30 days before (%.1f%% drop)",
+		recentKmpl, baselineKmpl, drop)
+	if threshold.Unit == models.FuelThresholdUnitKmPerLiter {
+		breached = recentKmpl < threshold.Value
+		description = fmt.Sprintf("%.2f km/L over the last 7 days is below the %.2f km/L floor", recentKmpl, threshold.Value)
+	}
+	if !breached {
+		return nil, nil
+	}
+
+	last := recent[len(recent)-1]
+	return s.raiseRuleAlert(threshold, vehicleID, last.driverID, nil, at, models.FuelAlertTypeEfficiencyDrop,
+		"Fuel efficiency dropped", description, baselineKmpl, recentKmpl, drop)
+}
+
+// raiseRuleAlert creates an alert for a breached rule unless the same rule already has an
+// unresolved alert for the vehicle (or this fuel event) from the last 24 hours
+func (s *FuelService) raiseRuleAlert(threshold models.FuelThreshold, vehicleID uint, driverID *uint, eventID *uint, at time.Time,
+	alertType models.FuelAlertType, title, description string, expected, actual, variance float64) (*models.FuelAlert, error) {
+	var existing int64
+	query := s.db.Model(&models.FuelAlert{}).
+		Where("vehicle_id = ? AND alert_type = ? AND is_resolved = ?", vehicleID, alertType, false)
+	if eventID != nil {
+		query = query.Where("(fuel_event_id = ? OR detected_at > ?)", *eventID, at.Add(-fuelRuleRefuelWindow))
+	} else {
+		query = query.Where("detected_at > ?", at.Add(-fuelRuleRefuelWindow))
+	}
+	if err := query.Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, nil
+	}
+
+	severity := threshold.Severity
+	if severity == "" {
+		severity = models.AlertSeverityMedium
+	}
+	expected, actual, variance = round2(expected), round2(actual), round2(variance)
+	thresholdValue := threshold.Value
+	alert := &models.FuelAlert{
+		AlertType:       alertType,
+		Severity:        severity,
+		Title:           title,
+		Description:     description,
+		Message:         fmt.Sprintf("%s: %s", title, description),
+		DetectedAt:      at,
+		ExpectedValue:   &expected,
+		ActualValue:     &actual,
+		Variance:        &variance,
+		ThresholdValue:  &thresholdValue,
+		VehicleID:       vehicleID,
+		DriverID:        driverID,
+		FuelEventID:     eventID,
+		FuelThresholdID: &threshold.ID,
+	}
+	if err := s.db.Create(alert).Error; err != nil {
+		return nil, fmt.Errorf("failed to create fuel alert: %w", err)
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionFuelAlertCreated, models.AuditSeverityWarning,
+		fmt.Sprintf("%s alert %d raised for vehicle %d by threshold %d", alertType, alert.ID, vehicleID, threshold.ID),
+		nil, alert, nil)
+	return alert, nil
+}
+
+// RunFuelRuleEvaluation applies every vehicle's effective rules to the day before a time:
+// consumption over the day's fills, refuels in the last 24 hours and the weekly efficiency trend
+func (s *FuelService) RunFuelRuleEvaluation(at time.Time) ([]models.FuelAlert, error) {
+	var vehicleIDs []uint
+	if err := s.db.Model(&models.FuelEvent{}).
+		Where("created_at > ? AND created_at <= ?", at.Add(-fuelRuleRecentWindow), at).
+		Distinct().Pluck("vehicle_id", &vehicleIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list vehicles for fuel rules: %w", err)
+	}
+
+	alerts := []models.FuelAlert{}
+	for _, vehicleID := range vehicleIDs {
+		thresholds, err := s.EffectiveFuelThresholds(vehicleID)
+		if err != nil {
+			return alerts, err
+		}
+
+		var raised []*models.FuelAlert
+		if threshold, ok := thresholds[models.FuelThresholdConsumptionVariance]; ok {
+			intervals, err := s.loadFillIntervals(vehicleID, at.Add(-fuelRuleRefuelWindow), at)
+			if err != nil {
+				return alerts, err
+			}
+			alert, err := s.checkConsumptionVariance(threshold, intervals, at, nil)
+			if err != nil {
+				return alerts, err
+			}
+			raised = append(raised, alert)
+		}
+		if threshold, ok := thresholds[models.FuelThresholdRefuelFrequency]; ok {
+			alert, err := s.checkRefuelFrequency(threshold, vehicleID, nil, at, nil)
+			if err != nil {
+				return alerts, err
+			}
+			raised = append(raised, alert)
+		}
+		if threshold, ok := thresholds[models.FuelThresholdEfficiencyDrop]; ok {
+			alert, err := s.checkEfficiencyDrop(threshold, vehicleID, at)
+			if err != nil {
+				return alerts, err
+			}
+			raised = append(raised, alert)
+		}
+
+		for _, alert := range raised {
+			if alert != nil {
+				alerts = append(alerts, *alert)
+			}
+		}
+	}
+	return alerts, nil
+}
+
+// StartFuelRuleEvaluation rebuilds the recent price history and runs the rule evaluation every
+// night at the given local hour
+func (s *FuelService) StartFuelRuleEvaluation(hour int) {
+	go func() {
+		for {
+			now := time.Now()
+			next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+			if !next.After(now) {
+				next = next.AddDate(0, 0, 1)
+			}
+			time.Sleep(time.Until(next))
+
+			// Late verifications change past days, so the whole reference window is rebuilt
+			if rows, err := s.RebuildFuelPriceHistory(time.Now().Add(-fuelPriceWindow), time.Now()); err != nil {
+				log.Printf("❌ Nightly fuel price history rebuild failed: %v", err)
+			} else {
+				log.Printf("⛽ Rebuilt %d fuel price history row(s)", rows)
+			}
+
+			alerts, err := s.RunFuelRuleEvaluation(time.Now())
+			if err != nil {
+				log.Printf("❌ Nightly fuel rule evaluation failed: %v", err)
+				continue
+			}
+			log.Printf("⛽ Nightly fuel rule evaluation raised %d alert(s)", len(alerts))
+		}
+	}()
+}
+
+// ResolveFuelAlert closes an alert, which also stops its escalation
+func (s *FuelService) ResolveFuelAlert(id, userID uint, notes string) (*models.FuelAlert, error) {
+	var alert models.FuelAlert
+	if err := s.db.First(&alert, id).Error; err != nil {
+		return nil, err
+	}
+	if alert.IsResolved {
+		return &alert, nil
+	}
+
+	alert.Resolve(userID, notes)
+	if err := s.db.Model(&alert).Updates(map[string]interface{}{
+		"is_resolved":      true,
+		"resolved_by":      alert.ResolvedBy,
+		"resolved_at":      alert.ResolvedAt,
+		"resolution_notes": notes,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to resolve fuel alert: %w", err)
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionFuelAlertResolved, models.AuditSeverityInfo,
+		fmt.Sprintf("Fuel alert %d resolved by user %d: %s", id, userID, notes),
+		nil, nil, &models.AuditContext{UserID: &userID})
+	return &alert, nil
+}
+
+// escalatedSeverity raises a severity by one step
+func escalatedSeverity(severity models.AlertSeverity) models.AlertSeverity {
+	switch severity {
+	case models.AlertSeverityLow:
+		return models.AlertSeverityMedium
+	case models.AlertSeverityMedium:
+		return models.AlertSeverityHigh
+	default:
+		return models.AlertSeverityCritical
+	}
+}
+
+// EscalateFuelAlerts escalates alerts left unresolved for longer than the timeout since they were
+// raised or last escalated: severity goes up one step and the next tier of staff is notified
+func (s *FuelService) EscalateFuelAlerts(timeout time.Duration) ([]models.FuelAlert, error) {
+	var pending []models.FuelAlert
+	if err := s.db.Where("is_resolved = ? AND escalation_level < ?", false, fuelAlertMaxEscalation).
+		Find(&pending).Error; err != nil {
+		return nil, err
+	}
+
+	escalated := []models.FuelAlert{}
+	for _, alert := range pending {
+		if !alert.ShouldEscalate(int(timeout.Minutes())) {
+			continue
+		}
+
+		now := time.Now()
+		level := alert.EscalationLevel + 1
+		severity := escalatedSeverity(alert.Severity)
+		result := s.db.Model(&models.FuelAlert{}).
+			Where("id = ? AND escalation_level = ? AND is_resolved = ?", alert.ID, alert.EscalationLevel, false).
+			Updates(map[string]interface{}{
+				"escalation_level":  level,
+				"last_escalated_at": now,
+				"severity":          severity,
+				"is_notified":       true,
+				"notification_sent": now,
+			})
+		if result.Error != nil {
+			return escalated, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue // Resolved or escalated concurrently
+		}
+
+		alert.EscalationLevel, alert.LastEscalatedAt, alert.Severity = level, &now, severity
+		alert.IsNotified, alert.NotificationSent = true, &now
+		s.notifyEscalation(&alert)
+		escalated = append(escalated, alert)
+
+		_ = s.auditService.LogAction(models.AuditActionFuelAlertEscalated, models.AuditSeverityWarning,
+			fmt.Sprintf("Fuel alert %d escalated to level %d (%s)", alert.ID, level, severity), nil, nil, nil)
+	}
+	return escalated, nil
+}
+
+// notifyEscalation texts the active staff of the tier matching the alert's escalation level
+func (s *FuelService) notifyEscalation(alert *models.FuelAlert) {
+	if s.notifications == nil {
+		return
+	}
+	role, ok := fuelEscalationRoles[alert.EscalationLevel]
+	if !ok {
+		return
+	}
+
+	var phones []string
+	if err := s.db.Model(&models.UserAccount{}).Where("role = ? AND is_active = ?", role, true).
+		Pluck("phone", &phones).Error; err != nil {
+		log.Printf("⚠️ Failed to load %s contacts for fuel alert %d: %v", role, alert.ID, err)
+		return
+	}
+	message := fmt.Sprintf("⛽ ESCALATED (level %d, %s): fuel alert %d on vehicle %d is unresolved. %s",
+		alert.EscalationLevel, alert.Severity, alert.ID, alert.VehicleID, alert.Message)
+	for _, phone := range phones {
+		if err := s.notifications.SendSMS(phone, message); err != nil {
+			log.Printf("⚠️ Failed to notify %s about fuel alert %d: %v", phone, alert.ID, err)
+		}
+	}
+}
+
+// StartFuelAlertEscalation checks for unresolved alerts to escalate every interval
+func (s *FuelService) StartFuelAlertEscalation(interval, timeout time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := s.EscalateFuelAlerts(timeout); err != nil {
+				log.Printf("❌ Fuel alert escalation failed: %v", err)
+			}
+		}
+	}()
+}
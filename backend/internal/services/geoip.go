@@ -0,0 +1,115 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GeoIPLocation is the approximate position of a client address
+type GeoIPLocation struct {
+	Latitude  float64
+	Longitude float64
+	City      string
+	Country   string
+}
+
+// GeoIPLocator resolves client IP addresses to a position on the server, so login
+// locations never depend on what the caller claims
+type GeoIPLocator interface {
+	Locate(ipAddress string) (*GeoIPLocation, bool)
+}
+
+type ipRange struct {
+	start, end netip.Addr
+	location   GeoIPLocation
+}
+
+// IPRangeLocator looks addresses up in a table of IP ranges, as published in the
+// city-level CSV exports of DB-IP and IP2Location LITE
+type IPRangeLocator struct {
+	ranges []ipRange // sorted by start address
+}
+
+// LoadIPRangeLocator reads an IP range table from a CSV file
+func LoadIPRangeLocator(path string) (*IPRangeLocator, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return NewIPRangeLocator(file)
+}
+
+// NewIPRangeLocator parses CSV rows of start_ip,end_ip,latitude,longitude[,city[,country]].
+// Rows whose addresses do not parse, such as a header row, are skipped.
+func NewIPRangeLocator(r io.Reader) (*IPRangeLocator, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	locator := &IPRangeLocator{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("geoip table line %d: %w", line, err)
+		}
+		if len(record) < 4 {
+			continue
+		}
+		start, startErr := netip.ParseAddr(strings.TrimSpace(record[0]))
+		end, endErr := netip.ParseAddr(strings.TrimSpace(record[1]))
+		if startErr != nil || endErr != nil {
+			continue
+		}
+		start, end = start.Unmap(), end.Unmap()
+		if start.BitLen() != end.BitLen() || end.Less(start) {
+			return nil, fmt.Errorf("geoip table line %d: invalid range %s-%s", line, start, end)
+		}
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		lng, lngErr := strconv.ParseFloat(strings.TrimSpace(record[3]), 64)
+		if latErr != nil || lngErr != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			return nil, fmt.Errorf("geoip table line %d: invalid coordinates", line)
+		}
+
+		entry := ipRange{start: start, end: end, location: GeoIPLocation{Latitude: lat, Longitude: lng}}
+		if len(record) > 4 {
+			entry.location.City = strings.TrimSpace(record[4])
+		}
+		if len(record) > 5 {
+			entry.location.Country = strings.TrimSpace(record[5])
+		}
+		locator.ranges = append(locator.ranges, entry)
+	}
+
+	sort.Slice(locator.ranges, func(i, j int) bool {
+		return locator.ranges[i].start.Less(locator.ranges[j].start)
+	})
+	return locator, nil
+}
+
+// Locate returns the position of the range containing the address
+func (l *IPRangeLocator) Locate(ipAddress string) (*GeoIPLocation, bool) {
+	addr, err := netip.ParseAddr(ipAddress)
+	if err != nil {
+		return nil, false
+	}
+	addr = addr.Unmap()
+
+	// The last range starting at or before the address is the only one that can contain it
+	i := sort.Search(len(l.ranges), func(i int) bool { return addr.Less(l.ranges[i].start) }) - 1
+	if i < 0 || l.ranges[i].end.Less(addr) {
+		return nil, false
+	}
+	location := l.ranges[i].location
+	return &location, true
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPRangeLocator(t *testing.T) {
+	locator, err := NewIPRangeLocator(strings.NewReader(
+		"start_ip,end_ip,latitude,longitude,city,country\n" +
+			"198.51.100.0,198.51.100.255,51.5074,-0.1278,London,GB\n" +
+			"203.0.113.0,203.0.113.127,19.0760,72.8777,Mumbai,IN\n" +
+			"2001:db8::,2001:db8::ffff,52.5200,13.4050,Berlin,DE\n"))
+	require.NoError(t, err)
+
+	location, ok := locator.Locate("203.0.113.42")
+	require.True(t, ok)
+	assert.Equal(t, "Mumbai", location.City)
+	assert.InDelta(t, 72.8777, location.Longitude, 0.0001)
+
+	// IPv4-mapped IPv6 addresses resolve like their IPv4 form
+	location, ok = locator.Locate("::ffff:198.51.100.7")
+	require.True(t, ok)
+	assert.Equal(t, "GB", location.Country)
+
+	location, ok = locator.Locate("2001:db8::1")
+	require.True(t, ok)
+	assert.Equal(t, "Berlin", location.City)
+
+	// Gaps between ranges and malformed addresses are unknown
+	for _, ip := range []string{"203.0.113.200", "10.0.0.1", "2001:db9::1", "not-an-ip", ""} {
+		_, ok := locator.Locate(ip)
+		assert.False(t, ok, ip)
+	}
+
+	_, err = NewIPRangeLocator(strings.NewReader("203.0.113.0,203.0.113.255,95.0,72.8\n"))
+	assert.Error(t, err)
+	_, err = NewIPRangeLocator(strings.NewReader("203.0.113.255,203.0.113.0,19.0,72.8\n"))
+	assert.Error(t, err)
+}
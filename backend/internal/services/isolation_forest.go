@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// eulerGamma is the Euler–Mascheroni constant used in the average path length of unsuccessful BST searches
+const eulerGamma = 0.5772156649015329
+
+// IsolationForest is an ensemble of random isolation trees (Liu, Ting & Zhou, 2008).
+// Anomalies are isolated in fewer random splits than normal points, so short average
+// path lengths translate into scores close to 1.
+type IsolationForest struct {
+	Trees      []*IsolationNode `json:"trees"`
+	SampleSize int              `json:"sample_size"` // Sub-sample size ψ each tree was grown on
+	Features   int              `json:"features"`
+}
+
+// IsolationNode is an internal split or, when Left is nil, a leaf. Size counts the training points that reached it.
+type IsolationNode struct {
+	Feature int            `json:"f,omitempty"`
+	Split   float64        `json:"s,omitempty"`
+	Left    *IsolationNode `json:"l,omitempty"`
+	Right   *IsolationNode `json:"r,omitempty"`
+	Size    int            `json:"n,omitempty"`
+}
+
+// TrainIsolationForest grows trees on random sub-samples of data. Every row must have the same width.
+func TrainIsolationForest(data [][]float64, trees, sampleSize int, rng *rand.Rand) (*IsolationForest, error) {
+	if len(data) < 2 {
+		return nil, errors.New("isolation forest needs at least two samples")
+	}
+	width := len(data[0])
+	for _, row := range data {
+		if len(row) != width {
+			return nil, errors.New("isolation forest samples have inconsistent widths")
+		}
+	}
+	if sampleSize > len(data) || sampleSize <= 0 {
+		sampleSize = len(data)
+	}
+
+	heightLimit := int(math.Ceil(math.Log2(float64(sampleSize))))
+	forest := &IsolationForest{SampleSize: sampleSize, Features: width}
+	for t := 0; t < trees; t++ {
+		sample := make([][]float64, sampleSize)
+		for i, idx := range rng.Perm(len(data))[:sampleSize] {
+			sample[i] = data[idx]
+		}
+		forest.Trees = append(forest.Trees, growIsolationTree(sample, 0, heightLimit, rng))
+	}
+	return forest, nil
+}
+
+// growIsolationTree splits on a random non-constant feature at a random value until the
+// point is isolated or the height limit is reached
+func growIsolationTree(sample [][]float64, depth, heightLimit int, rng *rand.Rand) *IsolationNode {
+	if depth >= heightLimit || len(sample) <= 1 {
+		return &IsolationNode{Size: len(sample)}
+	}
+
+	var candidates []int
+	for f := range sample[0] {
+		lo, hi := featureRange(sample, f)
+		if hi > lo {
+			candidates = append(candidates, f)
+		}
+	}
+	if len(candidates) == 0 {
+		return &IsolationNode{Size: len(sample)}
+	}
+
+	feature := candidates[rng.Intn(len(candidates))]
+	lo, hi := featureRange(sample, feature)
+	split := lo + rng.Float64()*(hi-lo)
+
+	var left, right [][]float64
+	for _, row := range sample {
+		if row[feature] < split {
+			left = append(left, row)
+		} else {
+			right = append(right, row)
+		}
+	}
+	return &IsolationNode{
+		Size:    len(sample),
+		Feature: feature,
+		Split:   split,
+		Left:    growIsolationTree(left, depth+1, heightLimit, rng),
+		Right:   growIsolationTree(right, depth+1, heightLimit, rng),
+	}
+}
+
+func featureRange(sample [][]float64, feature int) (lo, hi float64) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, row := range sample {
+		lo = math.Min(lo, row[feature])
+		hi = math.Max(hi, row[feature])
+	}
+	return lo, hi
+}
+
+// averagePathLength is c(n), the expected path length of an unsuccessful search in a BST of n points
+func averagePathLength(n int) float64 {
+	switch {
+	case n <= 1:
+		return 0
+	case n == 2:
+		return 1
+	}
+	harmonic := math.Log(float64(n-1)) + eulerGamma
+	return 2*harmonic - 2*float64(n-1)/float64(n)
+}
+
+// Score returns the anomaly score in (0, 1]; values well above 0.5 are anomalous
+func (f *IsolationForest) Score(x []float64) float64 {
+	score, _ := f.Explain(x)
+	return score
+}
+
+// Explain scores x and attributes the score to features. Each split on the isolation path
+// credits its feature with log2(parent/child) of the training points it separated x from, so a
+// feature that cuts x off from most of the sample in one split dominates one that merely
+// halves it. Contributions sum to 1 (or are all zero when no split was taken).
+func (f *IsolationForest) Explain(x []float64) (float64, []float64) {
+	contributions := make([]float64, f.Features)
+	if len(f.Trees) == 0 {
+		return 0, contributions
+	}
+
+	var totalPath float64
+	for _, tree := range f.Trees {
+		node, depth := tree, 0
+		for node.Left != nil {
+			parent := node
+			if x[node.Feature] < node.Split {
+				node = node.Left
+			} else {
+				node = node.Right
+			}
+			contributions[parent.Feature] += math.Log2(float64(parent.Size) / math.Max(float64(node.Size), 1))
+			depth++
+		}
+		totalPath += float64(depth) + averagePathLength(node.Size)
+	}
+
+	var sum float64
+	for _, c := range contributions {
+		sum += c
+	}
+	if sum > 0 {
+		for i := range contributions {
+			contributions[i] /= sum
+		}
+	}
+
+	meanPath := totalPath / float64(len(f.Trees))
+	norm := averagePathLength(f.SampleSize)
+	if norm == 0 {
+		return 0, contributions
+	}
+	return math.Pow(2, -meanPath/norm), contributions
+}
+
+// scoreQuantile returns the q-th quantile (0..1) of scores
+func scoreQuantile(scores []float64, q float64) float64 {
+	if len(scores) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), scores...)
+	sort.Float64s(sorted)
+	idx := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIsolationForest checks that outliers score above the inlier cluster and that the
+// feature responsible for the outlier gets the largest contribution
+func TestIsolationForest(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var data [][]float64
+	for i := 0; i < 500; i++ {
+		data = append(data, []float64{50 + rng.NormFloat64()*5, 100 + rng.NormFloat64()*2, 12 + rng.NormFloat64()*3})
+	}
+
+	forest, err := TrainIsolationForest(data, 100, 256, rand.New(rand.NewSource(2)))
+	require.NoError(t, err)
+	assert.Len(t, forest.Trees, 100)
+
+	inlier := forest.Score([]float64{50, 100, 12})
+	outlier, contributions := forest.Explain([]float64{50, 100, 40})
+	assert.Less(t, inlier, 0.5)
+	assert.Less(t, inlier+0.1, outlier)
+	assert.Greater(t, contributions[2], 0.5)
+	assert.InDelta(t, 1, contributions[0]+contributions[1]+contributions[2], 1e-9)
+
+	// The serialized forest scores identically
+	encoded, err := json.Marshal(forest)
+	require.NoError(t, err)
+	var decoded IsolationForest
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, outlier, decoded.Score([]float64{50, 100, 40}))
+
+	assert.InDelta(t, 0.5, scoreQuantile([]float64{0.1, 0.5, 0.9, 0.3}, 0.5), 0.2)
+	_, err = TrainIsolationForest([][]float64{{1}}, 10, 256, rng)
+	assert.Error(t, err)
+}
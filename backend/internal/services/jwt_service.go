@@ -1,7 +1,10 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/fleetflow/backend/internal/config"
@@ -17,34 +20,60 @@ type JWTClaims struct {
 	Phone    string      `json:"phone"`
 	Role     models.Role `json:"role"`
 	DriverID *uint       `json:"driver_id,omitempty"`
+	// SessionID ties the access token to a UserSession so revoking the session takes effect immediately
+	SessionID uint `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
+const (
+	accessTokenIssuer = "fleetflow"
+	mfaTokenIssuer    = "fleetflow-mfa"
+
+	// mfaTokenExpiry bounds how long a user has to enter their second factor after the password
+	mfaTokenExpiry = 5 * time.Minute
+)
+
 // JWTService handles JWT operations
 type JWTService struct {
-	config *config.Config
-	db     *gorm.DB
+	config       *config.Config
+	db           *gorm.DB
+	auditService *AuditService
+
+	settingsMu       sync.Mutex
+	settingsSource   func() *models.SecuritySettings
+	settingsCache    *models.SecuritySettings
+	settingsCachedAt time.Time
+
+	sessionsMu sync.Mutex
+	sessions   map[uint]*cachedSession
 }
 
 // NewJWTService creates a new JWT service
-func NewJWTService(cfg *config.Config, db *gorm.DB) *JWTService {
+func NewJWTService(cfg *config.Config, db *gorm.DB, auditService *AuditService) *JWTService {
 	return &JWTService{
-		config: cfg,
-		db:     db,
+		config:       cfg,
+		db:           db,
+		auditService: auditService,
 	}
 }
 
 // GenerateToken generates a new JWT token for a user
 func (j *JWTService) GenerateToken(user *models.UserAccount) (string, error) {
+	return j.GenerateSessionToken(user, 0)
+}
+
+// GenerateSessionToken generates an access token bound to a session
+func (j *JWTService) GenerateSessionToken(user *models.UserAccount, sessionID uint) (string, error) {
 	claims := JWTClaims{
-		UserID:   user.ID,
-		Phone:    user.Phone,
-		Role:     user.Role,
-		DriverID: user.DriverID,
+		UserID:    user.ID,
+		Phone:     user.Phone,
+		Role:      user.Role,
+		DriverID:  user.DriverID,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.config.JWTExpirationTime)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "fleetflow",
+			Issuer:    accessTokenIssuer,
 			Subject:   user.Phone,
 		},
 	}
@@ -53,8 +82,14 @@ func (j *JWTService) GenerateToken(user *models.UserAccount) (string, error) {
 	return token.SignedString([]byte(j.config.JWTSecret))
 }
 
-// GenerateRefreshToken generates a refresh token
+// GenerateRefreshToken starts a session without device information and returns its refresh token
 func (j *JWTService) GenerateRefreshToken(userID uint) (*models.RefreshToken, error) {
+	_, refreshToken, err := j.CreateSession(userID, nil)
+	return refreshToken, err
+}
+
+// issueRefreshToken signs and stores a refresh token in the given session family
+func (j *JWTService) issueRefreshToken(tx *gorm.DB, userID uint, sessionID *uint) (*models.RefreshToken, error) {
 	// Generate a random token with UUID for entropy to prevent UNIQUE constraints in fast tests
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
 		ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.config.RefreshTokenExpiry)),
@@ -71,12 +106,13 @@ func (j *JWTService) GenerateRefreshToken(userID uint) (*models.RefreshToken, er
 	refreshToken := &models.RefreshToken{
 		Token:     tokenString,
 		UserID:    userID,
+		SessionID: sessionID,
 		ExpiresAt: time.Now().Add(j.config.RefreshTokenExpiry),
 		Revoked:   false,
 	}
 
 	// Save to database
-	if err := j.db.Create(refreshToken).Error; err != nil {
+	if err := tx.Create(refreshToken).Error; err != nil {
 		return nil, err
 	}
 
@@ -85,9 +121,10 @@ func (j *JWTService) GenerateRefreshToken(userID uint) (*models.RefreshToken, er
 
 // ValidateToken validates a JWT token and returns the claims
 func (j *JWTService) ValidateToken(tokenString string) (*JWTClaims, error) {
+	// Only access tokens are accepted here; refresh and MFA challenge tokens use other issuers
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(j.config.JWTSecret), nil
-	})
+	}, jwt.WithIssuer(accessTokenIssuer))
 
 	if err != nil {
 		return nil, err
@@ -100,12 +137,16 @@ func (j *JWTService) ValidateToken(tokenString string) (*JWTClaims, error) {
 	return nil, jwt.ErrSignatureInvalid
 }
 
-// RefreshToken refreshes an access token using a refresh token
-func (j *JWTService) RefreshToken(refreshTokenString string) (string, *models.RefreshToken, error) {
-	// Find refresh token in database
+// RefreshToken rotates a refresh token and issues a new access token.
+// Presenting an already-rotated token revokes the whole session, as does presenting it from another device.
+func (j *JWTService) RefreshToken(refreshTokenString string, sc *SessionContext) (string, *models.RefreshToken, error) {
+	// Find refresh token in database, including revoked ones so reuse can be detected
 	var refreshToken models.RefreshToken
-	if err := j.db.Where("token = ? AND revoked = false", refreshTokenString).First(&refreshToken).Error; err != nil {
-		return "", nil, fmt.Errorf("refresh token not found or revoked: %w", err)
+	if err := j.db.Where("token = ?", refreshTokenString).First(&refreshToken).Error; err != nil {
+		return "", nil, fmt.Errorf("refresh token not found: %w", err)
+	}
+	if refreshToken.Revoked {
+		return "", nil, j.handleRefreshTokenReuse(&refreshToken, sc)
 	}
 
 	// Check if refresh token is expired
@@ -113,23 +154,81 @@ func (j *JWTService) RefreshToken(refreshTokenString string) (string, *models.Re
 		return "", nil, fmt.Errorf("refresh token expired at %v (current time %v)", refreshToken.ExpiresAt, time.Now())
 	}
 
+	// Tokens issued before sessions existed are moved into a new session on first refresh
+	var session models.UserSession
+	if refreshToken.SessionID == nil {
+		newSession, _, err := j.CreateSession(refreshToken.UserID, sc)
+		if err != nil {
+			return "", nil, err
+		}
+		_ = j.db.Model(&refreshToken).Update("session_id", newSession.ID).Error
+		refreshToken.SessionID = &newSession.ID
+		session = *newSession
+	} else if err := j.db.First(&session, *refreshToken.SessionID).Error; err != nil {
+		return "", nil, ErrSessionNotFound
+	}
+
+	if !session.IsActive() {
+		return "", nil, ErrSessionRevoked
+	}
+	if session.IsIdle(j.sessionIdleTimeout()) {
+		_ = j.revokeSession(&session, models.SessionRevokedIdleTimeout)
+		return "", nil, ErrSessionExpired
+	}
+	if session.DeviceFingerprint != "" && sc.Fingerprint() != session.DeviceFingerprint {
+		_ = j.revokeSession(&session, models.SessionRevokedDeviceMismatch)
+		j.logSessionSecurityEvent("REFRESH_TOKEN_DEVICE_MISMATCH", models.AuditSeverityCritical, refreshToken.UserID,
+			fmt.Sprintf("Refresh token for session %d of user ID %d presented from a different device; session revoked",
+				session.ID, refreshToken.UserID), sc)
+		return "", nil, ErrDeviceMismatch
+	}
+
 	// Get user
 	var user models.UserAccount
 	if err := j.db.First(&user, refreshToken.UserID).Error; err != nil {
 		return "", nil, err
 	}
 
-	// Generate new access token
-	accessToken, err := j.GenerateToken(&user)
+	// Rotate: only one caller can win the revoke, a concurrent second use counts as reuse
+	var newRefreshToken *models.RefreshToken
+	err := j.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked = ?", refreshToken.ID, false).
+			Update("revoked", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReuse
+		}
+
+		var err error
+		newRefreshToken, err = j.issueRefreshToken(tx, user.ID, &session.ID)
+		if err != nil {
+			return err
+		}
+		// A session in use lives as long as its newest refresh token
+		return tx.Model(&session).Update("expires_at", newRefreshToken.ExpiresAt).Error
+	})
+	if errors.Is(err, ErrRefreshTokenReuse) {
+		return "", nil, j.handleRefreshTokenReuse(&refreshToken, sc)
+	}
 	if err != nil {
 		return "", nil, err
 	}
 
-	// Generate new refresh token and revoke old one
-	refreshToken.Revoked = true
-	_ = j.db.Save(&refreshToken)
+	updates := map[string]interface{}{"last_used_at": time.Now()}
+	if sc != nil && sc.IPAddress != "" {
+		updates["last_ip_address"] = sc.IPAddress
+	}
+	if sc != nil && sc.Location != "" {
+		updates["location"] = sc.Location
+	}
+	_ = j.db.Model(&session).Updates(updates).Error
+	j.forgetSessions(func(cached *models.UserSession) bool { return cached.ID == session.ID })
 
-	newRefreshToken, err := j.GenerateRefreshToken(user.ID)
+	// Generate new access token
+	accessToken, err := j.GenerateSessionToken(&user, session.ID)
 	if err != nil {
 		return "", nil, err
 	}
@@ -137,16 +236,69 @@ func (j *JWTService) RefreshToken(refreshTokenString string) (string, *models.Re
 	return accessToken, newRefreshToken, nil
 }
 
-// RevokeRefreshToken revokes a refresh token
+// RevokeRefreshToken revokes a refresh token together with the rest of its session
 func (j *JWTService) RevokeRefreshToken(tokenString string) error {
-	return j.db.Model(&models.RefreshToken{}).
-		Where("token = ?", tokenString).
-		Update("revoked", true).Error
+	var refreshToken models.RefreshToken
+	if err := j.db.Where("token = ?", tokenString).First(&refreshToken).Error; err != nil {
+		return err
+	}
+	if refreshToken.SessionID == nil {
+		return j.db.Model(&refreshToken).Update("revoked", true).Error
+	}
+
+	var session models.UserSession
+	if err := j.db.First(&session, *refreshToken.SessionID).Error; err != nil {
+		return err
+	}
+	return j.revokeSession(&session, models.SessionRevokedLogout)
 }
 
-// RevokeAllUserTokens revokes all refresh tokens for a user
+// RevokeAllUserTokens revokes all sessions and refresh tokens for a user
 func (j *JWTService) RevokeAllUserTokens(userID uint) error {
-	return j.db.Model(&models.RefreshToken{}).
-		Where("user_id = ?", userID).
-		Update("revoked", true).Error
+	now := time.Now()
+	defer j.forgetSessions(func(cached *models.UserSession) bool { return cached.UserID == userID })
+	return j.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserSession{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Updates(map[string]interface{}{"revoked_at": now, "revoked_reason": models.SessionRevokedAll}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("user_id = ?", userID).
+			Update("revoked", true).Error
+	})
+}
+
+// GenerateMFAToken issues a short-lived token proving the password step succeeded
+func (j *JWTService) GenerateMFAToken(userID uint) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(mfaTokenExpiry)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		Issuer:    mfaTokenIssuer,
+		Subject:   strconv.FormatUint(uint64(userID), 10),
+		ID:        uuid.New().String(),
+	})
+	return token.SignedString([]byte(j.config.JWTSecret))
+}
+
+// ValidateMFAToken validates an MFA challenge token and returns the user ID it was issued for
+func (j *JWTService) ValidateMFAToken(tokenString string) (uint, error) {
+	claims := &jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(j.config.JWTSecret), nil
+	}, jwt.WithIssuer(mfaTokenIssuer))
+	if err != nil || !token.Valid {
+		return 0, ErrInvalidToken
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return uint(userID), nil
+}
+
+// MFATokenExpiry returns the lifetime of MFA challenge tokens
+func (j *JWTService) MFATokenExpiry() time.Duration {
+	return mfaTokenExpiry
 }
@@ -1,9 +1,9 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"math"
 	"time"
 
 	"github.com/fleetflow/backend/internal/models"
@@ -14,6 +14,7 @@ import (
 type LocationService struct {
 	db           *gorm.DB
 	auditService *AuditService
+	speedLimits  *SpeedLimitService
 }
 
 // NewLocationService creates a new location service
@@ -24,6 +25,11 @@ func NewLocationService(db *gorm.DB, auditService *AuditService) *LocationServic
 	}
 }
 
+// SetSpeedLimitService sets the provider of road and geofence speed limits
+func (s *LocationService) SetSpeedLimitService(speedLimits *SpeedLimitService) {
+	s.speedLimits = speedLimits
+}
+
 // SaveLocationPing saves a location ping with validation and processing
 func (s *LocationService) SaveLocationPing(ping *models.LocationPing) error {
 	// Validate coordinates
@@ -159,7 +165,7 @@ func (s *LocationService) GetLocationHistory(vehicleID uint, startTime, endTime
 
 	for i := 1; i < len(pings); i++ {
 		// Calculate distance between consecutive points
-		distance := s.calculateDistance(
+		distance := models.HaversineMeters(
 			pings[i-1].Latitude, pings[i-1].Longitude,
 			pings[i].Latitude, pings[i].Longitude,
 		)
@@ -436,8 +442,7 @@ func (s *LocationService) checkSpeedViolations(ping *models.LocationPing) {
 
 	speed := *ping.Speed
 
-	// Get speed limits for this area (mock implementation)
-	speedLimit := s.getSpeedLimitForLocation(ping.Latitude, ping.Longitude)
+	speedLimit := s.getSpeedLimitForLocation(ping)
 
 	if speed > speedLimit {
 		s.createSpeedViolationAlert(ping, speed, speedLimit)
@@ -457,7 +462,7 @@ func (s *LocationService) checkIdleTimeViolations(ping *models.LocationPing) {
 	}
 
 	// Calculate distance moved
-	distance := s.calculateDistance(lastPing.Latitude, lastPing.Longitude, ping.Latitude, ping.Longitude)
+	distance := models.HaversineMeters(lastPing.Latitude, lastPing.Longitude, ping.Latitude, ping.Longitude)
 	timeDiff := ping.CreatedAt.Sub(lastPing.CreatedAt)
 
 	// If vehicle hasn't moved much in a long time, it might be idling
@@ -493,7 +498,7 @@ func (s *LocationService) isPointInCircle(lat, lon float64, geofence *models.Geo
 	centerLon := geofence.Coordinates[1]
 	radius := geofence.Coordinates[2] // in meters
 
-	distance := s.calculateDistance(lat, lon, centerLat, centerLon)
+	distance := models.HaversineMeters(lat, lon, centerLat, centerLon)
 	return distance <= radius
 }
 
@@ -532,22 +537,6 @@ func (s *LocationService) isPointInRectangle(lat, lon float64, geofence *models.
 	return lat >= minLat && lat <= maxLat && lon >= minLon && lon <= maxLon
 }
 
-// Calculate distance between two points using Haversine formula
-func (s *LocationService) calculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
-	const R = 6371000 // Earth's radius in meters
-
-	dLat := (lat2 - lat1) * math.Pi / 180
-	dLon := (lon2 - lon1) * math.Pi / 180
-
-	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
-		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*
-			math.Sin(dLon/2)*math.Sin(dLon/2)
-
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-
-	return R * c
-}
-
 // Alert creation functions
 func (s *LocationService) createGeofenceAlert(ping *models.LocationPing, geofence *models.Geofence, alertType string) {
 	vehicleID := uint(0)
@@ -656,14 +645,28 @@ func (s *LocationService) calculateRouteDeviation(ping *models.LocationPing, tri
 	// Mock implementation - in production would calculate actual deviation from planned route
 	// For now, calculate distance from dropoff location as rough approximation
 
-	return s.calculateDistance(ping.Latitude, ping.Longitude,
+	return models.HaversineMeters(ping.Latitude, ping.Longitude,
 		trip.DropoffLatitude, trip.DropoffLongitude)
 }
 
-func (s *LocationService) getSpeedLimitForLocation(lat, lon float64) float64 {
-	// Mock implementation - in production would query speed limit database
-	// Return 60 km/h as default speed limit
-	return 60.0
+// getSpeedLimitForLocation is the limit for the ping's vehicle where it was taken
+func (s *LocationService) getSpeedLimitForLocation(ping *models.LocationPing) float64 {
+	if s.speedLimits == nil {
+		return defaultSpeedLimit
+	}
+
+	var limit *SpeedLimit
+	var err error
+	if ping.VehicleID != nil {
+		limit, err = s.speedLimits.LimitForVehicle(context.Background(), *ping.VehicleID, ping.Latitude, ping.Longitude)
+	} else {
+		limit, err = s.speedLimits.LimitAt(context.Background(), ping.Latitude, ping.Longitude, "")
+	}
+	if err != nil {
+		log.Printf("❌ Failed to look up speed limit: %v", err)
+		return defaultSpeedLimit
+	}
+	return limit.Limit
 }
 
 func (s *LocationService) updateFleetLocationCache(ping *models.LocationPing) {
@@ -906,7 +909,7 @@ func (s *LocationService) checkSpeedViolationEvent(ping *models.LocationPing) *m
 		return nil
 	}
 
-	speedLimit := s.getSpeedLimitForLocation(ping.Latitude, ping.Longitude)
+	speedLimit := s.getSpeedLimitForLocation(ping)
 	if *ping.Speed > speedLimit {
 		vehicleID := uint(0)
 		if ping.VehicleID != nil {
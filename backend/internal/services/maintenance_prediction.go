@@ -0,0 +1,706 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// maintenanceTelemetryWindow is how much sensor history a forecast looks at
+	maintenanceTelemetryWindow = 30 * 24 * time.Hour
+
+	// maintenanceDTCWindow is how far back fault codes count as recurring
+	maintenanceDTCWindow = 90 * 24 * time.Hour
+
+	// maintenanceFuelWindow is how far back fills are used for fuel-efficiency degradation
+	maintenanceFuelWindow = 120 * 24 * time.Hour
+
+	// maintenanceForecastHorizon is how far ahead a sensor trend may cross its limit and still count
+	maintenanceForecastHorizon = 90.0 // days
+
+	// maintenanceMinTrendDays is how many days of a sensor a trend needs
+	maintenanceMinTrendDays = 5
+
+	// maintenancePredictionMinRisk is the risk below which no prediction is kept
+	maintenancePredictionMinRisk = 0.3
+
+	// maintenanceWorkOrderRisk is the risk at which a work order is recommended and a due event published
+	maintenanceWorkOrderRisk = 0.6
+)
+
+// MaintenanceForecastResult summarizes a forecast run
+type MaintenanceForecastResult struct {
+	Vehicles    int                            `json:"vehicles"`
+	Predictions []models.MaintenancePrediction `json:"predictions"`
+	WorkOrders  []models.WorkOrder             `json:"work_orders"` // Recommended by this run
+	Cleared     int                            `json:"cleared"`
+	Events      []MaintenanceDueEvent          `json:"events"`
+}
+
+// telemetryTrendRule maps a sensor's daily level and trend onto a component's risk. Risk is 0
+// at normal and 1 at limit; limit may be above or below normal.
+type telemetryTrendRule struct {
+	component models.MaintenanceComponent
+	name      string
+	aggregate string // max, min or mean of each day's readings
+	normal    float64
+	limit     float64
+	value     func(*models.TelemetryLog) *float64
+	detail    string
+}
+
+var telemetryTrendRules = []telemetryTrendRule{
+	{
+		component: models.ComponentCoolingSystem, name: "coolant_temp", aggregate: "max", normal: 95, limit: 110,
+		value:  func(l *models.TelemetryLog) *float64 { return l.CoolantTemp },
+		detail: "daily peak coolant temperature %.1f°C",
+	},
+	{
+		component: models.ComponentBattery, name: "battery_voltage", aggregate: "mean", normal: 12.8, limit: 11.8,
+		value:  func(l *models.TelemetryLog) *float64 { return l.BatteryVoltage },
+		detail: "average battery voltage %.2fV",
+	},
+	{
+		component: models.ComponentEngine, name: "engine_load", aggregate: "mean", normal: 60, limit: 90,
+		value:  func(l *models.TelemetryLog) *float64 { return l.EngineLoad },
+		detail: "average engine load %.1f%%",
+	},
+}
+
+// maintenanceRecommendations is the work suggested for each component
+var maintenanceRecommendations = map[models.MaintenanceComponent]string{
+	models.ComponentCoolingSystem: "Inspect coolant level, thermostat, radiator and cooling fan",
+	models.ComponentBattery:       "Load-test the battery and check alternator charging output",
+	models.ComponentEngine:        "Inspect ignition, injectors and compression; check for misfires",
+	models.ComponentFuelSystem:    "Replace fuel and air filters and inspect injectors",
+	models.ComponentEmissions:     "Inspect EGR valve, DPF and catalytic converter",
+	models.ComponentTransmission:  "Inspect transmission fluid, clutch and gearbox",
+	models.ComponentBrakes:        "Inspect brake pads, lines and ABS sensors",
+	models.ComponentElectrical:    "Inspect wiring, ECU connectors and CAN bus",
+}
+
+// dtcSeverityWeight is the base risk a single fault code adds
+var dtcSeverityWeight = map[models.DiagnosticCodeSeverity]float64{
+	models.DTCSeverityLow:      0.1,
+	models.DTCSeverityMedium:   0.25,
+	models.DTCSeverityHigh:     0.45,
+	models.DTCSeverityCritical: 0.7,
+}
+
+// dtcComponent maps an OBD-II or J1939 code onto the system it reports on
+func dtcComponent(code string) models.MaintenanceComponent {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if definition, ok := obdiiCatalog[code]; ok {
+		return definition.Component
+	}
+	if _, source, spn, _, err := ParseDTC(code); err == nil && source == models.DTCSourceJ1939 {
+		if definition, ok := j1939SPNCatalog[*spn]; ok {
+			return definition.Component
+		}
+		return models.ComponentEngine
+	}
+	number := -1
+	if len(code) == 5 {
+		if n, err := strconv.ParseInt(code[1:], 16, 32); err == nil {
+			number = int(n)
+		}
+	}
+	inRange := func(from, to int) bool { return number >= from && number <= to }
+
+	switch {
+	case strings.HasPrefix(code, "P") && (inRange(0x0115, 0x0119) || inRange(0x0125, 0x0128) || code == "P0217" || inRange(0x0480, 0x0483)):
+		return models.ComponentCoolingSystem
+	case strings.HasPrefix(code, "P") && (inRange(0x0560, 0x0563) || inRange(0x0620, 0x0622)):
+		return models.ComponentBattery
+	case strings.HasPrefix(code, "P03"), strings.HasPrefix(code, "P05"):
+		return models.ComponentEngine
+	case strings.HasPrefix(code, "P01"), strings.HasPrefix(code, "P02"):
+		return models.ComponentFuelSystem
+	case strings.HasPrefix(code, "P04"):
+		return models.ComponentEmissions
+	case strings.HasPrefix(code, "P07"), strings.HasPrefix(code, "P08"), strings.HasPrefix(code, "P09"):
+		return models.ComponentTransmission
+	case strings.HasPrefix(code, "C"):
+		return models.ComponentBrakes
+	case strings.HasPrefix(code, "P06"), strings.HasPrefix(code, "U"), strings.HasPrefix(code, "B"):
+		return models.ComponentElectrical
+	default:
+		return models.ComponentEngine
+	}
+}
+
+// maintenanceRiskLevel buckets a risk score
+func maintenanceRiskLevel(risk float64) models.MaintenanceRiskLevel {
+	switch {
+	case risk >= 0.8:
+		return models.MaintenanceRiskCritical
+	case risk >= maintenanceWorkOrderRisk:
+		return models.MaintenanceRiskHigh
+	case risk >= maintenancePredictionMinRisk:
+		return models.MaintenanceRiskMedium
+	default:
+		return models.MaintenanceRiskLow
+	}
+}
+
+// maintenanceRiskRank orders risk levels; unset ranks lowest
+func maintenanceRiskRank(level models.MaintenanceRiskLevel) int {
+	switch level {
+	case models.MaintenanceRiskLow:
+		return 1
+	case models.MaintenanceRiskMedium:
+		return 2
+	case models.MaintenanceRiskHigh:
+		return 3
+	case models.MaintenanceRiskCritical:
+		return 4
+	default:
+		return 0
+	}
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// linearSlope is the least-squares slope of ys against xs
+func linearSlope(xs, ys []float64) float64 {
+	n := float64(len(xs))
+	var sumX, sumY, sumXY, sumXX float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+		sumXY += xs[i] * ys[i]
+		sumXX += xs[i] * xs[i]
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
+
+// componentForecast gathers the evidence for one component
+type componentForecast struct {
+	signals            []models.MaintenanceSignal
+	predictedFailureAt *time.Time
+}
+
+func (f *componentForecast) add(signal models.MaintenanceSignal, failureAt *time.Time) {
+	f.signals = append(f.signals, signal)
+	if failureAt != nil && (f.predictedFailureAt == nil || failureAt.Before(*f.predictedFailureAt)) {
+		f.predictedFailureAt = failureAt
+	}
+}
+
+// risk combines signal scores as independent failure indicators
+func (f *componentForecast) risk() float64 {
+	healthy := 1.0
+	for _, signal := range f.signals {
+		healthy *= 1 - signal.Score
+	}
+	return 1 - healthy
+}
+
+// RunMaintenanceForecast forecasts component failures for one vehicle, or every active vehicle
+// when vehicleID is nil, from telemetry trends, recurring fault codes and fuel-efficiency
+// degradation. High-risk components get a recommended work order and a vehicle_maintenance_due event.
+func (s *MaintenanceService) RunMaintenanceForecast(ctx context.Context, vehicleID *uint, now time.Time) (*MaintenanceForecastResult, error) {
+	query := s.db.Where("is_active = ?", true)
+	if vehicleID != nil {
+		query = s.db.Where("id = ?", *vehicleID)
+	}
+	var vehicles []models.Vehicle
+	if err := query.Find(&vehicles).Error; err != nil {
+		return nil, err
+	}
+	if vehicleID != nil && len(vehicles) == 0 {
+		return nil, fmt.Errorf("vehicle %d: %w", *vehicleID, gorm.ErrRecordNotFound)
+	}
+
+	result := &MaintenanceForecastResult{
+		Predictions: []models.MaintenancePrediction{},
+		WorkOrders:  []models.WorkOrder{},
+		Events:      []MaintenanceDueEvent{},
+	}
+	for i := range vehicles {
+		if err := s.forecastVehicle(&vehicles[i], now, result); err != nil {
+			return nil, fmt.Errorf("forecast for vehicle %d failed: %w", vehicles[i].ID, err)
+		}
+		result.Vehicles++
+	}
+	return result, nil
+}
+
+// forecastVehicle scores every component of a vehicle and updates its predictions
+func (s *MaintenanceService) forecastVehicle(vehicle *models.Vehicle, now time.Time, result *MaintenanceForecastResult) error {
+	// Evidence from before a component's last repair says nothing about it now
+	var resolved []models.MaintenancePrediction
+	if err := s.db.Where("vehicle_id = ? AND status = ?", vehicle.ID, models.MaintenancePredictionResolved).
+		Find(&resolved).Error; err != nil {
+		return err
+	}
+	repairedAt := make(map[models.MaintenanceComponent]time.Time)
+	for _, prediction := range resolved {
+		if prediction.ResolvedAt != nil && prediction.ResolvedAt.After(repairedAt[prediction.Component]) {
+			repairedAt[prediction.Component] = *prediction.ResolvedAt
+		}
+	}
+	since := func(component models.MaintenanceComponent, window time.Duration) time.Time {
+		start := now.Add(-window)
+		if repaired, ok := repairedAt[component]; ok && repaired.After(start) {
+			return repaired
+		}
+		return start
+	}
+
+	forecasts := make(map[models.MaintenanceComponent]*componentForecast)
+	forecast := func(component models.MaintenanceComponent) *componentForecast {
+		if forecasts[component] == nil {
+			forecasts[component] = &componentForecast{}
+		}
+		return forecasts[component]
+	}
+
+	if err := s.scoreTelemetryTrends(vehicle.ID, now, since, forecast); err != nil {
+		return err
+	}
+	if err := s.scoreRecurringDTCs(vehicle.ID, now, since, forecast); err != nil {
+		return err
+	}
+	if err := s.scoreFuelEfficiency(vehicle.ID, now, since, forecast); err != nil {
+		return err
+	}
+
+	var active []models.MaintenancePrediction
+	if err := s.db.Where("vehicle_id = ? AND status = ?", vehicle.ID, models.MaintenancePredictionActive).
+		Find(&active).Error; err != nil {
+		return err
+	}
+	existing := make(map[models.MaintenanceComponent]*models.MaintenancePrediction, len(active))
+	for i := range active {
+		existing[active[i].Component] = &active[i]
+	}
+
+	components := make([]models.MaintenanceComponent, 0, len(forecasts))
+	for component := range forecasts {
+		components = append(components, component)
+	}
+	sort.Slice(components, func(i, j int) bool { return components[i] < components[j] })
+
+	for _, component := range components {
+		f := forecasts[component]
+		risk := f.risk()
+		if risk < maintenancePredictionMinRisk {
+			continue
+		}
+		prediction := existing[component]
+		delete(existing, component)
+		if prediction == nil {
+			prediction = &models.MaintenancePrediction{
+				VehicleID: vehicle.ID,
+				Component: component,
+				Status:    models.MaintenancePredictionActive,
+			}
+		}
+		sort.Slice(f.signals, func(i, j int) bool { return f.signals[i].Score > f.signals[j].Score })
+		prediction.RiskScore = math.Round(risk*10000) / 10000
+		prediction.RiskLevel = maintenanceRiskLevel(risk)
+		prediction.PredictedFailureAt = f.predictedFailureAt
+		prediction.Signals = f.signals
+		prediction.Recommendation = maintenanceRecommendations[component]
+		prediction.WindowStart = since(component, maintenanceDTCWindow)
+		prediction.WindowEnd = now
+
+		if err := s.applyPredictionOutcome(vehicle, prediction, now, result); err != nil {
+			return err
+		}
+		result.Predictions = append(result.Predictions, *prediction)
+	}
+
+	// Components whose signals went back to normal
+	for _, prediction := range existing {
+		prediction.Status = models.MaintenancePredictionCleared
+		if err := s.db.Omit("Vehicle", "WorkOrder").Save(prediction).Error; err != nil {
+			return err
+		}
+		if prediction.WorkOrderID != nil {
+			workOrderID := *prediction.WorkOrderID
+			if err := s.db.Transaction(func(tx *gorm.DB) error {
+				result := tx.Model(&models.WorkOrder{}).
+					Where("id = ? AND status = ?", workOrderID, models.WorkOrderStatusRecommended).
+					Update("status", "CANCELLED")
+				if result.Error != nil || result.RowsAffected == 0 {
+					return result.Error
+				}
+				return releaseWorkOrderParts(tx, workOrderID)
+			}); err != nil {
+				return err
+			}
+		}
+		result.Cleared++
+	}
+	return nil
+}
+
+// applyPredictionOutcome recommends or re-prioritizes the work order for a high-risk prediction,
+// publishes the due event when its level rises, and saves it
+func (s *MaintenanceService) applyPredictionOutcome(vehicle *models.Vehicle, prediction *models.MaintenancePrediction, now time.Time, result *MaintenanceForecastResult) error {
+	if prediction.RiskScore >= maintenanceWorkOrderRisk {
+		var workOrder models.WorkOrder
+		hasOpen := false
+		if prediction.WorkOrderID != nil {
+			if err := s.db.First(&workOrder, *prediction.WorkOrderID).Error; err == nil {
+				hasOpen = workOrder.Status != "COMPLETED" && workOrder.Status != "CANCELLED"
+			}
+		}
+
+		// Book the work in before the predicted failure: within a day when critical, a week otherwise
+		scheduled := now.AddDate(0, 0, 7)
+		if prediction.RiskLevel == models.MaintenanceRiskCritical {
+			scheduled = now.AddDate(0, 0, 1)
+		}
+		if prediction.PredictedFailureAt != nil && prediction.PredictedFailureAt.Before(scheduled) {
+			scheduled = *prediction.PredictedFailureAt
+		}
+
+		if !hasOpen {
+			workOrder = models.WorkOrder{
+				VehicleID:     vehicle.ID,
+				Description:   fmt.Sprintf("Predicted %s failure (risk %.0f%%): %s", strings.ToLower(strings.ReplaceAll(string(prediction.Component), "_", " ")), prediction.RiskScore*100, prediction.Recommendation),
+				Status:        models.WorkOrderStatusRecommended,
+				Priority:      string(prediction.RiskLevel),
+				ScheduledDate: &scheduled,
+				Notes:         describeMaintenanceSignals(prediction.Signals),
+			}
+			if err := s.db.Omit("Vehicle", "MaintenanceTask").Create(&workOrder).Error; err != nil {
+				return fmt.Errorf("failed to recommend work order: %w", err)
+			}
+			prediction.WorkOrderID = &workOrder.ID
+			result.WorkOrders = append(result.WorkOrders, workOrder)
+		} else if workOrder.Status == models.WorkOrderStatusRecommended && workOrder.Priority != string(prediction.RiskLevel) {
+			if err := s.db.Model(&workOrder).Updates(map[string]interface{}{
+				"priority":       string(prediction.RiskLevel),
+				"scheduled_date": scheduled,
+			}).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	publish := prediction.RiskScore >= maintenanceWorkOrderRisk &&
+		maintenanceRiskRank(prediction.RiskLevel) > maintenanceRiskRank(prediction.NotifiedLevel)
+	if publish {
+		prediction.NotifiedLevel = prediction.RiskLevel
+	}
+	if err := s.db.Omit("Vehicle", "WorkOrder").Save(prediction).Error; err != nil {
+		return fmt.Errorf("failed to save maintenance prediction: %w", err)
+	}
+
+	if publish {
+		event := MaintenanceDueEvent{
+			VehicleID:          vehicle.ID,
+			LicensePlate:       vehicle.LicensePlate,
+			Source:             "PREDICTION",
+			Component:          string(prediction.Component),
+			RiskScore:          prediction.RiskScore,
+			RiskLevel:          string(prediction.RiskLevel),
+			PredictedFailureAt: prediction.PredictedFailureAt,
+			PredictionID:       &prediction.ID,
+			WorkOrderID:        prediction.WorkOrderID,
+			Message: fmt.Sprintf("%s: %s risk of %s failure. %s", vehicle.LicensePlate, prediction.RiskLevel,
+				strings.ToLower(strings.ReplaceAll(string(prediction.Component), "_", " ")), prediction.Recommendation),
+		}
+		s.publishMaintenanceDue(&event)
+		result.Events = append(result.Events, event)
+	}
+	return nil
+}
+
+// describeMaintenanceSignals lists the evidence for a work order's notes
+func describeMaintenanceSignals(signals []models.MaintenanceSignal) string {
+	details := make([]string, 0, len(signals))
+	for _, signal := range signals {
+		details = append(details, signal.Detail)
+	}
+	return strings.Join(details, "; ")
+}
+
+// scoreTelemetryTrends scores each sensor's current level and where its daily trend is heading
+func (s *MaintenanceService) scoreTelemetryTrends(vehicleID uint, now time.Time, since func(models.MaintenanceComponent, time.Duration) time.Time, forecast func(models.MaintenanceComponent) *componentForecast) error {
+	var logs []models.TelemetryLog
+	if err := s.db.Where("vehicle_id = ? AND timestamp BETWEEN ? AND ?", vehicleID, now.Add(-maintenanceTelemetryWindow), now).
+		Order("timestamp").Find(&logs).Error; err != nil {
+		return err
+	}
+
+	for _, rule := range telemetryTrendRules {
+		start := since(rule.component, maintenanceTelemetryWindow)
+
+		// Aggregate readings per day
+		type day struct {
+			at     time.Time
+			values []float64
+		}
+		var days []day
+		for i := range logs {
+			value := rule.value(&logs[i])
+			if value == nil || logs[i].Timestamp.Before(start) {
+				continue
+			}
+			at := logs[i].Timestamp.UTC().Truncate(24 * time.Hour)
+			if len(days) == 0 || !days[len(days)-1].at.Equal(at) {
+				days = append(days, day{at: at})
+			}
+			days[len(days)-1].values = append(days[len(days)-1].values, *value)
+		}
+		if len(days) == 0 {
+			continue
+		}
+
+		xs, ys := make([]float64, len(days)), make([]float64, len(days))
+		for i, d := range days {
+			xs[i] = d.at.Sub(days[0].at).Hours() / 24
+			switch rule.aggregate {
+			case "max":
+				ys[i] = d.values[0]
+				for _, v := range d.values {
+					ys[i] = math.Max(ys[i], v)
+				}
+			case "min":
+				ys[i] = d.values[0]
+				for _, v := range d.values {
+					ys[i] = math.Min(ys[i], v)
+				}
+			default:
+				for _, v := range d.values {
+					ys[i] += v
+				}
+				ys[i] /= float64(len(d.values))
+			}
+		}
+
+		// Current level is the last three days, so one bad reading does not dominate
+		recent := ys[int(math.Max(0, float64(len(ys)-3))):]
+		current := 0.0
+		for _, v := range recent {
+			current += v
+		}
+		current /= float64(len(recent))
+
+		span := rule.limit - rule.normal
+		levelScore := clamp01((current - rule.normal) / span)
+		signal := models.MaintenanceSignal{
+			Source: "TELEMETRY",
+			Name:   rule.name,
+			Value:  math.Round(current*100) / 100,
+			Limit:  rule.limit,
+			Score:  levelScore,
+			Detail: fmt.Sprintf(rule.detail, current),
+		}
+
+		var failureAt *time.Time
+		if levelScore >= 1 {
+			at := now
+			failureAt = &at
+		} else if len(days) >= maintenanceMinTrendDays {
+			slope := linearSlope(xs, ys)
+			rounded := math.Round(slope*1000) / 1000
+			signal.SlopePerDay = &rounded
+			// Only a trend towards the limit forecasts a failure
+			if slope*span > 0 {
+				daysToLimit := (rule.limit - current) / slope
+				if daysToLimit <= maintenanceForecastHorizon {
+					at := now.Add(time.Duration(daysToLimit * 24 * float64(time.Hour)))
+					failureAt = &at
+					// A limit reached within a week scores like being there; further out scores less
+					trendScore := clamp01(1 - (daysToLimit-7)/maintenanceForecastHorizon)
+					signal.Score = math.Max(signal.Score, trendScore*0.9)
+					signal.Detail += fmt.Sprintf(", trending %+.2f/day to reach %.1f in %.0f days", slope, rule.limit, daysToLimit)
+				}
+			}
+		}
+
+		signal.Score = math.Round(signal.Score*1000) / 1000
+		if signal.Score > 0 {
+			forecast(rule.component).add(signal, failureAt)
+		}
+	}
+	return nil
+}
+
+// scoreRecurringDTCs scores fault codes by severity and how often they came back
+func (s *MaintenanceService) scoreRecurringDTCs(vehicleID uint, now time.Time, since func(models.MaintenanceComponent, time.Duration) time.Time, forecast func(models.MaintenanceComponent) *componentForecast) error {
+	var codes []models.DiagnosticCode
+	if err := s.db.Where("vehicle_id = ? AND (last_seen >= ? OR is_active = ?)", vehicleID, now.Add(-maintenanceDTCWindow), true).
+		Order("first_seen").Find(&codes).Error; err != nil {
+		return err
+	}
+
+	type occurrence struct {
+		count    int
+		active   bool
+		severity models.DiagnosticCodeSeverity
+		last     time.Time
+	}
+	byCode := make(map[string]*occurrence)
+	var order []string
+	for _, code := range codes {
+		name := strings.ToUpper(strings.TrimSpace(code.Code))
+		// Faults from before the last repair were dealt with, unless still active
+		if code.LastSeen.Before(since(dtcComponent(name), maintenanceDTCWindow)) && !code.IsActive {
+			continue
+		}
+		o := byCode[name]
+		if o == nil {
+			o = &occurrence{}
+			byCode[name] = o
+			order = append(order, name)
+		}
+		o.count++
+		o.active = o.active || code.IsActive
+		if dtcSeverityWeight[code.Severity] > dtcSeverityWeight[o.severity] {
+			o.severity = code.Severity
+		}
+		if code.LastSeen.After(o.last) {
+			o.last = code.LastSeen
+		}
+	}
+
+	for _, name := range order {
+		o := byCode[name]
+		severity := o.severity
+		if severity == "" {
+			severity = models.DTCSeverityMedium
+		}
+		score := dtcSeverityWeight[severity] + 0.15*float64(o.count-1)
+		detail := fmt.Sprintf("%s fault code %s", strings.ToLower(string(severity)), name)
+		if o.count > 1 {
+			detail += fmt.Sprintf(" recurred %d times", o.count)
+		}
+		if o.active {
+			score += 0.1
+			detail += ", still active"
+		}
+		forecast(dtcComponent(name)).add(models.MaintenanceSignal{
+			Source: "DTC",
+			Name:   name,
+			Value:  float64(o.count),
+			Score:  math.Round(clamp01(score)*1000) / 1000,
+			Detail: detail,
+		}, nil)
+	}
+	return nil
+}
+
+// scoreFuelEfficiency compares recent km/L between fills with the vehicle's earlier baseline
+func (s *MaintenanceService) scoreFuelEfficiency(vehicleID uint, now time.Time, since func(models.MaintenanceComponent, time.Duration) time.Time, forecast func(models.MaintenanceComponent) *componentForecast) error {
+	var events []models.FuelEvent
+	if err := s.db.Where("vehicle_id = ? AND status = ? AND odometer_km > 0 AND created_at BETWEEN ? AND ?", vehicleID,
+		models.FuelEventStatusVerified,
+		since(models.ComponentFuelSystem, maintenanceFuelWindow), now).
+		Order("created_at").Find(&events).Error; err != nil {
+		return err
+	}
+
+	// Distance covered on the fuel of each fill
+	var efficiencies []float64
+	for i := 1; i < len(events); i++ {
+		distance := events[i].OdometerKm - events[i-1].OdometerKm
+		if distance > 0 && events[i].Liters > 0 {
+			efficiencies = append(efficiencies, distance/events[i].Liters)
+		}
+	}
+	if len(efficiencies) < 6 {
+		return nil
+	}
+
+	baseline := median(append([]float64(nil), efficiencies[:len(efficiencies)-3]...))
+	recent := median(append([]float64(nil), efficiencies[len(efficiencies)-3:]...))
+	if baseline <= 0 {
+		return nil
+	}
+	degradation := (baseline - recent) / baseline
+	// Up to 5% is normal variation (load, route, weather); a 25% drop scores fully
+	score := clamp01((degradation - 0.05) / 0.20)
+	if score == 0 {
+		return nil
+	}
+
+	forecast(models.ComponentFuelSystem).add(models.MaintenanceSignal{
+		Source: "FUEL_EFFICIENCY",
+		Name:   "km_per_litre",
+		Value:  round2(recent),
+		Limit:  round2(baseline),
+		Score:  math.Round(score*1000) / 1000,
+		Detail: fmt.Sprintf("fuel efficiency down %.0f%% to %.2f km/L from %.2f km/L", degradation*100, recent, baseline),
+	}, nil)
+	return nil
+}
+
+// publishMaintenanceDue hands a due event to MQTT when it is enabled
+func (s *MaintenanceService) publishMaintenanceDue(event *MaintenanceDueEvent) {
+	event.Event = MaintenanceDueEventName
+	event.Timestamp = time.Now()
+	if s.mqttService == nil || !s.mqttService.IsEnabled() {
+		return
+	}
+	if err := s.mqttService.PublishMaintenanceDue(event); err != nil {
+		log.Printf("❌ Failed to publish maintenance due event for vehicle %d: %v", event.VehicleID, err)
+	}
+}
+
+// GetMaintenancePredictions lists predictions, riskiest first
+func (s *MaintenanceService) GetMaintenancePredictions(ctx context.Context, filters map[string]interface{}) ([]models.MaintenancePrediction, error) {
+	query := s.db.Model(&models.MaintenancePrediction{})
+	if vehicleID, ok := filters["vehicle_id"].(uint); ok && vehicleID > 0 {
+		query = query.Where("vehicle_id = ?", vehicleID)
+	}
+	if status, ok := filters["status"].(string); ok && status != "" {
+		query = query.Where("status = ?", strings.ToUpper(status))
+	}
+	if level, ok := filters["risk_level"].(string); ok && level != "" {
+		query = query.Where("risk_level = ?", strings.ToUpper(level))
+	}
+	if component, ok := filters["component"].(string); ok && component != "" {
+		query = query.Where("component = ?", strings.ToUpper(component))
+	}
+
+	var predictions []models.MaintenancePrediction
+	if err := query.Preload("Vehicle").Preload("WorkOrder").
+		Order("risk_score DESC, id").Find(&predictions).Error; err != nil {
+		return nil, err
+	}
+	return predictions, nil
+}
+
+// StartMaintenanceForecast runs the failure forecast daily at the given local hour
+func (s *MaintenanceService) StartMaintenanceForecast(hour int) {
+	go func() {
+		for {
+			now := time.Now()
+			next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+			if !next.After(now) {
+				next = next.AddDate(0, 0, 1)
+			}
+			time.Sleep(time.Until(next))
+
+			result, err := s.RunMaintenanceForecast(context.Background(), nil, time.Now())
+			if err != nil {
+				log.Printf("❌ Nightly maintenance forecast failed: %v", err)
+			} else {
+				log.Printf("🔧 Maintenance forecast: %d vehicle(s), %d prediction(s), %d work order(s) recommended",
+					result.Vehicles, len(result.Predictions), len(result.WorkOrders))
+			}
+		}
+	}()
+}
@@ -2,8 +2,6 @@ package services
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 	"time"
 
 	"github.com/fleetflow/backend/internal/models"
@@ -12,92 +10,22 @@ import (
 
 // MaintenanceService handles maintenance and DVIR operations
 type MaintenanceService struct {
-	db *gorm.DB
+	db            *gorm.DB
+	mqttService   *MQTTService
+	notifications *NotificationService
 }
 
 // NewMaintenanceService creates a new maintenance service
-func NewMaintenanceService(db *gorm.DB) *MaintenanceService {
+func NewMaintenanceService(db *gorm.DB, mqttService *MQTTService) *MaintenanceService {
 	return &MaintenanceService{
-		db: db,
+		db:          db,
+		mqttService: mqttService,
 	}
 }
 
-// SubmitDVIR processes a driver vehicle inspection report
-func (s *MaintenanceService) SubmitDVIR(ctx context.Context, dvir *models.DVIR) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		// 1. Save the DVIR
-		if err := tx.Create(dvir).Error; err != nil {
-			return err
-		}
-
-		// 2. Check for defects
-		var defects []string
-		if err := json.Unmarshal([]byte(dvir.Defects), &defects); err != nil {
-			return fmt.Errorf("failed to parse defects: %w", err)
-		}
-
-		// 3. If defects found, create Work Order and update Vehicle Status
-		if len(defects) > 0 {
-			// Update Vehicle Status
-			if dvir.Status == "UNSAFE" {
-				if err := tx.Model(&models.Vehicle{}).Where("id = ?", dvir.VehicleID).
-					Update("status", models.VehicleStatusMaintenance).Error; err != nil {
-					return err
-				}
-			}
-
-			// Create Work Order
-			workOrder := models.WorkOrder{
-				VehicleID:   dvir.VehicleID,
-				Description: fmt.Sprintf("Defects reported in DVIR #%d: %v", dvir.ID, defects),
-				Status:      "OPEN",
-				Priority:    "HIGH",
-				CreatedAt:   time.Now(),
-			}
-			if err := tx.Create(&workOrder).Error; err != nil {
-				return err
-			}
-		}
-
-		return nil
-	})
-}
-
 // CheckMaintenanceDue checks all vehicles for due maintenance
 func (s *MaintenanceService) CheckMaintenanceDue(ctx context.Context) ([]models.ServiceSchedule, error) {
-	var dueSchedules []models.ServiceSchedule
-	var vehicles []models.Vehicle
-
-	// Get all active vehicles
-	if err := s.db.Where("is_active = ?", true).Find(&vehicles).Error; err != nil {
-		return nil, err
-	}
-
-	for _, vehicle := range vehicles {
-		var schedules []models.ServiceSchedule
-		if err := s.db.Where("vehicle_id = ? AND status = ?", vehicle.ID, "ACTIVE").
-			Preload("MaintenanceTask").Find(&schedules).Error; err != nil {
-			continue
-		}
-
-		for _, schedule := range schedules {
-			// Check mileage
-			mileageDue := schedule.NextDueMileage > 0 && int(vehicle.TotalKilometers) >= schedule.NextDueMileage
-
-			// Check time
-			timeDue := schedule.NextDueDate != nil && time.Now().After(*schedule.NextDueDate)
-
-			if mileageDue || timeDue {
-				dueSchedules = append(dueSchedules, schedule)
-
-				// Create alert (placeholder logic)
-				// In real system, this would trigger a notification
-				fmt.Printf("Maintenance Due: Vehicle %s needs %s\n", vehicle.LicensePlate, schedule.MaintenanceTask.Name)
-			}
-		}
-	}
-
-	return dueSchedules, nil
+	return s.DueServiceSchedules(ctx, false)
 }
 
 // CreateWorkOrder creates a new work order
@@ -133,6 +61,28 @@ func (s *MaintenanceService) ResolveWorkOrder(ctx context.Context, workOrderID u
 			return err
 		}
 
+		// Recorded parts and labour replace the typed-in costs; reserved parts were fitted
+		itemized, err := hasWorkOrderItems(tx, workOrder.ID)
+		if err != nil {
+			return err
+		}
+		if itemized {
+			var reserved []models.WorkOrderPart
+			if err := tx.Where("work_order_id = ? AND status = ?", workOrder.ID, models.WorkOrderPartReserved).
+				Find(&reserved).Error; err != nil {
+				return err
+			}
+			for i := range reserved {
+				// A part consumed or released meanwhile is skipped
+				if _, err := s.consumeWorkOrderPart(tx, &reserved[i], now); err != nil {
+					return err
+				}
+			}
+			if err := s.recalculateWorkOrderCosts(tx, workOrder.ID); err != nil {
+				return err
+			}
+		}
+
 		// Check if there are other open work orders for this vehicle
 		var count int64
 		_ = tx.Model(&models.WorkOrder{}).Where("vehicle_id = ? AND status IN ?", workOrder.VehicleID, []string{"OPEN", "IN_PROGRESS"}).Count(&count).Error
@@ -145,33 +95,13 @@ func (s *MaintenanceService) ResolveWorkOrder(ctx context.Context, workOrderID u
 			}
 		}
 
-		// Update Service Schedule if linked
-		if workOrder.MaintenanceTaskID != nil {
-			var schedule models.ServiceSchedule
-			if err := tx.Where("vehicle_id = ? AND maintenance_task_id = ?", workOrder.VehicleID, *workOrder.MaintenanceTaskID).
-				First(&schedule).Error; err == nil {
-
-				// Update schedule
-				schedule.LastPerformedAt = &now
-
-				// Get current vehicle mileage
-				var vehicle models.Vehicle
-				_ = tx.First(&vehicle, workOrder.VehicleID)
-				schedule.LastMileage = int(vehicle.TotalKilometers)
-
-				// Calculate next due
-				if schedule.IntervalMileage > 0 {
-					schedule.NextDueMileage = schedule.LastMileage + schedule.IntervalMileage
-				}
-				if schedule.IntervalMonths > 0 {
-					nextDate := now.AddDate(0, schedule.IntervalMonths, 0)
-					schedule.NextDueDate = &nextDate
-				}
-
-				_ = tx.Save(&schedule)
-			}
+		// Predictions this work order was recommended for are repaired
+		if err := tx.Model(&models.MaintenancePrediction{}).Where("work_order_id = ? AND status = ?", workOrder.ID, models.MaintenancePredictionActive).
+			Updates(map[string]interface{}{"status": models.MaintenancePredictionResolved, "resolved_at": now}).Error; err != nil {
+			return err
 		}
 
-		return nil
+		// Start the next cycle of the service schedule it was for
+		return s.completeServiceSchedule(tx, &workOrder, now)
 	})
 }
@@ -13,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/fleetflow/backend/internal/models"
 	"googlemaps.github.io/maps"
 )
 
@@ -514,21 +515,9 @@ func (ms *MapsService) calculateRouteDistance(start Location, points []RoutePoin
 	return total
 }
 
+// haversineDistance is the great-circle distance between two locations in kilometers
 func (ms *MapsService) haversineDistance(loc1, loc2 Location) float64 {
-	const earthRadius = 6371 // Earth's radius in kilometers
-
-	lat1Rad := loc1.Lat * math.Pi / 180
-	lat2Rad := loc2.Lat * math.Pi / 180
-	deltaLat := (loc2.Lat - loc1.Lat) * math.Pi / 180
-	deltaLng := (loc2.Lng - loc1.Lng) * math.Pi / 180
-
-	a := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) +
-		math.Cos(lat1Rad)*math.Cos(lat2Rad)*
-			math.Sin(deltaLng/2)*math.Sin(deltaLng/2)
-
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-
-	return earthRadius * c
+	return models.HaversineMeters(loc1.Lat, loc1.Lng, loc2.Lat, loc2.Lng) / 1000
 }
 
 func (ms *MapsService) getRouteForOrder(ctx context.Context, start Location, points []RoutePoint, vehicleType string) (*OptimizedRoute, error) {
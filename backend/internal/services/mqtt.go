@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
@@ -25,6 +26,15 @@ const (
 	TOPIC_VEHICLE_FUEL        = "fleetflow/vehicle/%d/fuel"        // Fuel events
 	TOPIC_VEHICLE_MAINTENANCE = "fleetflow/vehicle/%d/maintenance" // Maintenance alerts
 	TOPIC_VEHICLE_DIAGNOSTICS = "fleetflow/vehicle/%d/diagnostics" // Engine data
+	TOPIC_VEHICLE_TELEMETRY   = "fleetflow/vehicle/%d/telemetry"   // Sensor snapshots
+	TOPIC_VEHICLE_IMU         = "fleetflow/vehicle/%d/imu"         // Accelerometer and gyro samples
+
+	// Device Topics
+	TOPIC_DEVICE_RAW = "fleetflow/device/%s/%s" // Undecoded tracker frames by protocol and IMEI
+
+	// Camera Topics
+	TOPIC_CAMERA_COMMANDS  = "fleetflow/camera/%s/commands"  // Footage requests by camera serial
+	TOPIC_CAMERA_HEARTBEAT = "fleetflow/camera/%s/heartbeat" // Dashcam health reports
 
 	// Driver Topics
 	TOPIC_DRIVER_LOCATION = "fleetflow/driver/%d/location" // Driver GPS
@@ -76,6 +86,7 @@ type TelemetryUpdate struct {
 	EngineHours    *float64  `json:"engine_hours,omitempty"`
 	FuelUsed       *float64  `json:"fuel_used,omitempty"`
 	DTCs           []string  `json:"dtcs,omitempty"` // List of active fault codes
+	DM1            string    `json:"dm1,omitempty"`  // J1939 DM1 payload in hex
 }
 
 type VehicleStatusUpdate struct {
@@ -114,6 +125,27 @@ type FleetAlert struct {
 	RequiresAction bool            `json:"requires_action"`
 }
 
+// MaintenanceDueEventName is the event published when a vehicle needs maintenance
+const MaintenanceDueEventName = "vehicle_maintenance_due"
+
+// MaintenanceDueEvent announces maintenance a vehicle needs, from its service schedule or a failure forecast
+type MaintenanceDueEvent struct {
+	Event              string     `json:"event"` // vehicle_maintenance_due
+	VehicleID          uint       `json:"vehicle_id"`
+	LicensePlate       string     `json:"license_plate,omitempty"`
+	Source             string     `json:"source"` // SCHEDULE, PREDICTION
+	Component          string     `json:"component,omitempty"`
+	Task               string     `json:"task,omitempty"`
+	RiskScore          float64    `json:"risk_score,omitempty"`
+	RiskLevel          string     `json:"risk_level,omitempty"`
+	PredictedFailureAt *time.Time `json:"predicted_failure_at,omitempty"`
+	PredictionID       *uint      `json:"prediction_id,omitempty"`
+	ServiceScheduleID  *uint      `json:"service_schedule_id,omitempty"`
+	WorkOrderID        *uint      `json:"work_order_id,omitempty"`
+	Message            string     `json:"message"`
+	Timestamp          time.Time  `json:"timestamp"`
+}
+
 // NewMQTTService creates a new MQTT service
 func NewMQTTService(config *config.Config) *MQTTService {
 	service := &MQTTService{
@@ -210,7 +242,9 @@ func (m *MQTTService) PublishLocationUpdate(vehicleID uint, location *LocationUp
 	}
 
 	location.VehicleID = vehicleID
-	location.Timestamp = time.Now()
+	if location.Timestamp.IsZero() {
+		location.Timestamp = time.Now()
+	}
 
 	payload, err := json.Marshal(location)
 	if err != nil {
@@ -317,6 +351,31 @@ func (m *MQTTService) PublishFleetAlert(alert *FleetAlert) error {
 	return nil
 }
 
+// PublishMaintenanceDue publishes a vehicle_maintenance_due event on the vehicle's maintenance topic
+func (m *MQTTService) PublishMaintenanceDue(event *MaintenanceDueEvent) error {
+	if !m.IsEnabled() {
+		return fmt.Errorf("MQTT service not enabled")
+	}
+
+	event.Event = MaintenanceDueEventName
+	event.Timestamp = time.Now()
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal maintenance due event: %w", err)
+	}
+
+	topic := fmt.Sprintf(TOPIC_VEHICLE_MAINTENANCE, event.VehicleID)
+	token := m.client.Publish(topic, 1, false, payload)
+
+	if token.Wait() && token.Error() != nil {
+		return fmt.Errorf("failed to publish maintenance due event: %w", token.Error())
+	}
+
+	log.Printf("🔧 Published maintenance due for vehicle %d: %s", event.VehicleID, event.Message)
+	return nil
+}
+
 // SubscribeToVehicleLocation subscribes to vehicle location updates
 func (m *MQTTService) SubscribeToVehicleLocation(vehicleID uint, handler func(*LocationUpdate)) error {
 	if !m.IsEnabled() {
@@ -394,6 +453,151 @@ func (m *MQTTService) SubscribeToAllVehicleTelemetry(handler func(*TelemetryUpda
 	return nil
 }
 
+// PublishTelemetryUpdate publishes a vehicle sensor snapshot for the telemetry pipeline
+func (m *MQTTService) PublishTelemetryUpdate(vehicleID uint, telemetry *TelemetryUpdate) error {
+	if !m.IsEnabled() {
+		return fmt.Errorf("MQTT service not enabled")
+	}
+
+	telemetry.VehicleID = vehicleID
+	payload, err := json.Marshal(telemetry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal telemetry update: %w", err)
+	}
+
+	token := m.client.Publish(fmt.Sprintf(TOPIC_VEHICLE_TELEMETRY, vehicleID), 1, false, payload)
+	if token.Wait() && token.Error() != nil {
+		return fmt.Errorf("failed to publish telemetry update: %w", token.Error())
+	}
+	return nil
+}
+
+// PublishCameraCommand pushes a command to a dashcam by serial number
+func (m *MQTTService) PublishCameraCommand(serial string, command *CameraCommand) error {
+	if !m.IsEnabled() {
+		return fmt.Errorf("MQTT service not enabled")
+	}
+
+	payload, err := json.Marshal(command)
+	if err != nil {
+		return fmt.Errorf("failed to marshal camera command: %w", err)
+	}
+
+	token := m.client.Publish(fmt.Sprintf(TOPIC_CAMERA_COMMANDS, serial), 1, false, payload)
+	if token.Wait() && token.Error() != nil {
+		return fmt.Errorf("failed to publish camera command: %w", token.Error())
+	}
+	return nil
+}
+
+// SubscribeToDeviceFrames subscribes to raw tracker frames relayed by protocol and IMEI
+func (m *MQTTService) SubscribeToDeviceFrames(handler func(protocol, imei string, payload []byte)) error {
+	if !m.IsEnabled() {
+		return fmt.Errorf("MQTT service not enabled")
+	}
+
+	// Wildcard topic: fleetflow/device/+/+
+	topic := fmt.Sprintf(TOPIC_DEVICE_RAW, "+", "+")
+
+	token := m.client.Subscribe(topic, 1, func(client mqtt.Client, msg mqtt.Message) {
+		parts := strings.Split(msg.Topic(), "/")
+		if len(parts) != 4 {
+			log.Printf("❌ Unexpected device topic: %s", msg.Topic())
+			return
+		}
+		handler(parts[2], parts[3], msg.Payload())
+	})
+
+	if token.Wait() && token.Error() != nil {
+		return fmt.Errorf("failed to subscribe to device frames: %w", token.Error())
+	}
+
+	log.Printf("📟 Subscribed to raw tracker frames")
+	return nil
+}
+
+// SubscribeToCameraHeartbeats subscribes to health reports from all dashcams
+func (m *MQTTService) SubscribeToCameraHeartbeats(handler func(*CameraHeartbeat)) error {
+	if !m.IsEnabled() {
+		return fmt.Errorf("MQTT service not enabled")
+	}
+
+	// Wildcard topic: fleetflow/camera/+/heartbeat
+	topic := fmt.Sprintf(TOPIC_CAMERA_HEARTBEAT, "+")
+
+	token := m.client.Subscribe(topic, 1, func(client mqtt.Client, msg mqtt.Message) {
+		var heartbeat CameraHeartbeat
+		if err := json.Unmarshal(msg.Payload(), &heartbeat); err != nil {
+			log.Printf("❌ Failed to unmarshal camera heartbeat: %v", err)
+			return
+		}
+		// The topic names the camera
+		if parts := strings.Split(msg.Topic(), "/"); len(parts) == 4 {
+			heartbeat.Serial = parts[2]
+		}
+		handler(&heartbeat)
+	})
+
+	if token.Wait() && token.Error() != nil {
+		return fmt.Errorf("failed to subscribe to camera heartbeats: %w", token.Error())
+	}
+
+	log.Printf("🎥 Subscribed to camera heartbeats (Wildcard)")
+	return nil
+}
+
+// SubscribeToAllVehicleIMU subscribes to accelerometer and gyro batches from all vehicles
+func (m *MQTTService) SubscribeToAllVehicleIMU(handler func(*IMUBatch)) error {
+	if !m.IsEnabled() {
+		return fmt.Errorf("MQTT service not enabled")
+	}
+
+	// Wildcard topic: fleetflow/vehicle/+/imu
+	topic := "fleetflow/vehicle/+/imu"
+
+	token := m.client.Subscribe(topic, 1, func(client mqtt.Client, msg mqtt.Message) {
+		var batch IMUBatch
+		if err := json.Unmarshal(msg.Payload(), &batch); err == nil {
+			handler(&batch)
+		} else {
+			log.Printf("❌ Failed to unmarshal IMU batch: %v", err)
+		}
+	})
+
+	if token.Wait() && token.Error() != nil {
+		return fmt.Errorf("failed to subscribe to all vehicle IMU samples: %w", token.Error())
+	}
+
+	log.Printf("📈 Subscribed to ALL vehicle IMU samples (Wildcard)")
+	return nil
+}
+
+// PublishEmergencyAlert publishes an alert on the emergency topic whatever its severity
+func (m *MQTTService) PublishEmergencyAlert(alert *FleetAlert) error {
+	if !m.IsEnabled() {
+		return fmt.Errorf("MQTT service not enabled")
+	}
+
+	alert.ID = fmt.Sprintf("alert_%d", time.Now().UnixNano())
+	if alert.Timestamp.IsZero() {
+		alert.Timestamp = time.Now()
+	}
+	alert.RequiresAction = true
+
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal emergency alert: %w", err)
+	}
+
+	token := m.client.Publish(TOPIC_FLEET_EMERGENCY, 2, false, payload)
+	if token.Wait() && token.Error() != nil {
+		return fmt.Errorf("failed to publish emergency alert: %w", token.Error())
+	}
+
+	log.Printf("🚨 Published emergency alert: %s - %s", alert.Type, alert.Message)
+	return nil
+}
+
 // SubscribeToDriverMobile subscribes to driver mobile app communication
 func (m *MQTTService) SubscribeToDriverMobile(driverID uint, handler func([]byte)) error {
 	if !m.IsEnabled() {
@@ -0,0 +1,408 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// oidcMetadataTTL is how long discovery documents and key sets are cached
+const oidcMetadataTTL = time.Hour
+
+// ErrOIDCEndpointNotAllowed is returned for identity provider URLs that are not public https endpoints
+var ErrOIDCEndpointNotAllowed = errors.New("identity provider endpoints must be public https URLs")
+
+// OIDCProviderMetadata is the subset of the discovery document FleetFlow relies on
+type OIDCProviderMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+}
+
+// oidcTokenResponse is the token endpoint response for the authorization code grant
+type oidcTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// jsonWebKey is a single entry of a JWKS document (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type cachedOIDCProvider struct {
+	metadata  *OIDCProviderMetadata
+	keys      map[string]interface{}
+	fetchedAt time.Time
+	keysAt    time.Time
+}
+
+// OIDCClient performs discovery, code exchange and ID token verification against OpenID providers.
+// Issuers are entered by organization admins, so only public https endpoints are contacted.
+type OIDCClient struct {
+	httpClient   *http.Client
+	mu           sync.Mutex
+	providers    map[string]*cachedOIDCProvider
+	allowPrivate bool
+}
+
+// NewOIDCClient creates a new OIDC client; a nil httpClient uses a client with a 10s timeout that
+// refuses to connect to loopback, private and link-local addresses
+func NewOIDCClient(httpClient *http.Client) *OIDCClient {
+	c := &OIDCClient{
+		httpClient: httpClient,
+		providers:  make(map[string]*cachedOIDCProvider),
+	}
+	if c.httpClient == nil {
+		// Checked on the resolved address, so a public name pointing inside the network is refused too
+		dialer := &net.Dialer{Timeout: 5 * time.Second, Control: func(network, address string, _ syscall.RawConn) error {
+			if c.allowPrivate {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("%w: %s", ErrOIDCEndpointNotAllowed, host)
+			}
+			return nil
+		}}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = nil
+		transport.DialContext = dialer.DialContext
+		c.httpClient = &http.Client{Timeout: 10 * time.Second, Transport: transport}
+	}
+	return c
+}
+
+// AllowPrivateIssuers lets the client reach http and internal identity providers, for local
+// development and tests
+func (c *OIDCClient) AllowPrivateIssuers() {
+	c.allowPrivate = true
+}
+
+// CheckEndpoint rejects identity provider URLs that are not https on a public host
+func (c *OIDCClient) CheckEndpoint(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: %q", ErrOIDCEndpointNotAllowed, raw)
+	}
+	if c.allowPrivate {
+		return nil
+	}
+	if u.Scheme != "https" || u.User != nil {
+		return fmt.Errorf("%w: %s", ErrOIDCEndpointNotAllowed, raw)
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil && !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrOIDCEndpointNotAllowed, raw)
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".internal") || !strings.Contains(host, ".") {
+		return fmt.Errorf("%w: %s", ErrOIDCEndpointNotAllowed, raw)
+	}
+	return nil
+}
+
+// isPublicIP reports whether an address is routable on the internet
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	// Carrier-grade NAT (100.64.0.0/10) is not covered by IsPrivate
+	if ip4 := ip.To4(); ip4 != nil && ip4[0] == 100 && ip4[1]&0xc0 == 64 {
+		return false
+	}
+	return true
+}
+
+// Discover fetches (or returns the cached) discovery document for an issuer
+func (c *OIDCClient) Discover(ctx context.Context, issuer string) (*OIDCProviderMetadata, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+	if err := c.CheckEndpoint(issuer); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	cached, ok := c.providers[issuer]
+	c.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < oidcMetadataTTL {
+		return cached.metadata, nil
+	}
+
+	var metadata OIDCProviderMetadata
+	if err := c.getJSON(ctx, issuer+"/.well-known/openid-configuration", &metadata); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC discovery issuer mismatch: expected %s, got %s", issuer, metadata.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery document is missing required endpoints")
+	}
+	for _, endpoint := range []string{metadata.AuthorizationEndpoint, metadata.TokenEndpoint, metadata.JWKSURI} {
+		if err := c.CheckEndpoint(endpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	c.providers[issuer] = &cachedOIDCProvider{metadata: &metadata, fetchedAt: time.Now()}
+	c.mu.Unlock()
+
+	return &metadata, nil
+}
+
+// ExchangeCode redeems an authorization code for tokens using the PKCE verifier
+func (c *OIDCClient) ExchangeCode(ctx context.Context, metadata *OIDCProviderMetadata, clientID, clientSecret, redirectURL, code, codeVerifier string) (*oidcTokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"client_id":     {clientID},
+		"code_verifier": {codeVerifier},
+	}
+
+	// client_secret_basic is the spec default; fall back to client_secret_post when it's the only option
+	useBasic := clientSecret != ""
+	if clientSecret != "" && len(metadata.TokenEndpointAuthMethodsSupported) > 0 &&
+		!containsString(metadata.TokenEndpointAuthMethodsSupported, "client_secret_basic") {
+		useBasic = false
+		form.Set("client_secret", clientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasic {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token oidcTokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("invalid token response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("token endpoint returned %d: %s %s", resp.StatusCode, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("token response did not include an id_token")
+	}
+	return &token, nil
+}
+
+// VerifyIDToken validates an ID token's signature, issuer, audience, expiry and nonce
+func (c *OIDCClient) VerifyIDToken(ctx context.Context, metadata *OIDCProviderMetadata, clientID, rawIDToken, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.signingKey(ctx, metadata, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, errors.New("invalid ID token: nonce mismatch")
+	}
+	// With multiple audiences the authorized party must be this client
+	if aud, ok := claims["aud"].([]interface{}); ok && len(aud) > 1 {
+		if azp, _ := claims["azp"].(string); azp != clientID {
+			return nil, errors.New("invalid ID token: authorized party mismatch")
+		}
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, errors.New("invalid ID token: missing subject")
+	}
+
+	return claims, nil
+}
+
+// signingKey returns the provider key for kid, refreshing the key set once on a miss to follow rotation
+func (c *OIDCClient) signingKey(ctx context.Context, metadata *OIDCProviderMetadata, kid string) (interface{}, error) {
+	issuer := strings.TrimSuffix(metadata.Issuer, "/")
+
+	for attempt := 0; attempt < 2; attempt++ {
+		c.mu.Lock()
+		cached := c.providers[issuer]
+		var keys map[string]interface{}
+		if cached != nil && cached.keys != nil && time.Since(cached.keysAt) < oidcMetadataTTL {
+			keys = cached.keys
+		}
+		c.mu.Unlock()
+
+		if keys == nil || attempt == 1 {
+			var err error
+			if keys, err = c.fetchKeys(ctx, metadata.JWKSURI); err != nil {
+				return nil, err
+			}
+			c.mu.Lock()
+			if cached = c.providers[issuer]; cached == nil {
+				cached = &cachedOIDCProvider{metadata: metadata, fetchedAt: time.Now()}
+				c.providers[issuer] = cached
+			}
+			cached.keys = keys
+			cached.keysAt = time.Now()
+			c.mu.Unlock()
+		}
+
+		if kid == "" && len(keys) == 1 {
+			for _, key := range keys {
+				return key, nil
+			}
+		}
+		if key, ok := keys[kid]; ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("signing key %q not found in provider key set", kid)
+}
+
+// fetchKeys downloads and parses a JWKS document, skipping keys it can't use
+func (c *OIDCClient) fetchKeys(ctx context.Context, jwksURI string) (map[string]interface{}, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := c.getJSON(ctx, jwksURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch provider keys: %w", err)
+	}
+
+	keys := make(map[string]interface{})
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("provider key set has no usable signing keys")
+	}
+	return keys, nil
+}
+
+func (c *OIDCClient) getJSON(ctx context.Context, target string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %d", target, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+// publicKey converts a JWK into an *rsa.PublicKey or *ecdsa.PublicKey
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+
+// pkceChallenge derives the S256 code challenge for a verifier (RFC 7636 section 4.2)
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,580 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/fleetflow/backend/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password login errors
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrAccountLocked      = errors.New("account is temporarily locked due to too many failed login attempts")
+	ErrInvalidMFACode     = errors.New("invalid two-factor authentication code")
+	ErrPasswordPolicy     = errors.New("password does not meet the security policy")
+	ErrTOTPNotSetUp       = errors.New("two-factor authentication has not been set up")
+
+	ErrEmailInUse               = errors.New("email is already in use")
+	ErrInvalidEmailVerification = errors.New("email verification token is invalid or has expired")
+)
+
+const (
+	// accountLockoutDuration is how long an account stays locked after MaxLoginAttempts failures
+	accountLockoutDuration = 30 * time.Minute
+
+	// recoveryCodeCount is the number of recovery codes issued when TOTP is enabled
+	recoveryCodeCount = 10
+
+	// emailVerificationExpiry is how long the token confirming a new email address stays valid
+	emailVerificationExpiry = 24 * time.Hour
+)
+
+// PasswordLoginResult describes the outcome of a successful password check
+type PasswordLoginResult struct {
+	User *models.UserAccount
+	// MFARequired is set when the user must still present a TOTP or recovery code
+	MFARequired bool
+	// TwoFactorSetupRequired is set when the security policy mandates 2FA but the user has not enrolled
+	TwoFactorSetupRequired bool
+}
+
+// TOTPSetup holds the enrollment details shown to the user
+type TOTPSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// GetSecuritySettings returns the configured security policy or the defaults
+func (s *AuthService) GetSecuritySettings() *models.SecuritySettings {
+	settings, err := s.repo.GetSecuritySettings()
+	if err != nil {
+		return models.DefaultSecuritySettings()
+	}
+	return settings.WithDefaults()
+}
+
+// ValidatePasswordPolicy checks a password against the configured security policy
+func (s *AuthService) ValidatePasswordPolicy(password string) error {
+	return ValidatePasswordPolicy(password, s.GetSecuritySettings())
+}
+
+// ValidatePasswordPolicy checks a password against the given security settings
+func ValidatePasswordPolicy(password string, settings *models.SecuritySettings) error {
+	settings = settings.WithDefaults()
+
+	var problems []string
+	if len([]rune(password)) < settings.PasswordMinLength {
+		problems = append(problems, fmt.Sprintf("at least %d characters", settings.PasswordMinLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	if settings.PasswordRequireUpper && !hasUpper {
+		problems = append(problems, "an uppercase letter")
+	}
+	if settings.PasswordRequireLower && !hasLower {
+		problems = append(problems, "a lowercase letter")
+	}
+	if settings.PasswordRequireDigit && !hasDigit {
+		problems = append(problems, "a digit")
+	}
+	if settings.PasswordRequireSymbol && !hasSymbol {
+		problems = append(problems, "a symbol")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: requires %s", ErrPasswordPolicy, strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// LoginWithPassword authenticates a web user by email and password
+func (s *AuthService) LoginWithPassword(email, password, ipAddress, userAgent string) (*PasswordLoginResult, error) {
+	auditCtx := &models.AuditContext{IPAddress: ipAddress, UserAgent: userAgent}
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	user, err := s.repo.GetUserByEmail(email)
+	if err != nil {
+		_ = s.auditService.LogAction(models.AuditActionLoginFailed, models.AuditSeverityWarning,
+			fmt.Sprintf("Password login failed for unknown email %s", email), nil, nil, auditCtx)
+		return nil, ErrInvalidCredentials
+	}
+	auditCtx.UserID = &user.ID
+
+	if user.IsLocked() {
+		_ = s.auditService.LogAction(models.AuditActionLoginFailed, models.AuditSeverityWarning,
+			fmt.Sprintf("Password login attempted on locked account %s", email), nil, nil, auditCtx)
+		return nil, ErrAccountLocked
+	}
+
+	if user.Password == "" || user.ComparePassword(password) != nil {
+		return nil, s.registerFailedLogin(user, "invalid password", ErrInvalidCredentials, auditCtx)
+	}
+
+	// Only someone who knows the password learns that the account is deactivated
+	if !user.IsActive {
+		return nil, errors.New("user account is deactivated")
+	}
+
+	settings := s.GetSecuritySettings()
+	result := &PasswordLoginResult{User: user}
+
+	if user.TOTPEnabled {
+		// Password is correct, but the lockout counter is only cleared once the second factor passes
+		result.MFARequired = true
+		return result, nil
+	}
+
+	result.TwoFactorSetupRequired = settings.TwoFactorEnabled
+	if err := s.completeLogin(user, "password", auditCtx); err != nil {
+		return nil, err
+	}
+	result.User, _ = s.repo.GetUserByID(user.ID)
+	return result, nil
+}
+
+// VerifySecondFactor completes a password login with a TOTP or recovery code
+func (s *AuthService) VerifySecondFactor(userID uint, code, ipAddress, userAgent string) (*models.UserAccount, error) {
+	auditCtx := &models.AuditContext{UserID: &userID, IPAddress: ipAddress, UserAgent: userAgent}
+
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if !user.IsActive {
+		return nil, errors.New("user account is deactivated")
+	}
+
+	if user.IsLocked() {
+		return nil, ErrAccountLocked
+	}
+
+	if !user.TOTPEnabled {
+		return nil, ErrTOTPNotSetUp
+	}
+
+	method := "password+totp"
+	accepted, err := s.acceptTOTPCode(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		used, err := s.consumeRecoveryCode(user.ID, code)
+		if err != nil {
+			return nil, err
+		}
+		if !used {
+			return nil, s.registerFailedLogin(user, "invalid two-factor code", ErrInvalidMFACode, auditCtx)
+		}
+		method = "password+recovery_code"
+	}
+
+	if err := s.completeLogin(user, method, auditCtx); err != nil {
+		return nil, err
+	}
+	return s.repo.GetUserByID(user.ID)
+}
+
+// ChangePassword sets a new password after verifying the current one.
+// Users who have only ever logged in via OTP may set an initial password without a current one.
+func (s *AuthService) ChangePassword(userID uint, oldPassword, newPassword string) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.Password != "" && user.ComparePassword(oldPassword) != nil {
+		return ErrInvalidCredentials
+	}
+
+	if err := s.ValidatePasswordPolicy(newPassword); err != nil {
+		return err
+	}
+
+	hashed, err := s.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	now := time.Now()
+	if err := s.repo.UpdateUserFields(userID, map[string]interface{}{
+		"password":              hashed,
+		"password_changed_at":   now,
+		"failed_login_attempts": 0,
+		"locked_until":          nil,
+	}); err != nil {
+		return err
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionPasswordChanged, models.AuditSeverityInfo,
+		fmt.Sprintf("Password changed for user ID %d", userID), nil, nil, &models.AuditContext{
+			UserID: &userID,
+		})
+
+	return nil
+}
+
+// RequestEmailChange holds a new email address as pending until the user confirms it with the
+// token sent to that address. The current address keeps working for login and SSO linking until
+// then. Asking again for the current, unverified address verifies it. Returns the token to deliver.
+func (s *AuthService) RequestEmailChange(userID uint, email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return "", err
+	}
+	if email == strings.ToLower(user.Email) && user.EmailVerifiedAt != nil {
+		return "", nil
+	}
+	if existing, err := s.repo.GetUserByEmail(email); err == nil && existing.ID != userID {
+		return "", ErrEmailInUse
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate verification token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+	expiresAt := time.Now().Add(emailVerificationExpiry)
+	if err := s.repo.UpdateUserFields(userID, map[string]interface{}{
+		"pending_email":                 email,
+		"email_verification_hash":       hashEmailVerificationToken(token),
+		"email_verification_expires_at": expiresAt,
+	}); err != nil {
+		return "", err
+	}
+
+	// TODO: Send the verification link by email
+	if s.config.IsDevelopment() {
+		fmt.Printf("📧 Email verification token for %s: %s\n", email, token)
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionConfigChanged, models.AuditSeverityInfo,
+		fmt.Sprintf("Email change to %s requested for user ID %d", email, userID), nil, nil, &models.AuditContext{
+			UserID: &userID,
+		})
+
+	return token, nil
+}
+
+// VerifyEmail confirms a pending email address with its token and makes it the account's email
+func (s *AuthService) VerifyEmail(token string) (*models.UserAccount, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, ErrInvalidEmailVerification
+	}
+	user, err := s.repo.GetUserByEmailVerification(hashEmailVerificationToken(token))
+	if err != nil {
+		return nil, ErrInvalidEmailVerification
+	}
+	if user.PendingEmail == "" || user.EmailVerificationExpiresAt == nil || time.Now().After(*user.EmailVerificationExpiresAt) {
+		return nil, ErrInvalidEmailVerification
+	}
+	if existing, err := s.repo.GetUserByEmail(user.PendingEmail); err == nil && existing.ID != user.ID {
+		return nil, ErrEmailInUse
+	}
+
+	oldEmail := user.Email
+	if err := s.repo.UpdateUserFields(user.ID, map[string]interface{}{
+		"email":                         user.PendingEmail,
+		"email_verified_at":             time.Now(),
+		"pending_email":                 "",
+		"email_verification_hash":       "",
+		"email_verification_expires_at": nil,
+	}); err != nil {
+		return nil, err
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionConfigChanged, models.AuditSeverityWarning,
+		fmt.Sprintf("Email for user ID %d changed from %q to %q after verification", user.ID, oldEmail, user.PendingEmail), nil, nil, &models.AuditContext{
+			UserID: &user.ID,
+		})
+
+	return s.repo.GetUserByID(user.ID)
+}
+
+// hashEmailVerificationToken is how verification tokens are stored, so a database read does not reveal them
+func hashEmailVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SetupTOTP generates a new TOTP secret for the user; it is not active until confirmed
+func (s *AuthService) SetupTOTP(userID uint) (*TOTPSetup, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+
+	// Codes used with an earlier secret say nothing about the new one
+	if err := s.repo.UpdateUserFields(userID, map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}); err != nil {
+		return nil, err
+	}
+
+	accountName := user.Email
+	if accountName == "" {
+		accountName = user.Phone
+	}
+
+	return &TOTPSetup{
+		Secret:          secret,
+		ProvisioningURI: TOTPProvisioningURI(secret, accountName),
+	}, nil
+}
+
+// EnableTOTP confirms enrollment with a code from the authenticator app and returns recovery codes
+func (s *AuthService) EnableTOTP(userID uint, code string) ([]string, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPSecret == "" {
+		return nil, ErrTOTPNotSetUp
+	}
+
+	accepted, err := s.acceptTOTPCode(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, ErrInvalidMFACode
+	}
+
+	if err := s.repo.UpdateUserFields(userID, map[string]interface{}{"totp_enabled": true}); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.issueRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionConfigChanged, models.AuditSeverityInfo,
+		fmt.Sprintf("Two-factor authentication enabled for user ID %d", userID), nil, nil, &models.AuditContext{
+			UserID: &userID,
+		})
+
+	return codes, nil
+}
+
+// DisableTOTP turns off the second factor after re-checking the user's password
+func (s *AuthService) DisableTOTP(userID uint, password string) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.Password == "" || user.ComparePassword(password) != nil {
+		return ErrInvalidCredentials
+	}
+
+	if err := s.repo.UpdateUserFields(userID, map[string]interface{}{
+		"totp_enabled":   false,
+		"totp_secret":    "",
+		"totp_last_step": 0,
+	}); err != nil {
+		return err
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(userID, nil); err != nil {
+		return err
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionConfigChanged, models.AuditSeverityWarning,
+		fmt.Sprintf("Two-factor authentication disabled for user ID %d", userID), nil, nil, &models.AuditContext{
+			UserID: &userID,
+		})
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after verifying a current TOTP code
+func (s *AuthService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.TOTPEnabled {
+		return nil, ErrTOTPNotSetUp
+	}
+
+	accepted, err := s.acceptTOTPCode(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, ErrInvalidMFACode
+	}
+
+	return s.issueRecoveryCodes(userID)
+}
+
+// registerFailedLogin increments the failure counter and locks the account once the policy limit is hit.
+// It returns failErr, or ErrAccountLocked if this attempt triggered the lockout.
+func (s *AuthService) registerFailedLogin(user *models.UserAccount, reason string, failErr error, auditCtx *models.AuditContext) error {
+	settings := s.GetSecuritySettings()
+
+	// Counted in the database so parallel guesses cannot each see a stale count
+	attempts, err := s.repo.IncrementFailedLoginAttempts(user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record login attempt: %w", err)
+	}
+	user.FailedLoginAttempts = attempts
+
+	locked := attempts >= settings.MaxLoginAttempts
+	if locked {
+		lockedUntil := time.Now().Add(accountLockoutDuration)
+		user.LockedUntil = &lockedUntil
+		if err := s.repo.UpdateUserFields(user.ID, map[string]interface{}{"locked_until": lockedUntil}); err != nil {
+			return fmt.Errorf("failed to lock account: %w", err)
+		}
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionLoginFailed, models.AuditSeverityWarning,
+		fmt.Sprintf("Password login failed for user ID %d: %s (attempt %d of %d)",
+			user.ID, reason, user.FailedLoginAttempts, settings.MaxLoginAttempts), nil, nil, auditCtx)
+
+	if !locked {
+		return failErr
+	}
+
+	description := fmt.Sprintf("Account for user ID %d locked until %s after %d failed login attempts",
+		user.ID, user.LockedUntil.Format(time.RFC3339), user.FailedLoginAttempts)
+	_ = s.auditService.LogAction(models.AuditActionAccountLocked, models.AuditSeverityWarning,
+		description, nil, nil, auditCtx)
+	_ = s.auditService.LogSecurityEvent(string(models.AuditActionAccountLocked), "BLOCKED",
+		models.AuditSeverityWarning, description, auditCtx)
+
+	return ErrAccountLocked
+}
+
+// completeLogin clears lockout state and records the successful login
+func (s *AuthService) completeLogin(user *models.UserAccount, method string, auditCtx *models.AuditContext) error {
+	now := time.Now()
+	if err := s.repo.UpdateUserFields(user.ID, map[string]interface{}{
+		"failed_login_attempts": 0,
+		"locked_until":          nil,
+		"last_login":            now,
+	}); err != nil {
+		return fmt.Errorf("failed to update user login: %w", err)
+	}
+
+	_ = s.auditService.LogAction(models.AuditActionUserLogin, models.AuditSeverityInfo,
+		fmt.Sprintf("Successful %s login for user ID %d", method, user.ID), nil, nil, auditCtx)
+
+	return nil
+}
+
+// acceptTOTPCode checks a TOTP code and uses up its time step, so a code cannot be replayed
+// while it is still within its validity window
+func (s *AuthService) acceptTOTPCode(user *models.UserAccount, code string) (bool, error) {
+	step, ok := MatchTOTPCode(user.TOTPSecret, code, time.Now())
+	if !ok || step <= user.TOTPLastStep {
+		return false, nil
+	}
+	advanced, err := s.repo.AdvanceTOTPStep(user.ID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to record TOTP code: %w", err)
+	}
+	if advanced {
+		user.TOTPLastStep = step
+	}
+	return advanced, nil
+}
+
+// consumeRecoveryCode marks a matching unused recovery code as used. A code another login
+// spent first is not accepted.
+func (s *AuthService) consumeRecoveryCode(userID uint, code string) (bool, error) {
+	code = normalizeRecoveryCode(code)
+	if code == "" {
+		return false, nil
+	}
+
+	codes, err := s.repo.GetUnusedRecoveryCodes(userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, rc := range codes {
+		if bcrypt.CompareHashAndPassword([]byte(rc.CodeHash), []byte(code)) == nil {
+			claimed, err := s.repo.MarkRecoveryCodeUsed(rc.ID)
+			if err != nil {
+				return false, err
+			}
+			return claimed, nil
+		}
+	}
+	return false, nil
+}
+
+// issueRecoveryCodes generates a fresh set of recovery codes and stores their hashes
+func (s *AuthService) issueRecoveryCodes(userID uint) ([]string, error) {
+	plain := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(normalizeRecoveryCode(code)), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		plain = append(plain, code)
+		records = append(records, models.RecoveryCode{UserID: userID, CodeHash: string(hash)})
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(userID, records); err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+	return plain, nil
+}
+
+// generateRecoveryCode returns a random code formatted as xxxxx-xxxxx
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, 7)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	encoded := strings.ToLower(totpEncoding.EncodeToString(buf))[:10]
+	return encoded[:5] + "-" + encoded[5:], nil
+}
+
+// normalizeRecoveryCode strips formatting so codes can be typed with or without dashes
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
@@ -0,0 +1,536 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrLoginBlocked is returned when an identifier or IP is under an active risk block
+var ErrLoginBlocked = errors.New("sign-in temporarily blocked due to suspicious activity")
+
+const (
+	// riskWindow is the look-back period for velocity and brute-force factors
+	riskWindow = 15 * time.Minute
+
+	// riskBlockDuration is how long an automatic block lasts unless an admin lifts it
+	riskBlockDuration = 30 * time.Minute
+
+	// impossibleTravelSpeedKmh is faster than any commercial flight including airport time
+	impossibleTravelSpeedKmh = 900.0
+
+	// impossibleTravelMinKm ignores jumps within GeoIP/cell-tower accuracy
+	impossibleTravelMinKm = 200.0
+)
+
+// Risk factor names recorded in SecurityEvent.RiskFactors
+const (
+	RiskFactorIdentifierVelocity = "identifier_velocity"
+	RiskFactorIPVelocity         = "ip_velocity"
+	RiskFactorIPManyIdentifiers  = "ip_many_identifiers"
+	RiskFactorOTPBruteForce      = "otp_brute_force"
+	RiskFactorOTPRequestFlood    = "otp_request_flood"
+	RiskFactorNewDevice          = "new_device"
+	RiskFactorImpossibleTravel   = "impossible_travel"
+)
+
+// RiskAttempt describes a single authentication attempt to score
+type RiskAttempt struct {
+	EventType  string // models.SecurityEventLoginAttempt, SecurityEventOTPRequest, ...
+	Identifier string // Phone or email the attempt was made for
+	UserID     *uint
+	Success    bool
+	IPAddress  string
+	UserAgent  string
+	DeviceID   string
+	Location   string
+}
+
+// RiskFactor is one contribution to a risk score
+type RiskFactor struct {
+	Name   string  `json:"name"`
+	Score  float64 `json:"score"`
+	Detail string  `json:"detail"`
+	scope  string
+}
+
+// RiskAssessment is the outcome of scoring an attempt
+type RiskAssessment struct {
+	Score        float64
+	Factors      []RiskFactor
+	Blocked      bool
+	BlockReason  string
+	BlockedUntil *time.Time
+	Event        *models.SecurityEvent
+}
+
+type ipRiskState struct {
+	score        float64
+	blockedUntil time.Time
+	updatedAt    time.Time
+}
+
+// RiskService scores login and OTP attempts, blocks risky ones and supports admin investigation
+type RiskService struct {
+	db             *gorm.DB
+	auditService   *AuditService
+	settingsSource func() *models.SecuritySettings
+	geoLocator     GeoIPLocator
+
+	mu     sync.RWMutex
+	ipRisk map[string]*ipRiskState
+
+	settingsMu       sync.Mutex
+	settingsCache    *models.SecuritySettings
+	settingsCachedAt time.Time
+}
+
+// NewRiskService creates a new risk service
+func NewRiskService(db *gorm.DB, auditService *AuditService, settingsSource func() *models.SecuritySettings) *RiskService {
+	return &RiskService{
+		db:             db,
+		auditService:   auditService,
+		settingsSource: settingsSource,
+		ipRisk:         make(map[string]*ipRiskState),
+	}
+}
+
+// SetGeoIPLocator sets how client IPs are resolved to the login positions impossible travel is measured
+// between. Without one, the impossible travel factor is not scored.
+func (s *RiskService) SetGeoIPLocator(locator GeoIPLocator) {
+	s.geoLocator = locator
+}
+
+// CheckBlocked returns the active block covering an identifier or IP, if any
+func (s *RiskService) CheckBlocked(identifier, ipAddress string) (*models.SecurityEvent, error) {
+	query := s.db.Where("is_blocked = ? AND blocked_until > ?", true, time.Now())
+	switch {
+	case identifier != "" && ipAddress != "":
+		query = query.Where("(block_scope = ? AND identifier = ?) OR (block_scope = ? AND ip_address = ?)",
+			models.BlockScopeIdentifier, identifier, models.BlockScopeIP, ipAddress)
+	case identifier != "":
+		query = query.Where("block_scope = ? AND identifier = ?", models.BlockScopeIdentifier, identifier)
+	case ipAddress != "":
+		query = query.Where("block_scope = ? AND ip_address = ?", models.BlockScopeIP, ipAddress)
+	default:
+		return nil, nil
+	}
+
+	var event models.SecurityEvent
+	if err := query.Order("blocked_until DESC").First(&event).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Assess scores an attempt, records it as a SecurityEvent and blocks it when the score reaches the threshold.
+// Successful attempts that are not blocked update the user's last-login location.
+func (s *RiskService) Assess(attempt *RiskAttempt) (*RiskAssessment, error) {
+	now := time.Now()
+	since := now.Add(-riskWindow)
+
+	position := s.locate(attempt.IPAddress)
+
+	var factors []RiskFactor
+	factors = append(factors, s.velocityFactors(attempt, since)...)
+	if attempt.Success && attempt.UserID != nil {
+		factors = append(factors, s.deviceAndTravelFactors(attempt, position, now)...)
+	}
+
+	assessment := &RiskAssessment{Factors: factors}
+	identifierScore, ipScore := 0.0, 0.0
+	for _, f := range factors {
+		assessment.Score += f.Score
+		if f.scope == models.BlockScopeIP {
+			ipScore += f.Score
+		} else {
+			identifierScore += f.Score
+		}
+	}
+	if assessment.Score > 100 {
+		assessment.Score = 100
+	}
+
+	event := &models.SecurityEvent{
+		EventType:  attempt.EventType,
+		Status:     models.SecurityEventStatusFailed,
+		Severity:   models.AuditSeverityInfo,
+		Identifier: attempt.Identifier,
+		IPAddress:  attempt.IPAddress,
+		UserAgent:  attempt.UserAgent,
+		DeviceInfo: attempt.DeviceID,
+		Location:   attempt.Location,
+		RiskScore:  assessment.Score,
+		UserID:     attempt.UserID,
+	}
+	if attempt.Success {
+		event.Status = models.SecurityEventStatusSuccess
+	}
+	if factorsJSON, err := json.Marshal(factors); err == nil && len(factors) > 0 {
+		event.RiskFactors = string(factorsJSON)
+	}
+
+	threshold := float64(s.settings().RiskBlockThreshold)
+	if assessment.Score >= threshold {
+		blockedUntil := now.Add(riskBlockDuration)
+		scope := models.BlockScopeIdentifier
+		if ipScore > identifierScore || attempt.Identifier == "" {
+			scope = models.BlockScopeIP
+		}
+
+		event.Block(fmt.Sprintf("Risk score %.0f reached threshold %.0f: %s", assessment.Score, threshold, factorNames(factors)))
+		event.Status = models.SecurityEventStatusBlocked
+		event.BlockScope = scope
+		event.BlockedUntil = &blockedUntil
+		event.Severity = models.AuditSeverityCritical
+
+		assessment.Blocked = true
+		assessment.BlockReason = event.BlockReason
+		assessment.BlockedUntil = &blockedUntil
+	} else if assessment.Score >= threshold/2 {
+		event.Severity = models.AuditSeverityWarning
+	}
+	event.Description = describeAttempt(attempt, assessment)
+
+	if err := s.db.Create(event).Error; err != nil {
+		return nil, fmt.Errorf("failed to record security event: %w", err)
+	}
+	assessment.Event = event
+
+	s.updateIPRisk(attempt.IPAddress, ipScore, assessment)
+
+	if assessment.Blocked {
+		_ = s.auditService.LogAction(models.AuditActionUnauthorizedAccess, models.AuditSeverityCritical,
+			fmt.Sprintf("Security event %d blocked %s %s: %s", event.ID, strings.ToLower(event.BlockScope),
+				blockSubject(event), event.BlockReason), nil, nil,
+			&models.AuditContext{UserID: attempt.UserID, IPAddress: attempt.IPAddress, UserAgent: attempt.UserAgent})
+	} else if attempt.Success && attempt.UserID != nil {
+		s.recordLoginLocation(*attempt.UserID, attempt, position)
+	}
+
+	return assessment, nil
+}
+
+// IPRisk reports the recent risk level of an IP for the auth rate limiter
+func (s *RiskService) IPRisk(ipAddress string) (float64, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state, ok := s.ipRisk[ipAddress]
+	if !ok || time.Since(state.updatedAt) > riskWindow && time.Now().After(state.blockedUntil) {
+		return 0, time.Time{}
+	}
+	return state.score, state.blockedUntil
+}
+
+// GetSecurityEvent returns a single security event with its user and investigator
+func (s *RiskService) GetSecurityEvent(id uint) (*models.SecurityEvent, error) {
+	var event models.SecurityEvent
+	if err := s.db.Preload("User").Preload("InvestigatedByUser").First(&event, id).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Investigate records an admin's findings on a security event and optionally lifts its block
+func (s *RiskService) Investigate(eventID, adminID uint, notes string, liftBlock bool, auditCtx *models.AuditContext) (*models.SecurityEvent, error) {
+	var event models.SecurityEvent
+	if err := s.db.First(&event, eventID).Error; err != nil {
+		return nil, err
+	}
+
+	old := event
+	event.Investigate(adminID, notes)
+	updates := map[string]interface{}{
+		"is_investigated":     true,
+		"investigated_by":     adminID,
+		"investigated_at":     event.InvestigatedAt,
+		"investigation_notes": notes,
+	}
+
+	lifted := liftBlock && event.IsBlockActive()
+	if lifted {
+		now := time.Now()
+		event.BlockedUntil = &now
+		updates["blocked_until"] = now
+
+		if event.BlockScope == models.BlockScopeIP {
+			s.mu.Lock()
+			delete(s.ipRisk, event.IPAddress)
+			s.mu.Unlock()
+		}
+	}
+
+	if err := s.db.Model(&event).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update security event: %w", err)
+	}
+
+	description := fmt.Sprintf("Security event %d investigated", event.ID)
+	if lifted {
+		description += fmt.Sprintf("; block on %s lifted", blockSubject(&event))
+	}
+	_ = s.auditService.LogAction(models.AuditActionUserUpdated, models.AuditSeverityInfo, description, old, event, auditCtx)
+
+	return s.GetSecurityEvent(event.ID)
+}
+
+// velocityFactors scores attempt frequency per identifier and IP, and OTP guessing
+func (s *RiskService) velocityFactors(attempt *RiskAttempt, since time.Time) []RiskFactor {
+	var factors []RiskFactor
+	failedNow := int64(0)
+	if !attempt.Success && attempt.EventType != models.SecurityEventOTPRequest {
+		failedNow = 1
+	}
+
+	if attempt.Identifier != "" {
+		since := s.windowStart(models.BlockScopeIdentifier, attempt.Identifier, since)
+
+		var failures int64
+		s.db.Model(&models.SecurityEvent{}).
+			Where("identifier = ? AND status <> ? AND event_type <> ? AND created_at > ?",
+				attempt.Identifier, models.SecurityEventStatusSuccess, models.SecurityEventOTPRequest, since).
+			Count(&failures)
+		failures += failedNow
+		switch {
+		case failures >= 10:
+			factors = append(factors, RiskFactor{Name: RiskFactorIdentifierVelocity, Score: 50,
+				Detail: fmt.Sprintf("%d failed attempts in %s", failures, riskWindow), scope: models.BlockScopeIdentifier})
+		case failures >= 5:
+			factors = append(factors, RiskFactor{Name: RiskFactorIdentifierVelocity, Score: 30,
+				Detail: fmt.Sprintf("%d failed attempts in %s", failures, riskWindow), scope: models.BlockScopeIdentifier})
+		}
+
+		if attempt.EventType == models.SecurityEventOTPVerify || attempt.EventType == models.SecurityEventMFAVerify {
+			var otpFailures int64
+			s.db.Model(&models.SecurityEvent{}).
+				Where("identifier = ? AND status <> ? AND event_type IN ? AND created_at > ?",
+					attempt.Identifier, models.SecurityEventStatusSuccess,
+					[]string{models.SecurityEventOTPVerify, models.SecurityEventMFAVerify}, since).
+				Count(&otpFailures)
+			otpFailures += failedNow
+			switch {
+			case otpFailures >= 5:
+				factors = append(factors, RiskFactor{Name: RiskFactorOTPBruteForce, Score: 70,
+					Detail: fmt.Sprintf("%d wrong codes in %s", otpFailures, riskWindow), scope: models.BlockScopeIdentifier})
+			case otpFailures >= 3:
+				factors = append(factors, RiskFactor{Name: RiskFactorOTPBruteForce, Score: 40,
+					Detail: fmt.Sprintf("%d wrong codes in %s", otpFailures, riskWindow), scope: models.BlockScopeIdentifier})
+			}
+		}
+
+		if attempt.EventType == models.SecurityEventOTPRequest {
+			var requests int64
+			s.db.Model(&models.SecurityEvent{}).
+				Where("identifier = ? AND event_type = ? AND created_at > ?", attempt.Identifier, models.SecurityEventOTPRequest, since).
+				Count(&requests)
+			if requests+1 >= 5 {
+				factors = append(factors, RiskFactor{Name: RiskFactorOTPRequestFlood, Score: 40,
+					Detail: fmt.Sprintf("%d OTP requests in %s", requests+1, riskWindow), scope: models.BlockScopeIdentifier})
+			}
+		}
+	}
+
+	if attempt.IPAddress != "" {
+		since := s.windowStart(models.BlockScopeIP, attempt.IPAddress, since)
+
+		var attempts int64
+		s.db.Model(&models.SecurityEvent{}).
+			Where("ip_address = ? AND created_at > ?", attempt.IPAddress, since).
+			Count(&attempts)
+		if attempts+1 >= 30 {
+			factors = append(factors, RiskFactor{Name: RiskFactorIPVelocity, Score: 30,
+				Detail: fmt.Sprintf("%d attempts from %s in %s", attempts+1, attempt.IPAddress, riskWindow), scope: models.BlockScopeIP})
+		}
+
+		var identifiers []string
+		s.db.Model(&models.SecurityEvent{}).
+			Where("ip_address = ? AND identifier <> '' AND status <> ? AND created_at > ?",
+				attempt.IPAddress, models.SecurityEventStatusSuccess, since).
+			Distinct().Pluck("identifier", &identifiers)
+		distinct := len(identifiers)
+		if failedNow == 1 && attempt.Identifier != "" && !containsString(identifiers, attempt.Identifier) {
+			distinct++
+		}
+		if distinct >= 5 {
+			factors = append(factors, RiskFactor{Name: RiskFactorIPManyIdentifiers, Score: 40,
+				Detail: fmt.Sprintf("failed attempts for %d accounts from %s", distinct, attempt.IPAddress), scope: models.BlockScopeIP})
+		}
+	}
+
+	return factors
+}
+
+// windowStart moves the look-back start past the most recent block an admin lifted,
+// so the attempts that caused it don't immediately re-block the cleared subject
+func (s *RiskService) windowStart(scope, subject string, since time.Time) time.Time {
+	column := "identifier"
+	if scope == models.BlockScopeIP {
+		column = "ip_address"
+	}
+
+	var lifted models.SecurityEvent
+	err := s.db.Where("block_scope = ? AND "+column+" = ? AND is_blocked = ? AND is_investigated = ? AND blocked_until > ?",
+		scope, subject, true, true, since).
+		Order("blocked_until DESC").
+		First(&lifted).Error
+	if err != nil || lifted.BlockedUntil == nil || lifted.BlockedUntil.After(time.Now()) {
+		return since
+	}
+	return *lifted.BlockedUntil
+}
+
+// deviceAndTravelFactors scores a successful login against the user's known devices and last login location
+func (s *RiskService) deviceAndTravelFactors(attempt *RiskAttempt, position *GeoIPLocation, now time.Time) []RiskFactor {
+	var factors []RiskFactor
+
+	fingerprint := (&SessionContext{DeviceID: attempt.DeviceID, UserAgent: attempt.UserAgent}).Fingerprint()
+	if fingerprint != "" {
+		var known, matching int64
+		s.db.Model(&models.UserSession{}).Where("user_id = ?", *attempt.UserID).Count(&known)
+		s.db.Model(&models.UserSession{}).Where("user_id = ? AND device_fingerprint = ?", *attempt.UserID, fingerprint).Count(&matching)
+		if known > 0 && matching == 0 {
+			factors = append(factors, RiskFactor{Name: RiskFactorNewDevice, Score: 25,
+				Detail: "first login from this device", scope: models.BlockScopeIdentifier})
+		}
+	}
+
+	if position == nil {
+		return factors
+	}
+	var user models.UserAccount
+	if err := s.db.First(&user, *attempt.UserID).Error; err != nil {
+		return factors
+	}
+	if user.LastLoginLocatedAt == nil || user.LastLoginLatitude == nil || user.LastLoginLongitude == nil {
+		return factors
+	}
+
+	distanceKm := models.HaversineMeters(*user.LastLoginLatitude, *user.LastLoginLongitude, position.Latitude, position.Longitude) / 1000
+	elapsed := now.Sub(*user.LastLoginLocatedAt)
+	hours := elapsed.Hours()
+	if distanceKm < impossibleTravelMinKm {
+		return factors
+	}
+	// Clamp to a minute so back-to-back logins don't divide by ~zero
+	if hours < 1.0/60 {
+		hours = 1.0 / 60
+	}
+	if speed := distanceKm / hours; speed > impossibleTravelSpeedKmh {
+		factors = append(factors, RiskFactor{Name: RiskFactorImpossibleTravel, Score: 60,
+			Detail: fmt.Sprintf("%.0f km from last login %s ago (%.0f km/h)", distanceKm,
+				elapsed.Round(time.Minute), speed), scope: models.BlockScopeIdentifier})
+	}
+	return factors
+}
+
+// recordLoginLocation stores the login IP and the position it resolved to. An IP that does not
+// resolve clears the position, so travel is never measured from a stale one.
+func (s *RiskService) recordLoginLocation(userID uint, attempt *RiskAttempt, position *GeoIPLocation) {
+	updates := map[string]interface{}{
+		"last_login_ip":         attempt.IPAddress,
+		"last_login_latitude":   nil,
+		"last_login_longitude":  nil,
+		"last_login_located_at": nil,
+	}
+	if position != nil {
+		updates["last_login_latitude"] = position.Latitude
+		updates["last_login_longitude"] = position.Longitude
+		updates["last_login_located_at"] = time.Now()
+	}
+	_ = s.db.Model(&models.UserAccount{}).Where("id = ?", userID).Updates(updates).Error
+}
+
+func (s *RiskService) updateIPRisk(ipAddress string, ipScore float64, assessment *RiskAssessment) {
+	if ipAddress == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.ipRisk[ipAddress]
+	if !ok {
+		state = &ipRiskState{}
+		s.ipRisk[ipAddress] = state
+	}
+	state.score = ipScore
+	state.updatedAt = time.Now()
+	if assessment.Blocked && assessment.Event.BlockScope == models.BlockScopeIP {
+		state.blockedUntil = *assessment.BlockedUntil
+	}
+
+	// Opportunistic cleanup keeps the map bounded without a background goroutine
+	if len(s.ipRisk) > 10000 {
+		for ip, st := range s.ipRisk {
+			if time.Since(st.updatedAt) > riskWindow && time.Now().After(st.blockedUntil) {
+				delete(s.ipRisk, ip)
+			}
+		}
+	}
+}
+
+// locate resolves a client IP to its position, if a locator is configured and knows the address
+func (s *RiskService) locate(ipAddress string) *GeoIPLocation {
+	if s.geoLocator == nil || ipAddress == "" {
+		return nil
+	}
+	position, ok := s.geoLocator.Locate(ipAddress)
+	if !ok {
+		return nil
+	}
+	return position
+}
+
+// settings returns the security policy, cached for securitySettingsCacheTTL so scoring
+// an attempt does not read it from the database every time
+func (s *RiskService) settings() *models.SecuritySettings {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+
+	if s.settingsCache == nil || time.Since(s.settingsCachedAt) > securitySettingsCacheTTL {
+		settings := models.DefaultSecuritySettings()
+		if s.settingsSource != nil {
+			settings = s.settingsSource().WithDefaults()
+		}
+		s.settingsCache = settings
+		s.settingsCachedAt = time.Now()
+	}
+	return s.settingsCache
+}
+
+func factorNames(factors []RiskFactor) string {
+	names := make([]string, 0, len(factors))
+	for _, f := range factors {
+		names = append(names, f.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func describeAttempt(attempt *RiskAttempt, assessment *RiskAssessment) string {
+	outcome := "failed"
+	if attempt.Success {
+		outcome = "succeeded"
+	}
+	description := fmt.Sprintf("%s for %q from %s %s (risk %.0f)",
+		attempt.EventType, attempt.Identifier, attempt.IPAddress, outcome, assessment.Score)
+	if assessment.Blocked {
+		description += " and was blocked"
+	}
+	return description
+}
+
+func blockSubject(event *models.SecurityEvent) string {
+	if event.BlockScope == models.BlockScopeIP {
+		return event.IPAddress
+	}
+	return event.Identifier
+}
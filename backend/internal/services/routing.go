@@ -199,21 +199,9 @@ func (rs *RoutingService) CheckRouteDeviation(ctx context.Context, currentLoc Lo
 	return isDeviating, minDistance, nil
 }
 
-// distance calculates distance between two points in meters (Haversine approximation)
+// distance calculates distance between two points in meters
 func (rs *RoutingService) distance(p1, p2 maps.LatLng) float64 {
-	const R = 6371000 // Earth radius in meters
-
-	lat1 := p1.Lat * math.Pi / 180
-	lat2 := p2.Lat * math.Pi / 180
-	dLat := (p2.Lat - p1.Lat) * math.Pi / 180
-	dLng := (p2.Lng - p1.Lng) * math.Pi / 180
-
-	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
-		math.Cos(lat1)*math.Cos(lat2)*
-			math.Sin(dLng/2)*math.Sin(dLng/2)
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-
-	return R * c
+	return models.HaversineMeters(p1.Lat, p1.Lng, p2.Lat, p2.Lng)
 }
 
 // RouteAssignmentService handles automated trip assignment
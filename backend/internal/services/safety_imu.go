@@ -0,0 +1,495 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+)
+
+// ErrInvalidIMUBatch is returned for accelerometer batches that cannot be analysed
+var ErrInvalidIMUBatch = errors.New("invalid IMU batch")
+
+const (
+	standardGravity = 9.80665 // m/s²
+
+	// Lateral acceleration that makes a turn harsh, the level it must drop under to end the
+	// manoeuvre, and how long it must be held so a pothole does not count
+	corneringThresholdG  = 0.4
+	corneringReleaseG    = 0.25
+	minCorneringDuration = 250 * time.Millisecond
+
+	// Horizontal acceleration at which an impact is recorded, and at which it is treated as a crash
+	impactThresholdG = 2.5
+	crashThresholdG  = 4.0
+	impactPreWindow  = 10 * time.Second
+	impactPostWindow = 5 * time.Second
+	impactCooldown   = 10 * time.Second // Further spikes within this are the same collision
+
+	// Orientation learning
+	imuUpAlpha            = 0.02
+	imuForwardAlpha       = 0.05
+	imuForwardMinSamples  = 10
+	quasiStaticTolerance  = 0.3 // m/s² from 1 g for a sample to be taken as gravity alone
+	quasiStaticGyro       = 3   // deg/s
+	straightYawRate       = 2   // deg/s below which the vehicle is going straight
+	turningYawRate        = 5   // deg/s above which horizontal force is taken as cornering before calibration
+	minLongitudinalAccel  = 1.0 // m/s² horizontal for a sample to teach forward
+	minSpeedChangePerSec  = 0.5 // m/s² GPS speed change for a batch to teach forward
+	imuFrameDevice        = "device"
+	imuFrameVehicle       = "vehicle"
+	maxIMUSamplesPerBatch = 10000
+)
+
+// IMUSample is one accelerometer and gyro reading in the sensor's own axes. Accelerations are
+// specific force, as MEMS sensors report it, so a sensor at rest reads 1 g upwards.
+type IMUSample struct {
+	Timestamp time.Time `json:"timestamp"`
+	AX        float64   `json:"ax"` // m/s²
+	AY        float64   `json:"ay"`
+	AZ        float64   `json:"az"`
+	GX        float64   `json:"gx"` // deg/s
+	GY        float64   `json:"gy"`
+	GZ        float64   `json:"gz"`
+	Speed     *float64  `json:"speed,omitempty"` // GPS km/h when the sample was taken
+}
+
+// IMUBatch is a run of samples from one vehicle's sensor
+type IMUBatch struct {
+	VehicleID uint        `json:"vehicle_id"`
+	DriverID  *uint       `json:"driver_id,omitempty"`
+	Frame     string      `json:"frame,omitempty"` // "device" (default), mounted any way; "vehicle", x forward, y left, z up
+	Latitude  *float64    `json:"latitude,omitempty"`
+	Longitude *float64    `json:"longitude,omitempty"`
+	Samples   []IMUSample `json:"samples"`
+}
+
+// IMUAnalysis is what a batch of samples produced
+type IMUAnalysis struct {
+	Samples     int                    `json:"samples"`
+	Calibration *models.IMUCalibration `json:"calibration"`
+	Events      []models.SafetyEvent   `json:"events"`
+}
+
+// imuVehicleState is what the detectors remember about a vehicle between batches
+type imuVehicleState struct {
+	history     []models.IMUTraceSample // The last impactPreWindow of samples
+	cornerStart time.Time
+	cornerLast  time.Time
+	cornerPeak  models.IMUTraceSample
+	lastImpact  time.Time
+	pending     []*pendingTrace
+}
+
+// pendingTrace is an impact whose post-impact samples are still arriving
+type pendingTrace struct {
+	event *models.SafetyEvent
+	trace *models.SafetyEventTrace
+}
+
+type vec3 [3]float64
+
+func (v vec3) dot(o vec3) float64   { return v[0]*o[0] + v[1]*o[1] + v[2]*o[2] }
+func (v vec3) add(o vec3) vec3      { return vec3{v[0] + o[0], v[1] + o[1], v[2] + o[2]} }
+func (v vec3) sub(o vec3) vec3      { return vec3{v[0] - o[0], v[1] - o[1], v[2] - o[2]} }
+func (v vec3) scale(f float64) vec3 { return vec3{v[0] * f, v[1] * f, v[2] * f} }
+func (v vec3) norm() float64        { return math.Sqrt(v.dot(v)) }
+func (v vec3) cross(o vec3) vec3 {
+	return vec3{v[1]*o[2] - v[2]*o[1], v[2]*o[0] - v[0]*o[2], v[0]*o[1] - v[1]*o[0]}
+}
+func (v vec3) unit() vec3 {
+	if n := v.norm(); n > 0 {
+		return v.scale(1 / n)
+	}
+	return v
+}
+
+func calibrationUp(c *models.IMUCalibration) vec3 { return vec3{c.UpX, c.UpY, c.UpZ} }
+func calibrationForward(c *models.IMUCalibration) vec3 {
+	return vec3{c.ForwardX, c.ForwardY, c.ForwardZ}
+}
+
+// IngestIMU orients a batch of accelerometer and gyro samples to the vehicle, learning how the
+// sensor is mounted as it goes, and records harsh cornering and impacts
+func (s *SafetyService) IngestIMU(ctx context.Context, batch *IMUBatch) (*IMUAnalysis, error) {
+	if batch.VehicleID == 0 || len(batch.Samples) == 0 {
+		return nil, fmt.Errorf("%w: a vehicle and at least one sample are required", ErrInvalidIMUBatch)
+	}
+	if len(batch.Samples) > maxIMUSamplesPerBatch {
+		return nil, fmt.Errorf("%w: %d samples, at most %d per batch", ErrInvalidIMUBatch, len(batch.Samples), maxIMUSamplesPerBatch)
+	}
+	if batch.Frame == "" {
+		batch.Frame = imuFrameDevice
+	}
+	if batch.Frame != imuFrameDevice && batch.Frame != imuFrameVehicle {
+		return nil, fmt.Errorf("%w: frame %q, expected %q or %q", ErrInvalidIMUBatch, batch.Frame, imuFrameDevice, imuFrameVehicle)
+	}
+	var vehicle models.Vehicle
+	if err := s.db.WithContext(ctx).First(&vehicle, batch.VehicleID).Error; err != nil {
+		return nil, err
+	}
+	samples := append([]IMUSample(nil), batch.Samples...)
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Timestamp.Before(samples[j].Timestamp) })
+
+	s.imuMu.Lock()
+	defer s.imuMu.Unlock()
+
+	state, ok := s.imuState[batch.VehicleID]
+	if !ok {
+		state = &imuVehicleState{}
+		s.imuState[batch.VehicleID] = state
+	}
+
+	var calibration *models.IMUCalibration
+	if batch.Frame == imuFrameVehicle {
+		calibration = &models.IMUCalibration{VehicleID: batch.VehicleID, UpZ: 1, ForwardX: 1, Calibrated: true}
+	} else {
+		var stored []models.IMUCalibration
+		if err := s.db.WithContext(ctx).Where("vehicle_id = ?", batch.VehicleID).Limit(1).Find(&stored).Error; err != nil {
+			return nil, err
+		}
+		calibration = &models.IMUCalibration{VehicleID: batch.VehicleID}
+		if len(stored) > 0 {
+			calibration = &stored[0]
+		}
+		calibrateIMU(calibration, samples)
+		if calibration.UpSamples > 0 {
+			if err := s.db.WithContext(ctx).Save(calibration).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	analysis := &IMUAnalysis{Samples: len(samples), Calibration: calibration, Events: []models.SafetyEvent{}}
+	if calibration.UpSamples == 0 && batch.Frame == imuFrameDevice {
+		// Without knowing which way is up nothing can be told apart yet
+		return analysis, nil
+	}
+	latitude, longitude := s.imuPosition(batch)
+
+	for _, raw := range samples {
+		sample, horizontalG := orientIMU(calibration, raw)
+
+		for _, p := range state.pending {
+			if sample.Timestamp.After(p.trace.TriggeredAt) && !sample.Timestamp.After(p.trace.TriggeredAt.Add(impactPostWindow)) {
+				p.trace.Samples = append(p.trace.Samples, sample)
+				if horizontalG > p.event.Value {
+					p.event.Value = roundTo(horizontalG, 2)
+					p.event.Severity = impactSeverity(horizontalG)
+				}
+			}
+		}
+
+		if horizontalG >= impactThresholdG {
+			if state.lastImpact.IsZero() || sample.Timestamp.Sub(state.lastImpact) >= impactCooldown {
+				event, err := s.recordImpact(ctx, batch, state, sample, horizontalG, latitude, longitude)
+				if err != nil {
+					return nil, err
+				}
+				analysis.Events = append(analysis.Events, *event)
+			}
+			state.lastImpact = sample.Timestamp
+		}
+
+		if event := s.detectCornering(state, sample); event != nil {
+			event.VehicleID = batch.VehicleID
+			event.DriverID = batch.DriverID
+			event.Latitude, event.Longitude = latitude, longitude
+			if err := s.RecordSafetyEvent(event); err != nil {
+				return nil, err
+			}
+			analysis.Events = append(analysis.Events, *event)
+		}
+
+		state.history = append(state.history, sample)
+	}
+
+	last := samples[len(samples)-1].Timestamp
+	cut := 0
+	for cut < len(state.history) && last.Sub(state.history[cut].Timestamp) > impactPreWindow {
+		cut++
+	}
+	state.history = append([]models.IMUTraceSample(nil), state.history[cut:]...)
+
+	open := state.pending[:0]
+	for _, p := range state.pending {
+		p.trace.Complete = !last.Before(p.trace.TriggeredAt.Add(impactPostWindow))
+		if err := s.db.WithContext(ctx).Save(p.trace).Error; err != nil {
+			return nil, err
+		}
+		if err := s.db.WithContext(ctx).Model(p.event).
+			Updates(map[string]interface{}{"value": p.event.Value, "severity": p.event.Severity}).Error; err != nil {
+			return nil, err
+		}
+		if !p.trace.Complete {
+			open = append(open, p)
+		}
+	}
+	state.pending = open
+
+	return analysis, nil
+}
+
+// recordImpact records an impact event with the samples leading up to it and raises an emergency
+func (s *SafetyService) recordImpact(ctx context.Context, batch *IMUBatch, state *imuVehicleState, sample models.IMUTraceSample, horizontalG, latitude, longitude float64) (*models.SafetyEvent, error) {
+	event := &models.SafetyEvent{
+		VehicleID: batch.VehicleID,
+		DriverID:  batch.DriverID,
+		Type:      models.SafetyEventImpact,
+		Severity:  impactSeverity(horizontalG),
+		Value:     roundTo(horizontalG, 2),
+		Threshold: impactThresholdG,
+		Unit:      "g",
+		Latitude:  latitude,
+		Longitude: longitude,
+		Timestamp: sample.Timestamp,
+	}
+	if err := s.RecordSafetyEvent(event); err != nil {
+		return nil, err
+	}
+
+	samples := make([]models.IMUTraceSample, 0, len(state.history)+1)
+	for _, previous := range state.history {
+		if sample.Timestamp.Sub(previous.Timestamp) <= impactPreWindow {
+			samples = append(samples, previous)
+		}
+	}
+	trace := &models.SafetyEventTrace{
+		SafetyEventID: event.ID,
+		VehicleID:     batch.VehicleID,
+		TriggeredAt:   sample.Timestamp,
+		PreSeconds:    impactPreWindow.Seconds(),
+		PostSeconds:   impactPostWindow.Seconds(),
+		Samples:       append(samples, sample),
+	}
+	if err := s.db.WithContext(ctx).Create(trace).Error; err != nil {
+		return nil, err
+	}
+	state.pending = append(state.pending, &pendingTrace{event: event, trace: trace})
+	// A collision is not a cornering manoeuvre
+	state.cornerStart = time.Time{}
+
+	log.Printf("🚨 Impact of %.1f g recorded for vehicle %d", horizontalG, batch.VehicleID)
+	return event, nil
+}
+
+// detectCornering follows lateral acceleration through a turn and returns an event once a
+// harsh turn has ended
+func (s *SafetyService) detectCornering(state *imuVehicleState, sample models.IMUTraceSample) *models.SafetyEvent {
+	if !state.lastImpact.IsZero() && sample.Timestamp.Sub(state.lastImpact) < impactCooldown {
+		return nil
+	}
+	lateralG := math.Abs(sample.Lateral) / standardGravity
+	if lateralG >= corneringThresholdG {
+		if state.cornerStart.IsZero() {
+			state.cornerStart = sample.Timestamp
+			state.cornerPeak = sample
+		} else if math.Abs(sample.Lateral) > math.Abs(state.cornerPeak.Lateral) {
+			state.cornerPeak = sample
+		}
+		state.cornerLast = sample.Timestamp
+		return nil
+	}
+	if lateralG >= corneringReleaseG || state.cornerStart.IsZero() {
+		return nil
+	}
+
+	held := state.cornerLast.Sub(state.cornerStart)
+	peak := state.cornerPeak
+	state.cornerStart = time.Time{}
+	if held < minCorneringDuration {
+		return nil
+	}
+	peakG := math.Abs(peak.Lateral) / standardGravity
+	severity := models.SeverityMedium
+	switch {
+	case peakG >= 0.6:
+		severity = models.SeverityCritical
+	case peakG >= 0.5:
+		severity = models.SeverityHigh
+	}
+	return &models.SafetyEvent{
+		Type:      models.SafetyEventHarshCornering,
+		Severity:  severity,
+		Value:     roundTo(peakG, 2),
+		Threshold: corneringThresholdG,
+		Unit:      "g",
+		Timestamp: peak.Timestamp,
+	}
+}
+
+// imuPosition is where the vehicle was: as reported with the batch, else its last known location
+func (s *SafetyService) imuPosition(batch *IMUBatch) (float64, float64) {
+	if batch.Latitude != nil && batch.Longitude != nil {
+		return *batch.Latitude, *batch.Longitude
+	}
+	s.stateMu.RLock()
+	last, ok := s.vehicleState[batch.VehicleID]
+	s.stateMu.RUnlock()
+	if ok {
+		return last.Latitude, last.Longitude
+	}
+	var pings []models.LocationPing
+	if err := s.db.Where("vehicle_id = ?", batch.VehicleID).Order("timestamp DESC").Limit(1).Find(&pings).Error; err == nil && len(pings) > 0 {
+		return pings[0].Latitude, pings[0].Longitude
+	}
+	return 0, 0
+}
+
+// GetSafetyEventTrace returns the sensor trace recorded around an impact
+func (s *SafetyService) GetSafetyEventTrace(ctx context.Context, eventID uint) (*models.SafetyEventTrace, error) {
+	var trace models.SafetyEventTrace
+	if err := s.db.WithContext(ctx).Where("safety_event_id = ?", eventID).First(&trace).Error; err != nil {
+		return nil, err
+	}
+	return &trace, nil
+}
+
+// ResetIMUCalibration forgets how a vehicle's sensor is mounted, for when it has been refitted
+func (s *SafetyService) ResetIMUCalibration(ctx context.Context, vehicleID uint) error {
+	s.imuMu.Lock()
+	defer s.imuMu.Unlock()
+
+	delete(s.imuState, vehicleID)
+	return s.db.WithContext(ctx).Where("vehicle_id = ?", vehicleID).Delete(&models.IMUCalibration{}).Error
+}
+
+// handleIMUBatch analyses a batch received over MQTT
+func (s *SafetyService) handleIMUBatch(batch *IMUBatch) {
+	if _, err := s.IngestIMU(context.Background(), batch); err != nil {
+		log.Printf("❌ Failed to analyse IMU batch for vehicle %d: %v", batch.VehicleID, err)
+	}
+}
+
+// calibrateIMU learns up from samples where the sensor only feels gravity, and forward from
+// horizontal force while the vehicle speeds up or slows down in a straight line
+func calibrateIMU(c *models.IMUCalibration, samples []IMUSample) {
+	// A batch over which GPS speed changed has horizontal force in it, so it cannot teach up
+	changing, speedSign := speedTrend(samples)
+
+	if !changing {
+		var mean vec3
+		count := 0
+		for _, sample := range samples {
+			a := vec3{sample.AX, sample.AY, sample.AZ}
+			w := vec3{sample.GX, sample.GY, sample.GZ}
+			if math.Abs(a.norm()-standardGravity) > quasiStaticTolerance || w.norm() > quasiStaticGyro {
+				continue
+			}
+			if c.UpSamples == 0 {
+				mean = mean.add(a)
+				count++
+				continue
+			}
+			up := calibrationUp(c).scale(1 - imuUpAlpha).add(a.unit().scale(imuUpAlpha)).unit()
+			c.UpX, c.UpY, c.UpZ = up[0], up[1], up[2]
+			c.UpSamples++
+		}
+		if count > 0 {
+			up := mean.unit()
+			c.UpX, c.UpY, c.UpZ = up[0], up[1], up[2]
+			c.UpSamples = count
+		}
+	}
+	if c.UpSamples == 0 || !changing {
+		return
+	}
+
+	up := calibrationUp(c)
+	for _, sample := range samples {
+		a := vec3{sample.AX, sample.AY, sample.AZ}
+		w := vec3{sample.GX, sample.GY, sample.GZ}
+		if math.Abs(w.dot(up)) > straightYawRate {
+			continue
+		}
+		dynamic := a.sub(up.scale(standardGravity))
+		horizontal := dynamic.sub(up.scale(dynamic.dot(up)))
+		if horizontal.norm() < minLongitudinalAccel {
+			continue
+		}
+		direction := horizontal.unit().scale(speedSign)
+		forward := direction
+		if c.ForwardSamples > 0 {
+			forward = calibrationForward(c).scale(1 - imuForwardAlpha).add(direction.scale(imuForwardAlpha))
+		}
+		// Keep forward level as up is refined
+		forward = forward.sub(up.scale(forward.dot(up))).unit()
+		c.ForwardX, c.ForwardY, c.ForwardZ = forward[0], forward[1], forward[2]
+		c.ForwardSamples++
+	}
+	c.Calibrated = c.ForwardSamples >= imuForwardMinSamples
+}
+
+// speedTrend reports whether GPS speed changed over the samples, and in which direction
+func speedTrend(samples []IMUSample) (bool, float64) {
+	var first, last *IMUSample
+	for i := range samples {
+		if samples[i].Speed == nil {
+			continue
+		}
+		if first == nil {
+			first = &samples[i]
+		}
+		last = &samples[i]
+	}
+	if first == nil || first == last {
+		return false, 0
+	}
+	elapsed := last.Timestamp.Sub(first.Timestamp).Seconds()
+	if elapsed <= 0 {
+		return false, 0
+	}
+	rate := (*last.Speed - *first.Speed) / 3.6 / elapsed
+	if math.Abs(rate) < minSpeedChangePerSec {
+		return false, 0
+	}
+	return true, math.Copysign(1, rate)
+}
+
+// orientIMU expresses a sample in the vehicle's frame with gravity removed, and returns the
+// horizontal acceleration in g. Until forward is learned, horizontal force while turning is
+// taken as lateral.
+func orientIMU(c *models.IMUCalibration, sample IMUSample) (models.IMUTraceSample, float64) {
+	up := calibrationUp(c)
+	a := vec3{sample.AX, sample.AY, sample.AZ}
+	w := vec3{sample.GX, sample.GY, sample.GZ}
+	dynamic := a.sub(up.scale(standardGravity))
+	vertical := dynamic.dot(up)
+	horizontal := dynamic.sub(up.scale(vertical))
+
+	oriented := models.IMUTraceSample{
+		Timestamp: sample.Timestamp,
+		Vertical:  roundTo(vertical, 3),
+		YawRate:   roundTo(w.dot(up), 3),
+		Magnitude: roundTo(dynamic.norm()/standardGravity, 3),
+	}
+	switch {
+	case c.Calibrated:
+		forward := calibrationForward(c)
+		oriented.Longitudinal = roundTo(horizontal.dot(forward), 3)
+		oriented.Lateral = roundTo(horizontal.dot(up.cross(forward)), 3)
+	case math.Abs(oriented.YawRate) >= turningYawRate && sample.Speed != nil:
+		// Centripetal acceleration is speed times yaw rate
+		oriented.Lateral = roundTo(*sample.Speed/3.6*oriented.YawRate*math.Pi/180, 3)
+	case math.Abs(oriented.YawRate) >= turningYawRate:
+		oriented.Lateral = roundTo(math.Copysign(horizontal.norm(), oriented.YawRate), 3)
+	}
+	return oriented, horizontal.norm() / standardGravity
+}
+
+func impactSeverity(g float64) models.SafetyEventSeverity {
+	if g >= crashThresholdG {
+		return models.SeverityCritical
+	}
+	return models.SeverityHigh
+}
+
+func roundTo(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIMUOrientation learns a tilted, sideways mounting and reads a left turn back in the vehicle frame
+func TestIMUOrientation(t *testing.T) {
+	// The sensor's axes in vehicle terms: forward, left and up as device vectors
+	forward, left, up := vec3{0, -0.866025, 0.5}, vec3{1, 0, 0}, vec3{0, 0.5, 0.866025}
+	toDevice := func(v vec3) vec3 { return forward.scale(v[0]).add(left.scale(v[1])).add(up.scale(v[2])) }
+	sample := func(at time.Time, accel, gyro vec3, speed float64) IMUSample {
+		a, g := toDevice(accel), toDevice(gyro)
+		return IMUSample{Timestamp: at, AX: a[0], AY: a[1], AZ: a[2], GX: g[0], GY: g[1], GZ: g[2], Speed: &speed}
+	}
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	calibration := &models.IMUCalibration{}
+	var parked, braking []IMUSample
+	for i := 0; i < 20; i++ {
+		at := start.Add(time.Duration(i) * 100 * time.Millisecond)
+		parked = append(parked, sample(at, vec3{0, 0, standardGravity}, vec3{}, 0))
+		braking = append(braking, sample(at.Add(time.Minute), vec3{-3, 0, standardGravity}, vec3{}, 60-float64(i)*1.08))
+	}
+
+	// Braking before up is known teaches nothing
+	calibrateIMU(calibration, braking)
+	assert.Zero(t, calibration.UpSamples)
+
+	calibrateIMU(calibration, parked)
+	assert.Equal(t, 20, calibration.UpSamples)
+	assert.InDelta(t, 1, calibrationUp(calibration).dot(up), 1e-6)
+	assert.False(t, calibration.Calibrated)
+
+	// Deceleration points backwards, so forward is learned opposite to it
+	calibrateIMU(calibration, braking)
+	require.True(t, calibration.Calibrated)
+	assert.Equal(t, 20, calibration.UpSamples)
+	assert.InDelta(t, 1, calibrationForward(calibration).dot(forward), 1e-6)
+
+	turning, horizontalG := orientIMU(calibration, sample(start, vec3{0.5, 4.9, standardGravity + 1}, vec3{0, 0, 20}, 40))
+	assert.InDelta(t, 0.5, turning.Longitudinal, 1e-3)
+	assert.InDelta(t, 4.9, turning.Lateral, 1e-3)
+	assert.InDelta(t, 1, turning.Vertical, 1e-3)
+	assert.InDelta(t, 20, turning.YawRate, 1e-3)
+	assert.InDelta(t, 0.5, horizontalG, 0.01)
+
+	// Before forward is known, lateral comes from speed and yaw rate
+	uncalibrated := &models.IMUCalibration{UpX: up[0], UpY: up[1], UpZ: up[2], UpSamples: 1}
+	turning, _ = orientIMU(uncalibrated, sample(start, vec3{0, 4.9, standardGravity}, vec3{0, 0, 25}, 40))
+	assert.InDelta(t, 40/3.6*25*0.0174533, turning.Lateral, 1e-2)
+	assert.Zero(t, turning.Longitudinal)
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"sync"
@@ -22,6 +23,18 @@ type SafetyService struct {
 	geofences []models.Geofence
 
 	stateMu sync.RWMutex
+
+	// Accelerometer orientation and detector state (VehicleID -> state)
+	imuState map[uint]*imuVehicleState
+	imuMu    sync.Mutex
+
+	// Speed limits and open speeding episodes (VehicleID -> episode)
+	speedLimits *SpeedLimitService
+	speeding    map[uint]*speedingState
+	speedingMu  sync.Mutex
+
+	// Dashcam footage for recorded events
+	videoService *VideoService
 }
 
 // NewSafetyService creates a new safety service
@@ -30,6 +43,8 @@ func NewSafetyService(db *gorm.DB, mqttService *MQTTService) *SafetyService {
 		db:           db,
 		mqttService:  mqttService,
 		vehicleState: make(map[uint]*models.LocationPing),
+		imuState:     make(map[uint]*imuVehicleState),
+		speeding:     make(map[uint]*speedingState),
 	}
 
 	// Load initial geofences
@@ -37,16 +52,30 @@ func NewSafetyService(db *gorm.DB, mqttService *MQTTService) *SafetyService {
 
 	// Start periodic reload
 	go s.periodicGeofenceReload()
+	go s.periodicSpeedingSweep()
 
 	return s
 }
 
+// SetSpeedLimitService sets the provider of road and geofence speed limits
+func (s *SafetyService) SetSpeedLimitService(speedLimits *SpeedLimitService) {
+	s.speedLimits = speedLimits
+}
+
+// SetVideoService sets the service events are linked to dashcam footage through
+func (s *SafetyService) SetVideoService(videoService *VideoService) {
+	s.videoService = videoService
+}
+
 // Start begins the safety monitoring process
 func (s *SafetyService) Start() error {
 	// Subscribe to wildcard MQTT topic (Parallel to Ingestion)
 	if err := s.mqttService.SubscribeToAllVehicleLocations(s.handleLocationUpdate); err != nil {
 		return fmt.Errorf("failed to subscribe to vehicle locations: %w", err)
 	}
+	if err := s.mqttService.SubscribeToAllVehicleIMU(s.handleIMUBatch); err != nil {
+		return fmt.Errorf("failed to subscribe to vehicle IMU samples: %w", err)
+	}
 
 	log.Println("🛡️ Safety Service started: Monitoring driving behavior")
 	return nil
@@ -61,6 +90,7 @@ func (s *SafetyService) handleLocationUpdate(loc *LocationUpdate) {
 		Longitude: loc.Longitude,
 		Speed:     &loc.Speed,
 		Timestamp: loc.Timestamp,
+		DriverID:  loc.DriverID,
 	}
 
 	// 2. Get previous state
@@ -73,13 +103,15 @@ func (s *SafetyService) handleLocationUpdate(loc *LocationUpdate) {
 	s.vehicleState[loc.VehicleID] = currentPing
 	s.stateMu.Unlock()
 
+	// Speeding episodes span pings, so they are followed in arrival order
+	s.detectSpeeding(currentPing)
+
 	if !exists {
 		return // Need at least 2 points to calculate deltas
 	}
 
 	// 4. Run Detectors
 	go s.detectHarshDriving(currentPing, lastPing)
-	go s.detectSpeeding(currentPing)
 	go s.detectGeofence(currentPing, lastPing)
 }
 
@@ -114,6 +146,7 @@ func (s *SafetyService) detectHarshDriving(current, last *models.LocationPing) {
 	// Create Safety Event
 	event := &models.SafetyEvent{
 		VehicleID: *current.VehicleID,
+		DriverID:  current.DriverID,
 		Type:      eventType,
 		Severity:  models.SeverityHigh,
 		Value:     acceleration,
@@ -127,31 +160,10 @@ func (s *SafetyService) detectHarshDriving(current, last *models.LocationPing) {
 	s.recordEvent(event)
 }
 
-// detectSpeeding checks for absolute speed violations
+// detectSpeeding follows the vehicle's speeding episode against the limit where it is
 func (s *SafetyService) detectSpeeding(current *models.LocationPing) {
-	const speedLimit = 80.0 // Hardcoded for now, should be per-vehicle or per-road
-
-	if *current.Speed > speedLimit {
-		event := &models.SafetyEvent{
-			VehicleID: *current.VehicleID,
-			Type:      models.SafetyEventSpeeding,
-			Severity:  models.SeverityMedium,
-			Value:     *current.Speed,
-			Threshold: speedLimit,
-			Unit:      "km/h",
-			Latitude:  current.Latitude,
-			Longitude: current.Longitude,
-			Timestamp: current.Timestamp,
-		}
-
-		if *current.Speed > 100 {
-			event.Severity = models.SeverityHigh
-		}
-		if *current.Speed > 120 {
-			event.Severity = models.SeverityCritical
-		}
-
-		s.recordEvent(event)
+	if _, err := s.TrackSpeeding(context.Background(), current); err != nil {
+		log.Printf("❌ Failed to track speeding for vehicle %d: %v", *current.VehicleID, err)
 	}
 }
 
@@ -169,6 +181,7 @@ func (s *SafetyService) detectGeofence(current, last *models.LocationPing) {
 			// Entry Event
 			event := &models.SafetyEvent{
 				VehicleID: *current.VehicleID,
+				DriverID:  current.DriverID,
 				Type:      models.SafetyEventGeofenceEntry,
 				Severity:  models.SeverityLow,
 				Value:     1.0,
@@ -183,6 +196,7 @@ func (s *SafetyService) detectGeofence(current, last *models.LocationPing) {
 			// Exit Event
 			event := &models.SafetyEvent{
 				VehicleID: *current.VehicleID,
+				DriverID:  current.DriverID,
 				Type:      models.SafetyEventGeofenceExit,
 				Severity:  models.SeverityLow,
 				Value:     1.0,
@@ -220,27 +234,76 @@ func (s *SafetyService) periodicGeofenceReload() {
 	}
 }
 
-// recordEvent saves the event to DB and publishes an alert
+// recordEvent saves the event and publishes an alert
 func (s *SafetyService) recordEvent(event *models.SafetyEvent) {
-	// 1. Save to DB
-	if err := s.db.Create(event).Error; err != nil {
+	if err := s.RecordSafetyEvent(event); err != nil {
 		log.Printf("❌ Failed to save safety event: %v", err)
 		return
 	}
 
-	// 2. Publish Alert via MQTT
-	alert := &FleetAlert{
-		Type:           string(event.Type),
-		Severity:       string(event.Severity),
-		VehicleID:      &event.VehicleID,
-		Message:        fmt.Sprintf("%s detected: %.2f %s", event.Type, event.Value, event.Unit),
-		Timestamp:      event.Timestamp,
-		RequiresAction: event.Severity == models.SeverityCritical,
+	log.Printf("⚠️ Safety Event Recorded: %s for Vehicle %d (Value: %.2f)", event.Type, event.VehicleID, event.Value)
+}
+
+// attributeEvent fills in the trip the vehicle was on when an event happened and the driver
+// from the trip or the duty log
+func (s *SafetyService) attributeEvent(event *models.SafetyEvent) error {
+	if event.TripID != nil && event.DriverID != nil {
+		return nil
+	}
+	trip, driverID, err := vehicleAttribution(s.db, event.VehicleID, event.Timestamp)
+	if err != nil {
+		return err
+	}
+	if event.TripID == nil && trip != nil {
+		event.TripID = &trip.ID
+	}
+	if event.DriverID == nil {
+		event.DriverID = driverID
 	}
+	return nil
+}
 
-	if err := s.mqttService.PublishFleetAlert(alert); err != nil {
-		log.Printf("❌ Failed to publish safety alert: %v", err)
+// RecordSafetyEvent attributes an event to the active trip and driver, saves it and alerts the fleet
+func (s *SafetyService) RecordSafetyEvent(event *models.SafetyEvent) error {
+	if err := s.attributeEvent(event); err != nil {
+		return err
+	}
+	if err := s.db.Omit("Vehicle", "Driver", "Trip", "VideoClip").Create(event).Error; err != nil {
+		return err
+	}
+	if s.videoService != nil {
+		// Missing footage never loses the event
+		if _, err := s.videoService.LinkEventFootage(context.Background(), event, time.Now()); err != nil {
+			log.Printf("❌ Failed to link footage to safety event %d: %v", event.ID, err)
+		}
 	}
 
-	log.Printf("⚠️ Safety Event Recorded: %s for Vehicle %d (Value: %.2f)", event.Type, event.VehicleID, event.Value)
+	if s.mqttService != nil && s.mqttService.IsEnabled() {
+		alert := &FleetAlert{
+			Type:           string(event.Type),
+			Severity:       string(event.Severity),
+			VehicleID:      &event.VehicleID,
+			DriverID:       event.DriverID,
+			TripID:         event.TripID,
+			Message:        fmt.Sprintf("%s detected: %.2f %s", event.Type, event.Value, event.Unit),
+			Timestamp:      event.Timestamp,
+			RequiresAction: event.Severity == models.SeverityCritical,
+		}
+		publish := s.mqttService.PublishFleetAlert
+		if event.Type == models.SafetyEventImpact {
+			// A possible collision goes to the emergency channel whatever its severity
+			alert.Location = &LocationUpdate{
+				VehicleID: event.VehicleID,
+				DriverID:  event.DriverID,
+				Latitude:  event.Latitude,
+				Longitude: event.Longitude,
+				Timestamp: event.Timestamp,
+			}
+			publish = s.mqttService.PublishEmergencyAlert
+		}
+		if err := publish(alert); err != nil {
+			log.Printf("❌ Failed to publish safety alert: %v", err)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,435 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidServiceSchedule is returned when a schedule or template has no interval, or its task or vehicle is unknown
+var ErrInvalidServiceSchedule = errors.New("invalid service schedule")
+
+// maintenanceManagerRoles is who is told about due services
+var maintenanceManagerRoles = []models.Role{models.RoleDispatcher, models.RoleOrgAdmin}
+
+// ServiceSchedulerResult summarizes a scheduler run
+type ServiceSchedulerResult struct {
+	Evaluated  int                      `json:"evaluated"`
+	DueSoon    []models.ServiceSchedule `json:"due_soon"`
+	Due        []models.ServiceSchedule `json:"due"`
+	WorkOrders []models.WorkOrder       `json:"work_orders"` // Opened by this run
+}
+
+// SetNotificationService enables SMS notifications to fleet managers when services come due
+func (s *MaintenanceService) SetNotificationService(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// currentEngineHours is the latest engine-hour reading reported for the vehicle, 0 when none
+func (s *MaintenanceService) currentEngineHours(db *gorm.DB, vehicleID uint) float64 {
+	var logs []models.TelemetryLog
+	db.Select("engine_hours").Where("vehicle_id = ? AND engine_hours IS NOT NULL", vehicleID).
+		Order("timestamp DESC").Limit(1).Find(&logs)
+	if len(logs) == 0 || logs[0].EngineHours == nil {
+		return 0
+	}
+	return *logs[0].EngineHours
+}
+
+// validateServiceIntervals requires at least one interval
+func validateServiceIntervals(mileage, months int, engineHours float64) error {
+	if mileage < 0 || months < 0 || engineHours < 0 {
+		return fmt.Errorf("%w: intervals cannot be negative", ErrInvalidServiceSchedule)
+	}
+	if mileage == 0 && months == 0 && engineHours == 0 {
+		return fmt.Errorf("%w: set a mileage, month or engine-hour interval", ErrInvalidServiceSchedule)
+	}
+	return nil
+}
+
+// scheduleNextService starts a new service cycle from the given readings
+func scheduleNextService(schedule *models.ServiceSchedule, mileage int, engineHours float64, at time.Time) {
+	schedule.LastMileage = mileage
+	schedule.LastEngineHours = engineHours
+	schedule.NextDueMileage, schedule.NextDueEngineHours, schedule.NextDueDate = 0, 0, nil
+	if schedule.IntervalMileage > 0 {
+		schedule.NextDueMileage = mileage + schedule.IntervalMileage
+	}
+	if schedule.IntervalEngineHours > 0 {
+		schedule.NextDueEngineHours = engineHours + schedule.IntervalEngineHours
+	}
+	if schedule.IntervalMonths > 0 {
+		next := at.AddDate(0, schedule.IntervalMonths, 0)
+		schedule.NextDueDate = &next
+	}
+	schedule.DueStatus = models.ServiceDueOK
+	schedule.DueReason = ""
+	schedule.WorkOrderID = nil
+}
+
+// CreateServiceSchedule schedules a task for a vehicle, counting its first cycle from the
+// vehicle's current mileage and engine hours
+func (s *MaintenanceService) CreateServiceSchedule(ctx context.Context, schedule *models.ServiceSchedule) error {
+	if err := validateServiceIntervals(schedule.IntervalMileage, schedule.IntervalMonths, schedule.IntervalEngineHours); err != nil {
+		return err
+	}
+	var vehicle models.Vehicle
+	if err := s.db.First(&vehicle, schedule.VehicleID).Error; err != nil {
+		return err
+	}
+	var task models.MaintenanceTask
+	if err := s.db.First(&task, schedule.MaintenanceTaskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: maintenance task %d not found", ErrInvalidServiceSchedule, schedule.MaintenanceTaskID)
+		}
+		return err
+	}
+
+	schedule.Status = "ACTIVE"
+	scheduleNextService(schedule, int(vehicle.TotalKilometers), s.currentEngineHours(s.db, vehicle.ID), time.Now())
+	if err := s.db.Omit("Vehicle", "MaintenanceTask").Create(schedule).Error; err != nil {
+		return err
+	}
+	schedule.MaintenanceTask = task
+	return nil
+}
+
+// GetServiceSchedules lists the active schedules of a vehicle, or of every vehicle when vehicleID is 0
+func (s *MaintenanceService) GetServiceSchedules(ctx context.Context, vehicleID uint) ([]models.ServiceSchedule, error) {
+	query := s.db.Where("status = ?", "ACTIVE")
+	if vehicleID > 0 {
+		query = query.Where("vehicle_id = ?", vehicleID)
+	}
+	var schedules []models.ServiceSchedule
+	if err := query.Preload("MaintenanceTask").Order("vehicle_id, id").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// SaveServiceScheduleTemplate creates the template for a vehicle type and task, or updates it
+func (s *MaintenanceService) SaveServiceScheduleTemplate(ctx context.Context, template *models.ServiceScheduleTemplate) (*models.ServiceScheduleTemplate, error) {
+	if err := validateServiceIntervals(template.IntervalMileage, template.IntervalMonths, template.IntervalEngineHours); err != nil {
+		return nil, err
+	}
+	var task models.MaintenanceTask
+	if err := s.db.First(&task, template.MaintenanceTaskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: maintenance task %d not found", ErrInvalidServiceSchedule, template.MaintenanceTaskID)
+		}
+		return nil, err
+	}
+
+	var existing []models.ServiceScheduleTemplate
+	if err := s.db.Where("vehicle_type = ? AND maintenance_task_id = ?", template.VehicleType, template.MaintenanceTaskID).
+		Limit(1).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		template.ID = existing[0].ID
+		template.CreatedAt = existing[0].CreatedAt
+		template.CreatedBy = existing[0].CreatedBy
+	}
+	if err := s.db.Omit("MaintenanceTask").Save(template).Error; err != nil {
+		return nil, err
+	}
+	template.MaintenanceTask = &task
+	return template, nil
+}
+
+// GetServiceScheduleTemplates lists templates, optionally for one vehicle type
+func (s *MaintenanceService) GetServiceScheduleTemplates(ctx context.Context, vehicleType string) ([]models.ServiceScheduleTemplate, error) {
+	query := s.db.Model(&models.ServiceScheduleTemplate{})
+	if vehicleType != "" {
+		query = query.Where("vehicle_type = ?", strings.ToUpper(vehicleType))
+	}
+	var templates []models.ServiceScheduleTemplate
+	if err := query.Preload("MaintenanceTask").Order("vehicle_type, maintenance_task_id").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// ApplyServiceScheduleTemplates gives a vehicle a schedule for every active template of its type
+// that it does not already have
+func (s *MaintenanceService) ApplyServiceScheduleTemplates(ctx context.Context, vehicle *models.Vehicle) ([]models.ServiceSchedule, error) {
+	var templates []models.ServiceScheduleTemplate
+	if err := s.db.Where("vehicle_type = ? AND is_active = ?", vehicle.VehicleType, true).
+		Order("id").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+
+	created := []models.ServiceSchedule{}
+	engineHours := s.currentEngineHours(s.db, vehicle.ID)
+	for _, template := range templates {
+		var count int64
+		s.db.Model(&models.ServiceSchedule{}).
+			Where("vehicle_id = ? AND maintenance_task_id = ? AND status = ?", vehicle.ID, template.MaintenanceTaskID, "ACTIVE").
+			Count(&count)
+		if count > 0 {
+			continue
+		}
+
+		templateID := template.ID
+		schedule := models.ServiceSchedule{
+			VehicleID:           vehicle.ID,
+			MaintenanceTaskID:   template.MaintenanceTaskID,
+			TemplateID:          &templateID,
+			IntervalMileage:     template.IntervalMileage,
+			IntervalMonths:      template.IntervalMonths,
+			IntervalEngineHours: template.IntervalEngineHours,
+			DueSoonMileage:      template.DueSoonMileage,
+			DueSoonDays:         template.DueSoonDays,
+			DueSoonEngineHours:  template.DueSoonEngineHours,
+			Status:              "ACTIVE",
+		}
+		scheduleNextService(&schedule, int(vehicle.TotalKilometers), engineHours, time.Now())
+		if err := s.db.Omit("Vehicle", "MaintenanceTask").Create(&schedule).Error; err != nil {
+			return created, err
+		}
+		created = append(created, schedule)
+	}
+	return created, nil
+}
+
+// dueServiceSchedules evaluates every active schedule of the active vehicles. The returned
+// schedules carry their current due state but are not saved.
+func (s *MaintenanceService) dueServiceSchedules(now time.Time) ([]models.ServiceSchedule, map[uint]*models.Vehicle, error) {
+	var vehicles []models.Vehicle
+	if err := s.db.Where("is_active = ?", true).Find(&vehicles).Error; err != nil {
+		return nil, nil, err
+	}
+
+	byID := make(map[uint]*models.Vehicle, len(vehicles))
+	var evaluated []models.ServiceSchedule
+	for i := range vehicles {
+		vehicle := &vehicles[i]
+		byID[vehicle.ID] = vehicle
+
+		var schedules []models.ServiceSchedule
+		if err := s.db.Where("vehicle_id = ? AND status = ?", vehicle.ID, "ACTIVE").
+			Preload("MaintenanceTask").Find(&schedules).Error; err != nil {
+			return nil, nil, err
+		}
+		if len(schedules) == 0 {
+			continue
+		}
+
+		engineHours := s.currentEngineHours(s.db, vehicle.ID)
+		for _, schedule := range schedules {
+			state, reasons := schedule.DueState(int(vehicle.TotalKilometers), engineHours, now)
+			schedule.DueStatus = state
+			schedule.DueReason = strings.Join(reasons, ",")
+			evaluated = append(evaluated, schedule)
+		}
+	}
+	return evaluated, byID, nil
+}
+
+// DueServiceSchedules lists schedules that are due, and those due soon when includeDueSoon is set
+func (s *MaintenanceService) DueServiceSchedules(ctx context.Context, includeDueSoon bool) ([]models.ServiceSchedule, error) {
+	evaluated, _, err := s.dueServiceSchedules(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	due := []models.ServiceSchedule{}
+	for _, schedule := range evaluated {
+		if schedule.DueStatus == models.ServiceDueNow || (includeDueSoon && schedule.DueStatus == models.ServiceDueSoon) {
+			due = append(due, schedule)
+		}
+	}
+	return due, nil
+}
+
+// RunServiceScheduler records each schedule's due state, opens a work order for every service
+// that came due and notifies fleet managers when a service becomes due soon or due
+func (s *MaintenanceService) RunServiceScheduler(ctx context.Context, now time.Time) (*ServiceSchedulerResult, error) {
+	evaluated, vehicles, err := s.dueServiceSchedules(now)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ServiceSchedulerResult{
+		DueSoon:    []models.ServiceSchedule{},
+		Due:        []models.ServiceSchedule{},
+		WorkOrders: []models.WorkOrder{},
+	}
+	for i := range evaluated {
+		schedule := &evaluated[i]
+		vehicle := vehicles[schedule.VehicleID]
+		result.Evaluated++
+
+		var stored models.ServiceSchedule
+		if err := s.db.Select("due_status").First(&stored, schedule.ID).Error; err != nil {
+			return nil, err
+		}
+		changed := stored.DueStatus != schedule.DueStatus
+
+		switch schedule.DueStatus {
+		case models.ServiceDueSoon:
+			result.DueSoon = append(result.DueSoon, *schedule)
+			if changed {
+				s.notifyMaintenanceManagers(vehicle, fmt.Sprintf("🔧 %s is due soon for %s (%s). %s",
+					vehicle.LicensePlate, schedule.MaintenanceTask.Name, strings.ToLower(schedule.DueReason), describeNextService(schedule)))
+			}
+		case models.ServiceDueNow:
+			result.Due = append(result.Due, *schedule)
+			workOrder, err := s.openScheduledWorkOrder(schedule, vehicle, now)
+			if err != nil {
+				return nil, err
+			}
+			if workOrder != nil {
+				result.WorkOrders = append(result.WorkOrders, *workOrder)
+			}
+			if changed || workOrder != nil {
+				message := fmt.Sprintf("🔧 %s is due for %s (%s).", vehicle.LicensePlate, schedule.MaintenanceTask.Name,
+					strings.ToLower(schedule.DueReason))
+				if workOrder != nil {
+					message += fmt.Sprintf(" Work order #%d opened.", workOrder.ID)
+				}
+				s.notifyMaintenanceManagers(vehicle, message)
+
+				event := MaintenanceDueEvent{
+					VehicleID:         vehicle.ID,
+					LicensePlate:      vehicle.LicensePlate,
+					Source:            "SCHEDULE",
+					Task:              schedule.MaintenanceTask.Name,
+					ServiceScheduleID: &schedule.ID,
+					WorkOrderID:       schedule.WorkOrderID,
+					Message:           message,
+				}
+				s.publishMaintenanceDue(&event)
+			}
+		}
+
+		if err := s.db.Model(&models.ServiceSchedule{}).Where("id = ?", schedule.ID).Updates(map[string]interface{}{
+			"due_status":    schedule.DueStatus,
+			"due_reason":    schedule.DueReason,
+			"work_order_id": schedule.WorkOrderID,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// openScheduledWorkOrder opens the work order for a due service unless one is already open.
+// Opening it does not take the vehicle off the road; the shop does that when work starts.
+func (s *MaintenanceService) openScheduledWorkOrder(schedule *models.ServiceSchedule, vehicle *models.Vehicle, now time.Time) (*models.WorkOrder, error) {
+	if schedule.WorkOrderID != nil {
+		var existing models.WorkOrder
+		if err := s.db.First(&existing, *schedule.WorkOrderID).Error; err == nil &&
+			existing.Status != "COMPLETED" && existing.Status != "CANCELLED" {
+			return nil, nil
+		}
+	}
+
+	taskID := schedule.MaintenanceTaskID
+	workOrder := models.WorkOrder{
+		VehicleID:         vehicle.ID,
+		MaintenanceTaskID: &taskID,
+		Description:       fmt.Sprintf("Scheduled service: %s (due by %s)", schedule.MaintenanceTask.Name, strings.ToLower(schedule.DueReason)),
+		Status:            "OPEN",
+		Priority:          "MEDIUM",
+		ScheduledDate:     &now,
+		Notes:             describeNextService(schedule),
+	}
+	if err := s.db.Omit("Vehicle", "MaintenanceTask").Create(&workOrder).Error; err != nil {
+		return nil, fmt.Errorf("failed to open work order for schedule %d: %w", schedule.ID, err)
+	}
+	schedule.WorkOrderID = &workOrder.ID
+	return &workOrder, nil
+}
+
+// describeNextService lists the limits of the current service cycle
+func describeNextService(schedule *models.ServiceSchedule) string {
+	var limits []string
+	if schedule.NextDueMileage > 0 {
+		limits = append(limits, fmt.Sprintf("%d km", schedule.NextDueMileage))
+	}
+	if schedule.NextDueEngineHours > 0 {
+		limits = append(limits, fmt.Sprintf("%.0f engine hours", schedule.NextDueEngineHours))
+	}
+	if schedule.NextDueDate != nil {
+		limits = append(limits, schedule.NextDueDate.Format("2 Jan 2006"))
+	}
+	return "Due at " + strings.Join(limits, " or ") + ", whichever comes first"
+}
+
+// notifyMaintenanceManagers texts the active dispatchers and org admins; when the vehicle
+// belongs to a fleet, only those of the fleet's organization
+func (s *MaintenanceService) notifyMaintenanceManagers(vehicle *models.Vehicle, message string) {
+	if s.notifications == nil {
+		return
+	}
+
+	query := s.db.Model(&models.UserAccount{}).Where("role IN ? AND is_active = ?", maintenanceManagerRoles, true)
+	if vehicle.FleetID != nil {
+		var fleet models.Fleet
+		if err := s.db.First(&fleet, *vehicle.FleetID).Error; err == nil {
+			query = query.Where("organization_id = ?", fleet.OrganizationID)
+		}
+	}
+
+	var phones []string
+	if err := query.Pluck("phone", &phones).Error; err != nil {
+		log.Printf("⚠️ Failed to load fleet manager contacts for vehicle %d: %v", vehicle.ID, err)
+		return
+	}
+	for _, phone := range phones {
+		if err := s.notifications.SendSMS(phone, message); err != nil {
+			log.Printf("⚠️ Failed to notify %s about maintenance on vehicle %d: %v", phone, vehicle.ID, err)
+		}
+	}
+}
+
+// completeServiceSchedule starts the next cycle of the schedule a work order was for
+func (s *MaintenanceService) completeServiceSchedule(tx *gorm.DB, workOrder *models.WorkOrder, now time.Time) error {
+	var schedules []models.ServiceSchedule
+	if err := tx.Where("work_order_id = ?", workOrder.ID).Limit(1).Find(&schedules).Error; err != nil {
+		return err
+	}
+	if len(schedules) == 0 && workOrder.MaintenanceTaskID != nil {
+		if err := tx.Where("vehicle_id = ? AND maintenance_task_id = ? AND status = ?", workOrder.VehicleID, *workOrder.MaintenanceTaskID, "ACTIVE").
+			Limit(1).Find(&schedules).Error; err != nil {
+			return err
+		}
+	}
+	if len(schedules) == 0 {
+		return nil
+	}
+
+	schedule := schedules[0]
+	var vehicle models.Vehicle
+	if err := tx.First(&vehicle, workOrder.VehicleID).Error; err != nil {
+		return err
+	}
+	schedule.LastPerformedAt = &now
+	scheduleNextService(&schedule, int(vehicle.TotalKilometers), s.currentEngineHours(tx, vehicle.ID), now)
+	return tx.Omit("Vehicle", "MaintenanceTask").Save(&schedule).Error
+}
+
+// StartServiceScheduler evaluates service schedules every interval
+func (s *MaintenanceService) StartServiceScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			result, err := s.RunServiceScheduler(context.Background(), time.Now())
+			if err != nil {
+				log.Printf("❌ Service scheduler failed: %v", err)
+				continue
+			}
+			if len(result.WorkOrders) > 0 {
+				log.Printf("🔧 Service scheduler opened %d work order(s)", len(result.WorkOrders))
+			}
+		}
+	}()
+}
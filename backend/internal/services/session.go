@@ -0,0 +1,343 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Session errors
+var (
+	ErrSessionNotFound   = errors.New("session not found")
+	ErrSessionExpired    = errors.New("session has expired due to inactivity")
+	ErrSessionRevoked    = errors.New("session has been revoked")
+	ErrRefreshTokenReuse = errors.New("refresh token has already been used; all tokens for this session were revoked")
+	ErrDeviceMismatch    = errors.New("refresh token was issued to a different device")
+)
+
+const (
+	// sessionTouchInterval throttles last-used writes from authenticated requests
+	sessionTouchInterval = time.Minute
+
+	// securitySettingsCacheTTL bounds how stale the idle timeout may be
+	securitySettingsCacheTTL = time.Minute
+
+	// sessionCacheTTL bounds how long a revocation made by another instance can go unnoticed
+	sessionCacheTTL  = 15 * time.Second
+	sessionCacheSize = 10000
+)
+
+// cachedSession is a session as last read from or written to the database
+type cachedSession struct {
+	session  models.UserSession
+	loadedAt time.Time
+}
+
+// SessionContext describes the device and network a token request came from
+type SessionContext struct {
+	DeviceID   string
+	DeviceName string
+	UserAgent  string
+	IPAddress  string
+	Location   string
+	AuthMethod string
+}
+
+// Fingerprint identifies the device a session is bound to. Apps send a stable
+// install ID; browsers without one are bound to their browser family and platform,
+// so routine browser updates do not look like a different device.
+func (sc *SessionContext) Fingerprint() string {
+	if sc == nil {
+		return ""
+	}
+	source := "device:" + sc.DeviceID
+	if sc.DeviceID == "" {
+		if sc.UserAgent == "" {
+			return ""
+		}
+		browser, platform := userAgentFamily(sc.UserAgent)
+		source = "ua:" + browser + "/" + platform
+	}
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:])
+}
+
+// userAgentFamily reduces a user agent to its browser family and platform, ignoring versions
+func userAgentFamily(userAgent string) (browser, platform string) {
+	switch {
+	case strings.Contains(userAgent, "Edg/") || strings.Contains(userAgent, "EdgA/") || strings.Contains(userAgent, "EdgiOS/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "OPR/") || strings.Contains(userAgent, "Opera"):
+		browser = "Opera"
+	case strings.Contains(userAgent, "Firefox/") || strings.Contains(userAgent, "FxiOS/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/") || strings.Contains(userAgent, "CriOS/") || strings.Contains(userAgent, "Chromium/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	default:
+		// Non-browser clients, e.g. "okhttp/4.12.0": the product name without its version
+		browser, _, _ = strings.Cut(strings.Fields(userAgent + " ")[0], "/")
+	}
+
+	switch {
+	case strings.Contains(userAgent, "Windows"):
+		platform = "Windows"
+	case strings.Contains(userAgent, "Android"):
+		platform = "Android"
+	case strings.Contains(userAgent, "iPhone") || strings.Contains(userAgent, "iPad") || strings.Contains(userAgent, "iOS"):
+		platform = "iOS"
+	case strings.Contains(userAgent, "CrOS"):
+		platform = "ChromeOS"
+	case strings.Contains(userAgent, "Macintosh") || strings.Contains(userAgent, "Mac OS X"):
+		platform = "macOS"
+	case strings.Contains(userAgent, "Linux"):
+		platform = "Linux"
+	}
+	return browser, platform
+}
+
+// SetSecuritySettingsSource sets where the session idle timeout is read from
+func (j *JWTService) SetSecuritySettingsSource(source func() *models.SecuritySettings) {
+	j.settingsMu.Lock()
+	defer j.settingsMu.Unlock()
+	j.settingsSource = source
+	j.settingsCache = nil
+}
+
+// CreateSession starts a new session (token family) and issues its first refresh token
+func (j *JWTService) CreateSession(userID uint, sc *SessionContext) (*models.UserSession, *models.RefreshToken, error) {
+	if sc == nil {
+		sc = &SessionContext{}
+	}
+
+	now := time.Now()
+	session := &models.UserSession{
+		UserID:            userID,
+		DeviceID:          sc.DeviceID,
+		DeviceName:        sc.DeviceName,
+		DeviceFingerprint: sc.Fingerprint(),
+		UserAgent:         sc.UserAgent,
+		IPAddress:         sc.IPAddress,
+		LastIPAddress:     sc.IPAddress,
+		Location:          sc.Location,
+		AuthMethod:        sc.AuthMethod,
+		LastUsedAt:        now,
+		ExpiresAt:         now.Add(j.config.RefreshTokenExpiry),
+	}
+
+	var refreshToken *models.RefreshToken
+	err := j.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		var err error
+		refreshToken, err = j.issueRefreshToken(tx, userID, &session.ID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return session, refreshToken, nil
+}
+
+// ValidateSession checks that an access token's session is still usable and records activity.
+// Sessions are cached for sessionCacheTTL so most requests do not read the database;
+// revocations made by this instance take effect immediately.
+func (j *JWTService) ValidateSession(sessionID uint, ipAddress string) error {
+	session, cached, err := j.loadSession(sessionID, false)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+	if !session.IsActive() {
+		return ErrSessionRevoked
+	}
+	timeout := j.sessionIdleTimeout()
+	if cached && session.IsIdle(timeout) {
+		// Another instance may have seen the session more recently
+		if session, _, err = j.loadSession(sessionID, true); err != nil {
+			return ErrSessionNotFound
+		}
+	}
+	if session.IsIdle(timeout) {
+		_ = j.revokeSession(session, models.SessionRevokedIdleTimeout)
+		return ErrSessionExpired
+	}
+
+	if time.Since(session.LastUsedAt) > sessionTouchInterval || (ipAddress != "" && ipAddress != session.LastIPAddress) {
+		updates := map[string]interface{}{"last_used_at": time.Now()}
+		if ipAddress != "" {
+			updates["last_ip_address"] = ipAddress
+		}
+		if err := j.db.Model(&models.UserSession{}).Where("id = ?", session.ID).Updates(updates).Error; err == nil {
+			session.LastUsedAt = updates["last_used_at"].(time.Time)
+			if ipAddress != "" {
+				session.LastIPAddress = ipAddress
+			}
+			j.cacheSession(session)
+		}
+	}
+	return nil
+}
+
+// loadSession returns a copy of the session, from the cache unless it is stale or fresh is set,
+// and whether it came from the cache
+func (j *JWTService) loadSession(sessionID uint, fresh bool) (*models.UserSession, bool, error) {
+	if !fresh {
+		j.sessionsMu.Lock()
+		entry, ok := j.sessions[sessionID]
+		j.sessionsMu.Unlock()
+		if ok && time.Since(entry.loadedAt) < sessionCacheTTL {
+			session := entry.session
+			return &session, true, nil
+		}
+	}
+
+	var session models.UserSession
+	if err := j.db.First(&session, sessionID).Error; err != nil {
+		j.forgetSessions(func(cached *models.UserSession) bool { return cached.ID == sessionID })
+		return nil, false, err
+	}
+	j.cacheSession(&session)
+	return &session, false, nil
+}
+
+// cacheSession stores a copy of the session for ValidateSession
+func (j *JWTService) cacheSession(session *models.UserSession) {
+	j.sessionsMu.Lock()
+	defer j.sessionsMu.Unlock()
+	if j.sessions == nil || len(j.sessions) >= sessionCacheSize {
+		j.sessions = make(map[uint]*cachedSession)
+	}
+	j.sessions[session.ID] = &cachedSession{session: *session, loadedAt: time.Now()}
+}
+
+// forgetSessions drops the cached sessions matching the predicate
+func (j *JWTService) forgetSessions(match func(*models.UserSession) bool) {
+	j.sessionsMu.Lock()
+	defer j.sessionsMu.Unlock()
+	for id, entry := range j.sessions {
+		if match(&entry.session) {
+			delete(j.sessions, id)
+		}
+	}
+}
+
+// ListSessions returns a user's active sessions, most recently used first
+func (j *JWTService) ListSessions(userID uint) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+	err := j.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Sessions past the idle timeout are dead even if not yet swept
+	timeout := j.sessionIdleTimeout()
+	active := sessions[:0]
+	for _, session := range sessions {
+		if !session.IsIdle(timeout) {
+			active = append(active, session)
+		}
+	}
+	return active, nil
+}
+
+// RevokeSession revokes one of a user's sessions and all its refresh tokens
+func (j *JWTService) RevokeSession(userID, sessionID uint, reason string) error {
+	var session models.UserSession
+	if err := j.db.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+		return ErrSessionNotFound
+	}
+	if session.RevokedAt != nil {
+		return nil
+	}
+	return j.revokeSession(&session, reason)
+}
+
+// RevokeOtherSessions revokes every session of a user except keepSessionID
+func (j *JWTService) RevokeOtherSessions(userID, keepSessionID uint) (int64, error) {
+	var sessions []models.UserSession
+	if err := j.db.Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepSessionID).
+		Find(&sessions).Error; err != nil {
+		return 0, err
+	}
+	for i := range sessions {
+		if err := j.revokeSession(&sessions[i], models.SessionRevokedByUser); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(sessions)), nil
+}
+
+// revokeSession marks the session revoked and revokes every refresh token in its family
+func (j *JWTService) revokeSession(session *models.UserSession, reason string) error {
+	now := time.Now()
+	defer j.forgetSessions(func(cached *models.UserSession) bool { return cached.ID == session.ID })
+	return j.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserSession{}).
+			Where("id = ? AND revoked_at IS NULL", session.ID).
+			Updates(map[string]interface{}{"revoked_at": now, "revoked_reason": reason}).Error; err != nil {
+			return err
+		}
+		session.RevokedAt = &now
+		session.RevokedReason = reason
+
+		return tx.Model(&models.RefreshToken{}).
+			Where("session_id = ?", session.ID).
+			Update("revoked", true).Error
+	})
+}
+
+// handleRefreshTokenReuse revokes the whole family when a rotated token is presented again,
+// since either the client or an attacker holds a stolen copy
+func (j *JWTService) handleRefreshTokenReuse(token *models.RefreshToken, sc *SessionContext) error {
+	if token.SessionID == nil {
+		return ErrRefreshTokenReuse
+	}
+
+	var session models.UserSession
+	if err := j.db.First(&session, *token.SessionID).Error; err == nil && session.RevokedAt == nil {
+		_ = j.revokeSession(&session, models.SessionRevokedTokenReuse)
+		j.logSessionSecurityEvent("REFRESH_TOKEN_REUSE", models.AuditSeverityCritical, token.UserID,
+			fmt.Sprintf("Refresh token reuse detected for session %d of user ID %d; session revoked", session.ID, token.UserID), sc)
+	}
+	return ErrRefreshTokenReuse
+}
+
+// sessionIdleTimeout returns SecuritySettings.SessionTimeoutMinutes, cached briefly.
+// Zero, the default, means sessions are only bounded by their refresh token expiry.
+func (j *JWTService) sessionIdleTimeout() time.Duration {
+	j.settingsMu.Lock()
+	defer j.settingsMu.Unlock()
+
+	if j.settingsCache == nil || time.Since(j.settingsCachedAt) > securitySettingsCacheTTL {
+		settings := models.DefaultSecuritySettings()
+		if j.settingsSource != nil {
+			settings = j.settingsSource().WithDefaults()
+		}
+		j.settingsCache = settings
+		j.settingsCachedAt = time.Now()
+	}
+	return time.Duration(j.settingsCache.SessionTimeoutMinutes) * time.Minute
+}
+
+func (j *JWTService) logSessionSecurityEvent(eventType string, severity models.AuditSeverity, userID uint, description string, sc *SessionContext) {
+	if j.auditService == nil {
+		return
+	}
+	auditCtx := &models.AuditContext{UserID: &userID}
+	if sc != nil {
+		auditCtx.IPAddress = sc.IPAddress
+		auditCtx.UserAgent = sc.UserAgent
+	}
+	_ = j.auditService.LogSecurityEvent(eventType, "BLOCKED", severity, description, auditCtx)
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionFingerprint(t *testing.T) {
+	browser := func(userAgent string) string {
+		return (&SessionContext{UserAgent: userAgent}).Fingerprint()
+	}
+
+	chrome := browser("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/128.0.0.0 Safari/537.36")
+	// A browser update keeps the session on the same device
+	assert.Equal(t, chrome, browser("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.6668.59 Safari/537.36"))
+	// Another browser or platform does not
+	assert.NotEqual(t, chrome, browser("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36 Edg/129.0.0.0"))
+	assert.NotEqual(t, chrome, browser("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36"))
+	assert.NotEqual(t, chrome, browser("Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:131.0) Gecko/20100101 Firefox/131.0"))
+
+	assert.Equal(t, browser("okhttp/4.11.0"), browser("okhttp/4.12.0"))
+	assert.Empty(t, browser(""))
+
+	// An install ID takes precedence over the user agent
+	app := &SessionContext{DeviceID: "install-1", UserAgent: "okhttp/4.12.0"}
+	assert.Equal(t, app.Fingerprint(), (&SessionContext{DeviceID: "install-1", UserAgent: "okhttp/5.0.0"}).Fingerprint())
+	assert.NotEqual(t, app.Fingerprint(), browser("okhttp/4.12.0"))
+}
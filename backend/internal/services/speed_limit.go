@@ -0,0 +1,441 @@
+package services
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidSpeedLimit is returned for speed limits, overrides and road data that cannot be used
+var ErrInvalidSpeedLimit = errors.New("invalid speed limit")
+
+const (
+	defaultSpeedLimit  = 80.0 // km/h where no road data, override or geofence applies
+	roadMatchDistance  = 30.0 // m from a road's centreline for a position to be on it
+	metersPerDegree    = 111320.0
+	osmImportBatchSize = 500
+)
+
+// Where a speed limit came from
+const (
+	SpeedLimitSourceGeofence    = "GEOFENCE"
+	SpeedLimitSourceRoad        = "ROAD"       // Posted on the road
+	SpeedLimitSourceRoadClass   = "ROAD_CLASS" // The default for an untagged road's class
+	SpeedLimitSourceVehicleType = "VEHICLE_TYPE"
+	SpeedLimitSourceDefault     = "DEFAULT"
+)
+
+// roadClassSpeedLimits are the limits on roads without a maxspeed tag, by OSM highway class.
+// Only ways of these classes are imported.
+var roadClassSpeedLimits = map[string]float64{
+	"motorway":       100,
+	"motorway_link":  60,
+	"trunk":          80,
+	"trunk_link":     50,
+	"primary":        70,
+	"primary_link":   50,
+	"secondary":      60,
+	"secondary_link": 40,
+	"tertiary":       50,
+	"tertiary_link":  40,
+	"unclassified":   50,
+	"residential":    30,
+	"living_street":  20,
+	"service":        20,
+	"road":           50,
+}
+
+// heavyVehicleTypes are held to a road's heavy goods vehicle limit where it has one
+var heavyVehicleTypes = map[models.VehicleType]bool{
+	models.VehicleTypeTruck:   true,
+	models.VehicleTypeTrailer: true,
+}
+
+// SpeedLimit is the limit that applies to a vehicle at a position
+type SpeedLimit struct {
+	Limit         float64 `json:"limit"` // km/h
+	Source        string  `json:"source"`
+	RoadSegmentID *uint   `json:"road_segment_id,omitempty"`
+	RoadName      string  `json:"road_name,omitempty"`
+	Highway       string  `json:"highway,omitempty"`
+	GeofenceID    *uint   `json:"geofence_id,omitempty"`
+}
+
+// OSMImportResult summarises an OpenStreetMap import
+type OSMImportResult struct {
+	Ways         int `json:"ways"`           // Ways of a drivable road class
+	Imported     int `json:"imported"`       // Ways stored, replacing any earlier import of the same way
+	WithMaxSpeed int `json:"with_max_speed"` // Imported ways with a posted limit
+	Skipped      int `json:"skipped"`        // Ways whose nodes were missing from the extract
+}
+
+// SpeedLimitService resolves speed limits from imported road data, vehicle type overrides and geofence caps
+type SpeedLimitService struct {
+	db *gorm.DB
+}
+
+// NewSpeedLimitService creates a new speed limit service
+func NewSpeedLimitService(db *gorm.DB) *SpeedLimitService {
+	return &SpeedLimitService{db: db}
+}
+
+// LimitForVehicle resolves the speed limit at a position for a vehicle
+func (s *SpeedLimitService) LimitForVehicle(ctx context.Context, vehicleID uint, latitude, longitude float64) (*SpeedLimit, error) {
+	var vehicles []models.Vehicle
+	if err := s.db.WithContext(ctx).Select("id", "vehicle_type").Where("id = ?", vehicleID).Limit(1).Find(&vehicles).Error; err != nil {
+		return nil, err
+	}
+	var vehicleType models.VehicleType
+	if len(vehicles) > 0 {
+		vehicleType = vehicles[0].VehicleType
+	}
+	return s.LimitAt(ctx, latitude, longitude, vehicleType)
+}
+
+// LimitAt resolves the speed limit at a position for a vehicle type. A geofence cap takes
+// precedence; otherwise the nearest road's posted limit, or its class default, applies, lowered
+// by the heavy vehicle limit and any override for the vehicle type.
+func (s *SpeedLimitService) LimitAt(ctx context.Context, latitude, longitude float64, vehicleType models.VehicleType) (*SpeedLimit, error) {
+	var geofences []models.Geofence
+	if err := s.db.WithContext(ctx).Where("is_active = ? AND speed_limit IS NOT NULL", true).Find(&geofences).Error; err != nil {
+		return nil, err
+	}
+	var capped *SpeedLimit
+	for i := range geofences {
+		geofence := &geofences[i]
+		if geofence.IsInGeofence(latitude, longitude) && (capped == nil || *geofence.SpeedLimit < capped.Limit) {
+			capped = &SpeedLimit{Limit: *geofence.SpeedLimit, Source: SpeedLimitSourceGeofence, GeofenceID: &geofence.ID}
+		}
+	}
+	if capped != nil {
+		return capped, nil
+	}
+
+	limit := &SpeedLimit{Limit: defaultSpeedLimit, Source: SpeedLimitSourceDefault}
+	road, err := s.MatchRoad(ctx, latitude, longitude)
+	if err != nil {
+		return nil, err
+	}
+	if road != nil {
+		limit.RoadSegmentID = &road.ID
+		limit.RoadName = road.Name
+		limit.Highway = road.Highway
+		if road.MaxSpeed != nil {
+			limit.Limit, limit.Source = *road.MaxSpeed, SpeedLimitSourceRoad
+		} else if classLimit, ok := roadClassSpeedLimits[road.Highway]; ok {
+			limit.Limit, limit.Source = classLimit, SpeedLimitSourceRoadClass
+		}
+		if heavyVehicleTypes[vehicleType] && road.MaxSpeedHGV != nil && *road.MaxSpeedHGV < limit.Limit {
+			limit.Limit, limit.Source = *road.MaxSpeedHGV, SpeedLimitSourceRoad
+		}
+	}
+
+	if vehicleType != "" {
+		var overrides []models.SpeedLimitOverride
+		if err := s.db.WithContext(ctx).Where("vehicle_type = ? AND (highway = '' OR highway = ?)", vehicleType, limit.Highway).
+			Find(&overrides).Error; err != nil {
+			return nil, err
+		}
+		for _, override := range overrides {
+			if override.MaxSpeed < limit.Limit {
+				limit.Limit, limit.Source = override.MaxSpeed, SpeedLimitSourceVehicleType
+			}
+		}
+	}
+	return limit, nil
+}
+
+// MatchRoad returns the imported road nearest a position, or nil when none is within roadMatchDistance
+func (s *SpeedLimitService) MatchRoad(ctx context.Context, latitude, longitude float64) (*models.RoadSegment, error) {
+	latMargin := roadMatchDistance / metersPerDegree
+	lonMargin := latMargin / math.Max(math.Cos(latitude*math.Pi/180), 0.01)
+
+	var candidates []models.RoadSegment
+	if err := s.db.WithContext(ctx).
+		Where("min_latitude <= ? AND max_latitude >= ? AND min_longitude <= ? AND max_longitude >= ?",
+			latitude+latMargin, latitude-latMargin, longitude+lonMargin, longitude-lonMargin).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	var nearest *models.RoadSegment
+	nearestDistance := roadMatchDistance
+	for i := range candidates {
+		if distance := distanceToPolyline(latitude, longitude, candidates[i].Geometry); distance <= nearestDistance {
+			nearest, nearestDistance = &candidates[i], distance
+		}
+	}
+	return nearest, nil
+}
+
+// distanceToPolyline is the distance in meters from a point to the nearest part of a line of
+// latitude, longitude pairs, on a local flat projection
+func distanceToPolyline(latitude, longitude float64, points []float64) float64 {
+	scale := math.Cos(latitude * math.Pi / 180)
+	project := func(lat, lon float64) (float64, float64) {
+		return (lon - longitude) * scale * metersPerDegree, (lat - latitude) * metersPerDegree
+	}
+
+	nearest := math.Inf(1)
+	for i := 0; i+3 < len(points); i += 2 {
+		ax, ay := project(points[i], points[i+1])
+		bx, by := project(points[i+2], points[i+3])
+		dx, dy := bx-ax, by-ay
+		t := 0.0
+		if length := dx*dx + dy*dy; length > 0 {
+			t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/length))
+		}
+		nearest = math.Min(nearest, math.Hypot(ax+t*dx, ay+t*dy))
+	}
+	return nearest
+}
+
+type osmTag struct {
+	Key   string `xml:"k,attr"`
+	Value string `xml:"v,attr"`
+}
+
+type osmNode struct {
+	ID        int64   `xml:"id,attr"`
+	Latitude  float64 `xml:"lat,attr"`
+	Longitude float64 `xml:"lon,attr"`
+}
+
+type osmNodeRef struct {
+	Ref       int64    `xml:"ref,attr"`
+	Latitude  *float64 `xml:"lat,attr"` // Inline with Overpass "out geom"
+	Longitude *float64 `xml:"lon,attr"`
+}
+
+type osmWay struct {
+	ID    int64        `xml:"id,attr"`
+	Nodes []osmNodeRef `xml:"nd"`
+	Tags  []osmTag     `xml:"tag"`
+}
+
+func (w *osmWay) tag(key string) string {
+	for _, tag := range w.Tags {
+		if tag.Key == key {
+			return tag.Value
+		}
+	}
+	return ""
+}
+
+// ImportOSM imports the drivable ways of an OpenStreetMap XML extract, such as a Geofabrik
+// extract filtered to highways or an Overpass query result, with their maxspeed tags. Ways
+// already imported are replaced.
+func (s *SpeedLimitService) ImportOSM(ctx context.Context, r io.Reader) (*OSMImportResult, error) {
+	nodes := make(map[int64][2]float64)
+	var ways []osmWay
+
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: reading OSM XML: %v", ErrInvalidSpeedLimit, err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "node":
+			var node osmNode
+			if err := decoder.DecodeElement(&node, &start); err != nil {
+				return nil, fmt.Errorf("%w: reading OSM node: %v", ErrInvalidSpeedLimit, err)
+			}
+			nodes[node.ID] = [2]float64{node.Latitude, node.Longitude}
+		case "way":
+			var way osmWay
+			if err := decoder.DecodeElement(&way, &start); err != nil {
+				return nil, fmt.Errorf("%w: reading OSM way: %v", ErrInvalidSpeedLimit, err)
+			}
+			if _, drivable := roadClassSpeedLimits[way.tag("highway")]; drivable {
+				ways = append(ways, way)
+			}
+		}
+	}
+
+	result := &OSMImportResult{Ways: len(ways)}
+	segments := make([]models.RoadSegment, 0, len(ways))
+	for i := range ways {
+		segment, ok := roadSegmentFromWay(&ways[i], nodes)
+		if !ok {
+			result.Skipped++
+			continue
+		}
+		if segment.MaxSpeed != nil {
+			result.WithMaxSpeed++
+		}
+		segments = append(segments, segment)
+	}
+	if len(segments) > 0 {
+		err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "osm_way_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "highway", "max_speed", "max_speed_hgv", "geometry", "min_latitude", "max_latitude", "min_longitude", "max_longitude", "updated_at"}),
+		}).CreateInBatches(&segments, osmImportBatchSize).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	result.Imported = len(segments)
+
+	log.Printf("🛣️ Imported %d road segments (%d with posted limits, %d skipped)", result.Imported, result.WithMaxSpeed, result.Skipped)
+	return result, nil
+}
+
+// roadSegmentFromWay resolves a way's geometry and limits; false if any of its nodes is missing
+func roadSegmentFromWay(way *osmWay, nodes map[int64][2]float64) (models.RoadSegment, bool) {
+	segment := models.RoadSegment{
+		OSMWayID:     way.ID,
+		Name:         way.tag("name"),
+		Highway:      way.tag("highway"),
+		MinLatitude:  math.Inf(1),
+		MaxLatitude:  math.Inf(-1),
+		MinLongitude: math.Inf(1),
+		MaxLongitude: math.Inf(-1),
+	}
+	if segment.Name == "" {
+		segment.Name = way.tag("ref")
+	}
+	for _, ref := range way.Nodes {
+		var lat, lon float64
+		if ref.Latitude != nil && ref.Longitude != nil {
+			lat, lon = *ref.Latitude, *ref.Longitude
+		} else if node, ok := nodes[ref.Ref]; ok {
+			lat, lon = node[0], node[1]
+		} else {
+			return segment, false
+		}
+		segment.Geometry = append(segment.Geometry, lat, lon)
+		segment.MinLatitude = math.Min(segment.MinLatitude, lat)
+		segment.MaxLatitude = math.Max(segment.MaxLatitude, lat)
+		segment.MinLongitude = math.Min(segment.MinLongitude, lon)
+		segment.MaxLongitude = math.Max(segment.MaxLongitude, lon)
+	}
+	if len(segment.Geometry) < 4 {
+		return segment, false
+	}
+
+	if limit, ok := ParseMaxSpeed(way.tag("maxspeed")); ok {
+		segment.MaxSpeed = &limit
+	} else {
+		// Directional limits; the lower applies either way
+		for _, key := range []string{"maxspeed:forward", "maxspeed:backward"} {
+			if limit, ok := ParseMaxSpeed(way.tag(key)); ok && (segment.MaxSpeed == nil || limit < *segment.MaxSpeed) {
+				segment.MaxSpeed = &limit
+			}
+		}
+	}
+	if limit, ok := ParseMaxSpeed(way.tag("maxspeed:hgv")); ok {
+		segment.MaxSpeedHGV = &limit
+	}
+	return segment, true
+}
+
+// ParseMaxSpeed reads an OSM maxspeed value in km/h: "50", "30 mph", "10 knots", or the lowest of
+// "50;30". Values with no number, such as "none", "signals" or an implicit "IN:urban", are not limits.
+func ParseMaxSpeed(value string) (float64, bool) {
+	lowest, found := 0.0, false
+	for _, part := range strings.Split(value, ";") {
+		fields := strings.Fields(strings.TrimSpace(part))
+		if len(fields) == 0 {
+			continue
+		}
+		unit := ""
+		number := fields[0]
+		if len(fields) > 1 {
+			unit = fields[1]
+		} else if strings.HasSuffix(number, "mph") {
+			number, unit = strings.TrimSuffix(number, "mph"), "mph"
+		}
+		speed, err := strconv.ParseFloat(number, 64)
+		if err != nil || speed <= 0 {
+			continue
+		}
+		switch unit {
+		case "", "km/h", "kmh", "kph":
+		case "mph":
+			speed *= 1.609344
+		case "knots":
+			speed *= 1.852
+		default:
+			continue
+		}
+		speed = math.Round(speed*10) / 10
+		if !found || speed < lowest {
+			lowest, found = speed, true
+		}
+	}
+	return lowest, found
+}
+
+// GetOverrides lists vehicle type speed limit overrides
+func (s *SpeedLimitService) GetOverrides(ctx context.Context) ([]models.SpeedLimitOverride, error) {
+	var overrides []models.SpeedLimitOverride
+	err := s.db.WithContext(ctx).Order("vehicle_type, highway").Find(&overrides).Error
+	return overrides, err
+}
+
+// SaveOverride creates or replaces the override for a vehicle type and road class
+func (s *SpeedLimitService) SaveOverride(ctx context.Context, override *models.SpeedLimitOverride) error {
+	if override.VehicleType == "" || override.MaxSpeed <= 0 {
+		return fmt.Errorf("%w: a vehicle type and a positive limit are required", ErrInvalidSpeedLimit)
+	}
+	if _, ok := roadClassSpeedLimits[override.Highway]; override.Highway != "" && !ok {
+		return fmt.Errorf("%w: unknown road class %q", ErrInvalidSpeedLimit, override.Highway)
+	}
+
+	var existing []models.SpeedLimitOverride
+	if err := s.db.WithContext(ctx).Where("vehicle_type = ? AND highway = ?", override.VehicleType, override.Highway).
+		Limit(1).Find(&existing).Error; err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		override.ID = existing[0].ID
+		override.CreatedAt = existing[0].CreatedAt
+	}
+	return s.db.WithContext(ctx).Save(override).Error
+}
+
+// DeleteOverride removes a vehicle type speed limit override
+func (s *SpeedLimitService) DeleteOverride(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Delete(&models.SpeedLimitOverride{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SetGeofenceSpeedLimit sets or, with nil, removes a geofence's speed cap
+func (s *SpeedLimitService) SetGeofenceSpeedLimit(ctx context.Context, geofenceID uint, limit *float64) (*models.Geofence, error) {
+	if limit != nil && *limit <= 0 {
+		return nil, fmt.Errorf("%w: a geofence cap must be positive", ErrInvalidSpeedLimit)
+	}
+	var geofence models.Geofence
+	if err := s.db.WithContext(ctx).First(&geofence, geofenceID).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Model(&geofence).Update("speed_limit", limit).Error; err != nil {
+		return nil, err
+	}
+	geofence.SpeedLimit = limit
+	return &geofence, nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMaxSpeed(t *testing.T) {
+	cases := []struct {
+		value string
+		limit float64
+		ok    bool
+	}{
+		{"50", 50, true},
+		{"30 mph", 48.3, true},
+		{"30mph", 48.3, true},
+		{"10 knots", 18.5, true},
+		{"60;40", 40, true},
+		{"80 km/h", 80, true},
+		{"none", 0, false},
+		{"signals", 0, false},
+		{"IN:urban", 0, false},
+		{"", 0, false},
+		{"-5", 0, false},
+	}
+	for _, tc := range cases {
+		limit, ok := ParseMaxSpeed(tc.value)
+		assert.Equal(t, tc.ok, ok, tc.value)
+		assert.Equal(t, tc.limit, limit, tc.value)
+	}
+}
+
+func TestDistanceToPolyline(t *testing.T) {
+	// A road running east along latitude 18.6
+	road := []float64{18.6, 73.70, 18.6, 73.75, 18.6, 73.80}
+	assert.InDelta(t, 0, distanceToPolyline(18.6, 73.72, road), 0.01)
+	assert.InDelta(t, 111.3, distanceToPolyline(18.601, 73.76, road), 0.5)
+	// Beyond the end the nearest point is the end itself
+	assert.InDelta(t, 1055, distanceToPolyline(18.6, 73.81, road), 5)
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+)
+
+const (
+	speedingTolerance   = 5.0 // km/h over the limit before an episode starts; it lasts until back under the limit
+	minSpeedingDuration = 10 * time.Second
+	speedingSweepPeriod = time.Minute
+)
+
+// speedingState is a vehicle's open speeding episode
+type speedingState struct {
+	episode models.SpeedingEpisode
+	overSum float64
+}
+
+// TrackSpeeding compares a position with the limit where it was taken, opening, extending or
+// closing the vehicle's speeding episode. It returns the episode the position closed, if that
+// episode lasted long enough to record.
+func (s *SafetyService) TrackSpeeding(ctx context.Context, ping *models.LocationPing) (*models.SpeedingEpisode, error) {
+	if ping.VehicleID == nil || ping.Speed == nil {
+		return nil, nil
+	}
+	vehicleID := *ping.VehicleID
+
+	limit := &SpeedLimit{Limit: defaultSpeedLimit, Source: SpeedLimitSourceDefault}
+	if s.speedLimits != nil {
+		var err error
+		if limit, err = s.speedLimits.LimitForVehicle(ctx, vehicleID, ping.Latitude, ping.Longitude); err != nil {
+			return nil, err
+		}
+	}
+
+	s.speedingMu.Lock()
+	defer s.speedingMu.Unlock()
+
+	var closed *models.SpeedingEpisode
+	state := s.speeding[vehicleID]
+	if state != nil {
+		if !ping.Timestamp.After(state.episode.EndedAt) {
+			return nil, nil // Out of order or repeated
+		}
+		if ping.Timestamp.Sub(state.episode.EndedAt) > maxPingGap {
+			// Nothing is known about the gap, so the episode ends where the pings stopped
+			delete(s.speeding, vehicleID)
+			episode, err := s.closeSpeeding(ctx, state)
+			if err != nil {
+				return nil, err
+			}
+			closed, state = episode, nil
+		}
+	}
+
+	over := *ping.Speed - limit.Limit
+	switch {
+	case state == nil && over > speedingTolerance:
+		state = &speedingState{episode: models.SpeedingEpisode{
+			VehicleID:      vehicleID,
+			DriverID:       ping.DriverID,
+			StartedAt:      ping.Timestamp,
+			StartLatitude:  ping.Latitude,
+			StartLongitude: ping.Longitude,
+		}}
+		s.speeding[vehicleID] = state
+		state.extend(ping, limit, over)
+	case state != nil && over > 0:
+		state.extend(ping, limit, over)
+	case state != nil:
+		delete(s.speeding, vehicleID)
+		return s.closeSpeeding(ctx, state)
+	}
+	return closed, nil
+}
+
+// extend adds a position over the limit to the episode
+func (st *speedingState) extend(ping *models.LocationPing, limit *SpeedLimit, over float64) {
+	episode := &st.episode
+	episode.EndedAt = ping.Timestamp
+	episode.EndLatitude, episode.EndLongitude = ping.Latitude, ping.Longitude
+	episode.Pings++
+	st.overSum += over
+	if episode.DriverID == nil {
+		episode.DriverID = ping.DriverID
+	}
+	if over > episode.MaxOver {
+		episode.MaxOver = roundTo(over, 1)
+		episode.MaxSpeed = *ping.Speed
+		episode.SpeedLimit = limit.Limit
+		episode.LimitSource = limit.Source
+		episode.RoadSegmentID = limit.RoadSegmentID
+		episode.GeofenceID = limit.GeofenceID
+	}
+}
+
+// closeSpeeding records an episode that lasted long enough, with a speeding event for it
+func (s *SafetyService) closeSpeeding(ctx context.Context, state *speedingState) (*models.SpeedingEpisode, error) {
+	episode := state.episode
+	duration := episode.EndedAt.Sub(episode.StartedAt)
+	if duration < minSpeedingDuration {
+		return nil, nil
+	}
+	episode.DurationSeconds = duration.Seconds()
+	episode.AverageOver = roundTo(state.overSum/float64(episode.Pings), 1)
+	episode.Severity = speedingSeverity(episode.MaxOver)
+
+	event := &models.SafetyEvent{
+		VehicleID: episode.VehicleID,
+		DriverID:  episode.DriverID,
+		Type:      models.SafetyEventSpeeding,
+		Severity:  episode.Severity,
+		Value:     episode.MaxSpeed,
+		Threshold: episode.SpeedLimit,
+		Unit:      "km/h",
+		Latitude:  episode.StartLatitude,
+		Longitude: episode.StartLongitude,
+		Timestamp: episode.StartedAt,
+	}
+	if err := s.RecordSafetyEvent(event); err != nil {
+		return nil, err
+	}
+	episode.SafetyEventID = &event.ID
+	episode.TripID = event.TripID
+	episode.DriverID = event.DriverID
+
+	if err := s.db.WithContext(ctx).Omit("Vehicle", "Driver").Create(&episode).Error; err != nil {
+		return nil, err
+	}
+	log.Printf("⚠️ Speeding episode for vehicle %d: %.0fs, up to %.0f km/h over %.0f km/h (%s)",
+		episode.VehicleID, episode.DurationSeconds, episode.MaxOver, episode.SpeedLimit, episode.LimitSource)
+	return &episode, nil
+}
+
+// speedingSeverity grades an episode by how far over the limit it went
+func speedingSeverity(maxOver float64) models.SafetyEventSeverity {
+	switch {
+	case maxOver >= 40:
+		return models.SeverityCritical
+	case maxOver >= 20:
+		return models.SeverityHigh
+	case maxOver >= 10:
+		return models.SeverityMedium
+	default:
+		return models.SeverityLow
+	}
+}
+
+// CloseStaleSpeedingEpisodes closes the episodes of vehicles that have stopped reporting
+func (s *SafetyService) CloseStaleSpeedingEpisodes(ctx context.Context, now time.Time) ([]models.SpeedingEpisode, error) {
+	s.speedingMu.Lock()
+	defer s.speedingMu.Unlock()
+
+	closed := []models.SpeedingEpisode{}
+	for vehicleID, state := range s.speeding {
+		if now.Sub(state.episode.EndedAt) <= maxPingGap {
+			continue
+		}
+		delete(s.speeding, vehicleID)
+		episode, err := s.closeSpeeding(ctx, state)
+		if err != nil {
+			return closed, err
+		}
+		if episode != nil {
+			closed = append(closed, *episode)
+		}
+	}
+	return closed, nil
+}
+
+// periodicSpeedingSweep closes episodes left open by vehicles that went quiet
+func (s *SafetyService) periodicSpeedingSweep() {
+	ticker := time.NewTicker(speedingSweepPeriod)
+	for range ticker.C {
+		if _, err := s.CloseStaleSpeedingEpisodes(context.Background(), time.Now()); err != nil {
+			log.Printf("❌ Failed to close speeding episodes: %v", err)
+		}
+	}
+}
+
+// SpeedingEpisodeFilter narrows a speeding episode listing; zero values are ignored
+type SpeedingEpisodeFilter struct {
+	VehicleID *uint
+	DriverID  *uint
+	TripID    *uint
+	MinOver   float64
+	From      *time.Time
+	To        *time.Time
+}
+
+// GetSpeedingEpisodes lists recorded speeding episodes, newest first
+func (s *SafetyService) GetSpeedingEpisodes(ctx context.Context, filter SpeedingEpisodeFilter, page, limit int) ([]models.SpeedingEpisode, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.SpeedingEpisode{})
+	if filter.VehicleID != nil {
+		query = query.Where("vehicle_id = ?", *filter.VehicleID)
+	}
+	if filter.DriverID != nil {
+		query = query.Where("driver_id = ?", *filter.DriverID)
+	}
+	if filter.TripID != nil {
+		query = query.Where("trip_id = ?", *filter.TripID)
+	}
+	if filter.MinOver > 0 {
+		query = query.Where("max_over >= ?", filter.MinOver)
+	}
+	if filter.From != nil {
+		query = query.Where("started_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("started_at <= ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var episodes []models.SpeedingEpisode
+	err := query.Preload("Driver").Preload("Vehicle").
+		Order("started_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&episodes).Error
+	return episodes, total, err
+}
@@ -0,0 +1,627 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidThreshold is returned for telemetry threshold rules that cannot be applied
+var ErrInvalidThreshold = errors.New("invalid telemetry threshold")
+
+const (
+	// anomalyResolveAfter is how many consecutive normal readings resolve an anomaly
+	anomalyResolveAfter = 3
+	// anomalyCooldown is how long after resolving a breach of the same rule reopens the anomaly silently
+	anomalyCooldown = 10 * time.Minute
+	// baselineAlpha weights each new reading in a vehicle's running baseline
+	baselineAlpha = 0.05
+	// baselineMinSamples is how many readings a baseline needs before deviations are flagged
+	baselineMinSamples = 30
+	// maxRateInterval is the longest gap between readings that rate-of-change rules are applied across
+	maxRateInterval = 15 * time.Minute
+	// staleTelemetryAfter is how old the latest reading may be before a health score is marked stale
+	staleTelemetryAfter = 24 * time.Hour
+)
+
+// telemetrySignals reads each signal from a telemetry log, in evaluation order
+var telemetrySignals = []struct {
+	signal models.TelemetrySignal
+	unit   string
+	value  func(*models.TelemetryLog) *float64
+}{
+	{models.SignalEngineRPM, "rpm", func(l *models.TelemetryLog) *float64 {
+		if l.EngineRPM == nil {
+			return nil
+		}
+		rpm := float64(*l.EngineRPM)
+		return &rpm
+	}},
+	{models.SignalSpeed, "km/h", func(l *models.TelemetryLog) *float64 { return l.Speed }},
+	{models.SignalCoolantTemp, "°C", func(l *models.TelemetryLog) *float64 { return l.CoolantTemp }},
+	{models.SignalEngineLoad, "%", func(l *models.TelemetryLog) *float64 { return l.EngineLoad }},
+	{models.SignalThrottlePos, "%", func(l *models.TelemetryLog) *float64 { return l.ThrottlePos }},
+	{models.SignalFuelLevel, "%", func(l *models.TelemetryLog) *float64 { return l.FuelLevel }},
+	{models.SignalBatteryVoltage, "V", func(l *models.TelemetryLog) *float64 { return l.BatteryVoltage }},
+	{models.SignalOdometer, "km", func(l *models.TelemetryLog) *float64 { return l.Odometer }},
+	{models.SignalEngineHours, "h", func(l *models.TelemetryLog) *float64 { return l.EngineHours }},
+	{models.SignalFuelUsed, "L", func(l *models.TelemetryLog) *float64 { return l.FuelUsed }},
+}
+
+// thresholdLimit returns a pointer to a threshold value
+func thresholdLimit(v float64) *float64 { return &v }
+
+// defaultTelemetryThresholds apply to signals without a configured rule
+var defaultTelemetryThresholds = map[models.TelemetrySignal]models.TelemetryThreshold{
+	models.SignalCoolantTemp:    {Max: thresholdLimit(105), CriticalMax: thresholdLimit(110), MaxRisePerMin: thresholdLimit(5), Severity: models.DTCSeverityHigh},
+	models.SignalBatteryVoltage: {Min: thresholdLimit(11.5), Max: thresholdLimit(15), CriticalMin: thresholdLimit(10.5), Severity: models.DTCSeverityMedium},
+	models.SignalEngineRPM:      {Max: thresholdLimit(3500), CriticalMax: thresholdLimit(4500), Severity: models.DTCSeverityMedium},
+	models.SignalSpeed:          {Max: thresholdLimit(120), Severity: models.DTCSeverityMedium},
+	models.SignalEngineLoad:     {Max: thresholdLimit(95), Severity: models.DTCSeverityLow},
+	models.SignalFuelLevel:      {Min: thresholdLimit(10), MaxFallPerMin: thresholdLimit(5), Severity: models.DTCSeverityMedium}, // Sudden drops suggest leaks or theft
+	models.SignalOdometer:       {MaxRisePerMin: thresholdLimit(3), MaxFallPerMin: thresholdLimit(0), Severity: models.DTCSeverityMedium},
+	models.SignalEngineHours:    {MaxRisePerMin: thresholdLimit(0.05), MaxFallPerMin: thresholdLimit(0), Severity: models.DTCSeverityLow},
+}
+
+// baselineMinSpread is the smallest standard deviation assumed for signals watched against the
+// vehicle's baseline, so a very steady signal does not flag normal noise
+var baselineMinSpread = map[models.TelemetrySignal]float64{
+	models.SignalCoolantTemp:    2,
+	models.SignalBatteryVoltage: 0.2,
+	models.SignalEngineRPM:      100,
+	models.SignalEngineLoad:     5,
+}
+
+// Points each open fault code, anomaly and inspection defect takes off a health score
+var (
+	anomalyHealthPenalty = map[models.DiagnosticCodeSeverity]float64{
+		models.DTCSeverityLow:      2,
+		models.DTCSeverityMedium:   5,
+		models.DTCSeverityHigh:     10,
+		models.DTCSeverityCritical: 20,
+	}
+	dtcHealthPenalty = map[models.DiagnosticCodeSeverity]float64{
+		models.DTCSeverityLow:      3,
+		models.DTCSeverityMedium:   8,
+		models.DTCSeverityHigh:     15,
+		models.DTCSeverityCritical: 30,
+	}
+	defectHealthPenalty = map[models.DefectSeverity]float64{
+		models.DefectSeverityMinor:        3,
+		models.DefectSeverityMajor:        10,
+		models.DefectSeverityOutOfService: 25,
+	}
+)
+
+// Vehicle health statuses
+const (
+	HealthStatusGood     = "GOOD"
+	HealthStatusFair     = "FAIR"
+	HealthStatusPoor     = "POOR"
+	HealthStatusCritical = "CRITICAL"
+)
+
+// HealthFactor is an open issue lowering a vehicle's health score
+type HealthFactor struct {
+	Source      string  `json:"source"`    // DTC, ANOMALY or DEFECT
+	Reference   string  `json:"reference"` // Fault code, signal or defect ID
+	Severity    string  `json:"severity"`
+	Penalty     float64 `json:"penalty"`
+	Description string  `json:"description"`
+}
+
+// VehicleHealth is a 0-100 composite of a vehicle's active fault codes, telemetry anomalies and open inspection defects
+type VehicleHealth struct {
+	VehicleID       uint           `json:"vehicle_id"`
+	Score           int            `json:"score"`
+	Status          string         `json:"status"`
+	ActiveDTCs      int            `json:"active_dtcs"`
+	OpenAnomalies   int            `json:"open_anomalies"`
+	OpenDefects     int            `json:"open_defects"`
+	Factors         []HealthFactor `json:"factors"`
+	LastTelemetryAt *time.Time     `json:"last_telemetry_at,omitempty"`
+	Stale           bool           `json:"stale"` // No telemetry for a day
+}
+
+// LatestTelemetry is a vehicle's most recent telemetry reading with its health
+type LatestTelemetry struct {
+	models.TelemetryLog
+	Health *VehicleHealth `json:"health"`
+}
+
+// TelemetryAnalysis is what evaluating a telemetry reading changed
+type TelemetryAnalysis struct {
+	Log      *models.TelemetryLog      `json:"log"`
+	Opened   []models.TelemetryAnomaly `json:"opened"`
+	Updated  []models.TelemetryAnomaly `json:"updated"`
+	Resolved []models.TelemetryAnomaly `json:"resolved"`
+}
+
+// signalBreach is a rule a reading broke
+type signalBreach struct {
+	kind     models.TelemetryAnomalyKind
+	severity models.DiagnosticCodeSeverity
+	value    float64
+	expected float64
+	message  string
+}
+
+// IngestTelemetry stores a telemetry reading and checks it against the vehicle's thresholds,
+// rate-of-change rules and baseline
+func (s *TelemetryService) IngestTelemetry(ctx context.Context, data *TelemetryUpdate) (*TelemetryAnalysis, error) {
+	entry := &models.TelemetryLog{
+		VehicleID:      data.VehicleID,
+		Timestamp:      data.Timestamp,
+		EngineRPM:      data.EngineRPM,
+		Speed:          data.Speed,
+		CoolantTemp:    data.CoolantTemp,
+		EngineLoad:     data.EngineLoad,
+		ThrottlePos:    data.ThrottlePos,
+		FuelLevel:      data.FuelLevel,
+		BatteryVoltage: data.BatteryVoltage,
+		Odometer:       data.Odometer,
+		EngineHours:    data.EngineHours,
+		FuelUsed:       data.FuelUsed,
+		CreatedAt:      time.Now(),
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = entry.CreatedAt
+	}
+	if err := s.db.Create(entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to save telemetry log: %w", err)
+	}
+	return s.analyzeTelemetry(entry)
+}
+
+// analyzeTelemetry evaluates every signal in a reading. Breaches open an anomaly or update the one
+// already open for the same rule; readings within limits count towards resolving it.
+func (s *TelemetryService) analyzeTelemetry(entry *models.TelemetryLog) (*TelemetryAnalysis, error) {
+	s.analysisMu.Lock()
+	defer s.analysisMu.Unlock()
+
+	var vehicle models.Vehicle
+	if err := s.db.First(&vehicle, entry.VehicleID).Error; err != nil {
+		return nil, err
+	}
+	rules, err := s.effectiveThresholds(&vehicle)
+	if err != nil {
+		return nil, err
+	}
+
+	var baselines []models.TelemetryBaseline
+	if err := s.db.Where("vehicle_id = ?", vehicle.ID).Find(&baselines).Error; err != nil {
+		return nil, err
+	}
+	baselineBySignal := make(map[models.TelemetrySignal]*models.TelemetryBaseline, len(baselines))
+	for i := range baselines {
+		baselineBySignal[baselines[i].Signal] = &baselines[i]
+	}
+
+	var open []models.TelemetryAnomaly
+	if err := s.db.Where("vehicle_id = ? AND is_active = ?", vehicle.ID, true).Find(&open).Error; err != nil {
+		return nil, err
+	}
+
+	analysis := &TelemetryAnalysis{
+		Log:      entry,
+		Opened:   []models.TelemetryAnomaly{},
+		Updated:  []models.TelemetryAnomaly{},
+		Resolved: []models.TelemetryAnomaly{},
+	}
+	now := entry.Timestamp
+	for _, signal := range telemetrySignals {
+		value := signal.value(entry)
+		if value == nil {
+			continue
+		}
+		baseline := baselineBySignal[signal.signal]
+		breaches := evaluateSignal(signal.signal, signal.unit, *value, now, rules[signal.signal], baseline)
+
+		breached := make(map[models.TelemetryAnomalyKind]bool, len(breaches))
+		for _, breach := range breaches {
+			breached[breach.kind] = true
+			if err := s.recordBreach(&vehicle, signal.signal, breach, now, analysis); err != nil {
+				return nil, err
+			}
+		}
+		for i := range open {
+			anomaly := &open[i]
+			if anomaly.Signal != signal.signal || breached[anomaly.Kind] {
+				continue
+			}
+			anomaly.NormalStreak++
+			updates := map[string]interface{}{"normal_streak": anomaly.NormalStreak}
+			if anomaly.NormalStreak >= anomalyResolveAfter {
+				anomaly.IsActive = false
+				anomaly.ResolvedAt = &now
+				updates["is_active"] = false
+				updates["resolved_at"] = now
+			}
+			if err := s.db.Model(anomaly).Updates(updates).Error; err != nil {
+				return nil, err
+			}
+			if !anomaly.IsActive {
+				analysis.Resolved = append(analysis.Resolved, *anomaly)
+			}
+		}
+
+		if err := s.updateBaseline(vehicle.ID, signal.signal, baseline, *value, now); err != nil {
+			return nil, err
+		}
+	}
+	return analysis, nil
+}
+
+// evaluateSignal applies a signal's threshold, rate-of-change and baseline rules to a reading
+func evaluateSignal(signal models.TelemetrySignal, unit string, value float64, at time.Time, rule *models.TelemetryThreshold, baseline *models.TelemetryBaseline) []signalBreach {
+	var breaches []signalBreach
+	name := strings.ToLower(strings.ReplaceAll(string(signal), "_", " "))
+
+	if rule != nil {
+		switch {
+		case rule.CriticalMax != nil && value > *rule.CriticalMax:
+			breaches = append(breaches, signalBreach{models.AnomalyThreshold, models.DTCSeverityCritical, value, *rule.CriticalMax,
+				fmt.Sprintf("%s critical: %.1f%s above %.1f%s", name, value, unit, *rule.CriticalMax, unit)})
+		case rule.CriticalMin != nil && value < *rule.CriticalMin:
+			breaches = append(breaches, signalBreach{models.AnomalyThreshold, models.DTCSeverityCritical, value, *rule.CriticalMin,
+				fmt.Sprintf("%s critical: %.1f%s below %.1f%s", name, value, unit, *rule.CriticalMin, unit)})
+		case rule.Max != nil && value > *rule.Max:
+			breaches = append(breaches, signalBreach{models.AnomalyThreshold, rule.Severity, value, *rule.Max,
+				fmt.Sprintf("%s high: %.1f%s above %.1f%s", name, value, unit, *rule.Max, unit)})
+		case rule.Min != nil && value < *rule.Min:
+			breaches = append(breaches, signalBreach{models.AnomalyThreshold, rule.Severity, value, *rule.Min,
+				fmt.Sprintf("%s low: %.1f%s below %.1f%s", name, value, unit, *rule.Min, unit)})
+		}
+
+		if baseline != nil && baseline.Samples > 0 {
+			elapsed := at.Sub(baseline.LastSeenAt)
+			if elapsed > 0 && elapsed <= maxRateInterval {
+				rate := (value - baseline.LastValue) / elapsed.Minutes()
+				switch {
+				case rule.MaxRisePerMin != nil && rate > *rule.MaxRisePerMin:
+					breaches = append(breaches, signalBreach{models.AnomalyRateChange, rule.Severity, rate, *rule.MaxRisePerMin,
+						fmt.Sprintf("%s rising %.2f%s/min, limit %.2f%s/min", name, rate, unit, *rule.MaxRisePerMin, unit)})
+				case rule.MaxFallPerMin != nil && -rate > *rule.MaxFallPerMin:
+					breaches = append(breaches, signalBreach{models.AnomalyRateChange, rule.Severity, rate, -*rule.MaxFallPerMin,
+						fmt.Sprintf("%s falling %.2f%s/min, limit %.2f%s/min", name, -rate, unit, *rule.MaxFallPerMin, unit)})
+				}
+			}
+		}
+	}
+
+	if minSpread, watched := baselineMinSpread[signal]; watched && baseline != nil && baseline.Samples >= baselineMinSamples {
+		spread := math.Max(math.Sqrt(baseline.Variance), minSpread)
+		deviation := math.Abs(value-baseline.Mean) / spread
+		if deviation >= 4 {
+			severity := models.DTCSeverityLow
+			if deviation >= 6 {
+				severity = models.DTCSeverityMedium
+			}
+			breaches = append(breaches, signalBreach{models.AnomalyDeviation, severity, value, baseline.Mean,
+				fmt.Sprintf("%s %.1f%s is %.1f standard deviations from this vehicle's usual %.1f%s", name, value, unit, deviation, baseline.Mean, unit)})
+		}
+	}
+	return breaches
+}
+
+// recordBreach opens an anomaly and alerts, or folds the breach into the anomaly already open or
+// recently resolved for the same rule, alerting again only when it becomes more severe
+func (s *TelemetryService) recordBreach(vehicle *models.Vehicle, signal models.TelemetrySignal, breach signalBreach, now time.Time, analysis *TelemetryAnalysis) error {
+	var existing []models.TelemetryAnomaly
+	if err := s.db.Where("vehicle_id = ? AND signal = ? AND kind = ? AND (is_active = ? OR resolved_at > ?)",
+		vehicle.ID, signal, breach.kind, true, now.Add(-anomalyCooldown)).
+		Order("id DESC").Limit(1).Find(&existing).Error; err != nil {
+		return err
+	}
+
+	if len(existing) == 0 {
+		anomaly := models.TelemetryAnomaly{
+			VehicleID:   vehicle.ID,
+			Signal:      signal,
+			Kind:        breach.kind,
+			Severity:    breach.severity,
+			Message:     breach.message,
+			Value:       breach.value,
+			PeakValue:   breach.value,
+			Expected:    breach.expected,
+			Occurrences: 1,
+			IsActive:    true,
+			FirstSeen:   now,
+			LastSeen:    now,
+		}
+		if err := s.db.Create(&anomaly).Error; err != nil {
+			return err
+		}
+		analysis.Opened = append(analysis.Opened, anomaly)
+		s.alertAnomaly(vehicle, &anomaly)
+		return nil
+	}
+
+	anomaly := &existing[0]
+	escalated := dtcSeverityWeight[breach.severity] > dtcSeverityWeight[anomaly.Severity]
+	anomaly.Occurrences++
+	anomaly.Value = breach.value
+	anomaly.LastSeen = now
+	anomaly.NormalStreak = 0
+	anomaly.IsActive = true
+	anomaly.ResolvedAt = nil
+	if escalated {
+		anomaly.Severity = breach.severity
+		anomaly.Message = breach.message
+		anomaly.Expected = breach.expected
+		anomaly.PeakValue = breach.value
+	} else if math.Abs(breach.value-breach.expected) > math.Abs(anomaly.PeakValue-anomaly.Expected) {
+		anomaly.PeakValue = breach.value
+	}
+	if err := s.db.Save(anomaly).Error; err != nil {
+		return err
+	}
+	analysis.Updated = append(analysis.Updated, *anomaly)
+	if escalated {
+		s.alertAnomaly(vehicle, anomaly)
+	}
+	return nil
+}
+
+// alertAnomaly publishes a fleet alert for an anomaly
+func (s *TelemetryService) alertAnomaly(vehicle *models.Vehicle, anomaly *models.TelemetryAnomaly) {
+	if s.mqttService == nil || !s.mqttService.IsEnabled() {
+		return
+	}
+	alertType := "TELEMETRY_ANOMALY"
+	switch {
+	case anomaly.Signal == models.SignalCoolantTemp && anomaly.Kind == models.AnomalyThreshold && anomaly.Value > anomaly.Expected:
+		alertType = "ENGINE_OVERHEAT"
+	case anomaly.Signal == models.SignalBatteryVoltage && anomaly.Kind == models.AnomalyThreshold && anomaly.Value < anomaly.Expected:
+		alertType = "LOW_BATTERY"
+	}
+	s.sendAlert(vehicle.ID, alertType, fmt.Sprintf("%s: %s", vehicle.LicensePlate, anomaly.Message), string(anomaly.Severity))
+}
+
+// updateBaseline folds a reading into the vehicle's running mean and variance of a signal
+func (s *TelemetryService) updateBaseline(vehicleID uint, signal models.TelemetrySignal, baseline *models.TelemetryBaseline, value float64, at time.Time) error {
+	if baseline == nil {
+		return s.db.Create(&models.TelemetryBaseline{
+			VehicleID:  vehicleID,
+			Signal:     signal,
+			Mean:       value,
+			Samples:    1,
+			LastValue:  value,
+			LastSeenAt: at,
+		}).Error
+	}
+	diff := value - baseline.Mean
+	increment := baselineAlpha * diff
+	baseline.Mean += increment
+	baseline.Variance = (1 - baselineAlpha) * (baseline.Variance + diff*increment)
+	baseline.Samples++
+	baseline.LastValue = value
+	baseline.LastSeenAt = at
+	return s.db.Save(baseline).Error
+}
+
+// effectiveThresholds picks each signal's most specific rule for a vehicle: make and model,
+// then make, then fleet-wide, then the built-in default
+func (s *TelemetryService) effectiveThresholds(vehicle *models.Vehicle) (map[models.TelemetrySignal]*models.TelemetryThreshold, error) {
+	var configured []models.TelemetryThreshold
+	if err := s.db.Where("is_active = ? AND (vehicle_make = '' OR LOWER(vehicle_make) = ?)", true, strings.ToLower(vehicle.Make)).
+		Find(&configured).Error; err != nil {
+		return nil, err
+	}
+
+	rules := make(map[models.TelemetrySignal]*models.TelemetryThreshold)
+	for signal, rule := range defaultTelemetryThresholds {
+		rule := rule
+		rule.Signal, rule.IsActive = signal, true
+		rules[signal] = &rule
+	}
+	specificity := make(map[models.TelemetrySignal]int)
+	for i := range configured {
+		rule := &configured[i]
+		rank := 1
+		if rule.VehicleMake != "" {
+			rank = 2
+			if rule.VehicleModel != "" {
+				if !strings.EqualFold(rule.VehicleModel, vehicle.Model) {
+					continue
+				}
+				rank = 3
+			}
+		}
+		if rank > specificity[rule.Signal] {
+			specificity[rule.Signal] = rank
+			rules[rule.Signal] = rule
+		}
+	}
+	return rules, nil
+}
+
+// validTelemetrySignal reports whether a signal is read from telemetry logs
+func validTelemetrySignal(signal models.TelemetrySignal) bool {
+	for _, s := range telemetrySignals {
+		if s.signal == signal {
+			return true
+		}
+	}
+	return false
+}
+
+// SaveTelemetryThreshold creates or replaces the rule for a signal and vehicle make and model
+func (s *TelemetryService) SaveTelemetryThreshold(ctx context.Context, rule *models.TelemetryThreshold) (*models.TelemetryThreshold, error) {
+	rule.Signal = models.TelemetrySignal(strings.ToUpper(string(rule.Signal)))
+	rule.VehicleMake, rule.VehicleModel = strings.TrimSpace(rule.VehicleMake), strings.TrimSpace(rule.VehicleModel)
+	switch {
+	case !validTelemetrySignal(rule.Signal):
+		return nil, fmt.Errorf("%w: unknown signal %q", ErrInvalidThreshold, rule.Signal)
+	case rule.VehicleModel != "" && rule.VehicleMake == "":
+		return nil, fmt.Errorf("%w: a vehicle model needs its make", ErrInvalidThreshold)
+	case rule.Min != nil && rule.Max != nil && *rule.Min >= *rule.Max:
+		return nil, fmt.Errorf("%w: min must be below max", ErrInvalidThreshold)
+	case rule.CriticalMin != nil && rule.Min != nil && *rule.CriticalMin > *rule.Min:
+		return nil, fmt.Errorf("%w: critical min must not be above min", ErrInvalidThreshold)
+	case rule.CriticalMax != nil && rule.Max != nil && *rule.CriticalMax < *rule.Max:
+		return nil, fmt.Errorf("%w: critical max must not be below max", ErrInvalidThreshold)
+	case (rule.MaxRisePerMin != nil && *rule.MaxRisePerMin < 0) || (rule.MaxFallPerMin != nil && *rule.MaxFallPerMin < 0):
+		return nil, fmt.Errorf("%w: rates of change must not be negative", ErrInvalidThreshold)
+	}
+	if rule.Severity == "" {
+		rule.Severity = models.DTCSeverityMedium
+	}
+	if _, ok := dtcSeverityWeight[rule.Severity]; !ok {
+		return nil, fmt.Errorf("%w: unknown severity %q", ErrInvalidThreshold, rule.Severity)
+	}
+
+	var existing []models.TelemetryThreshold
+	if err := s.db.Where("vehicle_make = ? AND vehicle_model = ? AND signal = ?", rule.VehicleMake, rule.VehicleModel, rule.Signal).
+		Limit(1).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		rule.ID = existing[0].ID
+		rule.CreatedAt = existing[0].CreatedAt
+	}
+	if err := s.db.Save(rule).Error; err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// GetTelemetryThresholds lists configured rules, or the rules in effect for one vehicle
+// including built-in defaults when vehicleID is set
+func (s *TelemetryService) GetTelemetryThresholds(ctx context.Context, vehicleID *uint) ([]models.TelemetryThreshold, error) {
+	if vehicleID == nil {
+		var rules []models.TelemetryThreshold
+		if err := s.db.Order("signal, vehicle_make, vehicle_model").Find(&rules).Error; err != nil {
+			return nil, err
+		}
+		return rules, nil
+	}
+
+	var vehicle models.Vehicle
+	if err := s.db.First(&vehicle, *vehicleID).Error; err != nil {
+		return nil, err
+	}
+	effective, err := s.effectiveThresholds(&vehicle)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]models.TelemetryThreshold, 0, len(effective))
+	for _, signal := range telemetrySignals {
+		if rule, ok := effective[signal.signal]; ok {
+			rules = append(rules, *rule)
+		}
+	}
+	return rules, nil
+}
+
+// DeleteTelemetryThreshold removes a configured rule; the next most specific rule applies again
+func (s *TelemetryService) DeleteTelemetryThreshold(ctx context.Context, id uint) error {
+	result := s.db.Delete(&models.TelemetryThreshold{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetTelemetryAnomalies lists a vehicle's open anomalies, or all of them including resolved ones
+func (s *TelemetryService) GetTelemetryAnomalies(ctx context.Context, vehicleID uint, includeResolved bool) ([]models.TelemetryAnomaly, error) {
+	query := s.db.Where("vehicle_id = ?", vehicleID)
+	if !includeResolved {
+		query = query.Where("is_active = ?", true)
+	}
+	var anomalies []models.TelemetryAnomaly
+	if err := query.Order("last_seen DESC, id DESC").Find(&anomalies).Error; err != nil {
+		return nil, err
+	}
+	return anomalies, nil
+}
+
+// GetVehicleHealth scores a vehicle from 100 down by its active fault codes, open telemetry
+// anomalies and open inspection defects
+func (s *TelemetryService) GetVehicleHealth(ctx context.Context, vehicleID uint) (*VehicleHealth, error) {
+	var vehicle models.Vehicle
+	if err := s.db.First(&vehicle, vehicleID).Error; err != nil {
+		return nil, err
+	}
+
+	health := &VehicleHealth{VehicleID: vehicle.ID, Factors: []HealthFactor{}}
+	penalty := 0.0
+	add := func(factor HealthFactor) {
+		health.Factors = append(health.Factors, factor)
+		penalty += factor.Penalty
+	}
+
+	codes, err := s.GetDiagnosticCodes(ctx, vehicle.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	health.ActiveDTCs = len(codes)
+	for _, code := range codes {
+		add(HealthFactor{Source: "DTC", Reference: code.Code, Severity: string(code.Severity),
+			Penalty: dtcHealthPenalty[code.Severity], Description: code.Description})
+	}
+
+	anomalies, err := s.GetTelemetryAnomalies(ctx, vehicle.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	health.OpenAnomalies = len(anomalies)
+	for _, anomaly := range anomalies {
+		add(HealthFactor{Source: "ANOMALY", Reference: string(anomaly.Signal), Severity: string(anomaly.Severity),
+			Penalty: anomalyHealthPenalty[anomaly.Severity], Description: anomaly.Message})
+	}
+
+	var defects []models.DVIRDefect
+	if err := s.db.Where("vehicle_id = ? AND status = ?", vehicle.ID, models.DVIRDefectOpen).Find(&defects).Error; err != nil {
+		return nil, err
+	}
+	health.OpenDefects = len(defects)
+	for _, defect := range defects {
+		add(HealthFactor{Source: "DEFECT", Reference: fmt.Sprintf("%d", defect.ID), Severity: string(defect.Severity),
+			Penalty: defectHealthPenalty[defect.Severity], Description: defect.Description})
+	}
+
+	health.Score = int(math.Round(math.Max(0, 100-penalty)))
+	switch {
+	case health.Score >= 80:
+		health.Status = HealthStatusGood
+	case health.Score >= 60:
+		health.Status = HealthStatusFair
+	case health.Score >= 40:
+		health.Status = HealthStatusPoor
+	default:
+		health.Status = HealthStatusCritical
+	}
+
+	var latest []models.TelemetryLog
+	if err := s.db.Where("vehicle_id = ?", vehicle.ID).Order("timestamp DESC").Limit(1).Find(&latest).Error; err != nil {
+		return nil, err
+	}
+	if len(latest) > 0 {
+		health.LastTelemetryAt = &latest[0].Timestamp
+		health.Stale = time.Since(latest[0].Timestamp) > staleTelemetryAfter
+	}
+	return health, nil
+}
+
+// GetLatestTelemetry returns a vehicle's most recent telemetry reading with its health score
+func (s *TelemetryService) GetLatestTelemetry(ctx context.Context, vehicleID uint) (*LatestTelemetry, error) {
+	health, err := s.GetVehicleHealth(ctx, vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	latest := &LatestTelemetry{Health: health}
+	if err := s.db.Where("vehicle_id = ?", vehicleID).Order("timestamp DESC").Limit(1).Find(&latest.TelemetryLog).Error; err != nil {
+		return nil, err
+	}
+	return latest, nil
+}
@@ -1,25 +1,68 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/fleetflow/backend/internal/models"
 	"gorm.io/gorm"
 )
 
+// DefaultDTCClearAfterReports is how many diagnostic reports a code may be missing from before it is cleared
+const DefaultDTCClearAfterReports = 3
+
+// Channels a fault code alert is routed to
+const (
+	DTCAlertFleet    = "FLEET_ALERT"          // MQTT fleet alert for dashboards
+	DTCAlertManagers = "MAINTENANCE_MANAGERS" // SMS to dispatchers and organization admins
+	DTCAlertDriver   = "DRIVER"               // SMS to the driver of the vehicle's trip in progress
+)
+
+// dtcAlertRouting is where a new fault code of each severity is announced
+var dtcAlertRouting = map[models.DiagnosticCodeSeverity][]string{
+	models.DTCSeverityLow:      nil,
+	models.DTCSeverityMedium:   {DTCAlertFleet},
+	models.DTCSeverityHigh:     {DTCAlertFleet, DTCAlertManagers},
+	models.DTCSeverityCritical: {DTCAlertFleet, DTCAlertManagers, DTCAlertDriver},
+}
+
 // TelemetryService handles vehicle sensor data and diagnostics
 type TelemetryService struct {
-	db          *gorm.DB
-	mqttService *MQTTService
+	db            *gorm.DB
+	mqttService   *MQTTService
+	notifications *NotificationService
+	maintenance   *MaintenanceService
+	dtcClearAfter int
+	analysisMu    sync.Mutex // Serializes baseline and anomaly updates
 }
 
 // NewTelemetryService creates a new telemetry service
 func NewTelemetryService(db *gorm.DB, mqttService *MQTTService) *TelemetryService {
 	return &TelemetryService{
-		db:          db,
-		mqttService: mqttService,
+		db:            db,
+		mqttService:   mqttService,
+		dtcClearAfter: DefaultDTCClearAfterReports,
+	}
+}
+
+// SetNotificationService enables SMS alerts to drivers for critical fault codes
+func (s *TelemetryService) SetNotificationService(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// SetMaintenanceService enables SMS alerts to maintenance managers for serious fault codes
+func (s *TelemetryService) SetMaintenanceService(maintenance *MaintenanceService) {
+	s.maintenance = maintenance
+}
+
+// SetDTCClearAfterReports sets how many consecutive reports a code may be missing from before it is cleared
+func (s *TelemetryService) SetDTCClearAfterReports(reports int) {
+	if reports > 0 {
+		s.dtcClearAfter = reports
 	}
 }
 
@@ -36,79 +79,266 @@ func (s *TelemetryService) Start() error {
 
 // handleTelemetryUpdate processes incoming sensor data
 func (s *TelemetryService) handleTelemetryUpdate(data *TelemetryUpdate) {
-	// 1. Store Telemetry Log
-	logEntry := &models.TelemetryLog{
-		VehicleID:      data.VehicleID,
-		Timestamp:      data.Timestamp,
-		EngineRPM:      data.EngineRPM,
-		Speed:          data.Speed,
-		CoolantTemp:    data.CoolantTemp,
-		EngineLoad:     data.EngineLoad,
-		ThrottlePos:    data.ThrottlePos,
-		FuelLevel:      data.FuelLevel,
-		BatteryVoltage: data.BatteryVoltage,
-		Odometer:       data.Odometer,
-		EngineHours:    data.EngineHours,
-		FuelUsed:       data.FuelUsed,
-		CreatedAt:      time.Now(),
-	}
-
-	// Use a separate goroutine for DB writes to avoid blocking the MQTT handler
+	// 1. Store the telemetry log and check it against thresholds, rates and the vehicle's baseline.
+	// A separate goroutine keeps DB writes from blocking the MQTT handler.
 	go func() {
-		if err := s.db.Create(logEntry).Error; err != nil {
-			log.Printf("❌ Failed to save telemetry log: %v", err)
+		if _, err := s.IngestTelemetry(context.Background(), data); err != nil {
+			log.Printf("❌ Failed to process telemetry for vehicle %d: %v", data.VehicleID, err)
 		}
 	}()
 
-	// 2. Process DTCs
-	if len(data.DTCs) > 0 {
-		go s.processDTCs(data.VehicleID, data.DTCs)
+	// 2. Process DTCs; an empty list still counts towards clearing codes no longer reported
+	if data.DTCs != nil || data.DM1 != "" {
+		go func() {
+			if _, err := s.ProcessDiagnostics(context.Background(), &DiagnosticReport{
+				VehicleID: data.VehicleID,
+				Codes:     data.DTCs,
+				DM1:       data.DM1,
+				Timestamp: data.Timestamp,
+			}); err != nil {
+				log.Printf("❌ Failed to process diagnostics for vehicle %d: %v", data.VehicleID, err)
+			}
+		}()
 	}
+}
+
+// DiagnosticReport is the set of fault codes a vehicle currently reports, as codes and/or a J1939 DM1 payload
+type DiagnosticReport struct {
+	VehicleID uint      `json:"vehicle_id"`
+	Codes     []string  `json:"codes"`
+	DM1       string    `json:"dm1,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// DTCAlert records where a fault code was announced
+type DTCAlert struct {
+	Code     string                        `json:"code"`
+	Severity models.DiagnosticCodeSeverity `json:"severity"`
+	Channels []string                      `json:"channels"`
+}
+
+// DiagnosticReportResult is what processing a diagnostic report changed
+type DiagnosticReportResult struct {
+	New     []models.DiagnosticCode `json:"new"`
+	Updated []models.DiagnosticCode `json:"updated"`
+	Cleared []models.DiagnosticCode `json:"cleared"`
+	Invalid []string                `json:"invalid,omitempty"`
+	Lamps   *J1939Lamps             `json:"lamps,omitempty"`
+	Alerts  []DTCAlert              `json:"alerts"`
+}
 
-	// 3. Check Thresholds
-	go s.checkThresholds(data)
+// reportedDTC is a fault code in a report with its catalogue definition
+type reportedDTC struct {
+	definition      *DTCDefinition
+	occurrenceCount int
+	raw             string
 }
 
-// processDTCs handles diagnostic trouble codes
-func (s *TelemetryService) processDTCs(vehicleID uint, codes []string) {
-	for _, code := range codes {
-		// Check if active code exists
-		var existing models.DiagnosticCode
-		err := s.db.Where("vehicle_id = ? AND code = ? AND is_active = ?", vehicleID, code, true).First(&existing).Error
+// ProcessDiagnostics records the fault codes a vehicle reports. New codes are described from the
+// catalogue and announced according to their severity; active codes missing from enough
+// consecutive reports are cleared.
+func (s *TelemetryService) ProcessDiagnostics(ctx context.Context, report *DiagnosticReport) (*DiagnosticReportResult, error) {
+	var vehicle models.Vehicle
+	if err := s.db.First(&vehicle, report.VehicleID).Error; err != nil {
+		return nil, err
+	}
+	now := report.Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	result := &DiagnosticReportResult{
+		New:     []models.DiagnosticCode{},
+		Updated: []models.DiagnosticCode{},
+		Cleared: []models.DiagnosticCode{},
+		Alerts:  []DTCAlert{},
+	}
+	reported := make(map[string]*reportedDTC)
+	var order []string
+	add := func(code string, entry *reportedDTC) {
+		if _, seen := reported[code]; !seen {
+			order = append(order, code)
+		}
+		reported[code] = entry
+	}
+
+	for _, raw := range report.Codes {
+		definition, err := s.LookupDTC(ctx, raw)
+		if err != nil {
+			if errors.Is(err, ErrInvalidDTC) {
+				result.Invalid = append(result.Invalid, raw)
+				continue
+			}
+			return nil, err
+		}
+		add(definition.Code, &reportedDTC{definition: definition})
+	}
 
-		if err == gorm.ErrRecordNotFound {
-			// New Fault Code
-			newCode := models.DiagnosticCode{
-				VehicleID: vehicleID,
-				Code:      code,
-				IsActive:  true,
-				FirstSeen: time.Now(),
-				LastSeen:  time.Now(),
-				Severity:  models.DTCSeverityMedium, // Default, should lookup in a DB
+	// A DM1 frame that cannot be read does not hold up the OBD codes reported with it,
+	// and leaves the vehicle's J1939 codes as they are
+	dm1Decoded := false
+	if report.DM1 != "" {
+		message, err := DecodeDM1(report.DM1)
+		if err != nil {
+			log.Printf("⚠️ Ignoring unreadable DM1 frame from vehicle %d: %v", vehicle.ID, err)
+			result.Invalid = append(result.Invalid, report.DM1)
+		} else {
+			dm1Decoded = true
+			result.Lamps = &message.Lamps
+			for _, dtc := range message.DTCs {
+				spn, fmi := dtc.SPN, dtc.FMI
+				definition, err := s.lookupDTC(dtc.Code, models.DTCSourceJ1939, &spn, &fmi)
+				if err != nil {
+					return nil, err
+				}
+				// The ECU's lamps say how urgent its faults are
+				switch {
+				case message.Lamps.RedStop:
+					definition.Severity = raiseDTCSeverity(definition.Severity, models.DTCSeverityHigh)
+				case message.Lamps.AmberWarning:
+					definition.Severity = raiseDTCSeverity(definition.Severity, models.DTCSeverityMedium)
+				}
+				add(dtc.Code, &reportedDTC{definition: definition, occurrenceCount: dtc.OccurrenceCount, raw: dtc.Raw})
 			}
+		}
+	}
+
+	var active []models.DiagnosticCode
+	if err := s.db.Where("vehicle_id = ? AND is_active = ?", vehicle.ID, true).Find(&active).Error; err != nil {
+		return nil, err
+	}
 
-			if err := s.db.Create(&newCode).Error; err == nil {
-				s.sendAlert(vehicleID, "ENGINE_FAULT", fmt.Sprintf("New Fault Code Detected: %s", code), "HIGH")
+	for i := range active {
+		code := &active[i]
+		if entry, ok := reported[code.Code]; ok {
+			delete(reported, code.Code)
+			code.LastSeen = now
+			code.MissedReports = 0
+			if entry.occurrenceCount > 0 {
+				code.OccurrenceCount = entry.occurrenceCount
+			}
+			if entry.raw != "" {
+				code.RawData = entry.raw
+			}
+			escalated := dtcSeverityWeight[entry.definition.Severity] > dtcSeverityWeight[code.Severity]
+			if escalated {
+				code.Severity = entry.definition.Severity
+			}
+			if err := s.db.Omit("Vehicle").Save(code).Error; err != nil {
+				return nil, err
+			}
+			result.Updated = append(result.Updated, *code)
+			if escalated {
+				if alert := s.routeDTCAlert(&vehicle, code, "escalated"); len(alert.Channels) > 0 {
+					result.Alerts = append(result.Alerts, alert)
+				}
 			}
-		} else if err == nil {
-			// Update existing code
-			existing.LastSeen = time.Now()
-			_ = s.db.Save(&existing)
+			continue
+		}
+
+		// Only reports that cover the code's protocol count against it
+		if code.Source == models.DTCSourceJ1939 && !dm1Decoded {
+			continue
+		}
+		if code.Source != models.DTCSourceJ1939 && report.Codes == nil {
+			continue
+		}
+		code.MissedReports++
+		updates := map[string]interface{}{"missed_reports": code.MissedReports}
+		if code.MissedReports >= s.dtcClearAfter {
+			code.IsActive = false
+			code.ClearTime = &now
+			updates["is_active"] = false
+			updates["clear_time"] = now
+		}
+		if err := s.db.Model(code).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+		if !code.IsActive {
+			result.Cleared = append(result.Cleared, *code)
+		}
+	}
+
+	for _, key := range order {
+		entry, ok := reported[key]
+		if !ok {
+			continue
+		}
+		definition := entry.definition
+		code := models.DiagnosticCode{
+			VehicleID:         vehicle.ID,
+			Code:              definition.Code,
+			Description:       definition.Description,
+			Severity:          definition.Severity,
+			SPN:               definition.SPN,
+			FMI:               definition.FMI,
+			OccurrenceCount:   entry.occurrenceCount,
+			RecommendedAction: definition.RecommendedAction,
+			IsActive:          true,
+			FirstSeen:         now,
+			LastSeen:          now,
+			Source:            definition.Source,
+			RawData:           entry.raw,
+		}
+		if err := s.db.Omit("Vehicle").Create(&code).Error; err != nil {
+			return nil, err
+		}
+		result.New = append(result.New, code)
+		if alert := s.routeDTCAlert(&vehicle, &code, "detected"); len(alert.Channels) > 0 {
+			result.Alerts = append(result.Alerts, alert)
 		}
 	}
+
+	return result, nil
 }
 
-// checkThresholds monitors critical sensor values
-func (s *TelemetryService) checkThresholds(data *TelemetryUpdate) {
-	// Coolant Temperature Alert (> 110°C)
-	if data.CoolantTemp != nil && *data.CoolantTemp > 110 {
-		s.sendAlert(data.VehicleID, "ENGINE_OVERHEAT", fmt.Sprintf("Engine coolant critical: %.1f°C", *data.CoolantTemp), "CRITICAL")
+// routeDTCAlert announces a fault code on the channels its severity calls for; delivery is best effort
+func (s *TelemetryService) routeDTCAlert(vehicle *models.Vehicle, code *models.DiagnosticCode, event string) DTCAlert {
+	alert := DTCAlert{Code: code.Code, Severity: code.Severity, Channels: []string{}}
+	message := fmt.Sprintf("Fault code %s %s on %s: %s", code.Code, event, vehicle.LicensePlate, code.Description)
+	if code.RecommendedAction != "" {
+		message += ". " + code.RecommendedAction
 	}
 
-	// Battery Voltage Alert (< 11.5V)
-	if data.BatteryVoltage != nil && *data.BatteryVoltage < 11.5 {
-		s.sendAlert(data.VehicleID, "LOW_BATTERY", fmt.Sprintf("Battery voltage low: %.1fV", *data.BatteryVoltage), "MEDIUM")
+	for _, channel := range dtcAlertRouting[code.Severity] {
+		switch channel {
+		case DTCAlertFleet:
+			if s.mqttService != nil && s.mqttService.IsEnabled() {
+				s.sendAlert(vehicle.ID, "ENGINE_FAULT", message, string(code.Severity))
+			}
+		case DTCAlertManagers:
+			if s.maintenance != nil {
+				s.maintenance.notifyMaintenanceManagers(vehicle, message)
+			}
+		case DTCAlertDriver:
+			// Only a vehicle out on a trip has a driver to warn
+			var trips []models.Trip
+			if err := s.db.Preload("Driver").Where("vehicle_id = ? AND status = ? AND driver_id IS NOT NULL", vehicle.ID, models.TripStatusInProgress).
+				Limit(1).Find(&trips).Error; err != nil || len(trips) == 0 || trips[0].Driver == nil {
+				continue
+			}
+			if s.notifications != nil {
+				if err := s.notifications.SendSMS(trips[0].Driver.Phone, message); err != nil {
+					log.Printf("⚠️ Failed to notify driver about fault %s on vehicle %d: %v", code.Code, vehicle.ID, err)
+				}
+			}
+		}
+		alert.Channels = append(alert.Channels, channel)
+	}
+	return alert
+}
+
+// GetDiagnosticCodes lists a vehicle's active fault codes, or all of them including cleared ones
+func (s *TelemetryService) GetDiagnosticCodes(ctx context.Context, vehicleID uint, includeCleared bool) ([]models.DiagnosticCode, error) {
+	query := s.db.Where("vehicle_id = ?", vehicleID)
+	if !includeCleared {
+		query = query.Where("is_active = ?", true)
+	}
+	var codes []models.DiagnosticCode
+	if err := query.Order("last_seen DESC, id DESC").Find(&codes).Error; err != nil {
+		return nil, err
 	}
+	return codes, nil
 }
 
 // sendAlert publishes a fleet alert
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTOTPRFC6238Vectors checks the SHA1 test vectors from RFC 6238 Appendix B (truncated to 6 digits)
+func TestTOTPRFC6238Vectors(t *testing.T) {
+	// ASCII "12345678901234567890" in base32
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, expected := range vectors {
+		code, err := GenerateTOTPCode(secret, time.Unix(unix, 0))
+		require.NoError(t, err)
+		assert.Equal(t, expected, code, "unix time %d", unix)
+	}
+}
+
+// TestTOTPValidationSkew tests that adjacent time steps are accepted but older ones are not
+func TestTOTPValidationSkew(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	code, err := GenerateTOTPCode(secret, now)
+	require.NoError(t, err)
+
+	assert.True(t, ValidateTOTPCode(secret, code, now))
+	assert.True(t, ValidateTOTPCode(secret, code, now.Add(30*time.Second)))
+	assert.False(t, ValidateTOTPCode(secret, code, now.Add(2*time.Minute)))
+	assert.False(t, ValidateTOTPCode(secret, "12345", now), "wrong length must be rejected")
+}
+
+// TestPasswordPolicy tests password validation against SecuritySettings
+func TestPasswordPolicy(t *testing.T) {
+	settings := &models.SecuritySettings{
+		PasswordMinLength:     10,
+		PasswordRequireUpper:  true,
+		PasswordRequireLower:  true,
+		PasswordRequireDigit:  true,
+		PasswordRequireSymbol: true,
+	}
+
+	assert.NoError(t, ValidatePasswordPolicy("Fleet!Flow2024", settings))
+	assert.ErrorIs(t, ValidatePasswordPolicy("Short1!", settings), ErrPasswordPolicy)
+	assert.ErrorIs(t, ValidatePasswordPolicy("nouppercase1!", settings), ErrPasswordPolicy)
+	assert.ErrorIs(t, ValidatePasswordPolicy("NoSymbolHere1", settings), ErrPasswordPolicy)
+
+	// Unset settings fall back to the defaults (8 chars, upper, lower, digit)
+	assert.NoError(t, ValidatePasswordPolicy("Password1", nil))
+	assert.Error(t, ValidatePasswordPolicy("password", nil))
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -15,6 +16,7 @@ type TripService struct {
 	vehicleRepo  repositories.VehicleRepository
 	uploadRepo   repositories.UploadRepository
 	auditService *AuditService
+	maintenance  *MaintenanceService
 }
 
 // NewTripService creates a new trip service
@@ -32,37 +34,9 @@ func NewTripService(
 	}
 }
 
-// calculateDistance calculates the distance between two coordinates in meters using Haversine formula
-func (s *TripService) calculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
-	const earthRadius = 6371000 // meters
-
-	// Convert degrees to radians
-	toRad := func(deg float64) float64 {
-		return deg * 3.14159265359 / 180
-	}
-
-	deltaLat := toRad(lat2 - lat1)
-	deltaLon := toRad(lon2 - lon1)
-
-	// Haversine formula - using simplified math without package
-	sinLat := deltaLat / 2
-	sinLon := deltaLon / 2
-
-	// sin²(x) approximation for small angles
-	sin2Lat := sinLat * sinLat
-	sin2Lon := sinLon * sinLon
-
-	a := sin2Lat + (1-sin2Lat*sin2Lat/6)*(1-sin2Lon*sin2Lon/6)*sin2Lon
-
-	// c = 2 * asin(sqrt(a)) approximation
-	sqrtA := a
-	if sqrtA > 0.01 {
-		// Better approximation for larger values
-		sqrtA = a - a*a/6
-	}
-	c := 2 * sqrtA
-
-	return earthRadius * c
+// SetMaintenanceService enables refusing to start trips on vehicles with out-of-service defects
+func (s *TripService) SetMaintenanceService(maintenance *MaintenanceService) {
+	s.maintenance = maintenance
 }
 
 // CreateTrip creates a new trip
@@ -205,7 +179,7 @@ func (s *TripService) AssignTrip(tripID, driverID, vehicleID uint) error {
 }
 
 // StartTrip starts a trip
-func (s *TripService) StartTrip(tripID uint) error {
+func (s *TripService) StartTrip(ctx context.Context, tripID uint) error {
 	// Get trip
 	trip, err := s.repo.GetTripByID(tripID)
 	if err != nil {
@@ -217,6 +191,13 @@ func (s *TripService) StartTrip(tripID uint) error {
 		return fmt.Errorf("cannot start trip with status %s (must be ASSIGNED)", trip.Status)
 	}
 
+	// A vehicle with an uncertified out-of-service defect may not be driven
+	if trip.VehicleID != nil && s.maintenance != nil {
+		if err := s.maintenance.CheckVehicleInService(ctx, *trip.VehicleID); err != nil {
+			return err
+		}
+	}
+
 	// Geofence validation - verify driver is at pickup location
 	if trip.PickupLatitude != 0 && trip.PickupLongitude != 0 && trip.VehicleID != nil {
 		// Get latest location for the vehicle
@@ -224,7 +205,7 @@ func (s *TripService) StartTrip(tripID uint) error {
 
 		if err == nil && latestPing != nil {
 			// Calculate distance to pickup location
-			distance := s.calculateDistance(
+			distance := models.HaversineMeters(
 				latestPing.Latitude,
 				latestPing.Longitude,
 				trip.PickupLatitude,
@@ -289,7 +270,7 @@ func (s *TripService) CompleteTrip(tripID uint) error {
 
 		if err == nil && latestPing != nil {
 			// Calculate distance to dropoff location
-			distance := s.calculateDistance(
+			distance := models.HaversineMeters(
 				latestPing.Latitude,
 				latestPing.Longitude,
 				trip.DropoffLatitude,
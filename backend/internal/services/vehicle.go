@@ -1,8 +1,10 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log"
 
 	"github.com/fleetflow/backend/internal/models"
 	"github.com/fleetflow/backend/internal/repositories"
@@ -12,6 +14,7 @@ import (
 type VehicleService struct {
 	repo         repositories.VehicleRepository
 	auditService *AuditService
+	maintenance  *MaintenanceService
 }
 
 // NewVehicleService creates a new vehicle service
@@ -22,6 +25,11 @@ func NewVehicleService(repo repositories.VehicleRepository, auditService *AuditS
 	}
 }
 
+// SetMaintenanceService enables applying service schedule templates to new vehicles
+func (s *VehicleService) SetMaintenanceService(maintenance *MaintenanceService) {
+	s.maintenance = maintenance
+}
+
 // CreateVehicle creates a new vehicle
 func (s *VehicleService) CreateVehicle(vehicle *models.Vehicle) (*models.Vehicle, error) {
 	// Validate required fields
@@ -58,6 +66,13 @@ func (s *VehicleService) CreateVehicle(vehicle *models.Vehicle) (*models.Vehicle
 		fmt.Sprintf("Vehicle created: %s", vehicle.LicensePlate),
 	)
 
+	// Schedule the standard services for its type
+	if s.maintenance != nil {
+		if _, err := s.maintenance.ApplyServiceScheduleTemplates(context.Background(), vehicle); err != nil {
+			log.Printf("⚠️ Failed to apply service schedule templates to vehicle %d: %v", vehicle.ID, err)
+		}
+	}
+
 	return vehicle, nil
 }
 
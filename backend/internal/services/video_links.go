@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// eventFootagePadding is how much footage either side of a safety event is asked for
+const eventFootagePadding = 10 * time.Second
+
+// eventVideoTypes files footage asked for a safety event under the matching clip type
+var eventVideoTypes = map[models.SafetyEventType]models.VideoEventType{
+	models.SafetyEventHarshBraking:      models.VideoEventHarshBraking,
+	models.SafetyEventHarshAcceleration: models.VideoEventHarshAcceleration,
+	models.SafetyEventHarshCornering:    models.VideoEventHarshCornering,
+	models.SafetyEventSpeeding:          models.VideoEventSpeeding,
+	models.SafetyEventImpact:            models.VideoEventImpact,
+}
+
+// attributeClip fills in the trip a new clip's vehicle was on and the driver from the trip or duty log
+func (s *VideoService) attributeClip(db *gorm.DB, clip *models.VideoClip) error {
+	if clip.VehicleID == nil || (clip.TripID != nil && clip.DriverID != nil) {
+		return nil
+	}
+	trip, driverID, err := vehicleAttribution(db, *clip.VehicleID, clip.StartTime)
+	if err != nil {
+		return err
+	}
+	if clip.TripID == nil && trip != nil {
+		clip.TripID = &trip.ID
+	}
+	if clip.DriverID == nil {
+		clip.DriverID = driverID
+	}
+	return nil
+}
+
+// linkClipEvents links a clip to the safety events of its vehicle it covers that have no footage yet
+func (s *VideoService) linkClipEvents(db *gorm.DB, clip *models.VideoClip) error {
+	if clip.VehicleID == nil {
+		return nil
+	}
+	return db.Model(&models.SafetyEvent{}).
+		Where("vehicle_id = ? AND timestamp >= ? AND timestamp <= ? AND video_clip_id IS NULL", *clip.VehicleID, clip.StartTime, clip.EndTime).
+		Update("video_clip_id", clip.ID).Error
+}
+
+// LinkEventFootage links a safety event to a clip of its vehicle covering it. High and critical
+// events without one have the footage asked of the vehicle's camera, unless it already was;
+// the clip uploaded for it is linked when it arrives. Returns the request made, if any.
+func (s *VideoService) LinkEventFootage(ctx context.Context, event *models.SafetyEvent, now time.Time) (*models.VideoRetrievalRequest, error) {
+	if event.VideoClipID != nil {
+		return nil, nil
+	}
+	db := s.db.WithContext(ctx)
+
+	var clips []models.VideoClip
+	if err := db.Where("vehicle_id = ? AND start_time <= ? AND end_time >= ? AND status <> ?",
+		event.VehicleID, event.Timestamp, event.Timestamp, models.VideoClipExpired).
+		Order("start_time DESC").Limit(1).Find(&clips).Error; err != nil {
+		return nil, err
+	}
+	if len(clips) > 0 {
+		event.VideoClipID = &clips[0].ID
+		return nil, db.Model(event).Update("video_clip_id", clips[0].ID).Error
+	}
+
+	if event.Severity != models.SeverityHigh && event.Severity != models.SeverityCritical {
+		return nil, nil
+	}
+	var requested int64
+	if err := db.Model(&models.VideoRetrievalRequest{}).
+		Where("vehicle_id = ? AND start_time <= ? AND end_time >= ? AND status IN ?", event.VehicleID, event.Timestamp, event.Timestamp,
+			[]models.VideoRetrievalStatus{models.VideoRetrievalPending, models.VideoRetrievalSent}).
+		Count(&requested).Error; err != nil {
+		return nil, err
+	}
+	if requested > 0 {
+		return nil, nil
+	}
+
+	eventType, ok := eventVideoTypes[event.Type]
+	if !ok {
+		eventType = models.VideoEventManual
+	}
+	retrieval, err := s.RequestFootage(ctx, FootageRequest{
+		VehicleID: &event.VehicleID,
+		StartTime: event.Timestamp.Add(-eventFootagePadding),
+		EndTime:   event.Timestamp.Add(eventFootagePadding),
+		EventType: eventType,
+		Reason:    fmt.Sprintf("%s %s safety event %d", event.Severity, event.Type, event.ID),
+	}, now)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// No dashcam on the vehicle
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("🎥 Asked camera %d for footage of safety event %d", retrieval.CameraID, event.ID)
+	return retrieval, nil
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+)
+
+const (
+	// maxRetrievalWindow is the most footage one retrieval request may ask a camera for
+	maxRetrievalWindow = 10 * time.Minute
+	// retrievalTimeout is how long a camera has to answer a retrieval request
+	retrievalTimeout = 24 * time.Hour
+	// defaultClipRetentionDays applies to event types without a policy
+	defaultClipRetentionDays = 30
+)
+
+// defaultClipRetention keeps collision footage for a year and driving behaviour for a quarter
+var defaultClipRetention = map[models.VideoEventType]int{
+	models.VideoEventImpact:       365,
+	models.VideoEventHarshBraking: 90,
+	models.VideoEventSpeeding:     90,
+	models.VideoEventDistraction:  90,
+	models.VideoEventDrowsiness:   90,
+	models.VideoEventManual:       defaultClipRetentionDays,
+
+	models.VideoEventHarshAcceleration: 90,
+	models.VideoEventHarshCornering:    90,
+}
+
+// FootageRequest asks the camera on a vehicle, or a named camera, for the footage of a window
+type FootageRequest struct {
+	CameraID      *uint
+	VehicleID     *uint
+	StartTime     time.Time
+	EndTime       time.Time
+	EventType     models.VideoEventType
+	Reason        string
+	RequestedByID *uint
+}
+
+// CameraCommand is pushed to a camera over MQTT
+type CameraCommand struct {
+	Command            string    `json:"command"` // UPLOAD_FOOTAGE
+	RetrievalRequestID uint      `json:"retrieval_request_id"`
+	StartTime          time.Time `json:"start_time"`
+	EndTime            time.Time `json:"end_time"`
+	Timestamp          time.Time `json:"timestamp"`
+}
+
+// RequestFootage records a retrieval request and pushes it to the camera. Cameras that miss the
+// push find it among their pending requests and upload against it.
+func (s *VideoService) RequestFootage(ctx context.Context, req FootageRequest, now time.Time) (*models.VideoRetrievalRequest, error) {
+	if !req.EndTime.After(req.StartTime) || req.EndTime.Sub(req.StartTime) > maxRetrievalWindow {
+		return nil, fmt.Errorf("%w: the window must end after it starts and last at most %s", ErrInvalidVideoRequest, maxRetrievalWindow)
+	}
+	if req.StartTime.After(now) {
+		return nil, fmt.Errorf("%w: the window has not started yet", ErrInvalidVideoRequest)
+	}
+	if req.EventType == "" {
+		req.EventType = models.VideoEventManual
+	}
+
+	db := s.db.WithContext(ctx)
+	var camera models.Camera
+	switch {
+	case req.CameraID != nil:
+		if err := db.First(&camera, *req.CameraID).Error; err != nil {
+			return nil, err
+		}
+	case req.VehicleID != nil:
+		if err := db.Where("vehicle_id = ?", *req.VehicleID).Order("last_heartbeat DESC").First(&camera).Error; err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: give a camera or a vehicle", ErrInvalidVideoRequest)
+	}
+
+	retrieval := models.VideoRetrievalRequest{
+		CameraID:      camera.ID,
+		VehicleID:     camera.VehicleID,
+		StartTime:     req.StartTime,
+		EndTime:       req.EndTime,
+		EventType:     req.EventType,
+		Reason:        strings.TrimSpace(req.Reason),
+		Status:        models.VideoRetrievalPending,
+		RequestedByID: req.RequestedByID,
+	}
+	if err := db.Omit("Camera").Create(&retrieval).Error; err != nil {
+		return nil, err
+	}
+
+	if s.mqttService != nil && s.mqttService.IsEnabled() {
+		err := s.mqttService.PublishCameraCommand(camera.SerialNumber, &CameraCommand{
+			Command:            "UPLOAD_FOOTAGE",
+			RetrievalRequestID: retrieval.ID,
+			StartTime:          retrieval.StartTime,
+			EndTime:            retrieval.EndTime,
+			Timestamp:          now,
+		})
+		if err != nil {
+			log.Printf("⚠️ Retrieval %d left for camera %s to poll: %v", retrieval.ID, camera.SerialNumber, err)
+		} else {
+			retrieval.Status, retrieval.SentAt = models.VideoRetrievalSent, &now
+			if err := db.Model(&retrieval).Updates(map[string]interface{}{"status": retrieval.Status, "sent_at": now}).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+	retrieval.Camera = &camera
+	return &retrieval, nil
+}
+
+// VideoRetrievalFilter narrows a retrieval request listing; zero values are ignored
+type VideoRetrievalFilter struct {
+	CameraSerial string
+	VehicleID    *uint
+	Statuses     []models.VideoRetrievalStatus
+}
+
+// GetRetrievalRequests lists retrieval requests, oldest first so cameras work through them in order
+func (s *VideoService) GetRetrievalRequests(ctx context.Context, filter VideoRetrievalFilter) ([]models.VideoRetrievalRequest, error) {
+	query := s.db.WithContext(ctx).Preload("Camera")
+	if filter.CameraSerial != "" {
+		camera, err := s.GetCameraBySerial(filter.CameraSerial)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("camera_id = ?", camera.ID)
+	}
+	if filter.VehicleID != nil {
+		query = query.Where("vehicle_id = ?", *filter.VehicleID)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	var requests []models.VideoRetrievalRequest
+	err := query.Order("created_at, id").Limit(100).Find(&requests).Error
+	return requests, err
+}
+
+// FailRetrievalRequest records a camera answering that it no longer holds the footage
+func (s *VideoService) FailRetrievalRequest(ctx context.Context, id uint, reason string) (*models.VideoRetrievalRequest, error) {
+	var retrieval models.VideoRetrievalRequest
+	if err := s.db.WithContext(ctx).First(&retrieval, id).Error; err != nil {
+		return nil, err
+	}
+	if retrieval.Status != models.VideoRetrievalPending && retrieval.Status != models.VideoRetrievalSent {
+		return nil, fmt.Errorf("%w: retrieval is %s", ErrVideoConflict, retrieval.Status)
+	}
+	retrieval.Status, retrieval.Error = models.VideoRetrievalFailed, strings.TrimSpace(reason)
+	if err := s.db.WithContext(ctx).Model(&retrieval).Select("Status", "Error").Updates(&retrieval).Error; err != nil {
+		return nil, err
+	}
+	return &retrieval, nil
+}
+
+// GetRetentionPolicies returns the retention for every event type, configured or default
+func (s *VideoService) GetRetentionPolicies(ctx context.Context) ([]models.VideoRetentionPolicy, error) {
+	var stored []models.VideoRetentionPolicy
+	if err := s.db.WithContext(ctx).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	byType := make(map[models.VideoEventType]models.VideoRetentionPolicy)
+	for eventType, days := range defaultClipRetention {
+		byType[eventType] = models.VideoRetentionPolicy{EventType: eventType, RetentionDays: days}
+	}
+	for _, policy := range stored {
+		byType[policy.EventType] = policy
+	}
+	policies := make([]models.VideoRetentionPolicy, 0, len(byType))
+	for _, policy := range byType {
+		policies = append(policies, policy)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].EventType < policies[j].EventType })
+	return policies, nil
+}
+
+// SetRetentionPolicy sets how many days clips of an event type are kept
+func (s *VideoService) SetRetentionPolicy(ctx context.Context, eventType models.VideoEventType, days int) (*models.VideoRetentionPolicy, error) {
+	if _, ok := defaultClipRetention[eventType]; !ok {
+		return nil, fmt.Errorf("%w: unknown event type %q", ErrInvalidVideoRequest, eventType)
+	}
+	if days < 1 {
+		return nil, fmt.Errorf("%w: clips must be kept at least a day", ErrInvalidVideoRequest)
+	}
+	var policies []models.VideoRetentionPolicy
+	if err := s.db.WithContext(ctx).Where("event_type = ?", eventType).Limit(1).Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	policy := models.VideoRetentionPolicy{EventType: eventType}
+	if len(policies) > 0 {
+		policy = policies[0]
+	}
+	policy.RetentionDays = days
+	if err := s.db.WithContext(ctx).Save(&policy).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// RetentionResult counts what a retention run cleaned up
+type RetentionResult struct {
+	ClipsExpired       int `json:"clips_expired"`
+	UploadsAbandoned   int `json:"uploads_abandoned"`
+	RetrievalsTimedOut int `json:"retrievals_timed_out"`
+}
+
+// ApplyRetention deletes the footage of clips past their event type's retention, except starred
+// clips and clips waiting to be coached, abandons stalled uploads and times out retrievals
+// cameras never answered
+func (s *VideoService) ApplyRetention(ctx context.Context, now time.Time) (*RetentionResult, error) {
+	db := s.db.WithContext(ctx)
+	result := &RetentionResult{}
+
+	policies, err := s.GetRetentionPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	awaitingCoaching := s.db.Model(&models.CoachingSession{}).Select("video_clip_id").
+		Where("video_clip_id IS NOT NULL AND status = ?", models.CoachingStatusPending)
+	for _, policy := range policies {
+		var clips []models.VideoClip
+		if err := db.Where("event_type = ? AND status = ? AND start_time < ?", policy.EventType, models.VideoClipAvailable, now.AddDate(0, 0, -policy.RetentionDays)).
+			Where("is_starred = ? AND id NOT IN (?)", false, awaitingCoaching).
+			Find(&clips).Error; err != nil {
+			return nil, err
+		}
+		for _, clip := range clips {
+			if err := s.expireClip(ctx, &clip, now); err != nil {
+				return result, err
+			}
+			result.ClipsExpired++
+		}
+	}
+
+	var stalled []models.VideoUpload
+	if err := db.Where("status = ? AND updated_at < ?", models.VideoUploadOpen, now.Add(-clipUploadTimeout)).
+		Find(&stalled).Error; err != nil {
+		return result, err
+	}
+	for i := range stalled {
+		if err := s.failClipUpload(ctx, &stalled[i], "no chunk received for "+clipUploadTimeout.String()); err != nil {
+			return result, err
+		}
+		result.UploadsAbandoned++
+	}
+
+	timedOut := db.Model(&models.VideoRetrievalRequest{}).
+		Where("status IN ? AND video_clip_id IS NULL AND created_at < ?",
+			[]models.VideoRetrievalStatus{models.VideoRetrievalPending, models.VideoRetrievalSent}, now.Add(-retrievalTimeout)).
+		Updates(map[string]interface{}{"status": models.VideoRetrievalExpired, "error": "the camera did not answer"})
+	if timedOut.Error != nil {
+		return result, timedOut.Error
+	}
+	result.RetrievalsTimedOut = int(timedOut.RowsAffected)
+	return result, nil
+}
+
+// expireClip deletes a clip's footage and thumbnail, keeping its record
+func (s *VideoService) expireClip(ctx context.Context, clip *models.VideoClip, now time.Time) error {
+	for _, key := range []string{clip.StorageKey, clip.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := s.storage.DeleteFile(key); err != nil {
+			log.Printf("⚠️ Failed to delete %s of expired clip %d: %v", key, clip.ID, err)
+		}
+	}
+	return s.db.WithContext(ctx).Model(clip).Updates(map[string]interface{}{
+		"status":        models.VideoClipExpired,
+		"storage_key":   "",
+		"storage_url":   "",
+		"thumbnail_key": "",
+		"thumbnail_url": "",
+		"expired_at":    now,
+	}).Error
+}
+
+// StartRetentionSweep applies clip retention on an interval
+func (s *VideoService) StartRetentionSweep(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			result, err := s.ApplyRetention(context.Background(), time.Now())
+			if err != nil {
+				log.Printf("❌ Video retention failed: %v", err)
+				continue
+			}
+			if result.ClipsExpired+result.UploadsAbandoned+result.RetrievalsTimedOut > 0 {
+				log.Printf("🎥 Video retention: %d clip(s) expired, %d upload(s) abandoned, %d retrieval(s) timed out",
+					result.ClipsExpired, result.UploadsAbandoned, result.RetrievalsTimedOut)
+			}
+		}
+	}()
+}
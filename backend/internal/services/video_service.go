@@ -1,22 +1,81 @@
 package services
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/fleetflow/backend/internal/models"
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrInvalidVideoRequest is returned for uploads, retrievals and policies that fail validation
+	ErrInvalidVideoRequest = errors.New("invalid video request")
+	// ErrVideoConflict is returned when a clip, upload or retrieval is not in a state that allows the change
+	ErrVideoConflict = errors.New("video conflict")
+	// ErrInvalidMediaSignature is returned for playback URLs that were tampered with or have expired
+	ErrInvalidMediaSignature = errors.New("invalid or expired media signature")
+	// ErrClipUnavailable is returned when a clip's footage is not in storage
+	ErrClipUnavailable = errors.New("clip footage is not available")
+)
+
+const (
+	// VideoMediaPath is where signed playback URLs point
+	VideoMediaPath = "/api/v1/video/media"
+	// defaultPlaybackExpiry is how long a playback URL stays valid unless configured otherwise
+	defaultPlaybackExpiry = 15 * time.Minute
+)
+
+// Media kinds a playback URL can be signed for
+const (
+	MediaKindVideo     = "video"
+	MediaKindThumbnail = "thumbnail"
+)
+
 // VideoService handles video pipeline and AI events
 type VideoService struct {
-	db *gorm.DB
+	db               *gorm.DB
+	storage          StorageProvider
+	mqttService      *MQTTService
+	thumbnails       ThumbnailExtractor
+	urlSigningKey    []byte
+	playbackExpiry   time.Duration
+	heartbeatTimeout time.Duration
 }
 
 // NewVideoService creates a new video service
-func NewVideoService(db *gorm.DB) *VideoService {
+func NewVideoService(db *gorm.DB, storage StorageProvider, mqttService *MQTTService) *VideoService {
 	return &VideoService{
-		db: db,
+		db:               db,
+		storage:          storage,
+		mqttService:      mqttService,
+		thumbnails:       NewFFmpegThumbnailExtractor(),
+		playbackExpiry:   defaultPlaybackExpiry,
+		heartbeatTimeout: defaultHeartbeatTimeout,
+	}
+}
+
+// SetStorageProvider changes where clip footage is stored
+func (s *VideoService) SetStorageProvider(storage StorageProvider) {
+	s.storage = storage
+}
+
+// SetThumbnailExtractor changes how thumbnails are taken from uploaded clips; nil skips them
+func (s *VideoService) SetThumbnailExtractor(thumbnails ThumbnailExtractor) {
+	s.thumbnails = thumbnails
+}
+
+// SetURLSigningKey sets the key playback URLs are signed with and how long they last
+func (s *VideoService) SetURLSigningKey(key string, expiry time.Duration) {
+	s.urlSigningKey = []byte(key)
+	if expiry > 0 {
+		s.playbackExpiry = expiry
 	}
 }
 
@@ -37,11 +96,12 @@ func (s *VideoService) GetCameraBySerial(serial string) (*models.Camera, error)
 	return &camera, err
 }
 
-// ProcessAIEvent handles incoming AI detections from the camera
-func (s *VideoService) ProcessAIEvent(cameraSerial string, eventType models.VideoEventType, timestamp time.Time, detections []models.AIDetection) error {
+// ProcessAIEvent records the clip a camera captured around an AI detection. The footage follows
+// as an upload against the returned clip.
+func (s *VideoService) ProcessAIEvent(cameraSerial string, eventType models.VideoEventType, timestamp time.Time, detections []models.AIDetection) (*models.VideoClip, error) {
 	camera, err := s.GetCameraBySerial(cameraSerial)
 	if err != nil {
-		return fmt.Errorf("camera not found: %w", err)
+		return nil, fmt.Errorf("camera not found: %w", err)
 	}
 
 	// Create a clip record
@@ -52,25 +112,30 @@ func (s *VideoService) ProcessAIEvent(cameraSerial string, eventType models.Vide
 		StartTime:   timestamp.Add(-10 * time.Second), // 10s buffer before
 		EndTime:     timestamp.Add(10 * time.Second),  // 10s buffer after
 		DurationSec: 20,
+		Status:      models.VideoClipPendingUpload,
 		IsViewed:    false,
 		CreatedAt:   time.Now(),
-		// StorageURL would be generated here or passed in
-		StorageURL: fmt.Sprintf("https://storage.fleetflow.io/clips/%s/%d.mp4", cameraSerial, timestamp.Unix()),
 	}
 
-	if err := s.db.Create(clip).Error; err != nil {
-		return fmt.Errorf("failed to create clip: %w", err)
+	if err := s.attributeClip(s.db, clip); err != nil {
+		return nil, fmt.Errorf("failed to attribute clip: %w", err)
+	}
+	if err := s.db.Omit("Camera", "Vehicle", "Driver", "Trip").Create(clip).Error; err != nil {
+		return nil, fmt.Errorf("failed to create clip: %w", err)
+	}
+	if err := s.linkClipEvents(s.db, clip); err != nil {
+		return nil, fmt.Errorf("failed to link clip to safety events: %w", err)
 	}
 
 	// Save detections
 	for _, d := range detections {
 		d.VideoClipID = clip.ID
-		if err := s.db.Create(&d).Error; err != nil {
-			return fmt.Errorf("failed to save detection: %w", err)
+		if err := s.db.Omit("VideoClip").Create(&d).Error; err != nil {
+			return nil, fmt.Errorf("failed to save detection: %w", err)
 		}
 	}
 
-	return nil
+	return clip, nil
 }
 
 // GetClips returns a list of video clips with filters
@@ -88,3 +153,73 @@ func (s *VideoService) GetClips(vehicleID uint, eventType string) ([]models.Vide
 	err := query.Order("created_at desc").Limit(50).Find(&clips).Error
 	return clips, err
 }
+
+// GetClip returns a clip with its camera
+func (s *VideoService) GetClip(ctx context.Context, id uint) (*models.VideoClip, error) {
+	var clip models.VideoClip
+	if err := s.db.WithContext(ctx).Preload("Camera").Preload("Vehicle").First(&clip, id).Error; err != nil {
+		return nil, err
+	}
+	return &clip, nil
+}
+
+// PlaybackURLs are signed, time-limited links to a clip's footage and thumbnail
+type PlaybackURLs struct {
+	ClipID       uint      `json:"clip_id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// GetPlaybackURLs signs links to an uploaded clip's footage and thumbnail
+func (s *VideoService) GetPlaybackURLs(ctx context.Context, clip *models.VideoClip, now time.Time) (*PlaybackURLs, error) {
+	if clip.Status != models.VideoClipAvailable || clip.StorageKey == "" {
+		return nil, fmt.Errorf("%w: clip is %s", ErrClipUnavailable, clip.Status)
+	}
+	expires := now.Add(s.playbackExpiry).Truncate(time.Second)
+	urls := &PlaybackURLs{
+		ClipID:    clip.ID,
+		URL:       s.signedMediaURL(clip.ID, MediaKindVideo, expires),
+		ExpiresAt: expires,
+	}
+	if clip.ThumbnailKey != "" {
+		urls.ThumbnailURL = s.signedMediaURL(clip.ID, MediaKindThumbnail, expires)
+	}
+	return urls, nil
+}
+
+func (s *VideoService) signedMediaURL(clipID uint, kind string, expires time.Time) string {
+	return fmt.Sprintf("%s/%d/%s?expires=%d&signature=%s", VideoMediaPath, clipID, kind, expires.Unix(),
+		s.mediaSignature(clipID, kind, expires.Unix()))
+}
+
+func (s *VideoService) mediaSignature(clipID uint, kind string, expires int64) string {
+	mac := hmac.New(sha256.New, s.urlSigningKey)
+	mac.Write([]byte(strconv.FormatUint(uint64(clipID), 10) + "/" + kind + "/" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// OpenSignedMedia checks a playback URL's signature and expiry and returns the footage or thumbnail
+// with its content type
+func (s *VideoService) OpenSignedMedia(ctx context.Context, clipID uint, kind string, expires int64, signature string, now time.Time) (*models.VideoClip, []byte, string, error) {
+	expected := s.mediaSignature(clipID, kind, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) || now.Unix() > expires {
+		return nil, nil, "", ErrInvalidMediaSignature
+	}
+	clip, err := s.GetClip(ctx, clipID)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	key, contentType := clip.StorageKey, clip.ContentType
+	if kind == MediaKindThumbnail {
+		key, contentType = clip.ThumbnailKey, "image/jpeg"
+	}
+	if clip.Status != models.VideoClipAvailable || key == "" {
+		return nil, nil, "", fmt.Errorf("%w: clip is %s", ErrClipUnavailable, clip.Status)
+	}
+	data, err := s.storage.DownloadFile(key)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return clip, data, contentType, nil
+}
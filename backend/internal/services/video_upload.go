@@ -0,0 +1,425 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxClipSize caps a single clip upload
+	MaxClipSize = 512 << 20
+	// MaxClipChunkSize caps one chunk of an upload
+	MaxClipChunkSize = 16 << 20
+	// clipUploadTimeout is how long an upload may go without a chunk before it is abandoned
+	clipUploadTimeout = 24 * time.Hour
+)
+
+// clipExtensions are the container formats cameras may upload, by content type
+var clipExtensions = map[string]string{
+	"video/mp4":        ".mp4",
+	"video/quicktime":  ".mov",
+	"video/x-matroska": ".mkv",
+	"video/mp2t":       ".ts",
+	"video/webm":       ".webm",
+}
+
+// ThumbnailExtractor takes a still image from a clip's footage
+type ThumbnailExtractor interface {
+	ExtractThumbnail(ctx context.Context, video []byte) ([]byte, error)
+}
+
+// FFmpegThumbnailExtractor grabs the frame a second into the clip with ffmpeg
+type FFmpegThumbnailExtractor struct {
+	path string
+}
+
+// NewFFmpegThumbnailExtractor finds ffmpeg on the PATH; without it clips are stored without thumbnails
+func NewFFmpegThumbnailExtractor() ThumbnailExtractor {
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		log.Printf("⚠️ ffmpeg not found, video clips will be stored without thumbnails")
+		return nil
+	}
+	return &FFmpegThumbnailExtractor{path: path}
+}
+
+// ExtractThumbnail pipes the clip through ffmpeg and reads back one JPEG frame
+func (e *FFmpegThumbnailExtractor) ExtractThumbnail(ctx context.Context, video []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var out, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.path, "-hide_banner", "-loglevel", "error",
+		"-i", "pipe:0", "-ss", "1", "-frames:v", "1", "-vf", "scale=480:-2", "-f", "image2", "-c:v", "mjpeg", "pipe:1")
+	cmd.Stdin = bytes.NewReader(video)
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if out.Len() == 0 {
+		return nil, fmt.Errorf("ffmpeg produced no frame")
+	}
+	return out.Bytes(), nil
+}
+
+// ClipUploadRequest starts an upload of a clip's footage from its camera. The footage is for a
+// clip recorded for an AI event, for footage that was asked of the camera, or for a new clip.
+type ClipUploadRequest struct {
+	CameraSerial       string
+	EventType          models.VideoEventType
+	StartTime          time.Time
+	EndTime            time.Time
+	ContentType        string
+	FileSize           int64
+	SHA256             string
+	ClipID             *uint
+	RetrievalRequestID *uint
+}
+
+// StartClipUpload opens a resumable upload, creating the clip it fills unless one is named
+func (s *VideoService) StartClipUpload(ctx context.Context, req ClipUploadRequest) (*models.VideoUpload, error) {
+	if req.ContentType == "" {
+		req.ContentType = "video/mp4"
+	}
+	req.ContentType = strings.ToLower(req.ContentType)
+	if _, ok := clipExtensions[req.ContentType]; !ok {
+		return nil, fmt.Errorf("%w: unsupported content type %q", ErrInvalidVideoRequest, req.ContentType)
+	}
+	if req.FileSize <= 0 || req.FileSize > MaxClipSize {
+		return nil, fmt.Errorf("%w: file size must be between 1 byte and %d MB", ErrInvalidVideoRequest, MaxClipSize>>20)
+	}
+	if req.SHA256 != "" {
+		if digest, err := hex.DecodeString(req.SHA256); err != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("%w: sha256 must be a hex digest", ErrInvalidVideoRequest)
+		}
+		req.SHA256 = strings.ToLower(req.SHA256)
+	}
+
+	db := s.db.WithContext(ctx)
+	camera, err := s.GetCameraBySerial(req.CameraSerial)
+	if err != nil {
+		return nil, err
+	}
+
+	var clip models.VideoClip
+	switch {
+	case req.ClipID != nil:
+		if err := db.First(&clip, *req.ClipID).Error; err != nil {
+			return nil, err
+		}
+		if clip.CameraID != camera.ID {
+			return nil, fmt.Errorf("%w: clip %d was not recorded by camera %s", ErrInvalidVideoRequest, clip.ID, camera.SerialNumber)
+		}
+		if clip.Status != models.VideoClipPendingUpload && clip.Status != models.VideoClipFailed {
+			return nil, fmt.Errorf("%w: clip %d is %s", ErrVideoConflict, clip.ID, clip.Status)
+		}
+	case req.RetrievalRequestID != nil:
+		var retrieval models.VideoRetrievalRequest
+		if err := db.First(&retrieval, *req.RetrievalRequestID).Error; err != nil {
+			return nil, err
+		}
+		if retrieval.CameraID != camera.ID {
+			return nil, fmt.Errorf("%w: retrieval %d was not asked of camera %s", ErrInvalidVideoRequest, retrieval.ID, camera.SerialNumber)
+		}
+		if retrieval.Status != models.VideoRetrievalPending && retrieval.Status != models.VideoRetrievalSent {
+			return nil, fmt.Errorf("%w: retrieval %d is %s", ErrVideoConflict, retrieval.ID, retrieval.Status)
+		}
+		if retrieval.VideoClipID != nil {
+			if err := db.First(&clip, *retrieval.VideoClipID).Error; err != nil {
+				return nil, err
+			}
+			break
+		}
+		clip = models.VideoClip{
+			CameraID:  camera.ID,
+			VehicleID: camera.VehicleID,
+			EventType: retrieval.EventType,
+			StartTime: retrieval.StartTime,
+			EndTime:   retrieval.EndTime,
+		}
+		if !req.StartTime.IsZero() && !req.EndTime.IsZero() {
+			clip.StartTime, clip.EndTime = req.StartTime, req.EndTime
+		}
+	default:
+		if req.EventType == "" || req.StartTime.IsZero() || !req.EndTime.After(req.StartTime) {
+			return nil, fmt.Errorf("%w: a new clip needs an event type and a start before its end", ErrInvalidVideoRequest)
+		}
+		clip = models.VideoClip{
+			CameraID:  camera.ID,
+			VehicleID: camera.VehicleID,
+			EventType: req.EventType,
+			StartTime: req.StartTime,
+			EndTime:   req.EndTime,
+		}
+	}
+
+	if clip.ID != 0 {
+		var open int64
+		if err := db.Model(&models.VideoUpload{}).Where("video_clip_id = ? AND status = ?", clip.ID, models.VideoUploadOpen).
+			Count(&open).Error; err != nil {
+			return nil, err
+		}
+		if open > 0 {
+			return nil, fmt.Errorf("%w: clip %d already has an upload in progress; resume it", ErrVideoConflict, clip.ID)
+		}
+	}
+
+	uploadID, err := randomURLToken(16)
+	if err != nil {
+		return nil, err
+	}
+	upload := models.VideoUpload{
+		UploadID:    uploadID,
+		CameraID:    camera.ID,
+		ContentType: req.ContentType,
+		TotalBytes:  req.FileSize,
+		SHA256:      req.SHA256,
+		PartKeys:    []string{},
+		Status:      models.VideoUploadOpen,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		isNew := clip.ID == 0
+		if isNew {
+			if err := s.attributeClip(tx, &clip); err != nil {
+				return err
+			}
+		}
+		clip.Status = models.VideoClipUploading
+		clip.ContentType = req.ContentType
+		clip.DurationSec = int(clip.EndTime.Sub(clip.StartTime).Seconds())
+		if err := tx.Omit("Camera", "Vehicle", "Driver", "Trip", "Detections").Save(&clip).Error; err != nil {
+			return err
+		}
+		if isNew {
+			if err := s.linkClipEvents(tx, &clip); err != nil {
+				return err
+			}
+		}
+		if req.RetrievalRequestID != nil {
+			if err := tx.Model(&models.VideoRetrievalRequest{}).Where("id = ?", *req.RetrievalRequestID).
+				Update("video_clip_id", clip.ID).Error; err != nil {
+				return err
+			}
+		}
+		upload.VideoClipID = clip.ID
+		return tx.Create(&upload).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// GetClipUpload returns an upload, for a camera to find where to resume from
+func (s *VideoService) GetClipUpload(ctx context.Context, uploadID string) (*models.VideoUpload, error) {
+	var upload models.VideoUpload
+	if err := s.db.WithContext(ctx).Where("upload_id = ?", uploadID).First(&upload).Error; err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// WriteClipChunk stages the chunk of an upload starting at offset, which must be where the
+// upload left off. The chunk that completes the file assembles, checks and stores the clip.
+// If storing it fails, an empty chunk at the end of the file tries again.
+func (s *VideoService) WriteClipChunk(ctx context.Context, uploadID string, offset int64, data []byte) (*models.VideoUpload, error) {
+	upload, err := s.GetClipUpload(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Status != models.VideoUploadOpen {
+		return upload, fmt.Errorf("%w: upload is %s", ErrVideoConflict, upload.Status)
+	}
+	if offset != upload.ReceivedBytes {
+		return upload, fmt.Errorf("%w: expected offset %d, got %d", ErrVideoConflict, upload.ReceivedBytes, offset)
+	}
+	if offset == upload.TotalBytes && len(data) == 0 {
+		if err := s.completeClipUpload(ctx, upload); err != nil {
+			return upload, err
+		}
+		return upload, nil
+	}
+	if len(data) == 0 || len(data) > MaxClipChunkSize {
+		return upload, fmt.Errorf("%w: chunks must be between 1 byte and %d MB", ErrInvalidVideoRequest, MaxClipChunkSize>>20)
+	}
+	if offset+int64(len(data)) > upload.TotalBytes {
+		return upload, fmt.Errorf("%w: chunk runs past the declared size of %d bytes", ErrInvalidVideoRequest, upload.TotalBytes)
+	}
+
+	// Each attempt is staged under its own key, so a chunk that loses a race for its offset
+	// cannot overwrite the one that won
+	suffix, err := randomURLToken(6)
+	if err != nil {
+		return upload, err
+	}
+	key := uploadPartKey(uploadID, offset, suffix)
+	if _, err := s.storage.UploadFile(key, data, "application/octet-stream"); err != nil {
+		return upload, err
+	}
+	upload.PartKeys = append(upload.PartKeys, key)
+	upload.ReceivedBytes += int64(len(data))
+	// Only one of two chunks racing for the same offset is kept
+	result := s.db.WithContext(ctx).Model(upload).Where("received_bytes = ?", offset).
+		Select("PartKeys", "ReceivedBytes").Updates(upload)
+	if result.Error != nil {
+		s.deleteStagedChunk(uploadID, key)
+		return upload, result.Error
+	}
+	if result.RowsAffected == 0 {
+		s.deleteStagedChunk(uploadID, key)
+		if current, err := s.GetClipUpload(ctx, uploadID); err == nil {
+			upload = current
+		}
+		return upload, fmt.Errorf("%w: another chunk was written at offset %d", ErrVideoConflict, offset)
+	}
+
+	if upload.ReceivedBytes == upload.TotalBytes {
+		if err := s.completeClipUpload(ctx, upload); err != nil {
+			return upload, err
+		}
+	}
+	return upload, nil
+}
+
+// AbortClipUpload gives up on an open upload, discarding its chunks
+func (s *VideoService) AbortClipUpload(ctx context.Context, uploadID string) error {
+	upload, err := s.GetClipUpload(ctx, uploadID)
+	if err != nil {
+		return err
+	}
+	if upload.Status != models.VideoUploadOpen {
+		return fmt.Errorf("%w: upload is %s", ErrVideoConflict, upload.Status)
+	}
+	return s.failClipUpload(ctx, upload, "aborted by the camera")
+}
+
+// completeClipUpload joins the staged chunks, verifies them and stores the clip and its thumbnail
+func (s *VideoService) completeClipUpload(ctx context.Context, upload *models.VideoUpload) error {
+	file := make([]byte, 0, upload.TotalBytes)
+	for _, key := range upload.PartKeys {
+		part, err := s.storage.DownloadFile(key)
+		if err != nil {
+			return err
+		}
+		file = append(file, part...)
+	}
+	if int64(len(file)) != upload.TotalBytes {
+		reason := fmt.Sprintf("assembled %d of %d bytes", len(file), upload.TotalBytes)
+		if err := s.failClipUpload(ctx, upload, reason); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s, upload the clip again", ErrInvalidVideoRequest, reason)
+	}
+	sum := sha256.Sum256(file)
+	digest := hex.EncodeToString(sum[:])
+	if upload.SHA256 != "" && digest != upload.SHA256 {
+		if err := s.failClipUpload(ctx, upload, "checksum mismatch"); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: checksum mismatch, upload the clip again", ErrInvalidVideoRequest)
+	}
+
+	var clip models.VideoClip
+	if err := s.db.WithContext(ctx).Preload("Camera").First(&clip, upload.VideoClipID).Error; err != nil {
+		return err
+	}
+	key := fmt.Sprintf("video/clips/%s/%d%s", clip.Camera.SerialNumber, clip.ID, clipExtensions[upload.ContentType])
+	url, err := s.storage.UploadFile(key, file, upload.ContentType)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":       models.VideoClipAvailable,
+		"storage_key":  key,
+		"storage_url":  url,
+		"content_type": upload.ContentType,
+		"size_bytes":   upload.TotalBytes,
+		"sha256":       digest,
+		"uploaded_at":  now,
+	}
+	if s.thumbnails != nil {
+		if thumbnail, err := s.thumbnails.ExtractThumbnail(ctx, file); err != nil {
+			log.Printf("⚠️ No thumbnail for clip %d: %v", clip.ID, err)
+		} else {
+			thumbnailKey := fmt.Sprintf("video/clips/%s/%d.jpg", clip.Camera.SerialNumber, clip.ID)
+			thumbnailURL, err := s.storage.UploadFile(thumbnailKey, thumbnail, "image/jpeg")
+			if err != nil {
+				return err
+			}
+			updates["thumbnail_key"] = thumbnailKey
+			updates["thumbnail_url"] = thumbnailURL
+		}
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.VideoClip{}).Where("id = ?", clip.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.VideoRetrievalRequest{}).
+			Where("video_clip_id = ? AND status IN ?", clip.ID, []models.VideoRetrievalStatus{models.VideoRetrievalPending, models.VideoRetrievalSent}).
+			Updates(map[string]interface{}{"status": models.VideoRetrievalFulfilled, "fulfilled_at": now}).Error; err != nil {
+			return err
+		}
+		// Two retries may race to complete the same upload; only one stores it
+		result := tx.Model(upload).Where("status = ?", models.VideoUploadOpen).Update("status", models.VideoUploadComplete)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: upload was already completed", ErrVideoConflict)
+		}
+		upload.Status = models.VideoUploadComplete
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.deleteUploadParts(upload)
+	log.Printf("🎥 Stored clip %d from camera %s (%d bytes)", clip.ID, clip.Camera.SerialNumber, upload.TotalBytes)
+	return nil
+}
+
+// failClipUpload closes an upload that cannot complete and marks its clip for another attempt
+func (s *VideoService) failClipUpload(ctx context.Context, upload *models.VideoUpload, reason string) error {
+	upload.Status, upload.Error = models.VideoUploadFailed, reason
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(upload).Select("Status", "Error").Updates(upload).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.VideoClip{}).Where("id = ?", upload.VideoClipID).Update("status", models.VideoClipFailed).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.deleteUploadParts(upload)
+	return nil
+}
+
+func (s *VideoService) deleteUploadParts(upload *models.VideoUpload) {
+	for _, key := range upload.PartKeys {
+		s.deleteStagedChunk(upload.UploadID, key)
+	}
+}
+
+func (s *VideoService) deleteStagedChunk(uploadID, key string) {
+	if err := s.storage.DeleteFile(key); err != nil {
+		log.Printf("⚠️ Failed to delete staged chunk of upload %s: %v", uploadID, err)
+	}
+}
+
+func uploadPartKey(uploadID string, offset int64, suffix string) string {
+	return fmt.Sprintf("video/uploads/%s/%012d-%s", uploadID, offset, suffix)
+}
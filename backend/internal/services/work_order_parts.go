@@ -0,0 +1,445 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInsufficientStock is returned when a part cannot be reserved from the available stock
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrWorkOrderClosed is returned when parts or labour are added to a completed or cancelled work order
+	ErrWorkOrderClosed = errors.New("work order is closed")
+	// ErrInvalidWorkOrderItem is returned for bad quantities, hours, rates or mechanics
+	ErrInvalidWorkOrderItem = errors.New("invalid work order item")
+)
+
+// VehiclePartsHistory is every part fitted to a vehicle, newest first
+type VehiclePartsHistory struct {
+	VehicleID  uint                   `json:"vehicle_id"`
+	Parts      []models.WorkOrderPart `json:"parts"`
+	TotalParts int                    `json:"total_parts"`
+	TotalCost  float64                `json:"total_cost"`
+}
+
+// openWorkOrder loads a work order that parts and labour may still be added to
+func openWorkOrder(tx *gorm.DB, workOrderID uint) (*models.WorkOrder, error) {
+	var workOrder models.WorkOrder
+	if err := tx.First(&workOrder, workOrderID).Error; err != nil {
+		return nil, err
+	}
+	if workOrder.Status == "COMPLETED" || workOrder.Status == "CANCELLED" {
+		return nil, fmt.Errorf("%w: work order %d is %s", ErrWorkOrderClosed, workOrder.ID, workOrder.Status)
+	}
+	return &workOrder, nil
+}
+
+// GetWorkOrder returns a work order with its parts and labour
+func (s *MaintenanceService) GetWorkOrder(ctx context.Context, workOrderID uint) (*models.WorkOrder, error) {
+	var workOrder models.WorkOrder
+	if err := s.db.WithContext(ctx).Preload("MaintenanceTask").Preload("Parts", "status <> ?", models.WorkOrderPartReleased).
+		Preload("Parts.InventoryItem").Preload("Labor").Preload("Labor.Mechanic").
+		First(&workOrder, workOrderID).Error; err != nil {
+		return nil, err
+	}
+	return &workOrder, nil
+}
+
+// AddWorkOrderPart reserves stock of an inventory item for a work order
+func (s *MaintenanceService) AddWorkOrderPart(ctx context.Context, workOrderID, itemID uint, quantity int, userID *uint) (*models.WorkOrderPart, error) {
+	if quantity <= 0 {
+		return nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidWorkOrderItem)
+	}
+
+	var part models.WorkOrderPart
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		workOrder, err := openWorkOrder(tx, workOrderID)
+		if err != nil {
+			return err
+		}
+		var item models.InventoryItem
+		if err := tx.First(&item, itemID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: inventory item %d not found", ErrInvalidWorkOrderItem, itemID)
+			}
+			return err
+		}
+
+		// Reserve only if the stock is still there when the update runs
+		result := tx.Model(&models.InventoryItem{}).
+			Where("id = ? AND quantity - reserved_quantity >= ?", item.ID, quantity).
+			Update("reserved_quantity", gorm.Expr("reserved_quantity + ?", quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: %d of %s available, %d requested", ErrInsufficientStock, item.AvailableQuantity(), item.SKU, quantity)
+		}
+
+		part = models.WorkOrderPart{
+			WorkOrderID:     workOrder.ID,
+			VehicleID:       workOrder.VehicleID,
+			InventoryItemID: item.ID,
+			Quantity:        quantity,
+			UnitCost:        item.UnitCost,
+			TotalCost:       round2(item.UnitCost * float64(quantity)),
+			Status:          models.WorkOrderPartReserved,
+			CreatedBy:       userID,
+		}
+		if err := tx.Omit("InventoryItem").Create(&part).Error; err != nil {
+			return err
+		}
+		if err := s.suggestPurchaseIfLow(tx, item.ID, &workOrder.ID); err != nil {
+			return err
+		}
+		return s.recalculateWorkOrderCosts(tx, workOrder.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &part, nil
+}
+
+// ReleaseWorkOrderPart returns a reserved part to stock
+func (s *MaintenanceService) ReleaseWorkOrderPart(ctx context.Context, workOrderID, partID uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := openWorkOrder(tx, workOrderID); err != nil {
+			return err
+		}
+		var part models.WorkOrderPart
+		if err := tx.Where("id = ? AND work_order_id = ?", partID, workOrderID).First(&part).Error; err != nil {
+			return err
+		}
+		if part.Status != models.WorkOrderPartReserved {
+			return fmt.Errorf("%w: part %d is %s", ErrInvalidWorkOrderItem, part.ID, part.Status)
+		}
+		released, err := releaseWorkOrderPart(tx, &part)
+		if err != nil {
+			return err
+		}
+		if !released {
+			return fmt.Errorf("%w: part %d is no longer reserved", ErrInvalidWorkOrderItem, part.ID)
+		}
+		return s.recalculateWorkOrderCosts(tx, workOrderID)
+	})
+}
+
+// ConsumeWorkOrderPart takes a reserved part out of stock before the work order is resolved
+func (s *MaintenanceService) ConsumeWorkOrderPart(ctx context.Context, workOrderID, partID uint) (*models.WorkOrderPart, error) {
+	var part models.WorkOrderPart
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := openWorkOrder(tx, workOrderID); err != nil {
+			return err
+		}
+		if err := tx.Where("id = ? AND work_order_id = ?", partID, workOrderID).First(&part).Error; err != nil {
+			return err
+		}
+		if part.Status != models.WorkOrderPartReserved {
+			return fmt.Errorf("%w: part %d is %s", ErrInvalidWorkOrderItem, part.ID, part.Status)
+		}
+		consumed, err := s.consumeWorkOrderPart(tx, &part, time.Now())
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			return fmt.Errorf("%w: part %d is no longer reserved", ErrInvalidWorkOrderItem, part.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &part, nil
+}
+
+// consumeWorkOrderPart moves a reserved part out of stock. It reports false, changing nothing,
+// when another request already consumed or released the part.
+func (s *MaintenanceService) consumeWorkOrderPart(tx *gorm.DB, part *models.WorkOrderPart, now time.Time) (bool, error) {
+	result := tx.Model(&models.WorkOrderPart{}).Where("id = ? AND status = ?", part.ID, models.WorkOrderPartReserved).
+		Updates(map[string]interface{}{"status": models.WorkOrderPartConsumed, "consumed_at": now})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return false, nil
+	}
+	part.Status = models.WorkOrderPartConsumed
+	part.ConsumedAt = &now
+
+	if err := tx.Model(&models.InventoryItem{}).Where("id = ?", part.InventoryItemID).Updates(map[string]interface{}{
+		"quantity":          gorm.Expr("quantity - ?", part.Quantity),
+		"reserved_quantity": gorm.Expr("reserved_quantity - ?", part.Quantity),
+	}).Error; err != nil {
+		return false, err
+	}
+	return true, s.suggestPurchaseIfLow(tx, part.InventoryItemID, &part.WorkOrderID)
+}
+
+// releaseWorkOrderPart returns a reserved part's stock. It reports false, changing nothing,
+// when another request already consumed or released the part.
+func releaseWorkOrderPart(tx *gorm.DB, part *models.WorkOrderPart) (bool, error) {
+	result := tx.Model(&models.WorkOrderPart{}).Where("id = ? AND status = ?", part.ID, models.WorkOrderPartReserved).
+		Update("status", models.WorkOrderPartReleased)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return false, nil
+	}
+	part.Status = models.WorkOrderPartReleased
+
+	if err := tx.Model(&models.InventoryItem{}).Where("id = ?", part.InventoryItemID).
+		Update("reserved_quantity", gorm.Expr("reserved_quantity - ?", part.Quantity)).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// releaseWorkOrderParts returns every part still reserved for a work order, e.g. when it is cancelled
+func releaseWorkOrderParts(tx *gorm.DB, workOrderID uint) error {
+	var parts []models.WorkOrderPart
+	if err := tx.Where("work_order_id = ? AND status = ?", workOrderID, models.WorkOrderPartReserved).Find(&parts).Error; err != nil {
+		return err
+	}
+	for i := range parts {
+		if _, err := releaseWorkOrderPart(tx, &parts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddWorkOrderLabor records a mechanic's time on a work order, at the mechanic's standard rate
+// unless hourlyRate is given
+func (s *MaintenanceService) AddWorkOrderLabor(ctx context.Context, workOrderID, mechanicID uint, hours float64, hourlyRate *float64, description string, performedAt *time.Time) (*models.WorkOrderLabor, error) {
+	if hours <= 0 || hours > 24 {
+		return nil, fmt.Errorf("%w: hours must be between 0 and 24", ErrInvalidWorkOrderItem)
+	}
+
+	var labor models.WorkOrderLabor
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		workOrder, err := openWorkOrder(tx, workOrderID)
+		if err != nil {
+			return err
+		}
+		var mechanic models.UserAccount
+		if err := tx.First(&mechanic, mechanicID).Error; err != nil || mechanic.Role != models.RoleMechanic {
+			return fmt.Errorf("%w: user %d is not a mechanic", ErrInvalidWorkOrderItem, mechanicID)
+		}
+
+		rate := 0.0
+		if hourlyRate != nil {
+			rate = *hourlyRate
+		} else {
+			var rates []models.MechanicRate
+			if err := tx.Where("mechanic_id = ?", mechanicID).Limit(1).Find(&rates).Error; err != nil {
+				return err
+			}
+			if len(rates) == 0 {
+				return fmt.Errorf("%w: mechanic %d has no hourly rate; set one or give hourly_rate", ErrInvalidWorkOrderItem, mechanicID)
+			}
+			rate = rates[0].HourlyRate
+		}
+		if rate < 0 {
+			return fmt.Errorf("%w: hourly rate cannot be negative", ErrInvalidWorkOrderItem)
+		}
+
+		at := time.Now()
+		if performedAt != nil {
+			at = *performedAt
+		}
+		labor = models.WorkOrderLabor{
+			WorkOrderID: workOrder.ID,
+			MechanicID:  mechanicID,
+			Hours:       hours,
+			HourlyRate:  rate,
+			TotalCost:   round2(hours * rate),
+			Description: description,
+			PerformedAt: at,
+		}
+		if err := tx.Omit("Mechanic").Create(&labor).Error; err != nil {
+			return err
+		}
+		return s.recalculateWorkOrderCosts(tx, workOrder.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &labor, nil
+}
+
+// SetMechanicRate sets a mechanic's standard hourly rate
+func (s *MaintenanceService) SetMechanicRate(ctx context.Context, mechanicID uint, hourlyRate float64, userID *uint) (*models.MechanicRate, error) {
+	if hourlyRate < 0 {
+		return nil, fmt.Errorf("%w: hourly rate cannot be negative", ErrInvalidWorkOrderItem)
+	}
+	db := s.db.WithContext(ctx)
+	var mechanic models.UserAccount
+	if err := db.First(&mechanic, mechanicID).Error; err != nil {
+		return nil, err
+	}
+	if mechanic.Role != models.RoleMechanic {
+		return nil, fmt.Errorf("%w: user %d is not a mechanic", ErrInvalidWorkOrderItem, mechanicID)
+	}
+
+	var rates []models.MechanicRate
+	if err := db.Where("mechanic_id = ?", mechanicID).Limit(1).Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	rate := models.MechanicRate{MechanicID: mechanicID}
+	if len(rates) > 0 {
+		rate = rates[0]
+	}
+	rate.HourlyRate = hourlyRate
+	rate.UpdatedBy = userID
+	if err := db.Save(&rate).Error; err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// recalculateWorkOrderCosts totals the parts and labour recorded on a work order
+func (s *MaintenanceService) recalculateWorkOrderCosts(tx *gorm.DB, workOrderID uint) error {
+	var costParts, costLabor float64
+	if err := tx.Model(&models.WorkOrderPart{}).Select("COALESCE(SUM(total_cost), 0)").
+		Where("work_order_id = ? AND status <> ?", workOrderID, models.WorkOrderPartReleased).Scan(&costParts).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.WorkOrderLabor{}).Select("COALESCE(SUM(total_cost), 0)").
+		Where("work_order_id = ?", workOrderID).Scan(&costLabor).Error; err != nil {
+		return err
+	}
+	costParts, costLabor = round2(costParts), round2(costLabor)
+	return tx.Model(&models.WorkOrder{}).Where("id = ?", workOrderID).Updates(map[string]interface{}{
+		"cost_parts": costParts,
+		"cost_labor": costLabor,
+		"total_cost": round2(costParts + costLabor),
+	}).Error
+}
+
+// hasWorkOrderItems reports whether costs of a work order come from its parts and labour
+func hasWorkOrderItems(tx *gorm.DB, workOrderID uint) (bool, error) {
+	var parts, labor int64
+	if err := tx.Model(&models.WorkOrderPart{}).Where("work_order_id = ? AND status <> ?", workOrderID, models.WorkOrderPartReleased).
+		Count(&parts).Error; err != nil {
+		return false, err
+	}
+	if err := tx.Model(&models.WorkOrderLabor{}).Where("work_order_id = ?", workOrderID).Count(&labor).Error; err != nil {
+		return false, err
+	}
+	return parts+labor > 0, nil
+}
+
+// suggestPurchaseIfLow opens, or tops up, a purchase suggestion when an item's available stock
+// is below its minimum
+func (s *MaintenanceService) suggestPurchaseIfLow(tx *gorm.DB, itemID uint, workOrderID *uint) error {
+	var item models.InventoryItem
+	if err := tx.First(&item, itemID).Error; err != nil {
+		return err
+	}
+	available := item.AvailableQuantity()
+	if available >= item.MinQuantity {
+		return nil
+	}
+
+	// Refill to twice the minimum unless the item has a preferred order size
+	suggested := 2*item.MinQuantity - available
+	if item.ReorderQuantity > 0 {
+		suggested = int(math.Max(float64(item.ReorderQuantity), float64(item.MinQuantity-available)))
+	}
+
+	var pending []models.PurchaseOrderSuggestion
+	if err := tx.Where("inventory_item_id = ? AND status IN ?", item.ID,
+		[]models.PurchaseOrderSuggestionStatus{models.PurchaseSuggestionOpen, models.PurchaseSuggestionOrdered}).
+		Limit(1).Find(&pending).Error; err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		if pending[0].Status == models.PurchaseSuggestionOrdered {
+			return nil
+		}
+		return tx.Model(&pending[0]).Updates(map[string]interface{}{
+			"available_quantity": available,
+			"suggested_quantity": suggested,
+			"unit_cost":          item.UnitCost,
+			"estimated_cost":     round2(float64(suggested) * item.UnitCost),
+		}).Error
+	}
+
+	return tx.Omit("InventoryItem").Create(&models.PurchaseOrderSuggestion{
+		InventoryItemID:   item.ID,
+		Status:            models.PurchaseSuggestionOpen,
+		AvailableQuantity: available,
+		MinQuantity:       item.MinQuantity,
+		SuggestedQuantity: suggested,
+		UnitCost:          item.UnitCost,
+		EstimatedCost:     round2(float64(suggested) * item.UnitCost),
+		WorkOrderID:       workOrderID,
+	}).Error
+}
+
+// GetPurchaseOrderSuggestions lists restocking suggestions, open ones by default
+func (s *MaintenanceService) GetPurchaseOrderSuggestions(ctx context.Context, status string) ([]models.PurchaseOrderSuggestion, error) {
+	if status == "" {
+		status = string(models.PurchaseSuggestionOpen)
+	}
+	var suggestions []models.PurchaseOrderSuggestion
+	if err := s.db.WithContext(ctx).Where("status = ?", status).Preload("InventoryItem").
+		Order("created_at DESC").Find(&suggestions).Error; err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// UpdatePurchaseOrderSuggestion marks a suggestion ordered or dismissed
+func (s *MaintenanceService) UpdatePurchaseOrderSuggestion(ctx context.Context, id uint, status models.PurchaseOrderSuggestionStatus, userID *uint) (*models.PurchaseOrderSuggestion, error) {
+	db := s.db.WithContext(ctx)
+	var suggestion models.PurchaseOrderSuggestion
+	if err := db.First(&suggestion, id).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	switch {
+	case status == models.PurchaseSuggestionOrdered && suggestion.Status == models.PurchaseSuggestionOpen:
+		suggestion.OrderedAt = &now
+	case status == models.PurchaseSuggestionDismissed &&
+		(suggestion.Status == models.PurchaseSuggestionOpen || suggestion.Status == models.PurchaseSuggestionOrdered):
+		suggestion.ClosedAt = &now
+	default:
+		return nil, fmt.Errorf("%w: cannot move a %s suggestion to %s", ErrInvalidWorkOrderItem, suggestion.Status, status)
+	}
+	suggestion.Status = status
+	suggestion.UpdatedBy = userID
+	if err := db.Omit("InventoryItem").Save(&suggestion).Error; err != nil {
+		return nil, err
+	}
+	return &suggestion, nil
+}
+
+// GetVehiclePartsHistory lists the parts consumed on a vehicle's work orders
+func (s *MaintenanceService) GetVehiclePartsHistory(ctx context.Context, vehicleID uint) (*VehiclePartsHistory, error) {
+	db := s.db.WithContext(ctx)
+	var vehicle models.Vehicle
+	if err := db.Select("id").First(&vehicle, vehicleID).Error; err != nil {
+		return nil, err
+	}
+
+	history := &VehiclePartsHistory{VehicleID: vehicleID, Parts: []models.WorkOrderPart{}}
+	if err := db.Where("vehicle_id = ? AND status = ?", vehicleID, models.WorkOrderPartConsumed).
+		Preload("InventoryItem").Order("consumed_at DESC, id DESC").Find(&history.Parts).Error; err != nil {
+		return nil, err
+	}
+	for _, part := range history.Parts {
+		history.TotalParts += part.Quantity
+		history.TotalCost += part.TotalCost
+	}
+	history.TotalCost = round2(history.TotalCost)
+	return history, nil
+}
@@ -1,7 +1,7 @@
 package simulator
 
 import (
-	"math"
+	"github.com/fleetflow/backend/internal/models"
 )
 
 type RouteEngine struct {
@@ -32,7 +32,7 @@ func (r *RouteEngine) UpdatePosition(speedKmh float64, deltaSeconds float64) (fl
 	p1 := r.RoutePoints[r.CurrentIdx]
 	p2 := r.RoutePoints[(r.CurrentIdx+1)%len(r.RoutePoints)] // Loop route
 
-	distKm := haversine(p1.Lat, p1.Lon, p2.Lat, p2.Lon)
+	distKm := models.HaversineMeters(p1.Lat, p1.Lon, p2.Lat, p2.Lon) / 1000
 	if distKm == 0 {
 		r.CurrentIdx = (r.CurrentIdx + 1) % len(r.RoutePoints)
 		return p1.Lat, p1.Lon
@@ -58,20 +58,6 @@ func (r *RouteEngine) UpdatePosition(speedKmh float64, deltaSeconds float64) (fl
 	return newLat, newLon
 }
 
-// Haversine formula to calculate distance between two points in km
-func haversine(lat1, lon1, lat2, lon2 float64) float64 {
-	const R = 6371 // Earth radius in km
-	dLat := (lat2 - lat1) * (math.Pi / 180.0)
-	dLon := (lon2 - lon1) * (math.Pi / 180.0)
-
-	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
-		math.Cos(lat1*(math.Pi/180.0))*math.Cos(lat2*(math.Pi/180.0))*
-			math.Sin(dLon/2)*math.Sin(dLon/2)
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-
-	return R * c
-}
-
 // Predefined Routes (Bangalore)
 var RouteBangaloreCity = []Point{
 	{12.9716, 77.5946}, // MG Road
@@ -0,0 +1,128 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/fleetflow/backend/internal/config"
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditHashChain(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	orgID := uint(7)
+	audit := tf.Services.AuditService
+
+	setup := func(t *testing.T, records int) string {
+		tf.CleanDatabase()
+		admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+		require.NoError(t, err)
+		token, err := tf.GenerateJWTToken(admin)
+		require.NoError(t, err)
+
+		for i := 1; i <= records; i++ {
+			require.NoError(t, audit.LogAction(models.AuditActionFuelEventVerified, models.AuditSeverityInfo,
+				fmt.Sprintf("Fuel event %d verified", i), nil, map[string]int{"litres": 40 + i},
+				&models.AuditContext{OrganizationID: &orgID}))
+		}
+		return token
+	}
+
+	verify := func(t *testing.T, token, query string) map[string]interface{} {
+		w := sendJSON(tf, "GET", "/api/v1/admin/audit-logs/verify?organization_id=7"+query, nil, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return decodeBody(t, w)["chains"].([]interface{})[0].(map[string]interface{})
+	}
+
+	issueKinds := func(report map[string]interface{}) []string {
+		var kinds []string
+		issues, _ := report["issues"].([]interface{})
+		for _, issue := range issues {
+			kinds = append(kinds, issue.(map[string]interface{})["kind"].(string))
+		}
+		return kinds
+	}
+
+	t.Run("Records Are Linked Per Organization", func(t *testing.T) {
+		token := setup(t, 3)
+
+		var logs []models.AuditLog
+		require.NoError(t, tf.DB.Where("organization_id = ?", orgID).Order("sequence").Find(&logs).Error)
+		require.Len(t, logs, 3)
+		assert.Equal(t, models.AuditGenesisHash, logs[0].PrevHash)
+		assert.Equal(t, logs[0].Hash, logs[1].PrevHash)
+		assert.Equal(t, uint64(3), logs[2].Sequence)
+
+		report := verify(t, token, "")
+		assert.Equal(t, true, report["valid"])
+		assert.Equal(t, float64(3), report["verified_records"])
+	})
+
+	t.Run("Edited Record Is Detected", func(t *testing.T) {
+		token := setup(t, 3)
+		require.NoError(t, tf.DB.Exec("UPDATE audit_logs SET new_values = ? WHERE organization_id = ? AND sequence = 2",
+			`{"litres":4}`, orgID).Error)
+
+		report := verify(t, token, "")
+		assert.Equal(t, false, report["valid"])
+		assert.Equal(t, []string{services.AuditIssueHashMismatch}, issueKinds(report))
+	})
+
+	t.Run("Deleted Records Are Detected", func(t *testing.T) {
+		token := setup(t, 4)
+		w := sendJSON(tf, "POST", "/api/v1/admin/audit-logs/checkpoints?organization_id=7", nil, token)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		require.NoError(t, tf.DB.Exec("DELETE FROM audit_logs WHERE organization_id = ? AND sequence IN (2, 4)", orgID).Error)
+
+		report := verify(t, token, "")
+		assert.Equal(t, false, report["valid"])
+		kinds := issueKinds(report)
+		assert.Contains(t, kinds, services.AuditIssueGap)
+		assert.Contains(t, kinds, services.AuditIssueTruncated)
+	})
+
+	t.Run("Forged Checkpoint Is Detected", func(t *testing.T) {
+		token := setup(t, 2)
+		_, err := audit.CreateCheckpoint(orgID)
+		require.NoError(t, err)
+		require.NoError(t, tf.DB.Exec("UPDATE audit_checkpoints SET sequence = 1").Error)
+
+		report := verify(t, token, "")
+		assert.Contains(t, issueKinds(report), services.AuditIssueCheckpointSig)
+	})
+
+	t.Run("Archived Records Stay Verifiable", func(t *testing.T) {
+		token := setup(t, 3)
+		audit.SetArchiveStorage(services.NewLocalStorageService(&config.Config{LocalStoragePath: t.TempDir()}))
+		defer audit.SetArchiveStorage(tf.Services.StorageService)
+
+		// A negative retention archives everything written so far
+		archives, err := audit.ArchiveOldAuditLogs(-1)
+		require.NoError(t, err)
+		require.Len(t, archives, 1)
+		assert.Equal(t, uint64(1), archives[0].FromSequence)
+		assert.Equal(t, uint64(3), archives[0].ToSequence)
+
+		var remaining int64
+		tf.DB.Model(&models.AuditLog{}).Where("organization_id = ?", orgID).Count(&remaining)
+		assert.Zero(t, remaining)
+
+		// The chain continues from the archive
+		require.NoError(t, audit.LogAction(models.AuditActionFuelAlertResolved, models.AuditSeverityInfo,
+			"Fuel alert resolved", nil, nil, &models.AuditContext{OrganizationID: &orgID}))
+
+		report := verify(t, token, "&archives=true")
+		assert.Equal(t, true, report["valid"], report["issues"])
+		assert.Equal(t, float64(1), report["archives_verified"])
+		assert.Equal(t, float64(3), report["archived_through"])
+		assert.Equal(t, float64(4), report["last_sequence"])
+	})
+}
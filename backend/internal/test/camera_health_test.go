@@ -0,0 +1,142 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCameraHealth(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+	videoService := tf.Services.VideoService
+	videoService.SetHeartbeatTimeout(5 * time.Minute)
+
+	now := time.Now().Truncate(time.Second)
+	on, off := true, false
+	newCamera := func(serial, plate string, ignition *bool, ignitionAt time.Time) *models.Camera {
+		vehicle, err := tf.CreateTestVehicle(plate, "TRUCK")
+		require.NoError(t, err)
+		camera := &models.Camera{SerialNumber: serial, VehicleID: &vehicle.ID, Status: models.CameraStatusOffline, CreatedAt: now.Add(-30 * time.Minute)}
+		require.NoError(t, videoService.RegisterCamera(camera))
+		if ignition != nil {
+			require.NoError(t, tf.DB.Create(&models.TrackerDevice{
+				IMEI: "35620910000" + serial[len(serial)-4:], VehicleID: &vehicle.ID, Protocol: "teltonika",
+				IsActive: true, Ignition: ignition, IgnitionAt: &ignitionAt,
+			}).Error)
+		}
+		return camera
+	}
+	driving := newCamera("DC-1001", "MH12CH1001", nil, time.Time{})
+	parked := newCamera("DC-1002", "MH12CH1002", &off, now.Add(-9*time.Minute))
+	starting := newCamera("DC-1003", "MH12CH1003", &on, now.Add(-time.Minute))
+
+	heartbeat := func(serial string, payload map[string]interface{}) *models.Camera {
+		payload["serial"] = serial
+		w := sendJSON(tf, "POST", "/api/v1/video/cameras/heartbeat", payload, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var camera models.Camera
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &camera))
+		return &camera
+	}
+
+	// A clean heartbeat brings the camera online; faults mark it malfunctioning until they clear
+	camera := heartbeat(driving.SerialNumber, map[string]interface{}{"timestamp": now.Add(-2 * time.Minute), "firmware_ver": "2.4.1", "sd_card_status": "OK"})
+	assert.Equal(t, models.CameraStatusOnline, camera.Status)
+	assert.Equal(t, "2.4.1", camera.FirmwareVer)
+	camera = heartbeat(driving.SerialNumber, map[string]interface{}{
+		"timestamp": now.Add(-time.Minute), "sd_card_status": "ERROR", "lens_obstructed": true, "recording": false,
+	})
+	assert.Equal(t, models.CameraStatusMalfunction, camera.Status)
+	assert.Equal(t, []string{models.CameraFaultLensObstructed, models.CameraFaultNotRecording, models.CameraFaultSDCardFailure}, camera.Faults)
+	// A heartbeat delivered late changes nothing
+	camera = heartbeat(driving.SerialNumber, map[string]interface{}{"timestamp": now.Add(-90 * time.Second)})
+	assert.Equal(t, models.CameraStatusMalfunction, camera.Status)
+	camera = heartbeat(driving.SerialNumber, map[string]interface{}{"timestamp": now})
+	assert.Equal(t, models.CameraStatusOnline, camera.Status)
+	assert.Empty(t, camera.Faults)
+
+	// A camera that stops recording because the vehicle was switched off is not faulty
+	camera = heartbeat(parked.SerialNumber, map[string]interface{}{"timestamp": now.Add(-10 * time.Minute), "ignition": false, "recording": false})
+	assert.Equal(t, models.CameraStatusOnline, camera.Status)
+	heartbeat(starting.SerialNumber, map[string]interface{}{"timestamp": now.Add(-10 * time.Minute), "ignition": false})
+	w := sendJSON(tf, "POST", "/api/v1/video/cameras/heartbeat", map[string]interface{}{"serial": "DC-9999"}, token)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Silent cameras: the parked one is on standby, the one just switched on gets time to boot
+	changed, err := videoService.CheckCameraHealth(t.Context(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, changed)
+	status := func(camera *models.Camera) models.CameraStatus {
+		var stored models.Camera
+		require.NoError(t, tf.DB.First(&stored, camera.ID).Error)
+		return stored.Status
+	}
+	assert.Equal(t, models.CameraStatusOnline, status(driving))
+	assert.Equal(t, models.CameraStatusStandby, status(parked))
+	assert.Equal(t, models.CameraStatusOnline, status(starting))
+
+	later := now.Add(10 * time.Minute)
+	changed, err = videoService.CheckCameraHealth(t.Context(), later)
+	require.NoError(t, err)
+	assert.Equal(t, 2, changed)
+	assert.Equal(t, models.CameraStatusOffline, status(driving))
+	assert.Equal(t, models.CameraStatusStandby, status(parked))
+	assert.Equal(t, models.CameraStatusOffline, status(starting))
+
+	// Parking the vehicle of an offline camera puts it on standby
+	require.NoError(t, tf.DB.Model(&models.TrackerDevice{}).Where("vehicle_id = ?", *starting.VehicleID).
+		Updates(map[string]interface{}{"ignition": false, "ignition_at": later}).Error)
+	changed, err = videoService.CheckCameraHealth(t.Context(), later.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, changed)
+	assert.Equal(t, models.CameraStatusStandby, status(starting))
+
+	report, err := videoService.GetCameraHealthReport(t.Context(), now.Add(-time.Hour), later.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Total)
+	assert.Equal(t, 1, report.ByStatus[models.CameraStatusOffline])
+	assert.Equal(t, 2, report.ByStatus[models.CameraStatusStandby])
+	assert.Equal(t, 1, report.Firmware["2.4.1"])
+	require.Len(t, report.Cameras, 3)
+	assert.Equal(t, driving.ID, report.Cameras[0].CameraID)
+	assert.Equal(t, 2, report.Cameras[0].Incidents)
+	// Offline from registration until its first heartbeat, a minute malfunctioning and two offline
+	assert.Equal(t, 28+1+2, report.Cameras[0].DowntimeMinutes)
+	assert.Less(t, report.Cameras[0].AvailabilityPct, 100.0)
+	for _, health := range report.Cameras[1:] {
+		assert.Equal(t, models.CameraStatusStandby, health.Status)
+	}
+
+	w = sendJSON(tf, "GET", "/api/v1/video/cameras/health?days=7", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var served services.CameraHealthReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &served))
+	assert.Equal(t, 3, served.Total)
+	assert.Equal(t, http.StatusBadRequest, sendJSON(tf, "GET", "/api/v1/video/cameras/health?days=0", nil, token).Code)
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/video/cameras/%d/health", driving.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var history struct {
+		Changes []models.CameraStatusChange `json:"changes"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	require.Len(t, history.Changes, 4)
+	assert.Equal(t, models.CameraStatusOffline, history.Changes[0].ToStatus)
+	assert.Equal(t, models.CameraStatusMalfunction, history.Changes[2].ToStatus)
+	assert.Contains(t, history.Changes[2].Faults, models.CameraFaultSDCardFailure)
+}
@@ -0,0 +1,236 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoachingWorkflow(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	adminToken, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+	coach, err := tf.CreateTestUser("+919800000301", models.RoleDispatcher)
+	require.NoError(t, err)
+	driver, err := tf.CreateTestDriver("Coached Driver", "+919800000302", "MH1420120301")
+	require.NoError(t, err)
+	other, err := tf.CreateTestDriver("Other Driver", "+919800000303", "MH1420120302")
+	require.NoError(t, err)
+	driverUser, err := tf.CreateTestUser("+919800000302", models.RoleDriver)
+	require.NoError(t, err)
+	driverUser.DriverID = &driver.ID
+	require.NoError(t, tf.DB.Save(driverUser).Error)
+	driverToken, err := tf.GenerateJWTToken(driverUser)
+	require.NoError(t, err)
+	vehicle, err := tf.CreateTestVehicle("MH12CO0001", "TRUCK")
+	require.NoError(t, err)
+
+	now := time.Now()
+	newEvent := func(driverID uint, eventType models.SafetyEventType) models.SafetyEvent {
+		event := models.SafetyEvent{
+			VehicleID: vehicle.ID, DriverID: &driverID, Type: eventType, Severity: models.SeverityHigh,
+			Latitude: 18.52, Longitude: 73.85, Timestamp: now.Add(-2 * time.Hour),
+		}
+		require.NoError(t, tf.DB.Create(&event).Error)
+		return event
+	}
+	braking := newEvent(driver.ID, models.SafetyEventHarshBraking)
+	misread := newEvent(driver.ID, models.SafetyEventHarshBraking)
+	othersEvent := newEvent(other.ID, models.SafetyEventSpeeding)
+
+	decodeSession := func(w interface{ Bytes() []byte }) models.CoachingSession {
+		var session models.CoachingSession
+		require.NoError(t, json.Unmarshal(w.Bytes(), &session))
+		return session
+	}
+
+	// Queue the braking event; it is marked viewed and can only be queued once
+	w := sendJSON(tf, "POST", "/api/v1/safety/coaching", map[string]interface{}{
+		"safety_event_id": braking.ID, "note": "Braked hard behind a bus",
+	}, adminToken)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	session := decodeSession(w.Body)
+	assert.Equal(t, driver.ID, session.DriverID)
+	assert.Equal(t, models.CoachingStatusPending, session.Status)
+	require.Len(t, session.Notes, 1)
+	var stored models.SafetyEvent
+	require.NoError(t, tf.DB.First(&stored, braking.ID).Error)
+	assert.True(t, stored.IsViewed)
+
+	w = sendJSON(tf, "POST", "/api/v1/safety/coaching", map[string]interface{}{"safety_event_id": braking.ID}, adminToken)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = sendJSON(tf, "POST", "/api/v1/safety/coaching", map[string]interface{}{}, adminToken)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendJSON(tf, "POST", "/api/v1/safety/coaching", map[string]interface{}{"safety_event_id": othersEvent.ID}, driverToken)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Drivers cannot coach; dispatchers can
+	w = sendJSON(tf, "PUT", fmt.Sprintf("/api/v1/safety/coaching/%d/assign", session.ID), map[string]interface{}{"coach_id": driverUser.ID}, adminToken)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendJSON(tf, "PUT", fmt.Sprintf("/api/v1/safety/coaching/%d/assign", session.ID), map[string]interface{}{"coach_id": coach.ID}, adminToken)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	session = decodeSession(w.Body)
+	require.NotNil(t, session.CoachID)
+	assert.Equal(t, coach.ID, *session.CoachID)
+
+	w = sendJSON(tf, "POST", fmt.Sprintf("/api/v1/safety/coaching/%d/notes", session.ID), map[string]interface{}{"body": "Call scheduled"}, adminToken)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// Nothing to acknowledge until the coach has been through it
+	w = sendJSON(tf, "POST", fmt.Sprintf("/api/v1/safety/coaching/%d/acknowledge", session.ID), nil, driverToken)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = sendJSON(tf, "POST", fmt.Sprintf("/api/v1/safety/coaching/%d/resolve", session.ID), map[string]interface{}{
+		"status": "COACHED", "note": "Went over following distance",
+	}, adminToken)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	session = decodeSession(w.Body)
+	assert.Equal(t, models.CoachingStatusCoached, session.Status)
+	assert.NotNil(t, session.ScoreBefore)
+	assert.Len(t, session.Notes, 3)
+	w = sendJSON(tf, "POST", fmt.Sprintf("/api/v1/safety/coaching/%d/resolve", session.ID), map[string]interface{}{"status": "DISMISSED"}, adminToken)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// The driver sees and acknowledges their own session, once, and no one else's
+	w = sendJSON(tf, "POST", "/api/v1/safety/coaching", map[string]interface{}{"safety_event_id": othersEvent.ID}, adminToken)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	othersSession := decodeSession(w.Body)
+
+	w = sendJSON(tf, "GET", "/api/v1/safety/coaching", nil, driverToken)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var queue struct {
+		Sessions []models.CoachingSession `json:"sessions"`
+		Total    int64                    `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &queue))
+	assert.EqualValues(t, 1, queue.Total)
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/safety/coaching/%d", othersSession.ID), nil, driverToken)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = sendJSON(tf, "POST", fmt.Sprintf("/api/v1/safety/coaching/%d/acknowledge", othersSession.ID), nil, driverToken)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = sendJSON(tf, "POST", fmt.Sprintf("/api/v1/safety/coaching/%d/acknowledge", session.ID), map[string]interface{}{"comment": "Understood"}, driverToken)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	session = decodeSession(w.Body)
+	assert.NotNil(t, session.DriverAcknowledgedAt)
+	assert.Equal(t, "Understood", session.DriverComment)
+	w = sendJSON(tf, "POST", fmt.Sprintf("/api/v1/safety/coaching/%d/acknowledge", session.ID), nil, driverToken)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// A false positive stops counting against the driver
+	scores, err := tf.Services.SafetyService.RecalculateDriverScores(t.Context(), 30, now)
+	require.NoError(t, err)
+	brakingEvents := func(scores []models.DriverScore) int {
+		for _, score := range scores {
+			if score.DriverID == driver.ID {
+				return score.BrakingEvents
+			}
+		}
+		return -1
+	}
+	assert.Equal(t, 2, brakingEvents(scores))
+	w = sendJSON(tf, "POST", "/api/v1/safety/coaching", map[string]interface{}{"safety_event_id": misread.ID}, adminToken)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = sendJSON(tf, "POST", fmt.Sprintf("/api/v1/safety/coaching/%d/resolve", decodeSession(w.Body).ID), map[string]interface{}{"status": "FALSE_POSITIVE"}, adminToken)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	scores, err = tf.Services.SafetyService.RecalculateDriverScores(t.Context(), 30, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, brakingEvents(scores))
+
+	// A month after coaching, the clean month that followed shows as an improvement
+	require.NoError(t, tf.DB.Model(&models.CoachingSession{}).Where("id = ?", session.ID).Updates(map[string]interface{}{
+		"resolved_at": now.AddDate(0, 0, -40), "score_before": 60,
+	}).Error)
+	// Reading the summary does not measure; the background job does
+	w = sendJSON(tf, "GET", "/api/v1/safety/coaching/effectiveness", nil, adminToken)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var summary services.CoachingEffectiveness
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, 0, summary.Measured)
+	var unmeasured models.CoachingSession
+	require.NoError(t, tf.DB.First(&unmeasured, session.ID).Error)
+	assert.Nil(t, unmeasured.EffectMeasuredAt)
+
+	measured, err := tf.Services.SafetyService.MeasureCoachingEffectiveness(t.Context(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, measured)
+	w = sendJSON(tf, "GET", "/api/v1/safety/coaching/effectiveness", nil, adminToken)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	summary = services.CoachingEffectiveness{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, 1, summary.Coached)
+	assert.Equal(t, 1, summary.Acknowledged)
+	assert.Equal(t, 1, summary.FalsePositives)
+	assert.Equal(t, 1, summary.Pending)
+	assert.Equal(t, 1, summary.Measured)
+	assert.Equal(t, 1, summary.Improved)
+	require.NotNil(t, summary.AverageChange)
+	assert.Equal(t, 40.0, *summary.AverageChange)
+	w = sendJSON(tf, "GET", "/api/v1/safety/coaching/effectiveness", nil, driverToken)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestCoachingVideoClip(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+	driver, err := tf.CreateTestDriver("Clip Driver", "+919800000311", "MH1420120311")
+	require.NoError(t, err)
+	vehicle, err := tf.CreateTestVehicle("MH12CO0002", "VAN")
+	require.NoError(t, err)
+	trip, err := tf.CreateTestTrip("Pune", "Satara", driver.ID, vehicle.ID)
+	require.NoError(t, err)
+	start := time.Now().Add(-time.Hour)
+	require.NoError(t, tf.DB.Model(trip).Updates(map[string]interface{}{
+		"status": models.TripStatusInProgress, "actual_pickup_time": start,
+	}).Error)
+
+	camera := models.Camera{VehicleID: &vehicle.ID, SerialNumber: "CAM-CO-0001"}
+	require.NoError(t, tf.DB.Create(&camera).Error)
+	clip := models.VideoClip{
+		CameraID: camera.ID, VehicleID: &vehicle.ID, EventType: models.VideoEventDistraction,
+		StartTime: start.Add(20 * time.Minute), EndTime: start.Add(20*time.Minute + 15*time.Second),
+	}
+	require.NoError(t, tf.DB.Omit("Camera").Create(&clip).Error)
+
+	// The clip has no driver of its own; the one on the trip at the time is coached
+	w := sendJSON(tf, "POST", "/api/v1/safety/coaching", map[string]interface{}{"video_clip_id": clip.ID}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var session models.CoachingSession
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &session))
+	assert.Equal(t, driver.ID, session.DriverID)
+	require.NotNil(t, session.VideoClip)
+	assert.True(t, session.VideoClip.IsViewed)
+
+	w = sendJSON(tf, "POST", "/api/v1/safety/coaching", map[string]interface{}{"video_clip_id": 999999}, token)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Two coaches closing the session at once: only one outcome is recorded
+	results := make(chan int, 2)
+	for _, status := range []string{"COACHED", "DISMISSED"} {
+		go func(status string) {
+			results <- sendJSON(tf, "POST", fmt.Sprintf("/api/v1/safety/coaching/%d/resolve", session.ID), map[string]interface{}{"status": status}, token).Code
+		}(status)
+	}
+	statuses := []int{<-results, <-results}
+	assert.ElementsMatch(t, []int{http.StatusOK, http.StatusConflict}, statuses)
+}
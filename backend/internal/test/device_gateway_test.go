@@ -0,0 +1,200 @@
+package test
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// trackerFixture reads a hex capture shared with the decoder unit tests
+func trackerFixture(t *testing.T, name string) []byte {
+	raw, err := os.ReadFile("../services/testdata/tracker/" + name)
+	require.NoError(t, err)
+	payload, err := hex.DecodeString(strings.Join(strings.Fields(string(raw)), ""))
+	require.NoError(t, err)
+	return payload
+}
+
+// dialTracker serves one end of an in-memory connection with the gateway and returns the device's end
+func dialTracker(tf *TestFramework, protocol string) (net.Conn, <-chan struct{}) {
+	device, server := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		tf.Services.DeviceGateway.ServeConn(server, protocol)
+		close(done)
+	}()
+	device.SetDeadline(time.Now().Add(10 * time.Second))
+	return device, done
+}
+
+func TestDeviceGatewayTeltonika(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+	vehicle, err := tf.CreateTestVehicle("MH12TK8801", "TRUCK")
+	require.NoError(t, err)
+
+	// Registration validates the IMEI, protocol and vehicle
+	w := sendJSON(tf, "PUT", "/api/v1/telemetry/devices", map[string]interface{}{"imei": "12345", "protocol": "teltonika"}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendJSON(tf, "PUT", "/api/v1/telemetry/devices", map[string]interface{}{"imei": "356307042441013", "protocol": "meitrack"}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendJSON(tf, "PUT", "/api/v1/telemetry/devices", map[string]interface{}{
+		"imei": "356307042441013", "protocol": "Teltonika", "vehicle_id": vehicle.ID, "model": "FMC130",
+	}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "teltonika", decodeBody(t, w)["protocol"])
+
+	// The fixture is the IMEI handshake followed by a Codec 8E packet of two records
+	stream := trackerFixture(t, "teltonika_codec8e_stream.hex")
+	conn, done := dialTracker(tf, "teltonika")
+	_, err = conn.Write(stream[:17])
+	require.NoError(t, err)
+	reply := make([]byte, 1)
+	_, err = io.ReadFull(conn, reply)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x01}, reply)
+
+	_, err = conn.Write(stream[17:])
+	require.NoError(t, err)
+	reply = make([]byte, 4)
+	_, err = io.ReadFull(conn, reply)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 2}, reply)
+	conn.Close()
+	<-done
+
+	// Only the record with a fix becomes a location; both carry telemetry
+	var pings []models.LocationPing
+	require.NoError(t, tf.DB.Where("vehicle_id = ?", vehicle.ID).Find(&pings).Error)
+	require.Len(t, pings, 1)
+	assert.InDelta(t, 18.5204, pings[0].Latitude, 1e-6)
+	assert.Equal(t, "GPS_DEVICE", pings[0].Source)
+
+	var logs []models.TelemetryLog
+	require.NoError(t, tf.DB.Where("vehicle_id = ?", vehicle.ID).Order("timestamp").Find(&logs).Error)
+	require.Len(t, logs, 2)
+	require.NotNil(t, logs[0].EngineRPM)
+	assert.Equal(t, 1450, *logs[0].EngineRPM)
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/telemetry/devices?vehicle_id=%d", vehicle.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var devices []models.TrackerDevice
+	require.NoError(t, tf.DB.Where("vehicle_id = ?", vehicle.ID).Find(&devices).Error)
+	require.Len(t, devices, 1)
+	assert.NotNil(t, devices[0].LastSeenAt)
+	assert.Equal(t, "pipe", devices[0].LastRemoteAddr)
+	assert.EqualValues(t, 2, devices[0].FramesReceived)
+	require.NotNil(t, devices[0].Ignition)
+	assert.False(t, *devices[0].Ignition) // The later record was parked
+
+	// An unknown unit is refused and recorded for assignment
+	unknown := append([]byte{0x00, 0x0F}, "352093086403655"...)
+	conn, done = dialTracker(tf, "teltonika")
+	_, err = conn.Write(unknown)
+	require.NoError(t, err)
+	reply = make([]byte, 1)
+	_, err = io.ReadFull(conn, reply)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x00}, reply)
+	<-done
+	conn.Close()
+
+	w = sendJSON(tf, "GET", "/api/v1/telemetry/devices?unassigned=true", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var unassigned []models.TrackerDevice
+	require.NoError(t, tf.DB.Where("vehicle_id IS NULL").Find(&unassigned).Error)
+	require.Len(t, unassigned, 1)
+	assert.Equal(t, "352093086403655", unassigned[0].IMEI)
+	assert.EqualValues(t, 1, unassigned[0].FramesRejected)
+	assert.Contains(t, w.Body.String(), "352093086403655")
+
+	// Assigning it lets its UDP reports through
+	w = sendJSON(tf, "PUT", "/api/v1/telemetry/devices", map[string]interface{}{
+		"imei": "352093086403655", "protocol": "teltonika", "vehicle_id": vehicle.ID,
+	}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	ack := tf.Services.DeviceGateway.HandleDatagram("teltonika", trackerFixture(t, "teltonika_udp.hex"), "10.0.0.9:5027")
+	assert.Equal(t, []byte{0x00, 0x05, 0xCA, 0xFE, 0x01, 0x05, 0x01}, ack)
+
+	w = sendJSON(tf, "DELETE", fmt.Sprintf("/api/v1/telemetry/devices/%d", unassigned[0].ID), nil, token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = sendJSON(tf, "DELETE", fmt.Sprintf("/api/v1/telemetry/devices/%d", unassigned[0].ID), nil, token)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDeviceGatewayGT06AndDecode(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+	vehicle, err := tf.CreateTestVehicle("MH12GT0601", "VAN")
+	require.NoError(t, err)
+
+	w := sendJSON(tf, "PUT", "/api/v1/telemetry/devices", map[string]interface{}{
+		"imei": "123456789012345", "protocol": "gt06", "vehicle_id": vehicle.ID,
+	}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Login, two locations and a heartbeat; the login and heartbeat are acknowledged
+	conn, done := dialTracker(tf, "gt06")
+	go func() {
+		conn.Write(trackerFixture(t, "gt06_stream.hex"))
+	}()
+	reply := make([]byte, 20)
+	_, err = io.ReadFull(conn, reply)
+	require.NoError(t, err)
+	assert.Equal(t, "787805010001D9DC0D0A", strings.ToUpper(hex.EncodeToString(reply[:10])))
+	assert.Equal(t, byte(0x13), reply[13])
+	conn.Close()
+	<-done
+
+	var pings []models.LocationPing
+	require.NoError(t, tf.DB.Where("vehicle_id = ?", vehicle.ID).Find(&pings).Error)
+	require.Len(t, pings, 1)
+	assert.InDelta(t, 73.8567, pings[0].Longitude, 1e-6)
+
+	// The Concox location's mileage is telemetry
+	var logs []models.TelemetryLog
+	require.NoError(t, tf.DB.Where("vehicle_id = ?", vehicle.ID).Find(&logs).Error)
+	require.Len(t, logs, 1)
+	require.NotNil(t, logs[0].Odometer)
+	assert.InDelta(t, 45.678, *logs[0].Odometer, 1e-9)
+
+	// Captures decode without being stored
+	w = postJSON(tf, "/api/v1/telemetry/devices/decode", map[string]interface{}{
+		"protocol": "gt06", "payload": "78780D01 01234567 89012345 00018CDD 0D0A",
+	}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"imei":"123456789012345"`)
+	w = postJSON(tf, "/api/v1/telemetry/devices/decode", map[string]interface{}{
+		"protocol": "gt06", "payload": "78780D01012345678901234500018CDE0D0A",
+	}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = postJSON(tf, "/api/v1/telemetry/devices/decode", map[string]interface{}{
+		"protocol": "queclink", "payload": "+RESP:GTFRI,02010B,135790246811220,,0,0,1,1,4.3,92,70.0,121.354335,31.222073,20090214013254,0460,0000,18d8,6141,00,2000.0,20090214093254,11F0$",
+	}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"latitude":31.222073`)
+}
@@ -0,0 +1,173 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDriverSafetyScoring(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+
+	harsh, err := tf.CreateTestDriver("Harsh Braker", "+919800000101", "MH1420110001")
+	require.NoError(t, err)
+	smooth, err := tf.CreateTestDriver("Smooth Operator", "+919800000102", "MH1420110002")
+	require.NoError(t, err)
+	occasional, err := tf.CreateTestDriver("Occasional Driver", "+919800000103", "MH1420110003")
+	require.NoError(t, err)
+
+	// Each of the first two drivers drives 60 km in an hour, a ping a minute
+	start := time.Now().Add(-48 * time.Hour).Truncate(time.Minute)
+	drive := func(driver *models.Driver, plate string) (*models.Vehicle, *models.Trip) {
+		vehicle, err := tf.CreateTestVehicle(plate, "TRUCK")
+		require.NoError(t, err)
+		trip, err := tf.CreateTestTrip("Pune", "Mumbai", driver.ID, vehicle.ID)
+		require.NoError(t, err)
+		pickup, arrival := start, start.Add(time.Hour)
+		require.NoError(t, tf.DB.Model(trip).Updates(map[string]interface{}{
+			"status": models.TripStatusCompleted, "actual_pickup_time": pickup, "actual_arrival": arrival,
+		}).Error)
+		for minute := 0; minute <= 60; minute++ {
+			speed := 60.0
+			require.NoError(t, tf.DB.Create(&models.LocationPing{
+				VehicleID: &vehicle.ID, Latitude: 18.5 + float64(minute)*0.0089932, Longitude: 73.85,
+				Speed: &speed, Timestamp: start.Add(time.Duration(minute) * time.Minute), Source: "GPS_DEVICE",
+			}).Error)
+		}
+		return vehicle, trip
+	}
+	harshVehicle, harshTrip := drive(harsh, "MH12SC0001")
+	_, _ = drive(smooth, "MH12SC0002")
+
+	// Events carry only the vehicle; the trip and driver are attributed from the active trip
+	safety := tf.Services.SafetyService
+	for i, severity := range []models.SafetyEventSeverity{models.SeverityHigh, models.SeverityHigh, models.SeverityMedium} {
+		eventType := models.SafetyEventHarshBraking
+		if i == 2 {
+			eventType = models.SafetyEventSpeeding
+		}
+		event := &models.SafetyEvent{
+			VehicleID: harshVehicle.ID, Type: eventType, Severity: severity, Value: -14, Threshold: -12, Unit: "km/h/s",
+			Latitude: 18.6, Longitude: 73.85, Timestamp: start.Add(time.Duration(10+i*10) * time.Minute),
+		}
+		require.NoError(t, safety.RecordSafetyEvent(event))
+		require.NotNil(t, event.TripID)
+		assert.Equal(t, harshTrip.ID, *event.TripID)
+		require.NotNil(t, event.DriverID)
+		assert.Equal(t, harsh.ID, *event.DriverID)
+	}
+	// Outside any trip the event keeps the driver it was reported with
+	offTrip := &models.SafetyEvent{
+		VehicleID: harshVehicle.ID, DriverID: &occasional.ID, Type: models.SafetyEventHarshAcceleration,
+		Severity: models.SeverityLow, Latitude: 18.6, Longitude: 73.85, Timestamp: start.Add(-6 * time.Hour),
+	}
+	require.NoError(t, safety.RecordSafetyEvent(offTrip))
+	assert.Nil(t, offTrip.TripID)
+
+	// Filtered event queries
+	w := sendJSON(tf, "GET", fmt.Sprintf("/api/v1/safety/events?driver_id=%d&type=harsh_braking", harsh.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var page struct {
+		Events []models.SafetyEvent `json:"events"`
+		Total  int64                `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.EqualValues(t, 2, page.Total)
+	require.Len(t, page.Events, 2)
+	assert.True(t, page.Events[0].Timestamp.After(page.Events[1].Timestamp))
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/safety/events?trip_id=%d&severity=medium&limit=1", harshTrip.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.EqualValues(t, 1, page.Total)
+	assert.Equal(t, models.SafetyEventSpeeding, page.Events[0].Type)
+
+	w = sendJSON(tf, "GET", "/api/v1/safety/events?end_date="+start.Format(time.RFC3339), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.EqualValues(t, 1, page.Total)
+	w = sendJSON(tf, "GET", "/api/v1/safety/events?start_date=yesterday", nil, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Scores are normalised by the distance and time driven
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/safety/score?driver_id=%d&window=7", harsh.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var harshScore models.DriverScore
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &harshScore))
+	assert.Equal(t, 7, harshScore.WindowDays)
+	assert.InDelta(t, 60, harshScore.TotalDistance, 0.5)
+	assert.InDelta(t, 1, harshScore.TotalDriveTime, 0.01)
+	assert.Equal(t, 2, harshScore.BrakingEvents)
+	assert.Equal(t, 1, harshScore.SpeedingEvents)
+	// Two high severity brakes over 60 km is 6.7 weighted per 100 km; one medium speeding event in an hour is 1 per hour
+	assert.Equal(t, 31, harshScore.BrakingScore)
+	assert.Equal(t, 71, harshScore.SpeedingScore)
+	assert.Equal(t, 100, harshScore.AccelScore)
+	assert.Equal(t, 100, harshScore.CorneringScore)
+	assert.Equal(t, 71, harshScore.OverallScore)
+	assert.False(t, harshScore.Provisional)
+	require.NotNil(t, harshScore.FleetRank)
+	assert.Equal(t, 2, *harshScore.FleetRank)
+	assert.Equal(t, 50.0, *harshScore.Percentile)
+	assert.Equal(t, 2, harshScore.FleetSize)
+	assert.Equal(t, "Harsh Braker", harshScore.Driver.Name)
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/safety/score?driver_id=%d&window=7", smooth.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var smoothScore models.DriverScore
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &smoothScore))
+	assert.Equal(t, 100, smoothScore.OverallScore)
+	assert.Equal(t, 1, *smoothScore.FleetRank)
+	assert.Equal(t, 100.0, *smoothScore.Percentile)
+
+	// An event with no driving to normalise against is scored but not ranked
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/safety/score?driver_id=%d&window=7", occasional.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var occasionalScore models.DriverScore
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &occasionalScore))
+	assert.True(t, occasionalScore.Provisional)
+	assert.Nil(t, occasionalScore.FleetRank)
+	assert.Equal(t, 1, occasionalScore.AccelEvents)
+	assert.Less(t, occasionalScore.AccelScore, 100)
+
+	w = sendJSON(tf, "GET", "/api/v1/safety/scores?window=7", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var fleet []models.DriverScore
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fleet))
+	require.Len(t, fleet, 3)
+	assert.Equal(t, smooth.ID, fleet[0].DriverID)
+	assert.Equal(t, harsh.ID, fleet[1].DriverID)
+	assert.Equal(t, occasional.ID, fleet[2].DriverID)
+
+	// Older activity falls out of shorter windows
+	require.NoError(t, tf.DB.Model(&models.SafetyEvent{}).Where("driver_id = ?", harsh.ID).
+		Update("timestamp", time.Now().AddDate(0, 0, -20)).Error)
+	w = sendJSON(tf, "POST", "/api/v1/safety/scores/recalculate?window=7", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/safety/score?driver_id=%d&window=7", harsh.ID), nil, token)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &harshScore))
+	assert.Equal(t, 100, harshScore.OverallScore)
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/safety/score?driver_id=%d&window=30", harsh.ID), nil, token)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &harshScore))
+	assert.Equal(t, 30, harshScore.WindowDays)
+	assert.Equal(t, 31, harshScore.BrakingScore)
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/safety/score?driver_id=%d&window=14", harsh.ID), nil, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendJSON(tf, "GET", "/api/v1/safety/score?driver_id=999999", nil, token)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
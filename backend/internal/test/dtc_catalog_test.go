@@ -0,0 +1,147 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDTCCatalogAndClearing(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+	vehicle, err := tf.CreateTestVehicle("MH12DT4101", "TRUCK")
+	require.NoError(t, err)
+
+	report := func(payload map[string]interface{}) services.DiagnosticReportResult {
+		payload["vehicle_id"] = vehicle.ID
+		w := postJSON(tf, "/api/v1/telemetry/dtc", payload, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var result services.DiagnosticReportResult
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		return result
+	}
+
+	// OBD-II codes are described from the catalogue and routed by severity
+	result := report(map[string]interface{}{"codes": []string{"P0300", "p0420", "X123"}})
+	require.Len(t, result.New, 2)
+	misfire, catalyst := result.New[0], result.New[1]
+	assert.Equal(t, "Random/multiple cylinder misfire detected", misfire.Description)
+	assert.Equal(t, models.DTCSeverityHigh, misfire.Severity)
+	assert.NotEmpty(t, misfire.RecommendedAction)
+	assert.Equal(t, models.DTCSeverityLow, catalyst.Severity)
+	assert.Equal(t, []string{"X123"}, result.Invalid)
+	require.Len(t, result.Alerts, 1)
+	assert.Equal(t, "P0300", result.Alerts[0].Code)
+	assert.Equal(t, []string{services.DTCAlertFleet, services.DTCAlertManagers}, result.Alerts[0].Channels)
+
+	// A DM1 payload with the amber lamp on and coolant temperature above range (SPN 110, FMI 0)
+	w := sendJSON(tf, "GET", "/api/v1/telemetry/dtc/decode?dm1=04FF6E000003", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var decoded struct {
+		Lamps services.J1939Lamps      `json:"lamps"`
+		DTCs  []services.J1939DTC      `json:"dtcs"`
+		Defs  []services.DTCDefinition `json:"definitions"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &decoded))
+	assert.True(t, decoded.Lamps.AmberWarning)
+	require.Len(t, decoded.DTCs, 1)
+	assert.Equal(t, "SPN110-FMI0", decoded.DTCs[0].Code)
+	assert.Equal(t, 3, decoded.DTCs[0].OccurrenceCount)
+	assert.Equal(t, models.DTCSeverityCritical, decoded.Defs[0].Severity)
+
+	// ECUs send the same fault as a standard 8-byte frame padded with 0xFF
+	w = sendJSON(tf, "GET", "/api/v1/telemetry/dtc/decode?dm1=04FF6E000003FFFF", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &decoded))
+	require.Len(t, decoded.DTCs, 1)
+	assert.Equal(t, "SPN110-FMI0", decoded.DTCs[0].Code)
+	assert.Equal(t, "6E000003", decoded.DTCs[0].Raw)
+
+	// "No fault" frames and padding-only groups decode to no codes
+	for _, frame := range []string{"0000000000000000", "00FF00000000FFFF", "00FFFFFFFFFF"} {
+		message, err := services.DecodeDM1(frame)
+		require.NoError(t, err, frame)
+		assert.Empty(t, message.DTCs, frame)
+	}
+
+	result = report(map[string]interface{}{"codes": []string{"P0300", "P0420"}, "dm1": "04FF6E000003"})
+	require.Len(t, result.New, 1)
+	coolant := result.New[0]
+	assert.Equal(t, models.DTCSourceJ1939, coolant.Source)
+	assert.Equal(t, 110, *coolant.SPN)
+	assert.Equal(t, 0, *coolant.FMI)
+	assert.Contains(t, coolant.Description, "Engine coolant temperature")
+	assert.Equal(t, models.DTCSeverityCritical, coolant.Severity)
+	require.Len(t, result.Alerts, 1)
+	assert.Contains(t, result.Alerts[0].Channels, services.DTCAlertManagers)
+	assert.NotContains(t, result.Alerts[0].Channels, services.DTCAlertDriver, "no trip in progress")
+
+	// The fleet's own description of an SPN takes precedence
+	w = sendJSON(tf, "PUT", "/api/v1/telemetry/dtc/catalog", map[string]interface{}{
+		"code": "spn 190", "description": "Engine speed sensor", "severity": "LOW", "recommended_action": "Check at next service",
+	}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = sendJSON(tf, "GET", "/api/v1/telemetry/dtc/decode?code=190/2", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var definition services.DTCDefinition
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &definition))
+	assert.Equal(t, "SPN190-FMI2", definition.Code)
+	assert.Equal(t, models.DTCSeverityLow, definition.Severity)
+	assert.Contains(t, definition.Description, "Engine speed sensor")
+	assert.Equal(t, "Check at next service", definition.RecommendedAction)
+
+	w = sendJSON(tf, "GET", "/api/v1/telemetry/dtc/decode?code=ZZZ", nil, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Codes missing from three consecutive reports clear
+	for i := 0; i < 2; i++ {
+		result = report(map[string]interface{}{"codes": []string{"P0420"}, "dm1": "04FF6E000003FFFF"})
+		assert.Empty(t, result.Cleared)
+	}
+	// A report without a DM1 frame does not count against J1939 codes
+	result = report(map[string]interface{}{"codes": []string{"P0420"}})
+	require.Len(t, result.Cleared, 1)
+	assert.Equal(t, "P0300", result.Cleared[0].Code)
+	assert.NotNil(t, result.Cleared[0].ClearTime)
+
+	// Neither does an unreadable one, which is reported without dropping the OBD codes
+	result = report(map[string]interface{}{"codes": []string{"P0420"}, "dm1": "not-hex"})
+	assert.Equal(t, []string{"not-hex"}, result.Invalid)
+	require.Len(t, result.Updated, 1)
+	assert.Equal(t, "P0420", result.Updated[0].Code)
+	assert.Empty(t, result.Cleared)
+
+	var coolantCode models.DiagnosticCode
+	require.NoError(t, tf.DB.Where("vehicle_id = ? AND code = ?", vehicle.ID, "SPN110-FMI0").First(&coolantCode).Error)
+	assert.True(t, coolantCode.IsActive)
+	assert.Zero(t, coolantCode.MissedReports)
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/telemetry/dtc?vehicle_id=%d", vehicle.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var active []models.DiagnosticCode
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &active))
+	assert.Len(t, active, 2)
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/telemetry/dtc?vehicle_id=%d&include_cleared=true", vehicle.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &active))
+	assert.Len(t, active, 3)
+
+	var cleared models.DiagnosticCode
+	require.NoError(t, tf.DB.Where("vehicle_id = ? AND code = ?", vehicle.ID, "P0300").First(&cleared).Error)
+	assert.False(t, cleared.IsActive)
+	require.NotNil(t, cleared.ClearTime)
+}
@@ -0,0 +1,165 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDVIRDefectLifecycle(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+	mechanic, err := tf.CreateTestUser("+919876504001", models.RoleMechanic)
+	require.NoError(t, err)
+	mechanicToken, err := tf.GenerateJWTToken(mechanic)
+	require.NoError(t, err)
+	dispatcher, err := tf.CreateTestUser("+919876504002", models.RoleDispatcher)
+	require.NoError(t, err)
+	dispatcherToken, err := tf.GenerateJWTToken(dispatcher)
+	require.NoError(t, err)
+
+	driver, err := tf.CreateTestDriver("Ramesh Kumar", "+919876504003", "MH1220240040")
+	require.NoError(t, err)
+	vehicle, err := tf.CreateTestVehicle("MH12DV4001", "TRUCK")
+	require.NoError(t, err)
+
+	// Trucks are inspected against their own checklist
+	w := sendJSON(tf, "PUT", "/api/v1/maintenance/checklists", map[string]interface{}{
+		"vehicle_type": "TRUCK", "name": "Truck pre-trip",
+		"items": []map[string]interface{}{
+			{"code": "brakes_service", "category": "Brakes", "label": "Service brakes", "severity": "OUT_OF_SERVICE"},
+			{"code": "LIGHTS", "category": "Lights", "label": "Head and tail lights"},
+		},
+	}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/maintenance/vehicles/%d/checklist", vehicle.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var checklist models.InspectionChecklist
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &checklist))
+	require.Len(t, checklist.Items, 2)
+	brakes, lights := checklist.Items[0], checklist.Items[1]
+	assert.Equal(t, "BRAKES_SERVICE", brakes.Code)
+	assert.Equal(t, models.DefectSeverityMinor, lights.Severity)
+
+	photo := models.Upload{FileName: "brake.jpg", OriginalName: "brake.jpg", ContentType: "image/jpeg", FileSize: 2048,
+		FilePath: "uploads/brake.jpg", UploadType: models.UploadTypeDVIRDefect, UploadedBy: admin.ID}
+	require.NoError(t, tf.DB.Create(&photo).Error)
+
+	// A failed brake item takes the truck out of service whatever severity the driver picked
+	w = postJSON(tf, "/api/v1/maintenance/dvir", map[string]interface{}{
+		"vehicle_id": vehicle.ID, "driver_id": driver.ID, "type": "PRE_TRIP", "driver_sign": "R. Kumar",
+		"defect_items": []map[string]interface{}{
+			{"checklist_item_id": brakes.ID, "description": "Air leak at rear chamber", "severity": "MINOR", "photo_upload_ids": []uint{photo.ID}},
+			{"checklist_item_id": lights.ID},
+		},
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var dvir models.DVIR
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dvir))
+	assert.Equal(t, models.DVIRStatusUnsafe, dvir.Status)
+	assert.Equal(t, checklist.ID, *dvir.ChecklistID)
+	require.Len(t, dvir.DefectRecords, 2)
+	brakeDefect, lightDefect := dvir.DefectRecords[0], dvir.DefectRecords[1]
+	assert.Equal(t, models.DefectSeverityOutOfService, brakeDefect.Severity)
+	assert.Equal(t, "Head and tail lights", lightDefect.Description)
+	require.NotNil(t, brakeDefect.WorkOrderID)
+	assert.Equal(t, *brakeDefect.WorkOrderID, *lightDefect.WorkOrderID)
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/maintenance/dvir/%d", dvir.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dvir))
+	require.Len(t, dvir.DefectRecords[0].Photos, 1)
+	assert.Equal(t, photo.ID, dvir.DefectRecords[0].Photos[0].ID)
+
+	var truck models.Vehicle
+	require.NoError(t, tf.DB.First(&truck, vehicle.ID).Error)
+	assert.Equal(t, models.VehicleStatusMaintenance, truck.Status)
+
+	// The truck cannot be dispatched until a mechanic certifies the brakes
+	trip, err := tf.CreateTestTrip("Bhiwandi", "Pune", driver.ID, vehicle.ID)
+	require.NoError(t, err)
+	require.NoError(t, tf.DB.Model(trip).Update("status", models.TripStatusAssigned).Error)
+	w = postJSON(tf, fmt.Sprintf("/api/v1/trips/%d/start", trip.ID), nil, token)
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+	certify := func(defectID uint, resolution, authToken string) int {
+		w := postJSON(tf, fmt.Sprintf("/api/v1/maintenance/defects/%d/certify", defectID), map[string]interface{}{
+			"resolution": resolution, "notes": "Checked", "mechanic_sign": "S. Patil",
+		}, authToken)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusForbidden, certify(brakeDefect.ID, "CORRECTED", dispatcherToken))
+	assert.Equal(t, http.StatusOK, certify(brakeDefect.ID, "CORRECTED", mechanicToken))
+	assert.Equal(t, http.StatusBadRequest, certify(brakeDefect.ID, "CORRECTED", mechanicToken))
+
+	require.NoError(t, tf.DB.First(&dvir, dvir.ID).Error)
+	assert.Empty(t, dvir.MechanicSign)
+	assert.Equal(t, http.StatusOK, certify(lightDefect.ID, "NO_REPAIR_NEEDED", mechanicToken))
+	require.NoError(t, tf.DB.First(&dvir, dvir.ID).Error)
+	assert.Equal(t, "S. Patil", dvir.MechanicSign)
+
+	w = postJSON(tf, fmt.Sprintf("/api/v1/trips/%d/start", trip.ID), nil, token)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// The next pre-trip must acknowledge both certifications
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/maintenance/vehicles/%d/defects", vehicle.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var pending []models.DVIRDefect
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pending))
+	assert.Len(t, pending, 2)
+
+	preTrip := map[string]interface{}{
+		"vehicle_id": vehicle.ID, "driver_id": driver.ID, "type": "PRE_TRIP", "driver_sign": "R. Kumar",
+		"acknowledged_defect_ids": []uint{brakeDefect.ID},
+	}
+	w = postJSON(tf, "/api/v1/maintenance/dvir", preTrip, token)
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+	preTrip["acknowledged_defect_ids"] = []uint{brakeDefect.ID, lightDefect.ID}
+	w = postJSON(tf, "/api/v1/maintenance/dvir", preTrip, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var nextDVIR models.DVIR
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &nextDVIR))
+	assert.Equal(t, models.DVIRStatusSafe, nextDVIR.Status)
+
+	var acknowledged models.DVIRDefect
+	require.NoError(t, tf.DB.First(&acknowledged, brakeDefect.ID).Error)
+	assert.Equal(t, models.DVIRDefectAcknowledged, acknowledged.Status)
+	assert.Equal(t, nextDVIR.ID, *acknowledged.AcknowledgedDVIRID)
+	assert.Equal(t, driver.ID, *acknowledged.AcknowledgedBy)
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/maintenance/vehicles/%d/defects", vehicle.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pending))
+	assert.Empty(t, pending)
+
+	// Legacy free-text defects are still recorded one by one
+	w = postJSON(tf, "/api/v1/maintenance/dvir", map[string]interface{}{
+		"vehicle_id": vehicle.ID, "driver_id": driver.ID, "type": "POST_TRIP", "driver_sign": "R. Kumar",
+		"defects": `["Wiper blade torn"]`,
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &nextDVIR))
+	assert.Equal(t, models.DVIRStatusRepairsNeeded, nextDVIR.Status)
+	require.Len(t, nextDVIR.DefectRecords, 1)
+	assert.Equal(t, models.DefectSeverityMajor, nextDVIR.DefectRecords[0].Severity)
+
+	w = postJSON(tf, "/api/v1/maintenance/dvir", map[string]interface{}{
+		"vehicle_id": vehicle.ID, "driver_id": driver.ID, "type": "POST_TRIP", "driver_sign": "R. Kumar",
+		"defect_items": []map[string]interface{}{{"checklist_item_id": 99999}},
+	}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+}
@@ -48,18 +48,77 @@ func NewTestFramework() (*TestFramework, error) {
 	if !hasMigrated {
 		// Auto-migrate all models ONCE per test run
 		err = sharedDB.AutoMigrate(
+			&models.Organization{},
+			&models.Fleet{},
 			&models.UserAccount{},
 			&models.Driver{},
 			&models.Vehicle{},
 			&models.Trip{},
+			&models.DutyStatusLog{},
 			&models.LocationPing{},
 			&models.Geofence{},
 			&models.FuelEvent{},
 			&models.FuelAlert{},
+			&models.FuelThreshold{},
+			&models.FuelLevelEvent{},
+			&models.FuelAnomalyModel{},
+			&models.FuelStation{},
+			&models.FleetPreferredStation{},
+			&models.FuelCardProvider{},
+			&models.FuelCard{},
+			&models.FuelCardImport{},
+			&models.FuelCardTransaction{},
+			&models.FuelPrice{},
+			&models.TelemetryLog{},
+			&models.DiagnosticCode{},
+			&models.DTCCatalogEntry{},
+			&models.TelemetryThreshold{},
+			&models.TelemetryBaseline{},
+			&models.TelemetryAnomaly{},
+			&models.TrackerDevice{},
+			&models.SafetyEvent{},
+			&models.DriverScore{},
+			&models.IMUCalibration{},
+			&models.SafetyEventTrace{},
+			&models.RoadSegment{},
+			&models.SpeedLimitOverride{},
+			&models.SpeedingEpisode{},
+			&models.CoachingSession{},
+			&models.CoachingNote{},
+			&models.Camera{},
+			&models.CameraStatusChange{},
+			&models.VideoClip{},
+			&models.AIDetection{},
+			&models.VideoUpload{},
+			&models.VideoRetrievalRequest{},
+			&models.VideoRetentionPolicy{},
+			&models.MaintenanceTask{},
+			&models.ServiceSchedule{},
+			&models.ServiceScheduleTemplate{},
+			&models.WorkOrder{},
+			&models.DVIR{},
+			&models.InspectionChecklist{},
+			&models.InspectionChecklistItem{},
+			&models.DVIRDefect{},
+			&models.InventoryItem{},
+			&models.WorkOrderPart{},
+			&models.WorkOrderLabor{},
+			&models.MechanicRate{},
+			&models.PurchaseOrderSuggestion{},
+			&models.MaintenancePrediction{},
 			&models.RefreshToken{},
+			&models.UserSession{},
 			&models.OTPVerification{},
+			&models.RecoveryCode{},
+			&models.OIDCConfig{},
+			&models.OIDCAuthRequest{},
+			&models.UserIdentity{},
 			&models.Upload{},
 			&models.AuditLog{},
+			&models.AuditChainHead{},
+			&models.AuditCheckpoint{},
+			&models.AuditArchive{},
+			&models.SecurityEvent{},
 		)
 		if err != nil {
 			log.Printf("❌ AutoMigrate failed: %v\n", err)
@@ -79,6 +138,9 @@ func NewTestFramework() (*TestFramework, error) {
 		JWTExpirationTime:  24 * 60 * 60 * time.Second, // 24 hours for tests
 		RefreshTokenExpiry: 7 * 24 * time.Hour,
 		DatabaseURL:        ":memory:",
+
+		// The fake identity provider listens on localhost
+		OIDCAllowPrivateIssuers: true,
 	}
 
 	// Initialize services
@@ -257,12 +319,70 @@ func (tf *TestFramework) GenerateJWTToken(user *models.UserAccount) (string, err
 func (tf *TestFramework) CleanDatabase() {
 	// Delete all records from all tables
 	tf.DB.Exec("DELETE FROM audit_logs")
+	tf.DB.Exec("DELETE FROM audit_chain_heads")
+	tf.DB.Exec("DELETE FROM audit_checkpoints")
+	tf.DB.Exec("DELETE FROM audit_archives")
+	tf.DB.Exec("DELETE FROM security_events")
 	tf.DB.Exec("DELETE FROM refresh_tokens")
+	tf.DB.Exec("DELETE FROM user_sessions")
 	tf.DB.Exec("DELETE FROM otp_verifications")
+	tf.DB.Exec("DELETE FROM recovery_codes")
+	tf.DB.Exec("DELETE FROM user_identities")
+	tf.DB.Exec("DELETE FROM oidc_auth_requests")
+	tf.DB.Exec("DELETE FROM oidc_configs")
 	tf.DB.Exec("DELETE FROM uploads")
+	tf.DB.Exec("DELETE FROM fuel_card_transactions")
+	tf.DB.Exec("DELETE FROM fuel_card_imports")
+	tf.DB.Exec("DELETE FROM fuel_cards")
+	tf.DB.Exec("DELETE FROM fuel_card_providers")
+	tf.DB.Exec("DELETE FROM fuel_prices")
+	tf.DB.Exec("DELETE FROM fleet_preferred_stations")
+	tf.DB.Exec("DELETE FROM fuel_stations")
+	tf.DB.Exec("DELETE FROM fuel_level_events")
+	tf.DB.Exec("DELETE FROM fuel_anomaly_models")
 	tf.DB.Exec("DELETE FROM fuel_alerts")
+	tf.DB.Exec("DELETE FROM fuel_thresholds")
+	tf.DB.Exec("DELETE FROM maintenance_predictions")
+	tf.DB.Exec("DELETE FROM purchase_order_suggestions")
+	tf.DB.Exec("DELETE FROM mechanic_rates")
+	tf.DB.Exec("DELETE FROM work_order_labors")
+	tf.DB.Exec("DELETE FROM work_order_parts")
+	tf.DB.Exec("DELETE FROM inventory_items")
+	tf.DB.Exec("DELETE FROM work_orders")
+	tf.DB.Exec("DELETE FROM dvir_defects")
+	tf.DB.Exec("DELETE FROM dvirs")
+	tf.DB.Exec("DELETE FROM inspection_checklist_items")
+	tf.DB.Exec("DELETE FROM inspection_checklists")
+	tf.DB.Exec("DELETE FROM service_schedules")
+	tf.DB.Exec("DELETE FROM service_schedule_templates")
+	tf.DB.Exec("DELETE FROM maintenance_tasks")
+	tf.DB.Exec("DELETE FROM diagnostic_codes")
+	tf.DB.Exec("DELETE FROM dtc_catalog_entries")
+	tf.DB.Exec("DELETE FROM telemetry_thresholds")
+	tf.DB.Exec("DELETE FROM telemetry_baselines")
+	tf.DB.Exec("DELETE FROM telemetry_anomalies")
+	tf.DB.Exec("DELETE FROM tracker_devices")
+	tf.DB.Exec("DELETE FROM coaching_notes")
+	tf.DB.Exec("DELETE FROM coaching_sessions")
+	tf.DB.Exec("DELETE FROM video_uploads")
+	tf.DB.Exec("DELETE FROM video_retrieval_requests")
+	tf.DB.Exec("DELETE FROM video_retention_policies")
+	tf.DB.Exec("DELETE FROM a_idetections")
+	tf.DB.Exec("DELETE FROM video_clips")
+	tf.DB.Exec("DELETE FROM camera_status_changes")
+	tf.DB.Exec("DELETE FROM cameras")
+	tf.DB.Exec("DELETE FROM safety_events")
+	tf.DB.Exec("DELETE FROM driver_scores")
+	tf.DB.Exec("DELETE FROM safety_event_traces")
+	tf.DB.Exec("DELETE FROM imu_calibrations")
+	tf.DB.Exec("DELETE FROM speeding_episodes")
+	tf.DB.Exec("DELETE FROM speed_limit_overrides")
+	tf.DB.Exec("DELETE FROM road_segments")
+	tf.DB.Exec("DELETE FROM geofences")
+	tf.DB.Exec("DELETE FROM telemetry_logs")
 	tf.DB.Exec("DELETE FROM fuel_events")
 	tf.DB.Exec("DELETE FROM location_pings")
+	tf.DB.Exec("DELETE FROM duty_status_logs")
 	tf.DB.Exec("DELETE FROM trips")
 	tf.DB.Exec("DELETE FROM vehicles")
 	tf.DB.Exec("DELETE FROM drivers")
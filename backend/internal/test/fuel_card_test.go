@@ -0,0 +1,216 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func uploadStatement(t *testing.T, tf *TestFramework, providerID uint, name, content, token string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	require.NoError(t, writer.WriteField("provider_id", fmt.Sprint(providerID)))
+	part, err := writer.CreateFormFile("file", name)
+	require.NoError(t, err)
+	_, err = part.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req, _ := http.NewRequest("POST", "/api/v1/fuel/card-imports", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	tf.Router.ServeHTTP(w, req)
+	return w
+}
+
+func TestFuelCardImport(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+	truck, err := tf.CreateTestVehicle("MH12FC1001", "TRUCK") // 400 L tank
+	require.NoError(t, err)
+	van, err := tf.CreateTestVehicle("MH12FC1002", "VAN")
+	require.NoError(t, err)
+
+	hadapsar := models.FuelStation{Name: "HP Hadapsar", Brand: "HP", Latitude: 18.5089, Longitude: 73.9260, IsActive: true}
+	wakad := models.FuelStation{Name: "IOC Wakad", Brand: "IOC", Latitude: 18.5986, Longitude: 73.7650, IsActive: true}
+	require.NoError(t, tf.DB.Create(&hadapsar).Error)
+	require.NoError(t, tf.DB.Create(&wakad).Error)
+
+	base := time.Now().Add(-72 * time.Hour).UTC().Truncate(time.Minute)
+	ping := func(vehicleID uint, lat, lon float64, at time.Time) {
+		require.NoError(t, tf.DB.Create(&models.LocationPing{VehicleID: &vehicleID, Latitude: lat, Longitude: lon, Timestamp: at}).Error)
+	}
+	ping(truck.ID, 18.5090, 73.9262, base.Add(-5*time.Minute))         // At Hadapsar
+	ping(truck.ID, 18.5987, 73.7651, base.Add(6*time.Hour))            // At Wakad
+	ping(van.ID, 18.7300, 73.6800, base.Add(12*time.Hour+time.Minute)) // 20 km from Hadapsar
+	ping(truck.ID, 18.5088, 73.9259, base.Add(18*time.Hour))           // At Hadapsar
+
+	// A fill the driver entered by hand before the statement arrived
+	manual := models.FuelEvent{VehicleID: truck.ID, Liters: 100, AmountINR: 9150, CreatedAt: base.Add(6*time.Hour + 10*time.Minute)}
+	require.NoError(t, tf.DB.Create(&manual).Error)
+
+	w := postJSON(tf, "/api/v1/fuel/card-providers", map[string]interface{}{
+		"name": "IOCL XTRAPOWER",
+		"columns": map[string]string{
+			"card_number":     "Card No",
+			"transaction_ref": "Txn ID",
+			"transacted_at":   "Txn Date",
+			"litres":          "Qty (Ltr)",
+			"amount":          "Amount (Rs)",
+			"station_name":    "Outlet",
+		},
+		"date_format": "02/01/2006 15:04",
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	providerID := uint(decodeBody(t, w)["id"].(float64))
+
+	for card, vehicleID := range map[string]uint{"7001 2345 6789 0001": truck.ID, "7001-2345-6789-0002": van.ID} {
+		w = postJSON(tf, "/api/v1/fuel/cards", map[string]interface{}{
+			"provider_id": providerID, "card_number": card, "vehicle_id": vehicleID,
+		}, token)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+	w = postJSON(tf, "/api/v1/fuel/cards", map[string]interface{}{
+		"provider_id": providerID, "card_number": "7001234567890001", "vehicle_id": van.ID,
+	}, token)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	date := func(d time.Duration) string { return base.Add(d).Format("02/01/2006 15:04") }
+	statement := strings.Join([]string{
+		"Card No,Txn ID,Txn Date,Qty (Ltr),Amount (Rs),Outlet",
+		fmt.Sprintf("7001234567890001,T1,%s,120.00,\"10,980.00\",", date(0)),
+		fmt.Sprintf("7001234567890001,T2,%s,100.50,\"9,196.00\",IOC Wakad", date(6*time.Hour)),
+		fmt.Sprintf("7001234567890002,T3,%s,60.00,\"5,490.00\",HP Hadapsar", date(12*time.Hour)),
+		fmt.Sprintf("7001234567890001,T4,%s,450.00,\"41,175.00\",HP Hadapsar", date(18*time.Hour)),
+		fmt.Sprintf("7001234567899999,T5,%s,40.00,\"3,660.00\",", date(24*time.Hour)),
+		fmt.Sprintf("7001234567890001,T6,%s,abc,100.00,", date(30*time.Hour)),
+		fmt.Sprintf("7001234567890001,T1,%s,120.00,\"10,980.00\",", date(0)),
+	}, "\n")
+
+	w = uploadStatement(t, tf, providerID, "march.csv", statement, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var batch models.FuelCardImport
+	require.NoError(t, tf.DB.First(&batch).Error)
+	assert.Equal(t, 7, batch.Rows)
+	assert.Equal(t, 5, batch.Imported)
+	assert.Equal(t, 1, batch.Duplicates)
+	assert.Equal(t, 1, batch.Failed)
+	assert.Equal(t, 1, batch.Reconciled)
+	assert.Equal(t, 3, batch.Created)
+	assert.Equal(t, 1, batch.Unmatched)
+	assert.Equal(t, 3, batch.Flagged)
+	require.Len(t, batch.Errors, 1)
+	assert.Contains(t, batch.Errors[0], "row 7")
+
+	txns := make(map[string]models.FuelCardTransaction)
+	var stored []models.FuelCardTransaction
+	require.NoError(t, tf.DB.Find(&stored).Error)
+	for _, txn := range stored {
+		txns[txn.TransactionRef] = txn
+	}
+
+	// Clean purchase at a known station creates a verified event
+	t1 := txns["T1"]
+	assert.Equal(t, models.FuelCardTxnCreated, t1.Status)
+	assert.False(t, t1.Flagged)
+	require.NotNil(t, t1.FuelStationID)
+	assert.Equal(t, hadapsar.ID, *t1.FuelStationID)
+	var created models.FuelEvent
+	require.NoError(t, tf.DB.First(&created, *t1.FuelEventID).Error)
+	assert.Equal(t, models.FuelEventStatusVerified, created.Status)
+	assert.Equal(t, 120.0, created.Liters)
+	assert.Equal(t, "T1", created.ReceiptNumber)
+	assert.Equal(t, "HP Hadapsar", created.StationName)
+
+	// Matches the hand-entered event within tolerance and verifies it
+	t2 := txns["T2"]
+	assert.Equal(t, models.FuelCardTxnReconciled, t2.Status)
+	assert.Equal(t, manual.ID, *t2.FuelEventID)
+	assert.Empty(t, t2.Flags)
+	require.NoError(t, tf.DB.First(&manual, manual.ID).Error)
+	assert.Equal(t, models.FuelEventStatusVerified, manual.Status)
+	require.NotNil(t, manual.VerifiedBy)
+	assert.Equal(t, admin.ID, *manual.VerifiedBy)
+
+	// The van was 20 km away when its card was used
+	t3 := txns["T3"]
+	assert.Equal(t, []models.FuelCardFlag{models.FuelCardFlagVehicleElsewhere}, t3.Flags)
+	require.NotNil(t, t3.DistanceMeters)
+	assert.Greater(t, *t3.DistanceMeters, 15000.0)
+	require.NotNil(t, t3.FuelAlertID)
+	var elsewhere models.FuelAlert
+	require.NoError(t, tf.DB.First(&elsewhere, *t3.FuelAlertID).Error)
+	assert.Equal(t, models.FuelAlertTypeCardMismatch, elsewhere.AlertType)
+	assert.Equal(t, models.AlertSeverityHigh, elsewhere.Severity)
+	assert.Equal(t, van.ID, elsewhere.VehicleID)
+	var suspicious models.FuelEvent
+	require.NoError(t, tf.DB.First(&suspicious, *t3.FuelEventID).Error)
+	assert.Equal(t, models.FuelEventStatusSuspicious, suspicious.Status)
+	assert.Contains(t, suspicious.FraudReason, "km from the station")
+
+	// More than the 400 L tank holds
+	t4 := txns["T4"]
+	assert.Equal(t, []models.FuelCardFlag{models.FuelCardFlagExceedsCapacity}, t4.Flags)
+	var capacity models.FuelAlert
+	require.NoError(t, tf.DB.First(&capacity, *t4.FuelAlertID).Error)
+	assert.Equal(t, 400.0, *capacity.ExpectedValue)
+	assert.Equal(t, 450.0, *capacity.ActualValue)
+
+	t5 := txns["T5"]
+	assert.Equal(t, models.FuelCardTxnUnmatched, t5.Status)
+	assert.Equal(t, []models.FuelCardFlag{models.FuelCardFlagUnknownCard}, t5.Flags)
+	assert.Nil(t, t5.FuelEventID)
+
+	w = sendJSON(tf, "GET", "/api/v1/fuel/card-transactions?flagged=true&page=1&limit=20", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, float64(3), decodeBody(t, w)["total"])
+
+	// Importing the same statement again adds nothing
+	w = uploadStatement(t, tf, providerID, "march-again.csv", statement, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	again := decodeBody(t, w)["import"].(map[string]interface{})
+	assert.Equal(t, float64(0), again["imported"])
+	assert.Equal(t, float64(6), again["duplicates"])
+	var txnCount, alertCount int64
+	tf.DB.Model(&models.FuelCardTransaction{}).Count(&txnCount)
+	tf.DB.Model(&models.FuelAlert{}).Where("alert_type = ?", models.FuelAlertTypeCardMismatch).Count(&alertCount)
+	assert.Equal(t, int64(5), txnCount)
+	assert.Equal(t, int64(2), alertCount)
+
+	// Once the card is assigned the transaction reconciles; without GPS the event is not verified
+	w = postJSON(tf, "/api/v1/fuel/cards", map[string]interface{}{
+		"provider_id": providerID, "card_number": "7001234567899999", "vehicle_id": van.ID,
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = postJSON(tf, fmt.Sprintf("/api/v1/fuel/card-transactions/%d/reconcile", t5.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, tf.DB.First(&t5, t5.ID).Error)
+	assert.Equal(t, models.FuelCardTxnCreated, t5.Status)
+	assert.Equal(t, []models.FuelCardFlag{models.FuelCardFlagNoGPS}, t5.Flags)
+	assert.False(t, t5.Flagged)
+	var unverified models.FuelEvent
+	require.NoError(t, tf.DB.First(&unverified, *t5.FuelEventID).Error)
+	assert.NotEqual(t, models.FuelEventStatusVerified, unverified.Status)
+
+	// A mapping that does not match the file is rejected before anything is stored
+	w = uploadStatement(t, tf, providerID, "other.csv", "Card,Date,Litres\n1,2,3", token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,125 @@
+package test
+
+import (
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuelAnomalyModel(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+	truck, err := tf.CreateTestVehicle("MH12IF3232", "TRUCK") // 400 L tank
+	require.NoError(t, err)
+
+	// 75 daytime fills of 45-60% of the tank every ~2 days at ~27 L/100 km,
+	// with a rejected night-time over-fill after a short hop every 15th fill
+	rng := rand.New(rand.NewSource(42))
+	day := time.Now().AddDate(0, 0, -200)
+	at := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+	odometer := 50000.0
+	for i := 1; i <= 75; i++ {
+		at = at.Add(48 * time.Hour)
+		litres := 180 + rng.Float64()*60
+		km := litres / (0.25 + rng.Float64()*0.04)
+		odometer += km
+		require.NoError(t, tf.DB.Create(&models.FuelEvent{
+			VehicleID:     truck.ID,
+			Liters:        litres,
+			PricePerLiter: 94 + rng.Float64()*4,
+			AmountINR:     litres * 96,
+			OdometerKm:    odometer,
+			Status:        models.FuelEventStatusVerified,
+			VerifiedBy:    &admin.ID,
+			CreatedAt:     at.Add(time.Duration(8+rng.Intn(10)) * time.Hour),
+		}).Error)
+
+		if i%15 == 0 {
+			require.NoError(t, tf.DB.Create(&models.FuelEvent{
+				VehicleID:     truck.ID,
+				Liters:        380,
+				PricePerLiter: 128,
+				AmountINR:     380 * 128,
+				OdometerKm:    odometer + 40,
+				Status:        models.FuelEventStatusRejected,
+				VerifiedBy:    &admin.ID,
+				CreatedAt:     at.Add(26 * time.Hour),
+			}).Error)
+		}
+	}
+
+	train := func(payload interface{}) []interface{} {
+		w := postJSON(tf, "/api/v1/fuel/models/train", payload, token)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		return decodeBody(t, w)["models"].([]interface{})
+	}
+
+	trained := train(map[string]string{"vehicle_type": "truck"})
+	require.Len(t, trained, 1)
+	v1 := trained[0].(map[string]interface{})
+	assert.Equal(t, float64(1), v1["version"])
+	assert.Equal(t, true, v1["is_active"])
+	assert.Equal(t, float64(60), v1["training_samples"]) // Every fifth verified event is held out
+	assert.Equal(t, float64(20), v1["evaluation_samples"])
+	assert.Equal(t, float64(1), v1["recall"])
+	assert.Greater(t, v1["auc"].(float64), 0.9)
+
+	var stored models.FuelAnomalyModel
+	require.NoError(t, tf.DB.Where("vehicle_type = ? AND version = 1", "TRUCK").First(&stored).Error)
+	assert.NotEmpty(t, stored.Forest)
+	assert.Len(t, stored.FeatureNames, 6)
+
+	// A night-time over-fill is explained by the features that isolated it
+	suspicious, err := tf.Services.FuelService.CreateFuelEvent(&models.FuelEvent{
+		VehicleID:     truck.ID,
+		Liters:        390,
+		PricePerLiter: 130,
+		AmountINR:     390 * 130,
+		OdometerKm:    odometer + 30,
+		CreatedAt:     at.Add(27 * time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Contains(t, suspicious.FraudReason, "Unlike verified fills of this vehicle class")
+	assert.Regexp(t, `\[isolation forest TRUCK v1: score 0\.\d+ \(threshold 0\.\d+\); `+
+		`((litres_pct_tank|price_per_litre|hour_of_day|km_since_last_fill|litres_per_100km)=[\d.]+ \d+%(, |\]))+`,
+		suspicious.FraudReason)
+	assert.GreaterOrEqual(t, suspicious.FraudScore, 0.3)
+
+	// Retraining adds a version and retires the old one
+	require.NoError(t, tf.DB.Model(&models.FuelEvent{}).Where("id = ?", suspicious.ID).
+		Updates(map[string]interface{}{"status": models.FuelEventStatusRejected, "verified_by": admin.ID}).Error)
+	trained = train(nil)
+	require.Len(t, trained, 1)
+	assert.Equal(t, float64(2), trained[0].(map[string]interface{})["version"])
+
+	w := sendJSON(tf, "GET", "/api/v1/fuel/models?vehicle_type=TRUCK", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	versions := decodeBody(t, w)["models"].([]interface{})
+	require.Len(t, versions, 2)
+	assert.Equal(t, true, versions[0].(map[string]interface{})["is_active"])
+	assert.Equal(t, false, versions[1].(map[string]interface{})["is_active"])
+
+	// Offline evaluation of the retired version counts the new rejection as unseen
+	w = sendJSON(tf, "GET", "/api/v1/fuel/models/evaluation?vehicle_type=TRUCK&version=1", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	evaluation := decodeBody(t, w)
+	assert.Equal(t, float64(6), evaluation["positives"])
+	assert.Equal(t, float64(1), evaluation["recall"])
+	assert.Greater(t, evaluation["precision"].(float64), 0.5)
+
+	// Classes without verified history cannot be trained
+	w = postJSON(tf, "/api/v1/fuel/models/train", map[string]string{"vehicle_type": "VAN"}, token)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+}
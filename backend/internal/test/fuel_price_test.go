@@ -0,0 +1,136 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuelPriceTracking(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+	vehicle, err := tf.CreateTestVehicle("MH12FP3030", "TRUCK")
+	require.NoError(t, err)
+
+	pune := models.FuelStation{Name: "HP Kothrud", Brand: "HP", City: "Pune", State: "Maharashtra", Latitude: 18.5074, Longitude: 73.8077, IsActive: true}
+	bengaluru := models.FuelStation{Name: "BPCL Hebbal", Brand: "BPCL", City: "Bengaluru", State: "Karnataka", Latitude: 13.0358, Longitude: 77.5970, IsActive: true}
+	require.NoError(t, tf.DB.Create(&pune).Error)
+	require.NoError(t, tf.DB.Create(&bengaluru).Error)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	yesterday := today.Add(-12 * time.Hour)
+	fill := func(station *models.FuelStation, litres, amount float64, at time.Time, status models.FuelEventStatus) models.FuelEvent {
+		event := models.FuelEvent{VehicleID: vehicle.ID, Liters: litres, AmountINR: amount, Status: status, CreatedAt: at}
+		if station != nil {
+			event.StationID = fmt.Sprint(station.ID)
+		}
+		require.NoError(t, tf.DB.Create(&event).Error)
+		return event
+	}
+
+	// Verified history: about ₹92/L in Maharashtra, ₹88/L in Karnataka; pending and rejected fills are ignored
+	fill(&pune, 100, 9200, yesterday, models.FuelEventStatusVerified)
+	fill(&pune, 50, 4610, yesterday.Add(time.Hour), models.FuelEventStatusVerified)
+	fill(&bengaluru, 80, 7040, yesterday, models.FuelEventStatusVerified)
+	fill(&pune, 100, 15000, yesterday, models.FuelEventStatusRejected)
+	pending := fill(&pune, 60, 5508, yesterday.Add(2*time.Hour), models.FuelEventStatusPending)
+
+	w := postJSON(tf, "/api/v1/fuel/prices/rebuild", map[string]interface{}{"from": yesterday, "to": today}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, float64(5), decodeBody(t, w)["rows"]) // 2 stations, 2 regions, national
+
+	// Verifying a fill refreshes its day
+	require.NoError(t, tf.Services.FuelService.VerifyFuelEvent(pending.ID, admin.ID, "Receipt checked"))
+	var regional models.FuelPrice
+	require.NoError(t, tf.DB.Where("region = ? AND fuel_station_id IS NULL AND source = ?", "MAHARASHTRA", models.FuelPriceSourceEvents).
+		First(&regional).Error)
+	assert.Equal(t, 3, regional.Samples)
+	assert.Equal(t, 92.0, regional.PricePerLiter)
+	assert.Equal(t, 91.8, regional.MinPrice)
+	assert.Equal(t, 92.2, regional.MaxPrice)
+
+	var national models.FuelPrice
+	require.NoError(t, tf.DB.Where("region = ? AND source = ?", "", models.FuelPriceSourceEvents).First(&national).Error)
+	assert.Equal(t, 4, national.Samples)
+	assert.Equal(t, 91.9, national.PricePerLiter)
+
+	// A published price joins the regional history
+	w = postJSON(tf, "/api/v1/fuel/prices/feed", map[string]interface{}{
+		"source": "iocl",
+		"prices": []map[string]interface{}{
+			{"date": today.Format("2006-01-02"), "region": "Karnataka", "price_per_liter": 88.4},
+			{"date": today.Format("2006-01-02"), "fuel_station_id": pune.ID, "price_per_liter": 92.3},
+		},
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var feed models.FuelPrice
+	require.NoError(t, tf.DB.Where("source = ? AND fuel_station_id = ?", "IOCL", pune.ID).First(&feed).Error)
+	assert.Equal(t, "MAHARASHTRA", feed.Region)
+	assert.Equal(t, "DIESEL", feed.FuelType)
+
+	w = postJSON(tf, "/api/v1/fuel/prices/feed", map[string]interface{}{
+		"source": "EVENTS",
+		"prices": []map[string]interface{}{{"date": today.Format("2006-01-02"), "price_per_liter": 90}},
+	}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Receipts more than 5% above the regional median raise a PRICE_ANOMALY alert
+	w = postJSON(tf, "/api/v1/fuel/thresholds", map[string]interface{}{
+		"threshold_type": "PRICE_VARIANCE", "value": 5, "severity": "HIGH",
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	fair := models.FuelEvent{VehicleID: vehicle.ID, Liters: 100, AmountINR: 9450, StationID: fmt.Sprint(pune.ID), CreatedAt: today.Add(time.Hour)}
+	_, err = tf.Services.FuelService.CreateFuelEvent(&fair)
+	require.NoError(t, err)
+	var alertCount int64
+	tf.DB.Model(&models.FuelAlert{}).Where("alert_type = ?", models.FuelAlertTypePriceAnomaly).Count(&alertCount)
+	assert.Equal(t, int64(0), alertCount)
+
+	lat, lon := pune.Latitude+0.0005, pune.Longitude
+	inflated := models.FuelEvent{VehicleID: vehicle.ID, Liters: 100, AmountINR: 9900, Latitude: &lat, Longitude: &lon, CreatedAt: today.Add(2 * time.Hour)}
+	_, err = tf.Services.FuelService.CreateFuelEvent(&inflated)
+	require.NoError(t, err)
+
+	var alert models.FuelAlert
+	require.NoError(t, tf.DB.Where("alert_type = ?", models.FuelAlertTypePriceAnomaly).First(&alert).Error)
+	assert.Equal(t, inflated.ID, *alert.FuelEventID)
+	assert.Equal(t, models.AlertSeverityHigh, alert.Severity)
+	assert.Equal(t, 92.0, *alert.ExpectedValue)
+	assert.Equal(t, 99.0, *alert.ActualValue)
+	assert.InDelta(t, 7.61, *alert.Variance, 0.01)
+	assert.Contains(t, alert.Description, "MAHARASHTRA median")
+
+	// Trend per region and in the fuel analytics
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/fuel/prices/trend?region=KARNATAKA&from=%s&to=%s",
+		yesterday.Format("2006-01-02"), today.Format("2006-01-02")), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	trend := decodeBody(t, w)["trend"].([]interface{})
+	require.Len(t, trend, 2)
+	assert.Equal(t, 88.0, trend[0].(map[string]interface{})["price_per_liter"])
+	second := trend[1].(map[string]interface{})
+	assert.Equal(t, 88.4, second["price_per_liter"])
+	assert.Equal(t, 0.45, second["change_percent"])
+
+	w = sendJSON(tf, "GET", "/api/v1/fuel/analytics?period=week", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	analytics := decodeBody(t, w)
+	assert.Len(t, analytics["price_trend"], 1)
+	assert.Greater(t, analytics["average_price_per_liter"], 90.0)
+
+	w = sendJSON(tf, "GET", "/api/v1/fuel/prices?region=MAHARASHTRA&page=1&limit=20", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, float64(3), decodeBody(t, w)["total"]) // Station and region EVENTS rows, station feed row
+}
@@ -0,0 +1,161 @@
+package test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSensorFuelTheftDetection(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+	vehicle, err := tf.CreateTestVehicle("MH12FT4040", "TRUCK") // 400 L tank
+	require.NoError(t, err)
+
+	start := time.Now().Add(-10 * time.Hour).UTC().Truncate(time.Second)
+	at := start
+	slosh := []float64{0, 3, -2.5, 2, -3, 1}
+	n := 0
+	wasMoving := false
+
+	// record appends readings every 30s; level is the true tank percentage
+	record := func(minutes int, speed float64, rpm int, level func(i int) float64) {
+		for i := 0; i < minutes*2; i++ {
+			value := level(i)
+			if speed > 0 || (wasMoving && i < 2) {
+				value += slosh[n%len(slosh)] // Sloshing while driving and right after stopping
+			}
+			s, r := speed, rpm
+			require.NoError(t, tf.DB.Create(&models.TelemetryLog{
+				VehicleID: vehicle.ID,
+				Timestamp: at,
+				Speed:     &s,
+				EngineRPM: &r,
+				FuelLevel: &value,
+			}).Error)
+			at = at.Add(30 * time.Second)
+			n++
+		}
+		wasMoving = speed > 0
+	}
+	constant := func(level float64) func(int) float64 { return func(int) float64 { return level } }
+	ramp := func(from, to float64, steps int) func(int) float64 {
+		return func(i int) float64 {
+			if i >= steps {
+				return to
+			}
+			return from + (to-from)*float64(i)/float64(steps)
+		}
+	}
+
+	// Drive, then refuel 100 L at a station with a matching receipt
+	record(10, 60, 1500, constant(50))
+	record(5, 0, 0, constant(50))
+	refuelAt := at
+	record(10, 0, 0, ramp(50, 75, 6))
+	// The receipt carries the time of purchase but is entered at the end of the shift
+	fueledAt := refuelAt.Add(10 * time.Minute)
+	require.NoError(t, tf.DB.Create(&models.FuelEvent{VehicleID: vehicle.ID, Liters: 101, AmountINR: 9000,
+		FueledAt: &fueledAt, CreatedAt: refuelAt.Add(5 * time.Hour)}).Error)
+
+	// Drive, park on a slope (constant sensor offset) and idle for two hours: no alerts
+	record(10, 60, 1500, constant(75))
+	record(120, 0, 700, func(i int) float64 { return 79 - float64(i)*0.005 })
+
+	// Drive, park overnight with the engine off; the device sleeps and wakes 40 L down
+	record(10, 60, 1500, constant(74))
+	record(5, 0, 0, constant(74))
+	at = at.Add(90 * time.Minute)
+	record(5, 0, 0, constant(64))
+
+	// Drive, then a 60 L refuel billed as 100 L
+	record(10, 60, 1500, constant(63))
+	record(5, 0, 0, constant(63))
+	billedAt := at
+	record(10, 0, 0, ramp(63, 78, 5))
+	require.NoError(t, tf.DB.Create(&models.FuelEvent{VehicleID: vehicle.ID, Liters: 100, AmountINR: 9100,
+		CreatedAt: billedAt.Add(5 * time.Minute)}).Error)
+
+	// Drive, then a 48 L refuel whose receipt is only entered after the analysis
+	record(10, 60, 1500, constant(78))
+	record(5, 0, 0, constant(78))
+	lateAt := at
+	record(10, 0, 0, ramp(78, 90, 5))
+
+	analyze := func() map[string]interface{} {
+		w := postJSON(tf, "/api/v1/fuel/sensor-analysis", map[string]interface{}{
+			"vehicle_id": vehicle.ID,
+			"from":       start.Add(-time.Minute),
+			"to":         at.Add(time.Minute),
+		}, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return decodeBody(t, w)
+	}
+	analysis := analyze()
+
+	var events []models.FuelLevelEvent
+	require.NoError(t, tf.DB.Order("started_at").Find(&events).Error)
+	require.Len(t, events, 4, analysis)
+
+	assert.Equal(t, models.FuelLevelEventRefuel, events[0].EventType)
+	assert.Equal(t, models.FuelLevelStatusReconciled, events[0].Status)
+	assert.InDelta(t, 100, events[0].ChangeLitres, 3)
+
+	assert.Equal(t, models.FuelLevelEventDrop, events[1].EventType)
+	assert.Equal(t, models.FuelLevelStatusTheftSuspected, events[1].Status)
+	assert.InDelta(t, -40, events[1].ChangeLitres, 2)
+	assert.False(t, events[1].EngineOn)
+
+	assert.Equal(t, models.FuelLevelEventRefuel, events[2].EventType)
+	assert.Equal(t, models.FuelLevelStatusMismatch, events[2].Status)
+	require.NotNil(t, events[2].ReportedLitres)
+	assert.Equal(t, 100.0, *events[2].ReportedLitres)
+
+	assert.Equal(t, models.FuelLevelEventRefuel, events[3].EventType)
+	assert.Equal(t, models.FuelLevelStatusUnreported, events[3].Status)
+
+	var theft models.FuelAlert
+	require.NoError(t, tf.DB.Where("alert_type = ?", models.FuelAlertTypeTheftSuspected).First(&theft).Error)
+	require.NotNil(t, theft.ExpectedValue)
+	require.NotNil(t, theft.ActualValue)
+	assert.InDelta(t, 296, *theft.ExpectedValue, 2)
+	assert.InDelta(t, 256, *theft.ActualValue, 2)
+	assert.Equal(t, models.AlertSeverityHigh, theft.Severity)
+
+	var fraud models.FuelAlert
+	require.NoError(t, tf.DB.Where("alert_type = ?", models.FuelAlertTypeFraudDetected).First(&fraud).Error)
+	assert.Equal(t, 100.0, *fraud.ExpectedValue)
+	assert.InDelta(t, 60, *fraud.ActualValue, 3)
+
+	// Re-running the same window does not duplicate events or alerts
+	analyze()
+	var eventCount, alertCount int64
+	tf.DB.Model(&models.FuelLevelEvent{}).Count(&eventCount)
+	tf.DB.Model(&models.FuelAlert{}).Count(&alertCount)
+	assert.Equal(t, int64(4), eventCount)
+	assert.Equal(t, int64(2), alertCount)
+
+	// Entering the late receipt, with no purchase time, reconciles the refuel it paid for
+	receipt, err := tf.Services.FuelService.CreateFuelEvent(&models.FuelEvent{VehicleID: vehicle.ID, Liters: 48, AmountINR: 4400})
+	require.NoError(t, err)
+	assert.Greater(t, receipt.CreatedAt.Sub(lateAt), 2*time.Hour, "entered after the purchase window")
+	require.NoError(t, tf.DB.First(&events[3], events[3].ID).Error)
+	assert.Equal(t, models.FuelLevelStatusReconciled, events[3].Status)
+	require.NotNil(t, events[3].FuelEventID)
+	assert.Equal(t, receipt.ID, *events[3].FuelEventID)
+
+	w := sendJSON(tf, "GET", "/api/v1/fuel/alerts?alert_type=THEFT_SUSPECTED", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, float64(1), decodeBody(t, w)["total"])
+}
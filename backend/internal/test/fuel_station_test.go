@@ -0,0 +1,178 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"googlemaps.github.io/maps"
+)
+
+func TestFuelStationRegistry(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+
+	org := models.Organization{Name: "Deccan Freight", Code: "deccan", SubscriptionStatus: models.SubscriptionActive}
+	require.NoError(t, tf.DB.Create(&org).Error)
+	fleet := models.Fleet{Name: "Pune Line Haul", OrganizationID: org.ID}
+	require.NoError(t, tf.DB.Create(&fleet).Error)
+	vehicle, err := tf.CreateTestVehicle("MH12FS4040", "TRUCK")
+	require.NoError(t, err)
+	require.NoError(t, tf.DB.Model(vehicle).Update("fleet_id", fleet.ID).Error)
+
+	w := postJSON(tf, "/api/v1/fuel/stations", map[string]interface{}{
+		"name": "HP Kothrud", "brand": "hp", "state": "Maharashtra", "latitude": 18.5074, "longitude": 73.8077,
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	kothrud := decodeBody(t, w)
+	assert.Equal(t, "HP", kothrud["brand"])
+	assert.Equal(t, true, kothrud["is_active"])
+
+	w = postJSON(tf, "/api/v1/fuel/stations", map[string]interface{}{"name": "Nowhere"}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = postJSON(tf, "/api/v1/fuel/stations/import", map[string]interface{}{
+		"stations": []map[string]interface{}{
+			{"external_id": "IOC-27-0001", "name": "IOC Wakad", "brand": "IOC", "latitude": 18.5986, "longitude": 73.7650},
+			{"name": "BPCL Hinjewadi", "brand": "BPCL", "latitude": 18.5912, "longitude": 73.7389},
+			{"name": "HP Lonavala", "brand": "HP", "latitude": 18.7546, "longitude": 73.4062, "is_24_hours": true},
+		},
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, float64(3), decodeBody(t, w)["created"])
+
+	// CSV import updates by external ID and adds new stations
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "registry.csv")
+	require.NoError(t, err)
+	_, err = part.Write([]byte("external_id,name,brand,latitude,longitude,fuel_types,amenities,is_24_hours\n" +
+		"IOC-27-0001,IOC Wakad,IOC,18.5986,73.7650,diesel;petrol,truck_parking|restroom,true\n" +
+		",HP Hadapsar,HP,18.5089,73.9260,,,\n"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	req, _ := http.NewRequest("POST", "/api/v1/fuel/stations/import", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	tf.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	result := decodeBody(t, w)
+	assert.Equal(t, float64(1), result["created"])
+	assert.Equal(t, float64(1), result["updated"])
+
+	var wakad, hinjewadi models.FuelStation
+	require.NoError(t, tf.DB.Where("external_id = ?", "IOC-27-0001").First(&wakad).Error)
+	assert.Equal(t, []string{"DIESEL", "PETROL"}, wakad.FuelTypes)
+	assert.Equal(t, []string{"TRUCK_PARKING", "RESTROOM"}, wakad.Amenities)
+	assert.True(t, wakad.Is24Hours)
+	require.NoError(t, tf.DB.Where("name = ?", "BPCL Hinjewadi").First(&hinjewadi).Error)
+	var stationCount int64
+	tf.DB.Model(&models.FuelStation{}).Count(&stationCount)
+	assert.Equal(t, int64(5), stationCount)
+
+	w = postJSON(tf, "/api/v1/fuel/stations/import", map[string]interface{}{
+		"stations": []map[string]interface{}{{"name": "Bad", "latitude": 95, "longitude": 73.0}},
+	}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Radius search, nearest first, with the latest station price and the fleet's preferences
+	require.NoError(t, tf.DB.Create(&models.FuelPrice{
+		Date: time.Now().UTC().Truncate(24 * time.Hour), FuelType: "DIESEL", FuelStationID: &wakad.ID,
+		Source: models.FuelPriceSourceEvents, PricePerLiter: 92.4, Samples: 3,
+	}).Error)
+	w = sendJSON(tf, "PUT", fmt.Sprintf("/api/v1/fuel/fleets/%d/preferred-stations/%d", fleet.ID, wakad.ID),
+		map[string]interface{}{"priority": 1, "notes": "Fleet discount"}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/fuel/stations?lat=18.5990&lng=73.7655&radius=5&fleet_id=%d", fleet.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	nearby := decodeBody(t, w)["stations"].([]interface{})
+	require.Len(t, nearby, 2)
+	first := nearby[0].(map[string]interface{})
+	assert.Equal(t, "IOC Wakad", first["name"])
+	assert.Less(t, first["distance_meters"], 100.0)
+	assert.Equal(t, true, first["preferred"])
+	assert.Equal(t, 92.4, first["latest_price"].(map[string]interface{})["price_per_liter"])
+	second := nearby[1].(map[string]interface{})
+	assert.Equal(t, "BPCL Hinjewadi", second["name"])
+	assert.Equal(t, false, second["preferred"])
+
+	w = sendJSON(tf, "GET", "/api/v1/fuel/stations?lat=18.5990&lng=73.7655&radius=5&fuel_type=CNG", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, float64(1), decodeBody(t, w)["count"]) // Wakad lists its fuels; Hinjewadi lists none
+
+	w = sendJSON(tf, "GET", "/api/v1/fuel/stations?radius=5", nil, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Along-route search follows the trip's polyline from Shivajinagar past Wakad to Lonavala
+	driver, err := tf.CreateTestDriver("Route Driver", "+919876500040", "MH1220240040")
+	require.NoError(t, err)
+	trip, err := tf.CreateTestTrip("Shivajinagar, Pune", "Lonavala", driver.ID, vehicle.ID)
+	require.NoError(t, err)
+	require.NoError(t, tf.DB.Model(trip).Update("route_polyline", maps.Encode([]maps.LatLng{
+		{Lat: 18.5308, Lng: 73.8475}, {Lat: 18.5990, Lng: 73.7655}, {Lat: 18.7540, Lng: 73.4070},
+	})).Error)
+
+	along := func(corridor int) []string {
+		w := sendJSON(tf, "GET", fmt.Sprintf("/api/v1/fuel/stations/along-trip/%d?corridor=%d", trip.ID, corridor), nil, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var names []string
+		for _, station := range decodeBody(t, w)["stations"].([]interface{}) {
+			names = append(names, station.(map[string]interface{})["name"].(string))
+		}
+		return names
+	}
+	assert.Equal(t, []string{"IOC Wakad", "HP Lonavala"}, along(1500))
+	assert.Equal(t, []string{"IOC Wakad", "BPCL Hinjewadi", "HP Lonavala"}, along(3000))
+
+	other, err := tf.CreateTestDriver("City Driver", "+919876500041", "MH1220240041")
+	require.NoError(t, err)
+	unrouted, err := tf.CreateTestTrip("Kothrud", "Hadapsar", other.ID, vehicle.ID)
+	require.NoError(t, err)
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/fuel/stations/along-trip/%d", unrouted.ID), nil, token)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// The fraud scorer measures proximity to registered and preferred stations
+	warningsAt := func(lat, lon float64) []string {
+		event := &models.FuelEvent{VehicleID: vehicle.ID, Liters: 100, AmountINR: 9200, Latitude: &lat, Longitude: &lon, CreatedAt: time.Now()}
+		_, _, warnings := tf.Services.FuelService.RunFraudDetection(event)
+		return warnings
+	}
+	atWakad := warningsAt(18.5988, 73.7652)
+	assert.NotContains(t, atWakad, "Fueling at unregistered location")
+	assert.NotContains(t, atWakad, "Fueling outside the fleet's preferred stations")
+	assert.Contains(t, warningsAt(hinjewadi.Latitude, hinjewadi.Longitude), "Fueling outside the fleet's preferred stations")
+	offStation := warningsAt(18.6200, 73.8000)
+	assert.Contains(t, offStation, "Fueling at unregistered location")
+	assert.NotContains(t, offStation, "Fueling in remote area")
+	assert.Contains(t, warningsAt(19.9000, 75.3000), "Fueling in remote area")
+
+	// Removing the preference and deactivating a station take effect immediately
+	w = sendJSON(tf, "DELETE", fmt.Sprintf("/api/v1/fuel/fleets/%d/preferred-stations/%d", fleet.ID, wakad.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, warningsAt(hinjewadi.Latitude, hinjewadi.Longitude), "Fueling outside the fleet's preferred stations")
+
+	w = sendJSON(tf, "PUT", fmt.Sprintf("/api/v1/fuel/stations/%d", hinjewadi.ID), map[string]interface{}{"is_active": false}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = sendJSON(tf, "GET", "/api/v1/fuel/stations?lat=18.5990&lng=73.7655&radius=5", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, float64(1), decodeBody(t, w)["count"])
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/fuel/fleets/%d/preferred-stations", fleet.ID+100), nil, token)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,165 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuelThresholdRules(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+	_, err = tf.CreateTestUser("+919999000071", models.RoleDispatcher)
+	require.NoError(t, err)
+	truck, err := tf.CreateTestVehicle("MH12TH5151", "TRUCK")
+	require.NoError(t, err)
+
+	createThreshold := func(payload map[string]interface{}) (int, map[string]interface{}) {
+		w := postJSON(tf, "/api/v1/fuel/thresholds", payload, token)
+		return w.Code, decodeBody(t, w)
+	}
+
+	// Usual consumption: 100 L every 500 km, a fill every two days
+	now := time.Now()
+	odometer := 40000.0
+	for day := 22; day >= 4; day -= 2 {
+		odometer += 500
+		require.NoError(t, tf.DB.Create(&models.FuelEvent{VehicleID: truck.ID, Liters: 100, AmountINR: 9600,
+			OdometerKm: odometer, Status: models.FuelEventStatusVerified, CreatedAt: now.AddDate(0, 0, -day)}).Error)
+	}
+	refuel := func(at time.Time) *models.FuelEvent {
+		odometer += 500
+		event, err := tf.Services.FuelService.CreateFuelEvent(&models.FuelEvent{VehicleID: truck.ID, Liters: 140,
+			AmountINR: 13440, OdometerKm: odometer, CreatedAt: at})
+		require.NoError(t, err)
+		return event
+	}
+	alertsOf := func(alertType models.FuelAlertType) []models.FuelAlert {
+		var alerts []models.FuelAlert
+		require.NoError(t, tf.DB.Where("alert_type = ?", alertType).Order("id").Find(&alerts).Error)
+		return alerts
+	}
+
+	t.Run("Threshold CRUD", func(t *testing.T) {
+		code, body := createThreshold(map[string]interface{}{"threshold_type": "CONSUMPTION_VARIANCE", "value": 20, "severity": "HIGH"})
+		require.Equal(t, http.StatusCreated, code, body)
+		assert.Equal(t, "PERCENTAGE", body["unit"])
+		assert.Equal(t, true, body["is_active"])
+
+		code, body = createThreshold(map[string]interface{}{"threshold_type": "CONSUMPTION_VARIANCE", "value": 30})
+		assert.Equal(t, http.StatusConflict, code, body)
+		code, body = createThreshold(map[string]interface{}{"threshold_type": "REFUEL_FREQUENCY", "value": 2, "unit": "PERCENTAGE"})
+		assert.Equal(t, http.StatusBadRequest, code, body)
+		code, body = createThreshold(map[string]interface{}{"threshold_type": "CONSUMPTION_VARIANCE", "value": 20, "vehicle_id": 999999})
+		assert.Equal(t, http.StatusNotFound, code, body)
+
+		code, body = createThreshold(map[string]interface{}{"threshold_type": "CONSUMPTION_VARIANCE", "value": 50, "vehicle_id": truck.ID})
+		require.Equal(t, http.StatusCreated, code, body)
+
+		w := sendJSON(tf, "GET", fmt.Sprintf("/api/v1/fuel/thresholds/effective/%d", truck.ID), nil, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		effective := decodeBody(t, w)["thresholds"].(map[string]interface{})
+		assert.Equal(t, float64(50), effective["CONSUMPTION_VARIANCE"].(map[string]interface{})["value"])
+
+		w = sendJSON(tf, "GET", "/api/v1/fuel/thresholds?vehicle_id=global", nil, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Len(t, decodeBody(t, w)["thresholds"], 1)
+	})
+
+	t.Run("Vehicle Override Falls Back To Global", func(t *testing.T) {
+		// +40% is within the vehicle's 50% override
+		refuel(now.Add(-20 * time.Hour))
+		assert.Empty(t, alertsOf(models.FuelAlertTypeExcessConsumption))
+
+		var override models.FuelThreshold
+		require.NoError(t, tf.DB.Where("vehicle_id = ?", truck.ID).First(&override).Error)
+		w := sendJSON(tf, "DELETE", fmt.Sprintf("/api/v1/fuel/thresholds/%d", override.ID), nil, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		// Without the override the global 20% applies
+		event := refuel(now.Add(-time.Hour))
+		alerts := alertsOf(models.FuelAlertTypeExcessConsumption)
+		require.Len(t, alerts, 1)
+		assert.Equal(t, event.ID, *alerts[0].FuelEventID)
+		assert.Equal(t, models.AlertSeverityHigh, alerts[0].Severity)
+		// The baseline now includes the first 140 L fill: 1040 L over 5000 km
+		assert.InDelta(t, 104, *alerts[0].ExpectedValue, 0.01)
+		assert.InDelta(t, 140, *alerts[0].ActualValue, 0.01)
+		assert.InDelta(t, 34.62, *alerts[0].Variance, 0.01)
+		assert.Equal(t, float64(20), *alerts[0].ThresholdValue)
+	})
+
+	t.Run("Nightly Evaluation", func(t *testing.T) {
+		code, body := createThreshold(map[string]interface{}{"threshold_type": "REFUEL_FREQUENCY", "value": 1, "unit": "COUNT"})
+		require.Equal(t, http.StatusCreated, code, body)
+		code, body = createThreshold(map[string]interface{}{"threshold_type": "EFFICIENCY_DROP", "value": 10})
+		require.Equal(t, http.StatusCreated, code, body)
+
+		evaluate := func() []string {
+			w := postJSON(tf, "/api/v1/fuel/thresholds/evaluate", nil, token)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			var types []string
+			for _, alert := range decodeBody(t, w)["alerts"].([]interface{}) {
+				types = append(types, alert.(map[string]interface{})["alert_type"].(string))
+			}
+			return types
+		}
+
+		// The day's excess consumption is already alerted; frequency and efficiency are new
+		assert.ElementsMatch(t, []string{"REFUEL_FREQUENCY", "EFFICIENCY_DROP"}, evaluate())
+		assert.Empty(t, evaluate())
+
+		drop := alertsOf(models.FuelAlertTypeEfficiencyDrop)
+		require.Len(t, drop, 1)
+		assert.InDelta(t, 5, *drop[0].ExpectedValue, 0.01)
+		assert.Equal(t, models.AlertSeverityMedium, drop[0].Severity)
+	})
+
+	t.Run("Unresolved Alerts Escalate", func(t *testing.T) {
+		alert := alertsOf(models.FuelAlertTypeEfficiencyDrop)[0]
+		require.NoError(t, tf.DB.Model(&models.FuelAlert{}).Where("id = ?", alert.ID).
+			Update("created_at", time.Now().Add(-3*time.Hour)).Error)
+
+		escalated, err := tf.Services.FuelService.EscalateFuelAlerts(2 * time.Hour)
+		require.NoError(t, err)
+		require.Len(t, escalated, 1)
+		assert.Equal(t, 1, escalated[0].EscalationLevel)
+		assert.Equal(t, models.AlertSeverityHigh, escalated[0].Severity)
+		assert.True(t, escalated[0].IsNotified)
+
+		// Not again until another timeout passes since the last escalation
+		escalated, err = tf.Services.FuelService.EscalateFuelAlerts(2 * time.Hour)
+		require.NoError(t, err)
+		assert.Empty(t, escalated)
+
+		require.NoError(t, tf.DB.Model(&models.FuelAlert{}).Where("id = ?", alert.ID).
+			Update("last_escalated_at", time.Now().Add(-3*time.Hour)).Error)
+		escalated, err = tf.Services.FuelService.EscalateFuelAlerts(2 * time.Hour)
+		require.NoError(t, err)
+		require.Len(t, escalated, 1)
+		assert.Equal(t, 2, escalated[0].EscalationLevel)
+		assert.Equal(t, models.AlertSeverityCritical, escalated[0].Severity)
+
+		// Resolving stops escalation
+		w := postJSON(tf, fmt.Sprintf("/api/v1/fuel/alerts/%d/resolve", alert.ID),
+			map[string]string{"notes": "Injector replaced"}, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		require.NoError(t, tf.DB.Model(&models.FuelAlert{}).Where("id = ?", alert.ID).
+			Update("last_escalated_at", time.Now().Add(-3*time.Hour)).Error)
+		escalated, err = tf.Services.FuelService.EscalateFuelAlerts(2 * time.Hour)
+		require.NoError(t, err)
+		assert.Empty(t, escalated)
+	})
+}
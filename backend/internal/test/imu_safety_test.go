@@ -0,0 +1,160 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gravity = 9.80665
+
+// tiltedMount returns an IMU sample as read by a sensor mounted on its side and tilted back 30°,
+// given the acceleration (m/s², forward, left, up) and rotation (deg/s) in the vehicle frame
+func tiltedMount(at time.Time, accel, gyro [3]float64, speed float64) map[string]interface{} {
+	forward, left, up := [3]float64{0, -0.866025, 0.5}, [3]float64{1, 0, 0}, [3]float64{0, 0.5, 0.866025}
+	toDevice := func(v [3]float64) [3]float64 {
+		var d [3]float64
+		for i := range d {
+			d[i] = forward[i]*v[0] + left[i]*v[1] + up[i]*v[2]
+		}
+		return d
+	}
+	a, g := toDevice(accel), toDevice(gyro)
+	return map[string]interface{}{
+		"timestamp": at.Format(time.RFC3339Nano), "ax": a[0], "ay": a[1], "az": a[2],
+		"gx": g[0], "gy": g[1], "gz": g[2], "speed": speed,
+	}
+}
+
+func TestIMUCorneringAndImpact(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+	driver, err := tf.CreateTestDriver("Imu Driver", "+919800000201", "MH1420120001")
+	require.NoError(t, err)
+	vehicle, err := tf.CreateTestVehicle("MH12IM0001", "TRUCK")
+	require.NoError(t, err)
+	trip, err := tf.CreateTestTrip("Pune", "Nashik", driver.ID, vehicle.ID)
+	require.NoError(t, err)
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, tf.DB.Model(trip).Updates(map[string]interface{}{
+		"status": models.TripStatusInProgress, "actual_pickup_time": start,
+	}).Error)
+
+	// Ten samples a second; each phase follows on from the last
+	clock := start
+	phase := func(seconds float64, accel, gyro [3]float64, speed func(elapsed float64) float64) []map[string]interface{} {
+		var samples []map[string]interface{}
+		for i := 0; i < int(seconds*10); i++ {
+			samples = append(samples, tiltedMount(clock, accel, gyro, speed(float64(i)/10)))
+			clock = clock.Add(100 * time.Millisecond)
+		}
+		return samples
+	}
+	steady := func(kmh float64) func(float64) float64 { return func(float64) float64 { return kmh } }
+	ingest := func(samples []map[string]interface{}) services.IMUAnalysis {
+		w := postJSON(tf, "/api/v1/telemetry/imu", map[string]interface{}{
+			"vehicle_id": vehicle.ID, "latitude": 19.99, "longitude": 73.79, "samples": samples,
+		}, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var analysis services.IMUAnalysis
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &analysis))
+		return analysis
+	}
+
+	// Parked, then braking in a straight line, teaches the mounting
+	analysis := ingest(phase(3, [3]float64{0, 0, gravity}, [3]float64{}, steady(0)))
+	assert.Equal(t, 30, analysis.Calibration.UpSamples)
+	assert.False(t, analysis.Calibration.Calibrated)
+	analysis = ingest(phase(3, [3]float64{-3, 0, gravity}, [3]float64{}, func(s float64) float64 { return 60 - s*10.8 }))
+	assert.True(t, analysis.Calibration.Calibrated)
+	assert.Empty(t, analysis.Events)
+
+	// A 0.53 g left turn held for a second and a half, then straightening up
+	turn := append(phase(1.5, [3]float64{0, 5.2, gravity}, [3]float64{0, 0, 25}, steady(40)),
+		phase(1, [3]float64{0, 0, gravity}, [3]float64{}, steady(40))...)
+	analysis = ingest(turn)
+	require.Len(t, analysis.Events, 1)
+	cornering := analysis.Events[0]
+	assert.Equal(t, models.SafetyEventHarshCornering, cornering.Type)
+	assert.Equal(t, models.SeverityHigh, cornering.Severity)
+	assert.InDelta(t, 0.53, cornering.Value, 0.01)
+	require.NotNil(t, cornering.DriverID)
+	assert.Equal(t, driver.ID, *cornering.DriverID)
+
+	// A jolt through a pothole is not a turn
+	analysis = ingest(phase(0.1, [3]float64{0, 6, gravity + 8}, [3]float64{}, steady(40)))
+	assert.Empty(t, analysis.Events)
+
+	// A 4.5 g frontal impact two seconds into the batch
+	crash := append(phase(2, [3]float64{0, 0, gravity}, [3]float64{}, steady(50)),
+		phase(0.1, [3]float64{-4.5 * gravity, 0, gravity}, [3]float64{}, steady(50))...)
+	impactAt := clock.Add(-100 * time.Millisecond)
+	crash = append(crash, phase(2, [3]float64{0, 0, gravity}, [3]float64{}, steady(0))...)
+	analysis = ingest(crash)
+	require.Len(t, analysis.Events, 1)
+	impact := analysis.Events[0]
+	assert.Equal(t, models.SafetyEventImpact, impact.Type)
+	assert.Equal(t, models.SeverityCritical, impact.Severity)
+	assert.InDelta(t, 4.5, impact.Value, 0.01)
+	assert.Equal(t, 19.99, impact.Latitude)
+	require.NotNil(t, impact.TripID)
+	assert.Equal(t, trip.ID, *impact.TripID)
+	assert.Equal(t, driver.ID, *impact.DriverID)
+
+	var trace models.SafetyEventTrace
+	w := sendJSON(tf, "GET", fmt.Sprintf("/api/v1/safety/events/%d/trace", impact.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &trace))
+	assert.False(t, trace.Complete)
+	assert.True(t, trace.TriggeredAt.Equal(impactAt))
+	// Ten seconds before, in the vehicle frame
+	assert.True(t, trace.Samples[0].Timestamp.Equal(impactAt.Add(-10*time.Second)))
+	assert.Len(t, trace.Samples, 101+20)
+	assert.InDelta(t, -4.5*gravity, trace.Samples[100].Longitudinal, 0.01)
+
+	// A harder second hit within the same collision raises the impact rather than adding one
+	tail := append(phase(0.1, [3]float64{0, 5 * gravity, gravity}, [3]float64{}, steady(0)),
+		phase(3, [3]float64{0, 0, gravity}, [3]float64{}, steady(0))...)
+	analysis = ingest(tail)
+	assert.Empty(t, analysis.Events)
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/safety/events/%d/trace", impact.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &trace))
+	assert.True(t, trace.Complete)
+	assert.Len(t, trace.Samples, 101+50)
+	var stored models.SafetyEvent
+	require.NoError(t, tf.DB.First(&stored, impact.ID).Error)
+	assert.InDelta(t, 5, stored.Value, 0.01)
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/safety/events/%d/trace", cornering.ID), nil, token)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Validation, and starting the mounting over
+	w = postJSON(tf, "/api/v1/telemetry/imu", map[string]interface{}{
+		"vehicle_id": vehicle.ID, "frame": "sideways", "samples": turn[:1],
+	}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = postJSON(tf, "/api/v1/telemetry/imu", map[string]interface{}{"vehicle_id": 999999, "samples": turn[:1]}, token)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = sendJSON(tf, "DELETE", fmt.Sprintf("/api/v1/telemetry/imu/calibration/%d", vehicle.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code)
+	var calibrations int64
+	tf.DB.Model(&models.IMUCalibration{}).Where("vehicle_id = ?", vehicle.ID).Count(&calibrations)
+	assert.Zero(t, calibrations)
+}
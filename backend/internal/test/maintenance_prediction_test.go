@@ -0,0 +1,177 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPredictiveMaintenance(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+
+	overheating, err := tf.CreateTestVehicle("MH12PM3701", "TRUCK")
+	require.NoError(t, err)
+	thirsty, err := tf.CreateTestVehicle("MH12PM3702", "TRUCK")
+	require.NoError(t, err)
+	healthy, err := tf.CreateTestVehicle("MH12PM3703", "TRUCK")
+	require.NoError(t, err)
+
+	now := time.Now()
+	ptr := func(v float64) *float64 { return &v }
+
+	// Coolant peaks climb a degree a day towards the 110°C limit while the battery charges normally
+	noon := now.UTC().Truncate(24 * time.Hour).Add(12 * time.Hour)
+	for day := 10; day >= 1; day-- {
+		for hour := 0; hour < 3; hour++ {
+			at := noon.AddDate(0, 0, -day).Add(time.Duration(hour) * time.Hour)
+			require.NoError(t, tf.DB.Create(&models.TelemetryLog{
+				VehicleID: overheating.ID, Timestamp: at,
+				CoolantTemp:    ptr(96 + float64(10-day) - float64(hour)),
+				BatteryVoltage: ptr(14.1),
+			}).Error)
+			require.NoError(t, tf.DB.Create(&models.TelemetryLog{
+				VehicleID: healthy.ID, Timestamp: at, CoolantTemp: ptr(88), BatteryVoltage: ptr(14.0),
+			}).Error)
+		}
+	}
+
+	// A misfire code that keeps coming back after being cleared
+	for i, ago := range []int{40, 20, 2} {
+		code := models.DiagnosticCode{
+			VehicleID: overheating.ID, Code: "P0300", Severity: models.DTCSeverityMedium, Source: "OBDII",
+			FirstSeen: now.AddDate(0, 0, -ago), LastSeen: now.AddDate(0, 0, -ago).Add(time.Hour),
+		}
+		require.NoError(t, tf.DB.Create(&code).Error)
+		if i < 2 {
+			require.NoError(t, tf.DB.Model(&code).Update("is_active", false).Error)
+		}
+	}
+
+	// Fuel efficiency falls from 5 km/L to 3.5 km/L over the last three fills
+	odometer := 10000.0
+	for i, kmPerLitre := range []float64{5, 5.1, 4.9, 5, 5, 3.5, 3.6, 3.4} {
+		odometer += 500
+		require.NoError(t, tf.DB.Create(&models.FuelEvent{
+			VehicleID: thirsty.ID, Liters: 500 / kmPerLitre, AmountINR: 9000, OdometerKm: odometer,
+			Status: models.FuelEventStatusVerified, CreatedAt: now.AddDate(0, 0, -24+3*i),
+		}).Error)
+	}
+
+	w := sendJSON(tf, "POST", "/api/v1/maintenance/predictions/run", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result services.MaintenanceForecastResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 3, result.Vehicles)
+	require.Len(t, result.Predictions, 3)
+	require.Len(t, result.WorkOrders, 3)
+	require.Len(t, result.Events, 3)
+
+	byComponent := make(map[models.MaintenanceComponent]models.MaintenancePrediction)
+	for _, prediction := range result.Predictions {
+		assert.NotEqual(t, healthy.ID, prediction.VehicleID)
+		byComponent[prediction.Component] = prediction
+	}
+
+	cooling := byComponent[models.ComponentCoolingSystem]
+	assert.Equal(t, overheating.ID, cooling.VehicleID)
+	assert.Equal(t, models.MaintenanceRiskCritical, cooling.RiskLevel)
+	require.NotNil(t, cooling.PredictedFailureAt)
+	assert.WithinDuration(t, now.AddDate(0, 0, 5), *cooling.PredictedFailureAt, 3*24*time.Hour)
+	require.Len(t, cooling.Signals, 1)
+	assert.Equal(t, "coolant_temp", cooling.Signals[0].Name)
+	require.NotNil(t, cooling.Signals[0].SlopePerDay)
+	assert.InDelta(t, 1.0, *cooling.Signals[0].SlopePerDay, 0.05)
+
+	engine := byComponent[models.ComponentEngine]
+	assert.Equal(t, overheating.ID, engine.VehicleID)
+	assert.Equal(t, models.MaintenanceRiskHigh, engine.RiskLevel)
+	assert.Contains(t, engine.Signals[0].Detail, "recurred 3 times")
+
+	fuel := byComponent[models.ComponentFuelSystem]
+	assert.Equal(t, thirsty.ID, fuel.VehicleID)
+	assert.Equal(t, models.MaintenanceRiskCritical, fuel.RiskLevel)
+	assert.Equal(t, "FUEL_EFFICIENCY", fuel.Signals[0].Source)
+
+	// Recommended work orders do not take the vehicle off the road
+	var coolingOrder models.WorkOrder
+	require.NotNil(t, cooling.WorkOrderID)
+	require.NoError(t, tf.DB.First(&coolingOrder, *cooling.WorkOrderID).Error)
+	assert.Equal(t, models.WorkOrderStatusRecommended, coolingOrder.Status)
+	assert.Equal(t, "CRITICAL", coolingOrder.Priority)
+	var vehicle models.Vehicle
+	require.NoError(t, tf.DB.First(&vehicle, overheating.ID).Error)
+	assert.Equal(t, models.VehicleStatusActive, vehicle.Status)
+
+	for _, event := range result.Events {
+		assert.Equal(t, services.MaintenanceDueEventName, event.Event)
+		assert.Equal(t, "PREDICTION", event.Source)
+		assert.NotNil(t, event.WorkOrderID)
+	}
+
+	// The fuel service's efficiency insight reports the forecast
+	updates, err := tf.Services.FuelService.GetFuelEfficiencyUpdates([]uint32{uint32(thirsty.ID), uint32(healthy.ID)}, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, updates, 2)
+	assert.True(t, updates[0].MaintenanceRequired)
+	assert.False(t, updates[1].MaintenanceRequired)
+
+	// Re-running keeps one prediction per component and does not notify again
+	w = sendJSON(tf, "POST", fmt.Sprintf("/api/v1/maintenance/predictions/run?vehicle_id=%d", overheating.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Len(t, result.Predictions, 2)
+	assert.Empty(t, result.WorkOrders)
+	assert.Empty(t, result.Events)
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/maintenance/predictions?vehicle_id=%d", overheating.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var listed []models.MaintenancePrediction
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(t, listed, 2)
+	assert.Equal(t, models.ComponentCoolingSystem, listed[0].Component) // Riskiest first
+
+	// Completing the cooling work order resolves the prediction, and older readings stop counting
+	w = postJSON(tf, fmt.Sprintf("/api/v1/maintenance/work-order/%d/resolve", coolingOrder.ID), map[string]interface{}{
+		"notes": "Replaced thermostat", "cost_parts": 1800, "cost_labor": 600,
+	}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resolved models.MaintenancePrediction
+	require.NoError(t, tf.DB.First(&resolved, cooling.ID).Error)
+	assert.Equal(t, models.MaintenancePredictionResolved, resolved.Status)
+	assert.NotNil(t, resolved.ResolvedAt)
+
+	// Once the misfire stops being reported, the engine forecast clears and its order is withdrawn
+	require.NoError(t, tf.DB.Where("vehicle_id = ? AND code = ?", overheating.ID, "P0300").
+		Delete(&models.DiagnosticCode{}).Error)
+	w = sendJSON(tf, "POST", fmt.Sprintf("/api/v1/maintenance/predictions/run?vehicle_id=%d", overheating.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Empty(t, result.Predictions)
+	assert.Equal(t, 1, result.Cleared)
+	var engineOrder models.WorkOrder
+	require.NoError(t, tf.DB.First(&engineOrder, *engine.WorkOrderID).Error)
+	assert.Equal(t, "CANCELLED", engineOrder.Status)
+
+	w = sendJSON(tf, "GET", "/api/v1/maintenance/predictions?status=ALL&component=cooling_system", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	assert.Len(t, listed, 1)
+
+	w = sendJSON(tf, "POST", "/api/v1/maintenance/predictions/run?vehicle_id=99999", nil, token)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,335 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/repositories"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAdminEmail    = "ops@example.com"
+	testAdminPassword = "Fleet!Flow2024"
+)
+
+func TestPasswordLogin(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	createAdmin := func(t *testing.T) *models.UserAccount {
+		tf.CleanDatabase()
+		user := &models.UserAccount{
+			Phone:    TestAdminPhone,
+			Email:    testAdminEmail,
+			Password: testAdminPassword, // hashed by BeforeCreate
+			Role:     models.RoleAdmin,
+			IsActive: true,
+		}
+		require.NoError(t, tf.DB.Create(user).Error)
+		return user
+	}
+
+	t.Run("Valid Credentials", func(t *testing.T) {
+		createAdmin(t)
+
+		w := postJSON(tf, "/api/v1/auth/password/login", map[string]string{
+			"email":    "OPS@example.com",
+			"password": testAdminPassword,
+		}, "")
+
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		body := decodeBody(t, w)
+		assert.NotEmpty(t, body["access_token"])
+		assert.NotEmpty(t, body["refresh_token"])
+	})
+
+	t.Run("Lockout After Max Attempts", func(t *testing.T) {
+		user := createAdmin(t)
+		maxAttempts := models.DefaultSecuritySettings().MaxLoginAttempts
+
+		for i := 1; i < maxAttempts; i++ {
+			w := postJSON(tf, "/api/v1/auth/password/login", map[string]string{
+				"email":    testAdminEmail,
+				"password": "wrong-password",
+			}, "")
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		}
+
+		w := postJSON(tf, "/api/v1/auth/password/login", map[string]string{
+			"email":    testAdminEmail,
+			"password": "wrong-password",
+		}, "")
+		assert.Equal(t, http.StatusLocked, w.Code)
+
+		// Even the right password is refused while locked
+		w = postJSON(tf, "/api/v1/auth/password/login", map[string]string{
+			"email":    testAdminEmail,
+			"password": testAdminPassword,
+		}, "")
+		assert.Equal(t, http.StatusLocked, w.Code)
+
+		var count int64
+		tf.DB.Model(&models.AuditLog{}).
+			Where("action = ? AND user_id = ?", models.AuditActionAccountLocked, user.ID).
+			Count(&count)
+		assert.Equal(t, int64(1), count, "ACCOUNT_LOCKED audit event should be written once")
+
+		// Once the lock has expired a single mistyped password does not lock the account again
+		require.NoError(t, tf.DB.Model(user).Update("locked_until", time.Now().Add(-time.Minute)).Error)
+		w = postJSON(tf, "/api/v1/auth/password/login", map[string]string{
+			"email":    testAdminEmail,
+			"password": "wrong-password",
+		}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code, w.Body.String())
+		var unlocked models.UserAccount
+		require.NoError(t, tf.DB.First(&unlocked, user.ID).Error)
+		assert.Equal(t, 1, unlocked.FailedLoginAttempts)
+		assert.Nil(t, unlocked.LockedUntil)
+
+		w = postJSON(tf, "/api/v1/auth/password/login", map[string]string{
+			"email":    testAdminEmail,
+			"password": testAdminPassword,
+		}, "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	})
+
+	t.Run("TOTP Second Factor And Recovery Code", func(t *testing.T) {
+		user := createAdmin(t)
+		token, err := tf.GenerateJWTToken(user)
+		require.NoError(t, err)
+
+		// Enroll
+		w := postJSON(tf, "/api/v1/auth/2fa/totp/setup", nil, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		secret := decodeBody(t, w)["secret"].(string)
+
+		code, err := services.GenerateTOTPCode(secret, time.Now())
+		require.NoError(t, err)
+		w = postJSON(tf, "/api/v1/auth/2fa/totp/enable", map[string]string{"code": code}, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		recoveryCodes := decodeBody(t, w)["recovery_codes"].([]interface{})
+		require.Len(t, recoveryCodes, 10)
+
+		// Password step now returns a challenge instead of tokens
+		w = postJSON(tf, "/api/v1/auth/password/login", map[string]string{
+			"email":    testAdminEmail,
+			"password": testAdminPassword,
+		}, "")
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		challenge := decodeBody(t, w)
+		assert.Nil(t, challenge["access_token"])
+		mfaToken := challenge["mfa_token"].(string)
+
+		// The challenge token must not work as an access token
+		req, _ := http.NewRequest("GET", "/api/v1/auth/profile", nil)
+		req.Header.Set("Authorization", "Bearer "+mfaToken)
+		rec := httptest.NewRecorder()
+		tf.Router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		w = postJSON(tf, "/api/v1/auth/password/verify-2fa", map[string]string{
+			"mfa_token": mfaToken,
+			"code":      "000000",
+		}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		// A recovery code works exactly once
+		recovery := recoveryCodes[0].(string)
+		w = postJSON(tf, "/api/v1/auth/password/verify-2fa", map[string]string{
+			"mfa_token": mfaToken,
+			"code":      recovery,
+		}, "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.NotEmpty(t, decodeBody(t, w)["access_token"])
+
+		w = postJSON(tf, "/api/v1/auth/password/verify-2fa", map[string]string{
+			"mfa_token": mfaToken,
+			"code":      recovery,
+		}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Recovery Code Is Spent Once Under Concurrent Logins", func(t *testing.T) {
+		user := createAdmin(t)
+		token, err := tf.GenerateJWTToken(user)
+		require.NoError(t, err)
+
+		w := postJSON(tf, "/api/v1/auth/2fa/totp/setup", nil, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		secret := decodeBody(t, w)["secret"].(string)
+		code, err := services.GenerateTOTPCode(secret, time.Now())
+		require.NoError(t, err)
+		w = postJSON(tf, "/api/v1/auth/2fa/totp/enable", map[string]string{"code": code}, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		// Both logins read the code as unused before either spends it; only one claim succeeds
+		repo := repositories.NewPostgresAuthRepository(tf.DB)
+		unused, err := repo.GetUnusedRecoveryCodes(user.ID)
+		require.NoError(t, err)
+		require.NotEmpty(t, unused)
+		claimed, err := repo.MarkRecoveryCodeUsed(unused[0].ID)
+		require.NoError(t, err)
+		assert.True(t, claimed)
+		claimed, err = repo.MarkRecoveryCodeUsed(unused[0].ID)
+		require.NoError(t, err)
+		assert.False(t, claimed)
+
+		recovery := decodeBody(t, w)["recovery_codes"].([]interface{})[1].(string)
+		mfaToken, err := tf.Services.JWTService.GenerateMFAToken(user.ID)
+		require.NoError(t, err)
+		results := make(chan int, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				results <- postJSON(tf, "/api/v1/auth/password/verify-2fa", map[string]string{"mfa_token": mfaToken, "code": recovery}, "").Code
+			}()
+		}
+		statuses := []int{<-results, <-results}
+		assert.ElementsMatch(t, []int{http.StatusOK, http.StatusUnauthorized}, statuses)
+	})
+
+	t.Run("TOTP Code Is Single Use", func(t *testing.T) {
+		user := createAdmin(t)
+		token, err := tf.GenerateJWTToken(user)
+		require.NoError(t, err)
+
+		w := postJSON(tf, "/api/v1/auth/2fa/totp/setup", nil, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		secret := decodeBody(t, w)["secret"].(string)
+		code, err := services.GenerateTOTPCode(secret, time.Now())
+		require.NoError(t, err)
+		w = postJSON(tf, "/api/v1/auth/2fa/totp/enable", map[string]string{"code": code}, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		// The code that enabled TOTP cannot also complete a login, nor can an older one
+		mfaToken, err := tf.Services.JWTService.GenerateMFAToken(user.ID)
+		require.NoError(t, err)
+		previous, err := services.GenerateTOTPCode(secret, time.Now().Add(-30*time.Second))
+		require.NoError(t, err)
+		for _, replayed := range []string{code, previous} {
+			w = postJSON(tf, "/api/v1/auth/password/verify-2fa", map[string]string{"mfa_token": mfaToken, "code": replayed}, "")
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		}
+		next, err := services.GenerateTOTPCode(secret, time.Now().Add(30*time.Second))
+		require.NoError(t, err)
+		w = postJSON(tf, "/api/v1/auth/password/verify-2fa", map[string]string{"mfa_token": mfaToken, "code": next}, "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	})
+
+	t.Run("Deactivated Account Needs The Password", func(t *testing.T) {
+		user := createAdmin(t)
+		require.NoError(t, tf.DB.Model(user).Update("is_active", false).Error)
+
+		w := postJSON(tf, "/api/v1/auth/password/login", map[string]string{"email": testAdminEmail, "password": "wrong-password"}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.NotContains(t, w.Body.String(), "deactivated")
+		w = postJSON(tf, "/api/v1/auth/password/login", map[string]string{"email": testAdminEmail, "password": testAdminPassword}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "deactivated")
+	})
+
+	t.Run("Email Change Requires Verification", func(t *testing.T) {
+		user := createAdmin(t)
+		token, err := tf.GenerateJWTToken(user)
+		require.NoError(t, err)
+
+		w := putJSON(tf, "/api/v1/auth/profile", map[string]string{"email": "not-an-email"}, token)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// The new address is pending; login still uses the old one
+		w = putJSON(tf, "/api/v1/auth/profile", map[string]string{"email": "Fleet.Ops@example.com"}, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		profile := decodeBody(t, w)
+		assert.Equal(t, testAdminEmail, profile["email"])
+		assert.Equal(t, "fleet.ops@example.com", profile["pending_email"])
+		w = postJSON(tf, "/api/v1/auth/password/login", map[string]string{"email": "fleet.ops@example.com", "password": testAdminPassword}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		verification, err := tf.Services.AuthService.RequestEmailChange(user.ID, "fleet.ops@example.com")
+		require.NoError(t, err)
+		w = postJSON(tf, "/api/v1/auth/email/verify", map[string]string{"token": "not-the-token"}, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = postJSON(tf, "/api/v1/auth/email/verify", map[string]string{"token": verification}, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var stored models.UserAccount
+		require.NoError(t, tf.DB.First(&stored, user.ID).Error)
+		assert.Equal(t, "fleet.ops@example.com", stored.Email)
+		assert.NotNil(t, stored.EmailVerifiedAt)
+		assert.Empty(t, stored.PendingEmail)
+		// Tokens are single use
+		w = postJSON(tf, "/api/v1/auth/email/verify", map[string]string{"token": verification}, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// Another account's address cannot be claimed
+		other := &models.UserAccount{Phone: "+919800000555", Email: "dispatch@example.com", Role: models.RoleDispatcher, IsActive: true}
+		require.NoError(t, tf.DB.Create(other).Error)
+		w = putJSON(tf, "/api/v1/auth/profile", map[string]string{"email": "dispatch@example.com"}, token)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Change Password Enforces Policy", func(t *testing.T) {
+		user := createAdmin(t)
+		token, err := tf.GenerateJWTToken(user)
+		require.NoError(t, err)
+
+		w := putJSON(tf, "/api/v1/auth/password", map[string]string{
+			"current_password": testAdminPassword,
+			"new_password":     "weak",
+		}, token)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = putJSON(tf, "/api/v1/auth/password", map[string]string{
+			"current_password": "not-my-password",
+			"new_password":     "Str0nger!Password",
+		}, token)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = putJSON(tf, "/api/v1/auth/password", map[string]string{
+			"current_password": testAdminPassword,
+			"new_password":     "Str0nger!Password",
+		}, token)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = postJSON(tf, "/api/v1/auth/password/login", map[string]string{
+			"email":    testAdminEmail,
+			"password": "Str0nger!Password",
+		}, "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	})
+}
+
+func postJSON(tf *TestFramework, url string, payload interface{}, token string) *httptest.ResponseRecorder {
+	return sendJSON(tf, "POST", url, payload, token)
+}
+
+func putJSON(tf *TestFramework, url string, payload interface{}, token string) *httptest.ResponseRecorder {
+	return sendJSON(tf, "PUT", url, payload, token)
+}
+
+func sendJSON(tf *TestFramework, method, url string, payload interface{}, token string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest(method, url, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	tf.Router.ServeHTTP(w, req)
+	return w
+}
+
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body
+}
@@ -0,0 +1,149 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRiskEngine(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	// sendWithHeaders issues a request through a local proxy with extra client headers (device, forwarded IP)
+	sendWithHeaders := func(method, url string, payload interface{}, headers map[string]string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, url, bytes.NewBuffer(body))
+		req.RemoteAddr = "127.0.0.1:40000"
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		tf.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("OTP Brute Force Is Blocked Until Investigated", func(t *testing.T) {
+		tf.CleanDatabase()
+		_, err := tf.CreateTestUser(TestDriverPhone, models.RoleDriver)
+		require.NoError(t, err)
+		require.NoError(t, tf.DB.Create(&models.OTPVerification{
+			Phone:     TestDriverPhone,
+			OTP:       TestOTP,
+			ExpiresAt: time.Now().Add(5 * time.Minute),
+		}).Error)
+
+		wrong := map[string]string{"phone": TestDriverPhone, "otp": "000000"}
+		for i := 0; i < 4; i++ {
+			w := postJSON(tf, "/api/v1/auth/otp/verify", wrong, "")
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		}
+		w := postJSON(tf, "/api/v1/auth/otp/verify", wrong, "")
+		require.Equal(t, http.StatusTooManyRequests, w.Code, w.Body.String())
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+		// The right code is refused while the block is active
+		w = postJSON(tf, "/api/v1/auth/otp/verify", map[string]string{"phone": TestDriverPhone, "otp": TestOTP}, "")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+		var event models.SecurityEvent
+		require.NoError(t, tf.DB.Where("is_blocked = ?", true).First(&event).Error)
+		assert.Equal(t, models.BlockScopeIdentifier, event.BlockScope)
+		assert.Contains(t, event.RiskFactors, "otp_brute_force")
+		assert.NotEmpty(t, event.BlockReason)
+
+		// An admin reviews the event and lifts the block
+		admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+		require.NoError(t, err)
+		token, err := tf.GenerateJWTToken(admin)
+		require.NoError(t, err)
+
+		w = sendJSON(tf, "GET", "/api/v1/admin/security-events?is_blocked=true", nil, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, float64(1), decodeBody(t, w)["total"])
+
+		w = postJSON(tf, fmt.Sprintf("/api/v1/admin/security-events/%d/investigate", event.ID), map[string]interface{}{
+			"notes":      "Driver mistyped after SIM swap, confirmed by phone",
+			"lift_block": true,
+		}, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		investigated := decodeBody(t, w)
+		assert.Equal(t, true, investigated["is_investigated"])
+		assert.Equal(t, float64(admin.ID), investigated["investigated_by"])
+
+		w = postJSON(tf, "/api/v1/auth/otp/verify", map[string]string{"phone": TestDriverPhone, "otp": TestOTP}, "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	})
+
+	t.Run("Impossible Travel From New Device", func(t *testing.T) {
+		tf.CleanDatabase()
+		require.NoError(t, tf.DB.Create(&models.UserAccount{
+			Phone:    TestAdminPhone,
+			Email:    testAdminEmail,
+			Password: testAdminPassword,
+			Role:     models.RoleAdmin,
+			IsActive: true,
+		}).Error)
+		credentials := map[string]string{"email": testAdminEmail, "password": testAdminPassword}
+
+		locator, err := services.NewIPRangeLocator(strings.NewReader(
+			"start_ip,end_ip,latitude,longitude,city,country\n" +
+				"203.0.113.0,203.0.113.255,19.0760,72.8777,Mumbai,IN\n" +
+				"198.51.100.0,198.51.100.255,51.5074,-0.1278,London,GB\n"))
+		require.NoError(t, err)
+		tf.Services.RiskService.SetGeoIPLocator(locator)
+		defer tf.Services.RiskService.SetGeoIPLocator(nil)
+
+		// Mumbai from the usual laptop, which claims to be in London
+		w := sendWithHeaders("POST", "/api/v1/auth/password/login", credentials, map[string]string{
+			"X-Device-ID":        "laptop-1",
+			"X-Forwarded-For":    "203.0.113.10",
+			"X-Client-Latitude":  "51.5074",
+			"X-Client-Longitude": "-0.1278",
+		})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var user models.UserAccount
+		require.NoError(t, tf.DB.Where("email = ?", testAdminEmail).First(&user).Error)
+		require.NotNil(t, user.LastLoginLatitude)
+		assert.InDelta(t, 19.0760, *user.LastLoginLatitude, 0.001)
+
+		// From London on a new device, days after the Mumbai login: an ordinary trip
+		daysAgo := time.Now().Add(-3 * 24 * time.Hour)
+		require.NoError(t, tf.DB.Model(&user).Update("last_login_located_at", daysAgo).Error)
+		w = sendWithHeaders("POST", "/api/v1/auth/password/login", credentials, map[string]string{
+			"X-Device-ID":     "hotel-1",
+			"X-Forwarded-For": "198.51.100.20",
+		})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		// Back in Mumbai on the laptop, then minutes later from London on an unknown device
+		w = sendWithHeaders("POST", "/api/v1/auth/password/login", credentials, map[string]string{
+			"X-Device-ID":     "laptop-1",
+			"X-Forwarded-For": "203.0.113.10",
+		})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		w = sendWithHeaders("POST", "/api/v1/auth/password/login", credentials, map[string]string{
+			"X-Device-ID":     "unknown-1",
+			"X-Forwarded-For": "198.51.100.20",
+		})
+		require.Equal(t, http.StatusTooManyRequests, w.Code, w.Body.String())
+
+		var event models.SecurityEvent
+		require.NoError(t, tf.DB.Where("is_blocked = ?", true).First(&event).Error)
+		assert.Contains(t, event.RiskFactors, "impossible_travel")
+		assert.Contains(t, event.RiskFactors, "new_device")
+		assert.Equal(t, testAdminEmail, event.Identifier)
+	})
+}
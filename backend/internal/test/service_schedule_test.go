@@ -0,0 +1,160 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceSchedules(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+
+	oilChange := models.MaintenanceTask{Name: "Engine Oil Change", Category: "PREVENTIVE"}
+	require.NoError(t, tf.DB.Create(&oilChange).Error)
+	airFilter := models.MaintenanceTask{Name: "Air Filter Replacement", Category: "PREVENTIVE"}
+	require.NoError(t, tf.DB.Create(&airFilter).Error)
+
+	// Trucks get an oil change every 20,000 km, 500 engine hours or 6 months
+	w := sendJSON(tf, "PUT", "/api/v1/maintenance/schedule-templates", map[string]interface{}{
+		"vehicle_type": "TRUCK", "maintenance_task_id": oilChange.ID,
+		"interval_mileage": 20000, "interval_engine_hours": 500, "interval_months": 6, "due_soon_engine_hours": 30,
+	}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = sendJSON(tf, "PUT", "/api/v1/maintenance/schedule-templates", map[string]interface{}{
+		"vehicle_type": "VAN", "maintenance_task_id": airFilter.ID, "interval_months": 12,
+	}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = sendJSON(tf, "PUT", "/api/v1/maintenance/schedule-templates", map[string]interface{}{
+		"vehicle_type": "TRUCK", "maintenance_task_id": airFilter.ID,
+	}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendJSON(tf, "GET", "/api/v1/maintenance/schedule-templates?vehicle_type=truck", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var templates []models.ServiceScheduleTemplate
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &templates))
+	require.Len(t, templates, 1)
+	assert.True(t, templates[0].IsActive)
+
+	// Creating a truck applies the truck templates only
+	w = postJSON(tf, "/api/v1/vehicles", map[string]interface{}{
+		"license_plate": "MH12SS3801", "make": "Ashok Leyland", "model": "Ecomet", "vehicle_type": "TRUCK",
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	truckID := uint(decodeBody(t, w)["id"].(float64))
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/maintenance/schedules?vehicle_id=%d", truckID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var schedules []models.ServiceSchedule
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &schedules))
+	require.Len(t, schedules, 1)
+	oil := schedules[0]
+	assert.Equal(t, oilChange.ID, oil.MaintenanceTaskID)
+	assert.Equal(t, templates[0].ID, *oil.TemplateID)
+	assert.Equal(t, 20000, oil.NextDueMileage)
+	assert.Equal(t, 500.0, oil.NextDueEngineHours)
+	require.NotNil(t, oil.NextDueDate)
+	assert.WithinDuration(t, time.Now().AddDate(0, 6, 0), *oil.NextDueDate, time.Minute)
+
+	engineHours := func(hours float64) {
+		require.NoError(t, tf.DB.Create(&models.TelemetryLog{VehicleID: truckID, Timestamp: time.Now(), EngineHours: &hours}).Error)
+	}
+	runScheduler := func() services.ServiceSchedulerResult {
+		w := sendJSON(tf, "POST", "/api/v1/maintenance/schedules/run", nil, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var result services.ServiceSchedulerResult
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		return result
+	}
+
+	// Engine hours reach the due-soon window long before the mileage or date
+	engineHours(475)
+	result := runScheduler()
+	require.Len(t, result.DueSoon, 1)
+	assert.Equal(t, "ENGINE_HOURS", result.DueSoon[0].DueReason)
+	assert.Empty(t, result.WorkOrders)
+
+	w = sendJSON(tf, "GET", "/api/v1/maintenance/due", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &schedules))
+	assert.Empty(t, schedules)
+	w = sendJSON(tf, "GET", "/api/v1/maintenance/due?include_due_soon=true", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &schedules))
+	require.Len(t, schedules, 1)
+	assert.Equal(t, models.ServiceDueSoon, schedules[0].DueStatus)
+
+	// Crossing 500 hours opens one work order without grounding the truck
+	engineHours(503)
+	result = runScheduler()
+	require.Len(t, result.Due, 1)
+	require.Len(t, result.WorkOrders, 1)
+	workOrder := result.WorkOrders[0]
+	assert.Equal(t, "OPEN", workOrder.Status)
+	assert.Equal(t, oilChange.ID, *workOrder.MaintenanceTaskID)
+	assert.Contains(t, workOrder.Description, "engine_hours")
+
+	var truck models.Vehicle
+	require.NoError(t, tf.DB.First(&truck, truckID).Error)
+	assert.Equal(t, models.VehicleStatusActive, truck.Status)
+
+	result = runScheduler()
+	assert.Len(t, result.Due, 1)
+	assert.Empty(t, result.WorkOrders)
+
+	// Completing the work order restarts every interval from the current readings
+	require.NoError(t, tf.DB.Model(&truck).Update("total_kilometers", 12400).Error)
+	w = postJSON(tf, fmt.Sprintf("/api/v1/maintenance/work-order/%d/resolve", workOrder.ID), map[string]interface{}{
+		"notes": "Oil and filter changed", "cost_parts": 4200, "cost_labor": 800,
+	}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var serviced models.ServiceSchedule
+	require.NoError(t, tf.DB.First(&serviced, oil.ID).Error)
+	assert.Equal(t, models.ServiceDueOK, serviced.DueStatus)
+	assert.Nil(t, serviced.WorkOrderID)
+	assert.Equal(t, 503.0, serviced.LastEngineHours)
+	assert.Equal(t, 1003.0, serviced.NextDueEngineHours)
+	assert.Equal(t, 32400, serviced.NextDueMileage)
+	require.NotNil(t, serviced.LastPerformedAt)
+
+	// A calendar-only schedule comes due by date
+	van, err := tf.CreateTestVehicle("MH12SS3802", "VAN")
+	require.NoError(t, err)
+	w = postJSON(tf, "/api/v1/maintenance/schedules", map[string]interface{}{
+		"vehicle_id": van.ID, "maintenance_task_id": airFilter.ID, "interval_months": 12,
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var vanSchedule models.ServiceSchedule
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &vanSchedule))
+	require.NoError(t, tf.DB.Model(&vanSchedule).Update("next_due_date", time.Now().Add(-time.Hour)).Error)
+
+	result = runScheduler()
+	require.Len(t, result.Due, 1)
+	assert.Equal(t, "TIME", result.Due[0].DueReason)
+	require.Len(t, result.WorkOrders, 1)
+	assert.Equal(t, van.ID, result.WorkOrders[0].VehicleID)
+
+	w = postJSON(tf, "/api/v1/maintenance/schedules", map[string]interface{}{
+		"vehicle_id": van.ID, "maintenance_task_id": oilChange.ID,
+	}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = postJSON(tf, "/api/v1/maintenance/schedules", map[string]interface{}{
+		"vehicle_id": 99999, "maintenance_task_id": oilChange.ID, "interval_months": 3,
+	}, token)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,168 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionManagement(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	// sendFromDevice issues a request as a particular device
+	sendFromDevice := func(method, url, deviceID string, payload interface{}, token string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Device-ID", deviceID)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		tf.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	loginFrom := func(t *testing.T, deviceID string) (string, string) {
+		w := sendFromDevice("POST", "/api/v1/auth/password/login", deviceID, map[string]string{
+			"email":    testAdminEmail,
+			"password": testAdminPassword,
+		}, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		body := decodeBody(t, w)
+		return body["access_token"].(string), body["refresh_token"].(string)
+	}
+
+	setup := func(t *testing.T) {
+		tf.CleanDatabase()
+		require.NoError(t, tf.DB.Create(&models.UserAccount{
+			Phone:    TestAdminPhone,
+			Email:    testAdminEmail,
+			Password: testAdminPassword,
+			Role:     models.RoleAdmin,
+			IsActive: true,
+		}).Error)
+	}
+
+	t.Run("List And Revoke Sessions", func(t *testing.T) {
+		setup(t)
+		phoneAccess, _ := loginFrom(t, "phone-1")
+		laptopAccess, _ := loginFrom(t, "laptop-1")
+
+		w := sendFromDevice("GET", "/api/v1/auth/sessions", "laptop-1", nil, laptopAccess)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		sessions := decodeBody(t, w)["sessions"].([]interface{})
+		require.Len(t, sessions, 2)
+
+		var phoneSessionID float64
+		for _, s := range sessions {
+			session := s.(map[string]interface{})
+			if !session["current"].(bool) {
+				phoneSessionID = session["id"].(float64)
+			}
+		}
+		require.NotZero(t, phoneSessionID)
+
+		// The lost phone is revoked from the laptop and its access token stops working
+		w = sendFromDevice("DELETE", fmt.Sprintf("/api/v1/auth/sessions/%d", int(phoneSessionID)), "laptop-1", nil, laptopAccess)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = sendFromDevice("GET", "/api/v1/auth/profile", "phone-1", nil, phoneAccess)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = sendFromDevice("GET", "/api/v1/auth/profile", "laptop-1", nil, laptopAccess)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Refresh Token Reuse Revokes Family", func(t *testing.T) {
+		setup(t)
+		_, refresh1 := loginFrom(t, "phone-1")
+
+		w := sendFromDevice("POST", "/api/v1/auth/refresh", "phone-1", map[string]string{"refresh_token": refresh1}, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		refresh2 := decodeBody(t, w)["refresh_token"].(string)
+
+		// Replaying the rotated token is treated as theft
+		w = sendFromDevice("POST", "/api/v1/auth/refresh", "phone-1", map[string]string{"refresh_token": refresh1}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		// ...which also kills the legitimate latest token
+		w = sendFromDevice("POST", "/api/v1/auth/refresh", "phone-1", map[string]string{"refresh_token": refresh2}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		var session models.UserSession
+		require.NoError(t, tf.DB.First(&session).Error)
+		assert.Equal(t, models.SessionRevokedTokenReuse, session.RevokedReason)
+	})
+
+	t.Run("Rotation Keeps The Session Alive", func(t *testing.T) {
+		setup(t)
+		_, refresh := loginFrom(t, "phone-1")
+
+		// The login was almost a refresh token lifetime ago
+		originalExpiry := time.Now().Add(time.Second)
+		require.NoError(t, tf.DB.Model(&models.UserSession{}).Where("1 = 1").Update("expires_at", originalExpiry).Error)
+		require.NoError(t, tf.DB.Model(&models.RefreshToken{}).Where("1 = 1").Update("expires_at", originalExpiry).Error)
+
+		w := sendFromDevice("POST", "/api/v1/auth/refresh", "phone-1", map[string]string{"refresh_token": refresh}, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		refresh = decodeBody(t, w)["refresh_token"].(string)
+
+		time.Sleep(time.Until(originalExpiry) + 100*time.Millisecond)
+		w = sendFromDevice("POST", "/api/v1/auth/refresh", "phone-1", map[string]string{"refresh_token": refresh}, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		access := decodeBody(t, w)["access_token"].(string)
+		w = sendFromDevice("GET", "/api/v1/auth/profile", "phone-1", nil, access)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var session models.UserSession
+		require.NoError(t, tf.DB.First(&session).Error)
+		assert.True(t, session.ExpiresAt.After(time.Now().Add(time.Hour)))
+	})
+
+	t.Run("Refresh Token Bound To Device", func(t *testing.T) {
+		setup(t)
+		_, refresh := loginFrom(t, "phone-1")
+
+		w := sendFromDevice("POST", "/api/v1/auth/refresh", "other-phone", map[string]string{"refresh_token": refresh}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = sendFromDevice("POST", "/api/v1/auth/refresh", "phone-1", map[string]string{"refresh_token": refresh}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "session is revoked after a device mismatch")
+	})
+
+	t.Run("Idle Timeout", func(t *testing.T) {
+		setup(t)
+		idle := func() {
+			at := time.Now().Add(-31 * time.Minute)
+			require.NoError(t, tf.DB.Model(&models.UserSession{}).Where("1 = 1").Update("last_used_at", at).Error)
+		}
+
+		// Without a configured timeout an idle app session stays signed in
+		access, _ := loginFrom(t, "phone-1")
+		idle()
+		w := sendFromDevice("GET", "/api/v1/auth/profile", "phone-1", nil, access)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		tf.Services.JWTService.SetSecuritySettingsSource(func() *models.SecuritySettings {
+			return &models.SecuritySettings{SessionTimeoutMinutes: 30}
+		})
+		defer tf.Services.JWTService.SetSecuritySettingsSource(tf.Services.AuthService.GetSecuritySettings)
+
+		access, refresh := loginFrom(t, "phone-2")
+		idle()
+		w = sendFromDevice("GET", "/api/v1/auth/profile", "phone-2", nil, access)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = sendFromDevice("POST", "/api/v1/auth/refresh", "phone-2", map[string]string{"refresh_token": refresh}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
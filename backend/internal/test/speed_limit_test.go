@@ -0,0 +1,255 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func importRoadData(t *testing.T, tf *TestFramework, token string) *httptest.ResponseRecorder {
+	extract, err := os.ReadFile("../services/testdata/osm/pune_roads.osm")
+	require.NoError(t, err)
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "pune_roads.osm")
+	require.NoError(t, err)
+	_, err = part.Write(extract)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req, _ := http.NewRequest("POST", "/api/v1/safety/speed-limits/import", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	tf.Router.ServeHTTP(w, req)
+	return w
+}
+
+func speedLimitAt(t *testing.T, tf *TestFramework, token, query string) services.SpeedLimit {
+	w := sendJSON(tf, "GET", "/api/v1/safety/speed-limits?"+query, nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var limit services.SpeedLimit
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &limit))
+	return limit
+}
+
+func TestRoadSpeedLimits(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+
+	// Drivable ways are imported; the footway is not, and a way with a missing node is skipped
+	w := importRoadData(t, tf, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result services.OSMImportResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, services.OSMImportResult{Ways: 4, Imported: 3, WithMaxSpeed: 2, Skipped: 1}, result)
+
+	// Importing again replaces rather than duplicates
+	w = importRoadData(t, tf, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var segments []models.RoadSegment
+	require.NoError(t, tf.DB.Order("osm_way_id").Find(&segments).Error)
+	require.Len(t, segments, 3)
+	assert.Equal(t, "Mumbai Pune Expressway", segments[0].Name)
+	assert.Equal(t, 64.4, *segments[2].MaxSpeed)
+
+	// Posted limits, heavy vehicle limits and class defaults
+	limit := speedLimitAt(t, tf, token, "latitude=18.6001&longitude=73.72&vehicle_type=van")
+	assert.Equal(t, 100.0, limit.Limit)
+	assert.Equal(t, services.SpeedLimitSourceRoad, limit.Source)
+	assert.Equal(t, "motorway", limit.Highway)
+	limit = speedLimitAt(t, tf, token, "latitude=18.6001&longitude=73.72&vehicle_type=TRUCK")
+	assert.Equal(t, 80.0, limit.Limit)
+	limit = speedLimitAt(t, tf, token, "latitude=18.52&longitude=73.842")
+	assert.Equal(t, 30.0, limit.Limit)
+	assert.Equal(t, services.SpeedLimitSourceRoadClass, limit.Source)
+	assert.Equal(t, "Fergusson College Road", limit.RoadName)
+	// 100 m off the expressway is not on it
+	limit = speedLimitAt(t, tf, token, "latitude=18.601&longitude=73.72")
+	assert.Equal(t, services.SpeedLimitSourceDefault, limit.Source)
+	w = sendJSON(tf, "GET", "/api/v1/safety/speed-limits?latitude=91&longitude=73", nil, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Vehicle type overrides lower the limit on a road class or everywhere
+	w = sendJSON(tf, "PUT", "/api/v1/safety/speed-limits/overrides", map[string]interface{}{
+		"vehicle_type": "TRUCK", "highway": "motorway", "max_speed": 70,
+	}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = sendJSON(tf, "PUT", "/api/v1/safety/speed-limits/overrides", map[string]interface{}{
+		"vehicle_type": "TRUCK", "highway": "bridleway", "max_speed": 10,
+	}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendJSON(tf, "PUT", "/api/v1/safety/speed-limits/overrides", map[string]interface{}{"vehicle_type": "VAN", "max_speed": 90}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	vanOverride := decodeBody(t, w)
+
+	truck, err := tf.CreateTestVehicle("MH12SL0001", "TRUCK")
+	require.NoError(t, err)
+	limit = speedLimitAt(t, tf, token, fmt.Sprintf("latitude=18.6&longitude=73.72&vehicle_id=%d", truck.ID))
+	assert.Equal(t, 70.0, limit.Limit)
+	assert.Equal(t, services.SpeedLimitSourceVehicleType, limit.Source)
+	limit = speedLimitAt(t, tf, token, "latitude=18.6&longitude=73.72&vehicle_type=VAN")
+	assert.Equal(t, 90.0, limit.Limit)
+	limit = speedLimitAt(t, tf, token, "latitude=18.52&longitude=73.842&vehicle_type=VAN")
+	assert.Equal(t, 30.0, limit.Limit)
+
+	w = sendJSON(tf, "GET", "/api/v1/safety/speed-limits/overrides", nil, token)
+	require.Equal(t, http.StatusOK, w.Code)
+	var overrides []models.SpeedLimitOverride
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &overrides))
+	assert.Len(t, overrides, 2)
+	w = sendJSON(tf, "DELETE", fmt.Sprintf("/api/v1/safety/speed-limits/overrides/%v", vanOverride["id"]), nil, token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = sendJSON(tf, "DELETE", fmt.Sprintf("/api/v1/safety/speed-limits/overrides/%v", vanOverride["id"]), nil, token)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// A geofence cap takes precedence over the road
+	centerLat, centerLon, radius := 18.6, 73.78, 500.0
+	zone := &models.Geofence{
+		Name: "Toll plaza", Type: models.GeofenceTypeInclusion, ShapeType: models.GeofenceShapeTypeCircle,
+		CenterLatitude: &centerLat, CenterLongitude: &centerLon, Radius: &radius, IsActive: true,
+	}
+	require.NoError(t, tf.DB.Create(zone).Error)
+	w = sendJSON(tf, "PUT", fmt.Sprintf("/api/v1/safety/speed-limits/geofences/%d", zone.ID), map[string]interface{}{"speed_limit": 40}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	limit = speedLimitAt(t, tf, token, fmt.Sprintf("latitude=18.6&longitude=73.781&vehicle_id=%d", truck.ID))
+	assert.Equal(t, 40.0, limit.Limit)
+	assert.Equal(t, services.SpeedLimitSourceGeofence, limit.Source)
+	assert.Equal(t, zone.ID, *limit.GeofenceID)
+	w = sendJSON(tf, "PUT", "/api/v1/safety/speed-limits/geofences/999999", map[string]interface{}{"speed_limit": 40}, token)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = sendJSON(tf, "PUT", fmt.Sprintf("/api/v1/safety/speed-limits/geofences/%d", zone.ID), map[string]interface{}{"speed_limit": -1}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSpeedingEpisodes(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, importRoadData(t, tf, token).Code)
+
+	driver, err := tf.CreateTestDriver("Lead Foot", "+919800000301", "MH1420130001")
+	require.NoError(t, err)
+	truck, err := tf.CreateTestVehicle("MH12SE0001", "TRUCK")
+	require.NoError(t, err)
+	trip, err := tf.CreateTestTrip("Mumbai", "Pune", driver.ID, truck.ID)
+	require.NoError(t, err)
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, tf.DB.Model(trip).Updates(map[string]interface{}{
+		"status": models.TripStatusInProgress, "actual_pickup_time": start,
+	}).Error)
+
+	centerLat, centerLon, radius := 18.6, 73.78, 500.0
+	zone := &models.Geofence{
+		Name: "Toll plaza", Type: models.GeofenceTypeInclusion, ShapeType: models.GeofenceShapeTypeCircle,
+		CenterLatitude: &centerLat, CenterLongitude: &centerLon, Radius: &radius, IsActive: true,
+	}
+	require.NoError(t, tf.DB.Create(zone).Error)
+	_, err = tf.Services.SpeedLimitService.SetGeofenceSpeedLimit(t.Context(), zone.ID, floatPtr(40))
+	require.NoError(t, err)
+
+	// Pings every five seconds along the expressway, where the truck's limit is 80
+	safety := tf.Services.SafetyService
+	clock := start.Add(10 * time.Minute)
+	drive := func(longitude float64, speeds ...float64) []*models.SpeedingEpisode {
+		var closed []*models.SpeedingEpisode
+		for i, speed := range speeds {
+			speed := speed
+			episode, err := safety.TrackSpeeding(t.Context(), &models.LocationPing{
+				VehicleID: &truck.ID, Latitude: 18.6, Longitude: longitude + float64(i)*0.0005, Speed: &speed, Timestamp: clock,
+			})
+			require.NoError(t, err)
+			if episode != nil {
+				closed = append(closed, episode)
+			}
+			clock = clock.Add(5 * time.Second)
+		}
+		return closed
+	}
+
+	// Within the tolerance, then a single reading over, are not episodes
+	assert.Empty(t, drive(73.71, 78, 84, 90, 70))
+
+	// Over by more than the tolerance opens an episode; it lasts until back under the limit
+	closed := drive(73.72, 86, 95, 105, 82, 75)
+	require.Len(t, closed, 1)
+	episode := closed[0]
+	assert.True(t, episode.StartedAt.Equal(clock.Add(-25*time.Second)))
+	assert.Equal(t, 15.0, episode.DurationSeconds)
+	assert.Equal(t, 4, episode.Pings)
+	assert.Equal(t, 105.0, episode.MaxSpeed)
+	assert.Equal(t, 25.0, episode.MaxOver)
+	assert.Equal(t, 12.0, episode.AverageOver)
+	assert.Equal(t, 80.0, episode.SpeedLimit)
+	assert.Equal(t, services.SpeedLimitSourceRoad, episode.LimitSource)
+	assert.Equal(t, models.SeverityHigh, episode.Severity)
+	require.NotNil(t, episode.TripID)
+	assert.Equal(t, trip.ID, *episode.TripID)
+	assert.Equal(t, driver.ID, *episode.DriverID)
+
+	// One event per episode, not per ping
+	var events []models.SafetyEvent
+	require.NoError(t, tf.DB.Where("vehicle_id = ? AND type = ?", truck.ID, models.SafetyEventSpeeding).Find(&events).Error)
+	require.Len(t, events, 1)
+	assert.Equal(t, *episode.SafetyEventID, events[0].ID)
+	assert.Equal(t, 105.0, events[0].Value)
+	assert.Equal(t, 80.0, events[0].Threshold)
+
+	// Inside the toll plaza zone the cap applies; the vehicle then stops reporting
+	closed = drive(73.779, 50, 55, 52, 48)
+	assert.Empty(t, closed)
+	stale, err := safety.CloseStaleSpeedingEpisodes(t.Context(), clock.Add(10*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, stale, 1)
+	assert.Equal(t, services.SpeedLimitSourceGeofence, stale[0].LimitSource)
+	assert.Equal(t, zone.ID, *stale[0].GeofenceID)
+	assert.Equal(t, 40.0, stale[0].SpeedLimit)
+	assert.Equal(t, models.SeverityMedium, stale[0].Severity)
+	assert.Equal(t, 15.0, stale[0].DurationSeconds)
+
+	w := sendJSON(tf, "GET", fmt.Sprintf("/api/v1/safety/speeding-episodes?vehicle_id=%d", truck.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var page struct {
+		Episodes []models.SpeedingEpisode `json:"episodes"`
+		Total    int64                    `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.EqualValues(t, 2, page.Total)
+	assert.Equal(t, services.SpeedLimitSourceGeofence, page.Episodes[0].LimitSource)
+	assert.Equal(t, "Lead Foot", page.Episodes[0].Driver.Name)
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/safety/speeding-episodes?driver_id=%d&min_over=20", driver.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.EqualValues(t, 1, page.Total)
+	w = sendJSON(tf, "GET", "/api/v1/safety/speeding-episodes?min_over=fast", nil, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
@@ -0,0 +1,223 @@
+package test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCSingleSignOn(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	provider, err := NewFakeOIDCProvider("fleetflow-web")
+	require.NoError(t, err)
+	defer provider.Close()
+
+	// setup creates an organization with an admin who configures SSO against the fake provider
+	setup := func(t *testing.T) *models.Organization {
+		tf.CleanDatabase()
+
+		org := &models.Organization{Name: "Acme Logistics", Code: "acme", SubscriptionStatus: models.SubscriptionActive}
+		require.NoError(t, tf.DB.Create(org).Error)
+
+		admin := &models.UserAccount{
+			Phone:          TestAdminPhone,
+			Email:          "admin@acme.com",
+			Role:           models.RoleOrgAdmin,
+			IsActive:       true,
+			OrganizationID: &org.ID,
+		}
+		require.NoError(t, tf.DB.Create(admin).Error)
+		token, err := tf.GenerateJWTToken(admin)
+		require.NoError(t, err)
+
+		w := putJSON(tf, "/api/v1/organizations/me/sso", map[string]interface{}{
+			"issuer":                provider.Issuer(),
+			"client_id":             provider.ClientID,
+			"redirect_url":          "https://app.example.com/sso/callback",
+			"role_claim":            "groups",
+			"role_mapping":          map[string]string{"fleet-dispatch": "DISPATCHER", "fleet-admins": "ORG_ADMIN"},
+			"allowed_email_domains": "acme.com",
+		}, token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return org
+	}
+
+	// login runs the full browser round trip and returns the callback response
+	login := func(t *testing.T, claims jwt.MapClaims) (int, map[string]interface{}) {
+		w := sendJSON(tf, "GET", "/api/v1/auth/sso/acme/login", nil, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		start := decodeBody(t, w)
+
+		code, err := provider.Authorize(start["authorization_url"].(string), claims)
+		require.NoError(t, err)
+
+		w = postJSON(tf, "/api/v1/auth/sso/callback", map[string]string{
+			"code":  code,
+			"state": start["state"].(string),
+		}, "")
+		return w.Code, decodeBody(t, w)
+	}
+
+	t.Run("Provisions User With Mapped Role", func(t *testing.T) {
+		org := setup(t)
+
+		status, body := login(t, jwt.MapClaims{
+			"sub":            "idp-user-1",
+			"email":          "Dana@Acme.com",
+			"email_verified": true,
+			"groups":         []string{"everyone", "fleet-dispatch"},
+		})
+		require.Equal(t, http.StatusOK, status, body)
+		assert.NotEmpty(t, body["access_token"])
+		assert.NotEmpty(t, body["refresh_token"])
+
+		var user models.UserAccount
+		require.NoError(t, tf.DB.Where("email = ?", "dana@acme.com").First(&user).Error)
+		assert.Equal(t, models.RoleDispatcher, user.Role)
+		assert.Equal(t, org.ID, *user.OrganizationID)
+
+		// A second login resolves the same account and re-syncs the role
+		status, body = login(t, jwt.MapClaims{
+			"sub":            "idp-user-1",
+			"email":          "dana@acme.com",
+			"email_verified": true,
+			"groups":         []string{"fleet-admins"},
+		})
+		require.Equal(t, http.StatusOK, status, body)
+
+		var count int64
+		tf.DB.Model(&models.UserAccount{}).Where("email = ?", "dana@acme.com").Count(&count)
+		assert.Equal(t, int64(1), count)
+		require.NoError(t, tf.DB.First(&user, user.ID).Error)
+		assert.Equal(t, models.RoleOrgAdmin, user.Role)
+	})
+
+	t.Run("Links Existing Account By Email", func(t *testing.T) {
+		org := setup(t)
+		verifiedAt := time.Now()
+		existing := &models.UserAccount{
+			Phone:           "+15550002222",
+			Email:           "sam@acme.com",
+			EmailVerifiedAt: &verifiedAt,
+			Role:            models.RoleMechanic,
+			IsActive:        true,
+			OrganizationID:  &org.ID,
+		}
+		require.NoError(t, tf.DB.Create(existing).Error)
+
+		status, body := login(t, jwt.MapClaims{"sub": "idp-user-2", "email": "sam@acme.com", "email_verified": true})
+		require.Equal(t, http.StatusOK, status, body)
+		assert.Equal(t, float64(existing.ID), body["user"].(map[string]interface{})["id"])
+
+		var identity models.UserIdentity
+		require.NoError(t, tf.DB.Where("subject = ?", "idp-user-2").First(&identity).Error)
+		assert.Equal(t, existing.ID, identity.UserID)
+	})
+
+	t.Run("Unverified Emails Need An Admin To Link", func(t *testing.T) {
+		org := setup(t)
+		// A driver typed in an admin's address without verifying it
+		driver := &models.UserAccount{
+			Phone:          "+15550004444",
+			Email:          "boss@acme.com",
+			Role:           models.RoleDriver,
+			IsActive:       true,
+			OrganizationID: &org.ID,
+		}
+		require.NoError(t, tf.DB.Create(driver).Error)
+
+		admin := jwt.MapClaims{"sub": "idp-boss", "email": "boss@acme.com", "email_verified": true, "groups": []string{"fleet-admins"}}
+		status, body := login(t, admin)
+		assert.Equal(t, http.StatusForbidden, status, body)
+		// Nor does an IdP that leaves out email_verified link anything
+		status, _ = login(t, jwt.MapClaims{"sub": "idp-boss", "email": "boss@acme.com"})
+		assert.Equal(t, http.StatusForbidden, status)
+		require.NoError(t, tf.DB.First(driver, driver.ID).Error)
+		assert.Equal(t, models.RoleDriver, driver.Role)
+
+		// Without a matching account, an unverified address is not stored on the new one
+		status, body = login(t, jwt.MapClaims{"sub": "idp-new", "email": "new@acme.com"})
+		require.Equal(t, http.StatusOK, status, body)
+		var provisioned models.UserAccount
+		require.NoError(t, tf.DB.First(&provisioned, body["user"].(map[string]interface{})["id"]).Error)
+		assert.Empty(t, provisioned.Email)
+
+		// An admin links the identity after checking who it is
+		orgAdmin := &models.UserAccount{}
+		require.NoError(t, tf.DB.Where("email = ?", "admin@acme.com").First(orgAdmin).Error)
+		token, err := tf.GenerateJWTToken(orgAdmin)
+		require.NoError(t, err)
+		w := postJSON(tf, "/api/v1/organizations/me/sso/identities", map[string]interface{}{"user_id": driver.ID, "subject": "idp-driver"}, token)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		w = postJSON(tf, "/api/v1/organizations/me/sso/identities", map[string]interface{}{"user_id": driver.ID, "subject": "idp-driver"}, token)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		status, body = login(t, jwt.MapClaims{"sub": "idp-driver", "email": "boss@acme.com"})
+		require.Equal(t, http.StatusOK, status, body)
+		assert.Equal(t, float64(driver.ID), body["user"].(map[string]interface{})["id"])
+	})
+
+	t.Run("Issuer Must Be A Public HTTPS URL", func(t *testing.T) {
+		org := setup(t)
+		sso := services.NewSSOService(tf.DB, services.NewOIDCClient(nil), tf.Services.AuditService)
+		for _, issuer := range []string{provider.Issuer(), "https://169.254.169.254/latest", "https://10.0.0.5", "https://localhost:8443", "https://keycloak"} {
+			_, err := sso.SaveConfig(t.Context(), org.ID, &models.OIDCConfig{
+				Issuer: issuer, ClientID: "fleetflow-web", RedirectURL: "https://app.example.com/sso/callback",
+			}, nil)
+			assert.ErrorIs(t, err, services.ErrOIDCEndpointNotAllowed, issuer)
+		}
+	})
+
+	t.Run("Rejects Disallowed Domain And Foreign Accounts", func(t *testing.T) {
+		setup(t)
+
+		status, _ := login(t, jwt.MapClaims{"sub": "idp-user-3", "email": "eve@evil.com", "email_verified": true})
+		assert.Equal(t, http.StatusForbidden, status)
+
+		other := &models.Organization{Name: "Other", Code: "other", SubscriptionStatus: models.SubscriptionActive}
+		require.NoError(t, tf.DB.Create(other).Error)
+		require.NoError(t, tf.DB.Create(&models.UserAccount{
+			Phone:          "+15550003333",
+			Email:          "lee@acme.com",
+			Role:           models.RoleDispatcher,
+			IsActive:       true,
+			OrganizationID: &other.ID,
+		}).Error)
+
+		status, _ = login(t, jwt.MapClaims{"sub": "idp-user-4", "email": "lee@acme.com", "email_verified": true})
+		assert.Equal(t, http.StatusForbidden, status)
+	})
+
+	t.Run("State Cannot Be Replayed", func(t *testing.T) {
+		setup(t)
+
+		w := sendJSON(tf, "GET", "/api/v1/auth/sso/acme/login", nil, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		start := decodeBody(t, w)
+		claims := jwt.MapClaims{"sub": "idp-user-5", "email": "kim@acme.com", "email_verified": true}
+
+		code, err := provider.Authorize(start["authorization_url"].(string), claims)
+		require.NoError(t, err)
+		w = postJSON(tf, "/api/v1/auth/sso/callback", map[string]string{"code": code, "state": start["state"].(string)}, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		code, err = provider.Authorize(start["authorization_url"].(string), claims)
+		require.NoError(t, err)
+		w = postJSON(tf, "/api/v1/auth/sso/callback", map[string]string{"code": code, "state": start["state"].(string)}, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Unknown Organization", func(t *testing.T) {
+		setup(t)
+		w := sendJSON(tf, "GET", "/api/v1/auth/sso/nope/login", nil, "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
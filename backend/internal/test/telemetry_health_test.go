@@ -0,0 +1,153 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTelemetryAnomaliesAndHealthScore(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+	vehicle, err := tf.CreateTestVehicle("MH12TH4201", "TRUCK")
+	require.NoError(t, err)
+	require.NoError(t, tf.DB.Model(vehicle).Updates(map[string]interface{}{"make": "Tata", "model": "Prima"}).Error)
+
+	// Prima engines run cooler than the fleet default allows for
+	w := sendJSON(tf, "PUT", "/api/v1/telemetry/thresholds", map[string]interface{}{
+		"vehicle_make": "tata", "vehicle_model": "Prima", "signal": "coolant_temp", "max": 100, "critical_max": 108, "max_rise_per_min": 4, "severity": "HIGH",
+	}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = sendJSON(tf, "PUT", "/api/v1/telemetry/thresholds", map[string]interface{}{"signal": "COOLANT_TEMP", "min": 90, "max": 80}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/telemetry/thresholds?vehicle_id=%d", vehicle.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var rules []models.TelemetryThreshold
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rules))
+	for _, rule := range rules {
+		if rule.Signal == models.SignalCoolantTemp {
+			assert.Equal(t, 100.0, *rule.Max)
+		}
+	}
+
+	telemetry := tf.Services.TelemetryService
+	start := time.Now().Add(-2 * time.Hour)
+	minute := 0
+	ingest := func(coolant float64) *services.TelemetryAnalysis {
+		minute++
+		rpm := 1500 + (minute%3)*20
+		battery, fuel, odometer := 13.8, 80-0.1*float64(minute), 1000+float64(minute)
+		analysis, err := telemetry.IngestTelemetry(context.Background(), &services.TelemetryUpdate{
+			VehicleID: vehicle.ID, Timestamp: start.Add(time.Duration(minute) * time.Minute),
+			CoolantTemp: &coolant, EngineRPM: &rpm, BatteryVoltage: &battery, FuelLevel: &fuel, Odometer: &odometer,
+		})
+		require.NoError(t, err)
+		return analysis
+	}
+	opened := func(analysis *services.TelemetryAnalysis, kind models.TelemetryAnomalyKind) *models.TelemetryAnomaly {
+		for i := range analysis.Opened {
+			if analysis.Opened[i].Signal == models.SignalCoolantTemp && analysis.Opened[i].Kind == kind {
+				return &analysis.Opened[i]
+			}
+		}
+		return nil
+	}
+
+	// A steady baseline raises nothing
+	for i := 0; i < 35; i++ {
+		analysis := ingest(88 + float64(i%2))
+		require.Empty(t, analysis.Opened, "reading %d", i)
+	}
+
+	// A spike opens one anomaly per rule; readings that stay high fold into it
+	analysis := ingest(103)
+	overheat := opened(analysis, models.AnomalyThreshold)
+	require.NotNil(t, overheat)
+	assert.Equal(t, models.DTCSeverityHigh, overheat.Severity)
+	assert.Equal(t, 100.0, overheat.Expected)
+	assert.NotNil(t, opened(analysis, models.AnomalyRateChange))
+	assert.NotNil(t, opened(analysis, models.AnomalyDeviation))
+
+	for _, coolant := range []float64{104, 105} {
+		analysis = ingest(coolant)
+		assert.Nil(t, opened(analysis, models.AnomalyThreshold))
+	}
+	analysis = ingest(109)
+	var escalated *models.TelemetryAnomaly
+	for i := range analysis.Updated {
+		if analysis.Updated[i].ID == overheat.ID {
+			escalated = &analysis.Updated[i]
+		}
+	}
+	require.NotNil(t, escalated)
+	assert.Equal(t, models.DTCSeverityCritical, escalated.Severity)
+	assert.Equal(t, 4, escalated.Occurrences)
+	assert.Equal(t, 109.0, escalated.PeakValue)
+
+	// The health score reflects the open anomalies on both endpoints
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/telemetry/latest?vehicle_id=%d", vehicle.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var latest services.LatestTelemetry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &latest))
+	assert.Equal(t, 109.0, *latest.CoolantTemp)
+	require.NotNil(t, latest.Health)
+	assert.Less(t, latest.Health.Score, 80)
+	assert.GreaterOrEqual(t, latest.Health.OpenAnomalies, 2)
+	assert.False(t, latest.Health.Stale)
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/vehicles/%d", vehicle.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	detail := decodeBody(t, w)
+	assert.Equal(t, "MH12TH4201", detail["license_plate"])
+	health := detail["health"].(map[string]interface{})
+	assert.Equal(t, float64(latest.Health.Score), health["score"])
+
+	w = sendJSON(tf, "GET", "/api/v1/vehicles/999999", nil, token)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Normal readings resolve the anomaly; a breach soon after reopens it without a new alert
+	for i := 0; i < 3; i++ {
+		analysis = ingest(89)
+	}
+	var resolved bool
+	for _, anomaly := range analysis.Resolved {
+		resolved = resolved || anomaly.ID == overheat.ID
+	}
+	assert.True(t, resolved)
+
+	analysis = ingest(101)
+	assert.Nil(t, opened(analysis, models.AnomalyThreshold))
+	var reopened bool
+	for _, anomaly := range analysis.Updated {
+		reopened = reopened || (anomaly.ID == overheat.ID && anomaly.IsActive)
+	}
+	assert.True(t, reopened)
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/telemetry/anomalies?vehicle_id=%d", vehicle.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var anomalies []models.TelemetryAnomaly
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &anomalies))
+	var thresholdAnomalies int
+	for _, anomaly := range anomalies {
+		if anomaly.Kind == models.AnomalyThreshold {
+			thresholdAnomalies++
+		}
+	}
+	assert.Equal(t, 1, thresholdAnomalies)
+}
@@ -0,0 +1,150 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVideoEventLinking(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	token, camera := setupVideo(t, tf)
+	videoService := tf.Services.VideoService
+	safetyService := tf.Services.SafetyService
+
+	now := time.Now().Truncate(time.Second)
+	onTrip, err := tf.CreateTestDriver("Trip Driver", "+919800000101", "MH1220240000101")
+	require.NoError(t, err)
+	trip, err := tf.CreateTestTrip("Pune", "Mumbai", onTrip.ID, *camera.VehicleID)
+	require.NoError(t, err)
+	pickup := now.Add(-time.Hour)
+	require.NoError(t, tf.DB.Model(trip).Updates(&models.Trip{Status: models.TripStatusInProgress, ActualPickupTime: &pickup}).Error)
+
+	// A second vehicle has no trip, only its driver's duty log
+	van, err := tf.CreateTestVehicle("MH12VD0002", "VAN")
+	require.NoError(t, err)
+	vanCamera := &models.Camera{SerialNumber: "DC-0043", VehicleID: &van.ID, LastHeartbeat: now}
+	require.NoError(t, videoService.RegisterCamera(vanCamera))
+	onDuty, err := tf.CreateTestDriver("Duty Driver", "+919800000102", "MH1220240000102")
+	require.NoError(t, err)
+	require.NoError(t, tf.DB.Create(&models.DutyStatusLog{
+		DriverID: onDuty.ID, VehicleID: &van.ID, Status: models.DutyStatusDriving, StartTime: now.Add(-2 * time.Hour),
+	}).Error)
+
+	record := func(vehicleID uint, eventType models.SafetyEventType, severity models.SafetyEventSeverity, at time.Time) *models.SafetyEvent {
+		event := &models.SafetyEvent{VehicleID: vehicleID, Type: eventType, Severity: severity, Value: 8, Latitude: 18.52, Longitude: 73.85, Timestamp: at}
+		require.NoError(t, safetyService.RecordSafetyEvent(event))
+		return event
+	}
+	stored := func(event *models.SafetyEvent) models.SafetyEvent {
+		var reloaded models.SafetyEvent
+		require.NoError(t, tf.DB.First(&reloaded, event.ID).Error)
+		return reloaded
+	}
+
+	// An event recorded before the camera reports its clip is linked once the clip arrives
+	at := now.Add(-30 * time.Minute)
+	braking := record(*camera.VehicleID, models.SafetyEventHarshBraking, models.SeverityMedium, at)
+	assert.Nil(t, braking.VideoClipID)
+	clip, err := videoService.ProcessAIEvent(camera.SerialNumber, models.VideoEventHarshBraking, at.Add(2*time.Second), []models.AIDetection{
+		{Label: "cell_phone", Confidence: 0.91, Timestamp: at.Add(time.Second)},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, clip.DriverID)
+	assert.Equal(t, onTrip.ID, *clip.DriverID)
+	require.NotNil(t, clip.TripID)
+	assert.Equal(t, trip.ID, *clip.TripID)
+	require.NotNil(t, stored(braking).VideoClipID)
+	assert.Equal(t, clip.ID, *stored(braking).VideoClipID)
+
+	// One recorded after is linked straight away, and no footage is asked for
+	cornering := record(*camera.VehicleID, models.SafetyEventHarshCornering, models.SeverityHigh, at.Add(5*time.Second))
+	require.NotNil(t, cornering.VideoClipID)
+	assert.Equal(t, clip.ID, *cornering.VideoClipID)
+	assert.Equal(t, onTrip.ID, *cornering.DriverID)
+
+	// Without a trip the driver comes from the duty log
+	vanClip, err := videoService.ProcessAIEvent(vanCamera.SerialNumber, models.VideoEventDistraction, now.Add(-20*time.Minute), nil)
+	require.NoError(t, err)
+	require.NotNil(t, vanClip.DriverID)
+	assert.Equal(t, onDuty.ID, *vanClip.DriverID)
+	assert.Nil(t, vanClip.TripID)
+
+	// A serious event without footage has it asked of the camera, once
+	impactAt := now.Add(-10 * time.Minute)
+	impact := record(van.ID, models.SafetyEventImpact, models.SeverityCritical, impactAt)
+	assert.Nil(t, impact.VideoClipID)
+	record(van.ID, models.SafetyEventHarshBraking, models.SeverityHigh, impactAt.Add(3*time.Second))
+	minor := record(van.ID, models.SafetyEventHarshBraking, models.SeverityLow, now.Add(-5*time.Minute))
+	assert.Nil(t, minor.VideoClipID)
+	var retrievals []models.VideoRetrievalRequest
+	require.NoError(t, tf.DB.Where("vehicle_id = ?", van.ID).Find(&retrievals).Error)
+	require.Len(t, retrievals, 1)
+	assert.Equal(t, vanCamera.ID, retrievals[0].CameraID)
+	assert.Equal(t, models.VideoEventImpact, retrievals[0].EventType)
+	assert.True(t, retrievals[0].StartTime.Equal(impactAt.Add(-10*time.Second)))
+
+	// Vehicles without a dashcam just record the event
+	car, err := tf.CreateTestVehicle("MH12VD0003", "CAR")
+	require.NoError(t, err)
+	record(car.ID, models.SafetyEventImpact, models.SeverityCritical, impactAt)
+
+	// The requested footage links both events when it is uploaded
+	footage := []byte("impact footage")
+	upload, err := videoService.StartClipUpload(t.Context(), services.ClipUploadRequest{
+		CameraSerial: vanCamera.SerialNumber, FileSize: int64(len(footage)), RetrievalRequestID: &retrievals[0].ID,
+	})
+	require.NoError(t, err)
+	_, err = videoService.WriteClipChunk(t.Context(), upload.UploadID, 0, footage)
+	require.NoError(t, err)
+	var impactClip models.VideoClip
+	require.NoError(t, tf.DB.First(&impactClip, upload.VideoClipID).Error)
+	assert.Equal(t, onDuty.ID, *impactClip.DriverID)
+	require.NotNil(t, stored(impact).VideoClipID)
+	assert.Equal(t, impactClip.ID, *stored(impact).VideoClipID)
+	assert.Nil(t, stored(minor).VideoClipID)
+
+	// The events API returns the clip with what was detected in it
+	w := sendJSON(tf, "GET", fmt.Sprintf("/api/v1/safety/events?vehicle_id=%d&has_video=true", *camera.VehicleID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var listed struct {
+		Events []models.SafetyEvent `json:"events"`
+		Total  int64                `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	assert.Equal(t, int64(2), listed.Total)
+	for _, event := range listed.Events {
+		require.NotNil(t, event.VideoClip)
+		assert.Equal(t, camera.SerialNumber, event.VideoClip.Camera.SerialNumber)
+		require.Len(t, event.VideoClip.Detections, 1)
+		assert.Equal(t, "cell_phone", event.VideoClip.Detections[0].Label)
+	}
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/safety/events?vehicle_id=%d&has_video=false", van.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	assert.Equal(t, int64(1), listed.Total)
+	assert.Equal(t, http.StatusBadRequest, sendJSON(tf, "GET", "/api/v1/safety/events?has_video=maybe", nil, token).Code)
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/safety/events/%d", braking.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var event models.SafetyEvent
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &event))
+	require.NotNil(t, event.Trip)
+	assert.Equal(t, trip.ID, event.Trip.ID)
+	require.NotNil(t, event.Driver)
+	assert.Equal(t, onTrip.ID, event.Driver.ID)
+	require.NotNil(t, event.VideoClip)
+	assert.Len(t, event.VideoClip.Detections, 1)
+	assert.Equal(t, http.StatusNotFound, sendJSON(tf, "GET", "/api/v1/safety/events/999999", nil, token).Code)
+}
@@ -0,0 +1,327 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fleetflow/backend/internal/config"
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubThumbnails stands in for ffmpeg
+type stubThumbnails struct{}
+
+func (stubThumbnails) ExtractThumbnail(ctx context.Context, video []byte) ([]byte, error) {
+	return []byte("jpeg"), nil
+}
+
+// flakyStorage fails uploads under a key prefix until it is healed
+type flakyStorage struct {
+	services.StorageProvider
+	failPrefix string
+}
+
+func (f *flakyStorage) UploadFile(key string, data []byte, contentType string) (string, error) {
+	if f.failPrefix != "" && strings.HasPrefix(key, f.failPrefix) {
+		return "", fmt.Errorf("storage unavailable")
+	}
+	return f.StorageProvider.UploadFile(key, data, contentType)
+}
+
+func putChunk(tf *TestFramework, uploadID string, offset int, data []byte, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/api/v1/video/uploads/%s", uploadID), bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Upload-Offset", fmt.Sprint(offset))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	tf.Router.ServeHTTP(w, req)
+	return w
+}
+
+func getMedia(tf *TestFramework, mediaURL string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", mediaURL, nil)
+	w := httptest.NewRecorder()
+	tf.Router.ServeHTTP(w, req)
+	return w
+}
+
+func setupVideo(t *testing.T, tf *TestFramework) (string, *models.Camera) {
+	tf.Services.VideoService.SetStorageProvider(services.NewLocalStorageService(&config.Config{LocalStoragePath: t.TempDir()}))
+	tf.Services.VideoService.SetThumbnailExtractor(stubThumbnails{})
+	tf.Services.VideoService.SetURLSigningKey("test-video-secret", 0)
+
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+	vehicle, err := tf.CreateTestVehicle("MH12VD0001", "TRUCK")
+	require.NoError(t, err)
+	camera := &models.Camera{SerialNumber: "DC-0042", VehicleID: &vehicle.ID, Model: "DualCam", LastHeartbeat: time.Now()}
+	require.NoError(t, tf.Services.VideoService.RegisterCamera(camera))
+	return token, camera
+}
+
+func TestVideoClipUpload(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	token, camera := setupVideo(t, tf)
+
+	footage := bytes.Repeat([]byte("fleetflow-dashcam-"), 1000)
+	sum := sha256.Sum256(footage)
+	start := time.Now().Add(-time.Hour)
+
+	w := sendJSON(tf, "POST", "/api/v1/video/uploads", map[string]interface{}{
+		"camera_serial": camera.SerialNumber, "event_type": "HARSH_BRAKING",
+		"start_time": start, "end_time": start.Add(20 * time.Second),
+		"content_type": "video/mp4", "file_size": len(footage), "sha256": hex.EncodeToString(sum[:]),
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var upload models.VideoUpload
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &upload))
+	assert.Equal(t, models.VideoUploadOpen, upload.Status)
+
+	// The first chunk lands, then the camera reconnects and resends it
+	w = putChunk(tf, upload.UploadID, 0, footage[:7000], token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "7000", w.Header().Get("Upload-Offset"))
+	w = putChunk(tf, upload.UploadID, 0, footage[:7000], token)
+	require.Equal(t, http.StatusConflict, w.Code)
+	assert.EqualValues(t, 7000, decodeBody(t, w)["received_bytes"])
+
+	// It asks where to resume and finishes the file
+	w = sendJSON(tf, "GET", "/api/v1/video/uploads/"+upload.UploadID, nil, token)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "7000", w.Header().Get("Upload-Offset"))
+	w = putChunk(tf, upload.UploadID, 7000, footage[7000:14000], token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = putChunk(tf, upload.UploadID, 14000, footage[14000:], token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &upload))
+	assert.Equal(t, models.VideoUploadComplete, upload.Status)
+
+	var clip models.VideoClip
+	require.NoError(t, tf.DB.First(&clip, upload.VideoClipID).Error)
+	assert.Equal(t, models.VideoClipAvailable, clip.Status)
+	assert.Equal(t, int64(len(footage)), clip.SizeBytes)
+	assert.NotEmpty(t, clip.ThumbnailKey)
+
+	// Playback goes through signed links
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/video/clips/%d/playback", clip.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var urls services.PlaybackURLs
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &urls))
+	w = getMedia(tf, urls.URL)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, footage, w.Body.Bytes())
+	assert.Equal(t, "video/mp4", w.Header().Get("Content-Type"))
+	w = getMedia(tf, urls.ThumbnailURL)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "jpeg", w.Body.String())
+
+	tampered, err := url.Parse(urls.URL)
+	require.NoError(t, err)
+	query := tampered.Query()
+	query.Set("expires", fmt.Sprint(urls.ExpiresAt.Add(time.Hour).Unix()))
+	tampered.RawQuery = query.Encode()
+	assert.Equal(t, http.StatusForbidden, getMedia(tf, tampered.String()).Code)
+	assert.Equal(t, http.StatusForbidden, getMedia(tf, strings.Replace(urls.URL, "/video?", "/thumbnail?", 1)).Code)
+
+	signature := query.Get("signature")
+	_, _, _, err = tf.Services.VideoService.OpenSignedMedia(t.Context(), clip.ID, services.MediaKindVideo, urls.ExpiresAt.Unix(), signature, urls.ExpiresAt.Add(time.Second))
+	assert.ErrorIs(t, err, services.ErrInvalidMediaSignature)
+
+	// A file that does not match its checksum is rejected and the clip can be uploaded again
+	w = sendJSON(tf, "POST", "/api/v1/video/uploads", map[string]interface{}{
+		"camera_serial": camera.SerialNumber, "event_type": "IMPACT",
+		"start_time": start, "end_time": start.Add(20 * time.Second),
+		"file_size": 4, "sha256": hex.EncodeToString(sum[:]),
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &upload))
+	w = putChunk(tf, upload.UploadID, 0, []byte("junk"), token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var failed models.VideoClip
+	require.NoError(t, tf.DB.First(&failed, upload.VideoClipID).Error)
+	assert.Equal(t, models.VideoClipFailed, failed.Status)
+	w = sendJSON(tf, "POST", "/api/v1/video/uploads", map[string]interface{}{
+		"camera_serial": camera.SerialNumber, "clip_id": failed.ID, "file_size": 4,
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = sendJSON(tf, "POST", "/api/v1/video/uploads", map[string]interface{}{
+		"camera_serial": camera.SerialNumber, "clip_id": failed.ID, "file_size": 4,
+	}, token)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestVideoClipUploadRetriesCompletion(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	token, camera := setupVideo(t, tf)
+	storage := &flakyStorage{
+		StorageProvider: services.NewLocalStorageService(&config.Config{LocalStoragePath: t.TempDir()}),
+		failPrefix:      "video/clips/",
+	}
+	tf.Services.VideoService.SetStorageProvider(storage)
+
+	footage := []byte("footage stored on the second try")
+	start := time.Now().Add(-time.Hour)
+	w := sendJSON(tf, "POST", "/api/v1/video/uploads", map[string]interface{}{
+		"camera_serial": camera.SerialNumber, "event_type": "HARSH_BRAKING",
+		"start_time": start, "end_time": start.Add(20 * time.Second), "file_size": len(footage),
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var upload models.VideoUpload
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &upload))
+
+	// Every byte arrives but the clip cannot be stored
+	w = putChunk(tf, upload.UploadID, 0, footage, token)
+	assert.Equal(t, http.StatusInternalServerError, w.Code, w.Body.String())
+	require.NoError(t, tf.DB.Where("upload_id = ?", upload.UploadID).First(&upload).Error)
+	assert.Equal(t, models.VideoUploadOpen, upload.Status)
+	assert.Equal(t, upload.TotalBytes, upload.ReceivedBytes)
+
+	// Once storage is back, an empty chunk at the end stores it from the staged chunks
+	storage.failPrefix = ""
+	w = putChunk(tf, upload.UploadID, len(footage), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &upload))
+	assert.Equal(t, models.VideoUploadComplete, upload.Status)
+
+	var clip models.VideoClip
+	require.NoError(t, tf.DB.First(&clip, upload.VideoClipID).Error)
+	assert.Equal(t, models.VideoClipAvailable, clip.Status)
+	stored, err := storage.DownloadFile(clip.StorageKey)
+	require.NoError(t, err)
+	assert.Equal(t, footage, stored)
+
+	w = putChunk(tf, upload.UploadID, len(footage), nil, token)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestVideoRetrievalAndRetention(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	token, camera := setupVideo(t, tf)
+	videoService := tf.Services.VideoService
+	now := time.Now()
+
+	// Footage is asked of the vehicle's camera, which polls for it and uploads against it
+	w := sendJSON(tf, "POST", "/api/v1/video/retrievals", map[string]interface{}{
+		"vehicle_id": *camera.VehicleID, "start_time": now.Add(-30 * time.Minute), "end_time": now.Add(-10 * time.Minute),
+	}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendJSON(tf, "POST", "/api/v1/video/retrievals", map[string]interface{}{
+		"vehicle_id": *camera.VehicleID, "start_time": now.Add(-30 * time.Minute), "end_time": now.Add(-25 * time.Minute),
+		"event_type": "IMPACT", "reason": "Scraped gate reported",
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var retrieval models.VideoRetrievalRequest
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &retrieval))
+	assert.Equal(t, camera.ID, retrieval.CameraID)
+
+	w = sendJSON(tf, "GET", "/api/v1/video/retrievals?camera_serial="+camera.SerialNumber+"&status=PENDING,SENT", nil, token)
+	require.Equal(t, http.StatusOK, w.Code)
+	var listed struct {
+		Retrievals []models.VideoRetrievalRequest `json:"retrievals"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(t, listed.Retrievals, 1)
+
+	footage := []byte("impact footage")
+	upload, err := videoService.StartClipUpload(t.Context(), services.ClipUploadRequest{
+		CameraSerial: camera.SerialNumber, FileSize: int64(len(footage)), RetrievalRequestID: &retrieval.ID,
+	})
+	require.NoError(t, err)
+	_, err = videoService.WriteClipChunk(t.Context(), upload.UploadID, 0, footage)
+	require.NoError(t, err)
+	require.NoError(t, tf.DB.First(&retrieval, retrieval.ID).Error)
+	assert.Equal(t, models.VideoRetrievalFulfilled, retrieval.Status)
+	require.NotNil(t, retrieval.VideoClipID)
+	var impact models.VideoClip
+	require.NoError(t, tf.DB.First(&impact, *retrieval.VideoClipID).Error)
+	assert.Equal(t, models.VideoEventImpact, impact.EventType)
+
+	// Braking clips: one plain, one starred and one waiting to be coached
+	uploadClip := func(eventType models.VideoEventType) models.VideoClip {
+		upload, err := videoService.StartClipUpload(t.Context(), services.ClipUploadRequest{
+			CameraSerial: camera.SerialNumber, EventType: eventType, FileSize: 5,
+			StartTime: now.Add(-time.Hour), EndTime: now.Add(-time.Hour + 20*time.Second),
+		})
+		require.NoError(t, err)
+		_, err = videoService.WriteClipChunk(t.Context(), upload.UploadID, 0, []byte("clip!"))
+		require.NoError(t, err)
+		var clip models.VideoClip
+		require.NoError(t, tf.DB.First(&clip, upload.VideoClipID).Error)
+		return clip
+	}
+	plain := uploadClip(models.VideoEventHarshBraking)
+	starred := uploadClip(models.VideoEventHarshBraking)
+	require.NoError(t, tf.DB.Model(&starred).Update("is_starred", true).Error)
+	coached := uploadClip(models.VideoEventHarshBraking)
+	driver, err := tf.CreateTestDriver("Video Driver", "+919800000401", "MH1420120401")
+	require.NoError(t, err)
+	require.NoError(t, tf.DB.Create(&models.CoachingSession{DriverID: driver.ID, VideoClipID: &coached.ID, Status: models.CoachingStatusPending}).Error)
+
+	stalled, err := videoService.StartClipUpload(t.Context(), services.ClipUploadRequest{
+		CameraSerial: camera.SerialNumber, EventType: models.VideoEventManual, FileSize: 10,
+		StartTime: now.Add(-time.Hour), EndTime: now.Add(-time.Hour + 20*time.Second),
+	})
+	require.NoError(t, err)
+	unanswered, err := videoService.RequestFootage(t.Context(), services.FootageRequest{
+		CameraID: &camera.ID, StartTime: now.Add(-time.Hour), EndTime: now.Add(-55 * time.Minute),
+	}, now)
+	require.NoError(t, err)
+
+	// Braking footage goes after 90 days; impact footage is kept for a year
+	w = sendJSON(tf, "PUT", "/api/v1/video/retention", map[string]interface{}{"event_type": "HARSH_BRAKING", "retention_days": 0}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	result, err := videoService.ApplyRetention(t.Context(), now.AddDate(0, 0, 100))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.ClipsExpired)
+	assert.Equal(t, 1, result.UploadsAbandoned)
+	assert.Equal(t, 1, result.RetrievalsTimedOut)
+
+	require.NoError(t, tf.DB.First(&plain, plain.ID).Error)
+	assert.Equal(t, models.VideoClipExpired, plain.Status)
+	assert.Empty(t, plain.StorageKey)
+	_, err = videoService.GetPlaybackURLs(t.Context(), &plain, now)
+	assert.ErrorIs(t, err, services.ErrClipUnavailable)
+	for _, id := range []uint{starred.ID, coached.ID, impact.ID} {
+		var kept models.VideoClip
+		require.NoError(t, tf.DB.First(&kept, id).Error)
+		assert.Equal(t, models.VideoClipAvailable, kept.Status, "clip %d", id)
+	}
+	require.NoError(t, tf.DB.First(stalled, stalled.ID).Error)
+	assert.Equal(t, models.VideoUploadFailed, stalled.Status)
+	require.NoError(t, tf.DB.First(unanswered, unanswered.ID).Error)
+	assert.Equal(t, models.VideoRetrievalExpired, unanswered.Status)
+
+	// Shortening impact retention expires it on the next run
+	w = sendJSON(tf, "PUT", "/api/v1/video/retention", map[string]interface{}{"event_type": "IMPACT", "retention_days": 30}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	result, err = videoService.ApplyRetention(t.Context(), now.AddDate(0, 0, 100))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.ClipsExpired)
+}
@@ -0,0 +1,155 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fleetflow/backend/internal/models"
+	"github.com/fleetflow/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkOrderPartsAndLabor(t *testing.T) {
+	tf, err := NewTestFramework()
+	require.NoError(t, err)
+	defer tf.CleanDatabase()
+
+	tf.CleanDatabase()
+	admin, err := tf.CreateTestUser(TestAdminPhone, models.RoleAdmin)
+	require.NoError(t, err)
+	token, err := tf.GenerateJWTToken(admin)
+	require.NoError(t, err)
+	mechanic, err := tf.CreateTestUser("+919876503901", models.RoleMechanic)
+	require.NoError(t, err)
+	dispatcher, err := tf.CreateTestUser("+919876503902", models.RoleDispatcher)
+	require.NoError(t, err)
+
+	vehicle, err := tf.CreateTestVehicle("MH12WP3901", "TRUCK")
+	require.NoError(t, err)
+	filter := models.InventoryItem{Name: "Oil Filter", SKU: "OF-6BT", Quantity: 12, MinQuantity: 10, UnitCost: 350}
+	require.NoError(t, tf.DB.Create(&filter).Error)
+	oil := models.InventoryItem{Name: "Engine Oil 15W-40 (L)", SKU: "EO-1540", Quantity: 40, MinQuantity: 20, UnitCost: 480}
+	require.NoError(t, tf.DB.Create(&oil).Error)
+
+	w := postJSON(tf, "/api/v1/maintenance/work-order", map[string]interface{}{
+		"vehicle_id": vehicle.ID, "description": "Oil service", "priority": "MEDIUM",
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	workOrderID := uint(decodeBody(t, w)["id"].(float64))
+	base := fmt.Sprintf("/api/v1/maintenance/work-order/%d", workOrderID)
+
+	addPart := func(itemID uint, quantity int) *httptest.ResponseRecorder {
+		return postJSON(tf, base+"/parts", map[string]interface{}{"inventory_item_id": itemID, "quantity": quantity}, token)
+	}
+
+	// Parts reserve stock without taking it off the shelf
+	w = addPart(filter.ID, 1)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = addPart(oil.ID, 15)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	oilPart := uint(decodeBody(t, w)["id"].(float64))
+	w = addPart(filter.ID, 5)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	extraFilters := uint(decodeBody(t, w)["id"].(float64))
+
+	require.NoError(t, tf.DB.First(&filter, filter.ID).Error)
+	assert.Equal(t, 12, filter.Quantity)
+	assert.Equal(t, 6, filter.ReservedQuantity)
+
+	w = addPart(oil.ID, 30)
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+	// Reserving below the minimum suggests a purchase
+	w = sendJSON(tf, "GET", "/api/v1/maintenance/purchase-suggestions", nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var suggestions []models.PurchaseOrderSuggestion
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &suggestions))
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, filter.ID, suggestions[0].InventoryItemID)
+	assert.Equal(t, 6, suggestions[0].AvailableQuantity)
+	assert.Equal(t, 14, suggestions[0].SuggestedQuantity)
+	assert.Equal(t, 4900.0, suggestions[0].EstimatedCost)
+
+	w = sendJSON(tf, "DELETE", fmt.Sprintf("%s/parts/%d", base, extraFilters), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// A part is taken off the shelf or returned to it only once
+	w = sendJSON(tf, "DELETE", fmt.Sprintf("%s/parts/%d", base, extraFilters), nil, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	w = postJSON(tf, fmt.Sprintf("%s/parts/%d/consume", base, extraFilters), nil, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	require.NoError(t, tf.DB.First(&filter, filter.ID).Error)
+	assert.Equal(t, 12, filter.Quantity)
+	assert.Equal(t, 1, filter.ReservedQuantity)
+
+	w = postJSON(tf, fmt.Sprintf("%s/parts/%d/consume", base, oilPart), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = postJSON(tf, fmt.Sprintf("%s/parts/%d/consume", base, oilPart), nil, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	w = sendJSON(tf, "DELETE", fmt.Sprintf("%s/parts/%d", base, oilPart), nil, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	require.NoError(t, tf.DB.First(&oil, oil.ID).Error)
+	assert.Equal(t, 25, oil.Quantity)
+	assert.Equal(t, 0, oil.ReservedQuantity)
+
+	// Labour is charged at the mechanic's standard rate unless another is given
+	w = postJSON(tf, base+"/labor", map[string]interface{}{"mechanic_id": mechanic.ID, "hours": 1.5}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	w = sendJSON(tf, "PUT", fmt.Sprintf("/api/v1/maintenance/mechanics/%d/rate", mechanic.ID), map[string]interface{}{"hourly_rate": 450}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = postJSON(tf, base+"/labor", map[string]interface{}{"mechanic_id": mechanic.ID, "hours": 1.5, "description": "Drain and refill"}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, 675.0, decodeBody(t, w)["total_cost"])
+	w = postJSON(tf, base+"/labor", map[string]interface{}{"mechanic_id": mechanic.ID, "hours": 0.5, "hourly_rate": 600}, token)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = postJSON(tf, base+"/labor", map[string]interface{}{"mechanic_id": dispatcher.ID, "hours": 1, "hourly_rate": 300}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	w = sendJSON(tf, "GET", base, nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var workOrder models.WorkOrder
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &workOrder))
+	assert.Len(t, workOrder.Parts, 2)
+	assert.Len(t, workOrder.Labor, 2)
+	assert.Equal(t, 7550.0, workOrder.CostParts)
+	assert.Equal(t, 975.0, workOrder.CostLabor)
+
+	// Resolving consumes the reserved parts and keeps the itemised costs
+	w = postJSON(tf, base+"/resolve", map[string]interface{}{"notes": "Done", "cost_parts": 1, "cost_labor": 1}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, tf.DB.First(&workOrder, workOrderID).Error)
+	assert.Equal(t, 7550.0, workOrder.CostParts)
+	assert.Equal(t, 975.0, workOrder.CostLabor)
+	assert.Equal(t, 8525.0, workOrder.TotalCost)
+
+	require.NoError(t, tf.DB.First(&filter, filter.ID).Error)
+	assert.Equal(t, 11, filter.Quantity)
+	assert.Equal(t, 0, filter.ReservedQuantity)
+	require.NoError(t, tf.DB.First(&oil, oil.ID).Error)
+	assert.Equal(t, 25, oil.Quantity)
+
+	w = addPart(oil.ID, 1)
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+	w = sendJSON(tf, "GET", fmt.Sprintf("/api/v1/maintenance/vehicles/%d/parts-history", vehicle.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var history services.VehiclePartsHistory
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	assert.Len(t, history.Parts, 2)
+	assert.Equal(t, 16, history.TotalParts)
+	assert.Equal(t, 7550.0, history.TotalCost)
+
+	// Ordering and then restocking closes the suggestion
+	w = sendJSON(tf, "PUT", fmt.Sprintf("/api/v1/maintenance/purchase-suggestions/%d", suggestions[0].ID), map[string]interface{}{"status": "ORDERED"}, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = postJSON(tf, fmt.Sprintf("/api/v1/inventory/%d/update?change=14", filter.ID), nil, token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var suggestion models.PurchaseOrderSuggestion
+	require.NoError(t, tf.DB.First(&suggestion, suggestions[0].ID).Error)
+	assert.Equal(t, models.PurchaseSuggestionReceived, suggestion.Status)
+	assert.NotNil(t, suggestion.OrderedAt)
+}
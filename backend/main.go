@@ -129,6 +129,62 @@ func main() {
 		}
 	}
 
+	// Start Safety Service and keep driver safety scores current
+	if serviceContainer.SafetyService != nil {
+		if err := serviceContainer.SafetyService.Start(); err != nil {
+			log.Printf("❌ Failed to start safety service: %v", err)
+		}
+		serviceContainer.SafetyService.StartDriverScoreRecalculation(cfg.DriverScoreInterval)
+	}
+
+	// Start tracker protocol listeners
+	if serviceContainer.DeviceGateway != nil {
+		if err := serviceContainer.DeviceGateway.Start(cfg.TrackerListeners); err != nil {
+			log.Printf("❌ Failed to start tracker gateway: %v", err)
+		}
+	}
+
+	// Scan tank-level telemetry for refuels and suspected theft
+	if serviceContainer.FuelService != nil {
+		serviceContainer.FuelService.StartFuelLevelMonitor(5 * time.Minute)
+	}
+
+	// Retrain fuel anomaly models as admins review fuel events
+	if serviceContainer.FuelService != nil {
+		serviceContainer.FuelService.StartFuelModelRetraining(cfg.FuelModelRetrainInterval)
+	}
+
+	// Apply fuel thresholds nightly and escalate fuel alerts nobody resolves
+	if serviceContainer.FuelService != nil {
+		serviceContainer.FuelService.StartFuelRuleEvaluation(cfg.FuelRuleEvaluationHour)
+		serviceContainer.FuelService.StartFuelAlertEscalation(15*time.Minute, cfg.FuelAlertEscalationAfter)
+	}
+
+	// Forecast component failures nightly and open work orders for due services
+	if serviceContainer.MaintenanceService != nil {
+		serviceContainer.MaintenanceService.StartMaintenanceForecast(cfg.MaintenanceForecastHour)
+		serviceContainer.MaintenanceService.StartServiceScheduler(cfg.MaintenanceSchedulerInterval)
+	}
+
+	// Drop SSO login requests that expired or were already used
+	if serviceContainer.SSOService != nil {
+		serviceContainer.SSOService.StartAuthRequestCleanup(cfg.SSOCleanupInterval)
+	}
+
+	// Sign audit chain checkpoints and archive expired audit records
+	if serviceContainer.AuditService != nil {
+		serviceContainer.AuditService.StartChainMaintenance(cfg.AuditCheckpointInterval, cfg.AuditRetentionDays)
+	}
+
+	// Track dashcam heartbeats, expire footage past its retention and clean up stalled uploads
+	if serviceContainer.VideoService != nil {
+		if err := serviceContainer.VideoService.Start(); err != nil {
+			log.Printf("❌ Failed to start video service: %v", err)
+		}
+		serviceContainer.VideoService.StartCameraHealthMonitor(cfg.CameraHealthInterval)
+		serviceContainer.VideoService.StartRetentionSweep(cfg.VideoRetentionInterval)
+	}
+
 	// Start server with error recovery
 	go func() {
 		defer func() {
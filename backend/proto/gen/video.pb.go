@@ -0,0 +1,470 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v3.21.12
+// source: video.proto
+
+package gen
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Messages
+type ClipMetadata struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	CameraSerial       string                 `protobuf:"bytes,1,opt,name=camera_serial,json=cameraSerial,proto3" json:"camera_serial,omitempty"`
+	EventType          string                 `protobuf:"bytes,2,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"` // HARSH_BRAKING, HARSH_ACCELERATION, HARSH_CORNERING, IMPACT, SPEEDING, MANUAL, DISTRACTION, DROWSINESS
+	StartTime          *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime            *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	ContentType        string                 `protobuf:"bytes,5,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	FileSize           int64                  `protobuf:"varint,6,opt,name=file_size,json=fileSize,proto3" json:"file_size,omitempty"`
+	Sha256             string                 `protobuf:"bytes,7,opt,name=sha256,proto3" json:"sha256,omitempty"`                                                      // Hex digest of the whole file, checked once it is assembled
+	ClipId             uint32                 `protobuf:"varint,8,opt,name=clip_id,json=clipId,proto3" json:"clip_id,omitempty"`                                       // Clip already recorded for an AI event
+	RetrievalRequestId uint32                 `protobuf:"varint,9,opt,name=retrieval_request_id,json=retrievalRequestId,proto3" json:"retrieval_request_id,omitempty"` // Footage the camera was asked for
+	UploadId           string                 `protobuf:"bytes,10,opt,name=upload_id,json=uploadId,proto3" json:"upload_id,omitempty"`                                 // Resume this upload instead of starting one
+	Offset             int64                  `protobuf:"varint,11,opt,name=offset,proto3" json:"offset,omitempty"`                                                    // Where the chunks that follow start when resuming
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *ClipMetadata) Reset() {
+	*x = ClipMetadata{}
+	mi := &file_video_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClipMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClipMetadata) ProtoMessage() {}
+
+func (x *ClipMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_video_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClipMetadata.ProtoReflect.Descriptor instead.
+func (*ClipMetadata) Descriptor() ([]byte, []int) {
+	return file_video_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ClipMetadata) GetCameraSerial() string {
+	if x != nil {
+		return x.CameraSerial
+	}
+	return ""
+}
+
+func (x *ClipMetadata) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *ClipMetadata) GetStartTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartTime
+	}
+	return nil
+}
+
+func (x *ClipMetadata) GetEndTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EndTime
+	}
+	return nil
+}
+
+func (x *ClipMetadata) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *ClipMetadata) GetFileSize() int64 {
+	if x != nil {
+		return x.FileSize
+	}
+	return 0
+}
+
+func (x *ClipMetadata) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *ClipMetadata) GetClipId() uint32 {
+	if x != nil {
+		return x.ClipId
+	}
+	return 0
+}
+
+func (x *ClipMetadata) GetRetrievalRequestId() uint32 {
+	if x != nil {
+		return x.RetrievalRequestId
+	}
+	return 0
+}
+
+func (x *ClipMetadata) GetUploadId() string {
+	if x != nil {
+		return x.UploadId
+	}
+	return ""
+}
+
+func (x *ClipMetadata) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type UploadClipRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Data:
+	//
+	//	*UploadClipRequest_Metadata
+	//	*UploadClipRequest_ChunkData
+	Data          isUploadClipRequest_Data `protobuf_oneof:"data"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadClipRequest) Reset() {
+	*x = UploadClipRequest{}
+	mi := &file_video_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadClipRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadClipRequest) ProtoMessage() {}
+
+func (x *UploadClipRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_video_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadClipRequest.ProtoReflect.Descriptor instead.
+func (*UploadClipRequest) Descriptor() ([]byte, []int) {
+	return file_video_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *UploadClipRequest) GetData() isUploadClipRequest_Data {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *UploadClipRequest) GetMetadata() *ClipMetadata {
+	if x != nil {
+		if x, ok := x.Data.(*UploadClipRequest_Metadata); ok {
+			return x.Metadata
+		}
+	}
+	return nil
+}
+
+func (x *UploadClipRequest) GetChunkData() []byte {
+	if x != nil {
+		if x, ok := x.Data.(*UploadClipRequest_ChunkData); ok {
+			return x.ChunkData
+		}
+	}
+	return nil
+}
+
+type isUploadClipRequest_Data interface {
+	isUploadClipRequest_Data()
+}
+
+type UploadClipRequest_Metadata struct {
+	Metadata *ClipMetadata `protobuf:"bytes,1,opt,name=metadata,proto3,oneof"`
+}
+
+type UploadClipRequest_ChunkData struct {
+	ChunkData []byte `protobuf:"bytes,2,opt,name=chunk_data,json=chunkData,proto3,oneof"`
+}
+
+func (*UploadClipRequest_Metadata) isUploadClipRequest_Data() {}
+
+func (*UploadClipRequest_ChunkData) isUploadClipRequest_Data() {}
+
+type GetClipUploadStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UploadId      string                 `protobuf:"bytes,1,opt,name=upload_id,json=uploadId,proto3" json:"upload_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetClipUploadStatusRequest) Reset() {
+	*x = GetClipUploadStatusRequest{}
+	mi := &file_video_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetClipUploadStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetClipUploadStatusRequest) ProtoMessage() {}
+
+func (x *GetClipUploadStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_video_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetClipUploadStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetClipUploadStatusRequest) Descriptor() ([]byte, []int) {
+	return file_video_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetClipUploadStatusRequest) GetUploadId() string {
+	if x != nil {
+		return x.UploadId
+	}
+	return ""
+}
+
+type ClipUploadStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UploadId      string                 `protobuf:"bytes,1,opt,name=upload_id,json=uploadId,proto3" json:"upload_id,omitempty"`
+	ClipId        uint32                 `protobuf:"varint,2,opt,name=clip_id,json=clipId,proto3" json:"clip_id,omitempty"`
+	ReceivedBytes int64                  `protobuf:"varint,3,opt,name=received_bytes,json=receivedBytes,proto3" json:"received_bytes,omitempty"`
+	TotalBytes    int64                  `protobuf:"varint,4,opt,name=total_bytes,json=totalBytes,proto3" json:"total_bytes,omitempty"`
+	Status        string                 `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"` // OPEN, COMPLETE, FAILED
+	Success       bool                   `protobuf:"varint,6,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,7,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClipUploadStatus) Reset() {
+	*x = ClipUploadStatus{}
+	mi := &file_video_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClipUploadStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClipUploadStatus) ProtoMessage() {}
+
+func (x *ClipUploadStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_video_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClipUploadStatus.ProtoReflect.Descriptor instead.
+func (*ClipUploadStatus) Descriptor() ([]byte, []int) {
+	return file_video_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ClipUploadStatus) GetUploadId() string {
+	if x != nil {
+		return x.UploadId
+	}
+	return ""
+}
+
+func (x *ClipUploadStatus) GetClipId() uint32 {
+	if x != nil {
+		return x.ClipId
+	}
+	return 0
+}
+
+func (x *ClipUploadStatus) GetReceivedBytes() int64 {
+	if x != nil {
+		return x.ReceivedBytes
+	}
+	return 0
+}
+
+func (x *ClipUploadStatus) GetTotalBytes() int64 {
+	if x != nil {
+		return x.TotalBytes
+	}
+	return 0
+}
+
+func (x *ClipUploadStatus) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ClipUploadStatus) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ClipUploadStatus) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+var File_video_proto protoreflect.FileDescriptor
+
+const file_video_proto_rawDesc = "" +
+	"\n" +
+	"\vvideo.proto\x12\ffleetflow.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x9c\x03\n" +
+	"\fClipMetadata\x12#\n" +
+	"\rcamera_serial\x18\x01 \x01(\tR\fcameraSerial\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x02 \x01(\tR\teventType\x129\n" +
+	"\n" +
+	"start_time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tstartTime\x125\n" +
+	"\bend_time\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\aendTime\x12!\n" +
+	"\fcontent_type\x18\x05 \x01(\tR\vcontentType\x12\x1b\n" +
+	"\tfile_size\x18\x06 \x01(\x03R\bfileSize\x12\x16\n" +
+	"\x06sha256\x18\a \x01(\tR\x06sha256\x12\x17\n" +
+	"\aclip_id\x18\b \x01(\rR\x06clipId\x120\n" +
+	"\x14retrieval_request_id\x18\t \x01(\rR\x12retrievalRequestId\x12\x1b\n" +
+	"\tupload_id\x18\n" +
+	" \x01(\tR\buploadId\x12\x16\n" +
+	"\x06offset\x18\v \x01(\x03R\x06offset\"v\n" +
+	"\x11UploadClipRequest\x128\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x1a.fleetflow.v1.ClipMetadataH\x00R\bmetadata\x12\x1f\n" +
+	"\n" +
+	"chunk_data\x18\x02 \x01(\fH\x00R\tchunkDataB\x06\n" +
+	"\x04data\"9\n" +
+	"\x1aGetClipUploadStatusRequest\x12\x1b\n" +
+	"\tupload_id\x18\x01 \x01(\tR\buploadId\"\xdc\x01\n" +
+	"\x10ClipUploadStatus\x12\x1b\n" +
+	"\tupload_id\x18\x01 \x01(\tR\buploadId\x12\x17\n" +
+	"\aclip_id\x18\x02 \x01(\rR\x06clipId\x12%\n" +
+	"\x0ereceived_bytes\x18\x03 \x01(\x03R\rreceivedBytes\x12\x1f\n" +
+	"\vtotal_bytes\x18\x04 \x01(\x03R\n" +
+	"totalBytes\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\tR\x06status\x12\x18\n" +
+	"\asuccess\x18\x06 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\a \x01(\tR\amessage2\xc0\x01\n" +
+	"\fVideoService\x12O\n" +
+	"\n" +
+	"UploadClip\x12\x1f.fleetflow.v1.UploadClipRequest\x1a\x1e.fleetflow.v1.ClipUploadStatus(\x01\x12_\n" +
+	"\x13GetClipUploadStatus\x12(.fleetflow.v1.GetClipUploadStatusRequest\x1a\x1e.fleetflow.v1.ClipUploadStatusB(Z&github.com/fleetflow/backend/proto/genb\x06proto3"
+
+var (
+	file_video_proto_rawDescOnce sync.Once
+	file_video_proto_rawDescData []byte
+)
+
+func file_video_proto_rawDescGZIP() []byte {
+	file_video_proto_rawDescOnce.Do(func() {
+		file_video_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_video_proto_rawDesc), len(file_video_proto_rawDesc)))
+	})
+	return file_video_proto_rawDescData
+}
+
+var file_video_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_video_proto_goTypes = []any{
+	(*ClipMetadata)(nil),               // 0: fleetflow.v1.ClipMetadata
+	(*UploadClipRequest)(nil),          // 1: fleetflow.v1.UploadClipRequest
+	(*GetClipUploadStatusRequest)(nil), // 2: fleetflow.v1.GetClipUploadStatusRequest
+	(*ClipUploadStatus)(nil),           // 3: fleetflow.v1.ClipUploadStatus
+	(*timestamppb.Timestamp)(nil),      // 4: google.protobuf.Timestamp
+}
+var file_video_proto_depIdxs = []int32{
+	4, // 0: fleetflow.v1.ClipMetadata.start_time:type_name -> google.protobuf.Timestamp
+	4, // 1: fleetflow.v1.ClipMetadata.end_time:type_name -> google.protobuf.Timestamp
+	0, // 2: fleetflow.v1.UploadClipRequest.metadata:type_name -> fleetflow.v1.ClipMetadata
+	1, // 3: fleetflow.v1.VideoService.UploadClip:input_type -> fleetflow.v1.UploadClipRequest
+	2, // 4: fleetflow.v1.VideoService.GetClipUploadStatus:input_type -> fleetflow.v1.GetClipUploadStatusRequest
+	3, // 5: fleetflow.v1.VideoService.UploadClip:output_type -> fleetflow.v1.ClipUploadStatus
+	3, // 6: fleetflow.v1.VideoService.GetClipUploadStatus:output_type -> fleetflow.v1.ClipUploadStatus
+	5, // [5:7] is the sub-list for method output_type
+	3, // [3:5] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_video_proto_init() }
+func file_video_proto_init() {
+	if File_video_proto != nil {
+		return
+	}
+	file_video_proto_msgTypes[1].OneofWrappers = []any{
+		(*UploadClipRequest_Metadata)(nil),
+		(*UploadClipRequest_ChunkData)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_video_proto_rawDesc), len(file_video_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_video_proto_goTypes,
+		DependencyIndexes: file_video_proto_depIdxs,
+		MessageInfos:      file_video_proto_msgTypes,
+	}.Build()
+	File_video_proto = out.File
+	file_video_proto_goTypes = nil
+	file_video_proto_depIdxs = nil
+}
@@ -0,0 +1,163 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.0
+// - protoc             v3.21.12
+// source: video.proto
+
+package gen
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	VideoService_UploadClip_FullMethodName          = "/fleetflow.v1.VideoService/UploadClip"
+	VideoService_GetClipUploadStatus_FullMethodName = "/fleetflow.v1.VideoService/GetClipUploadStatus"
+)
+
+// VideoServiceClient is the client API for VideoService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Dashcam Video Service
+type VideoServiceClient interface {
+	// Clip upload from a camera: metadata first, then the file in chunks.
+	// An interrupted upload resumes by sending its upload_id and the offset to continue from.
+	UploadClip(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadClipRequest, ClipUploadStatus], error)
+	// How much of an upload has been received, to resume from
+	GetClipUploadStatus(ctx context.Context, in *GetClipUploadStatusRequest, opts ...grpc.CallOption) (*ClipUploadStatus, error)
+}
+
+type videoServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewVideoServiceClient(cc grpc.ClientConnInterface) VideoServiceClient {
+	return &videoServiceClient{cc}
+}
+
+func (c *videoServiceClient) UploadClip(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadClipRequest, ClipUploadStatus], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &VideoService_ServiceDesc.Streams[0], VideoService_UploadClip_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UploadClipRequest, ClipUploadStatus]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type VideoService_UploadClipClient = grpc.ClientStreamingClient[UploadClipRequest, ClipUploadStatus]
+
+func (c *videoServiceClient) GetClipUploadStatus(ctx context.Context, in *GetClipUploadStatusRequest, opts ...grpc.CallOption) (*ClipUploadStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ClipUploadStatus)
+	err := c.cc.Invoke(ctx, VideoService_GetClipUploadStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// VideoServiceServer is the server API for VideoService service.
+// All implementations must embed UnimplementedVideoServiceServer
+// for forward compatibility.
+//
+// Dashcam Video Service
+type VideoServiceServer interface {
+	// Clip upload from a camera: metadata first, then the file in chunks.
+	// An interrupted upload resumes by sending its upload_id and the offset to continue from.
+	UploadClip(grpc.ClientStreamingServer[UploadClipRequest, ClipUploadStatus]) error
+	// How much of an upload has been received, to resume from
+	GetClipUploadStatus(context.Context, *GetClipUploadStatusRequest) (*ClipUploadStatus, error)
+	mustEmbedUnimplementedVideoServiceServer()
+}
+
+// UnimplementedVideoServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedVideoServiceServer struct{}
+
+func (UnimplementedVideoServiceServer) UploadClip(grpc.ClientStreamingServer[UploadClipRequest, ClipUploadStatus]) error {
+	return status.Error(codes.Unimplemented, "method UploadClip not implemented")
+}
+func (UnimplementedVideoServiceServer) GetClipUploadStatus(context.Context, *GetClipUploadStatusRequest) (*ClipUploadStatus, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetClipUploadStatus not implemented")
+}
+func (UnimplementedVideoServiceServer) mustEmbedUnimplementedVideoServiceServer() {}
+func (UnimplementedVideoServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeVideoServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to VideoServiceServer will
+// result in compilation errors.
+type UnsafeVideoServiceServer interface {
+	mustEmbedUnimplementedVideoServiceServer()
+}
+
+func RegisterVideoServiceServer(s grpc.ServiceRegistrar, srv VideoServiceServer) {
+	// If the following call panics, it indicates UnimplementedVideoServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&VideoService_ServiceDesc, srv)
+}
+
+func _VideoService_UploadClip_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(VideoServiceServer).UploadClip(&grpc.GenericServerStream[UploadClipRequest, ClipUploadStatus]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type VideoService_UploadClipServer = grpc.ClientStreamingServer[UploadClipRequest, ClipUploadStatus]
+
+func _VideoService_GetClipUploadStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetClipUploadStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VideoServiceServer).GetClipUploadStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VideoService_GetClipUploadStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VideoServiceServer).GetClipUploadStatus(ctx, req.(*GetClipUploadStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// VideoService_ServiceDesc is the grpc.ServiceDesc for VideoService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var VideoService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "fleetflow.v1.VideoService",
+	HandlerType: (*VideoServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetClipUploadStatus",
+			Handler:    _VideoService_GetClipUploadStatus_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadClip",
+			Handler:       _VideoService_UploadClip_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "video.proto",
+}